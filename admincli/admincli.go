@@ -0,0 +1,212 @@
+// Package admincli is the command line client to manage a broker through its HTTP API, run as `webhook-broker admin`
+package admincli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	// ExitOK is the exit code of a command that succeeded
+	ExitOK = 0
+	// ExitError is the exit code of a command that failed, e.g. due to an error response from the broker
+	ExitError = 1
+	// ExitUsage is the exit code when the command or its flags and arguments are not valid
+	ExitUsage = 2
+)
+
+var (
+	// ErrUnknownCommand is returned when the command is not one of the admin commands
+	ErrUnknownCommand = errors.New("unknown command")
+	// ErrArgumentCount is returned when a command is not passed the arguments it expects
+	ErrArgumentCount = errors.New("wrong number of arguments")
+)
+
+// usageError is an error in how the command was invoked, as opposed to one in carrying it out
+type usageError struct {
+	err error
+	// reported is set when the flag package already printed the error along with the usage
+	reported bool
+}
+
+func (err *usageError) Error() string {
+	return err.err.Error()
+}
+
+func (err *usageError) Unwrap() error {
+	return err.err
+}
+
+// command is an admin command, e.g. `channel put`, along with its usage
+type command struct {
+	args        string
+	description string
+	run         func(ctx context.Context, cli *cli, args []string) error
+}
+
+// cli is the state shared by the commands of an invocation
+type cli struct {
+	name    string
+	args    string
+	client  *brokerClient
+	printer *printer
+	profile *Profile
+	stdin   io.Reader
+	stderr  io.Writer
+}
+
+// Run runs the admin command in args, e.g. `-profile prod channel list`, and returns the exit code for the process
+func Run(ctx context.Context, programName string, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	name := programName + " admin"
+	globalFlags := flag.NewFlagSet(name, flag.ContinueOnError)
+	var buf bytes.Buffer
+	globalFlags.SetOutput(&buf)
+	profileName := globalFlags.String("profile", getEnvOrDefault(ProfileEnvVar, DefaultProfileName), "Profile of the broker to manage")
+	profilesPath := globalFlags.String("profiles", GetDefaultProfilesPath(), "Profiles file location")
+	brokerURL := globalFlags.String("url", "", "Broker URL, overrides the profile's")
+	output := globalFlags.String("output", "", "Output format, table or json; overrides the profile's")
+	globalFlags.Usage = func() { printUsage(&buf, name, globalFlags) }
+	if err := globalFlags.Parse(args); err != nil {
+		return writeUsage(stdout, stderr, buf.String(), err)
+	}
+	args = globalFlags.Args()
+	if len(args) == 0 || args[0] == "help" {
+		printUsage(stdout, name, globalFlags)
+		return ExitOK
+	}
+	cmdName, cmd, args := findCommand(args)
+	if cmd == nil {
+		fmt.Fprintf(stderr, "%s: %s\n", ErrUnknownCommand, strings.Join(args, " "))
+		printUsage(stderr, name, globalFlags)
+		return ExitUsage
+	}
+	profile, err := LoadProfile(*profilesPath, *profileName)
+	if err == nil && len(*brokerURL) > 0 {
+		profile.URL, err = parseBrokerURL(*brokerURL)
+	}
+	if err == nil && len(*output) > 0 {
+		if profile.Output = *output; !isValidOutputFormat(profile.Output) {
+			err = ErrInvalidOutputFormat
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitUsage
+	}
+	instance := &cli{name: name + " " + cmdName, args: cmd.args, client: newBrokerClient(profile), printer: &printer{format: profile.Output, out: stdout},
+		profile: profile, stdin: stdin, stderr: stderr}
+	err = cmd.run(ctx, instance, args)
+	var usageErr *usageError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &usageErr):
+		if usageErr.err == flag.ErrHelp {
+			return ExitOK
+		}
+		if usageErr.reported {
+			return ExitUsage
+		}
+		fmt.Fprintf(stderr, "%s: %s\nUsage: %s %s\n", instance.name, err, instance.name, instance.args)
+		return ExitUsage
+	default:
+		fmt.Fprintf(stderr, "%s: %s\n", instance.name, err)
+		return ExitError
+	}
+}
+
+// findCommand finds the command, either a single word like `publish` or an entity with an action like `channel get`, and returns the
+// rest of the args for it
+func findCommand(args []string) (string, *command, []string) {
+	if len(args) > 1 {
+		if cmd, ok := commands[args[0]+" "+args[1]]; ok {
+			return args[0] + " " + args[1], cmd, args[2:]
+		}
+	}
+	if cmd, ok := commands[args[0]]; ok {
+		return args[0], cmd, args[1:]
+	}
+	return "", nil, args
+}
+
+func writeUsage(stdout, stderr io.Writer, usage string, err error) int {
+	if err == flag.ErrHelp {
+		fmt.Fprint(stdout, usage)
+		return ExitOK
+	}
+	fmt.Fprint(stderr, usage)
+	return ExitUsage
+}
+
+func printUsage(out io.Writer, name string, globalFlags *flag.FlagSet) {
+	fmt.Fprintf(out, "Usage: %s [flags] <command> [command flags] [args]\n\nFlags:\n", name)
+	globalFlags.SetOutput(out)
+	globalFlags.PrintDefaults()
+	fmt.Fprint(out, "\nCommands:\n")
+	names := make([]string, 0, len(commands))
+	for cmdName := range commands {
+		names = append(names, cmdName)
+	}
+	sort.Strings(names)
+	for _, cmdName := range names {
+		fmt.Fprintf(out, "  %s %s\n    \t%s\n", cmdName, commands[cmdName].args, commands[cmdName].description)
+	}
+	fmt.Fprintf(out, "\nRun '%s <command> -h' for the flags of a command\n", name)
+}
+
+// parseCommandFlags parses the flags of a command which could be before, after or in between its positional args, and checks the
+// count of positional args is within the range
+func (cli *cli) parseCommandFlags(flags *flag.FlagSet, args []string, minArgs, maxArgs int) ([]string, error) {
+	flags.SetOutput(cli.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(cli.stderr, "Usage: %s %s\n", cli.name, cli.args)
+		flags.PrintDefaults()
+	}
+	positionals := make([]string, 0, len(args))
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, &usageError{err: err, reported: true}
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			break
+		}
+		positionals = append(positionals, args[0])
+		args = args[1:]
+	}
+	if len(positionals) < minArgs || (maxArgs >= 0 && len(positionals) > maxArgs) {
+		return nil, &usageError{err: ErrArgumentCount}
+	}
+	return positionals, nil
+}
+
+// newFlagSet returns the flag set of the command being run
+func (cli *cli) newFlagSet() *flag.FlagSet {
+	return flag.NewFlagSet(cli.name, flag.ContinueOnError)
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); len(value) > 0 {
+		return value
+	}
+	return defaultValue
+}
+
+// stringsFlag is a flag that can be repeated, e.g. `-file a.json -file b.json`
+type stringsFlag []string
+
+func (values *stringsFlag) String() string {
+	return strings.Join(*values, ",")
+}
+
+func (values *stringsFlag) Set(value string) error {
+	*values = append(*values, value)
+	return nil
+}
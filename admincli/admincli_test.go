@@ -0,0 +1,474 @@
+package admincli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testPathPrefix   = "/prefix"
+	testLastModified = "Mon, 02 Jan 2006 15:04:05 GMT"
+)
+
+// recordedRequest is a request received by the fake broker
+type recordedRequest struct {
+	Method string
+	URI    string
+	Header http.Header
+	Body   string
+}
+
+// fakeBroker serves responses keyed by method and request URI, or just the path when not found, without the path prefix and records the
+// requests received
+type fakeBroker struct {
+	*httptest.Server
+	mutex     sync.Mutex
+	requests  []*recordedRequest
+	responses map[string]func(w http.ResponseWriter, r *http.Request)
+}
+
+func newFakeBroker(t *testing.T) *fakeBroker {
+	broker := &fakeBroker{responses: make(map[string]func(w http.ResponseWriter, r *http.Request))}
+	broker.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		uri := strings.TrimPrefix(r.URL.RequestURI(), testPathPrefix)
+		broker.mutex.Lock()
+		broker.requests = append(broker.requests, &recordedRequest{Method: r.Method, URI: uri, Header: r.Header, Body: string(body)})
+		respond, ok := broker.responses[r.Method+" "+uri]
+		if !ok {
+			respond, ok = broker.responses[r.Method+" "+strings.TrimPrefix(r.URL.Path, testPathPrefix)]
+		}
+		broker.mutex.Unlock()
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("404 page not found"))
+			return
+		}
+		respond(w, r)
+	}))
+	t.Cleanup(broker.Close)
+	return broker
+}
+
+func (broker *fakeBroker) handle(methodAndURI string, respond func(w http.ResponseWriter, r *http.Request)) {
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+	broker.responses[methodAndURI] = respond
+}
+
+func (broker *fakeBroker) respond(methodAndURI string, status int, body string, header ...string) {
+	broker.handle(methodAndURI, func(w http.ResponseWriter, r *http.Request) {
+		for index := 0; index+1 < len(header); index += 2 {
+			w.Header().Set(header[index], header[index+1])
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	})
+}
+
+func (broker *fakeBroker) getRequests(methodAndURI string) []*recordedRequest {
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+	requests := make([]*recordedRequest, 0)
+	for _, request := range broker.requests {
+		if request.Method+" "+request.URI == methodAndURI {
+			requests = append(requests, request)
+		}
+	}
+	return requests
+}
+
+func runTestCommand(ctx context.Context, t *testing.T, broker *fakeBroker, stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	allArgs := []string{"-profiles", filepath.Join(t.TempDir(), ProfilesFilename)}
+	if broker != nil {
+		allArgs = append(allArgs, "-url", broker.URL+testPathPrefix)
+	}
+	code := Run(ctx, "webhook-broker", append(allArgs, args...), strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRunUsage(t *testing.T) {
+	ctx := context.Background()
+	code, stdout, _ := runTestCommand(ctx, t, nil, "")
+	assert.Equal(t, ExitOK, code)
+	assert.Contains(t, stdout, "Usage: webhook-broker admin")
+	assert.Contains(t, stdout, "channel put [flags] <channel>")
+	code, stdout, _ = runTestCommand(ctx, t, nil, "", "-h")
+	assert.Equal(t, ExitOK, code)
+	assert.Contains(t, stdout, "dlq requeue")
+	code, _, stderr := runTestCommand(ctx, t, nil, "", "-no-such-flag")
+	assert.Equal(t, ExitUsage, code)
+	assert.Contains(t, stderr, "-no-such-flag")
+	code, _, stderr = runTestCommand(ctx, t, nil, "", "channel", "rename")
+	assert.Equal(t, ExitUsage, code)
+	assert.Contains(t, stderr, ErrUnknownCommand.Error()+": channel rename")
+	code, _, stderr = runTestCommand(ctx, t, nil, "", "channel", "get")
+	assert.Equal(t, ExitUsage, code)
+	assert.Contains(t, stderr, "webhook-broker admin channel get: "+ErrArgumentCount.Error())
+	assert.Contains(t, stderr, "Usage: webhook-broker admin channel get <channel>")
+	code, _, stderr = runTestCommand(ctx, t, nil, "", "channel", "put", "-h")
+	assert.Equal(t, ExitOK, code)
+	assert.Contains(t, stderr, "-max-payload-size")
+	code, _, stderr = runTestCommand(ctx, t, nil, "", "channel", "put", "-no-such-flag", "ch")
+	assert.Equal(t, ExitUsage, code)
+	assert.Equal(t, 1, strings.Count(stderr, "Usage:"))
+	code, _, stderr = runTestCommand(ctx, t, nil, "", "-output", "yaml", "channel", "list")
+	assert.Equal(t, ExitUsage, code)
+	assert.Contains(t, stderr, ErrInvalidOutputFormat.Error())
+	code, _, stderr = runTestCommand(ctx, t, nil, "", "-url", "localhost", "channel", "list")
+	assert.Equal(t, ExitUsage, code)
+	assert.Contains(t, stderr, ErrInvalidBrokerURL.Error())
+	code, _, stderr = runTestCommand(ctx, t, nil, "", "-profile", "prod", "channel", "list")
+	assert.Equal(t, ExitUsage, code)
+	assert.Contains(t, stderr, ErrProfileNotFound.Error())
+}
+
+func TestRunWithProfile(t *testing.T) {
+	broker := newFakeBroker(t)
+	broker.respond("GET /channels", http.StatusOK, `{"Result":[],"Pages":{}}`)
+	profilesPath := writeTestProfiles(t, "[prod]\nurl="+broker.URL+testPathPrefix+"\nusername=admin\npassword=secret\noutput=json\n")
+	var stdout, stderr bytes.Buffer
+	t.Setenv(ProfileEnvVar, "prod")
+	assert.Equal(t, ExitOK, Run(context.Background(), "webhook-broker", []string{"-profiles", profilesPath, "channel", "list"}, nil, &stdout, &stderr))
+	assert.Equal(t, "[]\n", stdout.String())
+	requests := broker.getRequests("GET /channels")
+	assert.Equal(t, 1, len(requests))
+	username, password, ok := (&http.Request{Header: requests[0].Header}).BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "admin", username)
+	assert.Equal(t, "secret", password)
+}
+
+func TestListCommands(t *testing.T) {
+	ctx := context.Background()
+	broker := newFakeBroker(t)
+	broker.respond("GET /channels", http.StatusOK, `{"Result":["/channel/a"],"Pages":{"next":"/channels?next=cursor"}}`)
+	broker.respond("GET /channels?next=cursor", http.StatusOK, `{"Result":["/channel/b%20c"],"Pages":{"previous":"/channels?previous=cursor"}}`)
+	broker.respond("GET /producers", http.StatusOK, `{"Result":["/producer/p"],"Pages":{}}`)
+	broker.respond("GET /channel/a/consumers", http.StatusOK, `{"Result":["/channel/a/consumer/c"],"Pages":{}}`)
+	code, stdout, stderr := runTestCommand(ctx, t, broker, "", "channel", "list")
+	assert.Equal(t, ExitOK, code, stderr)
+	assert.Equal(t, "ID   URL\na    /channel/a\nb c  /channel/b%20c\n", stdout)
+	code, stdout, _ = runTestCommand(ctx, t, broker, "", "-output", "json", "producer", "list")
+	assert.Equal(t, ExitOK, code)
+	assert.JSONEq(t, `[{"ID":"p","URL":"/producer/p"}]`, stdout)
+	code, stdout, _ = runTestCommand(ctx, t, broker, "", "consumer", "list", "a")
+	assert.Equal(t, ExitOK, code)
+	assert.Contains(t, stdout, "/channel/a/consumer/c")
+	code, _, stderr = runTestCommand(ctx, t, broker, "", "consumer", "list", "b")
+	assert.Equal(t, ExitError, code)
+	assert.Contains(t, stderr, "GET /prefix/channel/b/consumers: 404 Not Found: 404 page not found")
+}
+
+func TestGetCommands(t *testing.T) {
+	ctx := context.Background()
+	broker := newFakeBroker(t)
+	channel := `{"ID":"ch","Name":"Channel","Token":"ch-token","MaxPayloadSize":0,"RateLimit":{"MessagesPerSecond":10},"ChangedAt":"2006-01-02T15:04:05Z"}`
+	broker.respond("GET /channel/ch", http.StatusOK, channel, headerLastModified, testLastModified)
+	broker.respond("GET /producer/p", http.StatusOK, `{"ID":"p","Token":"p-token"}`)
+	broker.respond("GET /channel/ch/consumer/c", http.StatusOK, `{"ID":"c","CallbackURL":"https://example.com/hook\nnext"}`)
+	code, stdout, _ := runTestCommand(ctx, t, broker, "", "channel", "get", "ch")
+	assert.Equal(t, ExitOK, code)
+	assert.Equal(t, "FIELD           VALUE\nChangedAt       2006-01-02T15:04:05Z\nID              ch\nMaxPayloadSize  0\nName            Channel\n"+
+		"RateLimit       {\"MessagesPerSecond\":10}\nToken           ch-token\n", stdout)
+	code, stdout, _ = runTestCommand(ctx, t, broker, "", "-output", "json", "channel", "get", "ch")
+	assert.Equal(t, ExitOK, code)
+	assert.JSONEq(t, channel, stdout)
+	code, stdout, _ = runTestCommand(ctx, t, broker, "", "producer", "get", "p")
+	assert.Equal(t, ExitOK, code)
+	assert.Contains(t, stdout, "p-token")
+	code, stdout, _ = runTestCommand(ctx, t, broker, "", "consumer", "get", "ch", "c")
+	assert.Equal(t, ExitOK, code)
+	assert.Contains(t, stdout, `https://example.com/hook\nnext`)
+}
+
+func TestPutCommands(t *testing.T) {
+	ctx := context.Background()
+	t.Run("Update", func(t *testing.T) {
+		broker := newFakeBroker(t)
+		broker.respond("GET /channel/ch", http.StatusOK, `{"ID":"ch","Name":"Old","Token":"ch-token","MaxPayloadSize":1024,"RateLimit":{"MessagesPerSecond":10}}`,
+			headerLastModified, testLastModified)
+		broker.respond("PUT /channel/ch", http.StatusOK, `{"ID":"ch","Name":"New"}`)
+		code, stdout, stderr := runTestCommand(ctx, t, broker, "", "channel", "put", "-name", "New", "ch", "-messages-per-second", "5")
+		assert.Equal(t, ExitOK, code, stderr)
+		assert.Contains(t, stdout, "New")
+		requests := broker.getRequests("PUT /channel/ch")
+		assert.Equal(t, 1, len(requests))
+		assert.Equal(t, testLastModified, requests[0].Header.Get(headerUnmodifiedSince))
+		assert.Equal(t, formContentTypeValue, requests[0].Header.Get(headerContentType))
+		assert.Equal(t, "maxPayloadSize=1024&messagesPerSecond=5&name=New&token=ch-token", requests[0].Body)
+	})
+	t.Run("Create", func(t *testing.T) {
+		broker := newFakeBroker(t)
+		broker.respond("PUT /channel/ch/consumer/c", http.StatusCreated, `{"ID":"c"}`)
+		code, _, stderr := runTestCommand(ctx, t, broker, "", "consumer", "put", "ch", "c", "-callback-url", "https://example.com/hook",
+			"-backfill-since", "1h")
+		assert.Equal(t, ExitOK, code, stderr)
+		requests := broker.getRequests("PUT /channel/ch/consumer/c")
+		assert.Equal(t, 1, len(requests))
+		assert.Equal(t, "", requests[0].Header.Get(headerUnmodifiedSince))
+		assert.Equal(t, "backfillSince=1h&callbackUrl=https%3A%2F%2Fexample.com%2Fhook", requests[0].Body)
+	})
+	t.Run("Error", func(t *testing.T) {
+		broker := newFakeBroker(t)
+		broker.respond("GET /producer/p", http.StatusOK, `{"ID":"p","Token":"p-token"}`, headerLastModified, testLastModified)
+		broker.respond("PUT /producer/p", http.StatusPreconditionFailed, "Update failed")
+		code, _, stderr := runTestCommand(ctx, t, broker, "", "producer", "put", "p")
+		assert.Equal(t, ExitError, code)
+		assert.Contains(t, stderr, "PUT /prefix/producer/p: 412 Precondition Failed: Update failed")
+		broker.respond("GET /producer/q", http.StatusInternalServerError, "")
+		code, _, _ = runTestCommand(ctx, t, broker, "", "producer", "put", "q")
+		assert.Equal(t, ExitError, code)
+		assert.Equal(t, 0, len(broker.getRequests("PUT /producer/q")))
+	})
+}
+
+func TestDeleteCommands(t *testing.T) {
+	ctx := context.Background()
+	broker := newFakeBroker(t)
+	broker.respond("GET /channel/ch", http.StatusOK, `{"ID":"ch"}`, headerLastModified, testLastModified)
+	broker.respond("DELETE /channel/ch?drain=true", http.StatusNoContent, "")
+	code, stdout, stderr := runTestCommand(ctx, t, broker, "", "channel", "delete", "-drain", "ch")
+	assert.Equal(t, ExitOK, code, stderr)
+	assert.Contains(t, stdout, "deleted")
+	requests := broker.getRequests("DELETE /channel/ch?drain=true")
+	assert.Equal(t, 1, len(requests))
+	assert.Equal(t, testLastModified, requests[0].Header.Get(headerUnmodifiedSince))
+	code, _, _ = runTestCommand(ctx, t, broker, "", "producer", "delete", "-drain", "p")
+	assert.Equal(t, ExitUsage, code)
+	// A deleted producer can not be read, so the purge is retried with the `Last-Modified` of its rejection
+	broker.handle("DELETE /producer/p?purge=true", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headerLastModified, testLastModified)
+		if r.Header.Get(headerUnmodifiedSince) != testLastModified {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	code, stdout, stderr = runTestCommand(ctx, t, broker, "", "producer", "delete", "-purge", "p")
+	assert.Equal(t, ExitOK, code, stderr)
+	assert.Contains(t, stdout, "purged")
+	assert.Equal(t, 2, len(broker.getRequests("DELETE /producer/p?purge=true")))
+	code, _, _ = runTestCommand(ctx, t, broker, "", "consumer", "delete", "ch", "c")
+	assert.Equal(t, ExitError, code)
+}
+
+func TestPublish(t *testing.T) {
+	ctx := context.Background()
+	broker := newFakeBroker(t)
+	broker.respond("GET /channel/ch", http.StatusOK, `{"ID":"ch","Token":"ch-token"}`)
+	broker.respond("POST /channel/ch/broadcast", http.StatusAccepted, "")
+	dir := t.TempDir()
+	files := []string{filepath.Join(dir, "first.json"), filepath.Join(dir, "second.json")}
+	for _, file := range files {
+		assert.Nil(t, ioutil.WriteFile(file, []byte(`{"file":"`+filepath.Base(file)+`"}`), 0600))
+	}
+	t.Run("Files", func(t *testing.T) {
+		code, stdout, stderr := runTestCommand(ctx, t, broker, "", "publish", "-producer-id", "p", "-producer-token", "p-token", "-file", files[0],
+			"-file", files[1], "-content-type", "application/json", "-priority", "2", "-routing-key", "orders.created", "-attr", "region=us", "ch")
+		assert.Equal(t, ExitOK, code, stderr)
+		assert.Contains(t, stdout, "202 Accepted")
+		requests := broker.getRequests("POST /channel/ch/broadcast")
+		assert.Equal(t, 2, len(requests))
+		for index, request := range requests {
+			assert.Equal(t, `{"file":"`+filepath.Base(files[index])+`"}`, request.Body)
+			assert.Equal(t, "ch-token", request.Header.Get(headerChannelToken))
+			assert.Equal(t, "p", request.Header.Get(headerProducerID))
+			assert.Equal(t, "p-token", request.Header.Get(headerProducerToken))
+			assert.Equal(t, "application/json", request.Header.Get(headerContentType))
+			assert.Equal(t, "2", request.Header.Get(headerPriority))
+			assert.Equal(t, "orders.created", request.Header.Get(headerRoutingKey))
+			assert.Equal(t, "us", request.Header.Get(headerAttributePrefix+"Region"))
+			assert.NotEmpty(t, request.Header.Get(headerMessageID))
+			assert.Contains(t, stdout, request.Header.Get(headerMessageID))
+		}
+		assert.NotEqual(t, requests[0].Header.Get(headerMessageID), requests[1].Header.Get(headerMessageID))
+	})
+	t.Run("Stdin", func(t *testing.T) {
+		profilesPath := writeTestProfiles(t, "[default]\nurl="+broker.URL+testPathPrefix+"\nproducer-id=ops\nproducer-token=ops-token\n")
+		var stdout, stderr bytes.Buffer
+		code := Run(ctx, "webhook-broker", []string{"-profiles", profilesPath, "-output", "json", "publish", "-id", "msg-1", "-channel-token", "token",
+			"ch"}, strings.NewReader("from stdin"), &stdout, &stderr)
+		assert.Equal(t, ExitOK, code, stderr.String())
+		assert.JSONEq(t, `[{"File":"-","MessageID":"msg-1","Status":"202 Accepted"}]`, stdout.String())
+		requests := broker.getRequests("POST /channel/ch/broadcast")
+		request := requests[len(requests)-1]
+		assert.Equal(t, "from stdin", request.Body)
+		assert.Equal(t, "token", request.Header.Get(headerChannelToken))
+		assert.Equal(t, "ops", request.Header.Get(headerProducerID))
+		assert.Equal(t, "msg-1", request.Header.Get(headerMessageID))
+		assert.Equal(t, defaultContentType, request.Header.Get(headerContentType))
+		assert.Empty(t, request.Header.Get(headerPriority))
+	})
+	t.Run("Usage", func(t *testing.T) {
+		for _, args := range [][]string{
+			{"publish", "ch"},
+			{"publish", "-producer-id", "p", "-producer-token", "p-token", "-id", "m", "-file", files[0], "-file", files[1], "ch"},
+			{"publish", "-producer-id", "p", "-producer-token", "p-token", "-attr", "region", "ch"},
+		} {
+			code, _, _ := runTestCommand(ctx, t, broker, "", args...)
+			assert.Equal(t, ExitUsage, code, args)
+		}
+	})
+	t.Run("Error", func(t *testing.T) {
+		code, _, _ := runTestCommand(ctx, t, broker, "", "publish", "-producer-id", "p", "-producer-token", "p-token", "-file", "no-such-file", "ch")
+		assert.Equal(t, ExitError, code)
+		broker.respond("POST /channel/ch/broadcast", http.StatusForbidden, "Forbidden")
+		code, stdout, stderr := runTestCommand(ctx, t, broker, "", "publish", "-producer-id", "p", "-producer-token", "p-token", "ch")
+		assert.Equal(t, ExitError, code)
+		assert.Equal(t, "", stdout)
+		assert.Contains(t, stderr, "403 Forbidden")
+		code, _, _ = runTestCommand(ctx, t, broker, "", "publish", "-producer-id", "p", "-producer-token", "p-token", "other-channel")
+		assert.Equal(t, ExitError, code)
+	})
+}
+
+func TestTail(t *testing.T) {
+	broker := newFakeBroker(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var polls int
+	var froms []string
+	broker.handle("GET /channel/ch/messages", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, messageExpandPayload, r.URL.Query().Get(messageExpandParamName))
+		polls++
+		froms = append(froms, r.URL.Query().Get(messageFromParamName))
+		switch polls {
+		case 1:
+			w.Write([]byte(`{"Result":[],"Pages":{},"Messages":[{"MessageID":"m2","ReceivedAt":"2006-01-02T15:04:06.5Z","Payload":"two"},` +
+				`{"MessageID":"m1","ReceivedAt":"2006-01-02T15:04:05Z","Payload":"one"}]}`))
+		case 2:
+			w.Write([]byte(`{"Result":[],"Pages":{},"Messages":[{"MessageID":"m3","ReceivedAt":"2006-01-02T15:04:07Z","Payload":"three"},` +
+				`{"MessageID":"m2","ReceivedAt":"2006-01-02T15:04:06.5Z","Payload":"two"}]}`))
+		default:
+			cancel()
+			w.Write([]byte(`{"Result":[],"Pages":{}}`))
+		}
+	})
+	code, stdout, stderr := runTestCommand(ctx, t, broker, "", "-output", "json", "tail", "-interval", "10ms", "-since", "1h", "ch")
+	assert.Equal(t, ExitOK, code, stderr)
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	assert.Equal(t, 3, len(lines))
+	for index, expectedID := range []string{"m1", "m2", "m3"} {
+		message := make(map[string]interface{})
+		assert.Nil(t, json.Unmarshal([]byte(lines[index]), &message))
+		assert.Equal(t, expectedID, message["MessageID"])
+	}
+	assert.Equal(t, "2006-01-02T15:04:06Z", froms[1])
+	assert.Equal(t, "2006-01-02T15:04:07Z", froms[2])
+	since, err := time.Parse(time.RFC3339, froms[0])
+	assert.Nil(t, err)
+	assert.True(t, time.Since(since) > 59*time.Minute)
+}
+
+func TestTailTableAndError(t *testing.T) {
+	broker := newFakeBroker(t)
+	broker.respond("GET /channel/ch/messages", http.StatusOK, "{")
+	code, _, _ := runTestCommand(context.Background(), t, broker, "", "tail", "ch")
+	assert.Equal(t, ExitError, code)
+	var stdout bytes.Buffer
+	cli := &cli{printer: &printer{format: tableOutput, out: &stdout}}
+	messages := []map[string]interface{}{{"MessageID": "m1", "Payload": "one\ntwo", "Priority": json.Number("1")}}
+	assert.Nil(t, cli.printMessages(messages, true))
+	assert.Nil(t, cli.printMessages(messages, false))
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	assert.Equal(t, 3, len(lines))
+	assert.True(t, strings.HasPrefix(lines[0], "RECEIVEDAT"))
+	assert.Contains(t, lines[2], `one\ntwo`)
+}
+
+func TestDLQCommands(t *testing.T) {
+	ctx := context.Background()
+	broker := newFakeBroker(t)
+	broker.respond("GET /channel/ch/consumer/c", http.StatusOK, `{"ID":"c","Token":"c-token"}`)
+	broker.respond("GET /channel/ch/consumer/c/dlq", http.StatusOK, `{"DeadJobs":[{"JobURL":"/channel/ch/consumer/c/dlq/j1",`+
+		`"MessageURL":"/channel/ch/message/m1","ConsumerID":"c","FailureReason":"HTTP 500","RetryAttemptCount":3}],`+
+		`"Pages":{"next":"/channel/ch/consumer/c/dlq?next=cursor"}}`)
+	broker.respond("GET /channel/ch/consumer/c/dlq?next=cursor", http.StatusOK, `{"DeadJobs":[{"JobURL":"/channel/ch/consumer/c/dlq/j2",`+
+		`"MessageURL":"/channel/ch/message/m2"}],"Pages":{}}`)
+	broker.respond("POST /channel/ch/consumer/c/dlq", http.StatusAccepted, "")
+	broker.respond("POST /channel/ch/consumer/c/dlq/j1", http.StatusAccepted, "")
+	broker.respond("POST /channel/ch/consumer/c/dlq/j2", http.StatusAccepted, "")
+	code, stdout, stderr := runTestCommand(ctx, t, broker, "", "dlq", "list", "ch", "c")
+	assert.Equal(t, ExitOK, code, stderr)
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	assert.Equal(t, 3, len(lines))
+	assert.Equal(t, []string{"j1", "m1", "c", "HTTP", "500", "3"}, strings.Fields(lines[1]))
+	code, stdout, _ = runTestCommand(ctx, t, broker, "", "-output", "json", "dlq", "list", "-limit", "1", "ch", "c")
+	assert.Equal(t, ExitOK, code)
+	deadJobs := make([]map[string]interface{}, 0)
+	assert.Nil(t, json.Unmarshal([]byte(stdout), &deadJobs))
+	assert.Equal(t, 1, len(deadJobs))
+	assert.Equal(t, "j1", deadJobs[0]["JobID"])
+	assert.Equal(t, 1, len(broker.getRequests("GET /channel/ch/consumer/c/dlq?next=cursor")))
+	code, stdout, stderr = runTestCommand(ctx, t, broker, "", "dlq", "requeue", "ch", "c")
+	assert.Equal(t, ExitOK, code, stderr)
+	assert.Contains(t, stdout, "requeued")
+	requests := broker.getRequests("POST /channel/ch/consumer/c/dlq")
+	assert.Equal(t, 1, len(requests))
+	assert.Equal(t, "requeue=c-token", requests[0].Body)
+	code, stdout, stderr = runTestCommand(ctx, t, broker, "", "dlq", "requeue", "-discard", "ch", "c", "j1", "j2")
+	assert.Equal(t, ExitOK, code, stderr)
+	assert.Equal(t, 2, strings.Count(stdout, "discarded"))
+	for _, jobID := range []string{"j1", "j2"} {
+		requests = broker.getRequests("POST /channel/ch/consumer/c/dlq/" + jobID)
+		assert.Equal(t, 1, len(requests))
+		assert.Equal(t, "action=discard&requeue=c-token", requests[0].Body)
+	}
+	code, _, _ = runTestCommand(ctx, t, broker, "", "dlq", "requeue", "ch", "c", "j3")
+	assert.Equal(t, ExitError, code)
+	code, _, _ = runTestCommand(ctx, t, broker, "", "dlq", "requeue", "ch", "d")
+	assert.Equal(t, ExitError, code)
+	code, _, _ = runTestCommand(ctx, t, broker, "", "dlq", "list", "ch", "d")
+	assert.Equal(t, ExitError, code)
+}
+
+func TestMessageAndStatsCommands(t *testing.T) {
+	ctx := context.Background()
+	broker := newFakeBroker(t)
+	message := `{"Payload":"hello","ContentType":"text/plain","Status":"DISPATCHED","Jobs":[{"ListenerName":"c","ListenerEndpoint":"https://example.com",` +
+		`"Status":"DELIVERED","StatusChangedAt":"2006-01-02T15:04:05Z"}]}`
+	broker.respond("GET /channel/ch/message/m1", http.StatusOK, message)
+	broker.respond("GET /channel/ch/stats", http.StatusOK, `{"JobCounts":{"QUEUED":2},"SuccessRate":1}`)
+	broker.respond("GET /channel/ch/consumer/c/stats", http.StatusOK, `{"JobCounts":{"DEAD":1},"SuccessRate":0.5}`)
+	code, stdout, stderr := runTestCommand(ctx, t, broker, "", "message", "get", "ch", "m1")
+	assert.Equal(t, ExitOK, code, stderr)
+	sections := strings.Split(stdout, "\n\n")
+	assert.Equal(t, 2, len(sections))
+	assert.Contains(t, sections[0], "Payload      hello")
+	assert.NotContains(t, sections[0], "Jobs")
+	assert.Equal(t, []string{"c", "https://example.com", "DELIVERED", "2006-01-02T15:04:05Z"}, strings.Fields(strings.Split(sections[1], "\n")[1]))
+	code, stdout, _ = runTestCommand(ctx, t, broker, "", "-output", "json", "message", "get", "ch", "m1")
+	assert.Equal(t, ExitOK, code)
+	assert.JSONEq(t, message, stdout)
+	code, _, _ = runTestCommand(ctx, t, broker, "", "message", "get", "ch", "m2")
+	assert.Equal(t, ExitError, code)
+	code, stdout, _ = runTestCommand(ctx, t, broker, "", "stats", "ch")
+	assert.Equal(t, ExitOK, code)
+	assert.Contains(t, stdout, `{"QUEUED":2}`)
+	code, stdout, _ = runTestCommand(ctx, t, broker, "", "stats", "ch", "c")
+	assert.Equal(t, ExitOK, code)
+	assert.Contains(t, stdout, "0.5")
+	code, _, _ = runTestCommand(ctx, t, broker, "", "stats", "ch", "d")
+	assert.Equal(t, ExitError, code)
+}
+
+func TestBrokerUnreachable(t *testing.T) {
+	broker := newFakeBroker(t)
+	broker.Close()
+	code, _, stderr := runTestCommand(context.Background(), t, broker, "", "channel", "list")
+	assert.Equal(t, ExitError, code)
+	assert.Contains(t, stderr, "webhook-broker admin channel list: ")
+}
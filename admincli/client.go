@@ -0,0 +1,196 @@
+package admincli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	headerContentType     = "Content-Type"
+	headerLastModified    = "Last-Modified"
+	headerUnmodifiedSince = "If-Unmodified-Since"
+	formContentTypeValue  = "application/x-www-form-urlencoded"
+	nextPageKey           = "next"
+	maxErrorBodyLength    = 512
+)
+
+// apiError is a non 2xx response from the broker
+type apiError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Body       string
+	Header     http.Header
+}
+
+func (err *apiError) Error() string {
+	body := strings.TrimSpace(err.Body)
+	if len(body) > maxErrorBodyLength {
+		body = body[:maxErrorBodyLength] + "..."
+	}
+	return fmt.Sprintf("%s %s: %d %s: %s", err.Method, err.Path, err.StatusCode, http.StatusText(err.StatusCode), body)
+}
+
+// listResult is a page of the list of channels, producers, consumers or messages
+type listResult struct {
+	Result   []string
+	Pages    map[string]string
+	Messages []map[string]interface{}
+}
+
+// brokerClient calls the broker API of a profile
+type brokerClient struct {
+	baseURL    *url.URL
+	username   string
+	password   string
+	httpClient *http.Client
+}
+
+func newBrokerClient(profile *Profile) *brokerClient {
+	return &brokerClient{baseURL: profile.URL, username: profile.Username, password: profile.Password, httpClient: http.DefaultClient}
+}
+
+// resolve returns the URL of an API path or link relative to the broker URL, which could have a path prefix, with the query added
+func (client *brokerClient) resolve(ref string, query url.Values) (*url.URL, error) {
+	target, err := url.Parse(ref)
+	if err != nil {
+		return nil, err
+	}
+	if !target.IsAbs() {
+		resolved := *client.baseURL
+		resolved.Path = strings.TrimSuffix(client.baseURL.Path, "/") + "/" + strings.TrimPrefix(target.Path, "/")
+		resolved.RawPath = ""
+		resolved.RawQuery = target.RawQuery
+		target = &resolved
+	}
+	if len(query) > 0 {
+		targetQuery := target.Query()
+		for key, values := range query {
+			targetQuery[key] = values
+		}
+		target.RawQuery = targetQuery.Encode()
+	}
+	return target, nil
+}
+
+// do sends the request and returns the response with its body read; a non 2xx response is returned along with an *apiError
+func (client *brokerClient) do(ctx context.Context, method, path string, query url.Values, header http.Header, body []byte) (*http.Response, []byte, error) {
+	target, err := client.resolve(path, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), bodyReader)
+	if err != nil {
+		return nil, nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if len(client.username) > 0 || len(client.password) > 0 {
+		req.SetBasicAuth(client.username, client.password)
+	}
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return resp, nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp, respBody, &apiError{Method: method, Path: target.Path, StatusCode: resp.StatusCode, Body: string(respBody), Header: resp.Header}
+	}
+	return resp, respBody, nil
+}
+
+// getJSON GETs the path and decodes the response into v
+func (client *brokerClient) getJSON(ctx context.Context, path string, query url.Values, v interface{}) (http.Header, error) {
+	resp, body, err := client.do(ctx, http.MethodGet, path, query, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Header, decodeJSON(body, v)
+}
+
+// getStakeholder GETs a channel, producer or consumer into v, which could be nil, and returns when it was last modified; found is false
+// if it does not exist
+func (client *brokerClient) getStakeholder(ctx context.Context, path string, v interface{}) (lastModified string, found bool, err error) {
+	resp, body, err := client.do(ctx, http.MethodGet, path, nil, nil, nil)
+	if apiErr, ok := err.(*apiError); ok && apiErr.StatusCode == http.StatusNotFound {
+		return "", false, nil
+	}
+	if err == nil && v != nil {
+		err = decodeJSON(body, v)
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return resp.Header.Get(headerLastModified), true, nil
+}
+
+// sendConditionally sends the form, which could be nil, to the path of a channel, producer or consumer with the `If-Unmodified-Since`
+// header set to when it was last modified, as the broker requires to change an existing one; lastModified is empty for one that does
+// not exist yet. When purging a deleted one, which can not be read, the `Last-Modified` of the rejected request is used to send it
+// again.
+func (client *brokerClient) sendConditionally(ctx context.Context, method, path string, query url.Values, form url.Values, lastModified string,
+	v interface{}) error {
+	header := make(http.Header)
+	if len(lastModified) > 0 {
+		header.Set(headerUnmodifiedSince, lastModified)
+	}
+	var body []byte
+	if form != nil {
+		header.Set(headerContentType, formContentTypeValue)
+		body = []byte(form.Encode())
+	}
+	_, respBody, err := client.do(ctx, method, path, query, header, body)
+	if apiErr, ok := err.(*apiError); ok && apiErr.StatusCode == http.StatusBadRequest && len(lastModified) == 0 &&
+		len(apiErr.Header.Get(headerLastModified)) > 0 {
+		header.Set(headerUnmodifiedSince, apiErr.Header.Get(headerLastModified))
+		_, respBody, err = client.do(ctx, method, path, query, header, body)
+	}
+	if err != nil || v == nil || len(bytes.TrimSpace(respBody)) == 0 {
+		return err
+	}
+	return decodeJSON(respBody, v)
+}
+
+// postForm POSTs the form to the path
+func (client *brokerClient) postForm(ctx context.Context, path string, form url.Values) error {
+	header := make(http.Header)
+	header.Set(headerContentType, formContentTypeValue)
+	_, _, err := client.do(ctx, http.MethodPost, path, nil, header, []byte(form.Encode()))
+	return err
+}
+
+// listAll follows the pages of a list and returns the URLs of all the entities in it
+func (client *brokerClient) listAll(ctx context.Context, path string, query url.Values) ([]string, error) {
+	urls := make([]string, 0)
+	for len(path) > 0 {
+		page := &listResult{}
+		if _, err := client.getJSON(ctx, path, query, page); err != nil {
+			return nil, err
+		}
+		urls = append(urls, page.Result...)
+		path, query = page.Pages[nextPageKey], nil
+	}
+	return urls, nil
+}
+
+func decodeJSON(body []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
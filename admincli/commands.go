@@ -0,0 +1,520 @@
+package admincli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/xid"
+)
+
+const (
+	channelsPath            = "/channels"
+	producersPath           = "/producers"
+	headerChannelToken      = "X-Broker-Channel-Token"
+	headerProducerID        = "X-Broker-Producer-ID"
+	headerProducerToken     = "X-Broker-Producer-Token"
+	headerMessageID         = "X-Broker-Message-ID"
+	headerPriority          = "X-Broker-Message-Priority"
+	headerRoutingKey        = "X-Broker-Routing-Key"
+	headerAttributePrefix   = "X-Broker-Attr-"
+	defaultContentType      = "application/octet-stream"
+	stdinFileName           = "-"
+	requeueFormParamName    = "requeue"
+	dlqActionFormParamName  = "action"
+	dlqActionDiscard        = "discard"
+	messageFromParamName    = "from"
+	messageExpandParamName  = "expand"
+	messageExpandPayload    = "payload"
+	drainQueryParamName     = "drain"
+	purgeQueryParamName     = "purge"
+	defaultTailPollInterval = 2 * time.Second
+)
+
+var (
+	// ErrMessageIDForMultipleMessages is returned when a message ID is passed to publish more than one message
+	ErrMessageIDForMultipleMessages = errors.New("message ID can only be set when publishing a single message")
+	// ErrProducerRequired is returned when publishing without the producer ID and token either as flags or in the profile
+	ErrProducerRequired = errors.New("producer ID and token are required to publish, as flags or in the profile")
+	// ErrInvalidAttribute is returned when a message attribute is not in the `name=value` format
+	ErrInvalidAttribute = errors.New("attribute must be in the name=value format")
+
+	rateLimitFormFlags = []formFlag{
+		{"messages-per-second", "messagesPerSecond", "", "Messages per second allowed; the broker's default when not set"},
+		{"burst", "burst", "", "Messages allowed in a burst over messages per second; the broker's default when not set"},
+		{"bytes-per-minute", "bytesPerMinute", "", "Payload bytes per minute allowed; the broker's default when not set"},
+	}
+	channelFormFlags = append([]formFlag{
+		{"name", "name", "Name", "Name of the channel"},
+		{"token", "token", "Token", "Token of the channel; generated for a new channel when not set"},
+		{"max-payload-size", "maxPayloadSize", "MaxPayloadSize", "Max payload size of a message in bytes; 0 for the broker's default"},
+	}, rateLimitFormFlags...)
+	producerFormFlags = append([]formFlag{
+		{"name", "name", "Name", "Name of the producer"},
+		{"token", "token", "Token", "Token of the producer; generated for a new producer when not set"},
+		{"receipt-url", "receiptUrl", "ReceiptURL", "URL called with the outcome of the producer's messages"},
+	}, rateLimitFormFlags...)
+	consumerFormFlags = []formFlag{
+		{"name", "name", "Name", "Name of the consumer"},
+		{"token", "token", "Token", "Token of the consumer; generated for a new consumer when not set"},
+		{"callback-url", "callbackUrl", "CallbackURL", "URL messages are delivered to; required for a PUSH consumer"},
+		{"type", "type", "Type", "PUSH or STREAM"},
+		{"delivery-format", "deliveryFormat", "DeliveryFormat", "RAW, CLOUDEVENT-BINARY or CLOUDEVENT-STRUCTURED"},
+		{"routing-key-pattern", "routingKeyPattern", "RoutingKeyPattern", "Pattern of the routing keys of messages to consume"},
+		{"backfill-since", "backfillSince", "", "Deliver the channel's messages since this RFC3339 time or duration ago; only when created"},
+	}
+	messageColumns   = []string{"ReceivedAt", "MessageID", "Status", "Priority", "ContentType", "RoutingKey", "Payload"}
+	jobColumns       = []string{"ListenerName", "ListenerEndpoint", "Status", "StatusChangedAt"}
+	deadJobColumns   = []string{"JobID", "MessageID", "ConsumerID", "FailureReason", "RetryAttemptCount", "StatusChangedAt"}
+	publishedColumns = []string{"File", "MessageID", "Status"}
+
+	commands = map[string]*command{
+		"channel list":    {"", "List channels", listCommand(0, func(args []string) string { return channelsPath })},
+		"channel get":     {"<channel>", "Show a channel", getCommand(1, getChannelPath)},
+		"channel put":     {"[flags] <channel>", "Create or update a channel", putCommand(1, getChannelPath, channelFormFlags)},
+		"channel delete":  {"[-drain] [-purge] <channel>", "Delete a channel along with its consumers", deleteCommand(1, getChannelPath, true)},
+		"producer list":   {"", "List producers", listCommand(0, func(args []string) string { return producersPath })},
+		"producer get":    {"<producer>", "Show a producer", getCommand(1, getProducerPath)},
+		"producer put":    {"[flags] <producer>", "Create or update a producer", putCommand(1, getProducerPath, producerFormFlags)},
+		"producer delete": {"[-purge] <producer>", "Delete a producer", deleteCommand(1, getProducerPath, false)},
+		"consumer list":   {"<channel>", "List consumers of a channel", listCommand(1, getConsumersPath)},
+		"consumer get":    {"<channel> <consumer>", "Show a consumer", getCommand(2, getConsumerPath)},
+		"consumer put":    {"[flags] <channel> <consumer>", "Create or update a consumer", putCommand(2, getConsumerPath, consumerFormFlags)},
+		"consumer delete": {"[-drain] [-purge] <channel> <consumer>", "Delete a consumer", deleteCommand(2, getConsumerPath, true)},
+		"publish":         {"[flags] <channel>", "Publish a message per file, or stdin, to a channel", publish},
+		"tail":            {"[-since duration] [-interval duration] <channel>", "Print messages of a channel as they are received", tail},
+		"dlq list":        {"[-limit n] <channel> <consumer>", "List dead jobs of a consumer", listDeadJobs},
+		"dlq requeue":     {"[-discard] <channel> <consumer> [job...]", "Requeue, or discard, all or the given dead jobs of a consumer", requeueDeadJobs},
+		"message get":     {"<channel> <message>", "Show a message along with its delivery jobs", getMessage},
+		"stats":           {"<channel> [consumer]", "Show delivery stats of a channel or a consumer", getStats},
+	}
+)
+
+// formFlag is a flag of a `put` command sent as a form param. When not passed, the current value of the field is sent as the broker
+// replaces the whole entity on update; fields without current values, e.g. rate limits, fall back to the broker's default
+type formFlag struct {
+	name  string
+	param string
+	field string
+	usage string
+}
+
+func getChannelPath(args []string) string {
+	return "/channel/" + url.PathEscape(args[0])
+}
+
+func getProducerPath(args []string) string {
+	return "/producer/" + url.PathEscape(args[0])
+}
+
+func getConsumersPath(args []string) string {
+	return getChannelPath(args) + "/consumers"
+}
+
+func getConsumerPath(args []string) string {
+	return getChannelPath(args) + "/consumer/" + url.PathEscape(args[1])
+}
+
+func getDLQPath(args []string) string {
+	return getConsumerPath(args) + "/dlq"
+}
+
+// getIDFromURL returns the ID of the entity in its URL, i.e. the last path segment
+func getIDFromURL(entityURL string) string {
+	parsedURL, err := url.Parse(entityURL)
+	if err != nil {
+		return ""
+	}
+	id, err := url.PathUnescape(path.Base(parsedURL.Path))
+	if err != nil {
+		return path.Base(parsedURL.Path)
+	}
+	return id
+}
+
+func listCommand(argCount int, getPath func(args []string) string) func(ctx context.Context, cli *cli, args []string) error {
+	return func(ctx context.Context, cli *cli, args []string) error {
+		args, err := cli.parseCommandFlags(cli.newFlagSet(), args, argCount, argCount)
+		if err != nil {
+			return err
+		}
+		urls, err := cli.client.listAll(ctx, getPath(args), nil)
+		if err != nil {
+			return err
+		}
+		rows := make([]map[string]interface{}, 0, len(urls))
+		for _, entityURL := range urls {
+			rows = append(rows, map[string]interface{}{"ID": getIDFromURL(entityURL), "URL": entityURL})
+		}
+		return cli.printer.printList([]string{"ID", "URL"}, rows)
+	}
+}
+
+func getCommand(argCount int, getPath func(args []string) string) func(ctx context.Context, cli *cli, args []string) error {
+	return func(ctx context.Context, cli *cli, args []string) error {
+		args, err := cli.parseCommandFlags(cli.newFlagSet(), args, argCount, argCount)
+		if err != nil {
+			return err
+		}
+		object := make(map[string]interface{})
+		if _, err = cli.client.getJSON(ctx, getPath(args), nil, &object); err != nil {
+			return err
+		}
+		return cli.printer.printObject(object)
+	}
+}
+
+func putCommand(argCount int, getPath func(args []string) string, formFlags []formFlag) func(ctx context.Context, cli *cli, args []string) error {
+	return func(ctx context.Context, cli *cli, args []string) error {
+		flags := cli.newFlagSet()
+		values := make(map[string]*string, len(formFlags))
+		for _, formFlag := range formFlags {
+			values[formFlag.name] = flags.String(formFlag.name, "", formFlag.usage)
+		}
+		args, err := cli.parseCommandFlags(flags, args, argCount, argCount)
+		if err != nil {
+			return err
+		}
+		passed := make(map[string]bool)
+		flags.Visit(func(f *flag.Flag) { passed[f.Name] = true })
+		entityPath := getPath(args)
+		existing := make(map[string]interface{})
+		lastModified, found, err := cli.client.getStakeholder(ctx, entityPath, &existing)
+		if err != nil {
+			return err
+		}
+		form := url.Values{}
+		for _, formFlag := range formFlags {
+			value := *values[formFlag.name]
+			if found && !passed[formFlag.name] && len(formFlag.field) > 0 {
+				value = formatValue(existing[formFlag.field])
+			}
+			if len(value) > 0 {
+				form.Set(formFlag.param, value)
+			}
+		}
+		updated := make(map[string]interface{})
+		if err = cli.client.sendConditionally(ctx, http.MethodPut, entityPath, nil, form, lastModified, &updated); err != nil {
+			return err
+		}
+		return cli.printer.printObject(updated)
+	}
+}
+
+// deleteCommand soft deletes, or purges, an entity; only channels and consumers, which have queued messages, can be drained
+func deleteCommand(argCount int, getPath func(args []string) string, drainable bool) func(ctx context.Context, cli *cli, args []string) error {
+	return func(ctx context.Context, cli *cli, args []string) error {
+		flags := cli.newFlagSet()
+		drain := new(bool)
+		if drainable {
+			drain = flags.Bool(drainQueryParamName, false, "Deliver the queued messages before deleting")
+		}
+		purge := flags.Bool(purgeQueryParamName, false, "Permanently remove the deleted entity along with its data")
+		args, err := cli.parseCommandFlags(flags, args, argCount, argCount)
+		if err != nil {
+			return err
+		}
+		entityPath := getPath(args)
+		query, status := url.Values{}, "deleted"
+		if *drain {
+			query.Set(drainQueryParamName, "true")
+		}
+		if *purge {
+			query.Set(purgeQueryParamName, "true")
+			status = "purged"
+		}
+		lastModified, _, err := cli.client.getStakeholder(ctx, entityPath, nil)
+		if err != nil {
+			return err
+		}
+		if err = cli.client.sendConditionally(ctx, http.MethodDelete, entityPath, query, nil, lastModified, nil); err != nil {
+			return err
+		}
+		return cli.printer.printObject(map[string]interface{}{"URL": entityPath, "Status": status})
+	}
+}
+
+// publish broadcasts a message per file to the channel; with the producer from the profile unless passed as flags
+func publish(ctx context.Context, cli *cli, args []string) error {
+	flags := cli.newFlagSet()
+	var files, attributes stringsFlag
+	flags.Var(&files, "file", "File with the payload of a message, repeat for more messages; stdin when - or not set")
+	messageID := flags.String("id", "", "ID of the message; generated when not set")
+	priority := flags.Uint("priority", 0, "Priority of the messages")
+	contentType := flags.String("content-type", defaultContentType, "Content type of the payloads")
+	routingKey := flags.String("routing-key", "", "Routing key of the messages")
+	flags.Var(&attributes, "attr", "Attribute of the messages as name=value, repeat for more")
+	channelToken := flags.String("channel-token", "", "Token of the channel; read from the broker when not set")
+	producerID := flags.String("producer-id", cli.profile.ProducerID, "ID of the producer publishing the messages")
+	producerToken := flags.String("producer-token", cli.profile.ProducerToken, "Token of the producer publishing the messages")
+	args, err := cli.parseCommandFlags(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		files = append(files, stdinFileName)
+	}
+	switch {
+	case len(*messageID) > 0 && len(files) > 1:
+		return &usageError{err: ErrMessageIDForMultipleMessages}
+	case len(*producerID) == 0 || len(*producerToken) == 0:
+		return &usageError{err: ErrProducerRequired}
+	}
+	header := make(http.Header)
+	for _, attribute := range attributes {
+		name, value, ok := strings.Cut(attribute, "=")
+		if !ok || len(name) == 0 {
+			return &usageError{err: ErrInvalidAttribute}
+		}
+		header.Set(headerAttributePrefix+name, value)
+	}
+	channelPath := getChannelPath(args)
+	if len(*channelToken) == 0 {
+		channel := make(map[string]interface{})
+		if _, err = cli.client.getJSON(ctx, channelPath, nil, &channel); err != nil {
+			return err
+		}
+		*channelToken = formatValue(channel["Token"])
+	}
+	header.Set(headerChannelToken, *channelToken)
+	header.Set(headerProducerID, *producerID)
+	header.Set(headerProducerToken, *producerToken)
+	header.Set(headerContentType, *contentType)
+	if *priority > 0 {
+		header.Set(headerPriority, strconv.FormatUint(uint64(*priority), 10))
+	}
+	if len(*routingKey) > 0 {
+		header.Set(headerRoutingKey, *routingKey)
+	}
+	rows := make([]map[string]interface{}, 0, len(files))
+	for _, file := range files {
+		var payload []byte
+		if file == stdinFileName {
+			payload, err = ioutil.ReadAll(cli.stdin)
+		} else {
+			payload, err = ioutil.ReadFile(file)
+		}
+		if err != nil {
+			break
+		}
+		id := *messageID
+		if len(id) == 0 {
+			id = xid.New().String()
+		}
+		header.Set(headerMessageID, id)
+		var resp *http.Response
+		if resp, _, err = cli.client.do(ctx, http.MethodPost, channelPath+"/broadcast", nil, header, payload); err != nil {
+			break
+		}
+		rows = append(rows, map[string]interface{}{"File": file, "MessageID": id, "Status": resp.Status})
+	}
+	if len(rows) > 0 {
+		cli.printer.printList(publishedColumns, rows)
+	}
+	return err
+}
+
+// tail polls the messages of the channel and prints the ones not printed yet, oldest first, until interrupted
+func tail(ctx context.Context, cli *cli, args []string) error {
+	flags := cli.newFlagSet()
+	sinceAgo := flags.Duration("since", 0, "Also print the messages received this long ago")
+	interval := flags.Duration("interval", defaultTailPollInterval, "How often to poll for messages")
+	args, err := cli.parseCommandFlags(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	messagesPath := getChannelPath(args) + "/messages"
+	since := time.Now().Add(-*sinceAgo).Truncate(time.Second)
+	printed := make(map[string]time.Time)
+	withHeader := true
+	for {
+		messages, err := cli.getMessagesSince(ctx, messagesPath, since)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		rows := make([]map[string]interface{}, 0, len(messages))
+		for _, message := range messages {
+			messageID := formatValue(message["MessageID"])
+			if _, ok := printed[messageID]; ok {
+				continue
+			}
+			receivedAt, _ := time.Parse(time.RFC3339Nano, formatValue(message["ReceivedAt"]))
+			printed[messageID] = receivedAt
+			rows = append(rows, message)
+		}
+		sort.SliceStable(rows, func(i, j int) bool {
+			return printed[formatValue(rows[i]["MessageID"])].Before(printed[formatValue(rows[j]["MessageID"])])
+		})
+		if len(rows) > 0 {
+			if err = cli.printMessages(rows, withHeader); err != nil {
+				return err
+			}
+			withHeader = false
+			// `from` is inclusive and only to the second, so messages of the last second are polled again and deduplicated
+			since = printed[formatValue(rows[len(rows)-1]["MessageID"])].Truncate(time.Second)
+			for messageID, receivedAt := range printed {
+				if receivedAt.Before(since) {
+					delete(printed, messageID)
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(*interval):
+		}
+	}
+}
+
+// getMessagesSince returns the messages, with payloads, of all the pages of the channel's messages received since the time
+func (cli *cli) getMessagesSince(ctx context.Context, messagesPath string, since time.Time) ([]map[string]interface{}, error) {
+	query := url.Values{messageFromParamName: {since.UTC().Format(time.RFC3339)}, messageExpandParamName: {messageExpandPayload}}
+	messages := make([]map[string]interface{}, 0)
+	for len(messagesPath) > 0 {
+		page := &listResult{}
+		if _, err := cli.client.getJSON(ctx, messagesPath, query, page); err != nil {
+			return nil, err
+		}
+		messages = append(messages, page.Messages...)
+		messagesPath, query = page.Pages[nextPageKey], nil
+	}
+	return messages, nil
+}
+
+func (cli *cli) printMessages(messages []map[string]interface{}, withHeader bool) error {
+	if cli.printer.format == jsonOutput {
+		for _, message := range messages {
+			if err := cli.printer.printCompactJSON(message); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return cli.printer.printTable(messageColumns, messages, withHeader)
+}
+
+// listDeadJobs prints the dead jobs of the consumer, following the pages of the DLQ up to the limit
+func listDeadJobs(ctx context.Context, cli *cli, args []string) error {
+	flags := cli.newFlagSet()
+	limit := flags.Int("limit", 0, "Max dead jobs to list; 0 for all")
+	args, err := cli.parseCommandFlags(flags, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	deadJobs := make([]map[string]interface{}, 0)
+	for dlqPath := getDLQPath(args); len(dlqPath) > 0 && (*limit <= 0 || len(deadJobs) < *limit); {
+		page := &struct {
+			DeadJobs []map[string]interface{}
+			Pages    map[string]string
+		}{}
+		if _, err = cli.client.getJSON(ctx, dlqPath, nil, page); err != nil {
+			return err
+		}
+		deadJobs = append(deadJobs, page.DeadJobs...)
+		dlqPath = page.Pages[nextPageKey]
+	}
+	if *limit > 0 && len(deadJobs) > *limit {
+		deadJobs = deadJobs[:*limit]
+	}
+	for _, deadJob := range deadJobs {
+		deadJob["JobID"] = getIDFromURL(formatValue(deadJob["JobURL"]))
+		deadJob["MessageID"] = getIDFromURL(formatValue(deadJob["MessageURL"]))
+	}
+	return cli.printer.printList(deadJobColumns, deadJobs)
+}
+
+// requeueDeadJobs requeues, or discards, either all the dead jobs of the consumer or the ones passed; with the consumer's token read
+// from the broker
+func requeueDeadJobs(ctx context.Context, cli *cli, args []string) error {
+	flags := cli.newFlagSet()
+	discard := flags.Bool(dlqActionDiscard, false, "Discard the dead jobs instead of requeuing them")
+	args, err := cli.parseCommandFlags(flags, args, 2, -1)
+	if err != nil {
+		return err
+	}
+	consumer := make(map[string]interface{})
+	if _, err = cli.client.getJSON(ctx, getConsumerPath(args), nil, &consumer); err != nil {
+		return err
+	}
+	form, action := url.Values{requeueFormParamName: {formatValue(consumer["Token"])}}, "requeued"
+	if *discard {
+		form.Set(dlqActionFormParamName, dlqActionDiscard)
+		action = "discarded"
+	}
+	targets := []string{getDLQPath(args)}
+	if len(args) > 2 {
+		targets = targets[:0]
+		for _, jobID := range args[2:] {
+			targets = append(targets, getDLQPath(args)+"/"+url.PathEscape(jobID))
+		}
+	}
+	rows := make([]map[string]interface{}, 0, len(targets))
+	for _, target := range targets {
+		if err = cli.client.postForm(ctx, target, form); err != nil {
+			break
+		}
+		rows = append(rows, map[string]interface{}{"URL": target, "Status": action})
+	}
+	if len(rows) > 0 {
+		cli.printer.printList([]string{"URL", "Status"}, rows)
+	}
+	return err
+}
+
+// getMessage prints the message and, as a table, its delivery jobs below it
+func getMessage(ctx context.Context, cli *cli, args []string) error {
+	args, err := cli.parseCommandFlags(cli.newFlagSet(), args, 2, 2)
+	if err != nil {
+		return err
+	}
+	message := make(map[string]interface{})
+	if _, err = cli.client.getJSON(ctx, getChannelPath(args)+"/message/"+url.PathEscape(args[1]), nil, &message); err != nil {
+		return err
+	}
+	if cli.printer.format == jsonOutput {
+		return cli.printer.printObject(message)
+	}
+	jobs := make([]map[string]interface{}, 0)
+	if jobValues, ok := message["Jobs"].([]interface{}); ok {
+		for _, jobValue := range jobValues {
+			if job, ok := jobValue.(map[string]interface{}); ok {
+				jobs = append(jobs, job)
+			}
+		}
+	}
+	delete(message, "Jobs")
+	if err = cli.printer.printObject(message); err != nil {
+		return err
+	}
+	fmt.Fprintln(cli.printer.out)
+	return cli.printer.printList(jobColumns, jobs)
+}
+
+// getStats prints the delivery stats of the channel, or of the consumer when passed
+func getStats(ctx context.Context, cli *cli, args []string) error {
+	args, err := cli.parseCommandFlags(cli.newFlagSet(), args, 1, 2)
+	if err != nil {
+		return err
+	}
+	statsPath := getChannelPath(args) + "/stats"
+	if len(args) > 1 {
+		statsPath = getConsumerPath(args) + "/stats"
+	}
+	stats := make(map[string]interface{})
+	if _, err = cli.client.getJSON(ctx, statsPath, nil, &stats); err != nil {
+		return err
+	}
+	return cli.printer.printObject(stats)
+}
@@ -0,0 +1,114 @@
+package admincli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	tableOutput = "table"
+	jsonOutput  = "json"
+)
+
+func isValidOutputFormat(format string) bool {
+	return format == tableOutput || format == jsonOutput
+}
+
+// printer writes the result of a command either as a table or as JSON
+type printer struct {
+	format string
+	out    io.Writer
+}
+
+// printObject prints a single resource; as a table it is a row per field sorted by name
+func (p *printer) printObject(object map[string]interface{}) error {
+	if p.format == jsonOutput {
+		return p.printJSON(object)
+	}
+	fields := make([]string, 0, len(object))
+	for field := range object {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	rows := make([]map[string]interface{}, 0, len(fields))
+	for _, field := range fields {
+		rows = append(rows, map[string]interface{}{"Field": field, "Value": object[field]})
+	}
+	return p.printTable([]string{"Field", "Value"}, rows, true)
+}
+
+// printList prints a list of resources; as a table, the columns are the fields to print, nested ones as dot separated paths
+func (p *printer) printList(columns []string, rows []map[string]interface{}) error {
+	if p.format == jsonOutput {
+		return p.printJSON(rows)
+	}
+	return p.printTable(columns, rows, true)
+}
+
+func (p *printer) printJSON(value interface{}) error {
+	encoder := json.NewEncoder(p.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// printCompactJSON prints the value as a single line, e.g. for the messages streamed by `tail`
+func (p *printer) printCompactJSON(value interface{}) error {
+	return json.NewEncoder(p.out).Encode(value)
+}
+
+// printTable prints the rows as a table; the header is skipped when rows are added to a table printed earlier, e.g. by `tail`
+func (p *printer) printTable(columns []string, rows []map[string]interface{}, withHeader bool) error {
+	writer := tabwriter.NewWriter(p.out, 0, 4, 2, ' ', 0)
+	if withHeader {
+		fmt.Fprintln(writer, strings.ToUpper(strings.Join(columns, "\t")))
+	}
+	for _, row := range rows {
+		cells := make([]string, 0, len(columns))
+		for _, column := range columns {
+			cells = append(cells, formatValue(lookupField(row, column)))
+		}
+		fmt.Fprintln(writer, strings.Join(cells, "\t"))
+	}
+	return writer.Flush()
+}
+
+// lookupField returns the value at the dot separated path, e.g. `RateLimit.MessagesPerSecond`
+func lookupField(object map[string]interface{}, path string) interface{} {
+	var value interface{} = object
+	for _, field := range strings.Split(path, ".") {
+		nested, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = nested[field]
+	}
+	return value
+}
+
+// formatValue formats a value for a table cell; nested values are printed as compact JSON and line breaks are escaped to keep a row
+// in a line
+func formatValue(value interface{}) string {
+	var formatted string
+	switch typedValue := value.(type) {
+	case nil:
+		formatted = ""
+	case string:
+		formatted = typedValue
+	case json.Number:
+		formatted = typedValue.String()
+	case bool:
+		formatted = fmt.Sprint(typedValue)
+	default:
+		encoded, err := json.Marshal(typedValue)
+		if err != nil {
+			formatted = fmt.Sprint(typedValue)
+		} else {
+			formatted = string(encoded)
+		}
+	}
+	return strings.NewReplacer("\r", `\r`, "\n", `\n`, "\t", `\t`).Replace(formatted)
+}
@@ -0,0 +1,101 @@
+package admincli
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/go-ini/ini"
+)
+
+const (
+	// ProfilesFilename is the name of the profiles file looked up in `~/.webhook-broker/`
+	ProfilesFilename = "cli-profiles.cfg"
+	// DefaultProfileName is the profile used unless another is chosen
+	DefaultProfileName = "default"
+	// ProfileEnvVar chooses the profile if `-profile` flag is not passed
+	ProfileEnvVar = "WEBHOOK_BROKER_PROFILE"
+	// ProfilesFileEnvVar is the location of the profiles file if `-profiles` flag is not passed
+	ProfilesFileEnvVar = "WEBHOOK_BROKER_PROFILES"
+	defaultBrokerURL   = "http://localhost:8080"
+)
+
+var (
+	// ErrProfileNotFound is returned when a profile other than the default one is not in the profiles file
+	ErrProfileNotFound = errors.New("profile not found")
+	// ErrInvalidBrokerURL is returned when the broker URL of the profile is not an absolute http(s) URL
+	ErrInvalidBrokerURL = errors.New("broker URL must be an absolute http or https URL")
+	// ErrInvalidOutputFormat is returned when the output format is neither `table` nor `json`
+	ErrInvalidOutputFormat = errors.New("output must be either table or json")
+	userHomeDir            = os.UserHomeDir
+)
+
+// Profile is a broker to manage along with the credentials to manage it with; profiles are sections of an INI file, e.g.
+//
+//	[prod]
+//	url=https://broker.example.com
+//	username=admin
+//	password=secret
+//	producer-id=ops
+//	producer-token=ops-token
+//	output=json
+type Profile struct {
+	Name string
+	URL  *url.URL
+	// Username and Password, when set, are sent as basic authentication, e.g. for a broker behind an authenticating proxy
+	Username string
+	Password string
+	// ProducerID and ProducerToken are of the producer messages are published as
+	ProducerID    string
+	ProducerToken string
+	// Output is the default output format, `table` or `json`
+	Output string
+}
+
+// GetDefaultProfilesPath returns the location of the profiles file unless chosen otherwise; `~/.webhook-broker/cli-profiles.cfg`
+func GetDefaultProfilesPath() string {
+	if path := os.Getenv(ProfilesFileEnvVar); len(path) > 0 {
+		return path
+	}
+	home, err := userHomeDir()
+	if err != nil {
+		return ProfilesFilename
+	}
+	return filepath.Join(home, ".webhook-broker", ProfilesFilename)
+}
+
+// LoadProfile loads the named profile from the profiles file at path; the default profile, pointing to a broker on localhost, need not
+// be in the file nor the file exist
+func LoadProfile(path, name string) (*Profile, error) {
+	cfg, err := ini.LooseLoad(path)
+	if err != nil {
+		return nil, err
+	}
+	section, err := cfg.GetSection(name)
+	if err != nil {
+		if name != DefaultProfileName {
+			return nil, fmt.Errorf("%w: %s in %s", ErrProfileNotFound, name, path)
+		}
+		section = cfg.Section(name)
+	}
+	profile := &Profile{Name: name, Username: section.Key("username").String(), Password: section.Key("password").String(),
+		ProducerID: section.Key("producer-id").String(), ProducerToken: section.Key("producer-token").String(),
+		Output: section.Key("output").MustString(tableOutput)}
+	if profile.URL, err = parseBrokerURL(section.Key("url").MustString(defaultBrokerURL)); err != nil {
+		return nil, err
+	}
+	if !isValidOutputFormat(profile.Output) {
+		return nil, ErrInvalidOutputFormat
+	}
+	return profile, nil
+}
+
+func parseBrokerURL(value string) (*url.URL, error) {
+	brokerURL, err := url.Parse(value)
+	if err != nil || !brokerURL.IsAbs() || (brokerURL.Scheme != "http" && brokerURL.Scheme != "https") {
+		return nil, ErrInvalidBrokerURL
+	}
+	return brokerURL, nil
+}
@@ -0,0 +1,81 @@
+package admincli
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testProfiles = `
+[default]
+url=http://broker.local:9090/prefix/
+username=admin
+password=secret
+
+[prod]
+url=https://broker.example.com
+producer-id=ops
+producer-token=ops-token
+output=json
+
+[bad-url]
+url=/relative
+
+[bad-output]
+output=yaml
+`
+
+func writeTestProfiles(t *testing.T, content string) string {
+	profilesPath := filepath.Join(t.TempDir(), ProfilesFilename)
+	assert.Nil(t, ioutil.WriteFile(profilesPath, []byte(content), 0600))
+	return profilesPath
+}
+
+func TestLoadProfile(t *testing.T) {
+	profilesPath := writeTestProfiles(t, testProfiles)
+	t.Run("Default", func(t *testing.T) {
+		profile, err := LoadProfile(profilesPath, DefaultProfileName)
+		assert.Nil(t, err)
+		assert.Equal(t, "http://broker.local:9090/prefix/", profile.URL.String())
+		assert.Equal(t, "admin", profile.Username)
+		assert.Equal(t, "secret", profile.Password)
+		assert.Equal(t, tableOutput, profile.Output)
+	})
+	t.Run("Named", func(t *testing.T) {
+		profile, err := LoadProfile(profilesPath, "prod")
+		assert.Nil(t, err)
+		assert.Equal(t, &Profile{Name: "prod", URL: profile.URL, ProducerID: "ops", ProducerToken: "ops-token", Output: jsonOutput}, profile)
+		assert.Equal(t, "https://broker.example.com", profile.URL.String())
+	})
+	t.Run("NoFile", func(t *testing.T) {
+		noFilePath := filepath.Join(t.TempDir(), "none.cfg")
+		profile, err := LoadProfile(noFilePath, DefaultProfileName)
+		assert.Nil(t, err)
+		assert.Equal(t, defaultBrokerURL, profile.URL.String())
+		_, err = LoadProfile(noFilePath, "prod")
+		assert.True(t, errors.Is(err, ErrProfileNotFound))
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := LoadProfile(profilesPath, "bad-url")
+		assert.Equal(t, ErrInvalidBrokerURL, err)
+		_, err = LoadProfile(profilesPath, "bad-output")
+		assert.Equal(t, ErrInvalidOutputFormat, err)
+		_, err = LoadProfile(writeTestProfiles(t, "[default"), DefaultProfileName)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestGetDefaultProfilesPath(t *testing.T) {
+	oldUserHomeDir := userHomeDir
+	defer func() { userHomeDir = oldUserHomeDir }()
+	userHomeDir = func() (string, error) { return "/home/operator", nil }
+	t.Setenv(ProfilesFileEnvVar, "")
+	assert.Equal(t, "/home/operator/.webhook-broker/cli-profiles.cfg", GetDefaultProfilesPath())
+	userHomeDir = func() (string, error) { return "", errors.New("no home") }
+	assert.Equal(t, ProfilesFilename, GetDefaultProfilesPath())
+	t.Setenv(ProfilesFileEnvVar, "/etc/broker-profiles.cfg")
+	assert.Equal(t, "/etc/broker-profiles.cfg", GetDefaultProfilesPath())
+}
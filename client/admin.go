@@ -0,0 +1,292 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	channelsPath                = "/channels"
+	drainQueryParamName         = "drain"
+	purgeQueryParamName         = "purge"
+	requeueFormParamName        = "requeue"
+	dlqActionFormParamName      = "action"
+	dlqActionDiscard            = "discard"
+	consumerTokenFormParamName  = "token"
+	messageStatusParamName      = "status"
+	messageProducerIDParamName  = "producerId"
+	messageFromParamName        = "from"
+	messageToParamName          = "to"
+	messageContentTypeParamName = "contentType"
+	messagePriorityParamName    = "priority"
+	messageIDPrefixParamName    = "messageIdPrefix"
+	messageHasDeadJobsParamName = "hasDeadJobs"
+	messageExpandParamName      = "expand"
+	messageExpandPayload        = "payload"
+	messageExpandJobs           = "jobs"
+)
+
+// MessageFilter narrows down the messages of a channel listed; the zero value lists all of them
+type MessageFilter struct {
+	Statuses        []string
+	ProducerIDs     []string
+	From            time.Time
+	To              time.Time
+	ContentType     string
+	Priorities      []uint
+	MessageIDPrefix string
+	HasDeadJobs     bool
+	// WithJobs has the messages listed along with their delivery jobs; they are always listed with their payloads
+	WithJobs bool
+}
+
+func (filter *MessageFilter) getQuery() url.Values {
+	query := url.Values{messageStatusParamName: filter.Statuses, messageProducerIDParamName: filter.ProducerIDs}
+	if !filter.From.IsZero() {
+		query.Set(messageFromParamName, filter.From.UTC().Format(time.RFC3339))
+	}
+	if !filter.To.IsZero() {
+		query.Set(messageToParamName, filter.To.UTC().Format(time.RFC3339))
+	}
+	if len(filter.ContentType) > 0 {
+		query.Set(messageContentTypeParamName, filter.ContentType)
+	}
+	for _, priority := range filter.Priorities {
+		query.Add(messagePriorityParamName, strconv.FormatUint(uint64(priority), 10))
+	}
+	if len(filter.MessageIDPrefix) > 0 {
+		query.Set(messageIDPrefixParamName, filter.MessageIDPrefix)
+	}
+	if filter.HasDeadJobs {
+		query.Set(messageHasDeadJobsParamName, strconv.FormatBool(filter.HasDeadJobs))
+	}
+	// The list only has the URLs of the messages unless expanded
+	expansions := []string{messageExpandPayload}
+	if filter.WithJobs {
+		expansions = append(expansions, messageExpandJobs)
+	}
+	query.Set(messageExpandParamName, strings.Join(expansions, ","))
+	for key, values := range query {
+		if len(values) == 0 {
+			delete(query, key)
+		}
+	}
+	return query
+}
+
+// ChannelAdmin manages channels of the broker along with their messages
+type ChannelAdmin struct {
+	client *Client
+}
+
+// NewChannelAdmin creates the admin client for channels of the broker
+func NewChannelAdmin(client *Client) *ChannelAdmin {
+	return &ChannelAdmin{client: client}
+}
+
+// List returns the iterator over the channels of the broker
+func (admin *ChannelAdmin) List(ctx context.Context) *StakeholderIterator {
+	return &StakeholderIterator{pager: newPager(ctx, admin.client, channelsPath, nil)}
+}
+
+// Get returns the channel; the error satisfies IsNotFound if it does not exist
+func (admin *ChannelAdmin) Get(ctx context.Context, channelID string) (*Channel, error) {
+	channel := &Channel{}
+	if _, err := admin.client.getJSON(ctx, getChannelPath(channelID), nil, channel); err != nil {
+		return nil, err
+	}
+	return channel, nil
+}
+
+// Put creates the channel or replaces the existing one with the spec
+func (admin *ChannelAdmin) Put(ctx context.Context, channelID string, spec *ChannelSpec) (*Channel, error) {
+	form := url.Values{}
+	setFormValue(form, "name", spec.Name)
+	setFormValue(form, "token", spec.Token)
+	setFormUint(form, "maxPayloadSize", spec.MaxPayloadSize)
+	setRateLimitForm(form, &spec.RateLimit)
+	channel := &Channel{}
+	if err := admin.client.sendConditionally(ctx, http.MethodPut, getChannelPath(channelID), nil, form, channel); err != nil {
+		return nil, err
+	}
+	return channel, nil
+}
+
+// Delete soft deletes the channel along with its consumers; with drain, the messages already queued are delivered first
+func (admin *ChannelAdmin) Delete(ctx context.Context, channelID string, drain bool) error {
+	return admin.client.sendConditionally(ctx, http.MethodDelete, getChannelPath(channelID), getDeleteQuery(drain), nil, nil)
+}
+
+// Purge permanently removes the deleted channel along with its messages once its retention is over
+func (admin *ChannelAdmin) Purge(ctx context.Context, channelID string) error {
+	return admin.client.sendConditionally(ctx, http.MethodDelete, getChannelPath(channelID), url.Values{purgeQueryParamName: {"true"}}, nil, nil)
+}
+
+// ListMessages returns the iterator over the messages of the channel matching the filter, which could be nil, latest first
+func (admin *ChannelAdmin) ListMessages(ctx context.Context, channelID string, filter *MessageFilter) *MessageIterator {
+	if filter == nil {
+		filter = &MessageFilter{}
+	}
+	return &MessageIterator{pager: newPager(ctx, admin.client, getChannelPath(channelID)+"/messages", filter.getQuery())}
+}
+
+// GetMessage returns the message of the channel along with its delivery jobs
+func (admin *ChannelAdmin) GetMessage(ctx context.Context, channelID, messageID string) (*Message, error) {
+	message := &Message{}
+	if _, err := admin.client.getJSON(ctx, getChannelPath(channelID)+"/message/"+url.PathEscape(messageID), nil, message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// GetStats returns the delivery stats of all the consumers of the channel
+func (admin *ChannelAdmin) GetStats(ctx context.Context, channelID string) (*JobStats, error) {
+	return getJobStats(ctx, admin.client, getChannelPath(channelID)+"/stats")
+}
+
+// ConsumerAdmin manages consumers of the broker along with their dead letter queues
+type ConsumerAdmin struct {
+	client *Client
+}
+
+// NewConsumerAdmin creates the admin client for consumers of the broker
+func NewConsumerAdmin(client *Client) *ConsumerAdmin {
+	return &ConsumerAdmin{client: client}
+}
+
+// List returns the iterator over the consumers of the channel
+func (admin *ConsumerAdmin) List(ctx context.Context, channelID string) *StakeholderIterator {
+	return &StakeholderIterator{pager: newPager(ctx, admin.client, getChannelPath(channelID)+"/consumers", nil)}
+}
+
+// Get returns the consumer; the error satisfies IsNotFound if it does not exist
+func (admin *ConsumerAdmin) Get(ctx context.Context, channelID, consumerID string) (*Consumer, error) {
+	consumer := &Consumer{}
+	if _, err := admin.client.getJSON(ctx, getConsumerPath(channelID, consumerID), nil, consumer); err != nil {
+		return nil, err
+	}
+	return consumer, nil
+}
+
+// Put creates the consumer or replaces the existing one with the spec
+func (admin *ConsumerAdmin) Put(ctx context.Context, channelID, consumerID string, spec *ConsumerSpec) (*Consumer, error) {
+	form := url.Values{}
+	setFormValue(form, "name", spec.Name)
+	setFormValue(form, "token", spec.Token)
+	setFormValue(form, "callbackUrl", spec.CallbackURL)
+	setFormValue(form, "type", string(spec.Type))
+	setFormValue(form, "deliveryFormat", string(spec.DeliveryFormat))
+	setFormValue(form, "routingKeyPattern", spec.RoutingKeyPattern)
+	if !spec.BackfillSince.IsZero() {
+		form.Set("backfillSince", spec.BackfillSince.UTC().Format(time.RFC3339))
+	}
+	consumer := &Consumer{}
+	if err := admin.client.sendConditionally(ctx, http.MethodPut, getConsumerPath(channelID, consumerID), nil, form, consumer); err != nil {
+		return nil, err
+	}
+	return consumer, nil
+}
+
+// Delete soft deletes the consumer; with drain, the messages already queued for it are delivered first
+func (admin *ConsumerAdmin) Delete(ctx context.Context, channelID, consumerID string, drain bool) error {
+	return admin.client.sendConditionally(ctx, http.MethodDelete, getConsumerPath(channelID, consumerID), getDeleteQuery(drain), nil, nil)
+}
+
+// Purge permanently removes the deleted consumer along with its delivery jobs once its retention is over
+func (admin *ConsumerAdmin) Purge(ctx context.Context, channelID, consumerID string) error {
+	return admin.client.sendConditionally(ctx, http.MethodDelete, getConsumerPath(channelID, consumerID), url.Values{purgeQueryParamName: {"true"}}, nil,
+		nil)
+}
+
+// Pause stops deliveries to the consumer; messages keep getting queued for it until resumed
+func (admin *ConsumerAdmin) Pause(ctx context.Context, channelID, consumerID string) error {
+	return admin.postWithToken(ctx, channelID, consumerID, "/pause", consumerTokenFormParamName, nil)
+}
+
+// Resume restarts deliveries to the paused consumer, with the messages queued while paused delivered at the broker's catch-up rate
+func (admin *ConsumerAdmin) Resume(ctx context.Context, channelID, consumerID string) error {
+	return admin.postWithToken(ctx, channelID, consumerID, "/resume", consumerTokenFormParamName, nil)
+}
+
+// ListDeadJobs returns the iterator over the dead letter queue of the consumer
+func (admin *ConsumerAdmin) ListDeadJobs(ctx context.Context, channelID, consumerID string) *DeadJobIterator {
+	return &DeadJobIterator{pager: newPager(ctx, admin.client, getConsumerPath(channelID, consumerID)+"/dlq", nil)}
+}
+
+// Requeue queues the dead jobs, all of them if none are passed, for delivery again
+func (admin *ConsumerAdmin) Requeue(ctx context.Context, channelID, consumerID string, jobIDs ...string) error {
+	return admin.postToDLQ(ctx, channelID, consumerID, nil, jobIDs)
+}
+
+// Discard removes the dead jobs, all of them if none are passed, from the dead letter queue without delivering them
+func (admin *ConsumerAdmin) Discard(ctx context.Context, channelID, consumerID string, jobIDs ...string) error {
+	return admin.postToDLQ(ctx, channelID, consumerID, url.Values{dlqActionFormParamName: {dlqActionDiscard}}, jobIDs)
+}
+
+// GetStats returns the delivery stats of the consumer
+func (admin *ConsumerAdmin) GetStats(ctx context.Context, channelID, consumerID string) (*JobStats, error) {
+	return getJobStats(ctx, admin.client, getConsumerPath(channelID, consumerID)+"/stats")
+}
+
+func (admin *ConsumerAdmin) postToDLQ(ctx context.Context, channelID, consumerID string, form url.Values, jobIDs []string) error {
+	if len(jobIDs) == 0 {
+		return admin.postWithToken(ctx, channelID, consumerID, "/dlq", requeueFormParamName, form)
+	}
+	for _, jobID := range jobIDs {
+		if err := admin.postWithToken(ctx, channelID, consumerID, "/dlq/"+url.PathEscape(jobID), requeueFormParamName, form); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// postWithToken POSTs the form, which could be nil, to the path under the consumer along with the consumer's token, read from the
+// broker, which the broker requires to act on behalf of the consumer
+func (admin *ConsumerAdmin) postWithToken(ctx context.Context, channelID, consumerID, subPath, tokenParamName string, form url.Values) error {
+	consumer, err := admin.Get(ctx, channelID, consumerID)
+	if err != nil {
+		return err
+	}
+	tokenForm := url.Values{tokenParamName: {consumer.Token}}
+	for key, values := range form {
+		tokenForm[key] = values
+	}
+	return admin.client.postForm(ctx, getConsumerPath(channelID, consumerID)+subPath, tokenForm)
+}
+
+func getJobStats(ctx context.Context, client *Client, statsPath string) (*JobStats, error) {
+	stats := &JobStats{}
+	if _, err := client.getJSON(ctx, statsPath, nil, stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func getDeleteQuery(drain bool) url.Values {
+	if drain {
+		return url.Values{drainQueryParamName: {"true"}}
+	}
+	return nil
+}
+
+func setFormValue(form url.Values, name, value string) {
+	if len(value) > 0 {
+		form.Set(name, value)
+	}
+}
+
+func setFormUint(form url.Values, name string, value uint) {
+	if value > 0 {
+		form.Set(name, strconv.FormatUint(uint64(value), 10))
+	}
+}
+
+func setRateLimitForm(form url.Values, rateLimit *RateLimitSpec) {
+	setFormUint(form, "messagesPerSecond", rateLimit.MessagesPerSecond)
+	setFormUint(form, "burst", rateLimit.Burst)
+	setFormUint(form, "bytesPerMinute", rateLimit.BytesPerMinute)
+}
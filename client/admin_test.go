@@ -0,0 +1,157 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChannelAdmin(t *testing.T) {
+	ctx := context.Background()
+	t.Run("List", func(t *testing.T) {
+		broker := newFakeBroker(t)
+		broker.respond("GET /channels", http.StatusOK, `{"Result":["/channel/a","/channel/b%2Fc"],"Pages":{"next":"/channels?cursor=p2"}}`)
+		broker.respond("GET /channels?cursor=p2", http.StatusOK, `{"Result":[],"Pages":{"next":"/channels?cursor=p3"}}`)
+		broker.respond("GET /channels?cursor=p3", http.StatusOK, `{"Result":["/channel/d"],"Pages":{"previous":"/channels?cursor=p2"}}`)
+		iterator := NewChannelAdmin(newTestClient(t, broker)).List(ctx)
+		ids, urls := make([]string, 0), make([]string, 0)
+		for iterator.Next() {
+			ids = append(ids, iterator.ID())
+			urls = append(urls, iterator.URL())
+		}
+		assert.Nil(t, iterator.Err())
+		assert.Equal(t, []string{"a", "b/c", "d"}, ids)
+		assert.Equal(t, []string{"/channel/a", "/channel/b%2Fc", "/channel/d"}, urls)
+		assert.False(t, iterator.Next())
+	})
+	t.Run("ListError", func(t *testing.T) {
+		broker := newFakeBroker(t)
+		broker.respond("GET /channels", http.StatusOK, `{"Result":["/channel/a"],"Pages":{"next":"/channels?cursor=p2"}}`)
+		broker.respond("GET /channels?cursor=p2", http.StatusInternalServerError, "")
+		iterator := NewChannelAdmin(newTestClient(t, broker)).List(ctx)
+		assert.True(t, iterator.Next())
+		assert.False(t, iterator.Next())
+		assert.NotNil(t, iterator.Err())
+		assert.False(t, iterator.Next())
+	})
+	t.Run("Put", func(t *testing.T) {
+		broker := newFakeBroker(t)
+		broker.respond("GET /channel/test", http.StatusOK, `{"ID":"test"}`, headerLastModified, testLastModified)
+		broker.respond("PUT /channel/test", http.StatusOK, `{"ID":"test","Name":"Test","MaxPayloadSize":1024,"RateLimit":{"MessagesPerSecond":5}}`)
+		channel, err := NewChannelAdmin(newTestClient(t, broker)).Put(ctx, "test", &ChannelSpec{Name: "Test", MaxPayloadSize: 1024,
+			RateLimit: RateLimitSpec{MessagesPerSecond: 5}})
+		assert.Nil(t, err)
+		assert.Equal(t, "Test", channel.Name)
+		assert.Equal(t, uint(1024), channel.MaxPayloadSize)
+		assert.Equal(t, uint(5), channel.RateLimit.MessagesPerSecond)
+		requests := broker.getRequests("PUT /channel/test")
+		assert.Equal(t, 1, len(requests))
+		form, _ := url.ParseQuery(requests[0].Body)
+		assert.Equal(t, url.Values{"name": {"Test"}, "maxPayloadSize": {"1024"}, "messagesPerSecond": {"5"}}, form)
+		assert.Equal(t, formContentTypeValue, requests[0].Header.Get(headerContentType))
+	})
+	t.Run("ListMessages", func(t *testing.T) {
+		broker := newFakeBroker(t)
+		broker.handle("GET /channel/test/messages", func(w http.ResponseWriter, r *http.Request) {
+			query := r.URL.Query()
+			if query.Get("cursor") == "p2" {
+				w.Write([]byte(`{"Messages":[{"MessageID":"m3"}]}`))
+				return
+			}
+			assert.Equal(t, "payload,jobs", query.Get(messageExpandParamName))
+			assert.Equal(t, []string{"DISPATCHED"}, query[messageStatusParamName])
+			assert.Equal(t, []string{"1", "2"}, query[messagePriorityParamName])
+			assert.Equal(t, "2021-01-02T03:04:05Z", query.Get(messageFromParamName))
+			assert.Equal(t, "true", query.Get(messageHasDeadJobsParamName))
+			assert.Empty(t, query.Get(messageProducerIDParamName))
+			w.Write([]byte(`{"Messages":[{"MessageID":"m1","Payload":"p1","Priority":2},{"MessageID":"m2"}],` +
+				`"Pages":{"next":"/channel/test/messages?cursor=p2"}}`))
+		})
+		from := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+		iterator := NewChannelAdmin(newTestClient(t, broker)).ListMessages(ctx, "test", &MessageFilter{Statuses: []string{"DISPATCHED"},
+			Priorities: []uint{1, 2}, From: from, HasDeadJobs: true, WithJobs: true})
+		ids := make([]string, 0)
+		for iterator.Next() {
+			ids = append(ids, iterator.Message().MessageID)
+		}
+		assert.Nil(t, iterator.Err())
+		assert.Equal(t, []string{"m1", "m2", "m3"}, ids)
+	})
+	t.Run("DefaultMessageFilter", func(t *testing.T) {
+		assert.Equal(t, url.Values{messageExpandParamName: {messageExpandPayload}}, (&MessageFilter{}).getQuery())
+	})
+	t.Run("GetStats", func(t *testing.T) {
+		broker := newFakeBroker(t)
+		broker.respond("GET /channel/test/stats", http.StatusOK, `{"JobCounts":{"QUEUED":3},"SuccessRate":0.5}`)
+		stats, err := NewChannelAdmin(newTestClient(t, broker)).GetStats(ctx, "test")
+		assert.Nil(t, err)
+		assert.Equal(t, uint(3), stats.JobCounts["QUEUED"])
+		assert.Equal(t, 0.5, stats.SuccessRate)
+	})
+}
+
+func TestConsumerAdmin(t *testing.T) {
+	ctx := context.Background()
+	t.Run("Put", func(t *testing.T) {
+		broker := newFakeBroker(t)
+		broker.respond("PUT /channel/test/consumer/c1", http.StatusCreated, `{"ID":"c1","Type":"PUSH","DeliveryFormat":"CLOUDEVENT-BINARY",`+
+			`"Backfill":{"JobCount":2,"QueuedCount":2,"JobsCreated":true}}`)
+		consumer, err := NewConsumerAdmin(newTestClient(t, broker)).Put(ctx, "test", "c1", &ConsumerSpec{CallbackURL: "https://example.com/hook",
+			DeliveryFormat: BinaryCloudEventDelivery, BackfillSince: time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)})
+		assert.Nil(t, err)
+		assert.Equal(t, PushConsumer, consumer.Type)
+		assert.Equal(t, BinaryCloudEventDelivery, consumer.DeliveryFormat)
+		assert.Equal(t, uint(2), consumer.Backfill.QueuedCount)
+		assert.True(t, consumer.Backfill.JobsCreated)
+		assert.False(t, consumer.Backfill.Complete)
+		requests := broker.getRequests("PUT /channel/test/consumer/c1")
+		assert.Equal(t, 1, len(requests))
+		form, _ := url.ParseQuery(requests[0].Body)
+		assert.Equal(t, url.Values{"callbackUrl": {"https://example.com/hook"}, "deliveryFormat": {"CLOUDEVENT-BINARY"},
+			"backfillSince": {"2021-01-02T03:04:05Z"}}, form)
+	})
+	t.Run("PauseResume", func(t *testing.T) {
+		broker := newFakeBroker(t)
+		broker.respond("GET /channel/test/consumer/c1", http.StatusOK, `{"ID":"c1","Token":"secret"}`)
+		broker.respond("POST /channel/test/consumer/c1/pause", http.StatusNoContent, "")
+		broker.respond("POST /channel/test/consumer/c1/resume", http.StatusAccepted, "")
+		admin := NewConsumerAdmin(newTestClient(t, broker))
+		assert.Nil(t, admin.Pause(ctx, "test", "c1"))
+		assert.Nil(t, admin.Resume(ctx, "test", "c1"))
+		for _, methodAndURI := range []string{"POST /channel/test/consumer/c1/pause", "POST /channel/test/consumer/c1/resume"} {
+			requests := broker.getRequests(methodAndURI)
+			assert.Equal(t, 1, len(requests))
+			assert.Equal(t, "token=secret", requests[0].Body)
+		}
+		assert.True(t, IsNotFound(admin.Pause(ctx, "test", "missing")))
+	})
+	t.Run("DeadJobs", func(t *testing.T) {
+		broker := newFakeBroker(t)
+		broker.respond("GET /channel/test/consumer/c1", http.StatusOK, `{"ID":"c1","Token":"secret"}`)
+		broker.respond("GET /channel/test/consumer/c1/dlq", http.StatusOK, `{"DeadJobs":[{"JobURL":"/channel/test/consumer/c1/dlq/j1",`+
+			`"MessageURL":"/channel/test/message/m1","RetryAttemptCount":3}],"Pages":{}}`)
+		broker.respond("POST /channel/test/consumer/c1/dlq", http.StatusAccepted, "")
+		broker.respond("POST /channel/test/consumer/c1/dlq/j1", http.StatusAccepted, "")
+		admin := NewConsumerAdmin(newTestClient(t, broker))
+		iterator := admin.ListDeadJobs(ctx, "test", "c1")
+		assert.True(t, iterator.Next())
+		assert.Equal(t, "j1", iterator.DeadJob().GetJobID())
+		assert.Equal(t, "m1", iterator.DeadJob().GetMessageID())
+		assert.Equal(t, uint(3), iterator.DeadJob().RetryAttemptCount)
+		assert.False(t, iterator.Next())
+		assert.Nil(t, iterator.Err())
+		assert.Nil(t, admin.Requeue(ctx, "test", "c1"))
+		assert.Nil(t, admin.Discard(ctx, "test", "c1", "j1"))
+		requests := broker.getRequests("POST /channel/test/consumer/c1/dlq")
+		assert.Equal(t, 1, len(requests))
+		assert.Equal(t, "requeue=secret", requests[0].Body)
+		requests = broker.getRequests("POST /channel/test/consumer/c1/dlq/j1")
+		assert.Equal(t, 1, len(requests))
+		form, _ := url.ParseQuery(requests[0].Body)
+		assert.Equal(t, url.Values{"requeue": {"secret"}, "action": {"discard"}}, form)
+	})
+}
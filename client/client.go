@@ -0,0 +1,194 @@
+// Package client is the Go client of the broker's HTTP API. It has a Producer to publish messages, admin clients for channels and
+// consumers with iterators over their paginated lists, and ConsumerHandler, a middleware for the handler of a consumer's callback URL.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	headerContentType     = "Content-Type"
+	headerLastModified    = "Last-Modified"
+	headerUnmodifiedSince = "If-Unmodified-Since"
+	headerRetryAfter      = "Retry-After"
+	formContentTypeValue  = "application/x-www-form-urlencoded"
+	maxErrorBodyLength    = 512
+)
+
+var (
+	// ErrInvalidBrokerURL is returned when the broker URL is not an absolute http(s) URL
+	ErrInvalidBrokerURL = errors.New("broker URL must be an absolute http or https URL")
+)
+
+// APIError is a non 2xx response from the broker
+type APIError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Body       string
+	Header     http.Header
+}
+
+func (err *APIError) Error() string {
+	body := strings.TrimSpace(err.Body)
+	if len(body) > maxErrorBodyLength {
+		body = body[:maxErrorBodyLength] + "..."
+	}
+	return fmt.Sprintf("%s %s: %d %s: %s", err.Method, err.URL, err.StatusCode, http.StatusText(err.StatusCode), body)
+}
+
+// IsNotFound returns whether the error is the broker responding that the resource does not exist
+func IsNotFound(err error) bool {
+	return hasStatusCode(err, http.StatusNotFound)
+}
+
+func hasStatusCode(err error, statusCode int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == statusCode
+}
+
+// Client calls the broker's HTTP API; it is safe for concurrent use
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	// Username and Password, when set, are sent as basic authentication, e.g. for a broker behind an authenticating proxy
+	Username string
+	Password string
+}
+
+// NewClient creates a client of the broker at the URL, which could have a path prefix for a broker behind a proxy; http.DefaultClient is
+// used to make the calls if httpClient is nil
+func NewClient(brokerURL string, httpClient *http.Client) (*Client, error) {
+	baseURL, err := url.Parse(brokerURL)
+	if err != nil || !baseURL.IsAbs() || (baseURL.Scheme != "http" && baseURL.Scheme != "https") {
+		return nil, ErrInvalidBrokerURL
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{baseURL: baseURL, httpClient: httpClient}, nil
+}
+
+// resolve returns the URL of an API path or link, which the broker returns relative to itself, with the query added
+func (client *Client) resolve(ref string, query url.Values) (*url.URL, error) {
+	target, err := url.Parse(ref)
+	if err != nil {
+		return nil, err
+	}
+	if !target.IsAbs() {
+		// Joined escaped so that an escaped `/` in an ID stays escaped
+		escapedPath := strings.TrimSuffix(client.baseURL.EscapedPath(), "/") + "/" + strings.TrimPrefix(target.EscapedPath(), "/")
+		resolved := *client.baseURL
+		if resolved.Path, err = url.PathUnescape(escapedPath); err != nil {
+			return nil, err
+		}
+		resolved.RawPath = escapedPath
+		resolved.RawQuery = target.RawQuery
+		target = &resolved
+	}
+	if len(query) > 0 {
+		targetQuery := target.Query()
+		for key, values := range query {
+			targetQuery[key] = values
+		}
+		target.RawQuery = targetQuery.Encode()
+	}
+	return target, nil
+}
+
+// do sends the request and returns the response with its body read; a non 2xx response is returned along with an *APIError
+func (client *Client) do(ctx context.Context, method, path string, query url.Values, header http.Header, body []byte) (*http.Response, []byte, error) {
+	target, err := client.resolve(path, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), bodyReader)
+	if err != nil {
+		return nil, nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if len(client.Username) > 0 || len(client.Password) > 0 {
+		req.SetBasicAuth(client.Username, client.Password)
+	}
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return resp, nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp, respBody, &APIError{Method: method, URL: target.String(), StatusCode: resp.StatusCode, Body: string(respBody), Header: resp.Header}
+	}
+	return resp, respBody, nil
+}
+
+// getJSON GETs the path and decodes the response into v
+func (client *Client) getJSON(ctx context.Context, path string, query url.Values, v interface{}) (http.Header, error) {
+	resp, body, err := client.do(ctx, http.MethodGet, path, query, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Header, json.Unmarshal(body, v)
+}
+
+// postForm POSTs the form to the path
+func (client *Client) postForm(ctx context.Context, path string, form url.Values) error {
+	header := make(http.Header)
+	header.Set(headerContentType, formContentTypeValue)
+	_, _, err := client.do(ctx, http.MethodPost, path, nil, header, []byte(form.Encode()))
+	return err
+}
+
+// sendConditionally sends the form, which could be nil, to the path of a channel, producer or consumer with the `If-Unmodified-Since`
+// header set to when it was last modified, as the broker requires to change an existing one; the header is skipped if it does not exist
+// yet. When purging a deleted one, which can not be read, the `Last-Modified` of the rejected request is used to send it again.
+func (client *Client) sendConditionally(ctx context.Context, method, path string, query url.Values, form url.Values, v interface{}) error {
+	header := make(http.Header)
+	if resp, _, err := client.do(ctx, http.MethodGet, path, nil, nil, nil); err == nil {
+		header.Set(headerUnmodifiedSince, resp.Header.Get(headerLastModified))
+	} else if !IsNotFound(err) {
+		return err
+	}
+	var body []byte
+	if form != nil {
+		header.Set(headerContentType, formContentTypeValue)
+		body = []byte(form.Encode())
+	}
+	_, respBody, err := client.do(ctx, method, path, query, header, body)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest && len(header.Get(headerUnmodifiedSince)) == 0 &&
+		len(apiErr.Header.Get(headerLastModified)) > 0 {
+		header.Set(headerUnmodifiedSince, apiErr.Header.Get(headerLastModified))
+		_, respBody, err = client.do(ctx, method, path, query, header, body)
+	}
+	if err != nil || v == nil || len(bytes.TrimSpace(respBody)) == 0 {
+		return err
+	}
+	return json.Unmarshal(respBody, v)
+}
+
+func getChannelPath(channelID string) string {
+	return "/channel/" + url.PathEscape(channelID)
+}
+
+func getConsumerPath(channelID, consumerID string) string {
+	return getChannelPath(channelID) + "/consumer/" + url.PathEscape(consumerID)
+}
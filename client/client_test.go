@@ -0,0 +1,204 @@
+package client
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testPathPrefix   = "/prefix"
+	testLastModified = "Mon, 02 Jan 2006 15:04:05 GMT"
+)
+
+// recordedRequest is a request received by the fake broker
+type recordedRequest struct {
+	Method string
+	URI    string
+	Header http.Header
+	Body   string
+}
+
+// fakeBroker serves responses keyed by method and request URI, or just the path when not found, without the path prefix and records the
+// requests received
+type fakeBroker struct {
+	*httptest.Server
+	mutex     sync.Mutex
+	requests  []*recordedRequest
+	responses map[string]func(w http.ResponseWriter, r *http.Request)
+}
+
+func newFakeBroker(t *testing.T) *fakeBroker {
+	broker := &fakeBroker{responses: make(map[string]func(w http.ResponseWriter, r *http.Request))}
+	broker.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		uri := strings.TrimPrefix(r.URL.RequestURI(), testPathPrefix)
+		broker.mutex.Lock()
+		broker.requests = append(broker.requests, &recordedRequest{Method: r.Method, URI: uri, Header: r.Header, Body: string(body)})
+		respond, ok := broker.responses[r.Method+" "+uri]
+		if !ok {
+			respond, ok = broker.responses[r.Method+" "+strings.TrimPrefix(r.URL.Path, testPathPrefix)]
+		}
+		broker.mutex.Unlock()
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("404 page not found"))
+			return
+		}
+		respond(w, r)
+	}))
+	t.Cleanup(broker.Close)
+	return broker
+}
+
+func (broker *fakeBroker) handle(methodAndURI string, respond func(w http.ResponseWriter, r *http.Request)) {
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+	broker.responses[methodAndURI] = respond
+}
+
+func (broker *fakeBroker) respond(methodAndURI string, status int, body string, header ...string) {
+	broker.handle(methodAndURI, func(w http.ResponseWriter, r *http.Request) {
+		for index := 0; index+1 < len(header); index += 2 {
+			w.Header().Set(header[index], header[index+1])
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	})
+}
+
+func (broker *fakeBroker) getRequests(methodAndURI string) []*recordedRequest {
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+	requests := make([]*recordedRequest, 0)
+	for _, request := range broker.requests {
+		if request.Method+" "+request.URI == methodAndURI {
+			requests = append(requests, request)
+		}
+	}
+	return requests
+}
+
+func newTestClient(t *testing.T, broker *fakeBroker) *Client {
+	client, err := NewClient(broker.URL+testPathPrefix, nil)
+	assert.Nil(t, err)
+	return client
+}
+
+func TestNewClient(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		client, err := NewClient("https://broker.example.com/prefix/", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, http.DefaultClient, client.httpClient)
+		httpClient := &http.Client{}
+		client, err = NewClient("http://localhost:8080", httpClient)
+		assert.Nil(t, err)
+		assert.Equal(t, httpClient, client.httpClient)
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		for _, brokerURL := range []string{"", "localhost:8080", "/channels", "ftp://localhost", "http://local host"} {
+			_, err := NewClient(brokerURL, nil)
+			assert.Equal(t, ErrInvalidBrokerURL, err, brokerURL)
+		}
+	})
+}
+
+func TestClientResolve(t *testing.T) {
+	client, _ := NewClient("https://broker.example.com/prefix/", nil)
+	resolved, err := client.resolve("/channel/a%2Fb?cursor=abc", map[string][]string{"status": {"DISPATCHED"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "https://broker.example.com/prefix/channel/a%2Fb?cursor=abc&status=DISPATCHED", resolved.String())
+	resolved, err = client.resolve("http://other.example.com/channels", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "http://other.example.com/channels", resolved.String())
+}
+
+func TestClientDo(t *testing.T) {
+	broker := newFakeBroker(t)
+	client := newTestClient(t, broker)
+	client.Username, client.Password = "operator", "secret"
+	broker.respond("GET /channel/test", http.StatusOK, `{"ID":"test"}`)
+	broker.respond("GET /channel/broken", http.StatusInternalServerError, strings.Repeat("x", maxErrorBodyLength+10), "X-Test", "value")
+	t.Run("Success", func(t *testing.T) {
+		channel, err := NewChannelAdmin(client).Get(context.Background(), "test")
+		assert.Nil(t, err)
+		assert.Equal(t, "test", channel.ID)
+		requests := broker.getRequests("GET /channel/test")
+		assert.Equal(t, 1, len(requests))
+		username, password, ok := (&http.Request{Header: requests[0].Header}).BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "operator", username)
+		assert.Equal(t, "secret", password)
+	})
+	t.Run("APIError", func(t *testing.T) {
+		_, err := NewChannelAdmin(client).Get(context.Background(), "broken")
+		apiErr, ok := err.(*APIError)
+		assert.True(t, ok)
+		assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+		assert.Equal(t, "value", apiErr.Header.Get("X-Test"))
+		assert.Contains(t, err.Error(), "GET "+broker.URL+testPathPrefix+"/channel/broken: 500 Internal Server Error")
+		assert.True(t, strings.HasSuffix(err.Error(), "..."))
+		assert.False(t, IsNotFound(err))
+	})
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := NewChannelAdmin(client).Get(context.Background(), "missing")
+		assert.True(t, IsNotFound(err))
+	})
+	t.Run("TransportError", func(t *testing.T) {
+		client, _ := NewClient("http://127.0.0.1:1", nil)
+		_, err := NewChannelAdmin(client).Get(context.Background(), "test")
+		assert.NotNil(t, err)
+		assert.False(t, IsNotFound(err))
+	})
+}
+
+func TestClientSendConditionally(t *testing.T) {
+	t.Run("ExistingResource", func(t *testing.T) {
+		broker := newFakeBroker(t)
+		broker.respond("GET /channel/test", http.StatusOK, `{"ID":"test"}`, headerLastModified, testLastModified)
+		broker.respond("DELETE /channel/test", http.StatusNoContent, "")
+		assert.Nil(t, NewChannelAdmin(newTestClient(t, broker)).Delete(context.Background(), "test", false))
+		requests := broker.getRequests("DELETE /channel/test")
+		assert.Equal(t, 1, len(requests))
+		assert.Equal(t, testLastModified, requests[0].Header.Get(headerUnmodifiedSince))
+	})
+	t.Run("NewResource", func(t *testing.T) {
+		broker := newFakeBroker(t)
+		broker.handle("PUT /channel/test", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"ID":"test"}`))
+		})
+		channel, err := NewChannelAdmin(newTestClient(t, broker)).Put(context.Background(), "test", &ChannelSpec{})
+		assert.Nil(t, err)
+		assert.Equal(t, "test", channel.ID)
+		requests := broker.getRequests("PUT /channel/test")
+		assert.Equal(t, 1, len(requests))
+		assert.Empty(t, requests[0].Header.Get(headerUnmodifiedSince))
+	})
+	t.Run("PurgeRetry", func(t *testing.T) {
+		broker := newFakeBroker(t)
+		broker.handle("DELETE /channel/test?purge=true", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(headerLastModified, testLastModified)
+			if r.Header.Get(headerUnmodifiedSince) != testLastModified {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+		assert.Nil(t, NewChannelAdmin(newTestClient(t, broker)).Purge(context.Background(), "test"))
+		assert.Equal(t, 2, len(broker.getRequests("DELETE /channel/test?purge=true")))
+	})
+	t.Run("GetError", func(t *testing.T) {
+		broker := newFakeBroker(t)
+		broker.respond("GET /channel/test", http.StatusInternalServerError, "")
+		err := NewChannelAdmin(newTestClient(t, broker)).Delete(context.Background(), "test", true)
+		assert.NotNil(t, err)
+		assert.Equal(t, 0, len(broker.getRequests("DELETE /channel/test?drain=true")))
+	})
+}
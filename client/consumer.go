@@ -0,0 +1,138 @@
+package client
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	headerConsumerToken         = "X-Broker-Consumer-Token"
+	headerVerificationChallenge = "X-Broker-Verification-Challenge"
+	headerChannelID             = "X-Broker-Channel-ID"
+	headerJobID                 = "X-Broker-Job-ID"
+	headerMessageReceivedAt     = "X-Broker-Message-Received-At"
+	headerDeliveryAttempt       = "X-Broker-Delivery-Attempt"
+	headerRequestID             = "X-Request-ID"
+	textContentType             = "text/plain; charset=utf-8"
+)
+
+var (
+	// ErrMissingDeliveryMetadata is returned when a request does not have the headers the broker sends along with a message
+	ErrMissingDeliveryMetadata = errors.New("request is missing delivery metadata headers")
+	// ErrMalformedDeliveryMetadata is returned when a delivery metadata header of a request could not be parsed
+	ErrMalformedDeliveryMetadata = errors.New("request has malformed delivery metadata header")
+)
+
+type deliveryContextKey struct{}
+
+// Delivery is the metadata of a message the broker sends along with it to a consumer
+type Delivery struct {
+	MessageID   string
+	ChannelID   string
+	ProducerID  string
+	JobID       string
+	RequestID   string
+	ContentType string
+	Priority    uint
+	ReceivedAt  time.Time
+	// Attempt is 1 for the first delivery of the message to the consumer and incremented with each retry
+	Attempt uint
+	// Attributes of the message keyed by their canonical header name, e.g. `Tenant-Id` for `tenant-id`
+	Attributes map[string]string
+}
+
+// ParseDelivery parses the delivery metadata from the headers of a request from the broker
+func ParseDelivery(r *http.Request) (*Delivery, error) {
+	delivery := &Delivery{MessageID: r.Header.Get(headerMessageID), ChannelID: r.Header.Get(headerChannelID), ProducerID: r.Header.Get(headerProducerID),
+		JobID: r.Header.Get(headerJobID), RequestID: r.Header.Get(headerRequestID), ContentType: r.Header.Get(headerContentType),
+		Attributes: make(map[string]string)}
+	if len(delivery.MessageID) == 0 || len(delivery.ChannelID) == 0 || len(delivery.JobID) == 0 {
+		return nil, ErrMissingDeliveryMetadata
+	}
+	if priority := r.Header.Get(headerPriority); len(priority) > 0 {
+		value, err := strconv.ParseUint(priority, 10, 32)
+		if err != nil {
+			return nil, ErrMalformedDeliveryMetadata
+		}
+		delivery.Priority = uint(value)
+	}
+	if attempt := r.Header.Get(headerDeliveryAttempt); len(attempt) > 0 {
+		value, err := strconv.ParseUint(attempt, 10, 32)
+		if err != nil {
+			return nil, ErrMalformedDeliveryMetadata
+		}
+		delivery.Attempt = uint(value)
+	}
+	if receivedAt := r.Header.Get(headerMessageReceivedAt); len(receivedAt) > 0 {
+		value, err := time.Parse(time.RFC3339Nano, receivedAt)
+		if err != nil {
+			return nil, ErrMalformedDeliveryMetadata
+		}
+		delivery.ReceivedAt = value
+	}
+	for name, values := range r.Header {
+		if strings.HasPrefix(name, headerAttributePrefix) && len(name) > len(headerAttributePrefix) && len(values) > 0 {
+			delivery.Attributes[name[len(headerAttributePrefix):]] = values[0]
+		}
+	}
+	return delivery, nil
+}
+
+// DeliveryFromContext returns the delivery metadata the ConsumerHandler stored in the context of the request it passed on
+func DeliveryFromContext(ctx context.Context) (*Delivery, bool) {
+	delivery, ok := ctx.Value(deliveryContextKey{}).(*Delivery)
+	return delivery, ok
+}
+
+// ConsumerHandler is the middleware for the callback URL of push consumers. It only passes on calls from the broker to the next
+// handler, i.e. requests with a consumer token it accepts and valid delivery metadata, which is then available through
+// DeliveryFromContext; it also answers the callback URL verification challenge of the broker on its own.
+type ConsumerHandler struct {
+	// Tokens accepted as the consumer token; more than one while rotating the token of the consumer
+	Tokens []string
+	Next   http.Handler
+}
+
+// NewConsumerHandler creates the middleware passing calls from the broker with one of the tokens on to next
+func NewConsumerHandler(next http.Handler, tokens ...string) *ConsumerHandler {
+	return &ConsumerHandler{Tokens: tokens, Next: next}
+}
+
+func (handler *ConsumerHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if !handler.isTokenAccepted(r.Header.Get(headerConsumerToken)) {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	if challenge := r.Header.Get(headerVerificationChallenge); len(challenge) > 0 {
+		w.Header().Set(headerContentType, textContentType)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(challenge))
+		return
+	}
+	delivery, err := ParseDelivery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	handler.Next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), deliveryContextKey{}, delivery)))
+}
+
+func (handler *ConsumerHandler) isTokenAccepted(token string) bool {
+	accepted := false
+	for _, expected := range handler.Tokens {
+		if len(expected) > 0 && subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1 {
+			accepted = true
+		}
+	}
+	return accepted
+}
@@ -0,0 +1,109 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestDeliveryRequest(token string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader("hello"))
+	req.Header.Set(headerConsumerToken, token)
+	req.Header.Set(headerContentType, "text/plain")
+	req.Header.Set(headerPriority, "3")
+	req.Header.Set(headerRequestID, "r1")
+	req.Header.Set(headerMessageID, "m1")
+	req.Header.Set(headerChannelID, "test")
+	req.Header.Set(headerProducerID, "p1")
+	req.Header.Set(headerMessageReceivedAt, "2021-01-02T03:04:05.123456789Z")
+	req.Header.Set(headerJobID, "j1")
+	req.Header.Set(headerDeliveryAttempt, "2")
+	req.Header.Set(headerAttributePrefix+"tenant-id", "t1")
+	return req
+}
+
+func TestParseDelivery(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		delivery, err := ParseDelivery(newTestDeliveryRequest("secret"))
+		assert.Nil(t, err)
+		assert.Equal(t, &Delivery{MessageID: "m1", ChannelID: "test", ProducerID: "p1", JobID: "j1", RequestID: "r1", ContentType: "text/plain",
+			Priority: 3, ReceivedAt: time.Date(2021, 1, 2, 3, 4, 5, 123456789, time.UTC), Attempt: 2, Attributes: map[string]string{"Tenant-Id": "t1"}},
+			delivery)
+	})
+	t.Run("Missing", func(t *testing.T) {
+		req := newTestDeliveryRequest("secret")
+		req.Header.Del(headerJobID)
+		_, err := ParseDelivery(req)
+		assert.Equal(t, ErrMissingDeliveryMetadata, err)
+	})
+	t.Run("Malformed", func(t *testing.T) {
+		for _, header := range []string{headerPriority, headerDeliveryAttempt, headerMessageReceivedAt} {
+			req := newTestDeliveryRequest("secret")
+			req.Header.Set(header, "-x")
+			_, err := ParseDelivery(req)
+			assert.Equal(t, ErrMalformedDeliveryMetadata, err, header)
+		}
+	})
+}
+
+func TestConsumerHandler(t *testing.T) {
+	var received *Delivery
+	handler := NewConsumerHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ = DeliveryFromContext(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	}), "old", "secret")
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		received = nil
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+	t.Run("Delivery", func(t *testing.T) {
+		recorder := serve(newTestDeliveryRequest("secret"))
+		assert.Equal(t, http.StatusNoContent, recorder.Code)
+		assert.NotNil(t, received)
+		assert.Equal(t, "m1", received.MessageID)
+		recorder = serve(newTestDeliveryRequest("old"))
+		assert.Equal(t, http.StatusNoContent, recorder.Code)
+	})
+	t.Run("Unauthorized", func(t *testing.T) {
+		for _, token := range []string{"", "wrong"} {
+			recorder := serve(newTestDeliveryRequest(token))
+			assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+			assert.Nil(t, received)
+		}
+	})
+	t.Run("MethodNotAllowed", func(t *testing.T) {
+		req := newTestDeliveryRequest("secret")
+		req.Method = http.MethodGet
+		recorder := serve(req)
+		assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+		assert.Equal(t, http.MethodPost, recorder.Header().Get("Allow"))
+	})
+	t.Run("BadRequest", func(t *testing.T) {
+		req := newTestDeliveryRequest("secret")
+		req.Header.Set(headerDeliveryAttempt, "x")
+		recorder := serve(req)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Nil(t, received)
+	})
+	t.Run("Verification", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(`{"type":"url_verification","challenge":"c1"}`))
+		req.Header.Set(headerConsumerToken, "secret")
+		req.Header.Set(headerVerificationChallenge, "c1")
+		recorder := serve(req)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "c1", recorder.Body.String())
+		assert.Nil(t, received)
+		req.Header.Set(headerConsumerToken, "wrong")
+		assert.Equal(t, http.StatusUnauthorized, serve(req).Code)
+	})
+	t.Run("NoDeliveryInContext", func(t *testing.T) {
+		_, ok := DeliveryFromContext(httptest.NewRequest(http.MethodPost, "/hook", nil).Context())
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,167 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"path"
+)
+
+const (
+	nextPageKey = "next"
+)
+
+// pageLinks are the links to the previous and next pages of a list, with the cursors of the pages in them
+type pageLinks struct {
+	Pages map[string]string
+}
+
+func (links *pageLinks) getNextPage() string {
+	return links.Pages[nextPageKey]
+}
+
+type page interface {
+	getNextPage() string
+}
+
+// pager fetches the pages of a list one at a time following their `next` links
+type pager struct {
+	client *Client
+	ctx    context.Context
+	next   string
+	query  url.Values
+	err    error
+}
+
+func newPager(ctx context.Context, client *Client, path string, query url.Values) pager {
+	return pager{client: client, ctx: ctx, next: path, query: query}
+}
+
+// fetchNextPage loads the next page, if any, into result; returns false when there are no more pages or on error
+func (pager *pager) fetchNextPage(result page) bool {
+	if len(pager.next) == 0 || pager.err != nil {
+		return false
+	}
+	if _, pager.err = pager.client.getJSON(pager.ctx, pager.next, pager.query, result); pager.err != nil {
+		return false
+	}
+	pager.next, pager.query = result.getNextPage(), nil
+	return true
+}
+
+// Err returns the error, if any, that stopped the iteration
+func (pager *pager) Err() error {
+	return pager.err
+}
+
+type listResult struct {
+	pageLinks
+	Result   []string
+	Messages []*ListedMessage
+}
+
+// StakeholderIterator iterates over a list of channels, producers or consumers, fetching its pages as needed
+//
+//	iterator := channelAdmin.List(ctx)
+//	for iterator.Next() {
+//		fmt.Println(iterator.ID())
+//	}
+//	if err := iterator.Err(); err != nil {
+//		...
+//	}
+type StakeholderIterator struct {
+	pager
+	urls    []string
+	current string
+}
+
+// Next advances to the next stakeholder; returns false when there are no more or on error
+func (iterator *StakeholderIterator) Next() bool {
+	for len(iterator.urls) == 0 {
+		result := &listResult{}
+		if !iterator.fetchNextPage(result) {
+			return false
+		}
+		iterator.urls = result.Result
+	}
+	iterator.current, iterator.urls = iterator.urls[0], iterator.urls[1:]
+	return true
+}
+
+// URL returns the URL, relative to the broker, of the current stakeholder
+func (iterator *StakeholderIterator) URL() string {
+	return iterator.current
+}
+
+// ID returns the ID of the current stakeholder
+func (iterator *StakeholderIterator) ID() string {
+	return getIDFromURL(iterator.current)
+}
+
+// MessageIterator iterates over messages of a channel, fetching its pages as needed
+type MessageIterator struct {
+	pager
+	messages []*ListedMessage
+	current  *ListedMessage
+}
+
+// Next advances to the next message; returns false when there are no more or on error
+func (iterator *MessageIterator) Next() bool {
+	for len(iterator.messages) == 0 {
+		result := &listResult{}
+		if !iterator.fetchNextPage(result) {
+			return false
+		}
+		iterator.messages = result.Messages
+	}
+	iterator.current, iterator.messages = iterator.messages[0], iterator.messages[1:]
+	return true
+}
+
+// Message returns the current message
+func (iterator *MessageIterator) Message() *ListedMessage {
+	return iterator.current
+}
+
+type deadJobList struct {
+	pageLinks
+	DeadJobs []*DeadJob
+}
+
+// DeadJobIterator iterates over the dead letter queue of a consumer, fetching its pages as needed
+type DeadJobIterator struct {
+	pager
+	deadJobs []*DeadJob
+	current  *DeadJob
+}
+
+// Next advances to the next dead job; returns false when there are no more or on error
+func (iterator *DeadJobIterator) Next() bool {
+	for len(iterator.deadJobs) == 0 {
+		result := &deadJobList{}
+		if !iterator.fetchNextPage(result) {
+			return false
+		}
+		iterator.deadJobs = result.DeadJobs
+	}
+	iterator.current, iterator.deadJobs = iterator.deadJobs[0], iterator.deadJobs[1:]
+	return true
+}
+
+// DeadJob returns the current dead job
+func (iterator *DeadJobIterator) DeadJob() *DeadJob {
+	return iterator.current
+}
+
+// getIDFromURL returns the ID of the resource in its URL, i.e. the last path segment
+func getIDFromURL(resourceURL string) string {
+	parsedURL, err := url.Parse(resourceURL)
+	if err != nil {
+		return ""
+	}
+	escapedID := path.Base(parsedURL.EscapedPath())
+	id, err := url.PathUnescape(escapedID)
+	if err != nil {
+		return escapedID
+	}
+	return id
+}
@@ -0,0 +1,175 @@
+package client
+
+import (
+	"time"
+)
+
+// ConsumerType is how a consumer receives messages
+type ConsumerType string
+
+// DeliveryFormat is the format of the calls to a push consumer's callback URL
+type DeliveryFormat string
+
+const (
+	// PushConsumer receives messages as calls to its callback URL
+	PushConsumer ConsumerType = "PUSH"
+	// StreamConsumer holds a connection open to the broker to receive messages
+	StreamConsumer ConsumerType = "STREAM"
+	// RawDelivery sends the payload as is
+	RawDelivery DeliveryFormat = "RAW"
+	// BinaryCloudEventDelivery sends the payload as is along with CloudEvent `ce-*` headers
+	BinaryCloudEventDelivery DeliveryFormat = "CLOUDEVENT-BINARY"
+	// StructuredCloudEventDelivery sends the message as a CloudEvent JSON document
+	StructuredCloudEventDelivery DeliveryFormat = "CLOUDEVENT-STRUCTURED"
+)
+
+// Stakeholder has the fields common to channels, producers and consumers
+type Stakeholder struct {
+	ID        string
+	Name      string
+	Token     string
+	ChangedAt time.Time
+}
+
+// RateLimit is the publish rate limit in effect along with what can be published right away
+type RateLimit struct {
+	MessagesPerSecond uint
+	Burst             uint
+	BytesPerMinute    uint
+	RemainingMessages uint
+	RemainingBytes    int64
+}
+
+// RateLimitSpec is the publish rate limit to set; a 0 means the broker's default applies
+type RateLimitSpec struct {
+	MessagesPerSecond uint
+	Burst             uint
+	BytesPerMinute    uint
+}
+
+// Channel is a channel as returned by the broker
+type Channel struct {
+	Stakeholder
+	MaxPayloadSize uint
+	ConsumersURL   string
+	MessagesURL    string
+	BroadcastURL   string
+	RateLimit      *RateLimit
+}
+
+// ChannelSpec is what a channel is created, or replaced, with; the broker generates a token if it is empty
+type ChannelSpec struct {
+	Name  string
+	Token string
+	// MaxPayloadSize of a message in bytes; the broker's default if 0
+	MaxPayloadSize uint
+	RateLimit      RateLimitSpec
+}
+
+// Backfill is the progress of delivering a channel's past messages to a newly created consumer
+type Backfill struct {
+	Since          time.Time
+	Until          time.Time
+	JobCount       uint
+	QueuedCount    uint
+	InflightCount  uint
+	DeliveredCount uint
+	DeadCount      uint
+	DiscardedCount uint
+	CancelledCount uint
+	JobsCreated    bool
+	Complete       bool
+}
+
+// Consumer is a consumer as returned by the broker
+type Consumer struct {
+	Stakeholder
+	CallbackURL        string
+	DeadLetterQueueURL string
+	VerificationStatus string
+	VerificationURL    string
+	Paused             bool
+	RoutingKeyPattern  string
+	Type               ConsumerType
+	DeliveryFormat     DeliveryFormat
+	Backfill           *Backfill
+}
+
+// ConsumerSpec is what a consumer is created, or replaced, with; the broker generates a token if it is empty
+type ConsumerSpec struct {
+	Name  string
+	Token string
+	// CallbackURL is required for a push consumer
+	CallbackURL string
+	// Type is PushConsumer if empty
+	Type ConsumerType
+	// DeliveryFormat is RawDelivery if empty
+	DeliveryFormat    DeliveryFormat
+	RoutingKeyPattern string
+	// BackfillSince, when set, has the channel's messages received since then delivered to the consumer; only honored on creation
+	BackfillSince time.Time
+}
+
+// DeliveryJob is the delivery of a message to a consumer
+type DeliveryJob struct {
+	ListenerEndpoint string
+	ListenerName     string
+	Status           string
+	StatusChangedAt  time.Time
+}
+
+// Message is a message as returned by the broker
+type Message struct {
+	Payload      string
+	PayloadRef   string
+	RoutingKey   string
+	Attributes   map[string]string
+	ReceiptURL   string
+	ContentType  string
+	ProducedBy   string
+	ReceivedAt   time.Time
+	DispatchedAt time.Time
+	Status       string
+	Jobs         []*DeliveryJob
+}
+
+// ListedMessage is a message in the list of messages of a channel; its payload and jobs are only set when asked for
+type ListedMessage struct {
+	Message
+	MessageID  string
+	MessageURL string
+	Priority   uint
+}
+
+// DeadJob is a delivery job in a consumer's dead letter queue
+type DeadJob struct {
+	DeliveryJob
+	MessageURL        string
+	JobURL            string
+	ConsumerID        string
+	FailureReason     string
+	RetryAttemptCount uint
+}
+
+// JobStats is the delivery stats of a consumer or of all the consumers of a channel
+type JobStats struct {
+	JobCounts                 map[string]uint
+	OldestQueuedJobAgeSeconds float64
+	// DeliveredPerMinute is keyed by the window, e.g. `5m` for the last 5 minutes
+	DeliveredPerMinute   map[string]float64
+	SuccessRate          float64
+	AverageLatencyMillis int64
+	SampledJobCount      uint
+	NextRetryAt          *time.Time
+	ComputedAt           time.Time
+}
+
+// GetJobID returns the ID of the dead job, e.g. to requeue it
+func (deadJob *DeadJob) GetJobID() string {
+	return getIDFromURL(deadJob.JobURL)
+}
+
+// GetMessageID returns the ID of the message of the dead job
+func (deadJob *DeadJob) GetMessageID() string {
+	return getIDFromURL(deadJob.MessageURL)
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/xid"
+)
+
+const (
+	headerChannelToken    = "X-Broker-Channel-Token"
+	headerProducerID      = "X-Broker-Producer-ID"
+	headerProducerToken   = "X-Broker-Producer-Token"
+	headerMessageID       = "X-Broker-Message-ID"
+	headerPriority        = "X-Broker-Message-Priority"
+	headerRoutingKey      = "X-Broker-Routing-Key"
+	headerAttributePrefix = "X-Broker-Attr-"
+	headerReceiptURL      = "X-Broker-Receipt-URL"
+	// DefaultMaxPublishAttempts is how many times a message is attempted unless set otherwise
+	DefaultMaxPublishAttempts = 3
+	// DefaultPublishRetryBackoff is the wait before the first retry unless set otherwise
+	DefaultPublishRetryBackoff = 500 * time.Millisecond
+)
+
+// OutgoingMessage is a message to publish
+type OutgoingMessage struct {
+	// ID of the message, unique within the channel; generated if empty
+	ID      string
+	Payload []byte
+	// ContentType is the broker's default, `application/octet-stream`, if empty
+	ContentType string
+	Priority    uint
+	RoutingKey  string
+	Attributes  map[string]string
+	// ReceiptURL, when set, is called with the outcome of the message; overrides the producer's
+	ReceiptURL string
+}
+
+// Producer publishes messages as a producer of the broker; it is safe for concurrent use
+type Producer struct {
+	client        *Client
+	producerID    string
+	producerToken string
+	// MaxAttempts is how many times a message is attempted when the broker is unreachable or responds with 5xx or 429
+	MaxAttempts int
+	// RetryBackoff is the wait before the first retry, doubled for each one after; the broker's `Retry-After` is honored instead if sent
+	RetryBackoff time.Duration
+}
+
+// NewProducer creates the client to publish messages as the producer
+func NewProducer(client *Client, producerID, producerToken string) *Producer {
+	return &Producer{client: client, producerID: producerID, producerToken: producerToken, MaxAttempts: DefaultMaxPublishAttempts,
+		RetryBackoff: DefaultPublishRetryBackoff}
+}
+
+// Publish publishes the message to the channel and returns its ID. As the ID is set before the first attempt, a retry of a message the
+// broker did accept is rejected as a duplicate, which is taken as published.
+func (producer *Producer) Publish(ctx context.Context, channelID, channelToken string, message *OutgoingMessage) (string, error) {
+	messageID := message.ID
+	if len(messageID) == 0 {
+		messageID = xid.New().String()
+	}
+	header := make(http.Header)
+	header.Set(headerChannelToken, channelToken)
+	header.Set(headerProducerID, producer.producerID)
+	header.Set(headerProducerToken, producer.producerToken)
+	header.Set(headerMessageID, messageID)
+	if len(message.ContentType) > 0 {
+		header.Set(headerContentType, message.ContentType)
+	}
+	if message.Priority > 0 {
+		header.Set(headerPriority, strconv.FormatUint(uint64(message.Priority), 10))
+	}
+	if len(message.RoutingKey) > 0 {
+		header.Set(headerRoutingKey, message.RoutingKey)
+	}
+	if len(message.ReceiptURL) > 0 {
+		header.Set(headerReceiptURL, message.ReceiptURL)
+	}
+	for name, value := range message.Attributes {
+		header.Set(headerAttributePrefix+name, value)
+	}
+	payload := message.Payload
+	if payload == nil {
+		payload = []byte{}
+	}
+	broadcastPath := getChannelPath(channelID) + "/broadcast"
+	for attempt := 1; ; attempt++ {
+		_, _, err := producer.client.do(ctx, http.MethodPost, broadcastPath, nil, header, payload)
+		switch {
+		case err == nil:
+			return messageID, nil
+		case attempt > 1 && hasStatusCode(err, http.StatusConflict):
+			return messageID, nil
+		case attempt >= producer.MaxAttempts || !isRetryable(ctx, err):
+			return messageID, err
+		}
+		select {
+		case <-ctx.Done():
+			return messageID, ctx.Err()
+		case <-time.After(producer.getRetryWait(attempt, err)):
+		}
+	}
+}
+
+// isRetryable returns whether publishing could succeed if attempted again, i.e. the broker was unreachable, errored or rate limited
+func isRetryable(ctx context.Context, err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return ctx.Err() == nil
+	}
+	return apiErr.StatusCode >= http.StatusInternalServerError || apiErr.StatusCode == http.StatusTooManyRequests
+}
+
+func (producer *Producer) getRetryWait(attempt int, err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		if seconds, parseErr := strconv.Atoi(apiErr.Header.Get(headerRetryAfter)); parseErr == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return producer.RetryBackoff << (attempt - 1)
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testBroadcastPath = "POST /channel/test/broadcast"
+)
+
+func newTestProducer(t *testing.T, broker *fakeBroker) *Producer {
+	producer := NewProducer(newTestClient(t, broker), "p1", "p1-token")
+	producer.RetryBackoff = time.Millisecond
+	return producer
+}
+
+func respondInSequence(broker *fakeBroker, methodAndURI string, statuses ...int) {
+	attempt := 0
+	broker.handle(methodAndURI, func(w http.ResponseWriter, r *http.Request) {
+		status := statuses[len(statuses)-1]
+		if attempt < len(statuses) {
+			status = statuses[attempt]
+		}
+		attempt++
+		w.WriteHeader(status)
+	})
+}
+
+func TestProducerPublish(t *testing.T) {
+	ctx := context.Background()
+	t.Run("Success", func(t *testing.T) {
+		broker := newFakeBroker(t)
+		broker.respond(testBroadcastPath, http.StatusAccepted, "")
+		messageID, err := newTestProducer(t, broker).Publish(ctx, "test", "channel-token", &OutgoingMessage{ID: "m1", Payload: []byte("hello"),
+			ContentType: "text/plain", Priority: 2, RoutingKey: "orders.created", Attributes: map[string]string{"tenant": "t1"},
+			ReceiptURL: "https://example.com/receipts"})
+		assert.Nil(t, err)
+		assert.Equal(t, "m1", messageID)
+		requests := broker.getRequests(testBroadcastPath)
+		assert.Equal(t, 1, len(requests))
+		header := requests[0].Header
+		assert.Equal(t, "hello", requests[0].Body)
+		assert.Equal(t, "channel-token", header.Get(headerChannelToken))
+		assert.Equal(t, "p1", header.Get(headerProducerID))
+		assert.Equal(t, "p1-token", header.Get(headerProducerToken))
+		assert.Equal(t, "m1", header.Get(headerMessageID))
+		assert.Equal(t, "text/plain", header.Get(headerContentType))
+		assert.Equal(t, "2", header.Get(headerPriority))
+		assert.Equal(t, "orders.created", header.Get(headerRoutingKey))
+		assert.Equal(t, "t1", header.Get(headerAttributePrefix+"tenant"))
+		assert.Equal(t, "https://example.com/receipts", header.Get(headerReceiptURL))
+	})
+	t.Run("GeneratedID", func(t *testing.T) {
+		broker := newFakeBroker(t)
+		broker.respond(testBroadcastPath, http.StatusAccepted, "")
+		messageID, err := newTestProducer(t, broker).Publish(ctx, "test", "channel-token", &OutgoingMessage{})
+		assert.Nil(t, err)
+		assert.NotEmpty(t, messageID)
+		requests := broker.getRequests(testBroadcastPath)
+		assert.Equal(t, messageID, requests[0].Header.Get(headerMessageID))
+		assert.Empty(t, requests[0].Header.Get(headerPriority))
+	})
+	t.Run("RetryOnServerError", func(t *testing.T) {
+		broker := newFakeBroker(t)
+		respondInSequence(broker, testBroadcastPath, http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusAccepted)
+		messageID, err := newTestProducer(t, broker).Publish(ctx, "test", "channel-token", &OutgoingMessage{})
+		assert.Nil(t, err)
+		requests := broker.getRequests(testBroadcastPath)
+		assert.Equal(t, 3, len(requests))
+		for _, request := range requests {
+			assert.Equal(t, messageID, request.Header.Get(headerMessageID))
+		}
+	})
+	t.Run("DuplicateOnRetry", func(t *testing.T) {
+		broker := newFakeBroker(t)
+		respondInSequence(broker, testBroadcastPath, http.StatusBadGateway, http.StatusConflict)
+		_, err := newTestProducer(t, broker).Publish(ctx, "test", "channel-token", &OutgoingMessage{ID: "m1"})
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(broker.getRequests(testBroadcastPath)))
+	})
+	t.Run("DuplicateOnFirstAttempt", func(t *testing.T) {
+		broker := newFakeBroker(t)
+		broker.respond(testBroadcastPath, http.StatusConflict, "")
+		_, err := newTestProducer(t, broker).Publish(ctx, "test", "channel-token", &OutgoingMessage{ID: "m1"})
+		assert.True(t, hasStatusCode(err, http.StatusConflict))
+	})
+	t.Run("NoRetryOnClientError", func(t *testing.T) {
+		broker := newFakeBroker(t)
+		broker.respond(testBroadcastPath, http.StatusForbidden, "")
+		_, err := newTestProducer(t, broker).Publish(ctx, "test", "channel-token", &OutgoingMessage{})
+		assert.True(t, hasStatusCode(err, http.StatusForbidden))
+		assert.Equal(t, 1, len(broker.getRequests(testBroadcastPath)))
+	})
+	t.Run("MaxAttempts", func(t *testing.T) {
+		broker := newFakeBroker(t)
+		broker.respond(testBroadcastPath, http.StatusInternalServerError, "")
+		producer := newTestProducer(t, broker)
+		producer.MaxAttempts = 2
+		_, err := producer.Publish(ctx, "test", "channel-token", &OutgoingMessage{})
+		assert.True(t, hasStatusCode(err, http.StatusInternalServerError))
+		assert.Equal(t, 2, len(broker.getRequests(testBroadcastPath)))
+	})
+	t.Run("ContextCanceled", func(t *testing.T) {
+		broker := newFakeBroker(t)
+		broker.respond(testBroadcastPath, http.StatusTooManyRequests, "", headerRetryAfter, "60")
+		cancelCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		_, err := newTestProducer(t, broker).Publish(cancelCtx, "test", "channel-token", &OutgoingMessage{})
+		assert.Equal(t, context.DeadlineExceeded, err)
+		assert.Equal(t, 1, len(broker.getRequests(testBroadcastPath)))
+	})
+}
+
+func TestProducerGetRetryWait(t *testing.T) {
+	producer := &Producer{RetryBackoff: time.Second}
+	assert.Equal(t, time.Second, producer.getRetryWait(1, context.DeadlineExceeded))
+	assert.Equal(t, 4*time.Second, producer.getRetryWait(3, &APIError{StatusCode: http.StatusBadGateway, Header: http.Header{}}))
+	header := http.Header{}
+	header.Set(headerRetryAfter, "7")
+	assert.Equal(t, 7*time.Second, producer.getRetryWait(1, &APIError{StatusCode: http.StatusTooManyRequests, Header: header}))
+}
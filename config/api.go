@@ -18,6 +18,8 @@ type RelationalDatabaseConfig interface {
 	GetDBConnectionMaxLifetime() time.Duration
 	GetMaxIdleDBConnections() uint16
 	GetMaxOpenDBConnections() uint16
+	GetPayloadCompressionCodec() PayloadCompressionCodec
+	GetPayloadCompressionThreshold() uint
 }
 
 // HTTPConfig represents the HTTP configuration related behaviors
@@ -27,6 +29,12 @@ type HTTPConfig interface {
 	GetHTTPWriteTimeout() time.Duration
 }
 
+// GRPCConfig represents the gRPC API configuration related behaviors
+type GRPCConfig interface {
+	IsGRPCEnabled() bool
+	GetGRPCListeningAddr() string
+}
+
 // LogConfig represents the interface for log related configuration
 type LogConfig interface {
 	GetLogLevel() LogLevel
@@ -60,12 +68,19 @@ type SeedConsumer struct {
 	Channel string
 }
 
+// SeedPublishGrant represents pre configured grant for a producer to publish to a channel via configuration
+type SeedPublishGrant struct {
+	ProducerID string
+	ChannelID  string
+}
+
 // SeedData represents data specified in configuration to ensure is present when app starts up
 type SeedData struct {
-	DataHash  string
-	Producers []SeedProducer
-	Channels  []SeedChannel
-	Consumers []SeedConsumer
+	DataHash      string
+	Producers     []SeedProducer
+	Channels      []SeedChannel
+	Consumers     []SeedConsumer
+	PublishGrants []SeedPublishGrant
 }
 
 // Scan de-serializes SeedData for reading from DB
@@ -97,6 +112,7 @@ type ConsumerConnectionConfig interface {
 	GetTokenRequestHeaderName() string
 	GetUserAgent() string
 	GetConnectionTimeout() time.Duration
+	IsCallbackVerificationEnabled() bool
 }
 
 // BrokerConfig provides the interface for configuring the broker
@@ -109,4 +125,42 @@ type BrokerConfig interface {
 	GetRationalDelay() time.Duration
 	GetRetryBackoffDelays() []time.Duration
 	IsRecoveryWorkersEnabled() bool
+	GetResumeCatchUpRate() uint
+	GetMaxPayloadSize() uint
+	GetMaxBatchSize() uint
+	GetMaxPublishWait() time.Duration
+	GetPurgeRetention() time.Duration
+	IsReceiptOnDeliveredEnabled() bool
+	IsReceiptOnDeadEnabled() bool
+	IsPublishGrantEnforced() bool
+}
+
+// PublishRateLimit is the rate at which messages can be published; a zero field means the rate is not limited by it
+type PublishRateLimit struct {
+	// MessagesPerSecond is the rate the messages can be published at on average
+	MessagesPerSecond uint
+	// Burst is the number of messages that can be published at once; the messages per second if 0
+	Burst uint
+	// BytesPerMinute is the total size of the payloads that can be published in a minute
+	BytesPerMinute uint
+}
+
+// RateLimitConfig provides the interface for configuring the default publish rate limits of producers and channels
+type RateLimitConfig interface {
+	GetProducerRateLimit() PublishRateLimit
+	GetChannelRateLimit() PublishRateLimit
+}
+
+// AdminConfig provides the interface for configuring the web admin console and its basic authentication
+type AdminConfig interface {
+	IsAdminConsoleEnabled() bool
+	GetAdminUsername() string
+	GetAdminPassword() string
+}
+
+// BlobStoreConfig provides the interface for configuring where large message payloads are offloaded to
+type BlobStoreConfig interface {
+	GetBlobStoreProvider() BlobStoreProvider
+	GetBlobStoreFileSystemPath() string
+	GetPayloadOffloadThreshold() uint
 }
@@ -32,6 +32,12 @@ type DBDialect string
 // LogLevel represents the log level logger should use
 type LogLevel uint8
 
+// BlobStoreProvider represents the store large message payloads are offloaded to
+type BlobStoreProvider string
+
+// PayloadCompressionCodec represents the codec used to compress message payloads in storage
+type PayloadCompressionCodec string
+
 // GetVersion provides the current version of the project
 func GetVersion() AppVersion {
 	return "0.2.0-dev"
@@ -42,6 +48,16 @@ const (
 	SQLite3Dialect = DBDialect("sqlite3")
 	// MySQLDialect represents the DB Dialect for MySQL
 	MySQLDialect = DBDialect("mysql")
+	// NoPayloadCompression represents payloads being stored as is
+	NoPayloadCompression = PayloadCompressionCodec("none")
+	// GzipPayloadCompression represents payloads being stored compressed with gzip
+	GzipPayloadCompression = PayloadCompressionCodec("gzip")
+	// ZstdPayloadCompression represents payloads being stored compressed with zstd
+	ZstdPayloadCompression = PayloadCompressionCodec("zstd")
+	// NoBlobStoreProvider represents payloads never being offloaded from the database
+	NoBlobStoreProvider = BlobStoreProvider("none")
+	// FileSystemBlobStoreProvider represents payloads being offloaded to the local file system
+	FileSystemBlobStoreProvider = BlobStoreProvider("filesystem")
 	// ConfigFilename is the default config file name
 	ConfigFilename = "webhook-broker.cfg"
 	// DefaultSystemConfigFilePath is the default system location of the configuration
@@ -72,7 +88,7 @@ var (
 	loadConfiguration = defaultLoadFunc
 	errDBDialect      = errors.New("DB Dialect not supported")
 	// ConfigInjector sets up configuration related bindings
-	ConfigInjector = wire.NewSet(GetConfigurationFromCLIConfig, wire.Bind(new(SeedDataConfig), new(*Config)), wire.Bind(new(HTTPConfig), new(*Config)), wire.Bind(new(RelationalDatabaseConfig), new(*Config)), wire.Bind(new(LogConfig), new(*Config)), wire.Bind(new(BrokerConfig), new(*Config)), wire.Bind(new(ConsumerConnectionConfig), new(*Config)))
+	ConfigInjector = wire.NewSet(GetConfigurationFromCLIConfig, wire.Bind(new(SeedDataConfig), new(*Config)), wire.Bind(new(HTTPConfig), new(*Config)), wire.Bind(new(GRPCConfig), new(*Config)), wire.Bind(new(RelationalDatabaseConfig), new(*Config)), wire.Bind(new(LogConfig), new(*Config)), wire.Bind(new(BrokerConfig), new(*Config)), wire.Bind(new(ConsumerConnectionConfig), new(*Config)), wire.Bind(new(BlobStoreConfig), new(*Config)), wire.Bind(new(RateLimitConfig), new(*Config)), wire.Bind(new(AdminConfig), new(*Config)))
 )
 
 var currentUser = user.Current
@@ -87,33 +103,63 @@ func getUserHomeDirBasedDefaultConfigFileLocation() string {
 
 //Config represents the application configuration
 type Config struct {
-	DBDialect                 DBDialect
-	DBConnectionURL           string
-	DBConnectionMaxIdleTime   time.Duration
-	DBConnectionMaxLifetime   time.Duration
-	DBMaxIdleConnections      uint16
-	DBMaxOpenConnections      uint16
-	HTTPListeningAddr         string
-	HTTPReadTimeout           time.Duration
-	HTTPWriteTimeout          time.Duration
-	LogFilename               string
-	MaxFileSize               uint
-	MaxBackups                uint
-	MaxAge                    uint
-	CompressBackupsEnabled    bool
-	SeedData                  SeedData
-	TokenRequestHeaderName    string
-	UserAgent                 string
-	ConnectionTimeout         time.Duration
-	MaxMessageQueueSize       uint
-	MaxWorkers                uint
-	PriorityDispatcherEnabled bool
-	RecoveryWorkersEnabled    bool
-	RetriggerBaseEndpoint     string
-	MaxRetry                  uint8
-	RationalDelay             time.Duration
-	RetryBackoffDelays        []time.Duration
-	LogLevel                  LogLevel
+	DBDialect                   DBDialect
+	DBConnectionURL             string
+	DBConnectionMaxIdleTime     time.Duration
+	DBConnectionMaxLifetime     time.Duration
+	DBMaxIdleConnections        uint16
+	DBMaxOpenConnections        uint16
+	PayloadCompressionCodec     PayloadCompressionCodec
+	PayloadCompressionThreshold uint
+	HTTPListeningAddr           string
+	HTTPReadTimeout             time.Duration
+	HTTPWriteTimeout            time.Duration
+	GRPCEnabled                 bool
+	GRPCListeningAddr           string
+	LogFilename                 string
+	MaxFileSize                 uint
+	MaxBackups                  uint
+	MaxAge                      uint
+	CompressBackupsEnabled      bool
+	SeedData                    SeedData
+	TokenRequestHeaderName      string
+	UserAgent                   string
+	ConnectionTimeout           time.Duration
+	CallbackVerification        bool
+	MaxMessageQueueSize         uint
+	MaxWorkers                  uint
+	PriorityDispatcherEnabled   bool
+	RecoveryWorkersEnabled      bool
+	RetriggerBaseEndpoint       string
+	MaxRetry                    uint8
+	RationalDelay               time.Duration
+	RetryBackoffDelays          []time.Duration
+	ResumeCatchUpRate           uint
+	MaxPayloadSize              uint
+	MaxBatchSize                uint
+	MaxPublishWait              time.Duration
+	PurgeRetention              time.Duration
+	ReceiptOnDelivered          bool
+	ReceiptOnDead               bool
+	PublishGrantEnforced        bool
+	BlobStoreProvider           BlobStoreProvider
+	BlobStoreFileSystemPath     string
+	PayloadOffloadThreshold     uint
+	ProducerRateLimit           PublishRateLimit
+	ChannelRateLimit            PublishRateLimit
+	AdminUsername               string
+	AdminPassword               string
+	LogLevel                    LogLevel
+}
+
+// IsGRPCEnabled returns whether the gRPC API is served alongside the HTTP API
+func (config *Config) IsGRPCEnabled() bool {
+	return config.GRPCEnabled
+}
+
+// GetGRPCListeningAddr retrieves the address the gRPC API listens to
+func (config *Config) GetGRPCListeningAddr() string {
+	return config.GRPCListeningAddr
 }
 
 // GetLogLevel returns the log level as per the configuration
@@ -151,6 +197,16 @@ func (config *Config) GetMaxOpenDBConnections() uint16 {
 	return config.DBMaxOpenConnections
 }
 
+// GetPayloadCompressionCodec returns the codec to compress message payloads with
+func (config *Config) GetPayloadCompressionCodec() PayloadCompressionCodec {
+	return config.PayloadCompressionCodec
+}
+
+// GetPayloadCompressionThreshold returns the payload size in bytes above which message payloads are compressed
+func (config *Config) GetPayloadCompressionThreshold() uint {
+	return config.PayloadCompressionThreshold
+}
+
 // GetHTTPListeningAddr retrieves the connection string to listen to
 func (config *Config) GetHTTPListeningAddr() string {
 	return config.HTTPListeningAddr
@@ -216,6 +272,11 @@ func (config *Config) GetConnectionTimeout() time.Duration {
 	return config.ConnectionTimeout
 }
 
+// IsCallbackVerificationEnabled returns whether new or changed consumer callback URLs have to complete a verification handshake
+func (config *Config) IsCallbackVerificationEnabled() bool {
+	return config.CallbackVerification
+}
+
 // GetMaxMessageQueueSize returns the maximum number of messages to be queued without being dispatched
 func (config *Config) GetMaxMessageQueueSize() uint {
 	return config.MaxMessageQueueSize
@@ -256,6 +317,87 @@ func (config *Config) IsRecoveryWorkersEnabled() bool {
 	return config.RecoveryWorkersEnabled
 }
 
+// GetResumeCatchUpRate retrieves the maximum number of backlogged jobs per second attempted for a consumer once it is resumed, backfilled or replayed; 0 means no limit
+func (config *Config) GetResumeCatchUpRate() uint {
+	return config.ResumeCatchUpRate
+}
+
+// GetMaxPayloadSize retrieves the maximum size in bytes of message payloads accepted by channels that do not set their own; 0 means no limit
+func (config *Config) GetMaxPayloadSize() uint {
+	return config.MaxPayloadSize
+}
+
+// IsPublishGrantEnforced retrieves whether producers are rejected from publishing to channels they are not granted to; when not enforced they are
+// only logged so that grants can be created before turning enforcement on
+func (config *Config) IsPublishGrantEnforced() bool {
+	return config.PublishGrantEnforced
+}
+
+// GetMaxBatchSize retrieves the maximum size in bytes of a broadcast batch request body; 0 means no limit
+func (config *Config) GetMaxBatchSize() uint {
+	return config.MaxBatchSize
+}
+
+// GetMaxPublishWait retrieves the longest a broadcast can wait for the delivery outcome of its message when requested with `Prefer: wait`
+func (config *Config) GetMaxPublishWait() time.Duration {
+	return config.MaxPublishWait
+}
+
+// GetPurgeRetention retrieves how long a deleted producer, channel or consumer is retained before it can be purged
+func (config *Config) GetPurgeRetention() time.Duration {
+	return config.PurgeRetention
+}
+
+// IsReceiptOnDeliveredEnabled retrieves whether a receipt is sent to the producer once all the jobs of its message are delivered
+func (config *Config) IsReceiptOnDeliveredEnabled() bool {
+	return config.ReceiptOnDelivered
+}
+
+// IsReceiptOnDeadEnabled retrieves whether a receipt is sent to the producer when a job of its message is dead
+func (config *Config) IsReceiptOnDeadEnabled() bool {
+	return config.ReceiptOnDead
+}
+
+// GetBlobStoreProvider retrieves the store large message payloads are offloaded to
+func (config *Config) GetBlobStoreProvider() BlobStoreProvider {
+	return config.BlobStoreProvider
+}
+
+// GetBlobStoreFileSystemPath retrieves the directory the file system blob store keeps payloads in
+func (config *Config) GetBlobStoreFileSystemPath() string {
+	return config.BlobStoreFileSystemPath
+}
+
+// GetPayloadOffloadThreshold retrieves the payload size in bytes above which message payloads are offloaded to the blob store
+func (config *Config) GetPayloadOffloadThreshold() uint {
+	return config.PayloadOffloadThreshold
+}
+
+// GetProducerRateLimit retrieves the rate limit of producers publishing messages unless set on the producer
+func (config *Config) GetProducerRateLimit() PublishRateLimit {
+	return config.ProducerRateLimit
+}
+
+// GetChannelRateLimit retrieves the rate limit of messages published to channels unless set on the channel
+func (config *Config) GetChannelRateLimit() PublishRateLimit {
+	return config.ChannelRateLimit
+}
+
+// IsAdminConsoleEnabled returns whether the web admin console is served, which is only when an admin password is set
+func (config *Config) IsAdminConsoleEnabled() bool {
+	return len(config.AdminPassword) > 0
+}
+
+// GetAdminUsername retrieves the username to sign in to the admin console with
+func (config *Config) GetAdminUsername() string {
+	return config.AdminUsername
+}
+
+// GetAdminPassword retrieves the password to sign in to the admin console with
+func (config *Config) GetAdminPassword() string {
+	return config.AdminPassword
+}
+
 // func (config *Config) () {}
 
 // GetAutoConfiguration gets configuration from default config and system defined path chain of
@@ -286,10 +428,14 @@ func GetConfigurationFromParseConfig(cfg *ini.File) (*Config, error) {
 	configuration := &Config{}
 	setupStorageConfiguration(cfg, configuration)
 	setupHTTPConfiguration(cfg, configuration)
+	setupGRPCConfiguration(cfg, configuration)
 	setupLogConfiguration(cfg, configuration)
 	setupSeedDataConfiguration(cfg, configuration)
 	setupConsumerConnectionConfiguration(cfg, configuration)
 	setupBrokerConfiguration(cfg, configuration)
+	setupBlobStoreConfiguration(cfg, configuration)
+	setupRateLimitConfiguration(cfg, configuration)
+	setupAdminConfiguration(cfg, configuration)
 	if validationErr := validateConfigurationState(configuration); validationErr != nil {
 		return EmptyConfigurationForError, validationErr
 	}
@@ -312,6 +458,13 @@ func validateConfigurationState(configuration *Config) error {
 		return netErr
 	}
 	defer ln.Close()
+	if configuration.GRPCEnabled {
+		grpcLn, netErr := net.Listen("tcp", configuration.GRPCListeningAddr)
+		if netErr != nil {
+			return netErr
+		}
+		defer grpcLn.Close()
+	}
 	// Check DB Connection is valid
 	var ping func(*sql.DB) error
 	switch configuration.DBDialect {
@@ -382,6 +535,17 @@ func setupStorageConfiguration(cfg *ini.File, configuration *Config) {
 	configuration.DBConnectionMaxLifetime = time.Duration(dbMaxLifetimeInSec.MustUint(0)) * time.Second
 	configuration.DBMaxIdleConnections = uint16(dbMaxIdleConnections.MustUint(10))
 	configuration.DBMaxOpenConnections = uint16(dbMaxOpenConnections.MustUint(50))
+	payloadCompressionCodecKey, _ := dbSection.GetKey("payload-compression-codec")
+	payloadCompressionThresholdKey, _ := dbSection.GetKey("payload-compression-threshold-in-bytes")
+	switch PayloadCompressionCodec(strings.ToLower(payloadCompressionCodecKey.MustString(string(NoPayloadCompression)))) {
+	case GzipPayloadCompression:
+		configuration.PayloadCompressionCodec = GzipPayloadCompression
+	case ZstdPayloadCompression:
+		configuration.PayloadCompressionCodec = ZstdPayloadCompression
+	default:
+		configuration.PayloadCompressionCodec = NoPayloadCompression
+	}
+	configuration.PayloadCompressionThreshold = payloadCompressionThresholdKey.MustUint(4096)
 }
 
 func setupHTTPConfiguration(cfg *ini.File, configuration *Config) {
@@ -394,6 +558,14 @@ func setupHTTPConfiguration(cfg *ini.File, configuration *Config) {
 	configuration.HTTPWriteTimeout = time.Duration(httpWriteTimeout.MustUint(180)) * time.Second
 }
 
+func setupGRPCConfiguration(cfg *ini.File, configuration *Config) {
+	grpcSection, _ := cfg.GetSection("grpc")
+	grpcEnabled, _ := grpcSection.GetKey("enabled")
+	grpcListener, _ := grpcSection.GetKey("listener")
+	configuration.GRPCEnabled = grpcEnabled.MustBool(false)
+	configuration.GRPCListeningAddr = grpcListener.MustString(":9090")
+}
+
 func setupLogConfiguration(cfg *ini.File, configuration *Config) {
 	logSection, _ := cfg.GetSection("log")
 	logFilenameKey, _ := logSection.GetKey("filename")
@@ -438,6 +610,10 @@ func setupSeedDataConfiguration(cfg *ini.File, configuration *Config) {
 	seedProducers := parseProducers(initialProducers, initialProducerTokens)
 	seedData.Producers = seedProducers
 
+	if initialProducerChannels, err := cfg.GetSection("initial-producer-channels"); err == nil {
+		seedData.PublishGrants = parsePublishGrants(initialProducerChannels)
+	}
+
 	seedData.Consumers = parseConsumers(cfg, seedChannels)
 
 	var buf bytes.Buffer
@@ -502,14 +678,28 @@ func parseProducers(initialProducers *ini.Section, initialProducerTokens *ini.Se
 	return seedProducers
 }
 
+func parsePublishGrants(initialProducerChannels *ini.Section) []SeedPublishGrant {
+	seedGrants := make([]SeedPublishGrant, 0, len(initialProducerChannels.Keys()))
+	for _, producer := range initialProducerChannels.Keys() {
+		for _, channel := range producer.Strings(",") {
+			if len(channel) > 0 {
+				seedGrants = append(seedGrants, SeedPublishGrant{ProducerID: producer.Name(), ChannelID: channel})
+			}
+		}
+	}
+	return seedGrants
+}
+
 func setupConsumerConnectionConfiguration(cfg *ini.File, configuration *Config) {
 	consumerConnection, _ := cfg.GetSection("consumer-connection")
 	tokenHeaderName, _ := consumerConnection.GetKey("token-header-name")
 	userAgent, _ := consumerConnection.GetKey("user-agent")
 	connectionTimeoutInSecs, _ := consumerConnection.GetKey("connection-timeout-in-seconds")
+	callbackVerification, _ := consumerConnection.GetKey("callback-verification-enabled")
 	configuration.TokenRequestHeaderName = tokenHeaderName.MustString("")
 	configuration.UserAgent = userAgent.MustString("")
 	configuration.ConnectionTimeout = time.Duration(connectionTimeoutInSecs.MustUint(60)) * time.Second
+	configuration.CallbackVerification = callbackVerification.MustBool(false)
 }
 
 func setupBrokerConfiguration(cfg *ini.File, configuration *Config) {
@@ -522,6 +712,13 @@ func setupBrokerConfiguration(cfg *ini.File, configuration *Config) {
 	maxRetry, _ := broker.GetKey("max-retry")
 	rationalDelayInSecs, _ := broker.GetKey("rational-delay-in-seconds")
 	retryBackoffDelayInSecs, _ := broker.GetKey("retry-backoff-delays-in-seconds")
+	resumeCatchUpRate, _ := broker.GetKey("resume-catch-up-rate-per-second")
+	maxPayloadSize, _ := broker.GetKey("max-payload-size-in-bytes")
+	maxBatchSize, _ := broker.GetKey("max-batch-size-in-bytes")
+	maxPublishWaitInSecs, _ := broker.GetKey("max-publish-wait-in-seconds")
+	receiptEvents, _ := broker.GetKey("receipt-events")
+	purgeRetentionInHours, _ := broker.GetKey("purge-retention-in-hours")
+	enforcePublishGrants, _ := broker.GetKey("enforce-publish-grants")
 	configuration.MaxMessageQueueSize = maxMsgQueueSize.MustUint(100000)
 	configuration.MaxWorkers = maxWorkers.MustUint(100)
 	configuration.PriorityDispatcherEnabled = priorityDispatcher.MustBool(false)
@@ -529,6 +726,21 @@ func setupBrokerConfiguration(cfg *ini.File, configuration *Config) {
 	configuration.RetriggerBaseEndpoint = retriggerBaseEndpoint.MustString("")
 	configuration.MaxRetry = uint8(maxRetry.MustUint(10))
 	configuration.RationalDelay = time.Duration(rationalDelayInSecs.MustUint(30)) * time.Second
+	configuration.ResumeCatchUpRate = resumeCatchUpRate.MustUint(50)
+	configuration.MaxPayloadSize = maxPayloadSize.MustUint(16777215)
+	configuration.MaxBatchSize = maxBatchSize.MustUint(33554432)
+	configuration.MaxPublishWait = time.Duration(maxPublishWaitInSecs.MustUint(30)) * time.Second
+	configuration.PurgeRetention = time.Duration(purgeRetentionInHours.MustUint(168)) * time.Hour
+	configuration.PublishGrantEnforced = enforcePublishGrants.MustBool(false)
+	configuration.ReceiptOnDelivered, configuration.ReceiptOnDead = false, false
+	for _, receiptEvent := range strings.Split(receiptEvents.MustString("delivered,dead"), ",") {
+		switch strings.ToLower(strings.TrimSpace(receiptEvent)) {
+		case "delivered":
+			configuration.ReceiptOnDelivered = true
+		case "dead":
+			configuration.ReceiptOnDead = true
+		}
+	}
 	backoffDelayStrings := strings.Split(retryBackoffDelayInSecs.MustString("15"), ",")
 	var backoffDelays []time.Duration = make([]time.Duration, 0, len(backoffDelayStrings))
 	for _, backoffDelayString := range backoffDelayStrings {
@@ -540,3 +752,38 @@ func setupBrokerConfiguration(cfg *ini.File, configuration *Config) {
 	}
 	configuration.RetryBackoffDelays = backoffDelays
 }
+
+func setupBlobStoreConfiguration(cfg *ini.File, configuration *Config) {
+	blobStoreSection, _ := cfg.GetSection("blob-store")
+	providerKey, _ := blobStoreSection.GetKey("provider")
+	fileSystemPathKey, _ := blobStoreSection.GetKey("filesystem-path")
+	offloadThresholdKey, _ := blobStoreSection.GetKey("offload-threshold-in-bytes")
+	switch BlobStoreProvider(strings.ToLower(providerKey.MustString(string(NoBlobStoreProvider)))) {
+	case FileSystemBlobStoreProvider:
+		configuration.BlobStoreProvider = FileSystemBlobStoreProvider
+	default:
+		configuration.BlobStoreProvider = NoBlobStoreProvider
+	}
+	configuration.BlobStoreFileSystemPath = fileSystemPathKey.MustString("webhook-broker-blobs")
+	configuration.PayloadOffloadThreshold = offloadThresholdKey.MustUint(1048576)
+}
+
+func setupRateLimitConfiguration(cfg *ini.File, configuration *Config) {
+	rateLimitSection, _ := cfg.GetSection("rate-limit")
+	getRateLimit := func(prefix string) PublishRateLimit {
+		messagesPerSecondKey, _ := rateLimitSection.GetKey(prefix + "-messages-per-second")
+		burstKey, _ := rateLimitSection.GetKey(prefix + "-burst")
+		bytesPerMinuteKey, _ := rateLimitSection.GetKey(prefix + "-bytes-per-minute")
+		return PublishRateLimit{MessagesPerSecond: messagesPerSecondKey.MustUint(0), Burst: burstKey.MustUint(0), BytesPerMinute: bytesPerMinuteKey.MustUint(0)}
+	}
+	configuration.ProducerRateLimit = getRateLimit("producer")
+	configuration.ChannelRateLimit = getRateLimit("channel")
+}
+
+func setupAdminConfiguration(cfg *ini.File, configuration *Config) {
+	adminSection, _ := cfg.GetSection("admin")
+	usernameKey, _ := adminSection.GetKey("username")
+	passwordKey, _ := adminSection.GetKey("password")
+	configuration.AdminUsername = usernameKey.MustString("admin")
+	configuration.AdminPassword = passwordKey.MustString("")
+}
@@ -23,10 +23,15 @@ const (
 	connxn-max-lifetime-seconds=ascx0x
 	max-idle-connxns=as30
 	max-open-connxns=-100
+	payload-compression-codec=lz4
+	payload-compression-threshold-in-bytes=-1
 	[http]
 	listener=:7050
 	read-timeout=asd240
 	write-timeout=zf240
+	[grpc]
+	enabled=sure
+	listener=:7051
 	[log]
 	filename=/var/log/webhook-broker.log
 	max-file-size-in-mb=as200
@@ -44,12 +49,37 @@ const (
 	rational-delay-in-seconds=2sd0
 	retry-backoff-delays-in-seconds=5,30,asd 6a 
 	recovery-workers-enabled=random
+	resume-catch-up-rate-per-second=fast
+	max-payload-size-in-bytes=huge
+	max-batch-size-in-bytes=big
+	max-publish-wait-in-seconds=long
+	receipt-events=sometimes
+	purge-retention-in-hours=forever
+	enforce-publish-grants=always
+
+	[blob-store]
+	provider=s3
+	filesystem-path=/var/lib/webhook-broker/blobs
+	offload-threshold-in-bytes=1mb
+
+	[rate-limit]
+	producer-messages-per-second=ten
+	producer-burst=-5
+	producer-bytes-per-minute=1mb
+	channel-messages-per-second=100
+	channel-burst=many
+	channel-bytes-per-minute=
+
+	[admin]
+	username=
+	password=
 
 	# Generic consumer configuration such as - Token Header name, User Agent, Consumer connection timeout
 	[consumer-connection]
 	token-header-name=
 	user-agent=
 	connection-timeout-in-seconds=a d3d0
+	callback-verification-enabled=random
 
 	# Preemptive Channel, Producer, Consumer setup
 	[initial-channels]
@@ -100,9 +130,13 @@ func TestGetAutoConfiguration_Default(t *testing.T) {
 	assert.Equal(t, time.Duration(0), config.GetDBConnectionMaxLifetime())
 	assert.Equal(t, uint16(30), config.GetMaxIdleDBConnections())
 	assert.Equal(t, uint16(100), config.GetMaxOpenDBConnections())
+	assert.Equal(t, NoPayloadCompression, config.GetPayloadCompressionCodec())
+	assert.Equal(t, uint(4096), config.GetPayloadCompressionThreshold())
 	assert.Equal(t, ":7050", config.GetHTTPListeningAddr())
 	assert.Equal(t, toSecond(uint(240)), config.GetHTTPReadTimeout())
 	assert.Equal(t, toSecond(uint(240)), config.GetHTTPWriteTimeout())
+	assert.False(t, config.IsGRPCEnabled())
+	assert.Equal(t, ":9090", config.GetGRPCListeningAddr())
 	assert.Equal(t, "", config.GetLogFilename())
 	assert.Equal(t, Debug, config.GetLogLevel())
 	assert.Equal(t, uint(200), config.GetMaxLogFileSize())
@@ -129,9 +163,25 @@ func TestGetAutoConfiguration_Default(t *testing.T) {
 	assert.Equal(t, "sample-consumer-token", seedConsumer.Token)
 	assert.Equal(t, "http://sample-endpoint/webhook-receiver", seedConsumer.CallbackURL.String())
 	assert.Equal(t, "sample-channel", seedConsumer.Channel)
+	assert.Equal(t, []SeedPublishGrant{{ProducerID: "sample-producer", ChannelID: "sample-channel"}}, seedData.PublishGrants)
 	assert.Equal(t, "Webhook Message Broker", config.GetUserAgent())
 	assert.Equal(t, "X-Broker-Consumer-Token", config.GetTokenRequestHeaderName())
 	assert.Equal(t, toSecond(30), config.GetConnectionTimeout())
+	assert.False(t, config.IsCallbackVerificationEnabled())
+	assert.Equal(t, uint(50), config.GetResumeCatchUpRate())
+	assert.Equal(t, uint(16777215), config.GetMaxPayloadSize())
+	assert.Equal(t, uint(33554432), config.GetMaxBatchSize())
+	assert.Equal(t, toSecond(30), config.GetMaxPublishWait())
+	assert.Equal(t, 168*time.Hour, config.GetPurgeRetention())
+	assert.False(t, config.IsPublishGrantEnforced())
+	assert.True(t, config.IsReceiptOnDeliveredEnabled())
+	assert.True(t, config.IsReceiptOnDeadEnabled())
+	assert.Equal(t, NoBlobStoreProvider, config.GetBlobStoreProvider())
+	assert.Equal(t, uint(1048576), config.GetPayloadOffloadThreshold())
+	assert.Equal(t, PublishRateLimit{}, config.GetProducerRateLimit())
+	assert.Equal(t, PublishRateLimit{}, config.GetChannelRateLimit())
+	assert.False(t, config.IsAdminConsoleEnabled())
+	assert.Equal(t, "admin", config.GetAdminUsername())
 	assert.Equal(t, uint(10000), config.GetMaxMessageQueueSize())
 	assert.Equal(t, uint(200), config.GetMaxWorkers())
 	assert.Equal(t, true, config.IsPriorityDispatcherEnabled())
@@ -155,9 +205,13 @@ func TestGetAutoConfiguration_WrongValues(t *testing.T) {
 	assert.Equal(t, time.Duration(0), config.GetDBConnectionMaxLifetime())
 	assert.Equal(t, uint16(10), config.GetMaxIdleDBConnections())
 	assert.Equal(t, uint16(50), config.GetMaxOpenDBConnections())
+	assert.Equal(t, NoPayloadCompression, config.GetPayloadCompressionCodec())
+	assert.Equal(t, uint(4096), config.GetPayloadCompressionThreshold())
 	assert.Equal(t, ":7050", config.GetHTTPListeningAddr())
 	assert.Equal(t, toSecond(uint(180)), config.GetHTTPReadTimeout())
 	assert.Equal(t, toSecond(uint(180)), config.GetHTTPWriteTimeout())
+	assert.False(t, config.IsGRPCEnabled())
+	assert.Equal(t, ":7051", config.GetGRPCListeningAddr())
 	assert.Equal(t, "/var/log/webhook-broker.log", config.GetLogFilename())
 	assert.Equal(t, Debug, config.GetLogLevel())
 	assert.Equal(t, uint(50), config.GetMaxLogFileSize())
@@ -168,6 +222,21 @@ func TestGetAutoConfiguration_WrongValues(t *testing.T) {
 	assert.Equal(t, "Webhook Message Broker", config.GetUserAgent())
 	assert.Equal(t, "X-Broker-Consumer-Token", config.GetTokenRequestHeaderName())
 	assert.Equal(t, toSecond(60), config.GetConnectionTimeout())
+	assert.False(t, config.IsCallbackVerificationEnabled())
+	assert.Equal(t, uint(50), config.GetResumeCatchUpRate())
+	assert.Equal(t, uint(16777215), config.GetMaxPayloadSize())
+	assert.Equal(t, uint(33554432), config.GetMaxBatchSize())
+	assert.Equal(t, toSecond(30), config.GetMaxPublishWait())
+	assert.Equal(t, 168*time.Hour, config.GetPurgeRetention())
+	assert.False(t, config.IsPublishGrantEnforced())
+	assert.False(t, config.IsReceiptOnDeliveredEnabled())
+	assert.False(t, config.IsReceiptOnDeadEnabled())
+	assert.Equal(t, NoBlobStoreProvider, config.GetBlobStoreProvider())
+	assert.Equal(t, uint(1048576), config.GetPayloadOffloadThreshold())
+	assert.Equal(t, PublishRateLimit{}, config.GetProducerRateLimit())
+	assert.Equal(t, PublishRateLimit{MessagesPerSecond: 100}, config.GetChannelRateLimit())
+	assert.False(t, config.IsAdminConsoleEnabled())
+	assert.Equal(t, "admin", config.GetAdminUsername())
 	assert.Equal(t, uint(100000), config.GetMaxMessageQueueSize())
 	assert.Equal(t, uint(100), config.GetMaxWorkers())
 	assert.Equal(t, false, config.IsPriorityDispatcherEnabled())
@@ -222,9 +291,13 @@ func TestGetConfiguration(t *testing.T) {
 	assert.Equal(t, toSecond(10), config.GetDBConnectionMaxLifetime())
 	assert.Equal(t, uint16(300), config.GetMaxIdleDBConnections())
 	assert.Equal(t, uint16(1000), config.GetMaxOpenDBConnections())
+	assert.Equal(t, ZstdPayloadCompression, config.GetPayloadCompressionCodec())
+	assert.Equal(t, uint(1024), config.GetPayloadCompressionThreshold())
 	assert.Equal(t, ":7080", config.GetHTTPListeningAddr())
 	assert.Equal(t, toSecond(uint(2401)), config.GetHTTPReadTimeout())
 	assert.Equal(t, toSecond(uint(2401)), config.GetHTTPWriteTimeout())
+	assert.True(t, config.IsGRPCEnabled())
+	assert.Equal(t, ":7090", config.GetGRPCListeningAddr())
 	assert.Equal(t, "/var/log/webhook-broker.log", config.GetLogFilename())
 	assert.Equal(t, Error, config.GetLogLevel())
 	assert.Equal(t, uint(20), config.GetMaxLogFileSize())
@@ -276,9 +349,28 @@ func TestGetConfiguration(t *testing.T) {
 	assert.Equal(t, "test-consumer4", seedConsumer.Name)
 	assert.Equal(t, "", seedConsumer.Token)
 	assert.Equal(t, "http://imy13.us/webhook-receiver1", seedConsumer.CallbackURL.String())
+	assert.Equal(t, []SeedPublishGrant{{ProducerID: "sample-producer", ChannelID: "sample-channel"}, {ProducerID: "test-producer", ChannelID: "test-channel"},
+		{ProducerID: "test-producer", ChannelID: "test-channel2"}, {ProducerID: "test-producer2", ChannelID: "test-channel2"}}, seedData.PublishGrants)
 	assert.Equal(t, "Test User Agent", config.GetUserAgent())
 	assert.Equal(t, "X-Test-Consumer-Token", config.GetTokenRequestHeaderName())
 	assert.Equal(t, toSecond(300), config.GetConnectionTimeout())
+	assert.True(t, config.IsCallbackVerificationEnabled())
+	assert.Equal(t, uint(0), config.GetResumeCatchUpRate())
+	assert.Equal(t, uint(1024), config.GetMaxPayloadSize())
+	assert.Equal(t, uint(4096), config.GetMaxBatchSize())
+	assert.Equal(t, toSecond(10), config.GetMaxPublishWait())
+	assert.Equal(t, 24*time.Hour, config.GetPurgeRetention())
+	assert.True(t, config.IsPublishGrantEnforced())
+	assert.False(t, config.IsReceiptOnDeliveredEnabled())
+	assert.True(t, config.IsReceiptOnDeadEnabled())
+	assert.Equal(t, FileSystemBlobStoreProvider, config.GetBlobStoreProvider())
+	assert.Equal(t, "/tmp/webhook-broker-blobs", config.GetBlobStoreFileSystemPath())
+	assert.Equal(t, uint(512), config.GetPayloadOffloadThreshold())
+	assert.Equal(t, PublishRateLimit{MessagesPerSecond: 10, Burst: 20, BytesPerMinute: 1048576}, config.GetProducerRateLimit())
+	assert.Equal(t, PublishRateLimit{MessagesPerSecond: 100}, config.GetChannelRateLimit())
+	assert.True(t, config.IsAdminConsoleEnabled())
+	assert.Equal(t, "operator", config.GetAdminUsername())
+	assert.Equal(t, "operator-password", config.GetAdminPassword())
 	assert.Equal(t, uint(20000), config.GetMaxMessageQueueSize())
 	assert.Equal(t, uint(250), config.GetMaxWorkers())
 	assert.Equal(t, true, config.IsPriorityDispatcherEnabled())
@@ -404,6 +496,23 @@ func TestGetConfigurationFromParseConfig_ValueError(t *testing.T) {
 			assert.NotNil(t, err)
 		}
 	})
+	t.Run("GRPCListenerNotAvailable", func(t *testing.T) {
+		t.Parallel()
+		testConfig := `
+		[http]
+		listener=:47080
+		[grpc]
+		enabled=true
+		listener=:47090
+		`
+		ln, netErr := net.Listen("tcp", ":47090")
+		if netErr == nil {
+			defer ln.Close()
+			config, err := GetConfigurationFromParseConfig(loadTestConfiguration(testConfig))
+			assert.Equal(t, EmptyConfigurationForError, config)
+			assert.NotNil(t, err)
+		}
+	})
 	t.Run("DBPingErrorSQLite3", func(t *testing.T) {
 		t.Parallel()
 		db, mock, _ := sqlmock.New()
@@ -480,6 +589,9 @@ func TestGetVersion(t *testing.T) {
 func TestConfigInterfaces(t *testing.T) {
 	var _ RelationalDatabaseConfig = (*Config)(nil)
 	var _ HTTPConfig = (*Config)(nil)
+	var _ GRPCConfig = (*Config)(nil)
+	var _ RateLimitConfig = (*Config)(nil)
+	var _ AdminConfig = (*Config)(nil)
 	var _ LogConfig = (*Config)(nil)
 	var _ SeedDataConfig = (*Config)(nil)
 	var _ ConsumerConnectionConfig = (*Config)(nil)
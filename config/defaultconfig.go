@@ -8,10 +8,15 @@ connxn-max-idle-time-seconds=0
 connxn-max-lifetime-seconds=0
 max-idle-connxns=30
 max-open-connxns=100
+payload-compression-codec=none
+payload-compression-threshold-in-bytes=4096
 [http]
 listener=:8080
 read-timeout=240
 write-timeout=240
+[grpc]
+enabled=false
+listener=:9090
 [log]
 filename=
 max-file-size-in-mb=200
@@ -28,10 +33,32 @@ max-retry=5
 rational-delay-in-seconds=2
 retry-backoff-delays-in-seconds=5,30,60
 recovery-workers-enabled=true
+resume-catch-up-rate-per-second=50
+max-payload-size-in-bytes=16777215
+max-batch-size-in-bytes=33554432
+max-publish-wait-in-seconds=30
+receipt-events=delivered,dead
+purge-retention-in-hours=168
+enforce-publish-grants=false
+[blob-store]
+provider=none
+filesystem-path=webhook-broker-blobs
+offload-threshold-in-bytes=1048576
+[rate-limit]
+producer-messages-per-second=0
+producer-burst=0
+producer-bytes-per-minute=0
+channel-messages-per-second=0
+channel-burst=0
+channel-bytes-per-minute=0
+[admin]
+username=admin
+password=
 [consumer-connection]
 token-header-name=X-Broker-Consumer-Token
 user-agent=Webhook Message Broker
 connection-timeout-in-seconds=30
+callback-verification-enabled=false
 [initial-channels]
 sample-channel=Sample Channel
 [initial-producers]
@@ -42,6 +69,8 @@ sample-consumer=http://sample-endpoint/webhook-receiver
 sample-channel=sample-channel-token
 [initial-producer-tokens]
 sample-producer=sample-producer-token
+[initial-producer-channels]
+sample-producer=sample-channel
 [sample-consumer]
 token=sample-consumer-token
 channel=sample-channel
@@ -27,6 +27,48 @@ func (_m *BrokerConfig) GetMaxMessageQueueSize() uint {
 	return r0
 }
 
+// GetMaxPayloadSize provides a mock function with given fields:
+func (_m *BrokerConfig) GetMaxPayloadSize() uint {
+	ret := _m.Called()
+
+	var r0 uint
+	if rf, ok := ret.Get(0).(func() uint); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(uint)
+	}
+
+	return r0
+}
+
+// GetMaxBatchSize provides a mock function with given fields:
+func (_m *BrokerConfig) GetMaxBatchSize() uint {
+	ret := _m.Called()
+
+	var r0 uint
+	if rf, ok := ret.Get(0).(func() uint); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(uint)
+	}
+
+	return r0
+}
+
+// GetMaxPublishWait provides a mock function with given fields:
+func (_m *BrokerConfig) GetMaxPublishWait() time.Duration {
+	ret := _m.Called()
+
+	var r0 time.Duration
+	if rf, ok := ret.Get(0).(func() time.Duration); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	return r0
+}
+
 // GetMaxRetry provides a mock function with given fields:
 func (_m *BrokerConfig) GetMaxRetry() uint8 {
 	ret := _m.Called()
@@ -55,6 +97,20 @@ func (_m *BrokerConfig) GetMaxWorkers() uint {
 	return r0
 }
 
+// GetPurgeRetention provides a mock function with given fields:
+func (_m *BrokerConfig) GetPurgeRetention() time.Duration {
+	ret := _m.Called()
+
+	var r0 time.Duration
+	if rf, ok := ret.Get(0).(func() time.Duration); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	return r0
+}
+
 // GetRationalDelay provides a mock function with given fields:
 func (_m *BrokerConfig) GetRationalDelay() time.Duration {
 	ret := _m.Called()
@@ -69,6 +125,20 @@ func (_m *BrokerConfig) GetRationalDelay() time.Duration {
 	return r0
 }
 
+// GetResumeCatchUpRate provides a mock function with given fields:
+func (_m *BrokerConfig) GetResumeCatchUpRate() uint {
+	ret := _m.Called()
+
+	var r0 uint
+	if rf, ok := ret.Get(0).(func() uint); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(uint)
+	}
+
+	return r0
+}
+
 // GetRetriggerBaseEndpoint provides a mock function with given fields:
 func (_m *BrokerConfig) GetRetriggerBaseEndpoint() string {
 	ret := _m.Called()
@@ -113,6 +183,48 @@ func (_m *BrokerConfig) IsPriorityDispatcherEnabled() bool {
 	return r0
 }
 
+// IsPublishGrantEnforced provides a mock function with given fields:
+func (_m *BrokerConfig) IsPublishGrantEnforced() bool {
+	ret := _m.Called()
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// IsReceiptOnDeadEnabled provides a mock function with given fields:
+func (_m *BrokerConfig) IsReceiptOnDeadEnabled() bool {
+	ret := _m.Called()
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// IsReceiptOnDeliveredEnabled provides a mock function with given fields:
+func (_m *BrokerConfig) IsReceiptOnDeliveredEnabled() bool {
+	ret := _m.Called()
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // IsRecoveryWorkersEnabled provides a mock function with given fields:
 func (_m *BrokerConfig) IsRecoveryWorkersEnabled() bool {
 	ret := _m.Called()
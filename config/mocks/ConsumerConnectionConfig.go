@@ -54,3 +54,17 @@ func (_m *ConsumerConnectionConfig) GetUserAgent() string {
 
 	return r0
 }
+
+// IsCallbackVerificationEnabled provides a mock function with given fields:
+func (_m *ConsumerConnectionConfig) IsCallbackVerificationEnabled() bool {
+	ret := _m.Called()
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
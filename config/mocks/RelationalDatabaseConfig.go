@@ -97,3 +97,31 @@ func (_m *RelationalDatabaseConfig) GetMaxOpenDBConnections() uint16 {
 
 	return r0
 }
+
+// GetPayloadCompressionCodec provides a mock function with given fields:
+func (_m *RelationalDatabaseConfig) GetPayloadCompressionCodec() config.PayloadCompressionCodec {
+	ret := _m.Called()
+
+	var r0 config.PayloadCompressionCodec
+	if rf, ok := ret.Get(0).(func() config.PayloadCompressionCodec); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(config.PayloadCompressionCodec)
+	}
+
+	return r0
+}
+
+// GetPayloadCompressionThreshold provides a mock function with given fields:
+func (_m *RelationalDatabaseConfig) GetPayloadCompressionThreshold() uint {
+	ret := _m.Called()
+
+	var r0 uint
+	if rf, ok := ret.Get(0).(func() uint); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(uint)
+	}
+
+	return r0
+}
@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/newscred/webhook-broker/config"
+)
+
+const (
+	adminConsoleBasePath            = "/_admin/"
+	adminConsoleFilePathParamKey    = "filepath"
+	adminConsolePath                = adminConsoleBasePath + "*" + adminConsoleFilePathParamKey
+	headerWWWAuthenticate           = "WWW-Authenticate"
+	headerContentSecurityPolicy     = "Content-Security-Policy"
+	adminConsoleAuthenticateValue   = `Basic realm="Webhook Broker Admin", charset="UTF-8"`
+	adminConsoleSecurityPolicyValue = "default-src 'self'; frame-ancestors 'none'"
+)
+
+// adminConsoleAssets are the static files of the admin console; it is a plain HTML and JavaScript client of the HTTP API, so it needs no
+// build step and loads nothing from outside the broker
+//
+//go:embed admin
+var adminConsoleAssets embed.FS
+
+// AdminConsoleController represents the endpoint serving the web admin console to the admin signed in with basic authentication
+type AdminConsoleController struct {
+	AdminConfig config.AdminConfig
+	fileServer  http.Handler
+}
+
+// NewAdminConsoleController creates and returns a new instance of AdminConsoleController
+func NewAdminConsoleController(adminConfig config.AdminConfig) *AdminConsoleController {
+	assets, err := fs.Sub(adminConsoleAssets, "admin")
+	if err != nil {
+		panic(err)
+	}
+	return &AdminConsoleController{AdminConfig: adminConfig, fileServer: http.FileServer(http.FS(assets))}
+}
+
+func (controller *AdminConsoleController) isAdmin(r *http.Request) bool {
+	username, password, ok := r.BasicAuth()
+	// Both are compared irrespective of the other to not leak which one is wrong by the time taken
+	usernameMatch := subtle.ConstantTimeCompare([]byte(username), []byte(controller.AdminConfig.GetAdminUsername()))
+	passwordMatch := subtle.ConstantTimeCompare([]byte(password), []byte(controller.AdminConfig.GetAdminPassword()))
+	return ok && usernameMatch&passwordMatch == 1
+}
+
+// Get implements the GET /_admin/*filepath endpoint; not found unless the admin console is enabled
+func (controller *AdminConsoleController) Get(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	if !controller.AdminConfig.IsAdminConsoleEnabled() {
+		writeNotFound(w)
+		return
+	}
+	if !controller.isAdmin(r) {
+		w.Header().Set(headerWWWAuthenticate, adminConsoleAuthenticateValue)
+		writeStatus(w, http.StatusUnauthorized, ErrAdminUnauthorized)
+		return
+	}
+	w.Header().Set(headerContentSecurityPolicy, adminConsoleSecurityPolicyValue)
+	assetRequest := r.Clone(r.Context())
+	assetRequest.URL.Path = findParam(params, adminConsoleFilePathParamKey)
+	assetRequest.URL.RawPath = ""
+	controller.fileServer.ServeHTTP(w, assetRequest)
+}
+
+// GetPath returns the endpoint's path
+func (controller *AdminConsoleController) GetPath() string {
+	return adminConsolePath
+}
+
+// FormatAsRelativeLink Format as relative URL of this resource based on the params
+func (controller *AdminConsoleController) FormatAsRelativeLink(params ...httprouter.Param) string {
+	return adminConsoleBasePath
+}
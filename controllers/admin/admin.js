@@ -0,0 +1,366 @@
+// Webhook Broker admin console; it is a client of the broker's HTTP API routed by the URL hash, which mirrors the API paths, e.g.
+// `#/channel/sample-channel` shows what `GET /channel/sample-channel` returns
+(function () {
+  'use strict';
+
+  var statsRefreshIntervalMillis = 5000;
+  var view = document.getElementById('view');
+  var errorBanner = document.getElementById('error');
+  var timers = [];
+
+  // el creates an element with the attributes and children, where a string child is a text node so API data is never parsed as HTML
+  function el(tag, attrs) {
+    var element = document.createElement(tag);
+    Object.keys(attrs || {}).forEach(function (name) {
+      element.setAttribute(name, attrs[name]);
+    });
+    appendChildren(element, Array.prototype.slice.call(arguments, 2));
+    return element;
+  }
+
+  function appendChildren(element, children) {
+    children.forEach(function (child) {
+      if (child === null || child === undefined) {
+        return;
+      }
+      if (Array.isArray(child)) {
+        appendChildren(element, child);
+      } else if (child instanceof Node) {
+        element.appendChild(child);
+      } else {
+        element.appendChild(document.createTextNode(String(child)));
+      }
+    });
+  }
+
+  function replaceChildren(element) {
+    while (element.firstChild) {
+      element.removeChild(element.firstChild);
+    }
+    appendChildren(element, Array.prototype.slice.call(arguments, 1));
+  }
+
+  function showError(err) {
+    errorBanner.textContent = err.message || String(err);
+    errorBanner.classList.remove('hidden');
+  }
+
+  function clearError() {
+    errorBanner.textContent = '';
+    errorBanner.classList.add('hidden');
+  }
+
+  // api calls the broker's HTTP API with the form, if any, URL encoded; resolves to the parsed JSON or text of a successful response
+  function api(method, path, form) {
+    var init = {method: method, credentials: 'same-origin', headers: {}};
+    if (form) {
+      init.headers['Content-Type'] = 'application/x-www-form-urlencoded';
+      init.body = new URLSearchParams(form).toString();
+    }
+    return fetch(path, init).then(function (response) {
+      return response.text().then(function (body) {
+        if (!response.ok) {
+          throw new Error(method + ' ' + path + ' failed with ' + response.status + (body ? ': ' + body : ''));
+        }
+        var contentType = response.headers.get('Content-Type') || '';
+        return contentType.indexOf('application/json') === 0 ? JSON.parse(body) : body;
+      });
+    });
+  }
+
+  function apiPath() {
+    return '/' + Array.prototype.slice.call(arguments).map(encodeURIComponent).join('/');
+  }
+
+  function lastSegment(url) {
+    var segments = url.split('?')[0].split('/');
+    return decodeURIComponent(segments[segments.length - 1]);
+  }
+
+  function link(url, text) {
+    return el('a', {href: '#' + url}, text || lastSegment(url));
+  }
+
+  function formatTime(value) {
+    if (!value || value.indexOf('0001-01-01') === 0) {
+      return '-';
+    }
+    return new Date(value).toLocaleString();
+  }
+
+  function formatDuration(seconds) {
+    if (seconds < 60) {
+      return seconds.toFixed(1) + 's';
+    }
+    if (seconds < 3600) {
+      return (seconds / 60).toFixed(1) + 'm';
+    }
+    return (seconds / 3600).toFixed(1) + 'h';
+  }
+
+  function section(title) {
+    return el('section', null, el('h2', null, title), Array.prototype.slice.call(arguments, 1));
+  }
+
+  function table(headers, rows) {
+    if (rows.length === 0) {
+      return el('p', {class: 'muted'}, 'None');
+    }
+    return el('table', null,
+      el('thead', null, el('tr', null, headers.map(function (header) {
+        return el('th', null, header);
+      }))),
+      el('tbody', null, rows.map(function (row) {
+        return el('tr', null, row.map(function (cell) {
+          return el('td', null, cell);
+        }));
+      })));
+  }
+
+  function detailsTable(details) {
+    return table(['Field', 'Value'], Object.keys(details).map(function (name) {
+      return [name, details[name]];
+    }));
+  }
+
+  // paginatedList renders the list at the path with previous and next buttons following the `Pages` links of the API
+  function paginatedList(path, render) {
+    var container = el('div', null, el('p', {class: 'muted'}, 'Loading...'));
+    function load(pagePath) {
+      api('GET', pagePath).then(function (result) {
+        var pages = el('div', {class: 'pages'});
+        ['previous', 'next'].forEach(function (page) {
+          if (result.Pages && result.Pages[page]) {
+            var button = el('button', {type: 'button'}, page === 'previous' ? '< Previous' : 'Next >');
+            button.addEventListener('click', function () {
+              load(result.Pages[page]);
+            });
+            pages.appendChild(button);
+          }
+        });
+        replaceChildren(container, render(result), pages);
+      }).catch(showError);
+    }
+    load(path);
+    return container;
+  }
+
+  // form renders the fields, each an input or, if it has options, a select, and submits their values to the callback
+  function form(fields, submitLabel, onSubmit) {
+    var inputs = {};
+    var element = el('form', null, fields.map(function (field) {
+      var input;
+      if (field.options) {
+        input = el('select', {name: field.name, title: field.label}, field.options.map(function (option) {
+          return el('option', {value: option}, option || field.label);
+        }));
+      } else {
+        input = el('input', {name: field.name, type: field.type || 'text', placeholder: field.label, title: field.label});
+        if (field.required) {
+          input.setAttribute('required', 'required');
+        }
+      }
+      inputs[field.name] = input;
+      return input;
+    }), el('button', {type: 'submit'}, submitLabel));
+    element.addEventListener('submit', function (event) {
+      event.preventDefault();
+      var values = {};
+      Object.keys(inputs).forEach(function (name) {
+        var input = inputs[name];
+        var value = input.type === 'checkbox' ? (input.checked ? 'true' : '') : input.value;
+        if (value) {
+          values[name] = value;
+        }
+      });
+      clearError();
+      onSubmit(values);
+    });
+    return element;
+  }
+
+  // liveStats renders the job stats at the path, refreshing them until the view changes
+  function liveStats(path) {
+    var container = el('div', {class: 'stats'}, el('span', {class: 'muted'}, 'Loading...'));
+    var computedAt = el('p', {class: 'muted'});
+    function stat(label, value) {
+      return el('div', {class: 'stat'}, el('div', {class: 'muted'}, label), el('div', {class: 'value'}, value));
+    }
+    function refresh() {
+      api('GET', path).then(function (stats) {
+        var items = [];
+        Object.keys(stats.JobCounts).sort().forEach(function (status) {
+          items.push(stat(status + ' jobs', stats.JobCounts[status]));
+        });
+        items.push(stat('Oldest queued job age', formatDuration(stats.OldestQueuedJobAgeSeconds)));
+        Object.keys(stats.DeliveredPerMinute).sort(function (first, second) {
+          return parseInt(first, 10) - parseInt(second, 10);
+        }).forEach(function (statsWindow) {
+          items.push(stat('Delivered per minute (' + statsWindow + ')', stats.DeliveredPerMinute[statsWindow].toFixed(2)));
+        });
+        items.push(stat('Success rate (' + stats.SampledJobCount + ' jobs)', (stats.SuccessRate * 100).toFixed(1) + '%'));
+        items.push(stat('Average latency', stats.AverageLatencyMillis + 'ms'));
+        items.push(stat('Next retry', stats.NextRetryAt ? formatTime(stats.NextRetryAt) : '-'));
+        replaceChildren(container, items);
+        replaceChildren(computedAt, 'Computed at ' + formatTime(stats.ComputedAt) + ', refreshed every ' +
+          statsRefreshIntervalMillis / 1000 + ' seconds');
+      }).catch(showError);
+    }
+    refresh();
+    timers.push(setInterval(refresh, statsRefreshIntervalMillis));
+    return [container, computedAt];
+  }
+
+  function stakeholderList(result) {
+    return table(['ID'], result.Result.map(function (url) {
+      return [link(url)];
+    }));
+  }
+
+  function renderHome() {
+    replaceChildren(view,
+      section('Channels', paginatedList('/channels', stakeholderList),
+        form([{name: 'id', label: 'Channel ID', required: true}, {name: 'name', label: 'Name'}, {name: 'token', label: 'Token'}],
+          'Create channel', function (values) {
+            api('PUT', apiPath('channel', values.id), {name: values.name || '', token: values.token || ''}).then(render).catch(showError);
+          })),
+      section('Producers', paginatedList('/producers', stakeholderList),
+        form([{name: 'id', label: 'Producer ID', required: true}, {name: 'name', label: 'Name'}, {name: 'token', label: 'Token'}],
+          'Create producer', function (values) {
+            api('PUT', apiPath('producer', values.id), {name: values.name || '', token: values.token || ''}).then(render).catch(showError);
+          })));
+  }
+
+  function renderProducer(producerID) {
+    var details = el('div');
+    replaceChildren(view, section('Producer ' + producerID, details));
+    api('GET', apiPath('producer', producerID)).then(function (producer) {
+      replaceChildren(details, detailsTable({ID: producer.ID, Name: producer.Name, Token: producer.Token,
+        'Receipt URL': producer.ReceiptURL || '-', 'Changed at': formatTime(producer.ChangedAt)}));
+    }).catch(showError);
+  }
+
+  function messageList(result) {
+    return table(['Message ID', 'Status', 'Received at', 'Jobs'], (result.Messages || []).map(function (message) {
+      var jobCounts = {};
+      (message.Jobs || []).forEach(function (job) {
+        jobCounts[job.Status] = (jobCounts[job.Status] || 0) + 1;
+      });
+      return [link(message.MessageURL, message.MessageID), message.Status, formatTime(message.ReceivedAt),
+        Object.keys(jobCounts).map(function (status) {
+          return status + ': ' + jobCounts[status];
+        }).join(', ') || '-'];
+    }));
+  }
+
+  function renderChannel(channelID) {
+    var details = el('div');
+    var messagesPath = apiPath('channel', channelID, 'messages') + '?expand=jobs';
+    var messages = el('div');
+    function searchMessages(query) {
+      replaceChildren(messages, paginatedList(query ? messagesPath + '&' + new URLSearchParams(query).toString() : messagesPath,
+        function (result) {
+          return messageList(result);
+        }));
+    }
+    replaceChildren(view,
+      section('Channel ' + channelID, details),
+      section('Live stats', liveStats(apiPath('channel', channelID, 'stats'))),
+      section('Consumers', paginatedList(apiPath('channel', channelID, 'consumers'), stakeholderList),
+        form([{name: 'id', label: 'Consumer ID', required: true}, {name: 'name', label: 'Name'}, {name: 'token', label: 'Token'},
+          {name: 'callbackUrl', label: 'Callback URL', type: 'url'}, {name: 'type', label: 'Type', options: ['PUSH', 'STREAM']}],
+        'Create consumer', function (values) {
+          var consumerForm = {name: values.name || '', token: values.token || '', type: values.type};
+          if (values.callbackUrl) {
+            consumerForm.callbackUrl = values.callbackUrl;
+          }
+          api('PUT', apiPath('channel', channelID, 'consumer', values.id), consumerForm).then(render).catch(showError);
+        })),
+      section('Messages',
+        form([{name: 'status', label: 'Any status', options: ['', 'ACKNOWLEDGED', 'DISPATCHED']}, {name: 'messageIdPrefix', label: 'Message ID prefix'},
+          {name: 'producerId', label: 'Producer ID'}, {name: 'hasDeadJobs', label: 'Has dead jobs', options: ['', 'true', 'false']}],
+        'Search', searchMessages),
+        messages));
+    searchMessages(null);
+    api('GET', apiPath('channel', channelID)).then(function (channel) {
+      replaceChildren(details, detailsTable({ID: channel.ID, Name: channel.Name, Token: channel.Token, 'Changed at': formatTime(channel.ChangedAt),
+        'Max payload size': channel.MaxPayloadSize || 'Broker default', 'Broadcast URL': channel.BroadcastURL}));
+    }).catch(showError);
+  }
+
+  function renderConsumer(channelID, consumerID) {
+    var consumerPath = apiPath('channel', channelID, 'consumer', consumerID);
+    var details = el('div');
+    var deadJobs = el('div');
+    replaceChildren(view,
+      el('p', null, link(apiPath('channel', channelID), '< Channel ' + channelID)),
+      section('Consumer ' + consumerID, details),
+      section('Live stats', liveStats(consumerPath + '/stats')),
+      section('Dead letter queue', deadJobs));
+    api('GET', consumerPath).then(function (consumer) {
+      replaceChildren(details, detailsTable({ID: consumer.ID, Name: consumer.Name, Token: consumer.Token, Type: consumer.Type,
+        'Callback URL': consumer.CallbackURL || '-', 'Verification status': consumer.VerificationStatus, Paused: String(consumer.Paused),
+        'Routing key pattern': consumer.RoutingKeyPattern || '-', 'Changed at': formatTime(consumer.ChangedAt)}));
+      var requeueAll = el('button', {type: 'button'}, 'Requeue all');
+      requeueAll.addEventListener('click', function () {
+        clearError();
+        api('POST', consumer.DeadLetterQueueURL, {requeue: consumer.Token}).then(render).catch(showError);
+      });
+      replaceChildren(deadJobs, paginatedList(consumer.DeadLetterQueueURL, function (result) {
+        return table(['Job ID', 'Message', 'Failure reason', 'Attempts', 'Dead since', ''], result.DeadJobs.map(function (job) {
+          var requeue = el('button', {type: 'button'}, 'Requeue');
+          requeue.addEventListener('click', function () {
+            clearError();
+            api('POST', job.JobURL, {requeue: consumer.Token}).then(render).catch(showError);
+          });
+          return [lastSegment(job.JobURL), link(job.MessageURL), el('pre', null, job.FailureReason), job.RetryAttemptCount,
+            formatTime(job.StatusChangedAt), requeue];
+        }));
+      }), requeueAll);
+    }).catch(showError);
+  }
+
+  function renderMessage(channelID, messageID) {
+    var details = el('div');
+    var jobs = el('div');
+    replaceChildren(view,
+      el('p', null, link(apiPath('channel', channelID), '< Channel ' + channelID)),
+      section('Message ' + messageID, details),
+      section('Delivery jobs', jobs));
+    api('GET', apiPath('channel', channelID, 'message', messageID)).then(function (message) {
+      replaceChildren(details, detailsTable({Status: message.Status, 'Produced by': message.ProducedBy, 'Content type': message.ContentType,
+        'Routing key': message.RoutingKey || '-', 'Received at': formatTime(message.ReceivedAt), 'Dispatched at': formatTime(message.DispatchedAt),
+        Payload: el('pre', null, message.PayloadRef ? 'Offloaded to ' + message.PayloadRef : message.Payload)}));
+      replaceChildren(jobs, table(['Consumer', 'Callback URL', 'Status', 'Status changed at'], message.Jobs.map(function (job) {
+        return [job.ListenerName, job.ListenerEndpoint || '-', job.Status, formatTime(job.StatusChangedAt)];
+      })));
+    }).catch(showError);
+  }
+
+  var routes = [
+    {pattern: /^\/channel\/([^/]+)\/consumer\/([^/]+)$/, render: renderConsumer},
+    {pattern: /^\/channel\/([^/]+)\/message\/([^/]+)$/, render: renderMessage},
+    {pattern: /^\/channel\/([^/]+)$/, render: renderChannel},
+    {pattern: /^\/producer\/([^/]+)$/, render: renderProducer}
+  ];
+
+  function render() {
+    timers.forEach(clearInterval);
+    timers = [];
+    var path = window.location.hash.slice(1) || '/';
+    for (var index = 0; index < routes.length; index++) {
+      var match = routes[index].pattern.exec(path);
+      if (match) {
+        routes[index].render.apply(null, match.slice(1).map(decodeURIComponent));
+        return;
+      }
+    }
+    renderHome();
+  }
+
+  window.addEventListener('hashchange', function () {
+    clearError();
+    render();
+  });
+  render();
+})();
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	configmocks "github.com/newscred/webhook-broker/config/mocks"
+)
+
+func getAdminConsoleTestRouter(password string) http.Handler {
+	adminConfig := new(configmocks.AdminConfig)
+	adminConfig.On("IsAdminConsoleEnabled").Return(len(password) > 0)
+	adminConfig.On("GetAdminUsername").Return("admin")
+	adminConfig.On("GetAdminPassword").Return(password)
+	return createTestRouter(NewAdminConsoleController(adminConfig))
+}
+
+func getAdminConsoleTestRequest(path, username, password string) *http.Request {
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	if len(username) > 0 {
+		req.SetBasicAuth(username, password)
+	}
+	return req
+}
+
+func TestAdminConsoleController(t *testing.T) {
+	controller := NewAdminConsoleController(new(configmocks.AdminConfig))
+	assert.Equal(t, adminConsolePath, controller.GetPath())
+	assert.Equal(t, "/_admin/", controller.FormatAsRelativeLink())
+	testRouter := getAdminConsoleTestRouter("admin-password")
+	t.Run("Disabled", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		getAdminConsoleTestRouter("").ServeHTTP(rr, getAdminConsoleTestRequest("/_admin/", "admin", ""))
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+	t.Run("401", func(t *testing.T) {
+		for _, credentials := range [][]string{{"", ""}, {"admin", "wrong-password"}, {"operator", "admin-password"}} {
+			rr := httptest.NewRecorder()
+			testRouter.ServeHTTP(rr, getAdminConsoleTestRequest("/_admin/", credentials[0], credentials[1]))
+			assert.Equal(t, http.StatusUnauthorized, rr.Code, credentials)
+			assert.Equal(t, adminConsoleAuthenticateValue, rr.Header().Get(headerWWWAuthenticate))
+			assert.Equal(t, ErrAdminUnauthorized.Error(), rr.Body.String())
+		}
+	})
+	t.Run("200", func(t *testing.T) {
+		for path, expectedContentType := range map[string]string{"/_admin/": "text/html", "/_admin/admin.js": "javascript", "/_admin/admin.css": "text/css"} {
+			rr := httptest.NewRecorder()
+			testRouter.ServeHTTP(rr, getAdminConsoleTestRequest(path, "admin", "admin-password"))
+			assert.Equal(t, http.StatusOK, rr.Code, path)
+			assert.Contains(t, rr.Header().Get(headerContentType), expectedContentType, path)
+			assert.Equal(t, adminConsoleSecurityPolicyValue, rr.Header().Get(headerContentSecurityPolicy))
+		}
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getAdminConsoleTestRequest("/_admin/", "admin", "admin-password"))
+		body, _ := ioutil.ReadAll(rr.Body)
+		assert.True(t, strings.Contains(string(body), `<script src="admin.js"></script>`))
+		assert.False(t, strings.Contains(string(body), "://"), "must not load anything from outside the broker")
+	})
+	t.Run("404", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getAdminConsoleTestRequest("/_admin/no-such-file.js", "admin", "admin-password"))
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+	t.Run("Redirect", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getAdminConsoleTestRequest("/_admin", "admin", "admin-password"))
+		assert.Equal(t, http.StatusMovedPermanently, rr.Code)
+		assert.Equal(t, "/_admin/", rr.Header().Get(headerLocation))
+	})
+}
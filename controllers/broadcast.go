@@ -1,17 +1,21 @@
 package controllers
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"math"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/rs/zerolog/hlog"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/newscred/webhook-broker/config"
 	"github.com/newscred/webhook-broker/dispatcher"
+	"github.com/newscred/webhook-broker/publish"
 	"github.com/newscred/webhook-broker/storage"
 	"github.com/newscred/webhook-broker/storage/data"
 )
@@ -23,6 +27,9 @@ const (
 	headerProducerToken       = "X-Broker-Producer-Token"
 	headerProducerID          = "X-Broker-Producer-ID"
 	headerMessageID           = "X-Broker-Message-ID"
+	headerRoutingKey          = "X-Broker-Routing-Key"
+	headerAttributePrefix     = "X-Broker-Attr-"
+	headerReceiptURL          = "X-Broker-Receipt-URL"
 	defaultMessageContentType = "application/octet-stream"
 	messageIDLogFieldKey      = "messageId"
 )
@@ -32,22 +39,32 @@ var (
 	errProducerTokenNotMatching = errors.New("producer token does not match")
 	errProducerDoesNotExist     = errors.New("producer could not be found")
 	errBodyCouldNotBeRead       = errors.New("body could not be read")
+	errPayloadTooLarge          = publish.ErrPayloadTooLarge
+	errProducerNotGranted       = errors.New("producer is not granted to publish to the channel")
 )
 
-// BroadcastController receives new Message to broadcasted to a valid channel
+// BroadcastController receives new Message to broadcasted to a valid channel; messages go through the same publish pipeline as the gRPC API's
 type BroadcastController struct {
-	MessageRepository  storage.MessageRepository
-	ChannelRepository  storage.ChannelRepository
-	ProducerRepository storage.ProducerRepository
-	Dispatcher         dispatcher.MessageDispatcher
+	*publish.Publisher
+	ChannelRepository     storage.ChannelRepository
+	ProducerRepository    storage.ProducerRepository
+	DeliveryJobRepository storage.DeliveryJobRepository
+	GrantRepository       storage.PublishGrantRepository
+	Dispatcher            dispatcher.MessageDispatcher
 }
 
 // NewBroadcastController creates a new instance of the controller responsible for broadcasting a message
-func NewBroadcastController(channelRepo storage.ChannelRepository, msgRepo storage.MessageRepository, producerRepo storage.ProducerRepository, dispatcher dispatcher.MessageDispatcher) *BroadcastController {
-	return &BroadcastController{ChannelRepository: channelRepo, MessageRepository: msgRepo, ProducerRepository: producerRepo, Dispatcher: dispatcher}
+func NewBroadcastController(channelRepo storage.ChannelRepository, msgRepo storage.MessageRepository, producerRepo storage.ProducerRepository, djRepo storage.DeliveryJobRepository,
+	schemaRepo storage.SchemaRepository, quotaRepo storage.PublishQuotaRepository, grantRepo storage.PublishGrantRepository, dispatcher dispatcher.MessageDispatcher, blobStore storage.BlobStore,
+	blobStoreConfig config.BlobStoreConfig, brokerConfig config.BrokerConfig, rateLimitConfig config.RateLimitConfig) *BroadcastController {
+	return &BroadcastController{Publisher: publish.NewPublisher(msgRepo, schemaRepo, quotaRepo, blobStore, blobStoreConfig, brokerConfig, rateLimitConfig),
+		ChannelRepository: channelRepo, ProducerRepository: producerRepo, DeliveryJobRepository: djRepo, GrantRepository: grantRepo, Dispatcher: dispatcher}
 }
 
-// Post Receives message to be broadcasted to a channel
+// Post Receives message to be broadcasted to a channel; the message can also be a CloudEvent in binary mode, i.e. with `ce-*` headers, or in structured
+// mode, i.e. `application/cloudevents+json` body, whose `id` is used as the message ID. If the channel has a schema the payload is rejected with a
+// validation report unless it conforms to the requested, else the latest, version of the schema. A message exceeding the publish rate limit of the
+// producer or the channel is rejected with `Retry-After`.
 func (broadcastController *BroadcastController) Post(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	channel, producer, valid := broadcastController.getChannelAndProducerWithValidation(w, r, params)
 	if !valid {
@@ -56,30 +73,92 @@ func (broadcastController *BroadcastController) Post(w http.ResponseWriter, r *h
 	logger := hlog.FromRequest(r)
 	contentType := getContentType(r)
 	priority := getPriority(r)
-	body, err := ioutil.ReadAll(r.Body)
+	routingKey := r.Header.Get(headerRoutingKey)
+	attributes := getAttributes(r)
+	receiptURL := r.Header.Get(headerReceiptURL)
+	if err := broadcastController.ValidateMessage(routingKey, attributes, receiptURL); err != nil {
+		writeStatus(w, http.StatusBadRequest, err)
+		return
+	}
+	structuredCloudEvent := isStructuredCloudEvent(contentType)
+	var cloudEvent *incomingCloudEvent
+	var err error
+	if !structuredCloudEvent {
+		if cloudEvent, err = getBinaryCloudEvent(r.Header, contentType); err != nil {
+			writeStatus(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	var body io.Reader = r.Body
+	if maxPayloadSize := broadcastController.GetMaxPayloadSize(channel); maxPayloadSize > 0 {
+		body = http.MaxBytesReader(w, r.Body, int64(maxPayloadSize))
+	}
+	if structuredCloudEvent {
+		body, cloudEvent, err = readStructuredCloudEvent(body)
+	}
+	if err == nil {
+		messageContentType := contentType
+		if cloudEvent != nil {
+			messageContentType = cloudEvent.ContentType
+		}
+		body, err = broadcastController.CheckPayloadSchema(channel, messageContentType, attributes, body)
+	}
+	var payload, payloadRef string
+	counter := &countingReader{reader: body}
+	if err == nil {
+		payload, payloadRef, err = broadcastController.StorePayload(counter)
+	}
 	if err != nil {
-		logger.Error().Err(err).Msg("error reading body")
-		writeErr(w, errBodyCouldNotBeRead)
+		var maxBytesErr *http.MaxBytesError
+		var validationErr *publish.SchemaValidationError
+		if errors.As(err, &maxBytesErr) {
+			logger.Error().Err(err).Msg("message rejected because its payload is too large")
+			writeStatus(w, http.StatusRequestEntityTooLarge, errPayloadTooLarge)
+		} else if err == ErrBadRequestForCloudEvent || err == ErrBadRequestForSchemaVersion {
+			writeStatus(w, http.StatusBadRequest, err)
+		} else if errors.As(err, &validationErr) {
+			writeJSONWithStatus(w, http.StatusUnprocessableEntity, newSchemaValidationReport(validationErr))
+		} else {
+			logger.Error().Err(err).Msg("error reading body")
+			writeErr(w, errBodyCouldNotBeRead)
+		}
 		return
 	}
-	message, _ := data.NewMessage(channel, producer, string(body), contentType)
+	message, _ := data.NewMessage(channel, producer, payload, contentType)
+	message.PayloadRef = payloadRef
+	message.RoutingKey = routingKey
+	message.Attributes = attributes
+	message.ReceiptURL = receiptURL
 	incomingMsgID := r.Header.Get(headerMessageID)
 	if len(incomingMsgID) > 0 {
 		message.MessageID = incomingMsgID
 	}
+	if cloudEvent != nil {
+		message.MessageID = cloudEvent.ID
+		message.ContentType = cloudEvent.ContentType
+		message.CloudEvent = cloudEvent.Metadata
+	}
 	message.Priority = uint(math.Abs(float64(priority)))
-	if err = broadcastController.MessageRepository.Create(message); err == nil {
+	wait, err := broadcastController.Publish(message, counter.count)
+	switch err {
+	case nil:
 		logger.Info().Str(messageIDLogFieldKey, message.ID.String()).Msg("Message accepted for broadcast")
 		go broadcastController.Dispatcher.Dispatch(message)
+		if wait, ok := broadcastController.getPreferredWait(r); ok {
+			broadcastController.writePublishOutcome(w, r, message, wait)
+			return
+		}
 		writeStatus(w, http.StatusAccepted, nil)
-	} else if err == storage.ErrDuplicateMessageIDForChannel {
+	case errRateLimitExceeded:
+		logger.Info().Dur("retryAfter", wait).Msg("message rejected because it exceeds the publish rate limit")
+		writeTooManyRequests(w, wait)
+	case storage.ErrDuplicateMessageIDForChannel:
 		logger.Error().Err(err).Str(messageIDLogFieldKey, message.ID.String()).Msg("message rejected because its duplicate id in channel")
 		writeStatus(w, http.StatusConflict, err)
-	} else {
+	default:
 		logger.Error().Err(err).Msg("error creating message")
 		writeErr(w, err)
 	}
-
 }
 
 func (broadcastController *BroadcastController) getChannelAndProducerWithValidation(w http.ResponseWriter, r *http.Request, params httprouter.Params) (channel *data.Channel, producer *data.Producer, valid bool) {
@@ -107,6 +186,18 @@ func (broadcastController *BroadcastController) getChannelAndProducerWithValidat
 		logger.Error().Msg(fmt.Sprintf("producer token did not match: %s vs %s", producer.Token, producerToken))
 		writeStatus(w, http.StatusForbidden, errProducerTokenNotMatching)
 		valid = false
+	} else if _, err = broadcastController.GrantRepository.Get(channel, producer); err == sql.ErrNoRows {
+		// Until grants are enforced, publishes without one are only logged so that grants can be created during rollout
+		if broadcastController.BrokerConfig.IsPublishGrantEnforced() {
+			logger.Error().Msg(fmt.Sprintf("producer %s not granted to publish to channel %s", producerID, channelID))
+			writeStatus(w, http.StatusForbidden, errProducerNotGranted)
+			valid = false
+		} else {
+			logger.Warn().Msg(fmt.Sprintf("producer %s not granted to publish to channel %s, allowed as grants are not enforced", producerID, channelID))
+		}
+	} else if err != nil {
+		writeErr(w, err)
+		valid = false
 	}
 	return channel, producer, valid
 }
@@ -119,6 +210,21 @@ func getPriority(r *http.Request) int {
 	return priority
 }
 
+// getAttributes collects the `X-Broker-Attr-*` headers as message attributes keyed by the header name's suffix; multiple values of a header are
+// joined by comma
+func getAttributes(r *http.Request) data.MessageAttributes {
+	var attributes data.MessageAttributes
+	for name, values := range r.Header {
+		if strings.HasPrefix(name, headerAttributePrefix) {
+			if attributes == nil {
+				attributes = make(data.MessageAttributes)
+			}
+			attributes[strings.TrimPrefix(name, headerAttributePrefix)] = strings.Join(values, ",")
+		}
+	}
+	return attributes
+}
+
 func getContentType(r *http.Request) string {
 	contentType := r.Header.Get(headerContentType)
 	if len(contentType) < 1 {
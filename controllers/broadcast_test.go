@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
@@ -14,6 +15,8 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/newscred/webhook-broker/config"
+	configmocks "github.com/newscred/webhook-broker/config/mocks"
 	dispatchermocks "github.com/newscred/webhook-broker/dispatcher/mocks"
 	"github.com/newscred/webhook-broker/storage"
 	"github.com/newscred/webhook-broker/storage/data"
@@ -25,15 +28,25 @@ import (
 
 var (
 	messageRepo storage.MessageRepository
+	schemaRepo  storage.SchemaRepository
 )
 
 func BroadcastTestSetup() {
-	messageRepo = storage.NewMessageRepository(db, channelRepo, producerRepo)
+	messageRepo = storage.NewMessageRepository(db, channelRepo, producerRepo, storage.NewPayloadCompressor(configuration))
+	schemaRepo = storage.NewSchemaRepository(db)
+	grantBroadcastTestProducer(consumerTestChannel, "0")
+	grantBroadcastTestProducer(consumerTestChannel, "1")
+}
+
+// grantBroadcastTestProducer grants the shared test producer of the index to publish to the channel
+func grantBroadcastTestProducer(channel *data.Channel, index string) {
+	producer, _ := producerRepo.Get(listTestProducerIDPrefix + index)
+	grantRepo.Grant(channel, producer)
 }
 
 func getNewBroadcastController(msgRepo storage.MessageRepository) (*BroadcastController, *dispatchermocks.MessageDispatcher) {
 	mockDispatcher := new(dispatchermocks.MessageDispatcher)
-	return NewBroadcastController(channelRepo, msgRepo, producerRepo, mockDispatcher), mockDispatcher
+	return NewBroadcastController(channelRepo, msgRepo, producerRepo, djRepo, schemaRepo, quotaRepo, grantRepo, mockDispatcher, nil, configuration, configuration, configuration), mockDispatcher
 }
 
 type mockCloser struct {
@@ -395,3 +408,221 @@ func setupAsyncDispatchMock(mockDispatcher *dispatchermocks.MessageDispatcher, m
 	})
 	return &wg
 }
+
+func getPayloadLimitTestRequest(testURI string, channel *data.Channel, body string) *http.Request {
+	req, _ := http.NewRequest("POST", testURI, ioutil.NopCloser(strings.NewReader(body)))
+	req.Header.Add(headerContentType, formDataContentTypeHeaderValue)
+	req.Header.Add(headerChannelToken, channel.Token)
+	req.Header.Add(headerProducerID, listTestProducerIDPrefix+"0")
+	req.Header.Add(headerProducerToken, successfulGetTestToken+" - 0")
+	return req
+}
+
+func TestBroadcastControllerRoutingKey(t *testing.T) {
+	t.Run("202", func(t *testing.T) {
+		t.Parallel()
+		msgRepo := new(storagemocks.MessageRepository)
+		controller, mockDispatcher := getNewBroadcastController(msgRepo)
+		testRouter := createTestRouter(controller)
+		req := getPayloadLimitTestRequest(controller.FormatAsRelativeLink(getRouterParam(consumerTestChannel.ChannelID)), consumerTestChannel, "routed message")
+		req.Header.Add(headerRoutingKey, "user.created.v2")
+		matcher := func(msg *data.Message) bool {
+			return msg.RoutingKey == "user.created.v2" && msg.IsInValidState()
+		}
+		msgRepo.On("Create", mock.MatchedBy(matcher)).Return(nil)
+		wg := setupAsyncDispatchMock(mockDispatcher, matcher)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		wg.Wait()
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		msgRepo.AssertExpectations(t)
+		mockDispatcher.AssertExpectations(t)
+	})
+	t.Run("400", func(t *testing.T) {
+		t.Parallel()
+		msgRepo := new(storagemocks.MessageRepository)
+		controller, mockDispatcher := getNewBroadcastController(msgRepo)
+		testRouter := createTestRouter(controller)
+		req := getPayloadLimitTestRequest(controller.FormatAsRelativeLink(getRouterParam(consumerTestChannel.ChannelID)), consumerTestChannel, "routed message")
+		req.Header.Add(headerRoutingKey, "user.*")
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, ErrBadRequestForRoutingKey.Error(), rr.Body.String())
+		msgRepo.AssertExpectations(t)
+		mockDispatcher.AssertExpectations(t)
+	})
+}
+
+func TestBroadcastControllerAttributes(t *testing.T) {
+	t.Run("202", func(t *testing.T) {
+		t.Parallel()
+		msgRepo := new(storagemocks.MessageRepository)
+		controller, mockDispatcher := getNewBroadcastController(msgRepo)
+		testRouter := createTestRouter(controller)
+		req := getPayloadLimitTestRequest(controller.FormatAsRelativeLink(getRouterParam(consumerTestChannel.ChannelID)), consumerTestChannel, "attributed message")
+		req.Header.Add("X-Broker-Attr-Tenant-Id", "tenant-1")
+		req.Header.Add("x-broker-attr-trace", "abc")
+		req.Header.Add("X-Broker-Attr-Trace", "def")
+		matcher := func(msg *data.Message) bool {
+			return len(msg.Attributes) == 2 && msg.Attributes["Tenant-Id"] == "tenant-1" && msg.Attributes["Trace"] == "abc,def" && msg.IsInValidState()
+		}
+		msgRepo.On("Create", mock.MatchedBy(matcher)).Return(nil)
+		wg := setupAsyncDispatchMock(mockDispatcher, matcher)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		wg.Wait()
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		msgRepo.AssertExpectations(t)
+		mockDispatcher.AssertExpectations(t)
+	})
+	t.Run("400", func(t *testing.T) {
+		t.Parallel()
+		msgRepo := new(storagemocks.MessageRepository)
+		controller, mockDispatcher := getNewBroadcastController(msgRepo)
+		testRouter := createTestRouter(controller)
+		req := getPayloadLimitTestRequest(controller.FormatAsRelativeLink(getRouterParam(consumerTestChannel.ChannelID)), consumerTestChannel, "attributed message")
+		req.Header.Add("X-Broker-Attr-Tenant_Id", "tenant-1")
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, ErrBadRequestForMessageAttributes.Error(), rr.Body.String())
+		msgRepo.AssertExpectations(t)
+		mockDispatcher.AssertExpectations(t)
+	})
+}
+
+func TestBroadcastControllerReceiptURL(t *testing.T) {
+	t.Run("202", func(t *testing.T) {
+		t.Parallel()
+		msgRepo := new(storagemocks.MessageRepository)
+		controller, mockDispatcher := getNewBroadcastController(msgRepo)
+		testRouter := createTestRouter(controller)
+		req := getPayloadLimitTestRequest(controller.FormatAsRelativeLink(getRouterParam(consumerTestChannel.ChannelID)), consumerTestChannel, "message with receipt")
+		req.Header.Add(headerReceiptURL, "https://imytech.net/receipts")
+		matcher := func(msg *data.Message) bool {
+			return msg.ReceiptURL == "https://imytech.net/receipts" && msg.GetReceiptURL() == msg.ReceiptURL && msg.IsInValidState()
+		}
+		msgRepo.On("Create", mock.MatchedBy(matcher)).Return(nil)
+		wg := setupAsyncDispatchMock(mockDispatcher, matcher)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		wg.Wait()
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		msgRepo.AssertExpectations(t)
+		mockDispatcher.AssertExpectations(t)
+	})
+	t.Run("400", func(t *testing.T) {
+		t.Parallel()
+		msgRepo := new(storagemocks.MessageRepository)
+		controller, mockDispatcher := getNewBroadcastController(msgRepo)
+		testRouter := createTestRouter(controller)
+		req := getPayloadLimitTestRequest(controller.FormatAsRelativeLink(getRouterParam(consumerTestChannel.ChannelID)), consumerTestChannel, "message with receipt")
+		req.Header.Add(headerReceiptURL, "mailto:producer@imytech.net")
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, ErrBadRequestForReceiptURL.Error(), rr.Body.String())
+		msgRepo.AssertExpectations(t)
+		mockDispatcher.AssertExpectations(t)
+	})
+}
+
+func TestBroadcastControllerPayloadLimits(t *testing.T) {
+	limitedChannel, _ := data.NewChannel("broadcast-limited-channel", "broadcast-limited-channel-token")
+	limitedChannel.MaxPayloadSize = 16
+	limitedChannel, err := channelRepo.Store(limitedChannel)
+	assert.Nil(t, err)
+	grantBroadcastTestProducer(limitedChannel, "0")
+	blobDir := t.TempDir()
+	blobStoreConfig := new(configmocks.BlobStoreConfig)
+	blobStoreConfig.On("GetBlobStoreProvider").Return(config.FileSystemBlobStoreProvider)
+	blobStoreConfig.On("GetBlobStoreFileSystemPath").Return(blobDir)
+	blobStoreConfig.On("GetPayloadOffloadThreshold").Return(uint(8))
+	blobStore, err := storage.NewBlobStore(blobStoreConfig)
+	assert.Nil(t, err)
+	getOffloadingController := func(msgRepo storage.MessageRepository) (*BroadcastController, *dispatchermocks.MessageDispatcher) {
+		mockDispatcher := new(dispatchermocks.MessageDispatcher)
+		return NewBroadcastController(channelRepo, msgRepo, producerRepo, djRepo, schemaRepo, quotaRepo, grantRepo, mockDispatcher, blobStore, blobStoreConfig, configuration, configuration), mockDispatcher
+	}
+	t.Run("413:ChannelLimit", func(t *testing.T) {
+		msgRepo := new(storagemocks.MessageRepository)
+		controller, mockDispatcher := getNewBroadcastController(msgRepo)
+		rr := httptest.NewRecorder()
+		createTestRouter(controller).ServeHTTP(rr, getPayloadLimitTestRequest(controller.FormatAsRelativeLink(getRouterParam(limitedChannel.ChannelID)), limitedChannel,
+			"payload larger than 16 bytes"))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		assert.Equal(t, errPayloadTooLarge.Error(), rr.Body.String())
+		msgRepo.AssertExpectations(t)
+		mockDispatcher.AssertExpectations(t)
+	})
+	t.Run("413:BrokerLimit", func(t *testing.T) {
+		msgRepo := new(storagemocks.MessageRepository)
+		brokerConfig := new(configmocks.BrokerConfig)
+		brokerConfig.On("GetMaxPayloadSize").Return(uint(4))
+		controller := NewBroadcastController(channelRepo, msgRepo, producerRepo, djRepo, schemaRepo, quotaRepo, grantRepo, new(dispatchermocks.MessageDispatcher), nil, configuration, brokerConfig, configuration)
+		rr := httptest.NewRecorder()
+		createTestRouter(controller).ServeHTTP(rr, getPayloadLimitTestRequest(controller.FormatAsRelativeLink(getRouterParam(consumerTestChannel.ChannelID)), consumerTestChannel,
+			"test message body"))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		msgRepo.AssertExpectations(t)
+		brokerConfig.AssertExpectations(t)
+	})
+	t.Run("202:Offloaded", func(t *testing.T) {
+		msgRepo := new(storagemocks.MessageRepository)
+		controller, mockDispatcher := getOffloadingController(msgRepo)
+		bodyString := "offloaded body"
+		matcher := func(msg *data.Message) bool {
+			return msg.Payload == "" && len(msg.PayloadRef) > 0 && msg.IsInValidState()
+		}
+		msgRepo.On("Create", mock.MatchedBy(matcher)).Return(nil)
+		wg := setupAsyncDispatchMock(mockDispatcher, matcher)
+		rr := httptest.NewRecorder()
+		createTestRouter(controller).ServeHTTP(rr, getPayloadLimitTestRequest(controller.FormatAsRelativeLink(getRouterParam(limitedChannel.ChannelID)), limitedChannel, bodyString))
+		wg.Wait()
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		message := msgRepo.Calls[0].Arguments.Get(0).(*data.Message)
+		blob, err := blobStore.Get(message.PayloadRef)
+		assert.Nil(t, err)
+		defer blob.Close()
+		content, _ := ioutil.ReadAll(blob)
+		assert.Equal(t, bodyString, string(content))
+		msgRepo.AssertExpectations(t)
+		mockDispatcher.AssertExpectations(t)
+	})
+	t.Run("202:BelowOffloadThreshold", func(t *testing.T) {
+		msgRepo := new(storagemocks.MessageRepository)
+		controller, mockDispatcher := getOffloadingController(msgRepo)
+		matcher := func(msg *data.Message) bool {
+			return msg.Payload == "inline" && msg.PayloadRef == ""
+		}
+		msgRepo.On("Create", mock.MatchedBy(matcher)).Return(nil)
+		wg := setupAsyncDispatchMock(mockDispatcher, matcher)
+		rr := httptest.NewRecorder()
+		createTestRouter(controller).ServeHTTP(rr, getPayloadLimitTestRequest(controller.FormatAsRelativeLink(getRouterParam(limitedChannel.ChannelID)), limitedChannel, "inline"))
+		wg.Wait()
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		msgRepo.AssertExpectations(t)
+	})
+	t.Run("413:Offloading", func(t *testing.T) {
+		msgRepo := new(storagemocks.MessageRepository)
+		controller, _ := getOffloadingController(msgRepo)
+		rr := httptest.NewRecorder()
+		createTestRouter(controller).ServeHTTP(rr, getPayloadLimitTestRequest(controller.FormatAsRelativeLink(getRouterParam(limitedChannel.ChannelID)), limitedChannel,
+			"payload larger than 16 bytes"))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		msgRepo.AssertExpectations(t)
+	})
+	t.Run("409:BlobDeleted", func(t *testing.T) {
+		msgRepo := new(storagemocks.MessageRepository)
+		controller, _ := getOffloadingController(msgRepo)
+		msgRepo.On("Create", mock.Anything).Return(storage.ErrDuplicateMessageIDForChannel)
+		rr := httptest.NewRecorder()
+		createTestRouter(controller).ServeHTTP(rr, getPayloadLimitTestRequest(controller.FormatAsRelativeLink(getRouterParam(limitedChannel.ChannelID)), limitedChannel, "offloaded body"))
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		message := msgRepo.Calls[0].Arguments.Get(0).(*data.Message)
+		_, err := blobStore.Get(message.PayloadRef)
+		assert.True(t, os.IsNotExist(err))
+		msgRepo.AssertExpectations(t)
+	})
+}
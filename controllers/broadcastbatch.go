@@ -0,0 +1,232 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog/hlog"
+
+	"github.com/newscred/webhook-broker/publish"
+	"github.com/newscred/webhook-broker/storage"
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+const (
+	broadcastBatchPath     = channelPath + "/broadcast-batch"
+	jsonContentTypeValue   = "application/json"
+	maxBroadcastBatchSize  = 1000
+	batchMessageCountField = "messageCount"
+)
+
+var (
+	errBroadcastBatchTooLarge = errors.New("broadcast batch exceeds the maximum number of messages or size of a request")
+)
+
+// BatchMessageModel represents a message in the broadcast batch request
+type BatchMessageModel struct {
+	MessageID   string
+	ContentType string
+	Priority    int
+	RoutingKey  string
+	Attributes  data.MessageAttributes
+	ReceiptURL  string
+	Payload     string
+}
+
+// BatchMessageResult represents the outcome of a message in the broadcast batch; Status being the HTTP status the message would have received
+// if broadcasted on its own
+type BatchMessageResult struct {
+	MessageID        string
+	Status           int
+	Error            string                  `json:",omitempty"`
+	ValidationErrors []*data.SchemaViolation `json:",omitempty"`
+}
+
+// BatchBroadcastResult represents the response of the broadcast batch with results in the same order as messages in the request
+type BatchBroadcastResult struct {
+	Results []*BatchMessageResult
+}
+
+// BroadcastBatchController receives many messages to be broadcasted to a valid channel in a single request
+type BroadcastBatchController struct {
+	BroadcastController *BroadcastController
+}
+
+// NewBroadcastBatchController creates a new instance of the controller responsible for broadcasting a batch of messages
+func NewBroadcastBatchController(broadcastController *BroadcastController) *BroadcastBatchController {
+	return &BroadcastBatchController{BroadcastController: broadcastController}
+}
+
+// Post receives the batch of messages to be broadcasted to a channel; messages exceeding the publish rate limit are rejected individually and
+// `Retry-After` is set to when all of them could be retried
+func (batchController *BroadcastBatchController) Post(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	broadcastController := batchController.BroadcastController
+	channel, producer, valid := broadcastController.getChannelAndProducerWithValidation(w, r, params)
+	if !valid {
+		return
+	}
+	logger := hlog.FromRequest(r)
+	body := r.Body
+	if maxBatchSize := broadcastController.BrokerConfig.GetMaxBatchSize(); maxBatchSize > 0 {
+		body = http.MaxBytesReader(w, r.Body, int64(maxBatchSize))
+	}
+	batch, err := readBatch(r.Header.Get(headerContentType), body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case err == ErrUnsupportedMediaType:
+			writeUnsupportedMediaType(w)
+		case err == errBroadcastBatchTooLarge || errors.As(err, &maxBytesErr):
+			writeStatus(w, http.StatusRequestEntityTooLarge, errBroadcastBatchTooLarge)
+		default:
+			logger.Error().Err(err).Msg("error reading broadcast batch")
+			writeStatus(w, http.StatusBadRequest, ErrBadRequestForBroadcastBatch)
+		}
+		return
+	}
+	results := make([]*BatchMessageResult, len(batch))
+	messages := make([]*data.Message, 0, len(batch))
+	payloadSizes := make([]uint, 0, len(batch))
+	resultIndexes := make([]int, 0, len(batch))
+	for index, batchMessage := range batch {
+		results[index] = &BatchMessageResult{MessageID: batchMessage.MessageID, Status: http.StatusAccepted}
+		if err := broadcastController.CheckPayloadSize(channel, uint(len(batchMessage.Payload))); err != nil {
+			setBatchMessageError(results[index], http.StatusRequestEntityTooLarge, err)
+			continue
+		}
+		if err := broadcastController.ValidateMessage(batchMessage.RoutingKey, batchMessage.Attributes, batchMessage.ReceiptURL); err != nil {
+			setBatchMessageError(results[index], http.StatusBadRequest, err)
+			continue
+		}
+		if _, err := broadcastController.CheckPayloadSchema(channel, batchMessage.ContentType, batchMessage.Attributes, strings.NewReader(batchMessage.Payload)); err != nil {
+			var validationErr *publish.SchemaValidationError
+			switch {
+			case errors.As(err, &validationErr):
+				setBatchMessageError(results[index], http.StatusUnprocessableEntity, err)
+				results[index].ValidationErrors = validationErr.Violations
+			case err == ErrBadRequestForSchemaVersion:
+				setBatchMessageError(results[index], http.StatusBadRequest, err)
+			default:
+				logger.Error().Err(err).Msg("error validating payload of batch message")
+				setBatchMessageError(results[index], http.StatusInternalServerError, err)
+			}
+			continue
+		}
+		message, err := broadcastController.newBatchMessage(channel, producer, batchMessage)
+		if err != nil {
+			logger.Error().Err(err).Msg("error offloading payload of batch message")
+			setBatchMessageError(results[index], http.StatusInternalServerError, err)
+			continue
+		}
+		results[index].MessageID = message.MessageID
+		messages = append(messages, message)
+		payloadSizes = append(payloadSizes, uint(len(batchMessage.Payload)))
+		resultIndexes = append(resultIndexes, index)
+	}
+	errs, rateLimitWait := broadcastController.PublishBatch(messages, payloadSizes)
+	accepted := make([]*data.Message, 0, len(messages))
+	for index, err := range errs {
+		message, result := messages[index], results[resultIndexes[index]]
+		if err == nil {
+			accepted = append(accepted, message)
+			continue
+		}
+		switch err {
+		case storage.ErrDuplicateMessageIDForChannel:
+			setBatchMessageError(result, http.StatusConflict, err)
+		case data.ErrInsufficientInformationForCreating:
+			setBatchMessageError(result, http.StatusBadRequest, err)
+		case errRateLimitExceeded:
+			setBatchMessageError(result, http.StatusTooManyRequests, err)
+		default:
+			logger.Error().Err(err).Str(messageIDLogFieldKey, message.ID.String()).Msg("error creating message of batch")
+			setBatchMessageError(result, http.StatusInternalServerError, err)
+		}
+	}
+	logger.Info().Int(batchMessageCountField, len(accepted)).Msg("Messages of batch accepted for broadcast")
+	go func() {
+		for _, message := range accepted {
+			broadcastController.Dispatcher.Dispatch(message)
+		}
+	}()
+	if rateLimitWait > 0 {
+		setRetryAfter(w, rateLimitWait)
+	}
+	writeJSON(w, &BatchBroadcastResult{Results: results})
+}
+
+func (broadcastController *BroadcastController) newBatchMessage(channel *data.Channel, producer *data.Producer, batchMessage *BatchMessageModel) (*data.Message, error) {
+	contentType := batchMessage.ContentType
+	if len(contentType) < 1 {
+		contentType = defaultMessageContentType
+	}
+	payload, payloadRef, err := broadcastController.StorePayload(strings.NewReader(batchMessage.Payload))
+	if err != nil {
+		return nil, err
+	}
+	message, _ := data.NewMessage(channel, producer, payload, contentType)
+	message.PayloadRef = payloadRef
+	message.RoutingKey = batchMessage.RoutingKey
+	message.Attributes = batchMessage.Attributes
+	message.ReceiptURL = batchMessage.ReceiptURL
+	if len(batchMessage.MessageID) > 0 {
+		message.MessageID = batchMessage.MessageID
+	}
+	message.Priority = uint(math.Abs(float64(batchMessage.Priority)))
+	return message, nil
+}
+
+func setBatchMessageError(result *BatchMessageResult, status int, err error) {
+	result.Status = status
+	result.Error = err.Error()
+}
+
+// readBatch decodes the messages from a JSON array or NDJSON body as per the content type
+func readBatch(contentType string, body io.Reader) (batch []*BatchMessageModel, err error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	decoder := json.NewDecoder(body)
+	switch mediaType {
+	case jsonContentTypeValue:
+		err = decoder.Decode(&batch)
+	case ndjsonContentTypeValue:
+		for err == nil && len(batch) <= maxBroadcastBatchSize {
+			batchMessage := &BatchMessageModel{}
+			if err = decoder.Decode(batchMessage); err == nil {
+				batch = append(batch, batchMessage)
+			}
+		}
+		if err == io.EOF {
+			err = nil
+		}
+	default:
+		err = ErrUnsupportedMediaType
+	}
+	if err == nil && len(batch) > maxBroadcastBatchSize {
+		err = errBroadcastBatchTooLarge
+	}
+	if err == nil && len(batch) <= 0 {
+		err = ErrBadRequestForBroadcastBatch
+	}
+	for index := 0; err == nil && index < len(batch); index++ {
+		if batch[index] == nil {
+			err = ErrBadRequestForBroadcastBatch
+		}
+	}
+	return batch, err
+}
+
+// GetPath returns the endpoint's path
+func (batchController *BroadcastBatchController) GetPath() string {
+	return broadcastBatchPath
+}
+
+// FormatAsRelativeLink Format as relative URL of this resource based on the params
+func (batchController *BroadcastBatchController) FormatAsRelativeLink(params ...httprouter.Param) string {
+	return formatURL(params, broadcastBatchPath, channelIDPathParamKey)
+}
@@ -0,0 +1,200 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	configmocks "github.com/newscred/webhook-broker/config/mocks"
+	dispatchermocks "github.com/newscred/webhook-broker/dispatcher/mocks"
+	"github.com/newscred/webhook-broker/storage"
+	"github.com/newscred/webhook-broker/storage/data"
+	storagemocks "github.com/newscred/webhook-broker/storage/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func getNewBroadcastBatchController(msgRepo storage.MessageRepository) (*BroadcastBatchController, *sync.WaitGroup, *[]*data.Message) {
+	controller, mockDispatcher := getNewBroadcastController(msgRepo)
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	dispatched := make([]*data.Message, 0)
+	mockDispatcher.On("Dispatch", mock.Anything).Return().Run(func(args mock.Arguments) {
+		lock.Lock()
+		defer lock.Unlock()
+		dispatched = append(dispatched, args.Get(0).(*data.Message))
+		wg.Done()
+	})
+	return NewBroadcastBatchController(controller), &wg, &dispatched
+}
+
+func getBroadcastBatchTestRequest(testURI string, contentType string, body string) *http.Request {
+	req, _ := http.NewRequest("POST", testURI, ioutil.NopCloser(strings.NewReader(body)))
+	req.Header.Add(headerContentType, contentType)
+	req.Header.Add(headerChannelToken, consumerTestChannel.Token)
+	req.Header.Add(headerProducerID, listTestProducerIDPrefix+"0")
+	req.Header.Add(headerProducerToken, successfulGetTestToken+" - 0")
+	return req
+}
+
+func getBroadcastBatchResults(t *testing.T, rr *httptest.ResponseRecorder) []*BatchMessageResult {
+	result := &BatchBroadcastResult{}
+	assert.Nil(t, json.NewDecoder(rr.Body).Decode(result))
+	return result.Results
+}
+
+func TestBroadcastBatchControllerFormatAsRelativeLink(t *testing.T) {
+	controller, _, _ := getNewBroadcastBatchController(messageRepo)
+	assert.Equal(t, "/channel/"+channelTestConsumerID+"/broadcast-batch", controller.FormatAsRelativeLink(getRouterParam(consumerTestChannel.ChannelID)))
+}
+
+func TestBroadcastBatchControllerPost(t *testing.T) {
+	t.Run("200:JSON", func(t *testing.T) {
+		producer, _ := producerRepo.Get(listTestProducerIDPrefix + "0")
+		existing, _ := data.NewMessage(consumerTestChannel, producer, "existing", "text/plain")
+		assert.Nil(t, messageRepo.Create(existing))
+		controller, wg, dispatched := getNewBroadcastBatchController(messageRepo)
+		testRouter := createTestRouter(controller)
+		body := `[{"MessageID": "batch-json-1", "ContentType": "application/json", "Priority": -3, "RoutingKey": "user.created", "Attributes": {"Tenant-Id": "tenant-1"}, "ReceiptURL": "https://imytech.net/receipts", "Payload": "{\"a\": 1}"},
+			{"MessageID": "` + existing.MessageID + `", "Payload": "duplicate of stored"},
+			{"MessageID": "batch-json-1", "Payload": "duplicate in batch"},
+			{"Payload": "no id"},
+			{"MessageID": "batch-json-empty"},
+			{"MessageID": "batch-json-bad-routing-key", "RoutingKey": "user.#", "Payload": "bad routing key"},
+			{"MessageID": "batch-json-bad-attributes", "Attributes": {"Tenant Id": "tenant-1"}, "Payload": "bad attributes"},
+			{"MessageID": "batch-json-bad-receipt-url", "ReceiptURL": "/receipts", "Payload": "bad receipt URL"}]`
+		wg.Add(2)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getBroadcastBatchTestRequest(controller.FormatAsRelativeLink(getRouterParam(consumerTestChannel.ChannelID)), "application/json; charset=utf-8", body))
+		wg.Wait()
+		assert.Equal(t, http.StatusOK, rr.Code)
+		results := getBroadcastBatchResults(t, rr)
+		assert.Equal(t, 8, len(results))
+		assert.Equal(t, []int{http.StatusAccepted, http.StatusConflict, http.StatusConflict, http.StatusAccepted, http.StatusBadRequest, http.StatusBadRequest, http.StatusBadRequest, http.StatusBadRequest},
+			[]int{results[0].Status, results[1].Status, results[2].Status, results[3].Status, results[4].Status, results[5].Status, results[6].Status, results[7].Status})
+		assert.Equal(t, ErrBadRequestForRoutingKey.Error(), results[5].Error)
+		assert.Equal(t, ErrBadRequestForMessageAttributes.Error(), results[6].Error)
+		assert.Equal(t, ErrBadRequestForReceiptURL.Error(), results[7].Error)
+		assert.Equal(t, storage.ErrDuplicateMessageIDForChannel.Error(), results[1].Error)
+		assert.Empty(t, results[0].Error)
+		assert.NotEmpty(t, results[3].MessageID)
+		assert.Equal(t, 2, len(*dispatched))
+		msg, err := messageRepo.Get(consumerTestChannel.ChannelID, "batch-json-1")
+		assert.Nil(t, err)
+		assert.Equal(t, "application/json", msg.ContentType)
+		assert.Equal(t, uint(3), msg.Priority)
+		assert.Equal(t, "user.created", msg.RoutingKey)
+		assert.Equal(t, data.MessageAttributes{"Tenant-Id": "tenant-1"}, msg.Attributes)
+		assert.Equal(t, "https://imytech.net/receipts", msg.ReceiptURL)
+		assert.Equal(t, `{"a": 1}`, msg.Payload)
+		msg, err = messageRepo.Get(consumerTestChannel.ChannelID, results[3].MessageID)
+		assert.Nil(t, err)
+		assert.Equal(t, defaultMessageContentType, msg.ContentType)
+		msg, err = messageRepo.Get(consumerTestChannel.ChannelID, existing.MessageID)
+		assert.Nil(t, err)
+		assert.Equal(t, "existing", msg.Payload)
+	})
+	t.Run("200:NDJSON", func(t *testing.T) {
+		limitedChannel, _ := data.NewChannel("broadcast-batch-limited-channel", consumerTestChannel.Token)
+		limitedChannel.MaxPayloadSize = 16
+		limitedChannel, err := channelRepo.Store(limitedChannel)
+		assert.Nil(t, err)
+		grantBroadcastTestProducer(limitedChannel, "0")
+		controller, wg, dispatched := getNewBroadcastBatchController(messageRepo)
+		testRouter := createTestRouter(controller)
+		body := `{"MessageID": "batch-ndjson-1", "Payload": "first"}
+{"MessageID": "batch-ndjson-2", "Payload": "` + strings.Repeat("a", 17) + `"}
+{"MessageID": "batch-ndjson-3", "Payload": "third"}
+`
+		wg.Add(2)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getBroadcastBatchTestRequest(controller.FormatAsRelativeLink(getRouterParam(limitedChannel.ChannelID)), ndjsonContentTypeValue, body))
+		wg.Wait()
+		assert.Equal(t, http.StatusOK, rr.Code)
+		results := getBroadcastBatchResults(t, rr)
+		assert.Equal(t, 3, len(results))
+		assert.Equal(t, http.StatusAccepted, results[0].Status)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, results[1].Status)
+		assert.Equal(t, http.StatusAccepted, results[2].Status)
+		assert.Equal(t, "batch-ndjson-3", results[2].MessageID)
+		assert.Equal(t, 2, len(*dispatched))
+		_, err = messageRepo.Get(limitedChannel.ChannelID, "batch-ndjson-2")
+		assert.NotNil(t, err)
+	})
+	t.Run("500:CreateBatch", func(t *testing.T) {
+		t.Parallel()
+		msgRepo := new(storagemocks.MessageRepository)
+		expectedErr := errors.New("create batch failed")
+		msgRepo.On("CreateBatch", mock.MatchedBy(func(messages []*data.Message) bool { return len(messages) == 1 }), mock.Anything).Return([]error{expectedErr})
+		controller, _, _ := getNewBroadcastBatchController(msgRepo)
+		testRouter := createTestRouter(controller)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getBroadcastBatchTestRequest(controller.FormatAsRelativeLink(getRouterParam(consumerTestChannel.ChannelID)), jsonContentTypeValue, `[{"Payload": "failing"}]`))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		results := getBroadcastBatchResults(t, rr)
+		assert.Equal(t, http.StatusInternalServerError, results[0].Status)
+		assert.Equal(t, expectedErr.Error(), results[0].Error)
+		msgRepo.AssertExpectations(t)
+	})
+	t.Run("415", func(t *testing.T) {
+		t.Parallel()
+		controller, _, _ := getNewBroadcastBatchController(new(storagemocks.MessageRepository))
+		testRouter := createTestRouter(controller)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getBroadcastBatchTestRequest(controller.FormatAsRelativeLink(getRouterParam(consumerTestChannel.ChannelID)), formDataContentTypeHeaderValue, `[{"Payload": "a"}]`))
+		assert.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+	})
+	t.Run("400", func(t *testing.T) {
+		t.Parallel()
+		controller, _, _ := getNewBroadcastBatchController(new(storagemocks.MessageRepository))
+		testRouter := createTestRouter(controller)
+		testURI := controller.FormatAsRelativeLink(getRouterParam(consumerTestChannel.ChannelID))
+		for _, body := range []string{`[]`, `{"Payload": "not an array"}`, `[null]`, `[{"Payload": "a"}`} {
+			rr := httptest.NewRecorder()
+			testRouter.ServeHTTP(rr, getBroadcastBatchTestRequest(testURI, jsonContentTypeValue, body))
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			assert.Equal(t, ErrBadRequestForBroadcastBatch.Error(), rr.Body.String())
+		}
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getBroadcastBatchTestRequest(testURI, ndjsonContentTypeValue, `{"Payload": "a"} not json`))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+	t.Run("413", func(t *testing.T) {
+		t.Parallel()
+		controller, _, _ := getNewBroadcastBatchController(new(storagemocks.MessageRepository))
+		testRouter := createTestRouter(controller)
+		rr := httptest.NewRecorder()
+		body := strings.Repeat(`{"Payload": "a"}`+"\n", maxBroadcastBatchSize+1)
+		testRouter.ServeHTTP(rr, getBroadcastBatchTestRequest(controller.FormatAsRelativeLink(getRouterParam(consumerTestChannel.ChannelID)), ndjsonContentTypeValue, body))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	})
+	t.Run("413:BrokerBatchLimit", func(t *testing.T) {
+		t.Parallel()
+		msgRepo := new(storagemocks.MessageRepository)
+		brokerConfig := new(configmocks.BrokerConfig)
+		brokerConfig.On("GetMaxBatchSize").Return(uint(32))
+		controller := NewBroadcastBatchController(NewBroadcastController(channelRepo, msgRepo, producerRepo, djRepo, schemaRepo, quotaRepo, grantRepo, new(dispatchermocks.MessageDispatcher), nil,
+			configuration, brokerConfig, configuration))
+		rr := httptest.NewRecorder()
+		createTestRouter(controller).ServeHTTP(rr, getBroadcastBatchTestRequest(controller.FormatAsRelativeLink(getRouterParam(consumerTestChannel.ChannelID)), jsonContentTypeValue,
+			`[{"Payload": "first"}, {"Payload": "second"}]`))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		msgRepo.AssertExpectations(t)
+		brokerConfig.AssertExpectations(t)
+	})
+	t.Run("403", func(t *testing.T) {
+		t.Parallel()
+		controller, _, _ := getNewBroadcastBatchController(new(storagemocks.MessageRepository))
+		testRouter := createTestRouter(controller)
+		req := getBroadcastBatchTestRequest(controller.FormatAsRelativeLink(getRouterParam(consumerTestChannel.ChannelID)), jsonContentTypeValue, `[{"Payload": "a"}]`)
+		req.Header.Set(headerChannelToken, "no-such-token-for-broadcast")
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
@@ -2,16 +2,21 @@ package controllers
 
 import (
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/newscred/webhook-broker/config"
+	"github.com/newscred/webhook-broker/dispatcher"
 	"github.com/newscred/webhook-broker/storage"
 	"github.com/newscred/webhook-broker/storage/data"
 )
 
 const (
-	channelsPath          = "/channels"
-	channelIDPathParamKey = "channelId"
-	channelPath           = "/channel/:" + channelIDPathParamKey
+	channelsPath                = "/channels"
+	channelIDPathParamKey       = "channelId"
+	channelPath                 = "/channel/:" + channelIDPathParamKey
+	maxPayloadSizeFormParamName = "maxPayloadSize"
 )
 
 // ChannelController is for /channel/:prodId
@@ -20,21 +25,32 @@ type ChannelController struct {
 	ConsumersEndpoint EndpointController
 	MessagesEndpoint  EndpointController
 	BroadcastEndpoint EndpointController
+	SystemEvents      dispatcher.SystemEventPublisher
+	QuotaRepo         storage.PublishQuotaRepository
+	RateLimitConfig   config.RateLimitConfig
+	BlobStore         storage.BlobStore
+	PurgeRetention    time.Duration
 }
 
 // ChannelModel represents the Channel data
 type ChannelModel struct {
 	MsgStakeholder
-	ConsumersURL string
-	MessagesURL  string
-	BroadcastURL string
+	MaxPayloadSize uint
+	ConsumersURL   string
+	MessagesURL    string
+	BroadcastURL   string
+	RateLimit      *RateLimitModel
 }
 
 // Get implements the /channel/:prodId GET endpoint
 func (channelController *ChannelController) Get(w http.ResponseWriter, r *http.Request, param httprouter.Params) {
 	channelID := param.ByName(channelIDPathParamKey)
 	channelModel, err := channelController.ChannelRepo.Get(channelID)
-	writeGetResult(err, writeNotFound, w, channelController.getChannelModel(channelModel))
+	if err != nil {
+		writeNotFound(w)
+		return
+	}
+	channelController.writeChannel(w, channelModel)
 }
 
 // Put implements the /channel/:prodId PUT endpoint
@@ -42,22 +58,77 @@ func (channelController *ChannelController) Put(w http.ResponseWriter, r *http.R
 	validRequest := checkFormContentType(r, w)
 	channelID := param.ByName(channelIDPathParamKey)
 	channelModel, err := channelController.ChannelRepo.Get(channelID)
-	if err == nil && validRequest {
+	existing := err == nil
+	if existing && validRequest {
 		validRequest = isConditionalUpdateCalled(w, r, channelModel)
 	}
 	if !validRequest {
 		return
 	}
+	var maxPayloadSize uint64
+	if maxPayloadSizeString := r.PostFormValue(maxPayloadSizeFormParamName); len(maxPayloadSizeString) > 0 {
+		if maxPayloadSize, err = strconv.ParseUint(maxPayloadSizeString, 10, 0); err != nil {
+			writeStatus(w, http.StatusBadRequest, ErrBadRequestForMaxPayloadSize)
+			return
+		}
+	}
+	rateLimit, err := getRateLimitFromForm(r)
+	if err != nil {
+		writeStatus(w, http.StatusBadRequest, err)
+		return
+	}
 	token, name := getUpdateData(r, channelID)
 	channel, _ := data.NewChannel(channelID, token)
 	channel.Name = name
+	channel.MaxPayloadSize = uint(maxPayloadSize)
 	channel, err = channelController.ChannelRepo.Store(channel)
-	writeGetResult(err, func(w http.ResponseWriter) { writeErr(w, err) }, w, channelController.getChannelModel(channel))
+	if err == nil {
+		channelController.SystemEvents.Publish(data.SystemEventChannelUpdated, "", &dispatcher.ChannelEventData{ChannelID: channelID, Created: !existing,
+			TokenRotated: existing && channelModel.Token != channel.Token})
+		_, err = channelController.QuotaRepo.SetLimit(data.ChannelQuota, channelID, rateLimit)
+	}
+	if err != nil {
+		writeStoreErr(w, err)
+		return
+	}
+	channelController.writeChannel(w, channel)
+}
+
+// Delete implements the /channel/:prodId DELETE endpoint; soft deletes the channel along with its consumers or, with `purge=true`,
+// permanently deletes it along with its messages once its retention is over
+func (channelController *ChannelController) Delete(w http.ResponseWriter, r *http.Request, param httprouter.Params) {
+	channelID := param.ByName(channelIDPathParamKey)
+	if channelID == data.ReceiptsChannelID || channelID == data.SystemChannelID {
+		writeStatus(w, http.StatusForbidden, ErrReservedStakeholder)
+		return
+	}
+	channel, err := channelController.ChannelRepo.GetIncludingDeleted(channelID)
+	softDelete := func(drain bool) error { return channelController.ChannelRepo.Delete(channel, drain) }
+	purge := func() error {
+		payloadRefs, err := channelController.ChannelRepo.Purge(channel)
+		if err == nil {
+			deletePayloadBlobs(channelController.BlobStore, payloadRefs)
+		}
+		return err
+	}
+	if deleteStakeholder(w, r, channel, err, channelController.PurgeRetention, softDelete, purge) {
+		channelController.SystemEvents.Publish(data.SystemEventChannelDeleted, "", &dispatcher.ChannelEventData{ChannelID: channelID})
+	}
+}
+
+func (channelController *ChannelController) writeChannel(w http.ResponseWriter, channel *data.Channel) {
+	model := channelController.getChannelModel(channel)
+	var err error
+	if model.RateLimit, err = getRateLimitModel(channelController.QuotaRepo, data.ChannelQuota, channel.ChannelID, channelController.RateLimitConfig.GetChannelRateLimit()); err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeGetResult(nil, writeNotFound, w, model)
 }
 
 func (channelController *ChannelController) getChannelModel(channel *data.Channel) *ChannelModel {
 	channelIDParam := httprouter.Param{Key: channelIDPathParamKey, Value: channel.ChannelID}
-	return &ChannelModel{MsgStakeholder: *getMessageStakeholder(channel.ChannelID, &channel.MessageStakeholder),
+	return &ChannelModel{MsgStakeholder: *getMessageStakeholder(channel.ChannelID, &channel.MessageStakeholder), MaxPayloadSize: channel.MaxPayloadSize,
 		ConsumersURL: channelController.ConsumersEndpoint.FormatAsRelativeLink(channelIDParam),
 		MessagesURL:  channelController.MessagesEndpoint.FormatAsRelativeLink(channelIDParam),
 		BroadcastURL: channelController.BroadcastEndpoint.FormatAsRelativeLink(channelIDParam)}
@@ -105,8 +176,9 @@ func (channelsController *ChannelsController) FormatAsRelativeLink(params ...htt
 }
 
 // NewChannelController initialize new channels controller
-func NewChannelController(consumersController *ConsumersController, messagesController *MessagesController, broadcastController *BroadcastController, channelRepo storage.ChannelRepository) *ChannelController {
-	return &ChannelController{ChannelRepo: channelRepo, ConsumersEndpoint: consumersController, MessagesEndpoint: messagesController, BroadcastEndpoint: broadcastController}
+func NewChannelController(consumersController *ConsumersController, messagesController *MessagesController, broadcastController *BroadcastController, channelRepo storage.ChannelRepository, systemEvents dispatcher.SystemEventPublisher, quotaRepo storage.PublishQuotaRepository, rateLimitConfig config.RateLimitConfig, blobStore storage.BlobStore, brokerConfig config.BrokerConfig) *ChannelController {
+	return &ChannelController{ChannelRepo: channelRepo, ConsumersEndpoint: consumersController, MessagesEndpoint: messagesController, BroadcastEndpoint: broadcastController,
+		SystemEvents: systemEvents, QuotaRepo: quotaRepo, RateLimitConfig: rateLimitConfig, BlobStore: blobStore, PurgeRetention: brokerConfig.GetPurgeRetention()}
 }
 
 // NewChannelsController initialize new channels controller
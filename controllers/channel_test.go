@@ -13,6 +13,8 @@ import (
 
 	"github.com/rs/zerolog/log"
 
+	"github.com/newscred/webhook-broker/dispatcher"
+	dispatchermocks "github.com/newscred/webhook-broker/dispatcher/mocks"
 	"github.com/newscred/webhook-broker/storage"
 	"github.com/newscred/webhook-broker/storage/data"
 	storagemocks "github.com/newscred/webhook-broker/storage/mocks"
@@ -23,9 +25,10 @@ import (
 var channelRepo storage.ChannelRepository
 
 const (
-	listTestChannelIDPrefix    = "controller-get-list-"
-	createChannelIDWithData    = "put-channel-id"
-	createChannelIDWithoutData = "put-channel-id-without-data"
+	listTestChannelIDPrefix           = "controller-get-list-"
+	createChannelIDWithData           = "put-channel-id"
+	createChannelIDWithoutData        = "put-channel-id-without-data"
+	createChannelIDWithMaxPayloadSize = "put-channel-id-with-max-payload-size"
 )
 
 // ChannelTestSetup is called from TestMain for the package
@@ -150,7 +153,8 @@ func TestChannelGet(t *testing.T) {
 
 func getNewChannelController(channelRepo storage.ChannelRepository) *ChannelController {
 	bc, _ := getNewBroadcastController(messageRepo)
-	return NewChannelController(NewConsumersController(NewConsumerController(nil, nil, getDLQControllerWithMockedRepo()), nil), getMessagesController(), bc, channelRepo)
+	return NewChannelController(NewConsumersController(NewConsumerController(nil, nil, nil, getDLQControllerWithMockedRepo(), NewConsumerVerificationController(nil, nil), nil, getMockedSystemEventPublisher(), configuration, configuration), nil), getMessagesController(), bc, channelRepo,
+		getMockedSystemEventPublisher(), quotaRepo, configuration, nil, configuration)
 }
 
 func TestChannelPut(t *testing.T) {
@@ -207,6 +211,32 @@ func TestChannelPut(t *testing.T) {
 		assert.Contains(t, updatedBodyChannel.Token, "Updated")
 		assert.True(t, bodyChannel.ChangedAt.Before(updatedBodyChannel.ChangedAt))
 	})
+	t.Run("SuccessfulPutCreateWithMaxPayloadSize", func(t *testing.T) {
+		t.Parallel()
+		testRouter := createTestRouter(getNewChannelController(channelRepo))
+		req, _ := http.NewRequest("PUT", "/channel/"+createChannelIDWithMaxPayloadSize, nil)
+		req.Header.Add(headerContentType, formDataContentTypeHeaderValue)
+		req.PostForm = url.Values{}
+		req.PostForm.Add("maxPayloadSize", "2048")
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		bodyChannel := &ChannelModel{}
+		json.NewDecoder(strings.NewReader(rr.Body.String())).Decode(bodyChannel)
+		assert.Equal(t, createChannelIDWithMaxPayloadSize, bodyChannel.ID)
+		assert.Equal(t, uint(2048), bodyChannel.MaxPayloadSize)
+	})
+	t.Run("400:MaxPayloadSize", func(t *testing.T) {
+		t.Parallel()
+		testRouter := createTestRouter(getNewChannelController(channelRepo))
+		req, _ := http.NewRequest("PUT", "/channel/"+listTestChannelIDPrefix+"0", nil)
+		req.Header.Add(headerContentType, formDataContentTypeHeaderValue)
+		req.PostForm = url.Values{}
+		req.PostForm.Add("maxPayloadSize", "-1")
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
 	t.Run("415", func(t *testing.T) {
 		t.Parallel()
 		testRouter := createTestRouter(getNewChannelController(channelRepo))
@@ -248,3 +278,30 @@ func TestChannelPut(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, rr.Code)
 	})
 }
+
+func TestChannelPut_SystemEvent(t *testing.T) {
+	mockPublisher := new(dispatchermocks.SystemEventPublisher)
+	controller := getNewChannelController(channelRepo)
+	controller.SystemEvents = mockPublisher
+	testRouter := createTestRouter(controller)
+	mockPublisher.On("Publish", data.SystemEventChannelUpdated, "", &dispatcher.ChannelEventData{ChannelID: "system-event-channel", Created: true}).Return().Once()
+	req, _ := http.NewRequest("PUT", "/channel/system-event-channel", nil)
+	req.Header.Add(headerContentType, formDataContentTypeHeaderValue)
+	req.PostForm = url.Values{}
+	req.PostForm.Add("token", "system-event-token")
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	channel, err := channelRepo.Get("system-event-channel")
+	assert.Nil(t, err)
+	mockPublisher.On("Publish", data.SystemEventChannelUpdated, "", &dispatcher.ChannelEventData{ChannelID: "system-event-channel", TokenRotated: true}).Return().Once()
+	req, _ = http.NewRequest("PUT", "/channel/system-event-channel", nil)
+	req.Header.Add(headerContentType, formDataContentTypeHeaderValue)
+	req.Header.Add(headerUnmodifiedSince, channel.GetLastUpdatedHTTPTimeString())
+	req.PostForm = url.Values{}
+	req.PostForm.Add("token", "system-event-token-rotated")
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockPublisher.AssertExpectations(t)
+}
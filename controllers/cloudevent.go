@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+const (
+	headerCloudEventSpecVersion     = "Ce-Specversion"
+	headerCloudEventID              = "Ce-Id"
+	headerCloudEventSource          = "Ce-Source"
+	headerCloudEventType            = "Ce-Type"
+	headerCloudEventSubject         = "Ce-Subject"
+	headerCloudEventTime            = "Ce-Time"
+	structuredCloudEventContentType = "application/cloudevents+json"
+	defaultCloudEventDataType       = "application/json"
+)
+
+// CloudEventModel is the CloudEvents metadata of a message broadcasted as a CloudEvent
+type CloudEventModel struct {
+	Type    string
+	Source  string
+	Subject string     `json:",omitempty"`
+	Time    *time.Time `json:",omitempty"`
+}
+
+func newCloudEventModel(cloudEvent data.CloudEvent) *CloudEventModel {
+	if cloudEvent.IsZero() {
+		return nil
+	}
+	model := &CloudEventModel{Type: cloudEvent.Type, Source: cloudEvent.Source, Subject: cloudEvent.Subject}
+	if !cloudEvent.Time.IsZero() {
+		model.Time = &cloudEvent.Time
+	}
+	return model
+}
+
+// incomingCloudEvent is a CloudEvent received on broadcast, its ID becomes the message ID and its data content type the message's content type
+type incomingCloudEvent struct {
+	ID          string
+	ContentType string
+	Metadata    data.CloudEvent
+}
+
+// structuredCloudEvent is the JSON format of a structured mode CloudEvent; extension attributes are ignored
+type structuredCloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject"`
+	Time            string          `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+	DataBase64      string          `json:"data_base64"`
+}
+
+func isStructuredCloudEvent(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == structuredCloudEventContentType
+}
+
+// newIncomingCloudEvent validates the required attributes and the optional time of a CloudEvent
+func newIncomingCloudEvent(specVersion, id, source, eventType, subject, eventTime, contentType string) (*incomingCloudEvent, error) {
+	if specVersion != data.CloudEventsSpecVersion || len(id) <= 0 {
+		return nil, ErrBadRequestForCloudEvent
+	}
+	cloudEvent := &incomingCloudEvent{ID: id, ContentType: contentType, Metadata: data.CloudEvent{Type: eventType, Source: source, Subject: subject}}
+	if len(eventTime) > 0 {
+		var err error
+		if cloudEvent.Metadata.Time, err = time.Parse(time.RFC3339Nano, eventTime); err != nil {
+			return nil, ErrBadRequestForCloudEvent
+		}
+	}
+	if len(eventType) <= 0 || len(source) <= 0 || !cloudEvent.Metadata.IsValid() {
+		return nil, ErrBadRequestForCloudEvent
+	}
+	return cloudEvent, nil
+}
+
+// getBinaryCloudEvent returns the binary mode CloudEvent whose attributes are sent as `ce-*` headers; nil if the request has no `ce-specversion` header
+func getBinaryCloudEvent(header http.Header, contentType string) (*incomingCloudEvent, error) {
+	if len(header.Get(headerCloudEventSpecVersion)) <= 0 {
+		return nil, nil
+	}
+	return newIncomingCloudEvent(header.Get(headerCloudEventSpecVersion), header.Get(headerCloudEventID), header.Get(headerCloudEventSource),
+		header.Get(headerCloudEventType), header.Get(headerCloudEventSubject), header.Get(headerCloudEventTime), contentType)
+}
+
+// readStructuredCloudEvent reads the whole body as a structured mode CloudEvent and returns the reader of its data along with the event; the data is
+// `data_base64` decoded if present, else `data` as is if the data content type is JSON, else `data` unquoted if it is a JSON string
+func readStructuredCloudEvent(body io.Reader) (io.Reader, *incomingCloudEvent, error) {
+	content, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, nil, err
+	}
+	event := &structuredCloudEvent{}
+	if err = json.Unmarshal(content, event); err != nil {
+		return nil, nil, ErrBadRequestForCloudEvent
+	}
+	contentType := event.DataContentType
+	if len(contentType) <= 0 {
+		contentType = defaultCloudEventDataType
+	}
+	cloudEvent, err := newIncomingCloudEvent(event.SpecVersion, event.ID, event.Source, event.Type, event.Subject, event.Time, contentType)
+	if err != nil {
+		return nil, nil, err
+	}
+	var payload []byte
+	switch {
+	case len(event.DataBase64) > 0:
+		if payload, err = base64.StdEncoding.DecodeString(event.DataBase64); err != nil {
+			return nil, nil, ErrBadRequestForCloudEvent
+		}
+	case !isJSONContentType(contentType) && bytes.HasPrefix(event.Data, []byte(`"`)):
+		var text string
+		if err = json.Unmarshal(event.Data, &text); err != nil {
+			return nil, nil, ErrBadRequestForCloudEvent
+		}
+		payload = []byte(text)
+	case !bytes.Equal(event.Data, []byte("null")):
+		payload = event.Data
+	}
+	return bytes.NewReader(payload), cloudEvent, nil
+}
+
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == defaultCloudEventDataType || strings.HasSuffix(mediaType, "+json"))
+}
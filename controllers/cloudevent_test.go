@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/newscred/webhook-broker/storage/data"
+	storagemocks "github.com/newscred/webhook-broker/storage/mocks"
+)
+
+func TestBroadcastControllerCloudEvents(t *testing.T) {
+	eventTime := time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)
+	getRequest := func(controller *BroadcastController, contentType, body string) *http.Request {
+		req := getPayloadLimitTestRequest(controller.FormatAsRelativeLink(getRouterParam(consumerTestChannel.ChannelID)), consumerTestChannel, body)
+		req.Header.Set(headerContentType, contentType)
+		return req
+	}
+	acceptedCases := map[string]struct {
+		contentType string
+		header      map[string]string
+		body        string
+		matcher     func(msg *data.Message) bool
+	}{
+		"Binary": {"application/json", map[string]string{"ce-specversion": "1.0", "ce-id": "binary-event-1", "ce-source": "/orders",
+			"ce-type": "com.example.order.created", "ce-subject": "order-1", "ce-time": "2026-10-01T10:00:00Z", headerMessageID: "ignored"}, `{"id": 1}`,
+			func(msg *data.Message) bool {
+				return msg.MessageID == "binary-event-1" && msg.Payload == `{"id": 1}` && msg.ContentType == "application/json" &&
+					msg.CloudEvent == data.CloudEvent{Type: "com.example.order.created", Source: "/orders", Subject: "order-1", Time: eventTime}
+			}},
+		"StructuredJSON": {"application/cloudevents+json; charset=utf-8", nil, `{"specversion": "1.0", "id": "structured-event-1", "source": "/orders",
+			"type": "com.example.order.created", "time": "2026-10-01T10:00:00Z", "data": {"id": 1}}`,
+			func(msg *data.Message) bool {
+				return msg.MessageID == "structured-event-1" && msg.Payload == `{"id": 1}` && msg.ContentType == "application/json" &&
+					msg.CloudEvent == data.CloudEvent{Type: "com.example.order.created", Source: "/orders", Time: eventTime}
+			}},
+		"StructuredText": {"application/cloudevents+json", nil, `{"specversion": "1.0", "id": "structured-event-2", "source": "/orders",
+			"type": "com.example.order.noted", "datacontenttype": "text/plain", "data": "a note"}`,
+			func(msg *data.Message) bool {
+				return msg.MessageID == "structured-event-2" && msg.Payload == "a note" && msg.ContentType == "text/plain" && msg.CloudEvent.Time.IsZero()
+			}},
+		"StructuredBase64": {"application/cloudevents+json", nil, `{"specversion": "1.0", "id": "structured-event-3", "source": "/orders",
+			"type": "com.example.order.scanned", "datacontenttype": "application/octet-stream", "data_base64": "AAFiaW5hcnk="}`,
+			func(msg *data.Message) bool {
+				return msg.MessageID == "structured-event-3" && msg.Payload == "\x00\x01binary" && msg.ContentType == "application/octet-stream"
+			}},
+	}
+	for name, testCase := range acceptedCases {
+		testCase := testCase
+		t.Run(name+":202", func(t *testing.T) {
+			t.Parallel()
+			msgRepo := new(storagemocks.MessageRepository)
+			controller, mockDispatcher := getNewBroadcastController(msgRepo)
+			req := getRequest(controller, testCase.contentType, testCase.body)
+			for key, value := range testCase.header {
+				req.Header.Set(key, value)
+			}
+			matcher := func(msg *data.Message) bool { return testCase.matcher(msg) && msg.IsInValidState() }
+			msgRepo.On("Create", mock.MatchedBy(matcher)).Return(nil)
+			wg := setupAsyncDispatchMock(mockDispatcher, matcher)
+			rr := httptest.NewRecorder()
+			createTestRouter(controller).ServeHTTP(rr, req)
+			wg.Wait()
+			assert.Equal(t, http.StatusAccepted, rr.Code)
+			msgRepo.AssertExpectations(t)
+			mockDispatcher.AssertExpectations(t)
+		})
+	}
+	rejectedCases := map[string]struct {
+		contentType string
+		header      map[string]string
+		body        string
+	}{
+		"BinaryWrongSpecVersion":  {"text/plain", map[string]string{"ce-specversion": "0.3", "ce-id": "1", "ce-source": "/orders", "ce-type": "created"}, "body"},
+		"BinaryMissingID":         {"text/plain", map[string]string{"ce-specversion": "1.0", "ce-source": "/orders", "ce-type": "created"}, "body"},
+		"BinaryMissingType":       {"text/plain", map[string]string{"ce-specversion": "1.0", "ce-id": "1", "ce-source": "/orders"}, "body"},
+		"BinaryInvalidTime":       {"text/plain", map[string]string{"ce-specversion": "1.0", "ce-id": "1", "ce-source": "/orders", "ce-type": "created", "ce-time": "yesterday"}, "body"},
+		"StructuredInvalidJSON":   {"application/cloudevents+json", nil, `{"specversion": "1.0"`},
+		"StructuredMissingSource": {"application/cloudevents+json", nil, `{"specversion": "1.0", "id": "1", "type": "created", "data": {}}`},
+		"StructuredInvalidBase64": {"application/cloudevents+json", nil, `{"specversion": "1.0", "id": "1", "source": "/orders", "type": "created", "data_base64": "!"}`},
+	}
+	for name, testCase := range rejectedCases {
+		testCase := testCase
+		t.Run(name+":400", func(t *testing.T) {
+			t.Parallel()
+			msgRepo := new(storagemocks.MessageRepository)
+			controller, mockDispatcher := getNewBroadcastController(msgRepo)
+			req := getRequest(controller, testCase.contentType, testCase.body)
+			for key, value := range testCase.header {
+				req.Header.Set(key, value)
+			}
+			rr := httptest.NewRecorder()
+			createTestRouter(controller).ServeHTTP(rr, req)
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			assert.Equal(t, ErrBadRequestForCloudEvent.Error(), rr.Body.String())
+			msgRepo.AssertExpectations(t)
+			mockDispatcher.AssertExpectations(t)
+		})
+	}
+}
+
+func TestNewCloudEventModel(t *testing.T) {
+	assert.Nil(t, newCloudEventModel(data.CloudEvent{}))
+	model := newCloudEventModel(data.CloudEvent{Type: "created", Source: "/orders"})
+	assert.Equal(t, &CloudEventModel{Type: "created", Source: "/orders"}, model)
+	eventTime := time.Now()
+	model = newCloudEventModel(data.CloudEvent{Type: "created", Source: "/orders", Subject: "order-1", Time: eventTime})
+	assert.Equal(t, "order-1", model.Subject)
+	assert.Equal(t, eventTime, *model.Time)
+}
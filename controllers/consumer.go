@@ -4,16 +4,27 @@ import (
 	"database/sql"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/newscred/webhook-broker/config"
+	"github.com/newscred/webhook-broker/dispatcher"
 	"github.com/newscred/webhook-broker/storage"
 	"github.com/newscred/webhook-broker/storage/data"
 )
 
 const (
-	consumersPath          = channelPath + "/consumers"
-	consumerIDPathParamKey = "consumerId"
-	consumerPath           = channelPath + "/consumer/:" + consumerIDPathParamKey
+	consumersPath              = channelPath + "/consumers"
+	consumerIDPathParamKey     = "consumerId"
+	consumerPath               = channelPath + "/consumer/:" + consumerIDPathParamKey
+	consumerVerifyPath         = consumerPath + "/verify"
+	consumerPausePath          = consumerPath + "/pause"
+	consumerResumePath         = consumerPath + "/resume"
+	consumerTokenFormParamName = "token"
+	backfillSinceFormParamName = "backfillSince"
+	routingKeyPatternParamName = "routingKeyPattern"
+	consumerTypeFormParamName  = "type"
+	deliveryFormatParamName    = "deliveryFormat"
 )
 
 // ConsumerModel represents the data communicated to HTTP clients
@@ -21,34 +32,96 @@ type ConsumerModel struct {
 	MsgStakeholder
 	CallbackURL        string
 	DeadLetterQueueURL string
+	VerificationStatus string
+	VerificationURL    string
+	Paused             bool
+	RoutingKeyPattern  string
+	Type               string
+	DeliveryFormat     string
+	Backfill           *BackfillModel `json:",omitempty"`
+}
+
+// BackfillModel represents the progress of delivering a channel's past messages to a newly created consumer
+type BackfillModel struct {
+	Since          time.Time
+	Until          time.Time
+	JobCount       uint
+	QueuedCount    uint
+	InflightCount  uint
+	DeliveredCount uint
+	DeadCount      uint
+	DiscardedCount uint
+	CancelledCount uint
+	JobsCreated    bool
+	Complete       bool
+}
+
+func newBackfillModel(backfill *data.Backfill) *BackfillModel {
+	return &BackfillModel{Since: backfill.Since, Until: backfill.Until, JobCount: backfill.JobCount, QueuedCount: backfill.QueuedCount,
+		InflightCount: backfill.InflightCount, DeliveredCount: backfill.DeliveredCount, DeadCount: backfill.DeadCount,
+		DiscardedCount: backfill.DiscardedCount, CancelledCount: backfill.CancelledCount, JobsCreated: backfill.JobsCreated, Complete: backfill.IsComplete()}
 }
 
 // ConsumerController represents all endpoints related to a single consumer for a channel
 type ConsumerController struct {
-	ConsumerRepo storage.ConsumerRepository
-	ChannelRepo  storage.ChannelRepository
-	DLQEndpoint  EndpointController
+	ConsumerRepo         storage.ConsumerRepository
+	ChannelRepo          storage.ChannelRepository
+	DeliveryJobRepo      storage.DeliveryJobRepository
+	DLQEndpoint          EndpointController
+	VerifyEndpoint       EndpointController
+	Verifier             dispatcher.ConsumerVerifier
+	SystemEvents         dispatcher.SystemEventPublisher
+	VerificationRequired bool
+	PurgeRetention       time.Duration
 }
 
 // NewConsumerController creates and returns a new instance of ConsumerController
-func NewConsumerController(channelRepo storage.ChannelRepository, consumerRepo storage.ConsumerRepository, DLQController *DLQController) *ConsumerController {
-	return &ConsumerController{ConsumerRepo: consumerRepo, ChannelRepo: channelRepo, DLQEndpoint: DLQController}
+func NewConsumerController(channelRepo storage.ChannelRepository, consumerRepo storage.ConsumerRepository, djRepo storage.DeliveryJobRepository, DLQController *DLQController, verifyController *ConsumerVerificationController, verifier dispatcher.ConsumerVerifier, systemEvents dispatcher.SystemEventPublisher, consumerConfig config.ConsumerConnectionConfig, brokerConfig config.BrokerConfig) *ConsumerController {
+	return &ConsumerController{ConsumerRepo: consumerRepo, ChannelRepo: channelRepo, DeliveryJobRepo: djRepo, DLQEndpoint: DLQController, VerifyEndpoint: verifyController, Verifier: verifier, SystemEvents: systemEvents,
+		VerificationRequired: consumerConfig.IsCallbackVerificationEnabled(), PurgeRetention: brokerConfig.GetPurgeRetention()}
 }
 
 // Get implements the GET /channel/:channelId/consumer/:consumerId endpoint
 func (controller *ConsumerController) Get(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	consumer, err := controller.ConsumerRepo.Get(findParam(params, channelIDPathParamKey), findParam(params, consumerIDPathParamKey))
-	consumerModel := controller.getConsumerModel(consumer)
+	if err != nil {
+		writeNotFound(w)
+		return
+	}
+	consumerModel, err := controller.getConsumerModelWithBackfill(consumer)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
 	writeGetResult(err, writeNotFound, w, consumerModel)
 }
 
+// getConsumerModelWithBackfill returns the consumer model along with the progress of its backfill, if it was backfilled
+func (controller *ConsumerController) getConsumerModelWithBackfill(consumer *data.Consumer) (*ConsumerModel, error) {
+	consumerModel := controller.getConsumerModel(consumer)
+	backfill, err := controller.DeliveryJobRepo.GetBackfillForConsumer(consumer)
+	switch err {
+	case nil:
+		consumerModel.Backfill = newBackfillModel(backfill)
+	case sql.ErrNoRows:
+		err = nil
+	}
+	return consumerModel, err
+}
+
 func (controller *ConsumerController) getConsumerModel(consumer *data.Consumer) *ConsumerModel {
 	channelIDParam := httprouter.Param{Key: channelIDPathParamKey, Value: consumer.ConsumingFrom.ChannelID}
 	consumerIDParam := httprouter.Param{Key: consumerIDPathParamKey, Value: consumer.ConsumerID}
 	consumerModel := &ConsumerModel{
 		MsgStakeholder:     *getMessageStakeholder(consumer.ConsumerID, &consumer.MessageStakeholder),
 		CallbackURL:        consumer.CallbackURL,
-		DeadLetterQueueURL: controller.DLQEndpoint.FormatAsRelativeLink(channelIDParam, consumerIDParam)}
+		DeadLetterQueueURL: controller.DLQEndpoint.FormatAsRelativeLink(channelIDParam, consumerIDParam),
+		VerificationStatus: consumer.VerificationStatus.String(),
+		VerificationURL:    controller.VerifyEndpoint.FormatAsRelativeLink(channelIDParam, consumerIDParam),
+		Paused:             consumer.Paused,
+		RoutingKeyPattern:  consumer.RoutingKeyPattern,
+		Type:               consumer.Type.String(),
+		DeliveryFormat:     consumer.DeliveryFormat.String()}
 	return consumerModel
 }
 
@@ -56,6 +129,7 @@ func (controller *ConsumerController) getConsumerModel(consumer *data.Consumer)
 func (controller *ConsumerController) Put(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	validRequest := checkFormContentType(r, w)
 	var channel *data.Channel
+	var existingConsumer *data.Consumer
 	var err error
 	channelID := findParam(params, channelIDPathParamKey)
 	consumerID := findParam(params, consumerIDPathParamKey)
@@ -69,6 +143,7 @@ func (controller *ConsumerController) Put(w http.ResponseWriter, r *http.Request
 	if validRequest {
 		consumer, err := controller.ConsumerRepo.Get(channelID, consumerID)
 		if err == nil {
+			existingConsumer = consumer
 			validRequest = isConditionalUpdateCalled(w, r, consumer)
 		}
 	}
@@ -76,34 +151,113 @@ func (controller *ConsumerController) Put(w http.ResponseWriter, r *http.Request
 		return
 	}
 	token, name := getUpdateData(r, consumerID)
+	consumerType, ok := data.ParseConsumerType(r.PostFormValue(consumerTypeFormParamName))
+	if !ok {
+		writeStatus(w, http.StatusBadRequest, ErrBadRequestForConsumerType)
+		return
+	}
+	// Delivery format only applies to calls to the callback URL, streamed events have a format of their own
+	deliveryFormat, ok := data.ParseDeliveryFormat(r.PostFormValue(deliveryFormatParamName))
+	if !ok {
+		writeStatus(w, http.StatusBadRequest, ErrBadRequestForDeliveryFormat)
+		return
+	}
+	// Streaming consumers hold a connection open to receive messages, so they have no callback URL
 	urlString := r.PostFormValue("callbackUrl")
 	callbackURL, uErr := url.Parse(urlString)
-	if len(urlString) < 1 || uErr != nil || !callbackURL.IsAbs() {
+	if consumerType == data.PushConsumer && (len(urlString) < 1 || uErr != nil || !callbackURL.IsAbs()) {
 		writeBadRequest(w)
 		return
 	}
-	inComingConsumer, _ := data.NewConsumer(channel, consumerID, token, callbackURL)
+	// Backfill is only honored when the consumer is created
+	var backfillSince time.Time
+	if backfillSinceString := r.PostFormValue(backfillSinceFormParamName); existingConsumer == nil && len(backfillSinceString) > 0 {
+		var bErr error
+		if backfillSince, bErr = parseBackfillSince(backfillSinceString); bErr != nil || !backfillSince.Before(time.Now()) {
+			writeStatus(w, http.StatusBadRequest, ErrBadRequestForBackfill)
+			return
+		}
+	}
+	routingKeyPattern := r.PostFormValue(routingKeyPatternParamName)
+	if !data.IsValidRoutingKeyPattern(routingKeyPattern) {
+		writeStatus(w, http.StatusBadRequest, ErrBadRequestForRoutingKeyPattern)
+		return
+	}
+	var inComingConsumer *data.Consumer
+	if consumerType == data.StreamConsumer {
+		inComingConsumer, _ = data.NewStreamConsumer(channel, consumerID, token)
+	} else {
+		inComingConsumer, _ = data.NewConsumer(channel, consumerID, token, callbackURL)
+	}
 	inComingConsumer.Name = name
+	inComingConsumer.RoutingKeyPattern = routingKeyPattern
+	inComingConsumer.DeliveryFormat = deliveryFormat
+	verify := controller.setupVerification(existingConsumer, inComingConsumer)
 	consumer, updateErr := controller.ConsumerRepo.Store(inComingConsumer)
-	writeGetResult(updateErr, func(w http.ResponseWriter) { writeErr(w, updateErr) }, w, controller.getConsumerModel(consumer))
+	if updateErr == nil && verify {
+		go controller.Verifier.VerifyWithRetry(consumer)
+	}
+	if updateErr == nil && !backfillSince.IsZero() {
+		updateErr = controller.backfill(consumer, backfillSince)
+	}
+	var consumerModel *ConsumerModel
+	if updateErr == nil {
+		consumerModel, updateErr = controller.getConsumerModelWithBackfill(consumer)
+	}
+	if updateErr != nil {
+		writeStoreErr(w, updateErr)
+		return
+	}
+	controller.SystemEvents.Publish(data.SystemEventConsumerUpdated, "", newConsumerEventData(consumer, existingConsumer == nil))
+	writeGetResult(updateErr, func(w http.ResponseWriter) { writeErr(w, updateErr) }, w, consumerModel)
+}
+
+func newConsumerEventData(consumer *data.Consumer, created bool) *dispatcher.ConsumerEventData {
+	return &dispatcher.ConsumerEventData{ConsumerID: consumer.ConsumerID, ChannelID: consumer.GetChannelIDSafely(), CallbackURL: consumer.CallbackURL,
+		RoutingKeyPattern: consumer.RoutingKeyPattern, Created: created}
+}
+
+// parseBackfillSince accepts either a duration to look back from now, e.g. `24h`, or a RFC3339 timestamp
+func parseBackfillSince(value string) (time.Time, error) {
+	if duration, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-1 * duration), nil
+	}
+	return time.Parse(time.RFC3339, value)
 }
 
-// Delete implements the DELETE /channel/:channelId/consumer/:consumerId endpoint
+// backfill records the backfill of the channel messages received since the time provided; its delivery jobs are created by the dispatcher's
+// recovery workers, spread out at the configured catch-up rate, so that a long backfill window does not hold up the request
+func (controller *ConsumerController) backfill(consumer *data.Consumer, since time.Time) error {
+	backfill, err := data.NewBackfill(consumer, since)
+	if err == nil {
+		err = controller.DeliveryJobRepo.CreateBackfill(backfill)
+	}
+	return err
+}
+
+// setupVerification decides the verification state of the consumer to be stored and returns whether a handshake needs to be initiated
+func (controller *ConsumerController) setupVerification(existingConsumer *data.Consumer, consumer *data.Consumer) bool {
+	if !controller.VerificationRequired || consumer.IsStreaming() {
+		return false
+	}
+	if existingConsumer != nil && existingConsumer.CallbackURL == consumer.CallbackURL {
+		consumer.VerificationStatus = existingConsumer.VerificationStatus
+		consumer.VerificationChallenge = existingConsumer.VerificationChallenge
+		return false
+	}
+	consumer.VerificationStatus = data.ConsumerPendingVerification
+	consumer.VerificationChallenge = randomToken()
+	return true
+}
+
+// Delete implements the DELETE /channel/:channelId/consumer/:consumerId endpoint; soft deletes the consumer or, with `purge=true`,
+// permanently deletes it once its retention is over
 func (controller *ConsumerController) Delete(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
-	consumer, err := controller.ConsumerRepo.Get(findParam(params, channelIDPathParamKey), findParam(params, consumerIDPathParamKey))
-	switch err {
-	case nil:
-		if validRequest := isConditionalUpdateCalled(w, r, consumer); !validRequest {
-			writePreconditionFailed(w)
-		} else if delErr := controller.ConsumerRepo.Delete(consumer); delErr != nil {
-			writeErr(w, delErr)
-		} else {
-			writeStatus(w, http.StatusNoContent, nil)
-		}
-	case sql.ErrNoRows:
-		writeNotFound(w)
-	default:
-		writeErr(w, err)
+	consumer, err := controller.ConsumerRepo.GetIncludingDeleted(findParam(params, channelIDPathParamKey), findParam(params, consumerIDPathParamKey))
+	softDelete := func(drain bool) error { return controller.ConsumerRepo.Delete(consumer, drain) }
+	purge := func() error { return controller.ConsumerRepo.Purge(consumer) }
+	if deleteStakeholder(w, r, consumer, err, controller.PurgeRetention, softDelete, purge) {
+		controller.SystemEvents.Publish(data.SystemEventConsumerDeleted, "", newConsumerEventData(consumer, false))
 	}
 }
 
@@ -159,3 +313,148 @@ func (controller *ConsumersController) GetPath() string {
 func (controller *ConsumersController) FormatAsRelativeLink(params ...httprouter.Param) string {
 	return formatURL(params, consumersPath, channelIDPathParamKey)
 }
+
+// ConsumerVerificationController represents the endpoint for re-triggering the callback URL verification handshake of a consumer
+type ConsumerVerificationController struct {
+	ConsumerRepo storage.ConsumerRepository
+	Verifier     dispatcher.ConsumerVerifier
+}
+
+// NewConsumerVerificationController creates and returns a new instance of ConsumerVerificationController
+func NewConsumerVerificationController(consumerRepo storage.ConsumerRepository, verifier dispatcher.ConsumerVerifier) *ConsumerVerificationController {
+	return &ConsumerVerificationController{ConsumerRepo: consumerRepo, Verifier: verifier}
+}
+
+// Post implements the POST /channel/:channelId/consumer/:consumerId/verify endpoint; it issues a fresh challenge to a consumer pending verification
+func (controller *ConsumerVerificationController) Post(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	consumer := getConsumerForTokenAuthorizedPost(controller.ConsumerRepo, w, r, params)
+	if consumer == nil {
+		return
+	}
+	if consumer.IsVerified() {
+		writeStatus(w, http.StatusConflict, ErrConsumerAlreadyVerified)
+		return
+	}
+	update := *consumer
+	update.VerificationChallenge = randomToken()
+	consumer, err := controller.ConsumerRepo.Store(&update)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	go controller.Verifier.VerifyWithRetry(consumer)
+	writeStatus(w, http.StatusAccepted, nil)
+}
+
+// GetPath returns the endpoint's path
+func (controller *ConsumerVerificationController) GetPath() string {
+	return consumerVerifyPath
+}
+
+// FormatAsRelativeLink formats this controllers URL with the parameters provided. Both `consumerId` and `channelId` params must be sent else it will return the templated URL
+func (controller *ConsumerVerificationController) FormatAsRelativeLink(params ...httprouter.Param) string {
+	return formatURL(params, consumerVerifyPath, channelIDPathParamKey, consumerIDPathParamKey)
+}
+
+// getConsumerForTokenAuthorizedPost loads the consumer addressed by the path, provided the request is a form carrying the consumer's token; else
+// writes the error response and returns nil
+func getConsumerForTokenAuthorizedPost(consumerRepo storage.ConsumerRepository, w http.ResponseWriter, r *http.Request, params httprouter.Params) *data.Consumer {
+	if validRequest := checkFormContentType(r, w); !validRequest {
+		return nil
+	}
+	consumer, err := consumerRepo.Get(findParam(params, channelIDPathParamKey), findParam(params, consumerIDPathParamKey))
+	switch err {
+	case nil:
+	case sql.ErrNoRows:
+		writeNotFound(w)
+		return nil
+	default:
+		writeErr(w, err)
+		return nil
+	}
+	if r.PostFormValue(consumerTokenFormParamName) != consumer.Token {
+		writeStatus(w, http.StatusBadRequest, ErrBadRequestForConsumerToken)
+		return nil
+	}
+	return consumer
+}
+
+// ConsumerPauseController represents the endpoint for pausing deliveries to a consumer
+type ConsumerPauseController struct {
+	ConsumerRepo storage.ConsumerRepository
+}
+
+// NewConsumerPauseController creates and returns a new instance of ConsumerPauseController
+func NewConsumerPauseController(consumerRepo storage.ConsumerRepository) *ConsumerPauseController {
+	return &ConsumerPauseController{ConsumerRepo: consumerRepo}
+}
+
+// Post implements the POST /channel/:channelId/consumer/:consumerId/pause endpoint; jobs keep getting queued for the consumer but are not attempted
+func (controller *ConsumerPauseController) Post(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	consumer := getConsumerForTokenAuthorizedPost(controller.ConsumerRepo, w, r, params)
+	if consumer == nil {
+		return
+	}
+	if !consumer.Paused {
+		if err := controller.ConsumerRepo.SetPaused(consumer, true); err != nil {
+			writeErr(w, err)
+			return
+		}
+	}
+	writeStatus(w, http.StatusNoContent, nil)
+}
+
+// GetPath returns the endpoint's path
+func (controller *ConsumerPauseController) GetPath() string {
+	return consumerPausePath
+}
+
+// FormatAsRelativeLink formats this controllers URL with the parameters provided. Both `consumerId` and `channelId` params must be sent else it will return the templated URL
+func (controller *ConsumerPauseController) FormatAsRelativeLink(params ...httprouter.Param) string {
+	return formatURL(params, consumerPausePath, channelIDPathParamKey, consumerIDPathParamKey)
+}
+
+// ConsumerResumeController represents the endpoint for resuming deliveries to a paused consumer
+type ConsumerResumeController struct {
+	ConsumerRepo    storage.ConsumerRepository
+	DeliveryJobRepo storage.DeliveryJobRepository
+	CatchUpRate     uint
+}
+
+// NewConsumerResumeController creates and returns a new instance of ConsumerResumeController
+func NewConsumerResumeController(consumerRepo storage.ConsumerRepository, djRepo storage.DeliveryJobRepository, brokerConfig config.BrokerConfig) *ConsumerResumeController {
+	return &ConsumerResumeController{ConsumerRepo: consumerRepo, DeliveryJobRepo: djRepo, CatchUpRate: brokerConfig.GetResumeCatchUpRate()}
+}
+
+// Post implements the POST /channel/:channelId/consumer/:consumerId/resume endpoint; the jobs queued while paused are rescheduled to drain
+// at the configured catch-up rate before the consumer is marked active again, to be picked up by the dispatcher's retry recovery worker
+func (controller *ConsumerResumeController) Post(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	consumer := getConsumerForTokenAuthorizedPost(controller.ConsumerRepo, w, r, params)
+	if consumer == nil {
+		return
+	}
+	if consumer.Paused {
+		var err error
+		if controller.CatchUpRate > 0 {
+			err = controller.DeliveryJobRepo.RescheduleQueuedJobsForConsumer(consumer, time.Now(), time.Second/time.Duration(controller.CatchUpRate))
+		}
+		if err == nil {
+			err = controller.ConsumerRepo.SetPaused(consumer, false)
+		}
+		if err != nil {
+			writeErr(w, err)
+			return
+		}
+	}
+	writeStatus(w, http.StatusAccepted, nil)
+}
+
+// GetPath returns the endpoint's path
+func (controller *ConsumerResumeController) GetPath() string {
+	return consumerResumePath
+}
+
+// FormatAsRelativeLink formats this controllers URL with the parameters provided. Both `consumerId` and `channelId` params must be sent else it will return the templated URL
+func (controller *ConsumerResumeController) FormatAsRelativeLink(params ...httprouter.Param) string {
+	return formatURL(params, consumerResumePath, channelIDPathParamKey, consumerIDPathParamKey)
+}
@@ -14,6 +14,8 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/newscred/webhook-broker/dispatcher"
+	dispatchermocks "github.com/newscred/webhook-broker/dispatcher/mocks"
 	"github.com/newscred/webhook-broker/storage"
 	"github.com/newscred/webhook-broker/storage/data"
 	storagemocks "github.com/newscred/webhook-broker/storage/mocks"
@@ -30,12 +32,13 @@ var (
 )
 
 const (
-	listTestConsumerIDPrefix    = "consumer-get-list-"
-	channelTestConsumerID       = "consumer-channel-some-id"
-	createConsumerIDWithData    = "put-consumer-id"
-	createConsumerIDWithoutData = "put-consumer-id-without-data"
-	deleteConsumerIDWithData    = "delete-consumer-id"
-	deleteConsumerIDFailed      = "delete-consumer-failed-id"
+	listTestConsumerIDPrefix       = "consumer-get-list-"
+	channelTestConsumerID          = "consumer-channel-some-id"
+	createConsumerIDWithData       = "put-consumer-id"
+	createConsumerIDWithoutData    = "put-consumer-id-without-data"
+	createConsumerIDWithRoutingKey = "put-consumer-id-with-routing-key"
+	deleteConsumerIDWithData       = "delete-consumer-id"
+	deleteConsumerIDFailed         = "delete-consumer-failed-id"
 )
 
 // ChannelTestSetup is called from TestMain for the package
@@ -80,7 +83,13 @@ func ConsumerTestSetup() {
 }
 
 func getNewConsumerController(consumerRepo storage.ConsumerRepository) *ConsumerController {
-	return NewConsumerController(channelRepo, consumerRepo, getDLQControllerWithMockedRepo())
+	return NewConsumerController(channelRepo, consumerRepo, djRepo, getDLQControllerWithMockedRepo(), NewConsumerVerificationController(consumerRepo, nil), nil, getMockedSystemEventPublisher(), configuration, configuration)
+}
+
+func getMockedSystemEventPublisher() *dispatchermocks.SystemEventPublisher {
+	mockPublisher := new(dispatchermocks.SystemEventPublisher)
+	mockPublisher.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return()
+	return mockPublisher
 }
 
 func TestConsumerFormatAsRelativeLink(t *testing.T) {
@@ -267,7 +276,7 @@ func TestConsumerDelete(t *testing.T) {
 		t.Parallel()
 		mockConsumerRepo := new(storagemocks.ConsumerRepository)
 		expectedErr := errors.New("test error")
-		mockConsumerRepo.On("Get", mock.Anything, mock.Anything).Return(nil, expectedErr)
+		mockConsumerRepo.On("GetIncludingDeleted", mock.Anything, mock.Anything).Return(nil, expectedErr)
 		getController := getNewConsumerController(mockConsumerRepo)
 		testRouter := createTestRouter(getController)
 		req, _ := http.NewRequest("DELETE", "/channel/"+consumerTestChannel.ChannelID+"/consumer/"+time.Now().String(), nil)
@@ -280,8 +289,8 @@ func TestConsumerDelete(t *testing.T) {
 		t.Parallel()
 		mockConsumerRepo := new(storagemocks.ConsumerRepository)
 		expectedErr := errors.New("test error")
-		mockConsumerRepo.On("Get", mock.Anything, mock.Anything).Return(deleteFailedConsumer, nil)
-		mockConsumerRepo.On("Delete", mock.Anything).Return(expectedErr)
+		mockConsumerRepo.On("GetIncludingDeleted", mock.Anything, mock.Anything).Return(deleteFailedConsumer, nil)
+		mockConsumerRepo.On("Delete", mock.Anything, mock.Anything).Return(expectedErr)
 		getController := getNewConsumerController(mockConsumerRepo)
 		testRouter := createTestRouter(getController)
 		req, _ := http.NewRequest("DELETE", "/channel/"+consumerTestChannel.ChannelID+"/consumer/"+time.Now().String(), nil)
@@ -406,6 +415,41 @@ func TestConsumerPut(t *testing.T) {
 		testRouter.ServeHTTP(rr, req)
 		assert.Equal(t, http.StatusBadRequest, rr.Code)
 	})
+	t.Run("SuccessfulPutCreateWithRoutingKeyPattern", func(t *testing.T) {
+		t.Parallel()
+		putController := getNewConsumerController(consumerRepo)
+		testRouter := createTestRouter(putController)
+		testURI := putController.FormatAsRelativeLink(httprouter.Param{Key: channelIDPathParamKey, Value: consumerTestChannel.ChannelID}, httprouter.Param{Key: consumerIDPathParamKey, Value: createConsumerIDWithRoutingKey})
+		req, _ := http.NewRequest("PUT", testURI, nil)
+		req.Header.Add(headerContentType, formDataContentTypeHeaderValue)
+		req.PostForm = url.Values{}
+		req.PostForm.Add("callbackUrl", callbackURL.String()+"test1")
+		req.PostForm.Add("routingKeyPattern", "user.#")
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		bodyConsumer := &ConsumerModel{}
+		json.NewDecoder(strings.NewReader(rr.Body.String())).Decode(bodyConsumer)
+		assert.Equal(t, "user.#", bodyConsumer.RoutingKeyPattern)
+		consumer, err := consumerRepo.Get(consumerTestChannel.ChannelID, createConsumerIDWithRoutingKey)
+		assert.Nil(t, err)
+		assert.Equal(t, "user.#", consumer.RoutingKeyPattern)
+	})
+	t.Run("400:InvalidRoutingKeyPattern", func(t *testing.T) {
+		t.Parallel()
+		putController := getNewConsumerController(consumerRepo)
+		testRouter := createTestRouter(putController)
+		testURI := putController.FormatAsRelativeLink(httprouter.Param{Key: channelIDPathParamKey, Value: consumerTestChannel.ChannelID}, httprouter.Param{Key: consumerIDPathParamKey, Value: createConsumerIDWithRoutingKey + "-invalid"})
+		req, _ := http.NewRequest("PUT", testURI, nil)
+		req.Header.Add(headerContentType, formDataContentTypeHeaderValue)
+		req.PostForm = url.Values{}
+		req.PostForm.Add("callbackUrl", callbackURL.String()+"test1")
+		req.PostForm.Add("routingKeyPattern", "user.*s")
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, ErrBadRequestForRoutingKeyPattern.Error(), rr.Body.String())
+	})
 	t.Run("400:InvalidURL", func(t *testing.T) {
 		t.Parallel()
 		putController := getNewConsumerController(consumerRepo)
@@ -478,3 +522,366 @@ func TestConsumerPut(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, rr.Code)
 	})
 }
+
+func TestConsumerVerificationFormatAsRelativeLink(t *testing.T) {
+	t.Parallel()
+	controller := NewConsumerVerificationController(nil, nil)
+	assert.Equal(t, consumerVerifyPath, controller.GetPath())
+	assert.Equal(t, "/channel/someChannelId/consumer/someConsumerId/verify", controller.FormatAsRelativeLink(httprouter.Param{Key: channelIDPathParamKey, Value: "someChannelId"},
+		httprouter.Param{Key: consumerIDPathParamKey, Value: "someConsumerId"}))
+}
+
+func getVerificationRequest(testURI string, token string) *http.Request {
+	req, _ := http.NewRequest("POST", testURI, nil)
+	req.Header.Add(headerContentType, formDataContentTypeHeaderValue)
+	req.PostForm = url.Values{}
+	req.PostForm.Add(consumerTokenFormParamName, token)
+	return req
+}
+
+func TestConsumerPutWithVerification(t *testing.T) {
+	verifiedCh := make(chan *data.Consumer, 3)
+	mockVerifier := new(dispatchermocks.ConsumerVerifier)
+	mockVerifier.On("VerifyWithRetry", mock.Anything).Run(func(args mock.Arguments) {
+		verifiedCh <- args.Get(0).(*data.Consumer)
+	}).Return()
+	putController := getNewConsumerController(consumerRepo)
+	putController.Verifier = mockVerifier
+	putController.VerificationRequired = true
+	verifyController := NewConsumerVerificationController(consumerRepo, mockVerifier)
+	testRouter := createTestRouter(putController, verifyController)
+	consumerID := "put-consumer-pending-verification"
+	params := []httprouter.Param{{Key: channelIDPathParamKey, Value: consumerTestChannel.ChannelID}, {Key: consumerIDPathParamKey, Value: consumerID}}
+	testURI := putController.FormatAsRelativeLink(params...)
+	req, _ := http.NewRequest("PUT", testURI, nil)
+	req.Header.Add(headerContentType, formDataContentTypeHeaderValue)
+	req.PostForm = url.Values{}
+	req.PostForm.Add("token", successfulGetTestToken)
+	req.PostForm.Add("callbackUrl", callbackURL.String()+"verify")
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	body := &ConsumerModel{}
+	json.NewDecoder(strings.NewReader(rr.Body.String())).Decode(body)
+	assert.Equal(t, data.ConsumerPendingVerificationStr, body.VerificationStatus)
+	assert.Equal(t, verifyController.FormatAsRelativeLink(params...), body.VerificationURL)
+	firstAttempt := <-verifiedCh
+	assert.Equal(t, consumerID, firstAttempt.ConsumerID)
+	assert.Equal(t, data.ConsumerPendingVerification, firstAttempt.VerificationStatus)
+	assert.NotEmpty(t, firstAttempt.VerificationChallenge)
+	t.Run("BadToken", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getVerificationRequest(verifyController.FormatAsRelativeLink(params...), "wrong token"))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+	t.Run("NotFound", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getVerificationRequest(verifyController.FormatAsRelativeLink(params[0], httprouter.Param{Key: consumerIDPathParamKey, Value: "non-existing"}), successfulGetTestToken))
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+	t.Run("Retrigger", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getVerificationRequest(verifyController.FormatAsRelativeLink(params...), successfulGetTestToken))
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		secondAttempt := <-verifiedCh
+		assert.Equal(t, data.ConsumerPendingVerification, secondAttempt.VerificationStatus)
+		assert.NotEqual(t, firstAttempt.VerificationChallenge, secondAttempt.VerificationChallenge)
+		assert.Nil(t, consumerRepo.MarkVerified(secondAttempt, secondAttempt.VerificationChallenge))
+	})
+	t.Run("AlreadyVerified", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getVerificationRequest(verifyController.FormatAsRelativeLink(params...), successfulGetTestToken))
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+	t.Run("UnchangedCallbackKeepsStatus", func(t *testing.T) {
+		consumer, _ := consumerRepo.Get(consumerTestChannel.ChannelID, consumerID)
+		req, _ := http.NewRequest("PUT", testURI, nil)
+		req.Header.Add(headerContentType, formDataContentTypeHeaderValue)
+		req.Header.Add(headerUnmodifiedSince, consumer.GetLastUpdatedHTTPTimeString())
+		req.PostForm = url.Values{}
+		req.PostForm.Add("token", successfulGetTestToken)
+		req.PostForm.Add("name", "Renamed")
+		req.PostForm.Add("callbackUrl", callbackURL.String()+"verify")
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		body := &ConsumerModel{}
+		json.NewDecoder(strings.NewReader(rr.Body.String())).Decode(body)
+		assert.Equal(t, data.ConsumerVerifiedStr, body.VerificationStatus)
+		assert.Equal(t, 0, len(verifiedCh))
+	})
+	t.Run("StoreError", func(t *testing.T) {
+		expectedErr := errors.New("expected error")
+		consumer, _ := data.NewConsumer(consumerTestChannel, deleteConsumerIDFailed, successfulGetTestToken, callbackURL)
+		consumer.VerificationStatus = data.ConsumerPendingVerification
+		mockConsumerRepo := new(storagemocks.ConsumerRepository)
+		mockConsumerRepo.On("Get", consumerTestChannel.ChannelID, deleteConsumerIDFailed).Return(consumer, nil)
+		mockConsumerRepo.On("Store", mock.Anything).Return(nil, expectedErr)
+		controller := NewConsumerVerificationController(mockConsumerRepo, mockVerifier)
+		rr := httptest.NewRecorder()
+		createTestRouter(controller).ServeHTTP(rr, getVerificationRequest(controller.FormatAsRelativeLink(params[0], httprouter.Param{Key: consumerIDPathParamKey, Value: deleteConsumerIDFailed}), consumer.Token))
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		mockConsumerRepo.AssertExpectations(t)
+	})
+}
+
+func TestConsumerPauseResume(t *testing.T) {
+	consumerID := "consumer-pause-resume"
+	consumer, _ := data.NewConsumer(consumerTestChannel, consumerID, successfulGetTestToken, callbackURL)
+	consumer, err := consumerRepo.Store(consumer)
+	assert.Nil(t, err)
+	mockDJRepo := new(storagemocks.DeliveryJobRepository)
+	mockDJRepo.On("RescheduleQueuedJobsForConsumer", mock.Anything, mock.Anything, time.Second/time.Duration(configuration.GetResumeCatchUpRate())).Return(nil).Once()
+	pauseController := NewConsumerPauseController(consumerRepo)
+	resumeController := NewConsumerResumeController(consumerRepo, mockDJRepo, configuration)
+	testRouter := createTestRouter(pauseController, resumeController)
+	params := []httprouter.Param{{Key: channelIDPathParamKey, Value: consumerTestChannel.ChannelID}, {Key: consumerIDPathParamKey, Value: consumerID}}
+	assert.Equal(t, "/channel/"+consumerTestChannel.ChannelID+"/consumer/"+consumerID+"/pause", pauseController.FormatAsRelativeLink(params...))
+	assert.Equal(t, "/channel/"+consumerTestChannel.ChannelID+"/consumer/"+consumerID+"/resume", resumeController.FormatAsRelativeLink(params...))
+	assert.Equal(t, consumerPausePath, pauseController.GetPath())
+	assert.Equal(t, consumerResumePath, resumeController.GetPath())
+	t.Run("BadToken", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getVerificationRequest(pauseController.FormatAsRelativeLink(params...), "wrong token"))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+	t.Run("Pause", func(t *testing.T) {
+		for attempt := 0; attempt < 2; attempt++ {
+			rr := httptest.NewRecorder()
+			testRouter.ServeHTTP(rr, getVerificationRequest(pauseController.FormatAsRelativeLink(params...), successfulGetTestToken))
+			assert.Equal(t, http.StatusNoContent, rr.Code)
+		}
+		dbConsumer, err := consumerRepo.Get(consumerTestChannel.ChannelID, consumerID)
+		assert.Nil(t, err)
+		assert.True(t, dbConsumer.Paused)
+		assert.True(t, getNewConsumerController(consumerRepo).getConsumerModel(dbConsumer).Paused)
+	})
+	t.Run("Resume", func(t *testing.T) {
+		for attempt := 0; attempt < 2; attempt++ {
+			rr := httptest.NewRecorder()
+			testRouter.ServeHTTP(rr, getVerificationRequest(resumeController.FormatAsRelativeLink(params...), successfulGetTestToken))
+			assert.Equal(t, http.StatusAccepted, rr.Code)
+		}
+		dbConsumer, err := consumerRepo.Get(consumerTestChannel.ChannelID, consumerID)
+		assert.Nil(t, err)
+		assert.False(t, dbConsumer.Paused)
+		mockDJRepo.AssertExpectations(t)
+	})
+	t.Run("ResumeRescheduleError", func(t *testing.T) {
+		expectedErr := errors.New("expected error")
+		assert.Nil(t, consumerRepo.SetPaused(consumer, true))
+		failingDJRepo := new(storagemocks.DeliveryJobRepository)
+		failingDJRepo.On("RescheduleQueuedJobsForConsumer", mock.Anything, mock.Anything, mock.Anything).Return(expectedErr)
+		rr := httptest.NewRecorder()
+		createTestRouter(NewConsumerResumeController(consumerRepo, failingDJRepo, configuration)).ServeHTTP(rr, getVerificationRequest(resumeController.FormatAsRelativeLink(params...), successfulGetTestToken))
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		dbConsumer, _ := consumerRepo.Get(consumerTestChannel.ChannelID, consumerID)
+		assert.True(t, dbConsumer.Paused)
+	})
+	t.Run("PauseError", func(t *testing.T) {
+		expectedErr := errors.New("expected error")
+		activeConsumer, _ := data.NewConsumer(consumerTestChannel, consumerID, successfulGetTestToken, callbackURL)
+		mockConsumerRepo := new(storagemocks.ConsumerRepository)
+		mockConsumerRepo.On("Get", consumerTestChannel.ChannelID, consumerID).Return(activeConsumer, nil)
+		mockConsumerRepo.On("SetPaused", activeConsumer, true).Return(expectedErr)
+		rr := httptest.NewRecorder()
+		createTestRouter(NewConsumerPauseController(mockConsumerRepo)).ServeHTTP(rr, getVerificationRequest(pauseController.FormatAsRelativeLink(params...), successfulGetTestToken))
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		mockConsumerRepo.AssertExpectations(t)
+	})
+}
+
+func TestConsumerPutWithBackfill(t *testing.T) {
+	channel, _ := data.NewChannel("backfill-test-channel", successfulGetTestToken)
+	channel, err := channelRepo.Store(channel)
+	assert.Nil(t, err)
+	for index := 0; index < 3; index++ {
+		message, _ := data.NewMessage(channel, messageProducer, messagePayload, messageContentType)
+		assert.Nil(t, messageRepo.Create(message))
+		assert.Nil(t, djRepo.DispatchMessage(message))
+	}
+	putController := getNewConsumerController(consumerRepo)
+	testRouter := createTestRouter(putController)
+	getPutRequest := func(consumerID, backfillSince string) *http.Request {
+		testURI := putController.FormatAsRelativeLink(httprouter.Param{Key: channelIDPathParamKey, Value: channel.ChannelID}, httprouter.Param{Key: consumerIDPathParamKey, Value: consumerID})
+		req, _ := http.NewRequest("PUT", testURI, nil)
+		req.Header.Add(headerContentType, formDataContentTypeHeaderValue)
+		req.PostForm = url.Values{}
+		req.PostForm.Add("token", successfulGetTestToken)
+		req.PostForm.Add("callbackUrl", callbackURL.String())
+		req.PostForm.Add(backfillSinceFormParamName, backfillSince)
+		return req
+	}
+	t.Run("InvalidBackfillSince", func(t *testing.T) {
+		for _, backfillSince := range []string{"bogus", "-1h", time.Now().Add(time.Hour).Format(time.RFC3339)} {
+			rr := httptest.NewRecorder()
+			testRouter.ServeHTTP(rr, getPutRequest("invalid-backfill-consumer", backfillSince))
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			assert.Equal(t, ErrBadRequestForBackfill.Error(), rr.Body.String())
+		}
+	})
+	getBackfill := func(consumerID string) *BackfillModel {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", putController.FormatAsRelativeLink(httprouter.Param{Key: channelIDPathParamKey, Value: channel.ChannelID}, httprouter.Param{Key: consumerIDPathParamKey, Value: consumerID}), nil)
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		consumerModel := &ConsumerModel{}
+		assert.Nil(t, json.NewDecoder(rr.Body).Decode(consumerModel))
+		return consumerModel.Backfill
+	}
+	t.Run("CreateWithBackfill", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getPutRequest("backfill-consumer", "1h"))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		consumerModel := &ConsumerModel{}
+		assert.Nil(t, json.NewDecoder(rr.Body).Decode(consumerModel))
+		assert.NotNil(t, consumerModel.Backfill)
+		assert.False(t, consumerModel.Backfill.Complete)
+		// Jobs are created by the dispatcher's recovery workers
+		backfill := getBackfill("backfill-consumer")
+		assert.WithinDuration(t, time.Now().Add(-1*time.Hour), backfill.Since, time.Minute)
+		assert.False(t, backfill.JobsCreated)
+		assert.Equal(t, uint(0), backfill.JobCount)
+		assert.False(t, backfill.Complete)
+	})
+	t.Run("CreateWithTimestampBackfill", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		since := time.Now().Add(-1 * time.Hour)
+		testRouter.ServeHTTP(rr, getPutRequest("timestamp-backfill-consumer", since.Format(time.RFC3339)))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, since.Unix(), getBackfill("timestamp-backfill-consumer").Since.Unix())
+	})
+	t.Run("CreateWithoutBackfill", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getPutRequest("no-backfill-consumer", ""))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		consumerModel := &ConsumerModel{}
+		assert.Nil(t, json.NewDecoder(rr.Body).Decode(consumerModel))
+		assert.Nil(t, consumerModel.Backfill)
+		// backfill is ignored on update
+		rr = httptest.NewRecorder()
+		req := getPutRequest("no-backfill-consumer", "1h")
+		req.Header.Add(headerUnmodifiedSince, consumerModel.ChangedAt.Format(http.TimeFormat))
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		consumerModel = &ConsumerModel{}
+		assert.Nil(t, json.NewDecoder(rr.Body).Decode(consumerModel))
+		assert.Nil(t, consumerModel.Backfill)
+	})
+	t.Run("BackfillError", func(t *testing.T) {
+		mockDJRepo := new(storagemocks.DeliveryJobRepository)
+		mockDJRepo.On("CreateBackfill", mock.Anything).Return(errExpected)
+		controller := NewConsumerController(channelRepo, consumerRepo, mockDJRepo, getDLQControllerWithMockedRepo(), NewConsumerVerificationController(consumerRepo, nil), nil, getMockedSystemEventPublisher(), configuration, configuration)
+		rr := httptest.NewRecorder()
+		createTestRouter(controller).ServeHTTP(rr, getPutRequest("failed-backfill-consumer", "1h"))
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		mockDJRepo.AssertExpectations(t)
+	})
+}
+
+func TestConsumerSystemEvents(t *testing.T) {
+	mockPublisher := new(dispatchermocks.SystemEventPublisher)
+	controller := getNewConsumerController(consumerRepo)
+	controller.SystemEvents = mockPublisher
+	testRouter := createTestRouter(controller)
+	testURI := controller.FormatAsRelativeLink(httprouter.Param{Key: channelIDPathParamKey, Value: consumerTestChannel.ChannelID}, httprouter.Param{Key: consumerIDPathParamKey, Value: "system-event-consumer"})
+	isEventOf := func(created bool) func(eventData *dispatcher.ConsumerEventData) bool {
+		return func(eventData *dispatcher.ConsumerEventData) bool {
+			return eventData.ConsumerID == "system-event-consumer" && eventData.ChannelID == consumerTestChannel.ChannelID &&
+				eventData.CallbackURL == callbackURL.String()+"system-event" && eventData.RoutingKeyPattern == "user.#" && eventData.Created == created
+		}
+	}
+	mockPublisher.On("Publish", data.SystemEventConsumerUpdated, "", mock.MatchedBy(isEventOf(true))).Return().Once()
+	req, _ := http.NewRequest("PUT", testURI, nil)
+	req.Header.Add(headerContentType, formDataContentTypeHeaderValue)
+	req.PostForm = url.Values{}
+	req.PostForm.Add("callbackUrl", callbackURL.String()+"system-event")
+	req.PostForm.Add(routingKeyPatternParamName, "user.#")
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	consumer, err := consumerRepo.Get(consumerTestChannel.ChannelID, "system-event-consumer")
+	assert.Nil(t, err)
+	mockPublisher.On("Publish", data.SystemEventConsumerDeleted, "", mock.MatchedBy(isEventOf(false))).Return().Once()
+	req, _ = http.NewRequest("DELETE", testURI, nil)
+	req.Header.Add(headerUnmodifiedSince, consumer.GetLastUpdatedHTTPTimeString())
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	mockPublisher.AssertExpectations(t)
+}
+
+func TestConsumerPut_Stream(t *testing.T) {
+	mockVerifier := new(dispatchermocks.ConsumerVerifier)
+	putController := getNewConsumerController(consumerRepo)
+	putController.Verifier = mockVerifier
+	putController.VerificationRequired = true
+	testRouter := createTestRouter(putController)
+	getRequest := func(consumerType string) *http.Request {
+		testURI := putController.FormatAsRelativeLink(httprouter.Param{Key: channelIDPathParamKey, Value: consumerTestChannel.ChannelID}, httprouter.Param{Key: consumerIDPathParamKey, Value: "put-streaming-consumer"})
+		req, _ := http.NewRequest("PUT", testURI, nil)
+		req.Header.Add(headerContentType, formDataContentTypeHeaderValue)
+		req.PostForm = url.Values{}
+		req.PostForm.Add("token", successfulGetTestToken)
+		req.PostForm.Add(consumerTypeFormParamName, consumerType)
+		return req
+	}
+	t.Run("InvalidType", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getRequest("pull"))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, ErrBadRequestForConsumerType.Error(), rr.Body.String())
+	})
+	t.Run("PushWithoutCallbackURL", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getRequest("push"))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+	t.Run("StreamWithoutCallbackURL", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getRequest("stream"))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		body := &ConsumerModel{}
+		json.NewDecoder(strings.NewReader(rr.Body.String())).Decode(body)
+		assert.Equal(t, data.StreamConsumerStr, body.Type)
+		assert.Empty(t, body.CallbackURL)
+		// Streaming consumers have no callback URL to verify
+		assert.Equal(t, data.ConsumerVerifiedStr, body.VerificationStatus)
+		mockVerifier.AssertNotCalled(t, "VerifyWithRetry", mock.Anything)
+		dbConsumer, err := consumerRepo.Get(consumerTestChannel.ChannelID, "put-streaming-consumer")
+		assert.Nil(t, err)
+		assert.True(t, dbConsumer.IsStreaming())
+	})
+}
+
+func TestConsumerPut_DeliveryFormat(t *testing.T) {
+	putController := getNewConsumerController(consumerRepo)
+	testRouter := createTestRouter(putController)
+	getRequest := func(deliveryFormat string) *http.Request {
+		testURI := putController.FormatAsRelativeLink(httprouter.Param{Key: channelIDPathParamKey, Value: consumerTestChannel.ChannelID}, httprouter.Param{Key: consumerIDPathParamKey, Value: "put-cloudevent-consumer"})
+		req, _ := http.NewRequest("PUT", testURI, nil)
+		req.Header.Add(headerContentType, formDataContentTypeHeaderValue)
+		req.PostForm = url.Values{}
+		req.PostForm.Add("token", successfulGetTestToken)
+		req.PostForm.Add("callbackUrl", callbackURL.String()+"cloudevent")
+		req.PostForm.Add(deliveryFormatParamName, deliveryFormat)
+		return req
+	}
+	t.Run("InvalidFormat", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getRequest("cloudevent"))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, ErrBadRequestForDeliveryFormat.Error(), rr.Body.String())
+	})
+	t.Run("CloudEvent", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getRequest("cloudevent-structured"))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		body := &ConsumerModel{}
+		json.NewDecoder(strings.NewReader(rr.Body.String())).Decode(body)
+		assert.Equal(t, data.StructuredCloudEventDeliveryStr, body.DeliveryFormat)
+		dbConsumer, err := consumerRepo.Get(consumerTestChannel.ChannelID, "put-cloudevent-consumer")
+		assert.Nil(t, err)
+		assert.Equal(t, data.StructuredCloudEventDelivery, dbConsumer.DeliveryFormat)
+	})
+}
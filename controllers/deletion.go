@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/newscred/webhook-broker/storage"
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+const (
+	drainQueryParamName = "drain"
+	purgeQueryParamName = "purge"
+)
+
+// deletable is a producer, channel or consumer which is first soft deleted and then purged once its retention is over
+type deletable interface {
+	data.Updateable
+	IsDeleted() bool
+	IsPurgeable(retention time.Duration) bool
+}
+
+func isQueryParamSet(r *http.Request, name string) bool {
+	value, err := strconv.ParseBool(r.URL.Query().Get(name))
+	return err == nil && value
+}
+
+// deleteStakeholder soft deletes the stakeholder loaded including deleted ones, letting its outstanding jobs drain if `drain=true`, or
+// purges it if `purge=true`; both are conditional on the `If-Unmodified-Since` header. Returns whether the stakeholder got soft deleted
+func deleteStakeholder(w http.ResponseWriter, r *http.Request, stakeholder deletable, loadErr error, retention time.Duration,
+	softDelete func(drain bool) error, purge func() error) (deleted bool) {
+	switch {
+	case loadErr == sql.ErrNoRows:
+		writeNotFound(w)
+		return false
+	case loadErr != nil:
+		writeErr(w, loadErr)
+		return false
+	}
+	var err error
+	if !isQueryParamSet(r, purgeQueryParamName) {
+		if stakeholder.IsDeleted() {
+			writeNotFound(w)
+			return false
+		}
+		if !isConditionalUpdateCalled(w, r, stakeholder) {
+			return false
+		}
+		err = softDelete(isQueryParamSet(r, drainQueryParamName))
+		deleted = err == nil
+	} else {
+		w.Header().Add(headerLastModified, stakeholder.GetLastUpdatedHTTPTimeString())
+		if !stakeholder.IsDeleted() {
+			writeStatus(w, http.StatusConflict, ErrPurgeNotDeleted)
+			return false
+		}
+		if !stakeholder.IsPurgeable(retention) {
+			writeStatus(w, http.StatusConflict, ErrPurgeBeforeRetention)
+			return false
+		}
+		if !isConditionalUpdateCalled(w, r, stakeholder) {
+			return false
+		}
+		err = purge()
+	}
+	switch err {
+	case nil:
+		if deleted {
+			w.Header().Set(headerLastModified, stakeholder.GetLastUpdatedHTTPTimeString())
+		}
+		writeStatus(w, http.StatusNoContent, nil)
+	case storage.ErrNoRowsUpdated:
+		writeStatus(w, http.StatusPreconditionFailed, ErrConditionalFailed)
+	default:
+		writeErr(w, err)
+	}
+	return deleted
+}
+
+// writeStoreErr writes the error of storing a producer, channel or consumer, where recreating a deleted one is a conflict
+func writeStoreErr(w http.ResponseWriter, err error) {
+	if err == storage.ErrDeleted {
+		writeStatus(w, http.StatusConflict, ErrStakeholderDeleted)
+		return
+	}
+	writeErr(w, err)
+}
+
+// deletePayloadBlobs deletes the offloaded payloads of purged messages; failures are only logged as the messages are already gone
+func deletePayloadBlobs(blobStore storage.BlobStore, payloadRefs []string) {
+	if blobStore == nil {
+		return
+	}
+	for _, payloadRef := range payloadRefs {
+		if err := blobStore.Delete(payloadRef); err != nil {
+			log.Error().Err(err).Msg("error - could not delete blob of purged message " + payloadRef)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/newscred/webhook-broker/storage/data"
+	storagemocks "github.com/newscred/webhook-broker/storage/mocks"
+)
+
+func getDeleteTestRequest(path, unmodifiedSince string) *http.Request {
+	req, _ := http.NewRequest(http.MethodDelete, path, nil)
+	if len(unmodifiedSince) > 0 {
+		req.Header.Add(headerUnmodifiedSince, unmodifiedSince)
+	}
+	return req
+}
+
+func TestChannelControllerDelete(t *testing.T) {
+	channel, _ := data.NewChannel("channel-for-delete", "channel-for-delete-token")
+	channel, err := channelRepo.Store(channel)
+	assert.Nil(t, err)
+	controller := getNewChannelController(channelRepo)
+	testRouter := createTestRouter(controller)
+	channelURL := controller.FormatAsRelativeLink(getRouterParam(channel.ChannelID))
+	t.Run("Reserved", func(t *testing.T) {
+		for _, channelID := range []string{data.ReceiptsChannelID, data.SystemChannelID} {
+			rr := httptest.NewRecorder()
+			testRouter.ServeHTTP(rr, getDeleteTestRequest(controller.FormatAsRelativeLink(getRouterParam(channelID)), ""))
+			assert.Equal(t, http.StatusForbidden, rr.Code)
+			assert.Equal(t, ErrReservedStakeholder.Error(), rr.Body.String())
+		}
+	})
+	t.Run("404", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getDeleteTestRequest("/channel/no-such-channel-to-delete", ""))
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+	t.Run("DeleteAndPurge", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getDeleteTestRequest(channelURL+"?purge=true", channel.GetLastUpdatedHTTPTimeString()))
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Equal(t, ErrPurgeNotDeleted.Error(), rr.Body.String())
+
+		rr = httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getDeleteTestRequest(channelURL, ""))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+		rr = httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getDeleteTestRequest(channelURL, channel.GetLastUpdatedHTTPTimeString()))
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+		lastModified := rr.Header().Get(headerLastModified)
+		assert.NotEmpty(t, lastModified)
+
+		for _, method := range []string{http.MethodGet, http.MethodDelete} {
+			req := getDeleteTestRequest(channelURL, lastModified)
+			req.Method = method
+			rr = httptest.NewRecorder()
+			testRouter.ServeHTTP(rr, req)
+			assert.Equal(t, http.StatusNotFound, rr.Code, method)
+		}
+		rr = httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getRateLimitTestPutRequest(channelURL, url.Values{}))
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Equal(t, ErrStakeholderDeleted.Error(), rr.Body.String())
+
+		rr = httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getDeleteTestRequest(channelURL+"?purge=true", lastModified))
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Equal(t, ErrPurgeBeforeRetention.Error(), rr.Body.String())
+		assert.Equal(t, lastModified, rr.Header().Get(headerLastModified))
+
+		controller.PurgeRetention = 0
+		rr = httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getDeleteTestRequest(channelURL+"?purge=true", lastModified))
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+		_, err := channelRepo.GetIncludingDeleted(channel.ChannelID)
+		assert.Equal(t, sql.ErrNoRows, err)
+
+		rr = httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getRateLimitTestPutRequest(channelURL, url.Values{}))
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+	t.Run("500", func(t *testing.T) {
+		mockChannelRepo := new(storagemocks.ChannelRepository)
+		mockChannelRepo.On("GetIncludingDeleted", mock.Anything).Return(nil, errors.New("channel error"))
+		rr := httptest.NewRecorder()
+		createTestRouter(getNewChannelController(mockChannelRepo)).ServeHTTP(rr, getDeleteTestRequest(channelURL, ""))
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+}
+
+func TestProducerControllerDelete(t *testing.T) {
+	producer, _ := data.NewProducer("producer-for-delete", "producer-for-delete-token")
+	producer, err := producerRepo.Store(producer)
+	assert.Nil(t, err)
+	controller := NewProducerController(producerRepo, quotaRepo, configuration, nil, configuration)
+	testRouter := createTestRouter(controller)
+	producerURL := controller.FormatAsRelativeLink(httprouter.Param{Key: producerIDPathParamKey, Value: producer.ProducerID})
+	t.Run("Reserved", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getDeleteTestRequest(controller.FormatAsRelativeLink(httprouter.Param{Key: producerIDPathParamKey, Value: data.BrokerProducerID}), ""))
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+	t.Run("DeleteAndPurge", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getDeleteTestRequest(producerURL+"?drain=true", producer.GetLastUpdatedHTTPTimeString()))
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+		lastModified := rr.Header().Get(headerLastModified)
+
+		rr = httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getDeleteTestRequest(producerURL, lastModified))
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		rr = httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getRateLimitTestPutRequest(producerURL, url.Values{}))
+		assert.Equal(t, http.StatusConflict, rr.Code)
+
+		controller.PurgeRetention = 0
+		rr = httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getDeleteTestRequest(producerURL+"?purge=true", ""))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		rr = httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getDeleteTestRequest(producerURL+"?purge=true", lastModified))
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+		_, err := producerRepo.GetIncludingDeleted(producer.ProducerID)
+		assert.Equal(t, sql.ErrNoRows, err)
+	})
+	t.Run("500", func(t *testing.T) {
+		mockProducerRepo := new(storagemocks.ProducerRepository)
+		mockProducerRepo.On("GetIncludingDeleted", mock.Anything).Return(producer, nil)
+		mockProducerRepo.On("Delete", mock.Anything, false).Return(errors.New("producer error"))
+		rr := httptest.NewRecorder()
+		createTestRouter(NewProducerController(mockProducerRepo, quotaRepo, configuration, nil, configuration)).ServeHTTP(rr,
+			getDeleteTestRequest(producerURL, producer.GetLastUpdatedHTTPTimeString()))
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		mockProducerRepo.AssertExpectations(t)
+	})
+}
+
+func TestConsumerControllerPurge(t *testing.T) {
+	callbackURL, _ := url.Parse("https://imytech.net/")
+	consumer, _ := data.NewConsumer(consumerTestChannel, "consumer-for-purge", "consumer-for-purge-token", callbackURL)
+	consumer, err := consumerRepo.Store(consumer)
+	assert.Nil(t, err)
+	controller := getNewConsumerController(consumerRepo)
+	testRouter := createTestRouter(controller)
+	consumerURL := controller.FormatAsRelativeLink(getRouterParam(consumerTestChannel.ChannelID), httprouter.Param{Key: consumerIDPathParamKey, Value: consumer.ConsumerID})
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, getDeleteTestRequest(consumerURL, consumer.GetLastUpdatedHTTPTimeString()))
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	lastModified := rr.Header().Get(headerLastModified)
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, getDeleteTestRequest(consumerURL+"?purge=true", lastModified))
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	controller.PurgeRetention = 0
+	rr = httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, getDeleteTestRequest(consumerURL+"?purge=true", lastModified))
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	_, err = consumerRepo.GetIncludingDeleted(consumerTestChannel.ChannelID, consumer.ConsumerID)
+	assert.Equal(t, sql.ErrNoRows, err)
+}
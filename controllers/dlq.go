@@ -0,0 +1,466 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog/log"
+
+	"github.com/newscred/webhook-broker/storage"
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+const (
+	dlqPath                   = consumerPath + "/dlq"
+	deadJobIDPathParamKey     = "jobId"
+	deadJobPath               = dlqPath + "/:" + deadJobIDPathParamKey
+	channelDLQPath            = channelPath + "/dlq"
+	dlqExportPath             = channelDLQPath + "/export"
+	requeueFormParamName      = "requeue"
+	dlqActionParamName        = "action"
+	dlqActionRequeue          = "requeue"
+	dlqActionDiscard          = "discard"
+	dlqFromParamName          = "from"
+	dlqToParamName            = "to"
+	dlqJobIDParamName         = "jobId"
+	dlqMessageIDParamName     = "messageId"
+	dlqFailureReasonParamName = "failureReason"
+	dlqConsumerIDParamName    = "consumerId"
+	dlqExportFormatParamName  = "format"
+	dlqExportFormatCSV        = "csv"
+	ndjsonContentTypeValue    = "application/x-ndjson"
+	csvContentTypeValue       = "text/csv"
+	headerContentDisposition  = "Content-Disposition"
+	dlqExportFileNamePrefix   = "dlq-"
+)
+
+var (
+	dlqExportCSVHeader = []string{"JobID", "ConsumerID", "MessageID", "ContentType", "Payload", "FailureReason", "RetryAttemptCount", "ReceivedAt", "DeadAt", "PayloadRef"}
+)
+
+// DeadDeliveryJobModel is a DeliveryJobModel with reference to its message and to be used for DLQ
+type DeadDeliveryJobModel struct {
+	DeliveryJobModel
+	MessageURL        string
+	JobURL            string
+	ConsumerID        string
+	FailureReason     string
+	RetryAttemptCount uint
+}
+
+// DLQList represents the list of jobs that are dead
+type DLQList struct {
+	DeadJobs []*DeadDeliveryJobModel
+	Pages    map[string]string
+}
+
+// DeadJobExportRecord represents a dead job along with its message's payload in DLQ export; an offloaded payload is read from the blob store
+// and PayloadRef is the reference to it
+type DeadJobExportRecord struct {
+	JobID             string
+	ConsumerID        string
+	MessageID         string
+	ContentType       string
+	Payload           string
+	FailureReason     string
+	RetryAttemptCount uint
+	ReceivedAt        time.Time
+	DeadAt            time.Time
+	PayloadRef        string `json:",omitempty"`
+}
+
+func newDeadJobExportRecord(job *data.DeliveryJob) *DeadJobExportRecord {
+	return &DeadJobExportRecord{JobID: job.ID.String(), ConsumerID: job.Listener.ConsumerID, MessageID: job.Message.MessageID, ContentType: job.Message.ContentType,
+		Payload: job.Message.Payload, PayloadRef: job.Message.PayloadRef, FailureReason: job.FailureReason, RetryAttemptCount: job.RetryAttemptCount, ReceivedAt: job.Message.ReceivedAt, DeadAt: job.StatusChangedAt}
+}
+
+func (record *DeadJobExportRecord) toCSVRow() []string {
+	return []string{record.JobID, record.ConsumerID, record.MessageID, record.ContentType, record.Payload, record.FailureReason, strconv.FormatUint(uint64(record.RetryAttemptCount), 10),
+		record.ReceivedAt.Format(time.RFC3339), record.DeadAt.Format(time.RFC3339), record.PayloadRef}
+}
+
+// readPayloadBlob reads the offloaded payload of a message
+func readPayloadBlob(blobStore storage.BlobStore, payloadRef string) (string, error) {
+	if blobStore == nil {
+		return "", storage.ErrBlobStoreNotConfigured
+	}
+	body, err := blobStore.Get(payloadRef)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	payload, err := ioutil.ReadAll(body)
+	return string(payload), err
+}
+
+func newDeadDeliveryJobs(msgController EndpointController, deadJobController EndpointController, jobs ...*data.DeliveryJob) []*DeadDeliveryJobModel {
+	result := make([]*DeadDeliveryJobModel, 0, len(jobs))
+	for _, job := range jobs {
+		channelIDParam := httprouter.Param{Key: channelIDPathParamKey, Value: job.Message.BroadcastedTo.ChannelID}
+		messageURL := msgController.FormatAsRelativeLink(channelIDParam, httprouter.Param{Key: messageIDParamKey, Value: job.Message.MessageID})
+		jobURL := deadJobController.FormatAsRelativeLink(channelIDParam, httprouter.Param{Key: consumerIDPathParamKey, Value: job.Listener.ConsumerID},
+			httprouter.Param{Key: deadJobIDPathParamKey, Value: job.ID.String()})
+		result = append(result, &DeadDeliveryJobModel{DeliveryJobModel: *newDeliveryJobModel(job), MessageURL: messageURL, JobURL: jobURL, ConsumerID: job.Listener.ConsumerID,
+			FailureReason: job.FailureReason, RetryAttemptCount: job.RetryAttemptCount})
+	}
+	return result
+}
+
+// getDeadJobFilter builds the filter for dead jobs of the channel, or of the consumer when provided, from the request's query and form params;
+// also returns whether any criteria other than the channel and consumer was provided
+func getDeadJobFilter(r *http.Request, channel *data.Channel, consumer *data.Consumer) (filter *data.DeadJobFilter, hasCriteria bool, err error) {
+	r.ParseForm()
+	filter = &data.DeadJobFilter{Channel: channel, Consumer: consumer, JobIDs: r.Form[dlqJobIDParamName], MessageIDs: r.Form[dlqMessageIDParamName],
+		FailureReason: r.Form.Get(dlqFailureReasonParamName)}
+	if from := r.Form.Get(dlqFromParamName); len(from) > 0 && err == nil {
+		filter.DeadSince, err = time.Parse(time.RFC3339, from)
+	}
+	if to := r.Form.Get(dlqToParamName); len(to) > 0 && err == nil {
+		filter.DeadUntil, err = time.Parse(time.RFC3339, to)
+	}
+	if err == nil && !filter.IsInValidState() {
+		err = ErrBadRequestForDLQFilter
+	}
+	hasCriteria = len(filter.JobIDs) > 0 || len(filter.MessageIDs) > 0 || len(filter.FailureReason) > 0 || !filter.DeadSince.IsZero() || !filter.DeadUntil.IsZero()
+	return filter, hasCriteria, err
+}
+
+// getDLQAction returns the action requested for the dead jobs, requeue being the default
+func getDLQAction(r *http.Request) (string, bool) {
+	action := r.PostFormValue(dlqActionParamName)
+	switch action {
+	case "":
+		return dlqActionRequeue, true
+	case dlqActionRequeue, dlqActionDiscard:
+		return action, true
+	default:
+		return action, false
+	}
+}
+
+func applyDLQAction(djRepo storage.DeliveryJobRepository, action string, filter *data.DeadJobFilter) error {
+	if action == dlqActionDiscard {
+		return djRepo.DiscardDeadJobs(filter)
+	}
+	return djRepo.RequeueDeadJobs(filter)
+}
+
+func isConsumerTokenForDLQ(r *http.Request, consumer *data.Consumer) bool {
+	return r.PostFormValue(requeueFormParamName) == consumer.Token || r.PostFormValue(consumerTokenFormParamName) == consumer.Token
+}
+
+func getConsumerForDLQ(consumerRepo storage.ConsumerRepository, w http.ResponseWriter, params httprouter.Params) *data.Consumer {
+	consumer, err := consumerRepo.Get(params.ByName(channelIDPathParamKey), params.ByName(consumerIDPathParamKey))
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			writeNotFound(w)
+		default:
+			writeErr(w, err)
+		}
+		return nil
+	}
+	return consumer
+}
+
+// DLQController represents the GET and POST endpoint for reading dead and requeuing or discarding dead messages of a consumer.
+type DLQController struct {
+	MessageController EndpointController
+	DeadJobController EndpointController
+	DeliveryJobRepo   storage.DeliveryJobRepository
+	ConsumerRepo      storage.ConsumerRepository
+}
+
+// NewDLQController retrieves the controller for DLQ list and requeue endpoints
+func NewDLQController(msgController *MessageController, deadJobController *DeadJobController, djRepo storage.DeliveryJobRepository, consumerRepo storage.ConsumerRepository) *DLQController {
+	return &DLQController{MessageController: msgController, DeadJobController: deadJobController, DeliveryJobRepo: djRepo, ConsumerRepo: consumerRepo}
+}
+
+// GetPath returns the endpoint's path
+func (controller *DLQController) GetPath() string {
+	return dlqPath
+}
+
+// FormatAsRelativeLink formats this controllers URL with the parameters provided. Both `consumerId` and `channelId` params must be sent else it will return the templated URL
+func (controller *DLQController) FormatAsRelativeLink(params ...httprouter.Param) (result string) {
+	return formatURL(params, dlqPath, channelIDPathParamKey, consumerIDPathParamKey)
+}
+
+// Get Retrieves dead jobs for a specific consumer, optionally filtered by `from`/`to` time they died, `jobId`, `messageId` and `failureReason`
+func (controller *DLQController) Get(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	consumer := controller.getConsumer(w, params)
+	if consumer != nil {
+		filter, hasCriteria, err := getDeadJobFilter(r, consumer.ConsumingFrom, consumer)
+		if err != nil {
+			writeStatus(w, http.StatusBadRequest, ErrBadRequestForDLQFilter)
+			return
+		}
+		var deadJobs []*data.DeliveryJob
+		var resultPagination *data.Pagination
+		if hasCriteria {
+			deadJobs, resultPagination, err = controller.DeliveryJobRepo.GetDeadJobs(filter, getPagination(r))
+		} else {
+			deadJobs, resultPagination, err = controller.DeliveryJobRepo.GetJobsForConsumer(consumer, data.JobDead, getPagination(r))
+		}
+		if err == nil {
+			data := &DLQList{DeadJobs: newDeadDeliveryJobs(controller.MessageController, controller.DeadJobController, deadJobs...), Pages: getPaginationLinks(r, resultPagination)}
+			writeJSON(w, data)
+		} else {
+			writeErr(w, err)
+		}
+	}
+}
+
+func (controller *DLQController) getConsumer(w http.ResponseWriter, params httprouter.Params) *data.Consumer {
+	return getConsumerForDLQ(controller.ConsumerRepo, w, params)
+}
+
+// Post Requeue dead jobs for another single delivery attempt, or discard them when `action` is `discard`; the same filters as Get narrow
+// down the dead jobs acted upon
+func (controller *DLQController) Post(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	validRequest := checkFormContentType(r, w)
+	if !validRequest {
+		return
+	}
+	consumer := controller.getConsumer(w, params)
+	if consumer != nil {
+		if !isConsumerTokenForDLQ(r, consumer) {
+			writeStatus(w, http.StatusBadRequest, ErrBadRequestForRequeue)
+			return
+		}
+		action, validAction := getDLQAction(r)
+		filter, hasCriteria, err := getDeadJobFilter(r, consumer.ConsumingFrom, consumer)
+		if !validAction || err != nil {
+			writeStatus(w, http.StatusBadRequest, ErrBadRequestForDLQFilter)
+			return
+		}
+		if hasCriteria || action != dlqActionRequeue {
+			err = applyDLQAction(controller.DeliveryJobRepo, action, filter)
+		} else {
+			err = controller.DeliveryJobRepo.RequeueDeadJobsForConsumer(consumer)
+		}
+		if err == nil {
+			writeStatus(w, http.StatusAccepted, nil)
+		} else {
+			writeErr(w, err)
+		}
+	}
+}
+
+// DeadJobController represents the GET and POST endpoint for a single dead job of a consumer
+type DeadJobController struct {
+	MessageController EndpointController
+	DeliveryJobRepo   storage.DeliveryJobRepository
+	ConsumerRepo      storage.ConsumerRepository
+}
+
+// NewDeadJobController retrieves the controller for a single dead job
+func NewDeadJobController(msgController *MessageController, djRepo storage.DeliveryJobRepository, consumerRepo storage.ConsumerRepository) *DeadJobController {
+	return &DeadJobController{MessageController: msgController, DeliveryJobRepo: djRepo, ConsumerRepo: consumerRepo}
+}
+
+// GetPath returns the endpoint's path
+func (controller *DeadJobController) GetPath() string {
+	return deadJobPath
+}
+
+// FormatAsRelativeLink formats this controllers URL with the parameters provided. `channelId`, `consumerId` and `jobId` params must be sent else it will return the templated URL
+func (controller *DeadJobController) FormatAsRelativeLink(params ...httprouter.Param) (result string) {
+	return formatURL(params, deadJobPath, channelIDPathParamKey, consumerIDPathParamKey, deadJobIDPathParamKey)
+}
+
+// getDeadJob loads the dead job for the path params; writes the appropriate response and returns nil if the job is not found or not dead
+func (controller *DeadJobController) getDeadJob(w http.ResponseWriter, params httprouter.Params) (*data.DeadJobFilter, *data.DeliveryJob) {
+	consumer := getConsumerForDLQ(controller.ConsumerRepo, w, params)
+	if consumer == nil {
+		return nil, nil
+	}
+	filter := &data.DeadJobFilter{Channel: consumer.ConsumingFrom, Consumer: consumer, JobIDs: []string{params.ByName(deadJobIDPathParamKey)}}
+	deadJobs, _, err := controller.DeliveryJobRepo.GetDeadJobs(filter, data.NewPagination(nil, nil))
+	if err != nil {
+		writeErr(w, err)
+		return nil, nil
+	}
+	if len(deadJobs) < 1 {
+		writeNotFound(w)
+		return nil, nil
+	}
+	return filter, deadJobs[0]
+}
+
+// Get implements GET /channel/:channelId/consumer/:consumerId/dlq/:jobId
+func (controller *DeadJobController) Get(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	_, deadJob := controller.getDeadJob(w, params)
+	if deadJob != nil {
+		writeJSON(w, newDeadDeliveryJobs(controller.MessageController, controller, deadJob)[0])
+	}
+}
+
+// Post implements POST /channel/:channelId/consumer/:consumerId/dlq/:jobId to requeue, or discard when `action` is `discard`, a single dead job
+func (controller *DeadJobController) Post(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	validRequest := checkFormContentType(r, w)
+	if !validRequest {
+		return
+	}
+	filter, deadJob := controller.getDeadJob(w, params)
+	if deadJob == nil {
+		return
+	}
+	if !isConsumerTokenForDLQ(r, deadJob.Listener) {
+		writeStatus(w, http.StatusBadRequest, ErrBadRequestForRequeue)
+		return
+	}
+	action, validAction := getDLQAction(r)
+	if !validAction {
+		writeStatus(w, http.StatusBadRequest, ErrBadRequestForDLQFilter)
+		return
+	}
+	if err := applyDLQAction(controller.DeliveryJobRepo, action, filter); err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeStatus(w, http.StatusAccepted, nil)
+}
+
+// getChannelDeadJobFilter loads the channel and, if `consumerId` is provided, the consumer to build the dead jobs filter; writes the appropriate
+// response and returns nil if request is not valid
+func getChannelDeadJobFilter(channelRepo storage.ChannelRepository, consumerRepo storage.ConsumerRepository, w http.ResponseWriter, r *http.Request, params httprouter.Params) *data.DeadJobFilter {
+	channel, err := channelRepo.Get(params.ByName(channelIDPathParamKey))
+	if err != nil {
+		writeNotFound(w)
+		return nil
+	}
+	var consumer *data.Consumer
+	if consumerID := r.URL.Query().Get(dlqConsumerIDParamName); len(consumerID) > 0 {
+		if consumer, err = consumerRepo.Get(channel.ChannelID, consumerID); err != nil {
+			writeStatus(w, http.StatusBadRequest, ErrBadRequestForDLQFilter)
+			return nil
+		}
+	}
+	filter, _, err := getDeadJobFilter(r, channel, consumer)
+	if err != nil {
+		writeStatus(w, http.StatusBadRequest, ErrBadRequestForDLQFilter)
+		return nil
+	}
+	return filter
+}
+
+// ChannelDLQController represents the GET endpoint for dead jobs of all consumers of a channel
+type ChannelDLQController struct {
+	MessageController EndpointController
+	DeadJobController EndpointController
+	DeliveryJobRepo   storage.DeliveryJobRepository
+	ChannelRepo       storage.ChannelRepository
+	ConsumerRepo      storage.ConsumerRepository
+}
+
+// NewChannelDLQController retrieves the controller for channel wide DLQ list
+func NewChannelDLQController(msgController *MessageController, deadJobController *DeadJobController, djRepo storage.DeliveryJobRepository, channelRepo storage.ChannelRepository, consumerRepo storage.ConsumerRepository) *ChannelDLQController {
+	return &ChannelDLQController{MessageController: msgController, DeadJobController: deadJobController, DeliveryJobRepo: djRepo, ChannelRepo: channelRepo, ConsumerRepo: consumerRepo}
+}
+
+// GetPath returns the endpoint's path
+func (controller *ChannelDLQController) GetPath() string {
+	return channelDLQPath
+}
+
+// FormatAsRelativeLink formats this controllers URL with the parameters provided. `channelId` param must be sent else it will return the templated URL
+func (controller *ChannelDLQController) FormatAsRelativeLink(params ...httprouter.Param) (result string) {
+	return formatURL(params, channelDLQPath, channelIDPathParamKey)
+}
+
+// Get implements GET /channel/:channelId/dlq; supports the same filters as the consumer DLQ along with `consumerId`
+func (controller *ChannelDLQController) Get(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	filter := getChannelDeadJobFilter(controller.ChannelRepo, controller.ConsumerRepo, w, r, params)
+	if filter == nil {
+		return
+	}
+	deadJobs, resultPagination, err := controller.DeliveryJobRepo.GetDeadJobs(filter, getPagination(r))
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeJSON(w, &DLQList{DeadJobs: newDeadDeliveryJobs(controller.MessageController, controller.DeadJobController, deadJobs...), Pages: getPaginationLinks(r, resultPagination)})
+}
+
+// DLQExportController represents the GET endpoint exporting dead jobs of a channel along with their payloads
+type DLQExportController struct {
+	DeliveryJobRepo storage.DeliveryJobRepository
+	ChannelRepo     storage.ChannelRepository
+	ConsumerRepo    storage.ConsumerRepository
+	BlobStore       storage.BlobStore
+}
+
+// NewDLQExportController retrieves the controller for DLQ export
+func NewDLQExportController(djRepo storage.DeliveryJobRepository, channelRepo storage.ChannelRepository, consumerRepo storage.ConsumerRepository, blobStore storage.BlobStore) *DLQExportController {
+	return &DLQExportController{DeliveryJobRepo: djRepo, ChannelRepo: channelRepo, ConsumerRepo: consumerRepo, BlobStore: blobStore}
+}
+
+// GetPath returns the endpoint's path
+func (controller *DLQExportController) GetPath() string {
+	return dlqExportPath
+}
+
+// FormatAsRelativeLink formats this controllers URL with the parameters provided. `channelId` param must be sent else it will return the templated URL
+func (controller *DLQExportController) FormatAsRelativeLink(params ...httprouter.Param) (result string) {
+	return formatURL(params, dlqExportPath, channelIDPathParamKey)
+}
+
+// Get implements GET /channel/:channelId/dlq/export; streams all the dead jobs matching the channel DLQ filters as NDJSON, or as CSV if
+// `format` is `csv`
+func (controller *DLQExportController) Get(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	filter := getChannelDeadJobFilter(controller.ChannelRepo, controller.ConsumerRepo, w, r, params)
+	if filter == nil {
+		return
+	}
+	page := data.NewPagination(nil, nil)
+	deadJobs, resultPagination, err := controller.DeliveryJobRepo.GetDeadJobs(filter, page)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	csvFormat := r.URL.Query().Get(dlqExportFormatParamName) == dlqExportFormatCSV
+	var csvWriter *csv.Writer
+	var jsonEncoder *json.Encoder
+	if csvFormat {
+		w.Header().Set(headerContentType, csvContentTypeValue)
+		w.Header().Set(headerContentDisposition, "attachment; filename=\""+dlqExportFileNamePrefix+filter.Channel.ChannelID+".csv\"")
+		csvWriter = csv.NewWriter(w)
+		csvWriter.Write(dlqExportCSVHeader)
+	} else {
+		w.Header().Set(headerContentType, ndjsonContentTypeValue)
+		w.Header().Set(headerContentDisposition, "attachment; filename=\""+dlqExportFileNamePrefix+filter.Channel.ChannelID+".ndjson\"")
+		jsonEncoder = json.NewEncoder(w)
+	}
+	w.WriteHeader(http.StatusOK)
+	for err == nil && len(deadJobs) > 0 {
+		for _, deadJob := range deadJobs {
+			record := newDeadJobExportRecord(deadJob)
+			if len(record.PayloadRef) > 0 {
+				var blobErr error
+				if record.Payload, blobErr = readPayloadBlob(controller.BlobStore, record.PayloadRef); blobErr != nil {
+					log.Error().Err(blobErr).Str(requestIDLogFieldKey, getRequestID(r)).Msg("error - could not read offloaded payload of dead job " + record.JobID)
+				}
+			}
+			if csvFormat {
+				csvWriter.Write(record.toCSVRow())
+			} else {
+				jsonEncoder.Encode(record)
+			}
+		}
+		if csvFormat {
+			csvWriter.Flush()
+		}
+		page.Next = resultPagination.Next
+		deadJobs, resultPagination, err = controller.DeliveryJobRepo.GetDeadJobs(filter, page)
+	}
+	if err != nil {
+		log.Error().Err(err).Str(requestIDLogFieldKey, getRequestID(r)).Msg("error - DLQ export truncated")
+	}
+}
@@ -0,0 +1,302 @@
+package controllers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/newscred/webhook-broker/storage/data"
+	storagemocks "github.com/newscred/webhook-broker/storage/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const (
+	dlqManagementChannelID = "dlq-management-channel"
+)
+
+func createDeadJobsForDLQTest(t *testing.T, channel *data.Channel, consumer *data.Consumer, failureReasons ...string) []*data.DeliveryJob {
+	deadJobs := make([]*data.DeliveryJob, 0, len(failureReasons))
+	for _, failureReason := range failureReasons {
+		message, _ := data.NewMessage(channel, messageProducer, messagePayload, messageContentType)
+		assert.Nil(t, messageRepo.Create(message))
+		job, _ := data.NewDeliveryJob(message, consumer)
+		assert.Nil(t, djRepo.DispatchMessage(message, job))
+		assert.Nil(t, djRepo.MarkJobInflight(job))
+		job.FailureReason = failureReason
+		assert.Nil(t, djRepo.MarkJobDead(job))
+		deadJobs = append(deadJobs, job)
+	}
+	return deadJobs
+}
+
+func getDLQActionRequest(testURI string, form url.Values) *http.Request {
+	req, _ := http.NewRequest(http.MethodPost, testURI, nil)
+	req.Header.Add(headerContentType, formDataContentTypeHeaderValue)
+	req.PostForm = form
+	return req
+}
+
+func getDLQList(t *testing.T, testRouter http.Handler, testURI string) *DLQList {
+	req, _ := http.NewRequest(http.MethodGet, testURI, nil)
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	dlqList := &DLQList{}
+	assert.Nil(t, json.NewDecoder(rr.Body).Decode(dlqList))
+	return dlqList
+}
+
+func TestDLQManagement(t *testing.T) {
+	channel, _ := data.NewChannel(dlqManagementChannelID, successfulGetTestToken)
+	channel, err := channelRepo.Store(channel)
+	assert.Nil(t, err)
+	consumer1, _ := data.NewConsumer(channel, "dlq-management-consumer-1", successfulGetTestToken, callbackURL)
+	consumer1, err = consumerRepo.Store(consumer1)
+	assert.Nil(t, err)
+	consumer2, _ := data.NewConsumer(channel, "dlq-management-consumer-2", successfulGetTestToken, callbackURL)
+	consumer2, err = consumerRepo.Store(consumer2)
+	assert.Nil(t, err)
+	consumer1Jobs := createDeadJobsForDLQTest(t, channel, consumer1, "503 Service Unavailable", "502 Bad Gateway", "connection refused")
+	consumer2Jobs := createDeadJobsForDLQTest(t, channel, consumer2, "503 Service Unavailable")
+	deadJobController := NewDeadJobController(getMessageController(), djRepo, consumerRepo)
+	dlqController := NewDLQController(getMessageController(), deadJobController, djRepo, consumerRepo)
+	channelDLQController := NewChannelDLQController(getMessageController(), deadJobController, djRepo, channelRepo, consumerRepo)
+	exportController := NewDLQExportController(djRepo, channelRepo, consumerRepo, nil)
+	testRouter := createTestRouter(dlqController, deadJobController, channelDLQController, exportController)
+	channelIDParam := httprouter.Param{Key: channelIDPathParamKey, Value: channel.ChannelID}
+	consumer1Params := []httprouter.Param{channelIDParam, {Key: consumerIDPathParamKey, Value: consumer1.ConsumerID}}
+	consumer1DLQURL := dlqController.FormatAsRelativeLink(consumer1Params...)
+	channelDLQURL := channelDLQController.FormatAsRelativeLink(channelIDParam)
+	exportURL := exportController.FormatAsRelativeLink(channelIDParam)
+	t.Run("Paths", func(t *testing.T) {
+		assert.Equal(t, deadJobPath, deadJobController.GetPath())
+		assert.Equal(t, channelDLQPath, channelDLQController.GetPath())
+		assert.Equal(t, dlqExportPath, exportController.GetPath())
+		assert.Equal(t, "/channel/"+dlqManagementChannelID+"/dlq", channelDLQURL)
+		assert.Equal(t, "/channel/"+dlqManagementChannelID+"/dlq/export", exportURL)
+		assert.Equal(t, consumer1DLQURL+"/"+consumer1Jobs[0].ID.String(), deadJobController.FormatAsRelativeLink(append(consumer1Params, httprouter.Param{Key: deadJobIDPathParamKey, Value: consumer1Jobs[0].ID.String()})...))
+	})
+	t.Run("ChannelDLQ", func(t *testing.T) {
+		dlqList := getDLQList(t, testRouter, channelDLQURL)
+		assert.Equal(t, 4, len(dlqList.DeadJobs))
+		dlqList = getDLQList(t, testRouter, channelDLQURL+"?failureReason=503")
+		assert.Equal(t, 2, len(dlqList.DeadJobs))
+		dlqList = getDLQList(t, testRouter, channelDLQURL+"?failureReason=503&consumerId="+consumer2.ConsumerID)
+		assert.Equal(t, 1, len(dlqList.DeadJobs))
+		assert.Equal(t, consumer2.ConsumerID, dlqList.DeadJobs[0].ConsumerID)
+		assert.Equal(t, "503 Service Unavailable", dlqList.DeadJobs[0].FailureReason)
+		assert.Equal(t, data.JobDeadStr, dlqList.DeadJobs[0].Status)
+		for _, invalidQuery := range []string{"?consumerId=non-existent", "?from=yesterday", "?to=today"} {
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, channelDLQURL+invalidQuery, nil)
+			testRouter.ServeHTTP(rr, req)
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+		}
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/channel/non-existent-channel/dlq", nil)
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+	t.Run("ConsumerDLQFilter", func(t *testing.T) {
+		dlqList := getDLQList(t, testRouter, consumer1DLQURL+"?failureReason=50&from="+url.QueryEscape(time.Now().Add(-1*time.Hour).Format(time.RFC3339)))
+		assert.Equal(t, 2, len(dlqList.DeadJobs))
+		dlqList = getDLQList(t, testRouter, consumer1DLQURL+"?messageId="+consumer1Jobs[2].Message.MessageID)
+		assert.Equal(t, 1, len(dlqList.DeadJobs))
+		assert.Equal(t, "connection refused", dlqList.DeadJobs[0].FailureReason)
+		req, _ := http.NewRequest(http.MethodGet, dlqList.DeadJobs[0].JobURL, nil)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		deadJob := &DeadDeliveryJobModel{}
+		assert.Nil(t, json.NewDecoder(rr.Body).Decode(deadJob))
+		assert.Equal(t, dlqList.DeadJobs[0].JobURL, deadJob.JobURL)
+	})
+	t.Run("Export", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, exportURL, nil)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, ndjsonContentTypeValue, rr.Header().Get(headerContentType))
+		scanner := bufio.NewScanner(rr.Body)
+		records := 0
+		for scanner.Scan() {
+			record := &DeadJobExportRecord{}
+			assert.Nil(t, json.Unmarshal(scanner.Bytes(), record))
+			assert.Equal(t, messagePayload, record.Payload)
+			records++
+		}
+		assert.Equal(t, 4, records)
+		req, _ = http.NewRequest(http.MethodGet, exportURL+"?format=csv&consumerId="+consumer1.ConsumerID, nil)
+		rr = httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, csvContentTypeValue, rr.Header().Get(headerContentType))
+		rows, err := csv.NewReader(rr.Body).ReadAll()
+		assert.Nil(t, err)
+		assert.Equal(t, 4, len(rows))
+		assert.Equal(t, dlqExportCSVHeader, rows[0])
+		assert.Equal(t, consumer1.ConsumerID, rows[1][1])
+	})
+	t.Run("SingleJob", func(t *testing.T) {
+		jobURL := consumer1DLQURL + "/" + consumer1Jobs[0].ID.String()
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getDLQActionRequest(jobURL, url.Values{consumerTokenFormParamName: {"wrong token"}}))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		rr = httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getDLQActionRequest(jobURL, url.Values{consumerTokenFormParamName: {successfulGetTestToken}, dlqActionParamName: {"delete"}}))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		rr = httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getDLQActionRequest(jobURL, url.Values{consumerTokenFormParamName: {successfulGetTestToken}, dlqActionParamName: {dlqActionDiscard}}))
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		job, err := djRepo.GetByID(consumer1Jobs[0].ID.String())
+		assert.Nil(t, err)
+		assert.Equal(t, data.JobDiscarded, job.Status)
+		// Job no longer dead
+		rr = httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getDLQActionRequest(jobURL, url.Values{consumerTokenFormParamName: {successfulGetTestToken}}))
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		rr = httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getDLQActionRequest(jobURL, nil))
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		rr = httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, jobURL, nil)
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+	})
+	t.Run("FilteredRequeue", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getDLQActionRequest(consumer1DLQURL, url.Values{consumerTokenFormParamName: {successfulGetTestToken}, dlqActionParamName: {"delete"}}))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, ErrBadRequestForDLQFilter.Error(), rr.Body.String())
+		rr = httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getDLQActionRequest(consumer1DLQURL, url.Values{consumerTokenFormParamName: {successfulGetTestToken}, dlqFailureReasonParamName: {"refused"}}))
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		job, err := djRepo.GetByID(consumer1Jobs[2].ID.String())
+		assert.Nil(t, err)
+		assert.Equal(t, data.JobQueued, job.Status)
+		job, err = djRepo.GetByID(consumer1Jobs[1].ID.String())
+		assert.Nil(t, err)
+		assert.Equal(t, data.JobDead, job.Status)
+		job, err = djRepo.GetByID(consumer2Jobs[0].ID.String())
+		assert.Nil(t, err)
+		assert.Equal(t, data.JobDead, job.Status)
+	})
+	t.Run("DiscardAll", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getDLQActionRequest(consumer1DLQURL, url.Values{requeueFormParamName: {successfulGetTestToken}, dlqActionParamName: {dlqActionDiscard}}))
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		assert.Equal(t, 0, len(getDLQList(t, testRouter, consumer1DLQURL).DeadJobs))
+		assert.Equal(t, 1, len(getDLQList(t, testRouter, channelDLQURL).DeadJobs))
+	})
+}
+
+func TestDLQManagementErrors(t *testing.T) {
+	baseURL := "/channel/" + messageChannelID + "/consumer/" + dlqTestConsumerID + "/dlq"
+	t.Run("FilteredGetError", func(t *testing.T) {
+		t.Parallel()
+		controller := getDLQControllerWithMockedRepo()
+		mockedConsumerRepo := controller.ConsumerRepo.(*storagemocks.ConsumerRepository)
+		mockedDJRepo := controller.DeliveryJobRepo.(*storagemocks.DeliveryJobRepository)
+		mockedConsumerRepo.On("Get", messageChannelID, dlqTestConsumerID).Return(dlqConsumer, nil)
+		mockedDJRepo.On("GetDeadJobs", mock.Anything, mock.Anything).Return(nil, nil, errExpected)
+		req, _ := http.NewRequest(http.MethodGet, baseURL+"?failureReason=500", nil)
+		rr := httptest.NewRecorder()
+		createTestRouter(controller).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		mockedDJRepo.AssertExpectations(t)
+	})
+	t.Run("FilteredGetBadRequest", func(t *testing.T) {
+		t.Parallel()
+		controller := getDLQControllerWithMockedRepo()
+		mockedConsumerRepo := controller.ConsumerRepo.(*storagemocks.ConsumerRepository)
+		mockedConsumerRepo.On("Get", messageChannelID, dlqTestConsumerID).Return(dlqConsumer, nil)
+		req, _ := http.NewRequest(http.MethodGet, baseURL+"?from=yesterday", nil)
+		rr := httptest.NewRecorder()
+		createTestRouter(controller).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+	t.Run("DiscardError", func(t *testing.T) {
+		t.Parallel()
+		controller := getDLQControllerWithMockedRepo()
+		mockedConsumerRepo := controller.ConsumerRepo.(*storagemocks.ConsumerRepository)
+		mockedDJRepo := controller.DeliveryJobRepo.(*storagemocks.DeliveryJobRepository)
+		mockedConsumerRepo.On("Get", messageChannelID, dlqTestConsumerID).Return(dlqConsumer, nil)
+		mockedDJRepo.On("DiscardDeadJobs", mock.Anything).Return(errExpected)
+		rr := httptest.NewRecorder()
+		createTestRouter(controller).ServeHTTP(rr, getDLQActionRequest(baseURL, url.Values{consumerTokenFormParamName: {successfulGetTestToken}, dlqActionParamName: {dlqActionDiscard}}))
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		mockedDJRepo.AssertExpectations(t)
+	})
+	t.Run("DeadJobErrors", func(t *testing.T) {
+		t.Parallel()
+		mockedConsumerRepo := new(storagemocks.ConsumerRepository)
+		mockedDJRepo := new(storagemocks.DeliveryJobRepository)
+		mockedConsumerRepo.On("Get", messageChannelID, dlqTestConsumerID).Return(dlqConsumer, nil)
+		mockedConsumerRepo.On("Get", messageChannelID, "consumer-error").Return(nil, errExpected)
+		mockedDJRepo.On("GetDeadJobs", mock.MatchedBy(func(filter *data.DeadJobFilter) bool { return filter.JobIDs[0] == "get-error" }), mock.Anything).Return(nil, nil, errExpected)
+		deadJob := jobs[messages[0]]
+		mockedDJRepo.On("GetDeadJobs", mock.MatchedBy(func(filter *data.DeadJobFilter) bool { return filter.JobIDs[0] == "requeue-error" }), mock.Anything).Return([]*data.DeliveryJob{deadJob}, &data.Pagination{}, nil)
+		mockedDJRepo.On("RequeueDeadJobs", mock.Anything).Return(errExpected)
+		testRouter := createTestRouter(NewDeadJobController(getMessageController(), mockedDJRepo, mockedConsumerRepo))
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, baseURL+"/get-error", nil)
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		rr = httptest.NewRecorder()
+		req, _ = http.NewRequest(http.MethodGet, "/channel/"+messageChannelID+"/consumer/consumer-error/dlq/some-job", nil)
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		rr = httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getDLQActionRequest(baseURL+"/requeue-error", url.Values{consumerTokenFormParamName: {successfulGetTestToken}}))
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		mockedDJRepo.AssertExpectations(t)
+	})
+	t.Run("ExportOffloadedPayload", func(t *testing.T) {
+		t.Parallel()
+		channel, err := channelRepo.Get(messageChannelID)
+		assert.Nil(t, err)
+		consumer, _ := data.NewConsumer(channel, "dlq-export-offloaded-consumer", successfulGetTestToken, callbackURL)
+		message, _ := data.NewMessage(channel, messageProducer, "", messageContentType)
+		message.PayloadRef = "offloaded-payload-ref"
+		deadJob, _ := data.NewDeliveryJob(message, consumer)
+		mockedDJRepo := new(storagemocks.DeliveryJobRepository)
+		mockedDJRepo.On("GetDeadJobs", mock.Anything, mock.Anything).Return([]*data.DeliveryJob{deadJob}, &data.Pagination{}, nil).Once()
+		mockedDJRepo.On("GetDeadJobs", mock.Anything, mock.Anything).Return([]*data.DeliveryJob{}, &data.Pagination{}, nil).Once()
+		blobStore := new(storagemocks.BlobStore)
+		blobStore.On("Get", message.PayloadRef).Return(ioutil.NopCloser(strings.NewReader("offloaded payload")), nil)
+		exportController := NewDLQExportController(mockedDJRepo, channelRepo, consumerRepo, blobStore)
+		req, _ := http.NewRequest(http.MethodGet, exportController.FormatAsRelativeLink(httprouter.Param{Key: channelIDPathParamKey, Value: messageChannelID})+"?format=csv", nil)
+		rr := httptest.NewRecorder()
+		createTestRouter(exportController).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		rows, err := csv.NewReader(rr.Body).ReadAll()
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(rows))
+		assert.Equal(t, "offloaded payload", rows[1][4])
+		assert.Equal(t, message.PayloadRef, rows[1][len(dlqExportCSVHeader)-1])
+		mockedDJRepo.AssertExpectations(t)
+		blobStore.AssertExpectations(t)
+	})
+	t.Run("ChannelDLQErrors", func(t *testing.T) {
+		t.Parallel()
+		mockedDJRepo := new(storagemocks.DeliveryJobRepository)
+		mockedDJRepo.On("GetDeadJobs", mock.Anything, mock.Anything).Return(nil, nil, errExpected)
+		testRouter := createTestRouter(NewChannelDLQController(getMessageController(), nil, mockedDJRepo, channelRepo, consumerRepo), NewDLQExportController(mockedDJRepo, channelRepo, consumerRepo, nil))
+		for _, testURI := range []string{"/channel/" + messageChannelID + "/dlq", "/channel/" + messageChannelID + "/dlq/export"} {
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, testURI, nil)
+			testRouter.ServeHTTP(rr, req)
+			assert.Equal(t, http.StatusInternalServerError, rr.Code)
+			assert.True(t, strings.Contains(rr.Body.String(), errExpected.Error()))
+		}
+	})
+}
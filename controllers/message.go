@@ -3,6 +3,8 @@ package controllers
 import (
 	"database/sql"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/julienschmidt/httprouter"
@@ -11,11 +13,21 @@ import (
 )
 
 const (
-	messageIDParamKey    = "messageId"
-	messagePath          = channelPath + "/message/:" + messageIDParamKey
-	messagesPath         = channelPath + "/messages"
-	dlqPath              = consumerPath + "/dlq"
-	requeueFormParamName = "requeue"
+	messageIDParamKey           = "messageId"
+	messagePath                 = channelPath + "/message/:" + messageIDParamKey
+	messagesPath                = channelPath + "/messages"
+	messageStatusParamName      = "status"
+	messageProducerIDParamName  = "producerId"
+	messageFromParamName        = "from"
+	messageToParamName          = "to"
+	messageContentTypeParamName = "contentType"
+	messagePriorityParamName    = "priority"
+	messageIDPrefixParamName    = "messageIdPrefix"
+	messageHasDeadJobsParamName = "hasDeadJobs"
+	messageExpandParamName      = "expand"
+	messageExpandPayload        = "payload"
+	messageExpandJobs           = "jobs"
+	messageExpandSeparator      = ","
 )
 
 // DeliveryJobModel represents a delivery job of a message
@@ -26,21 +38,14 @@ type DeliveryJobModel struct {
 	StatusChangedAt  time.Time
 }
 
-// DeadDeliveryJobModel is a DeliveryJobModel with reference to its message and to be used for DLQ
-type DeadDeliveryJobModel struct {
-	DeliveryJobModel
-	MessageURL string
-}
-
-// DLQList represents the list of jobs that are dead
-type DLQList struct {
-	DeadJobs []*DeadDeliveryJobModel
-	Pages    map[string]string
-}
-
 // MessageModel represents a single message
 type MessageModel struct {
 	Payload      string
+	PayloadRef   string `json:",omitempty"`
+	RoutingKey   string
+	Attributes   map[string]string `json:",omitempty"`
+	CloudEvent   *CloudEventModel  `json:",omitempty"`
+	ReceiptURL   string            `json:",omitempty"`
 	ContentType  string
 	ProducedBy   string
 	ReceivedAt   time.Time
@@ -52,6 +57,11 @@ type MessageModel struct {
 func newMessageModel(message *data.Message, jobs ...*data.DeliveryJob) *MessageModel {
 	messageModel := &MessageModel{
 		Payload:      message.Payload,
+		PayloadRef:   message.PayloadRef,
+		RoutingKey:   message.RoutingKey,
+		Attributes:   message.Attributes,
+		CloudEvent:   newCloudEventModel(message.CloudEvent),
+		ReceiptURL:   message.ReceiptURL,
 		ContentType:  message.ContentType,
 		ReceivedAt:   message.ReceivedAt,
 		DispatchedAt: message.OutboxedAt,
@@ -74,14 +84,18 @@ func newDeliveryJobModel(job *data.DeliveryJob) *DeliveryJobModel {
 	}
 }
 
-func newDeadDeliveryJobs(msgController EndpointController, jobs ...*data.DeliveryJob) []*DeadDeliveryJobModel {
-	result := make([]*DeadDeliveryJobModel, 0, len(jobs))
-	for _, job := range jobs {
-		messageURL := msgController.FormatAsRelativeLink(httprouter.Param{Key: channelIDPathParamKey, Value: job.Message.BroadcastedTo.ChannelID},
-			httprouter.Param{Key: messageIDParamKey, Value: job.Message.MessageID})
-		result = append(result, &DeadDeliveryJobModel{DeliveryJobModel: *newDeliveryJobModel(job), MessageURL: messageURL})
-	}
-	return result
+// ExpandedMessageModel is a message in the list of messages of a channel; its payload and jobs are only set when expanded
+type ExpandedMessageModel struct {
+	MessageModel
+	MessageID  string
+	MessageURL string
+	Priority   uint
+}
+
+// MessageListResult is the resource returned by /channel/:channelId/messages; it has the messages themselves only when expanded
+type MessageListResult struct {
+	ListResult
+	Messages []*ExpandedMessageModel `json:",omitempty"`
 }
 
 // MessageController represents the GET endpoint for a single message broadcasted to a channel
@@ -135,15 +149,17 @@ func (messageController *MessageController) Get(w http.ResponseWriter, r *http.R
 	}
 }
 
-// MessagesController represents the GET endpoint for listing all messages broadcasted to a channel
+// MessagesController represents the GET endpoint for listing and searching messages broadcasted to a channel
 type MessagesController struct {
 	MessageController EndpointController
 	MessageRepo       storage.MessageRepository
+	ChannelRepo       storage.ChannelRepository
+	DeliveryJobRepo   storage.DeliveryJobRepository
 }
 
 // NewMessagesController initializes the controller for messages in a channel
-func NewMessagesController(msgController *MessageController, msgRepo storage.MessageRepository) *MessagesController {
-	return &MessagesController{MessageController: msgController, MessageRepo: msgRepo}
+func NewMessagesController(msgController *MessageController, msgRepo storage.MessageRepository, channelRepo storage.ChannelRepository, djRepo storage.DeliveryJobRepository) *MessagesController {
+	return &MessagesController{MessageController: msgController, MessageRepo: msgRepo, ChannelRepo: channelRepo, DeliveryJobRepo: djRepo}
 }
 
 // GetPath returns the endpoint's path
@@ -156,10 +172,85 @@ func (messagesController *MessagesController) FormatAsRelativeLink(params ...htt
 	return formatURL(params, messagesPath, channelIDPathParamKey)
 }
 
-// Get implements GET /channel/:channelId/messages
+// getMessageFilter builds the filter for messages of the channel from the request's query params; also returns whether any criteria
+// other than the channel was provided
+func getMessageFilter(r *http.Request, channel *data.Channel) (filter *data.MessageFilter, hasCriteria bool, err error) {
+	query := r.URL.Query()
+	filter = &data.MessageFilter{Channel: channel, ProducerIDs: query[messageProducerIDParamName], ContentType: query.Get(messageContentTypeParamName),
+		MessageIDPrefix: query.Get(messageIDPrefixParamName)}
+	for _, value := range query[messageStatusParamName] {
+		status, ok := data.ParseMsgStatus(value)
+		if !ok {
+			return filter, true, ErrBadRequestForMessageFilter
+		}
+		filter.Statuses = append(filter.Statuses, status)
+	}
+	for _, value := range query[messagePriorityParamName] {
+		priority, pErr := strconv.ParseUint(value, 10, 0)
+		if pErr != nil {
+			return filter, true, ErrBadRequestForMessageFilter
+		}
+		filter.Priorities = append(filter.Priorities, uint(priority))
+	}
+	if from := query.Get(messageFromParamName); len(from) > 0 && err == nil {
+		filter.ReceivedSince, err = time.Parse(time.RFC3339, from)
+	}
+	if to := query.Get(messageToParamName); len(to) > 0 && err == nil {
+		filter.ReceivedUntil, err = time.Parse(time.RFC3339, to)
+	}
+	if hasDeadJobs := query.Get(messageHasDeadJobsParamName); len(hasDeadJobs) > 0 && err == nil {
+		filter.HasDeadJobs, err = strconv.ParseBool(hasDeadJobs)
+	}
+	if err != nil || !filter.IsInValidState() {
+		err = ErrBadRequestForMessageFilter
+	}
+	hasCriteria = len(filter.Statuses) > 0 || len(filter.ProducerIDs) > 0 || !filter.ReceivedSince.IsZero() || !filter.ReceivedUntil.IsZero() ||
+		len(filter.ContentType) > 0 || len(filter.Priorities) > 0 || len(filter.MessageIDPrefix) > 0 || filter.HasDeadJobs
+	return filter, hasCriteria, err
+}
+
+// getMessageExpansion returns whether payloads and jobs of the messages listed are to be included
+func getMessageExpansion(r *http.Request) (payload bool, jobs bool, err error) {
+	for _, value := range r.URL.Query()[messageExpandParamName] {
+		for _, expansion := range strings.Split(value, messageExpandSeparator) {
+			switch strings.TrimSpace(expansion) {
+			case messageExpandPayload:
+				payload = true
+			case messageExpandJobs:
+				jobs = true
+			default:
+				return payload, jobs, ErrBadRequestForMessageFilter
+			}
+		}
+	}
+	return payload, jobs, nil
+}
+
+// Get implements GET /channel/:channelId/messages; messages are searched when filter query params are provided and expanded with `expand`
 func (messagesController *MessagesController) Get(w http.ResponseWriter, r *http.Request, param httprouter.Params) {
 	channelID := param.ByName(channelIDPathParamKey)
-	messages, resultPagination, err := messagesController.MessageRepo.GetMessagesForChannel(channelID, getPagination(r))
+	expandPayload, expandJobs, err := getMessageExpansion(r)
+	if err != nil {
+		writeStatus(w, http.StatusBadRequest, err)
+		return
+	}
+	channel, err := messagesController.ChannelRepo.Get(channelID)
+	if err != nil {
+		writeNotFound(w)
+		return
+	}
+	filter, hasCriteria, err := getMessageFilter(r, channel)
+	if err != nil {
+		writeStatus(w, http.StatusBadRequest, err)
+		return
+	}
+	var messages []*data.Message
+	var resultPagination *data.Pagination
+	if hasCriteria {
+		messages, resultPagination, err = messagesController.MessageRepo.SearchMessages(filter, getPagination(r))
+	} else {
+		messages, resultPagination, err = messagesController.MessageRepo.GetMessagesForChannel(channelID, getPagination(r))
+	}
 	if err != nil {
 		switch err {
 		case sql.ErrNoRows:
@@ -174,77 +265,39 @@ func (messagesController *MessagesController) Get(w http.ResponseWriter, r *http
 	for index, msg := range messages {
 		msgURLs[index] = messagesController.MessageController.FormatAsRelativeLink(channelIDParam, httprouter.Param{Key: messageIDParamKey, Value: msg.MessageID})
 	}
-	data := ListResult{Result: msgURLs, Pages: getPaginationLinks(r, resultPagination)}
-	writeJSON(w, data)
-}
-
-// DLQController represents the GET and POST endpoint for reading dead and requeuing all dead messages for delivery.
-type DLQController struct {
-	MessageController EndpointController
-	DeliveryJobRepo   storage.DeliveryJobRepository
-	ConsumerRepo      storage.ConsumerRepository
-}
-
-// NewDLQController retrieves the controller for DLQ list and requeue endpoints
-func NewDLQController(msgController *MessageController, djRepo storage.DeliveryJobRepository, consumerRepo storage.ConsumerRepository) *DLQController {
-	return &DLQController{MessageController: msgController, DeliveryJobRepo: djRepo, ConsumerRepo: consumerRepo}
-}
-
-// GetPath returns the endpoint's path
-func (controller *DLQController) GetPath() string {
-	return dlqPath
-}
-
-// FormatAsRelativeLink formats this controllers URL with the parameters provided. Both `consumerId` and `channelId` params must be sent else it will return the templated URL
-func (controller *DLQController) FormatAsRelativeLink(params ...httprouter.Param) (result string) {
-	return formatURL(params, dlqPath, channelIDPathParamKey, consumerIDPathParamKey)
-}
-
-// Get Retrieves dead jobs for a specific consumer
-func (controller *DLQController) Get(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
-	consumer := controller.getConsumer(w, params)
-	if consumer != nil {
-		deadJobs, resultPagination, err := controller.DeliveryJobRepo.GetJobsForConsumer(consumer, data.JobDead, getPagination(r))
-		if err == nil {
-			data := &DLQList{DeadJobs: newDeadDeliveryJobs(controller.MessageController, deadJobs...), Pages: getPaginationLinks(r, resultPagination)}
-			writeJSON(w, data)
-		} else {
+	result := &MessageListResult{ListResult: ListResult{Result: msgURLs, Pages: getPaginationLinks(r, resultPagination)}}
+	if expandPayload || expandJobs {
+		if result.Messages, err = messagesController.expandMessages(messages, msgURLs, expandPayload, expandJobs); err != nil {
 			writeErr(w, err)
+			return
 		}
 	}
+	writeJSON(w, result)
 }
 
-func (controller *DLQController) getConsumer(w http.ResponseWriter, params httprouter.Params) *data.Consumer {
-	consumer, err := controller.ConsumerRepo.Get(params.ByName(channelIDPathParamKey), params.ByName(consumerIDPathParamKey))
-	if err != nil {
-		switch err {
-		case sql.ErrNoRows:
-			writeNotFound(w)
-		default:
-			writeErr(w, err)
+// expandMessages returns the models of the messages, with their jobs loaded for all of them at once
+func (messagesController *MessagesController) expandMessages(messages []*data.Message, msgURLs []string, expandPayload bool, expandJobs bool) ([]*ExpandedMessageModel, error) {
+	jobsByMessage := make(map[*data.Message][]*data.DeliveryJob, len(messages))
+	if expandJobs {
+		jobs, err := messagesController.DeliveryJobRepo.GetJobsForMessages(messages)
+		if err != nil {
+			return nil, err
+		}
+		for _, job := range jobs {
+			jobsByMessage[job.Message] = append(jobsByMessage[job.Message], job)
 		}
-		return nil
-	}
-	return consumer
-}
-
-// Post Requeue dead jobs for another single delivery attempt
-func (controller *DLQController) Post(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
-	validRequest := checkFormContentType(r, w)
-	if !validRequest {
-		return
 	}
-	consumer := controller.getConsumer(w, params)
-	if consumer != nil {
-		if r.PostFormValue(requeueFormParamName) != consumer.Token {
-			writeStatus(w, http.StatusBadRequest, ErrBadRequestForRequeue)
-			return
+	result := make([]*ExpandedMessageModel, 0, len(messages))
+	for index, message := range messages {
+		model := &ExpandedMessageModel{MessageModel: *newMessageModel(message, jobsByMessage[message]...), MessageID: message.MessageID, MessageURL: msgURLs[index],
+			Priority: message.Priority}
+		if !expandPayload {
+			model.Payload = ""
 		}
-		err := controller.DeliveryJobRepo.RequeueDeadJobsForConsumer(consumer)
-		if err == nil {
-			writeStatus(w, http.StatusAccepted, nil)
-		} else {
-			writeErr(w, err)
+		if !expandJobs {
+			model.Jobs = nil
 		}
+		result = append(result, model)
 	}
+	return result, nil
 }
@@ -22,13 +22,16 @@ import (
 )
 
 const (
-	dlqTestConsumerID  = "consumer-dlq-test"
-	messageProducerID  = "message-test-producer-id"
-	messageChannelID   = "message-test-channel-id"
-	messageIDPrefix    = "message-test-id-"
-	messagePayload     = "<test>hello world</test>"
-	messageContentType = "text/xml"
-	messagesCount      = 45
+	dlqTestConsumerID     = "consumer-dlq-test"
+	messageProducerID     = "message-test-producer-id"
+	messageChannelID      = "message-test-channel-id"
+	messageIDPrefix       = "message-test-id-"
+	messagePayload        = "<test>hello world</test>"
+	messageContentType    = "text/xml"
+	messageRoutingKey     = "message.test.created"
+	messageAttributeName  = "Tenant-Id"
+	messageAttributeValue = "message-test-tenant"
+	messagesCount         = 45
 )
 
 var (
@@ -54,6 +57,8 @@ func MessageTestSetup() {
 	for index := 0; index < messagesCount; index++ {
 		messages[index], _ = data.NewMessage(messageChannel, messageProducer, messagePayload, messageContentType)
 		messages[index].MessageID = messageIDPrefix + strconv.Itoa(index)
+		messages[index].RoutingKey = messageRoutingKey
+		messages[index].Attributes = data.MessageAttributes{messageAttributeName: messageAttributeValue}
 		messageRepo.Create(messages[index])
 		jobs[messages[index]], _ = data.NewDeliveryJob(messages[index], dlqConsumer)
 		err := djRepo.DispatchMessage(messages[index], jobs[messages[index]])
@@ -85,13 +90,13 @@ func getMessageController() *MessageController {
 }
 
 func getMessagesController() *MessagesController {
-	return NewMessagesController(getMessageController(), messageRepo)
+	return NewMessagesController(getMessageController(), messageRepo, channelRepo, djRepo)
 }
 
 func getDLQControllerWithMockedRepo() *DLQController {
 	consumerRepo := new(storagemocks.ConsumerRepository)
 	djRepo := new(storagemocks.DeliveryJobRepository)
-	return NewDLQController(getMessageController(), djRepo, consumerRepo)
+	return NewDLQController(getMessageController(), NewDeadJobController(getMessageController(), djRepo, consumerRepo), djRepo, consumerRepo)
 }
 
 func TestMessageFormatRelativeLink(t *testing.T) {
@@ -120,6 +125,8 @@ func TestMessageGet(t *testing.T) {
 				assert.Nil(t, err)
 				assert.Equal(t, messagePayload, msgModel.Payload)
 				assert.Equal(t, messageContentType, msgModel.ContentType)
+				assert.Equal(t, messageRoutingKey, msgModel.RoutingKey)
+				assert.Equal(t, map[string]string{messageAttributeName: messageAttributeValue}, msgModel.Attributes)
 				assert.Equal(t, data.MsgStatusDispatched.String(), msgModel.Status)
 				assert.Equal(t, 1, len(msgModel.Jobs))
 				assert.Equal(t, messageProducer.Name, msgModel.ProducedBy)
@@ -221,6 +228,85 @@ func TestMessagesGet(t *testing.T) {
 	})
 }
 
+func getMessagesSearchResult(t *testing.T, testRouter http.Handler, path string) (int, *MessageListResult) {
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	rr := httptest.NewRecorder()
+	testRouter.ServeHTTP(rr, req)
+	result := &MessageListResult{}
+	if rr.Code == http.StatusOK {
+		assert.Nil(t, json.NewDecoder(rr.Body).Decode(result))
+	}
+	return rr.Code, result
+}
+
+func TestMessagesSearch(t *testing.T) {
+	baseURL := "/channel/" + messageChannelID + "/messages"
+	testRouter := createTestRouter(getMessagesController())
+	t.Run("400", func(t *testing.T) {
+		t.Parallel()
+		for _, query := range []string{"status=DELIVERED", "priority=high", "from=yesterday", "hasDeadJobs=maybe", "expand=consumers", "expand=payload,attributes",
+			"from=2021-01-02T00:00:00Z&to=2021-01-01T00:00:00Z"} {
+			code, _ := getMessagesSearchResult(t, testRouter, baseURL+"?"+query)
+			assert.Equal(t, http.StatusBadRequest, code, query)
+		}
+	})
+	t.Run("404", func(t *testing.T) {
+		t.Parallel()
+		code, _ := getMessagesSearchResult(t, testRouter, "/channel/no-such-channel-to-search/messages?hasDeadJobs=true")
+		assert.Equal(t, http.StatusNotFound, code)
+	})
+	t.Run("Filters", func(t *testing.T) {
+		t.Parallel()
+		code, result := getMessagesSearchResult(t, testRouter, baseURL+"?hasDeadJobs=true&expand=jobs")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, 9, len(result.Result))
+		assert.Equal(t, 9, len(result.Messages))
+		for index, message := range result.Messages {
+			assert.Equal(t, result.Result[index], message.MessageURL)
+			assert.Empty(t, message.Payload)
+			assert.Equal(t, 1, len(message.Jobs))
+			assert.Equal(t, data.JobDead.String(), message.Jobs[0].Status)
+		}
+		code, result = getMessagesSearchResult(t, testRouter, baseURL+"?messageIdPrefix="+messageIDPrefix+"1&expand=payload")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, 11, len(result.Messages))
+		for _, message := range result.Messages {
+			assert.True(t, strings.HasPrefix(message.MessageID, messageIDPrefix+"1"))
+			assert.Equal(t, messagePayload, message.Payload)
+			assert.Nil(t, message.Jobs)
+		}
+		code, result = getMessagesSearchResult(t, testRouter, baseURL+"?status=acknowledged")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Empty(t, result.Result)
+		assert.Nil(t, result.Messages)
+	})
+	t.Run("Pagination", func(t *testing.T) {
+		t.Parallel()
+		query := url.Values{messageProducerIDParamName: {messageProducerID}, messageStatusParamName: {data.MsgStatusDispatchedStr}, messagePriorityParamName: {"0"},
+			messageContentTypeParamName: {messageContentType}, messageFromParamName: {"2021-01-01T00:00:00Z"}}
+		code, result := getMessagesSearchResult(t, testRouter, baseURL+"?"+query.Encode())
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, 25, len(result.Result))
+		next, _ := url.Parse(result.Pages[nextPaginationQueryParamKey])
+		assert.Equal(t, messageProducerID, next.Query().Get(messageProducerIDParamName))
+		code, result = getMessagesSearchResult(t, testRouter, next.String())
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, 20, len(result.Result))
+	})
+	t.Run("500", func(t *testing.T) {
+		t.Parallel()
+		mockedMessageRepo := new(storagemocks.MessageRepository)
+		mockedMessageRepo.On("SearchMessages", mock.Anything, mock.Anything).Return(nil, nil, errExpected)
+		mockedDJRepo := new(storagemocks.DeliveryJobRepository)
+		mockedDJRepo.On("GetJobsForMessages", mock.Anything).Return(nil, errExpected)
+		for path, controller := range map[string]*MessagesController{baseURL + "?hasDeadJobs=true": NewMessagesController(getMessageController(), mockedMessageRepo, channelRepo, djRepo),
+			baseURL + "?expand=jobs": NewMessagesController(getMessageController(), messageRepo, channelRepo, mockedDJRepo)} {
+			code, _ := getMessagesSearchResult(t, createTestRouter(controller), path)
+			assert.Equal(t, http.StatusInternalServerError, code, path)
+		}
+	})
+}
+
 func TestDLQFormatLinks(t *testing.T) {
 	controller := getDLQControllerWithMockedRepo()
 	assert.Equal(t, "/channel/"+messageChannelID+"/consumer/"+dlqTestConsumerID+"/dlq", controller.FormatAsRelativeLink(httprouter.Param{Key: channelIDPathParamKey, Value: messageChannelID}, httprouter.Param{Key: consumerIDPathParamKey, Value: dlqTestConsumerID}))
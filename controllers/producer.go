@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/newscred/webhook-broker/config"
 	"github.com/newscred/webhook-broker/storage"
 	"github.com/newscred/webhook-broker/storage/data"
 )
@@ -13,6 +14,7 @@ const (
 	producersPath          = "/producers"
 	producerIDPathParamKey = "producerId"
 	producerPath           = "/producer/:" + producerIDPathParamKey
+	receiptURLParamName    = "receiptUrl"
 )
 
 // MsgStakeholder is the
@@ -32,6 +34,17 @@ func getMessageStakeholder(id string, stakeholderModel *data.MessageStakeholder)
 	return &MsgStakeholder{ID: id, Name: stakeholderModel.Name, Token: stakeholderModel.Token, ChangedAt: stakeholderModel.UpdatedAt}
 }
 
+// ProducerModel is the producer resource along with where its delivery receipts are sent to and its publish rate limit
+type ProducerModel struct {
+	MsgStakeholder
+	ReceiptURL string `json:",omitempty"`
+	RateLimit  *RateLimitModel
+}
+
+func getProducerModel(producer *data.Producer) *ProducerModel {
+	return &ProducerModel{MsgStakeholder: *getMessageStakeholder(producer.ProducerID, &producer.MessageStakeholder), ReceiptURL: producer.ReceiptURL}
+}
+
 // ListResult is the resource returned by /producers endpoint
 type ListResult struct {
 	Result []string
@@ -77,14 +90,33 @@ func getUpdateData(r *http.Request, defaultName string) (token string, name stri
 
 // ProducerController is for /producer/:prodId
 type ProducerController struct {
-	ProducerRepo storage.ProducerRepository
+	ProducerRepo    storage.ProducerRepository
+	QuotaRepo       storage.PublishQuotaRepository
+	RateLimitConfig config.RateLimitConfig
+	BlobStore       storage.BlobStore
+	PurgeRetention  time.Duration
 }
 
 // Get implements the /producer/:prodId GET endpoint
 func (prodController *ProducerController) Get(w http.ResponseWriter, r *http.Request, param httprouter.Params) {
 	producerID := param.ByName(producerIDPathParamKey)
 	producerModel, err := prodController.ProducerRepo.Get(producerID)
-	writeGetResult(err, writeNotFound, w, getMessageStakeholder(producerID, &producerModel.MessageStakeholder))
+	producerModel.ProducerID = producerID
+	if err != nil {
+		writeNotFound(w)
+		return
+	}
+	prodController.writeProducer(w, producerModel)
+}
+
+func (prodController *ProducerController) writeProducer(w http.ResponseWriter, producer *data.Producer) {
+	model := getProducerModel(producer)
+	var err error
+	if model.RateLimit, err = getRateLimitModel(prodController.QuotaRepo, data.ProducerQuota, producer.ProducerID, prodController.RateLimitConfig.GetProducerRateLimit()); err != nil {
+		writeErr(w, err)
+		return
+	}
+	writeGetResult(nil, writeNotFound, w, model)
 }
 
 // Put implements the /producer/:prodId PUT endpoint
@@ -98,11 +130,50 @@ func (prodController *ProducerController) Put(w http.ResponseWriter, r *http.Req
 	if !validRequest {
 		return
 	}
+	receiptURL := r.PostFormValue(receiptURLParamName)
+	if !data.IsValidReceiptURL(receiptURL) {
+		writeStatus(w, http.StatusBadRequest, ErrBadRequestForReceiptURL)
+		return
+	}
+	rateLimit, err := getRateLimitFromForm(r)
+	if err != nil {
+		writeStatus(w, http.StatusBadRequest, err)
+		return
+	}
 	token, name := getUpdateData(r, producerID)
 	producer, _ := data.NewProducer(producerID, token)
 	producer.Name = name
+	producer.ReceiptURL = receiptURL
 	producer, err = prodController.ProducerRepo.Store(producer)
-	writeGetResult(err, func(w http.ResponseWriter) { writeErr(w, err) }, w, getMessageStakeholder(producerID, &producer.MessageStakeholder))
+	producer.ProducerID = producerID
+	if err == nil {
+		_, err = prodController.QuotaRepo.SetLimit(data.ProducerQuota, producerID, rateLimit)
+	}
+	if err != nil {
+		writeStoreErr(w, err)
+		return
+	}
+	prodController.writeProducer(w, producer)
+}
+
+// Delete implements the /producer/:prodId DELETE endpoint; soft deletes the producer or, with `purge=true`, permanently deletes it along
+// with its messages once its retention is over
+func (prodController *ProducerController) Delete(w http.ResponseWriter, r *http.Request, param httprouter.Params) {
+	producerID := param.ByName(producerIDPathParamKey)
+	if producerID == data.BrokerProducerID {
+		writeStatus(w, http.StatusForbidden, ErrReservedStakeholder)
+		return
+	}
+	producer, err := prodController.ProducerRepo.GetIncludingDeleted(producerID)
+	softDelete := func(drain bool) error { return prodController.ProducerRepo.Delete(producer, drain) }
+	purge := func() error {
+		payloadRefs, err := prodController.ProducerRepo.Purge(producer)
+		if err == nil {
+			deletePayloadBlobs(prodController.BlobStore, payloadRefs)
+		}
+		return err
+	}
+	deleteStakeholder(w, r, producer, err, prodController.PurgeRetention, softDelete, purge)
 }
 
 func checkFormContentType(r *http.Request, w http.ResponseWriter) bool {
@@ -156,8 +227,8 @@ func (prodController *ProducersController) FormatAsRelativeLink(params ...httpro
 }
 
 // NewProducerController initialize new producers controller
-func NewProducerController(producerRepo storage.ProducerRepository) *ProducerController {
-	return &ProducerController{ProducerRepo: producerRepo}
+func NewProducerController(producerRepo storage.ProducerRepository, quotaRepo storage.PublishQuotaRepository, rateLimitConfig config.RateLimitConfig, blobStore storage.BlobStore, brokerConfig config.BrokerConfig) *ProducerController {
+	return &ProducerController{ProducerRepo: producerRepo, QuotaRepo: quotaRepo, RateLimitConfig: rateLimitConfig, BlobStore: blobStore, PurgeRetention: brokerConfig.GetPurgeRetention()}
 }
 
 // NewProducersController initialize new producers controller
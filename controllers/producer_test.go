@@ -20,18 +20,25 @@ import (
 	"github.com/stretchr/testify/mock"
 )
 
-var producerRepo storage.ProducerRepository
+var (
+	producerRepo storage.ProducerRepository
+	quotaRepo    storage.PublishQuotaRepository
+	grantRepo    storage.PublishGrantRepository
+)
 
 const (
 	successfulGetTestToken      = "sometokenforget"
 	listTestProducerIDPrefix    = "controller-get-list-"
 	createProducerIDWithData    = "put-producer-id"
 	createProducerIDWithoutData = "put-producer-id-without-data"
+	createProducerIDWithReceipt = "put-producer-id-with-receipt"
 )
 
 // ProducerTestSetup is called from TestMain for the package
 func ProducerTestSetup() {
 	producerRepo = storage.NewProducerRepository(db)
+	quotaRepo = storage.NewPublishQuotaRepository(db)
+	grantRepo = storage.NewPublishGrantRepository(db)
 	for index := 48; index > -1; index = index - 1 {
 		indexString := strconv.Itoa(index)
 		producer, err := data.NewProducer(listTestProducerIDPrefix+indexString, successfulGetTestToken+" - "+indexString)
@@ -45,7 +52,7 @@ func ProducerTestSetup() {
 }
 
 func TestProducersControllerGet(t *testing.T) {
-	testRouter := createTestRouter(NewProducersController(producerRepo, NewProducerController(producerRepo)))
+	testRouter := createTestRouter(NewProducersController(producerRepo, NewProducerController(producerRepo, quotaRepo, configuration, nil, configuration)))
 	req, _ := http.NewRequest("GET", "/producers", nil)
 	rr := httptest.NewRecorder()
 	testRouter.ServeHTTP(rr, req)
@@ -98,7 +105,7 @@ func TestProducersControllerGet_Error(t *testing.T) {
 	mockProducerRepo := new(storagemocks.ProducerRepository)
 	expectedErr := errors.New("GetList error")
 	mockProducerRepo.On("GetList", mock.Anything).Return(nil, nil, expectedErr)
-	testRouter := createTestRouter(NewProducersController(mockProducerRepo, NewProducerController(mockProducerRepo)))
+	testRouter := createTestRouter(NewProducersController(mockProducerRepo, NewProducerController(mockProducerRepo, quotaRepo, configuration, nil, configuration)))
 	req, _ := http.NewRequest("GET", "/producers", nil)
 	rr := httptest.NewRecorder()
 	testRouter.ServeHTTP(rr, req)
@@ -106,18 +113,18 @@ func TestProducersControllerGet_Error(t *testing.T) {
 }
 
 func TestProducersFormatAsRelativeLink(t *testing.T) {
-	listController := NewProducersController(producerRepo, NewProducerController(producerRepo))
+	listController := NewProducersController(producerRepo, NewProducerController(producerRepo, quotaRepo, configuration, nil, configuration))
 	assert.Equal(t, "/producers", listController.FormatAsRelativeLink())
 }
 
 func TestProducerControllerFormatAsRelativeLink_NoParam(t *testing.T) {
-	assert.Equal(t, "/producer/:producerId", NewProducerController(producerRepo).FormatAsRelativeLink())
+	assert.Equal(t, "/producer/:producerId", NewProducerController(producerRepo, quotaRepo, configuration, nil, configuration).FormatAsRelativeLink())
 }
 
 func TestProducerGet(t *testing.T) {
 	t.Run("SuccessfulGet", func(t *testing.T) {
 		t.Parallel()
-		testRouter := createTestRouter(NewProducerController(producerRepo))
+		testRouter := createTestRouter(NewProducerController(producerRepo, quotaRepo, configuration, nil, configuration))
 		req, _ := http.NewRequest("GET", "/producer/"+listTestProducerIDPrefix+"0", nil)
 		rr := httptest.NewRecorder()
 		testRouter.ServeHTTP(rr, req)
@@ -132,7 +139,7 @@ func TestProducerGet(t *testing.T) {
 	})
 	t.Run("NotFound", func(t *testing.T) {
 		t.Parallel()
-		testRouter := createTestRouter(NewProducerController(producerRepo))
+		testRouter := createTestRouter(NewProducerController(producerRepo, quotaRepo, configuration, nil, configuration))
 		req, _ := http.NewRequest("GET", "/producer/"+time.Now().String(), nil)
 		rr := httptest.NewRecorder()
 		testRouter.ServeHTTP(rr, req)
@@ -143,7 +150,7 @@ func TestProducerGet(t *testing.T) {
 func TestProducerPut(t *testing.T) {
 	t.Run("SuccessfulPutCreateWithNameToken", func(t *testing.T) {
 		t.Parallel()
-		testRouter := createTestRouter(NewProducerController(producerRepo))
+		testRouter := createTestRouter(NewProducerController(producerRepo, quotaRepo, configuration, nil, configuration))
 		req, _ := http.NewRequest("PUT", "/producer/"+createProducerIDWithData, nil)
 		req.Header.Add(headerContentType, formDataContentTypeHeaderValue)
 		req.PostForm = url.Values{}
@@ -160,7 +167,7 @@ func TestProducerPut(t *testing.T) {
 	})
 	t.Run("SuccessfulPutCreateWithoutNameToken", func(t *testing.T) {
 		t.Parallel()
-		testRouter := createTestRouter(NewProducerController(producerRepo))
+		testRouter := createTestRouter(NewProducerController(producerRepo, quotaRepo, configuration, nil, configuration))
 		req, _ := http.NewRequest("PUT", "/producer/"+createProducerIDWithoutData, nil)
 		req.Header.Add(headerContentType, formDataContentTypeHeaderValue)
 		rr := httptest.NewRecorder()
@@ -174,7 +181,7 @@ func TestProducerPut(t *testing.T) {
 	})
 	t.Run("SuccessfulPutUpdate", func(t *testing.T) {
 		t.Parallel()
-		testRouter := createTestRouter(NewProducerController(producerRepo))
+		testRouter := createTestRouter(NewProducerController(producerRepo, quotaRepo, configuration, nil, configuration))
 		greq, _ := http.NewRequest("GET", "/producer/"+listTestProducerIDPrefix+"0", nil)
 		grr := httptest.NewRecorder()
 		testRouter.ServeHTTP(grr, greq)
@@ -194,9 +201,43 @@ func TestProducerPut(t *testing.T) {
 		assert.Contains(t, updatedBodyProducer.Token, "Updated")
 		assert.True(t, bodyProducer.ChangedAt.Before(updatedBodyProducer.ChangedAt))
 	})
+	t.Run("SuccessfulPutCreateWithReceiptURL", func(t *testing.T) {
+		t.Parallel()
+		testRouter := createTestRouter(NewProducerController(producerRepo, quotaRepo, configuration, nil, configuration))
+		req, _ := http.NewRequest("PUT", "/producer/"+createProducerIDWithReceipt, nil)
+		req.Header.Add(headerContentType, formDataContentTypeHeaderValue)
+		req.PostForm = url.Values{}
+		req.PostForm.Add(receiptURLParamName, "https://imytech.net/receipts")
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		bodyProducer := &ProducerModel{}
+		json.NewDecoder(strings.NewReader(rr.Body.String())).Decode(bodyProducer)
+		assert.Equal(t, createProducerIDWithReceipt, bodyProducer.ID)
+		assert.Equal(t, "https://imytech.net/receipts", bodyProducer.ReceiptURL)
+		greq, _ := http.NewRequest("GET", "/producer/"+createProducerIDWithReceipt, nil)
+		grr := httptest.NewRecorder()
+		testRouter.ServeHTTP(grr, greq)
+		assert.Equal(t, http.StatusOK, grr.Code)
+		bodyProducer = &ProducerModel{}
+		json.NewDecoder(strings.NewReader(grr.Body.String())).Decode(bodyProducer)
+		assert.Equal(t, "https://imytech.net/receipts", bodyProducer.ReceiptURL)
+	})
+	t.Run("400:ReceiptURL", func(t *testing.T) {
+		t.Parallel()
+		testRouter := createTestRouter(NewProducerController(producerRepo, quotaRepo, configuration, nil, configuration))
+		req, _ := http.NewRequest("PUT", "/producer/"+createProducerIDWithReceipt+"-invalid", nil)
+		req.Header.Add(headerContentType, formDataContentTypeHeaderValue)
+		req.PostForm = url.Values{}
+		req.PostForm.Add(receiptURLParamName, "receipts")
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, ErrBadRequestForReceiptURL.Error(), rr.Body.String())
+	})
 	t.Run("415", func(t *testing.T) {
 		t.Parallel()
-		testRouter := createTestRouter(NewProducerController(producerRepo))
+		testRouter := createTestRouter(NewProducerController(producerRepo, quotaRepo, configuration, nil, configuration))
 		req, _ := http.NewRequest("PUT", "/producer/"+listTestProducerIDPrefix+"0", nil)
 		rr := httptest.NewRecorder()
 		testRouter.ServeHTTP(rr, req)
@@ -204,7 +245,7 @@ func TestProducerPut(t *testing.T) {
 	})
 	t.Run("400", func(t *testing.T) {
 		t.Parallel()
-		testRouter := createTestRouter(NewProducerController(producerRepo))
+		testRouter := createTestRouter(NewProducerController(producerRepo, quotaRepo, configuration, nil, configuration))
 		req, _ := http.NewRequest("PUT", "/producer/"+listTestProducerIDPrefix+"0", nil)
 		req.Header.Add(headerContentType, formDataContentTypeHeaderValue)
 		rr := httptest.NewRecorder()
@@ -213,7 +254,7 @@ func TestProducerPut(t *testing.T) {
 	})
 	t.Run("412", func(t *testing.T) {
 		t.Parallel()
-		testRouter := createTestRouter(NewProducerController(producerRepo))
+		testRouter := createTestRouter(NewProducerController(producerRepo, quotaRepo, configuration, nil, configuration))
 		req, _ := http.NewRequest("PUT", "/producer/"+listTestProducerIDPrefix+"0", nil)
 		req.Header.Add(headerContentType, formDataContentTypeHeaderValue)
 		req.Header.Add(headerUnmodifiedSince, time.Now().Add(-1*time.Duration(10)*time.Hour).Format(http.TimeFormat))
@@ -227,7 +268,7 @@ func TestProducerPut(t *testing.T) {
 		expectedErr := errors.New("error")
 		mockProducerRepo.On("Get", mock.Anything).Return(&data.Producer{}, expectedErr)
 		mockProducerRepo.On("Store", mock.Anything).Return(&data.Producer{}, expectedErr)
-		testRouter := createTestRouter(NewProducerController(mockProducerRepo))
+		testRouter := createTestRouter(NewProducerController(mockProducerRepo, quotaRepo, configuration, nil, configuration))
 		req, _ := http.NewRequest("PUT", "/producer/"+listTestProducerIDPrefix+"0", nil)
 		req.Header.Add(headerContentType, formDataContentTypeHeaderValue)
 		rr := httptest.NewRecorder()
@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/newscred/webhook-broker/storage"
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+const (
+	publishGrantPath = channelPath + "/producers/:" + producerIDPathParamKey
+)
+
+// PublishGrantModel represents the grant of a producer to publish to a channel
+type PublishGrantModel struct {
+	ChannelID   string
+	ProducerID  string
+	ChannelURL  string
+	ProducerURL string
+	GrantedAt   time.Time
+}
+
+// PublishGrantController represents the endpoint for granting producers to publish to a channel
+type PublishGrantController struct {
+	ChannelRepo      storage.ChannelRepository
+	ProducerRepo     storage.ProducerRepository
+	GrantRepo        storage.PublishGrantRepository
+	ChannelEndpoint  EndpointController
+	ProducerEndpoint EndpointController
+}
+
+// NewPublishGrantController creates and returns a new instance of PublishGrantController
+func NewPublishGrantController(channelRepo storage.ChannelRepository, producerRepo storage.ProducerRepository, grantRepo storage.PublishGrantRepository,
+	channelController *ChannelController, producerController *ProducerController) *PublishGrantController {
+	return &PublishGrantController{ChannelRepo: channelRepo, ProducerRepo: producerRepo, GrantRepo: grantRepo, ChannelEndpoint: channelController,
+		ProducerEndpoint: producerController}
+}
+
+func (controller *PublishGrantController) getChannelAndProducer(params httprouter.Params) (*data.Channel, *data.Producer, error) {
+	channel, err := controller.ChannelRepo.Get(findParam(params, channelIDPathParamKey))
+	if err != nil {
+		return nil, nil, err
+	}
+	producer, err := controller.ProducerRepo.Get(findParam(params, producerIDPathParamKey))
+	return channel, producer, err
+}
+
+func (controller *PublishGrantController) newPublishGrantModel(grant *data.PublishGrant) *PublishGrantModel {
+	return &PublishGrantModel{ChannelID: grant.Channel.ChannelID, ProducerID: grant.Producer.ProducerID, GrantedAt: grant.CreatedAt,
+		ChannelURL:  controller.ChannelEndpoint.FormatAsRelativeLink(httprouter.Param{Key: channelIDPathParamKey, Value: grant.Channel.ChannelID}),
+		ProducerURL: controller.ProducerEndpoint.FormatAsRelativeLink(httprouter.Param{Key: producerIDPathParamKey, Value: grant.Producer.ProducerID})}
+}
+
+// Get implements the GET /channel/:channelId/producers/:producerId endpoint; not found if the producer is not granted to publish to the channel
+func (controller *PublishGrantController) Get(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	channel, producer, err := controller.getChannelAndProducer(params)
+	var grant *data.PublishGrant
+	if err == nil {
+		grant, err = controller.GrantRepo.Get(channel, producer)
+	}
+	switch err {
+	case nil:
+		writeJSON(w, controller.newPublishGrantModel(grant))
+	case sql.ErrNoRows:
+		writeNotFound(w)
+	default:
+		writeErr(w, err)
+	}
+}
+
+// Put implements the PUT /channel/:channelId/producers/:producerId endpoint; granting a producer already granted is a no-op
+func (controller *PublishGrantController) Put(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	channel, producer, err := controller.getChannelAndProducer(params)
+	var grant *data.PublishGrant
+	if err == nil {
+		grant, err = controller.GrantRepo.Grant(channel, producer)
+	}
+	switch err {
+	case nil:
+		writeJSON(w, controller.newPublishGrantModel(grant))
+	case sql.ErrNoRows:
+		writeNotFound(w)
+	default:
+		writeErr(w, err)
+	}
+}
+
+// Delete implements the DELETE /channel/:channelId/producers/:producerId endpoint; the producer can no longer publish to the channel
+func (controller *PublishGrantController) Delete(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	channel, producer, err := controller.getChannelAndProducer(params)
+	var grant *data.PublishGrant
+	if err == nil {
+		grant, err = controller.GrantRepo.Get(channel, producer)
+	}
+	if err == nil {
+		err = controller.GrantRepo.Revoke(grant)
+	}
+	switch err {
+	case nil:
+		writeStatus(w, http.StatusNoContent, nil)
+	case sql.ErrNoRows, storage.ErrNoRowsUpdated:
+		writeNotFound(w)
+	default:
+		writeErr(w, err)
+	}
+}
+
+// GetPath returns the endpoint's path
+func (controller *PublishGrantController) GetPath() string {
+	return publishGrantPath
+}
+
+// FormatAsRelativeLink Format as relative URL of this resource based on the params
+func (controller *PublishGrantController) FormatAsRelativeLink(params ...httprouter.Param) string {
+	return formatURL(params, publishGrantPath, channelIDPathParamKey, producerIDPathParamKey)
+}
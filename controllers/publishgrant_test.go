@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/newscred/webhook-broker/storage/data"
+	storagemocks "github.com/newscred/webhook-broker/storage/mocks"
+)
+
+func getNewPublishGrantController() *PublishGrantController {
+	return NewPublishGrantController(channelRepo, producerRepo, grantRepo, getNewChannelController(channelRepo), NewProducerController(producerRepo, quotaRepo, configuration, nil, configuration))
+}
+
+func getPublishGrantTestRequest(method, path string) *http.Request {
+	req, _ := http.NewRequest(method, path, nil)
+	return req
+}
+
+func TestPublishGrantController(t *testing.T) {
+	channel, _ := data.NewChannel("channel-for-grants", consumerTestChannel.Token)
+	channel, err := channelRepo.Store(channel)
+	assert.Nil(t, err)
+	producer, _ := data.NewProducer("producer-for-grants", "producer-for-grants-token")
+	producer, err = producerRepo.Store(producer)
+	assert.Nil(t, err)
+	controller := getNewPublishGrantController()
+	testRouter := createTestRouter(controller)
+	grantURL := controller.FormatAsRelativeLink(getRouterParam(channel.ChannelID), httprouter.Param{Key: producerIDPathParamKey, Value: producer.ProducerID})
+	assert.Equal(t, "/channel/channel-for-grants/producers/producer-for-grants", grantURL)
+	assert.Equal(t, publishGrantPath, controller.GetPath())
+	t.Run("404", func(t *testing.T) {
+		for _, path := range []string{"/channel/no-such-channel/producers/producer-for-grants", "/channel/channel-for-grants/producers/no-such-producer"} {
+			for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+				rr := httptest.NewRecorder()
+				testRouter.ServeHTTP(rr, getPublishGrantTestRequest(method, path))
+				assert.Equal(t, http.StatusNotFound, rr.Code, method+" "+path)
+			}
+		}
+	})
+	t.Run("GrantAndRevoke", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getPublishGrantTestRequest(http.MethodGet, grantURL))
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		broadcastController, mockDispatcher := getNewBroadcastController(messageRepo)
+		mockDispatcher.On("Dispatch", mock.Anything).Return()
+		broadcastRouter := createTestRouter(broadcastController)
+		broadcast := func() *httptest.ResponseRecorder {
+			rr := httptest.NewRecorder()
+			broadcastRouter.ServeHTTP(rr, setSchemaTestProducer(getPayloadLimitTestRequest(broadcastController.FormatAsRelativeLink(getRouterParam(channel.ChannelID)),
+				channel, "granted message"), producer))
+			return rr
+		}
+		rr = broadcast()
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, errProducerNotGranted.Error(), rr.Body.String())
+
+		for index := 0; index < 2; index++ {
+			rr = httptest.NewRecorder()
+			testRouter.ServeHTTP(rr, getPublishGrantTestRequest(http.MethodPut, grantURL))
+			assert.Equal(t, http.StatusOK, rr.Code)
+			grant := &PublishGrantModel{}
+			assert.Nil(t, json.NewDecoder(rr.Body).Decode(grant))
+			assert.Equal(t, channel.ChannelID, grant.ChannelID)
+			assert.Equal(t, producer.ProducerID, grant.ProducerID)
+			assert.Equal(t, "/channel/channel-for-grants", grant.ChannelURL)
+			assert.Equal(t, "/producer/producer-for-grants", grant.ProducerURL)
+			assert.False(t, grant.GrantedAt.IsZero())
+		}
+		rr = httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getPublishGrantTestRequest(http.MethodGet, grantURL))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, http.StatusAccepted, broadcast().Code)
+
+		rr = httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getPublishGrantTestRequest(http.MethodDelete, grantURL))
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+		rr = httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getPublishGrantTestRequest(http.MethodDelete, grantURL))
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, http.StatusForbidden, broadcast().Code)
+	})
+	t.Run("NotEnforced", func(t *testing.T) {
+		permissiveConfig := *configuration
+		permissiveConfig.PublishGrantEnforced = false
+		broadcastController, mockDispatcher := getNewBroadcastController(messageRepo)
+		broadcastController.BrokerConfig = &permissiveConfig
+		mockDispatcher.On("Dispatch", mock.Anything).Return()
+		_, err := grantRepo.Get(channel, producer)
+		assert.NotNil(t, err)
+		rr := httptest.NewRecorder()
+		createTestRouter(broadcastController).ServeHTTP(rr, setSchemaTestProducer(getPayloadLimitTestRequest(broadcastController.FormatAsRelativeLink(getRouterParam(channel.ChannelID)),
+			channel, "ungranted message"), producer))
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+	})
+	t.Run("500", func(t *testing.T) {
+		mockGrantRepo := new(storagemocks.PublishGrantRepository)
+		expectedErr := errors.New("grant error")
+		mockGrantRepo.On("Get", mock.Anything, mock.Anything).Return(nil, expectedErr)
+		mockGrantRepo.On("Grant", mock.Anything, mock.Anything).Return(nil, expectedErr)
+		errController := getNewPublishGrantController()
+		errController.GrantRepo = mockGrantRepo
+		errRouter := createTestRouter(errController)
+		for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+			rr := httptest.NewRecorder()
+			errRouter.ServeHTTP(rr, getPublishGrantTestRequest(method, grantURL))
+			assert.Equal(t, http.StatusInternalServerError, rr.Code, method)
+		}
+		broadcastController, _ := getNewBroadcastController(new(storagemocks.MessageRepository))
+		broadcastController.GrantRepository = mockGrantRepo
+		rr := httptest.NewRecorder()
+		createTestRouter(broadcastController).ServeHTTP(rr, setSchemaTestProducer(getPayloadLimitTestRequest(
+			broadcastController.FormatAsRelativeLink(getRouterParam(channel.ChannelID)), channel, "failing"), producer))
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+}
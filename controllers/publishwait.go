@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/hlog"
+
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+const (
+	headerPrefer            = "Prefer"
+	headerPreferenceApplied = "Preference-Applied"
+	preferWaitPrefix        = "wait="
+	publishWaitPollInterval = 250 * time.Millisecond
+	// DeliveryOutcomeDelivered is the outcome of a job whose consumer received the message
+	DeliveryOutcomeDelivered = "DELIVERED"
+	// DeliveryOutcomeFailed is the outcome of a job that failed at least once and is being retried
+	DeliveryOutcomeFailed = "FAILED"
+	// DeliveryOutcomeDead is the outcome of a job that exhausted its retries or was discarded from the DLQ
+	DeliveryOutcomeDead = "DEAD"
+	// DeliveryOutcomePending is the outcome of a job whose first attempt has not completed yet
+	DeliveryOutcomePending = "PENDING"
+)
+
+// DeliveryOutcomeModel represents the outcome of delivering the message to a consumer
+type DeliveryOutcomeModel struct {
+	ConsumerID    string
+	JobID         string
+	Outcome       string
+	Status        string
+	Attempts      uint
+	FailureReason string `json:",omitempty"`
+}
+
+// PublishOutcomeModel represents the delivery outcome of a message broadcasted with `Prefer: wait=<seconds>`; Completed is false when the wait
+// timed out before every delivery had an outcome
+type PublishOutcomeModel struct {
+	MessageID  string
+	Completed  bool
+	Deliveries []*DeliveryOutcomeModel
+}
+
+func newDeliveryOutcomeModel(job *data.DeliveryJob) *DeliveryOutcomeModel {
+	outcome := &DeliveryOutcomeModel{JobID: job.ID.String(), Status: job.Status.String(), Attempts: job.RetryAttemptCount, FailureReason: job.FailureReason}
+	if job.Listener != nil {
+		outcome.ConsumerID = job.Listener.ConsumerID
+	}
+	switch {
+	case job.Status == data.JobDelivered:
+		outcome.Outcome = DeliveryOutcomeDelivered
+		outcome.Attempts++
+	case job.Status == data.JobDead || job.Status == data.JobDiscarded || job.Status == data.JobCancelled:
+		outcome.Outcome = DeliveryOutcomeDead
+	case job.RetryAttemptCount > 0:
+		outcome.Outcome = DeliveryOutcomeFailed
+	default:
+		outcome.Outcome = DeliveryOutcomePending
+	}
+	return outcome
+}
+
+// getPreferredWait returns the wait requested with `Prefer: wait=<seconds>` header capped to the configured maximum; ok is false when no wait
+// was requested or synchronous publish is disabled
+func (broadcastController *BroadcastController) getPreferredWait(r *http.Request) (wait time.Duration, ok bool) {
+	maxWait := broadcastController.BrokerConfig.GetMaxPublishWait()
+	for _, headerValue := range r.Header.Values(headerPrefer) {
+		for _, preference := range strings.Split(headerValue, ",") {
+			preference = strings.TrimSpace(strings.SplitN(preference, ";", 2)[0])
+			if len(preference) <= len(preferWaitPrefix) || !strings.EqualFold(preference[:len(preferWaitPrefix)], preferWaitPrefix) {
+				continue
+			}
+			if seconds, err := strconv.ParseUint(strings.TrimSpace(preference[len(preferWaitPrefix):]), 10, 32); err == nil && seconds > 0 {
+				wait, ok = time.Duration(seconds)*time.Second, true
+			}
+		}
+	}
+	if wait > maxWait {
+		wait = maxWait
+	}
+	return wait, ok && wait > 0
+}
+
+// writePublishOutcome waits for the delivery outcome of the message and writes it; 200 if every delivery has an outcome else 202 once the wait
+// is over. Outcome is read from the DB as the message may be delivered by workers of other brokers
+func (broadcastController *BroadcastController) writePublishOutcome(w http.ResponseWriter, r *http.Request, message *data.Message, wait time.Duration) {
+	outcome, err := broadcastController.waitForPublishOutcome(r.Context(), message, wait)
+	if err != nil {
+		hlog.FromRequest(r).Error().Err(err).Str(messageIDLogFieldKey, message.ID.String()).Msg("error retrieving delivery outcome of message")
+	}
+	w.Header().Set(headerPreferenceApplied, preferWaitPrefix+strconv.Itoa(int(wait/time.Second)))
+	status := http.StatusAccepted
+	if outcome.Completed {
+		status = http.StatusOK
+	}
+	writeJSONWithStatus(w, status, outcome)
+}
+
+func (broadcastController *BroadcastController) waitForPublishOutcome(ctx context.Context, message *data.Message, wait time.Duration) (*PublishOutcomeModel, error) {
+	timeout := time.NewTimer(wait)
+	defer timeout.Stop()
+	ticker := time.NewTicker(publishWaitPollInterval)
+	defer ticker.Stop()
+	for {
+		outcome, err := broadcastController.getPublishOutcome(message)
+		if err != nil || outcome.Completed {
+			return outcome, err
+		}
+		select {
+		case <-ctx.Done():
+			return outcome, nil
+		case <-timeout.C:
+			return broadcastController.getPublishOutcome(message)
+		case <-ticker.C:
+		}
+	}
+}
+
+// getPublishOutcome retrieves the outcome of all the jobs of the message; it is not complete until the message is dispatched and every job has
+// an outcome other than pending
+func (broadcastController *BroadcastController) getPublishOutcome(message *data.Message) (*PublishOutcomeModel, error) {
+	outcome := &PublishOutcomeModel{MessageID: message.MessageID, Deliveries: make([]*DeliveryOutcomeModel, 0)}
+	storedMessage, err := broadcastController.MessageRepository.GetByID(message.ID.String())
+	if err != nil || storedMessage.Status != data.MsgStatusDispatched {
+		return outcome, err
+	}
+	completed := true
+	page := data.NewPagination(nil, nil)
+	jobs, resultPagination, err := broadcastController.DeliveryJobRepository.GetJobsForMessage(storedMessage, page)
+	for err == nil && len(jobs) > 0 {
+		for _, job := range jobs {
+			delivery := newDeliveryOutcomeModel(job)
+			completed = completed && delivery.Outcome != DeliveryOutcomePending
+			outcome.Deliveries = append(outcome.Deliveries, delivery)
+		}
+		page.Next = resultPagination.Next
+		jobs, resultPagination, err = broadcastController.DeliveryJobRepository.GetJobsForMessage(storedMessage, page)
+	}
+	outcome.Completed = err == nil && completed
+	return outcome, err
+}
@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	dispatchermocks "github.com/newscred/webhook-broker/dispatcher/mocks"
+	"github.com/newscred/webhook-broker/storage/data"
+	storagemocks "github.com/newscred/webhook-broker/storage/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func getNewPublishWaitController(msgRepo *storagemocks.MessageRepository, djRepo *storagemocks.DeliveryJobRepository) (*BroadcastController, *dispatchermocks.MessageDispatcher) {
+	mockDispatcher := new(dispatchermocks.MessageDispatcher)
+	mockDispatcher.On("Dispatch", mock.Anything).Return()
+	return NewBroadcastController(channelRepo, msgRepo, producerRepo, djRepo, schemaRepo, quotaRepo, grantRepo, mockDispatcher, nil, configuration, configuration, configuration), mockDispatcher
+}
+
+func getPublishWaitTestJob(message *data.Message, consumerID string, status data.JobStatus, retryAttemptCount uint) *data.DeliveryJob {
+	callbackURL, _ := url.Parse("https://imytech.net/")
+	consumer, _ := data.NewConsumer(consumerTestChannel, consumerID, successfulGetTestToken, callbackURL)
+	job, _ := data.NewDeliveryJob(message, consumer)
+	job.Status = status
+	job.RetryAttemptCount = retryAttemptCount
+	return job
+}
+
+// getPublishWaitTestRequest broadcasts as a producer whose token is not updated by producer tests, as they run before these
+func getPublishWaitTestRequest(controller *BroadcastController) *http.Request {
+	req := getPayloadLimitTestRequest(controller.FormatAsRelativeLink(getRouterParam(consumerTestChannel.ChannelID)), consumerTestChannel, "waited message")
+	req.Header.Set(headerProducerID, listTestProducerIDPrefix+"1")
+	req.Header.Set(headerProducerToken, successfulGetTestToken+" - 1")
+	return req
+}
+
+// getNextPagePagination always points to a next page as GetJobsForMessage mock return value
+func getNextPagePagination(message *data.Message, page *data.Pagination) *data.Pagination {
+	return &data.Pagination{Next: &data.Cursor{ID: "next", Timestamp: time.Now()}}
+}
+
+func getPublishOutcome(t *testing.T, rr *httptest.ResponseRecorder) *PublishOutcomeModel {
+	outcome := &PublishOutcomeModel{}
+	assert.Nil(t, json.NewDecoder(rr.Body).Decode(outcome))
+	return outcome
+}
+
+func TestBroadcastControllerPublishWait(t *testing.T) {
+	t.Run("200", func(t *testing.T) {
+		t.Parallel()
+		msgRepo := new(storagemocks.MessageRepository)
+		djRepo := new(storagemocks.DeliveryJobRepository)
+		controller, _ := getNewPublishWaitController(msgRepo, djRepo)
+		testRouter := createTestRouter(controller)
+		req := getPublishWaitTestRequest(controller)
+		req.Header.Add(headerPrefer, "respond-async, wait=5")
+		var message *data.Message
+		msgRepo.On("Create", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			message = args.Get(0).(*data.Message)
+		})
+		msgRepo.On("GetByID", mock.Anything).Return(func(id string) *data.Message {
+			return message
+		}, nil).Once()
+		msgRepo.On("GetByID", mock.Anything).Return(func(id string) *data.Message {
+			dispatched := *message
+			dispatched.Status = data.MsgStatusDispatched
+			return &dispatched
+		}, nil)
+		djRepo.On("GetJobsForMessage", mock.Anything, mock.Anything).Return(getPublishWaitTestJobs, getNextPagePagination, nil)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "wait=5", rr.Header().Get(headerPreferenceApplied))
+		outcome := getPublishOutcome(t, rr)
+		assert.Equal(t, message.MessageID, outcome.MessageID)
+		assert.True(t, outcome.Completed)
+		assert.Equal(t, 3, len(outcome.Deliveries))
+		assert.Equal(t, []string{DeliveryOutcomeDelivered, DeliveryOutcomeFailed, DeliveryOutcomeDead},
+			[]string{outcome.Deliveries[0].Outcome, outcome.Deliveries[1].Outcome, outcome.Deliveries[2].Outcome})
+		assert.Equal(t, "publish-wait-delivered", outcome.Deliveries[0].ConsumerID)
+		assert.Equal(t, uint(1), outcome.Deliveries[0].Attempts)
+		assert.Equal(t, data.JobQueuedStr, outcome.Deliveries[1].Status)
+		msgRepo.AssertExpectations(t)
+		djRepo.AssertExpectations(t)
+	})
+	t.Run("202", func(t *testing.T) {
+		t.Parallel()
+		msgRepo := new(storagemocks.MessageRepository)
+		djRepo := new(storagemocks.DeliveryJobRepository)
+		controller, _ := getNewPublishWaitController(msgRepo, djRepo)
+		testRouter := createTestRouter(controller)
+		req := getPublishWaitTestRequest(controller)
+		req.Header.Add(headerPrefer, "wait=1")
+		var message *data.Message
+		msgRepo.On("Create", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			message = args.Get(0).(*data.Message)
+			message.Status = data.MsgStatusDispatched
+		})
+		msgRepo.On("GetByID", mock.Anything).Return(func(id string) *data.Message {
+			return message
+		}, nil)
+		djRepo.On("GetJobsForMessage", mock.Anything, mock.Anything).Return(func(msg *data.Message, page *data.Pagination) []*data.DeliveryJob {
+			if page.Next == nil {
+				return []*data.DeliveryJob{getPublishWaitTestJob(msg, "publish-wait-pending", data.JobInflight, 0)}
+			}
+			return []*data.DeliveryJob{}
+		}, getNextPagePagination, nil)
+		start := time.Now()
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		assert.True(t, time.Since(start) >= time.Second)
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		assert.Equal(t, "wait=1", rr.Header().Get(headerPreferenceApplied))
+		outcome := getPublishOutcome(t, rr)
+		assert.False(t, outcome.Completed)
+		assert.Equal(t, 1, len(outcome.Deliveries))
+		assert.Equal(t, DeliveryOutcomePending, outcome.Deliveries[0].Outcome)
+		assert.Equal(t, data.JobInflightStr, outcome.Deliveries[0].Status)
+	})
+	t.Run("202:Error", func(t *testing.T) {
+		t.Parallel()
+		msgRepo := new(storagemocks.MessageRepository)
+		djRepo := new(storagemocks.DeliveryJobRepository)
+		controller, _ := getNewPublishWaitController(msgRepo, djRepo)
+		testRouter := createTestRouter(controller)
+		req := getPublishWaitTestRequest(controller)
+		req.Header.Add(headerPrefer, "wait=3")
+		msgRepo.On("Create", mock.Anything).Return(nil)
+		msgRepo.On("GetByID", mock.Anything).Return(nil, errExpected)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		outcome := getPublishOutcome(t, rr)
+		assert.False(t, outcome.Completed)
+		assert.Equal(t, 0, len(outcome.Deliveries))
+		djRepo.AssertExpectations(t)
+	})
+}
+
+func getPublishWaitTestJobs(message *data.Message, page *data.Pagination) []*data.DeliveryJob {
+	if page.Next != nil {
+		return []*data.DeliveryJob{}
+	}
+	failed := getPublishWaitTestJob(message, "publish-wait-failed", data.JobQueued, 1)
+	failed.FailureReason = "503 Service Unavailable"
+	return []*data.DeliveryJob{getPublishWaitTestJob(message, "publish-wait-delivered", data.JobDelivered, 0), failed,
+		getPublishWaitTestJob(message, "publish-wait-dead", data.JobDead, 5)}
+}
+
+func TestGetPreferredWait(t *testing.T) {
+	controller := NewBroadcastController(channelRepo, messageRepo, producerRepo, djRepo, schemaRepo, quotaRepo, grantRepo, nil, nil, configuration, configuration, configuration)
+	preferences := map[string]time.Duration{"wait=10": 10 * time.Second, "respond-async, Wait=2": 2 * time.Second, "wait=3; foo=bar": 3 * time.Second,
+		"wait=3600": configuration.GetMaxPublishWait()}
+	for preference, expected := range preferences {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Add(headerPrefer, preference)
+		wait, ok := controller.getPreferredWait(req)
+		assert.True(t, ok, preference)
+		assert.Equal(t, expected, wait, preference)
+	}
+	for _, preference := range []string{"", "respond-async", "wait=", "wait=abc", "wait=0", "wait=-5", "waiting=5"} {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Add(headerPrefer, preference)
+		_, ok := controller.getPreferredWait(req)
+		assert.False(t, ok, preference)
+	}
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/newscred/webhook-broker/config"
+	"github.com/newscred/webhook-broker/publish"
+	"github.com/newscred/webhook-broker/storage"
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+const (
+	headerRetryAfter               = "Retry-After"
+	messagesPerSecondFormParamName = "messagesPerSecond"
+	burstFormParamName             = "burst"
+	bytesPerMinuteFormParamName    = "bytesPerMinute"
+)
+
+var (
+	errRateLimitExceeded = publish.ErrRateLimitExceeded
+)
+
+// RateLimitModel is the publish rate limit in effect for a producer or a channel, the one set on it else the broker-wide default, along with
+// its current usage; a zero limit means the rate is not limited by it
+type RateLimitModel struct {
+	MessagesPerSecond uint
+	Burst             uint
+	BytesPerMinute    uint
+	// RemainingMessages is the number of messages that can be published right away when messages per second is limited
+	RemainingMessages uint
+	// RemainingBytes is the number of payload bytes that can be published right away when bytes per minute is limited; negative when a
+	// message larger than what was remaining was published
+	RemainingBytes int64
+}
+
+func getRateLimitModel(quotaRepo storage.PublishQuotaRepository, subjectType data.QuotaSubjectType, subjectID string, defaultLimit config.PublishRateLimit) (*RateLimitModel, error) {
+	quota, err := quotaRepo.Get(subjectType, subjectID)
+	if err != nil {
+		return nil, err
+	}
+	usage := quota.GetUsage(defaultLimit, time.Now())
+	return &RateLimitModel{MessagesPerSecond: usage.Limit.MessagesPerSecond, Burst: usage.Limit.Burst, BytesPerMinute: usage.Limit.BytesPerMinute,
+		RemainingMessages: usage.RemainingMessages, RemainingBytes: usage.RemainingBytes}, nil
+}
+
+// getRateLimitFromForm reads the rate limit to be set on a producer or a channel; a param not sent means the broker-wide default applies
+func getRateLimitFromForm(r *http.Request) (limit config.PublishRateLimit, err error) {
+	params := []struct {
+		name  string
+		value *uint
+	}{{messagesPerSecondFormParamName, &limit.MessagesPerSecond}, {burstFormParamName, &limit.Burst}, {bytesPerMinuteFormParamName, &limit.BytesPerMinute}}
+	for _, param := range params {
+		if valueString := r.PostFormValue(param.name); len(valueString) > 0 {
+			value, parseErr := strconv.ParseUint(valueString, 10, 0)
+			if parseErr != nil {
+				return limit, ErrBadRequestForRateLimit
+			}
+			*param.value = uint(value)
+		}
+	}
+	return limit, nil
+}
+
+func setRetryAfter(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set(headerRetryAfter, strconv.Itoa(int(math.Max(math.Ceil(wait.Seconds()), 1))))
+}
+
+func writeTooManyRequests(w http.ResponseWriter, wait time.Duration) {
+	setRetryAfter(w, wait)
+	writeStatus(w, http.StatusTooManyRequests, errRateLimitExceeded)
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	reader io.Reader
+	count  uint
+}
+
+func (counter *countingReader) Read(p []byte) (n int, err error) {
+	n, err = counter.reader.Read(p)
+	counter.count += uint(n)
+	return n, err
+}
@@ -0,0 +1,189 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/newscred/webhook-broker/config"
+	"github.com/newscred/webhook-broker/storage"
+	"github.com/newscred/webhook-broker/storage/data"
+	storagemocks "github.com/newscred/webhook-broker/storage/mocks"
+)
+
+func getRateLimitTestPutRequest(path string, values url.Values) *http.Request {
+	req, _ := http.NewRequest("PUT", path, nil)
+	req.Header.Add(headerContentType, formDataContentTypeHeaderValue)
+	req.PostForm = values
+	return req
+}
+
+func TestProducerControllerRateLimit(t *testing.T) {
+	testRouter := createTestRouter(NewProducerController(producerRepo, quotaRepo, configuration, nil, configuration))
+	t.Run("Put:200", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getRateLimitTestPutRequest("/producer/producer-for-rate-limit", url.Values{messagesPerSecondFormParamName: {"5"},
+			bytesPerMinuteFormParamName: {"1024"}}))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		producer := &ProducerModel{}
+		assert.Nil(t, json.NewDecoder(rr.Body).Decode(producer))
+		assert.Equal(t, &RateLimitModel{MessagesPerSecond: 5, BytesPerMinute: 1024, RemainingMessages: 5, RemainingBytes: 1024}, producer.RateLimit)
+
+		rr = httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/producer/producer-for-rate-limit", nil)
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		producer = &ProducerModel{}
+		assert.Nil(t, json.NewDecoder(rr.Body).Decode(producer))
+		assert.Equal(t, uint(5), producer.RateLimit.MessagesPerSecond)
+		assert.Equal(t, uint(1024), producer.RateLimit.BytesPerMinute)
+	})
+	t.Run("Put:400", func(t *testing.T) {
+		for _, param := range []string{messagesPerSecondFormParamName, burstFormParamName, bytesPerMinuteFormParamName} {
+			rr := httptest.NewRecorder()
+			testRouter.ServeHTTP(rr, getRateLimitTestPutRequest("/producer/producer-for-rate-limit-400", url.Values{param: {"-1"}}))
+			assert.Equal(t, http.StatusBadRequest, rr.Code, param)
+			assert.Equal(t, ErrBadRequestForRateLimit.Error(), rr.Body.String())
+		}
+	})
+	t.Run("Get:Default", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/producer/"+listTestProducerIDPrefix+"1", nil)
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		producer := &ProducerModel{}
+		assert.Nil(t, json.NewDecoder(rr.Body).Decode(producer))
+		assert.Equal(t, &RateLimitModel{}, producer.RateLimit)
+	})
+	t.Run("Get:500", func(t *testing.T) {
+		mockQuotaRepo := new(storagemocks.PublishQuotaRepository)
+		mockQuotaRepo.On("Get", data.ProducerQuota, listTestProducerIDPrefix+"1").Return(nil, errors.New("quota error"))
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/producer/"+listTestProducerIDPrefix+"1", nil)
+		createTestRouter(NewProducerController(producerRepo, mockQuotaRepo, configuration, nil, configuration)).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+}
+
+func TestChannelControllerRateLimit(t *testing.T) {
+	testRouter := createTestRouter(getNewChannelController(channelRepo))
+	t.Run("Put:200", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getRateLimitTestPutRequest("/channel/channel-for-rate-limit", url.Values{messagesPerSecondFormParamName: {"2"},
+			burstFormParamName: {"10"}}))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		channel := &ChannelModel{}
+		assert.Nil(t, json.NewDecoder(rr.Body).Decode(channel))
+		assert.Equal(t, &RateLimitModel{MessagesPerSecond: 2, Burst: 10, RemainingMessages: 10}, channel.RateLimit)
+	})
+	t.Run("Put:400", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getRateLimitTestPutRequest("/channel/channel-for-rate-limit-400", url.Values{burstFormParamName: {"many"}}))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, ErrBadRequestForRateLimit.Error(), rr.Body.String())
+	})
+}
+
+func TestBroadcastControllerRateLimit(t *testing.T) {
+	channel, _ := data.NewChannel("channel-for-broadcast-rate-limit", consumerTestChannel.Token)
+	channel, err := channelRepo.Store(channel)
+	assert.Nil(t, err)
+	producer, _ := data.NewProducer("producer-for-broadcast-rate-limit", "producer-for-broadcast-rate-limit-token")
+	producer, err = producerRepo.Store(producer)
+	assert.Nil(t, err)
+	_, err = grantRepo.Grant(channel, producer)
+	assert.Nil(t, err)
+	_, err = quotaRepo.SetLimit(data.ProducerQuota, producer.ProducerID, config.PublishRateLimit{MessagesPerSecond: 1, Burst: 2})
+	assert.Nil(t, err)
+	channelParam := getRouterParam(channel.ChannelID)
+	t.Run("429", func(t *testing.T) {
+		controller, mockDispatcher := getNewBroadcastController(messageRepo)
+		mockDispatcher.On("Dispatch", mock.Anything).Return()
+		testRouter := createTestRouter(controller)
+		for index, expectedStatus := range []int{http.StatusAccepted, http.StatusAccepted, http.StatusTooManyRequests} {
+			rr := httptest.NewRecorder()
+			testRouter.ServeHTTP(rr, setSchemaTestProducer(getPayloadLimitTestRequest(controller.FormatAsRelativeLink(channelParam), channel, "rate limited"), producer))
+			assert.Equal(t, expectedStatus, rr.Code, index)
+			if expectedStatus == http.StatusTooManyRequests {
+				assert.Equal(t, "1", rr.Header().Get(headerRetryAfter))
+				assert.Equal(t, errRateLimitExceeded.Error(), rr.Body.String())
+			}
+		}
+	})
+	t.Run("Batch:429", func(t *testing.T) {
+		batchController, wg, _ := getNewBroadcastBatchController(messageRepo)
+		testRouter := createTestRouter(batchController)
+		_, err = quotaRepo.SetLimit(data.ProducerQuota, producer.ProducerID, config.PublishRateLimit{})
+		assert.Nil(t, err)
+		_, err = quotaRepo.SetLimit(data.ChannelQuota, channel.ChannelID, config.PublishRateLimit{BytesPerMinute: 4})
+		assert.Nil(t, err)
+		wg.Add(1)
+		rr := httptest.NewRecorder()
+		req := setSchemaTestProducer(getPayloadLimitTestRequest(batchController.FormatAsRelativeLink(channelParam), channel, `[{"Payload": "first"}, {"Payload": "second"}]`), producer)
+		req.Header.Set(headerContentType, jsonContentTypeValue)
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		results := getBroadcastBatchResults(t, rr)
+		assert.Equal(t, http.StatusAccepted, results[0].Status)
+		assert.Equal(t, http.StatusTooManyRequests, results[1].Status)
+		assert.Equal(t, errRateLimitExceeded.Error(), results[1].Error)
+		assert.Equal(t, "30", rr.Header().Get(headerRetryAfter))
+		wg.Wait()
+	})
+	t.Run("429:ChangedConcurrently", func(t *testing.T) {
+		controller, _ := getNewBroadcastController(new(storagemocks.MessageRepository))
+		mockQuotaRepo := new(storagemocks.PublishQuotaRepository)
+		mockQuotaRepo.On("TakeTokens", mock.Anything, mock.Anything, mock.Anything).Return(time.Duration(0), storage.ErrQuotaChangedConcurrently)
+		controller.QuotaRepository = mockQuotaRepo
+		rr := httptest.NewRecorder()
+		createTestRouter(controller).ServeHTTP(rr, setSchemaTestProducer(getPayloadLimitTestRequest(controller.FormatAsRelativeLink(channelParam), channel, "contended"), producer))
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "1", rr.Header().Get(headerRetryAfter))
+	})
+	t.Run("500", func(t *testing.T) {
+		controller, _ := getNewBroadcastController(new(storagemocks.MessageRepository))
+		mockQuotaRepo := new(storagemocks.PublishQuotaRepository)
+		mockQuotaRepo.On("TakeTokens", mock.Anything, mock.Anything, mock.Anything).Return(time.Duration(0), errors.New("quota error"))
+		controller.QuotaRepository = mockQuotaRepo
+		rr := httptest.NewRecorder()
+		createTestRouter(controller).ServeHTTP(rr, setSchemaTestProducer(getPayloadLimitTestRequest(controller.FormatAsRelativeLink(channelParam), channel, "failing"), producer))
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+}
+
+func TestBroadcastBatchRateLimitSkipsDuplicates(t *testing.T) {
+	channel, _ := data.NewChannel("channel-for-batch-rate-limit", consumerTestChannel.Token)
+	channel, err := channelRepo.Store(channel)
+	assert.Nil(t, err)
+	producer, _ := data.NewProducer("producer-for-batch-rate-limit", "producer-for-batch-rate-limit-token")
+	producer, err = producerRepo.Store(producer)
+	assert.Nil(t, err)
+	_, err = grantRepo.Grant(channel, producer)
+	assert.Nil(t, err)
+	_, err = quotaRepo.SetLimit(data.ProducerQuota, producer.ProducerID, config.PublishRateLimit{MessagesPerSecond: 1, Burst: 1})
+	assert.Nil(t, err)
+	existing, _ := data.NewMessage(channel, producer, "existing", defaultMessageContentType)
+	existing.MessageID = "batch-rate-limit-existing"
+	assert.Nil(t, messageRepo.Create(existing))
+	batchController, wg, _ := getNewBroadcastBatchController(messageRepo)
+	wg.Add(1)
+	rr := httptest.NewRecorder()
+	req := setSchemaTestProducer(getPayloadLimitTestRequest(batchController.FormatAsRelativeLink(getRouterParam(channel.ChannelID)), channel,
+		`[{"MessageID": "batch-rate-limit-existing", "Payload": "duplicate"}, {"MessageID": "batch-rate-limit-new", "Payload": "new"}]`), producer)
+	req.Header.Set(headerContentType, jsonContentTypeValue)
+	createTestRouter(batchController).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	results := getBroadcastBatchResults(t, rr)
+	// The duplicate does not use up the only token of the producer
+	assert.Equal(t, http.StatusConflict, results[0].Status)
+	assert.Equal(t, http.StatusAccepted, results[1].Status)
+	assert.Empty(t, rr.Header().Get(headerRetryAfter))
+	wg.Wait()
+}
@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/newscred/webhook-broker/storage"
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+const (
+	replaysPath             = consumerPath + "/replay"
+	replayIDPathParamKey    = "replayId"
+	replayPath              = replaysPath + "/:" + replayIDPathParamKey
+	replayFromFormParamName = "from"
+	replayToFormParamName   = "to"
+	replayMessageIDFormName = "messageId"
+	headerLocation          = "Location"
+)
+
+// ReplayModel represents the progress of a replay communicated to HTTP clients
+type ReplayModel struct {
+	ID               string
+	From             *time.Time `json:",omitempty"`
+	To               *time.Time `json:",omitempty"`
+	MessageIDs       []string
+	Status           string
+	TotalCount       uint
+	ProcessedCount   uint
+	DeliveredCount   uint
+	FailedCount      uint
+	CursorReceivedAt *time.Time `json:",omitempty"`
+	CursorID         string
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+func optionalTime(value time.Time) *time.Time {
+	if value.IsZero() {
+		return nil
+	}
+	return &value
+}
+
+func newReplayModel(replay *data.Replay) *ReplayModel {
+	return &ReplayModel{ID: replay.ID.String(), From: optionalTime(replay.From), To: optionalTime(replay.To), MessageIDs: replay.MessageIDs,
+		Status: replay.Status.String(), TotalCount: replay.TotalCount, ProcessedCount: replay.ProcessedCount, DeliveredCount: replay.DeliveredCount,
+		FailedCount: replay.FailedCount, CursorReceivedAt: optionalTime(replay.CursorReceivedAt), CursorID: replay.CursorID,
+		CreatedAt: replay.CreatedAt, UpdatedAt: replay.UpdatedAt}
+}
+
+// ReplaysController represents the endpoint for re-delivering past messages of a channel to a consumer
+type ReplaysController struct {
+	ConsumerRepo   storage.ConsumerRepository
+	ReplayRepo     storage.ReplayRepository
+	ReplayEndpoint EndpointController
+}
+
+// NewReplaysController creates and returns a new instance of ReplaysController
+func NewReplaysController(consumerRepo storage.ConsumerRepository, replayRepo storage.ReplayRepository, replayController *ReplayController) *ReplaysController {
+	return &ReplaysController{ConsumerRepo: consumerRepo, ReplayRepo: replayRepo, ReplayEndpoint: replayController}
+}
+
+// Post implements the POST /channel/:channelId/consumer/:consumerId/replay endpoint; messages are selected by RFC3339 `from`/`to`
+// received at range and/or repeated `messageId` form params and are re-delivered asynchronously in received at order
+func (controller *ReplaysController) Post(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	consumer := getConsumerForTokenAuthorizedPost(controller.ConsumerRepo, w, r, params)
+	if consumer == nil {
+		return
+	}
+	if consumer.IsStreaming() {
+		writeStatus(w, http.StatusConflict, ErrConsumerIsStreaming)
+		return
+	}
+	from, fromErr := parseOptionalTime(r.PostFormValue(replayFromFormParamName))
+	to, toErr := parseOptionalTime(r.PostFormValue(replayToFormParamName))
+	if fromErr != nil || toErr != nil || (from.IsZero() && !to.IsZero()) {
+		writeStatus(w, http.StatusBadRequest, ErrBadRequestForReplay)
+		return
+	}
+	replay, err := data.NewReplay(consumer, from, to, r.PostForm[replayMessageIDFormName])
+	if err != nil {
+		writeStatus(w, http.StatusBadRequest, ErrBadRequestForReplay)
+		return
+	}
+	if err = controller.ReplayRepo.Create(replay); err != nil {
+		writeErr(w, err)
+		return
+	}
+	w.Header().Set(headerLocation, controller.ReplayEndpoint.FormatAsRelativeLink(append(params, httprouter.Param{Key: replayIDPathParamKey, Value: replay.ID.String()})...))
+	writeStatus(w, http.StatusAccepted, nil)
+}
+
+func parseOptionalTime(value string) (time.Time, error) {
+	if len(value) <= 0 {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// GetPath returns the endpoint's path
+func (controller *ReplaysController) GetPath() string {
+	return replaysPath
+}
+
+// FormatAsRelativeLink Format as relative URL of this resource based on the params
+func (controller *ReplaysController) FormatAsRelativeLink(params ...httprouter.Param) string {
+	return formatURL(params, replaysPath, channelIDPathParamKey, consumerIDPathParamKey)
+}
+
+// ReplayController represents the endpoint for tracking progress of a single replay
+type ReplayController struct {
+	ConsumerRepo storage.ConsumerRepository
+	ReplayRepo   storage.ReplayRepository
+}
+
+// NewReplayController creates and returns a new instance of ReplayController
+func NewReplayController(consumerRepo storage.ConsumerRepository, replayRepo storage.ReplayRepository) *ReplayController {
+	return &ReplayController{ConsumerRepo: consumerRepo, ReplayRepo: replayRepo}
+}
+
+// Get implements the GET /channel/:channelId/consumer/:consumerId/replay/:replayId endpoint
+func (controller *ReplayController) Get(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	consumer, err := controller.ConsumerRepo.Get(findParam(params, channelIDPathParamKey), findParam(params, consumerIDPathParamKey))
+	var replay *data.Replay
+	if err == nil {
+		replay, err = controller.ReplayRepo.Get(consumer, findParam(params, replayIDPathParamKey))
+	}
+	switch err {
+	case nil:
+		writeJSON(w, newReplayModel(replay))
+	case sql.ErrNoRows:
+		writeNotFound(w)
+	default:
+		writeErr(w, err)
+	}
+}
+
+// GetPath returns the endpoint's path
+func (controller *ReplayController) GetPath() string {
+	return replayPath
+}
+
+// FormatAsRelativeLink Format as relative URL of this resource based on the params
+func (controller *ReplayController) FormatAsRelativeLink(params ...httprouter.Param) string {
+	return formatURL(params, replayPath, channelIDPathParamKey, consumerIDPathParamKey, replayIDPathParamKey)
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/newscred/webhook-broker/storage"
+	"github.com/newscred/webhook-broker/storage/data"
+	storagemocks "github.com/newscred/webhook-broker/storage/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func getReplayRequest(testURI string, form url.Values) *http.Request {
+	req, _ := http.NewRequest("POST", testURI, nil)
+	req.Header.Add(headerContentType, formDataContentTypeHeaderValue)
+	req.PostForm = form
+	return req
+}
+
+func TestReplayControllers(t *testing.T) {
+	consumerID := "consumer-for-replay"
+	consumer, _ := data.NewConsumer(consumerTestChannel, consumerID, successfulGetTestToken, callbackURL)
+	_, err := consumerRepo.Store(consumer)
+	assert.Nil(t, err)
+	replayRepo := storage.NewReplayRepository(db, consumerRepo, storage.NewProducerRepository(db))
+	replayController := NewReplayController(consumerRepo, replayRepo)
+	replaysController := NewReplaysController(consumerRepo, replayRepo, replayController)
+	testRouter := createTestRouter(replaysController, replayController)
+	params := []httprouter.Param{{Key: channelIDPathParamKey, Value: consumerTestChannel.ChannelID}, {Key: consumerIDPathParamKey, Value: consumerID}}
+	replaysURL := replaysController.FormatAsRelativeLink(params...)
+	assert.Equal(t, "/channel/"+consumerTestChannel.ChannelID+"/consumer/"+consumerID+"/replay", replaysURL)
+	assert.Equal(t, replaysPath, replaysController.GetPath())
+	assert.Equal(t, replayPath, replayController.GetPath())
+	t.Run("BadToken", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getReplayRequest(replaysURL, url.Values{consumerTokenFormParamName: {"wrong token"}, replayMessageIDFormName: {"some-id"}}))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+	t.Run("BadCriteria", func(t *testing.T) {
+		for _, form := range []url.Values{
+			{},
+			{replayFromFormParamName: {"yesterday"}},
+			{replayToFormParamName: {time.Now().Format(time.RFC3339)}},
+			{replayFromFormParamName: {time.Now().Format(time.RFC3339)}, replayToFormParamName: {time.Now().Add(-1 * time.Hour).Format(time.RFC3339)}},
+		} {
+			form.Set(consumerTokenFormParamName, successfulGetTestToken)
+			rr := httptest.NewRecorder()
+			testRouter.ServeHTTP(rr, getReplayRequest(replaysURL, form))
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			assert.Equal(t, ErrBadRequestForReplay.Error(), rr.Body.String())
+		}
+	})
+	t.Run("StreamingConsumer", func(t *testing.T) {
+		streamConsumer, _ := data.NewStreamConsumer(consumerTestChannel, "streaming-consumer-for-replay", successfulGetTestToken)
+		_, err := consumerRepo.Store(streamConsumer)
+		assert.Nil(t, err)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getReplayRequest(replaysController.FormatAsRelativeLink(httprouter.Param{Key: channelIDPathParamKey, Value: consumerTestChannel.ChannelID},
+			httprouter.Param{Key: consumerIDPathParamKey, Value: streamConsumer.ConsumerID}), url.Values{consumerTokenFormParamName: {successfulGetTestToken}, replayMessageIDFormName: {"some-id"}}))
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Equal(t, ErrConsumerIsStreaming.Error(), rr.Body.String())
+	})
+	t.Run("CreateAndGet", func(t *testing.T) {
+		from := time.Now().Add(-1 * time.Hour).Truncate(time.Second)
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getReplayRequest(replaysURL, url.Values{consumerTokenFormParamName: {successfulGetTestToken}, replayFromFormParamName: {from.Format(time.RFC3339)},
+			replayMessageIDFormName: {"message-1", "message-2"}}))
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		location := rr.Header().Get(headerLocation)
+		assert.Contains(t, location, replaysURL+"/")
+		rr = httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", location, nil)
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		replayModel := &ReplayModel{}
+		assert.Nil(t, json.NewDecoder(rr.Body).Decode(replayModel))
+		assert.Equal(t, data.ReplayQueuedStr, replayModel.Status)
+		assert.Equal(t, []string{"message-1", "message-2"}, replayModel.MessageIDs)
+		assert.Equal(t, from.Unix(), replayModel.From.Unix())
+		assert.NotNil(t, replayModel.To)
+		assert.Nil(t, replayModel.CursorReceivedAt)
+		assert.Equal(t, uint(0), replayModel.TotalCount)
+	})
+	t.Run("GetNotFound", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", replaysURL+"/non-existent", nil)
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		rr = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/channel/"+consumerTestChannel.ChannelID+"/consumer/non-existent/replay/some-id", nil)
+		testRouter.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+	t.Run("CreateError", func(t *testing.T) {
+		expectedErr := errors.New("expected error")
+		mockReplayRepo := new(storagemocks.ReplayRepository)
+		mockReplayRepo.On("Create", mock.Anything).Return(expectedErr)
+		rr := httptest.NewRecorder()
+		createTestRouter(NewReplaysController(consumerRepo, mockReplayRepo, replayController)).ServeHTTP(rr, getReplayRequest(replaysURL,
+			url.Values{consumerTokenFormParamName: {successfulGetTestToken}, replayMessageIDFormName: {"message-1"}}))
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		mockReplayRepo.AssertExpectations(t)
+	})
+	t.Run("GetError", func(t *testing.T) {
+		expectedErr := errors.New("expected error")
+		mockReplayRepo := new(storagemocks.ReplayRepository)
+		mockReplayRepo.On("Get", mock.Anything, "some-id").Return(nil, expectedErr)
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", replaysURL+"/some-id", nil)
+		createTestRouter(NewReplayController(consumerRepo, mockReplayRepo)).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		mockReplayRepo.AssertExpectations(t)
+	})
+}
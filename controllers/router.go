@@ -3,8 +3,9 @@ package controllers
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
 	"errors"
-	"math/rand"
+	"math/big"
 	"net/http"
 	"net/url"
 	"os"
@@ -24,6 +25,7 @@ import (
 	"github.com/google/wire"
 	"github.com/julienschmidt/httprouter"
 	"github.com/newscred/webhook-broker/config"
+	"github.com/newscred/webhook-broker/publish"
 	"github.com/newscred/webhook-broker/storage/data"
 )
 
@@ -32,7 +34,7 @@ var (
 	routerInitializer sync.Once
 	server            *http.Server
 	// ControllerInjector for binding controllers
-	ControllerInjector = wire.NewSet(ConfigureAPI, NewRouter, NewStatusController, NewProducersController, NewProducerController, NewChannelController, NewChannelsController, NewConsumerController, NewConsumersController, NewBroadcastController, NewMessageController, NewMessagesController, NewDLQController, wire.Struct(new(Controllers), "StatusController", "ProducersController", "ProducerController", "ChannelController", "ConsumerController", "ConsumersController", "BroadcastController", "MessageController", "MessagesController", "DLQController", "ChannelsController"))
+	ControllerInjector = wire.NewSet(ConfigureAPI, NewRouter, NewStatusController, NewProducersController, NewProducerController, NewChannelController, NewChannelsController, NewConsumerController, NewConsumersController, NewBroadcastController, NewBroadcastBatchController, NewMessageController, NewMessagesController, NewDLQController, NewDeadJobController, NewChannelDLQController, NewDLQExportController, NewConsumerVerificationController, NewConsumerPauseController, NewConsumerResumeController, NewReplaysController, NewReplayController, NewConsumerStreamController, NewConsumerStreamAckController, NewSchemasController, NewSchemaController, NewPublishGrantController, NewConsumerStatsController, NewChannelStatsController, NewAdminConsoleController, wire.Struct(new(Controllers), "StatusController", "ProducersController", "ProducerController", "ChannelController", "ConsumerController", "ConsumersController", "BroadcastController", "BroadcastBatchController", "MessageController", "MessagesController", "DLQController", "DeadJobController", "ChannelDLQController", "DLQExportController", "ChannelsController", "ConsumerVerificationController", "ConsumerPauseController", "ConsumerResumeController", "ReplaysController", "ReplayController", "ConsumerStreamController", "ConsumerStreamAckController", "SchemasController", "SchemaController", "PublishGrantController", "ConsumerStatsController", "ChannelStatsController", "AdminConsoleController"))
 	// ErrUnsupportedMediaType is returned when client does not provide appropriate `Content-Type` header
 	ErrUnsupportedMediaType = errors.New("Media type not supported")
 	// ErrConditionalFailed is returned when update is missing `If-Unmodified-Since` header
@@ -41,8 +43,67 @@ var (
 	ErrNotFound = errors.New("Request resource not found")
 	// ErrBadRequest is returned when protocol for a PUT/POST/DELETE request is not met
 	ErrBadRequest = errors.New("Bad Request: Update is missing `If-Unmodified-Since` header ")
-	// ErrBadRequestForRequeue is returned when requeue form param does not match consumer token
-	ErrBadRequestForRequeue = errors.New("`requeue` form param must match consumer token")
+	// ErrBadRequestForRequeue is returned when neither requeue nor token form param matches consumer token
+	ErrBadRequestForRequeue = errors.New("`requeue` or `token` form param must match consumer token")
+	// ErrBadRequestForDLQFilter is returned when the dead jobs' filter or action is not valid
+	ErrBadRequestForDLQFilter = errors.New("DLQ filter needs RFC3339 `from`/`to`, `consumerId` of the channel and `action` one of `requeue` or `discard`")
+	// ErrBadRequestForConsumerToken is returned when token form param does not match consumer token
+	ErrBadRequestForConsumerToken = errors.New("`token` form param must match consumer token")
+	// ErrConsumerAlreadyVerified is returned when verification is re-triggered for a consumer that is already verified
+	ErrConsumerAlreadyVerified = errors.New("consumer callback URL is already verified")
+	// ErrBadRequestForBackfill is returned when `backfillSince` form param is neither a duration nor a RFC3339 timestamp in the past
+	ErrBadRequestForBackfill = errors.New("`backfillSince` form param must be either a duration, e.g. `24h`, or a RFC3339 timestamp in the past")
+	// ErrBadRequestForMaxPayloadSize is returned when `maxPayloadSize` form param is not a non-negative integer
+	ErrBadRequestForMaxPayloadSize = errors.New("`maxPayloadSize` form param must be a non-negative number of bytes")
+	// ErrBadRequestForRoutingKeyPattern is returned when `routingKeyPattern` form param is not dot separated words, `*` or `#`
+	ErrBadRequestForRoutingKeyPattern = errors.New("`routingKeyPattern` form param must be dot separated words of letters, digits, `_` and `-` or wildcards `*` and `#`")
+	// ErrBadRequestForRoutingKey is returned when the message's routing key is not dot separated words
+	ErrBadRequestForRoutingKey = publish.ErrInvalidRoutingKey
+	// ErrBadRequestForMessageAttributes is returned when the message's attributes have invalid names or values or are too large
+	ErrBadRequestForMessageAttributes = publish.ErrInvalidAttributes
+	// ErrBadRequestForReceiptURL is returned when the receipt URL of a producer or a message is not an absolute HTTP(S) URL
+	ErrBadRequestForReceiptURL = publish.ErrInvalidReceiptURL
+	// ErrBadRequestForBroadcastBatch is returned when the broadcast batch body is not a non-empty JSON array or NDJSON of messages
+	ErrBadRequestForBroadcastBatch = errors.New("broadcast batch must be a JSON array or NDJSON of messages with `Payload` and optionally `MessageID`, `ContentType`, `Priority`, `RoutingKey`, `Attributes` and `ReceiptURL`")
+	// ErrBadRequestForConsumerType is returned when `type` form param is neither `push` nor `stream`
+	ErrBadRequestForConsumerType = errors.New("`type` form param must be either `push` or `stream`")
+	// ErrBadRequestForStreamToken is returned when neither the consumer token header nor `token` query param match consumer token
+	ErrBadRequestForStreamToken = errors.New("`X-Broker-Consumer-Token` header or `token` query param must match consumer token")
+	// ErrBadRequestForStreamAck is returned when `eventId` form param is missing
+	ErrBadRequestForStreamAck = errors.New("`eventId` form param is required")
+	// ErrConsumerIsStreaming is returned when an operation requiring a callback URL is requested for a streaming consumer
+	ErrConsumerIsStreaming = errors.New("consumer is streaming and has no callback URL")
+	// ErrConsumerNotStreaming is returned when a push consumer connects to or acks over the stream
+	ErrConsumerNotStreaming = errors.New("consumer is not streaming, set its `type` to `stream`")
+	// ErrBadRequestForCloudEvent is returned when a message broadcasted as a CloudEvent is missing required attributes or is malformed
+	ErrBadRequestForCloudEvent = errors.New("CloudEvent must have `specversion` 1.0, `id`, `source` and `type`, `time` if present must be RFC3339 and structured mode body must be valid JSON")
+	// ErrBadRequestForDeliveryFormat is returned when `deliveryFormat` form param is not one of `raw`, `cloudevent-binary` or `cloudevent-structured`
+	ErrBadRequestForDeliveryFormat = errors.New("`deliveryFormat` form param must be one of `raw`, `cloudevent-binary` or `cloudevent-structured`")
+	// ErrBadRequestForSchema is returned when the schema registered is not a self contained JSON Schema
+	ErrBadRequestForSchema = errors.New("schema must be a valid JSON Schema without references to other documents and at most 1MB")
+	// ErrBadRequestForSchemaVersion is returned when the message's `Schema-Version` attribute or `schema-version` content type parameter is not
+	// a version of the channel's schema
+	ErrBadRequestForSchemaVersion = publish.ErrInvalidSchemaVersion
+	// ErrBadRequestForRateLimit is returned when the rate limit form params of a producer or a channel are not valid
+	ErrBadRequestForRateLimit = errors.New("`messagesPerSecond`, `burst` and `bytesPerMinute` form params must be non-negative numbers")
+	// ErrStreamEventNotInflight is returned when the event acked is already acked or was not acked in time
+	ErrStreamEventNotInflight = errors.New("stream event is already acked or was not acked in time")
+	// ErrStreamingUnsupported is returned when the response can not be flushed as events are written
+	ErrStreamingUnsupported = errors.New("streaming is not supported by the connection")
+	// ErrBadRequestForReplay is returned when replay is requested without a valid RFC3339 `from`/`to` range or `messageId` form params
+	ErrBadRequestForReplay = errors.New("replay needs a valid RFC3339 `from` (and optionally `to`) and/or `messageId` form params")
+	// ErrBadRequestForMessageFilter is returned when the messages' search params or `expand` are not valid
+	ErrBadRequestForMessageFilter = errors.New("messages can be filtered by `status` one of `ACKNOWLEDGED` or `DISPATCHED`, `producerId`, RFC3339 `from`/`to` time received, `contentType` prefix, numeric `priority`, `messageIdPrefix` and boolean `hasDeadJobs`, and expanded with `expand` of `payload` and/or `jobs`")
+	// ErrReservedStakeholder is returned when deleting a channel or producer the broker itself depends on
+	ErrReservedStakeholder = errors.New("reserved channels and producers of the broker can not be deleted")
+	// ErrStakeholderDeleted is returned when a deleted producer, channel or consumer is recreated before it is purged
+	ErrStakeholderDeleted = errors.New("deleted and can not be recreated until it is purged with `purge=true`")
+	// ErrPurgeNotDeleted is returned when purging a producer, channel or consumer that is not deleted yet
+	ErrPurgeNotDeleted = errors.New("only deleted producers, channels and consumers can be purged, delete it first")
+	// ErrPurgeBeforeRetention is returned when purging a deleted producer, channel or consumer before its retention is over
+	ErrPurgeBeforeRetention = errors.New("deleted producers, channels and consumers can only be purged once their retention is over")
+	// ErrAdminUnauthorized is returned when the admin console is requested without the admin's basic authentication credentials
+	ErrAdminUnauthorized = errors.New("admin console needs the admin username and password")
 )
 
 const (
@@ -60,17 +121,34 @@ const (
 type (
 	// Controllers represents factory object containing all the controllers
 	Controllers struct {
-		StatusController    *StatusController
-		ProducersController *ProducersController
-		ProducerController  *ProducerController
-		ChannelController   *ChannelController
-		ChannelsController  *ChannelsController
-		ConsumerController  *ConsumerController
-		ConsumersController *ConsumersController
-		BroadcastController *BroadcastController
-		MessageController   *MessageController
-		MessagesController  *MessagesController
-		DLQController       *DLQController
+		StatusController               *StatusController
+		ProducersController            *ProducersController
+		ProducerController             *ProducerController
+		ChannelController              *ChannelController
+		ChannelsController             *ChannelsController
+		ConsumerController             *ConsumerController
+		ConsumersController            *ConsumersController
+		BroadcastController            *BroadcastController
+		BroadcastBatchController       *BroadcastBatchController
+		MessageController              *MessageController
+		MessagesController             *MessagesController
+		DLQController                  *DLQController
+		DeadJobController              *DeadJobController
+		ChannelDLQController           *ChannelDLQController
+		DLQExportController            *DLQExportController
+		ConsumerVerificationController *ConsumerVerificationController
+		ConsumerPauseController        *ConsumerPauseController
+		ConsumerResumeController       *ConsumerResumeController
+		ReplaysController              *ReplaysController
+		ReplayController               *ReplayController
+		ConsumerStreamController       *ConsumerStreamController
+		ConsumerStreamAckController    *ConsumerStreamAckController
+		SchemasController              *SchemasController
+		SchemaController               *SchemaController
+		PublishGrantController         *PublishGrantController
+		ConsumerStatsController        *ConsumerStatsController
+		ChannelStatsController         *ChannelStatsController
+		AdminConsoleController         *AdminConsoleController
 	}
 
 	// ServerLifecycleListener listens to key server lifecycle error
@@ -213,8 +291,12 @@ func NewRouter(controllers *Controllers) *httprouter.Router {
 	apiRouter.Handler(http.MethodGet, "/debug/pprof/threadcreate", pprof.Handler("threadcreate"))
 	apiRouter.Handler(http.MethodGet, "/debug/pprof/block", pprof.Handler("block"))
 	setupAPIRoutes(apiRouter, controllers.StatusController, controllers.ProducersController, controllers.ProducerController, controllers.ChannelController,
-		controllers.ConsumerController, controllers.ConsumersController, controllers.BroadcastController, controllers.MessageController,
-		controllers.MessagesController, controllers.DLQController, controllers.ChannelsController)
+		controllers.ConsumerController, controllers.ConsumersController, controllers.BroadcastController, controllers.BroadcastBatchController, controllers.MessageController,
+		controllers.MessagesController, controllers.DLQController, controllers.ChannelsController, controllers.ConsumerVerificationController,
+		controllers.ConsumerPauseController, controllers.ConsumerResumeController, controllers.ReplaysController, controllers.ReplayController, controllers.DeadJobController,
+		controllers.ChannelDLQController, controllers.DLQExportController, controllers.ConsumerStreamController, controllers.ConsumerStreamAckController,
+		controllers.SchemasController, controllers.SchemaController, controllers.PublishGrantController, controllers.ConsumerStatsController,
+		controllers.ChannelStatsController, controllers.AdminConsoleController)
 	return apiRouter
 }
 
@@ -244,14 +326,14 @@ func getPaginationLinks(req *http.Request, pagination *data.Pagination) map[stri
 		originalURL := req.URL
 		if pagination.Previous != nil {
 			previous := cloneBaseURL(originalURL)
-			prevQueries := make(url.Values)
+			prevQueries := getNonPaginationQueries(originalURL)
 			prevQueries.Set(previousPaginationQueryParamKey, pagination.Previous.String())
 			previous.RawQuery = prevQueries.Encode()
 			links[previousPaginationQueryParamKey] = previous.String()
 		}
 		if pagination.Next != nil {
 			next := cloneBaseURL(originalURL)
-			nextQueries := make(url.Values)
+			nextQueries := getNonPaginationQueries(originalURL)
 			nextQueries.Set(nextPaginationQueryParamKey, pagination.Next.String())
 			next.RawQuery = nextQueries.Encode()
 			links[nextPaginationQueryParamKey] = next.String()
@@ -260,6 +342,14 @@ func getPaginationLinks(req *http.Request, pagination *data.Pagination) map[stri
 	return links
 }
 
+// getNonPaginationQueries retains query params such as filters for the pagination links
+func getNonPaginationQueries(originalURL *url.URL) url.Values {
+	queries := originalURL.Query()
+	queries.Del(previousPaginationQueryParamKey)
+	queries.Del(nextPaginationQueryParamKey)
+	return queries
+}
+
 func cloneBaseURL(originalURL *url.URL) *url.URL {
 	newURL := &url.URL{}
 	newURL.Scheme = originalURL.Scheme
@@ -317,6 +407,10 @@ func writeStatus(w http.ResponseWriter, code int, err error) {
 }
 
 func writeJSON(w http.ResponseWriter, data interface{}) {
+	writeJSONWithStatus(w, http.StatusOK, data)
+}
+
+func writeJSONWithStatus(w http.ResponseWriter, code int, data interface{}) {
 	// Write JSON
 	var buf bytes.Buffer
 	err := getJSON(&buf, data)
@@ -325,18 +419,21 @@ func writeJSON(w http.ResponseWriter, data interface{}) {
 		writeErr(w, err)
 		return
 	}
-	w.WriteHeader(200)
 	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(code)
 	w.Write(buf.Bytes())
 }
 
-var seededRand *rand.Rand = rand.New(
-	rand.NewSource(time.Now().UnixNano()))
-
+// randomToken generates tokens and verification challenges, so they must not be predictable
 func randomToken() string {
 	b := make([]byte, 12)
+	max := big.NewInt(int64(len(charset)))
 	for i := range b {
-		b[i] = charset[seededRand.Intn(len(charset))]
+		index, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			panic(err)
+		}
+		b[i] = charset[index.Int64()]
 	}
 	return string(b)
 }
@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/newscred/webhook-broker/publish"
+	"github.com/newscred/webhook-broker/storage"
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+const (
+	schemasPath               = channelPath + "/schemas"
+	schemaVersionPathParamKey = "version"
+	schemaPath                = channelPath + "/schema/:" + schemaVersionPathParamKey
+	maxSchemaDefinitionSize   = 1 << 20
+)
+
+var (
+	errPayloadNotConforming = publish.ErrPayloadNotConforming
+	errSchemaIncompatible   = errors.New("schema is not backward compatible with the latest version of the channel's schema")
+)
+
+// SchemaModel represents a version of the JSON Schema of a channel
+type SchemaModel struct {
+	Version    uint
+	Definition json.RawMessage
+	CreatedAt  time.Time
+}
+
+func newSchemaModel(schema *data.ChannelSchema) *SchemaModel {
+	return &SchemaModel{Version: schema.Version, Definition: json.RawMessage(schema.Definition), CreatedAt: schema.CreatedAt}
+}
+
+// SchemasModel represents all the versions of the JSON Schema of a channel, most recent first
+type SchemasModel struct {
+	Schemas []*SchemaModel
+}
+
+// SchemaValidationReport is the response for a payload rejected as it does not conform to the schema version it was validated against
+type SchemaValidationReport struct {
+	Error         string
+	SchemaVersion uint
+	Errors        []*data.SchemaViolation
+}
+
+func newSchemaValidationReport(validationErr *publish.SchemaValidationError) *SchemaValidationReport {
+	return &SchemaValidationReport{Error: validationErr.Error(), SchemaVersion: validationErr.SchemaVersion, Errors: validationErr.Violations}
+}
+
+// SchemaIncompatibilityReport is the response for a schema version rejected as it is not backward compatible with the latest version
+type SchemaIncompatibilityReport struct {
+	Error             string
+	LatestVersion     uint
+	Incompatibilities []string
+}
+
+// SchemasController represents the endpoint for listing and registering versions of a channel's JSON Schema
+type SchemasController struct {
+	ChannelRepo    storage.ChannelRepository
+	SchemaRepo     storage.SchemaRepository
+	SchemaEndpoint EndpointController
+}
+
+// NewSchemasController creates and returns a new instance of SchemasController
+func NewSchemasController(channelRepo storage.ChannelRepository, schemaRepo storage.SchemaRepository, schemaController *SchemaController) *SchemasController {
+	return &SchemasController{ChannelRepo: channelRepo, SchemaRepo: schemaRepo, SchemaEndpoint: schemaController}
+}
+
+// Get implements the GET /channel/:channelId/schemas endpoint
+func (controller *SchemasController) Get(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	channel, err := controller.ChannelRepo.Get(findParam(params, channelIDPathParamKey))
+	if err != nil {
+		writeNotFound(w)
+		return
+	}
+	schemas, err := controller.SchemaRepo.GetList(channel)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	result := &SchemasModel{Schemas: make([]*SchemaModel, 0, len(schemas))}
+	for _, schema := range schemas {
+		result.Schemas = append(result.Schemas, newSchemaModel(schema))
+	}
+	writeJSON(w, result)
+}
+
+// Post implements the POST /channel/:channelId/schemas endpoint; the JSON body is registered as the next version of the channel's schema
+// provided it is backward compatible with the latest version
+func (controller *SchemasController) Post(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	if !isJSONContentType(r.Header.Get(headerContentType)) {
+		writeUnsupportedMediaType(w)
+		return
+	}
+	channel, err := controller.ChannelRepo.Get(findParam(params, channelIDPathParamKey))
+	if err != nil {
+		writeNotFound(w)
+		return
+	}
+	definition, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxSchemaDefinitionSize))
+	if err != nil {
+		writeStatus(w, http.StatusBadRequest, ErrBadRequestForSchema)
+		return
+	}
+	latest, err := controller.SchemaRepo.GetLatest(channel)
+	var version uint = 1
+	switch err {
+	case nil:
+		version = latest.Version + 1
+	case sql.ErrNoRows:
+		latest = nil
+	default:
+		writeErr(w, err)
+		return
+	}
+	schema, err := data.NewChannelSchema(channel, version, string(definition))
+	if err != nil {
+		writeStatus(w, http.StatusBadRequest, ErrBadRequestForSchema)
+		return
+	}
+	if latest != nil {
+		incompatibilities, err := schema.GetIncompatibilities(latest)
+		if err != nil {
+			writeErr(w, err)
+			return
+		}
+		if len(incompatibilities) > 0 {
+			writeJSONWithStatus(w, http.StatusConflict, &SchemaIncompatibilityReport{Error: errSchemaIncompatible.Error(), LatestVersion: latest.Version,
+				Incompatibilities: incompatibilities})
+			return
+		}
+	}
+	switch err = controller.SchemaRepo.Create(schema); err {
+	case nil:
+		w.Header().Set(headerLocation, controller.SchemaEndpoint.FormatAsRelativeLink(append(params, httprouter.Param{Key: schemaVersionPathParamKey,
+			Value: strconv.FormatUint(uint64(schema.Version), 10)})...))
+		writeJSONWithStatus(w, http.StatusCreated, newSchemaModel(schema))
+	case storage.ErrDuplicateSchemaVersion:
+		writeStatus(w, http.StatusConflict, err)
+	default:
+		writeErr(w, err)
+	}
+}
+
+// GetPath returns the endpoint's path
+func (controller *SchemasController) GetPath() string {
+	return schemasPath
+}
+
+// FormatAsRelativeLink Format as relative URL of this resource based on the params
+func (controller *SchemasController) FormatAsRelativeLink(params ...httprouter.Param) string {
+	return formatURL(params, schemasPath, channelIDPathParamKey)
+}
+
+// SchemaController represents the endpoint for a single version of a channel's JSON Schema
+type SchemaController struct {
+	ChannelRepo storage.ChannelRepository
+	SchemaRepo  storage.SchemaRepository
+}
+
+// NewSchemaController creates and returns a new instance of SchemaController
+func NewSchemaController(channelRepo storage.ChannelRepository, schemaRepo storage.SchemaRepository) *SchemaController {
+	return &SchemaController{ChannelRepo: channelRepo, SchemaRepo: schemaRepo}
+}
+
+// Get implements the GET /channel/:channelId/schema/:version endpoint
+func (controller *SchemaController) Get(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	version, err := strconv.ParseUint(findParam(params, schemaVersionPathParamKey), 10, 0)
+	var channel *data.Channel
+	if err == nil {
+		channel, err = controller.ChannelRepo.Get(findParam(params, channelIDPathParamKey))
+	}
+	var schema *data.ChannelSchema
+	if err == nil {
+		schema, err = controller.SchemaRepo.Get(channel, uint(version))
+	}
+	switch {
+	case err == nil:
+		writeJSON(w, newSchemaModel(schema))
+	case err == sql.ErrNoRows || errors.Is(err, strconv.ErrSyntax) || errors.Is(err, strconv.ErrRange):
+		writeNotFound(w)
+	default:
+		writeErr(w, err)
+	}
+}
+
+// GetPath returns the endpoint's path
+func (controller *SchemaController) GetPath() string {
+	return schemaPath
+}
+
+// FormatAsRelativeLink Format as relative URL of this resource based on the params
+func (controller *SchemaController) FormatAsRelativeLink(params ...httprouter.Param) string {
+	return formatURL(params, schemaPath, channelIDPathParamKey, schemaVersionPathParamKey)
+}
@@ -0,0 +1,222 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/newscred/webhook-broker/storage/data"
+	storagemocks "github.com/newscred/webhook-broker/storage/mocks"
+)
+
+const (
+	schemaTestV1 = `{"type": "object", "properties": {"id": {"type": "integer"}, "name": {"type": "string"}}, "required": ["id"]}`
+	schemaTestV2 = `{"type": "object", "properties": {"id": {"type": "integer"}, "name": {"type": ["string", "null"]}, "tags": {"type": "array"}}, "required": ["id"]}`
+)
+
+func getSchemaRequest(method, testURI, contentType, body string) *http.Request {
+	req, _ := http.NewRequest(method, testURI, strings.NewReader(body))
+	req.Header.Set(headerContentType, contentType)
+	return req
+}
+
+// setSchemaTestProducer makes the request broadcast as a producer of its own as the shared test producers' tokens are updated by other tests
+func setSchemaTestProducer(req *http.Request, producer *data.Producer) *http.Request {
+	req.Header.Set(headerProducerID, producer.ProducerID)
+	req.Header.Set(headerProducerToken, producer.Token)
+	return req
+}
+
+func TestSchemaControllers(t *testing.T) {
+	channel, _ := data.NewChannel("channel-for-schemas", consumerTestChannel.Token)
+	channel, err := channelRepo.Store(channel)
+	assert.Nil(t, err)
+	producer, _ := data.NewProducer("producer-for-schemas", "producer-for-schemas-token")
+	producer, err = producerRepo.Store(producer)
+	assert.Nil(t, err)
+	_, err = grantRepo.Grant(channel, producer)
+	assert.Nil(t, err)
+	schemaController := NewSchemaController(channelRepo, schemaRepo)
+	schemasController := NewSchemasController(channelRepo, schemaRepo, schemaController)
+	testRouter := createTestRouter(schemasController, schemaController)
+	channelParam := getRouterParam(channel.ChannelID)
+	schemasURL := schemasController.FormatAsRelativeLink(channelParam)
+	assert.Equal(t, "/channel/channel-for-schemas/schemas", schemasURL)
+	assert.Equal(t, "/channel/channel-for-schemas/schema/1", schemaController.FormatAsRelativeLink(channelParam, httprouter.Param{Key: schemaVersionPathParamKey, Value: "1"}))
+	assert.Equal(t, schemasPath, schemasController.GetPath())
+	assert.Equal(t, schemaPath, schemaController.GetPath())
+	t.Run("Post:415", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getSchemaRequest(http.MethodPost, schemasURL, formDataContentTypeHeaderValue, schemaTestV1))
+		assert.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+	})
+	t.Run("Post:404", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getSchemaRequest(http.MethodPost, schemasController.FormatAsRelativeLink(getRouterParam("no-such-channel")), jsonContentTypeValue, schemaTestV1))
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+	t.Run("Post:400", func(t *testing.T) {
+		for _, definition := range []string{`{"type": `, `{"type": "unknown"}`, `{"$ref": "https://example.com/schema.json"}`} {
+			rr := httptest.NewRecorder()
+			testRouter.ServeHTTP(rr, getSchemaRequest(http.MethodPost, schemasURL, "application/schema+json", definition))
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			assert.Equal(t, ErrBadRequestForSchema.Error(), rr.Body.String())
+		}
+	})
+	t.Run("Get:Empty", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getSchemaRequest(http.MethodGet, schemasURL, "", ""))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, `{"Schemas":[]}`, strings.TrimSpace(rr.Body.String()))
+	})
+	t.Run("PostAndGet", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getSchemaRequest(http.MethodPost, schemasURL, jsonContentTypeValue, schemaTestV1))
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, "/channel/channel-for-schemas/schema/1", rr.Header().Get(headerLocation))
+		created := &SchemaModel{}
+		assert.Nil(t, json.NewDecoder(rr.Body).Decode(created))
+		assert.Equal(t, uint(1), created.Version)
+		assert.JSONEq(t, schemaTestV1, string(created.Definition))
+
+		rr = httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getSchemaRequest(http.MethodPost, schemasURL, jsonContentTypeValue, `{"type": "object", "properties": {"id": {"type": "string"}}, "required": ["id", "name"]}`))
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		report := &SchemaIncompatibilityReport{}
+		assert.Nil(t, json.NewDecoder(rr.Body).Decode(report))
+		assert.Equal(t, uint(1), report.LatestVersion)
+		assert.Equal(t, []string{`#: property "name" is now required`, "#/properties/id: type is narrowed to string"}, report.Incompatibilities)
+
+		rr = httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getSchemaRequest(http.MethodPost, schemasURL, jsonContentTypeValue, schemaTestV2))
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, "/channel/channel-for-schemas/schema/2", rr.Header().Get(headerLocation))
+
+		rr = httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getSchemaRequest(http.MethodGet, schemasURL, "", ""))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		schemas := &SchemasModel{}
+		assert.Nil(t, json.NewDecoder(rr.Body).Decode(schemas))
+		assert.Equal(t, 2, len(schemas.Schemas))
+		assert.Equal(t, uint(2), schemas.Schemas[0].Version)
+		assert.Equal(t, uint(1), schemas.Schemas[1].Version)
+
+		rr = httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, getSchemaRequest(http.MethodGet, "/channel/channel-for-schemas/schema/1", "", ""))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		schema := &SchemaModel{}
+		assert.Nil(t, json.NewDecoder(rr.Body).Decode(schema))
+		assert.JSONEq(t, schemaTestV1, string(schema.Definition))
+		for _, path := range []string{"/channel/channel-for-schemas/schema/3", "/channel/channel-for-schemas/schema/latest", "/channel/no-such-channel/schema/1"} {
+			rr = httptest.NewRecorder()
+			testRouter.ServeHTTP(rr, getSchemaRequest(http.MethodGet, path, "", ""))
+			assert.Equal(t, http.StatusNotFound, rr.Code, path)
+		}
+	})
+	t.Run("Broadcast", func(t *testing.T) {
+		getRequest := func(controller *BroadcastController, contentType, body string) *http.Request {
+			req := setSchemaTestProducer(getPayloadLimitTestRequest(controller.FormatAsRelativeLink(channelParam), channel, body), producer)
+			req.Header.Set(headerContentType, contentType)
+			return req
+		}
+		acceptedCases := map[string]struct {
+			contentType string
+			version     string
+			body        string
+		}{
+			"Latest":             {"application/json", "", `{"id": 1, "name": null}`},
+			"VersionAttribute":   {"application/json", "1", `{"id": 1, "name": "first"}`},
+			"ContentTypeVersion": {"application/json; schema-version=1", "", `{"id": 1, "name": "first"}`},
+		}
+		for name, testCase := range acceptedCases {
+			msgRepo := new(storagemocks.MessageRepository)
+			controller, mockDispatcher := getNewBroadcastController(msgRepo)
+			matcher := func(msg *data.Message) bool { return msg.Payload == testCase.body }
+			msgRepo.On("Create", mock.MatchedBy(matcher)).Return(nil)
+			wg := setupAsyncDispatchMock(mockDispatcher, matcher)
+			req := getRequest(controller, testCase.contentType, testCase.body)
+			if len(testCase.version) > 0 {
+				req.Header.Set(headerAttributePrefix+data.SchemaVersionAttributeName, testCase.version)
+			}
+			rr := httptest.NewRecorder()
+			createTestRouter(controller).ServeHTTP(rr, req)
+			if !assert.Equal(t, http.StatusAccepted, rr.Code, name+": "+rr.Body.String()) {
+				continue
+			}
+			wg.Wait()
+			msgRepo.AssertExpectations(t)
+		}
+		rejectedCases := map[string]struct {
+			contentType string
+			version     string
+			body        string
+			errors      []*data.SchemaViolation
+		}{
+			"Latest": {"application/json", "", `{"name": "first", "tags": "a"}`, []*data.SchemaViolation{
+				{InstanceLocation: "", KeywordLocation: "/required", Error: "missing properties: 'id'"},
+				{InstanceLocation: "/tags", KeywordLocation: "/properties/tags/type", Error: "expected array, but got string"}}},
+			"VersionAttribute": {"application/json", "1", `{"id": 1, "name": null}`, []*data.SchemaViolation{
+				{InstanceLocation: "/name", KeywordLocation: "/properties/name/type", Error: "expected string, but got null"}}},
+			"NotJSON": {"text/plain", "", `id=1`, []*data.SchemaViolation{{Error: data.ErrPayloadNotJSON.Error()}}},
+		}
+		for name, testCase := range rejectedCases {
+			controller, _ := getNewBroadcastController(new(storagemocks.MessageRepository))
+			req := getRequest(controller, testCase.contentType, testCase.body)
+			if len(testCase.version) > 0 {
+				req.Header.Set(headerAttributePrefix+data.SchemaVersionAttributeName, testCase.version)
+			}
+			rr := httptest.NewRecorder()
+			createTestRouter(controller).ServeHTTP(rr, req)
+			assert.Equal(t, http.StatusUnprocessableEntity, rr.Code, name)
+			report := &SchemaValidationReport{}
+			assert.Nil(t, json.NewDecoder(rr.Body).Decode(report))
+			assert.Equal(t, errPayloadNotConforming.Error(), report.Error)
+			assert.Equal(t, testCase.errors, report.Errors, name)
+		}
+		for _, version := range []string{"3", "0", "latest"} {
+			controller, _ := getNewBroadcastController(new(storagemocks.MessageRepository))
+			req := getRequest(controller, "application/json", `{"id": 1}`)
+			req.Header.Set(headerAttributePrefix+data.SchemaVersionAttributeName, version)
+			rr := httptest.NewRecorder()
+			createTestRouter(controller).ServeHTTP(rr, req)
+			assert.Equal(t, http.StatusBadRequest, rr.Code, version)
+			assert.Equal(t, ErrBadRequestForSchemaVersion.Error(), rr.Body.String())
+		}
+	})
+	t.Run("BroadcastBatch", func(t *testing.T) {
+		controller, wg, dispatched := getNewBroadcastBatchController(messageRepo)
+		body := `[{"MessageID": "schema-batch-1", "Payload": "{\"id\": 1}"},
+			{"MessageID": "schema-batch-2", "Payload": "{\"id\": \"1\"}"},
+			{"MessageID": "schema-batch-3", "Attributes": {"Schema-Version": "5"}, "Payload": "{\"id\": 1}"}]`
+		wg.Add(1)
+		req := setSchemaTestProducer(getBroadcastBatchTestRequest(controller.FormatAsRelativeLink(channelParam), jsonContentTypeValue, body), producer)
+		req.Header.Set(headerChannelToken, channel.Token)
+		rr := httptest.NewRecorder()
+		createTestRouter(controller).ServeHTTP(rr, req)
+		wg.Wait()
+		assert.Equal(t, http.StatusOK, rr.Code)
+		results := getBroadcastBatchResults(t, rr)
+		assert.Equal(t, []int{http.StatusAccepted, http.StatusUnprocessableEntity, http.StatusBadRequest}, []int{results[0].Status, results[1].Status, results[2].Status})
+		assert.Equal(t, []*data.SchemaViolation{{InstanceLocation: "/id", KeywordLocation: "/properties/id/type", Error: "expected integer, but got string"}},
+			results[1].ValidationErrors)
+		assert.Equal(t, ErrBadRequestForSchemaVersion.Error(), results[2].Error)
+		assert.Equal(t, 1, len(*dispatched))
+	})
+	t.Run("BroadcastSchemaLookupError", func(t *testing.T) {
+		mockSchemaRepo := new(storagemocks.SchemaRepository)
+		mockSchemaRepo.On("GetLatest", mock.Anything).Return(nil, errors.New("lookup failed"))
+		controller, _ := getNewBroadcastController(new(storagemocks.MessageRepository))
+		controller.SchemaRepository = mockSchemaRepo
+		rr := httptest.NewRecorder()
+		createTestRouter(controller).ServeHTTP(rr, setSchemaTestProducer(getPayloadLimitTestRequest(controller.FormatAsRelativeLink(channelParam), channel, `{"id": 1}`), producer))
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		mockSchemaRepo.AssertExpectations(t)
+	})
+}
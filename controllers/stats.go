@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/newscred/webhook-broker/storage"
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+const (
+	consumerStatsPath = consumerPath + "/stats"
+	channelStatsPath  = channelPath + "/stats"
+)
+
+// JobStatsModel represents the backlog and recent delivery stats of a consumer's, or all consumers of a channel's, jobs
+type JobStatsModel struct {
+	JobCounts                 map[string]uint
+	OldestQueuedJobAgeSeconds float64
+	// DeliveredPerMinute is keyed by the window, e.g. `5m` for the last 5 minutes
+	DeliveredPerMinute map[string]float64
+	// SuccessRate and AverageLatencyMillis are of the most recent jobs, up to SampledJobCount, finished within the last hour
+	SuccessRate          float64
+	AverageLatencyMillis int64
+	SampledJobCount      uint
+	NextRetryAt          *time.Time `json:",omitempty"`
+	ComputedAt           time.Time
+}
+
+func newJobStatsModel(stats *data.JobStats) *JobStatsModel {
+	model := &JobStatsModel{JobCounts: make(map[string]uint), DeliveredPerMinute: make(map[string]float64),
+		OldestQueuedJobAgeSeconds: stats.GetOldestQueuedAge().Seconds(), SuccessRate: stats.GetSuccessRate(),
+		AverageLatencyMillis: stats.GetAverageLatency().Milliseconds(), SampledJobCount: stats.SampledCount,
+		NextRetryAt: optionalTime(stats.NextRetryAt), ComputedAt: stats.ComputedAt}
+	for status, count := range stats.StatusCounts {
+		model.JobCounts[status.String()] = count
+	}
+	for _, window := range data.JobStatsWindows {
+		model.DeliveredPerMinute[fmt.Sprintf("%dm", int(window.Minutes()))] = stats.GetDeliveredPerMinute(window)
+	}
+	return model
+}
+
+func writeJobStats(w http.ResponseWriter, stats *data.JobStats, err error) {
+	switch err {
+	case nil:
+		writeJSON(w, newJobStatsModel(stats))
+	case sql.ErrNoRows:
+		writeNotFound(w)
+	default:
+		writeErr(w, err)
+	}
+}
+
+// ConsumerStatsController represents the endpoint for the backlog and lag of a consumer
+type ConsumerStatsController struct {
+	ConsumerRepo    storage.ConsumerRepository
+	DeliveryJobRepo storage.DeliveryJobRepository
+}
+
+// NewConsumerStatsController creates and returns a new instance of ConsumerStatsController
+func NewConsumerStatsController(consumerRepo storage.ConsumerRepository, djRepo storage.DeliveryJobRepository) *ConsumerStatsController {
+	return &ConsumerStatsController{ConsumerRepo: consumerRepo, DeliveryJobRepo: djRepo}
+}
+
+// Get implements the GET /channel/:channelId/consumer/:consumerId/stats endpoint
+func (controller *ConsumerStatsController) Get(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	consumer, err := controller.ConsumerRepo.Get(findParam(params, channelIDPathParamKey), findParam(params, consumerIDPathParamKey))
+	var stats *data.JobStats
+	if err == nil {
+		stats, err = controller.DeliveryJobRepo.GetJobStatsForConsumer(consumer)
+	}
+	writeJobStats(w, stats, err)
+}
+
+// GetPath returns the endpoint's path
+func (controller *ConsumerStatsController) GetPath() string {
+	return consumerStatsPath
+}
+
+// FormatAsRelativeLink Format as relative URL of this resource based on the params
+func (controller *ConsumerStatsController) FormatAsRelativeLink(params ...httprouter.Param) string {
+	return formatURL(params, consumerStatsPath, channelIDPathParamKey, consumerIDPathParamKey)
+}
+
+// ChannelStatsController represents the endpoint for the backlog and lag of all consumers of a channel
+type ChannelStatsController struct {
+	ChannelRepo     storage.ChannelRepository
+	DeliveryJobRepo storage.DeliveryJobRepository
+}
+
+// NewChannelStatsController creates and returns a new instance of ChannelStatsController
+func NewChannelStatsController(channelRepo storage.ChannelRepository, djRepo storage.DeliveryJobRepository) *ChannelStatsController {
+	return &ChannelStatsController{ChannelRepo: channelRepo, DeliveryJobRepo: djRepo}
+}
+
+// Get implements the GET /channel/:channelId/stats endpoint
+func (controller *ChannelStatsController) Get(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	channel, err := controller.ChannelRepo.Get(findParam(params, channelIDPathParamKey))
+	var stats *data.JobStats
+	if err == nil {
+		stats, err = controller.DeliveryJobRepo.GetJobStatsForChannel(channel)
+	}
+	writeJobStats(w, stats, err)
+}
+
+// GetPath returns the endpoint's path
+func (controller *ChannelStatsController) GetPath() string {
+	return channelStatsPath
+}
+
+// FormatAsRelativeLink Format as relative URL of this resource based on the params
+func (controller *ChannelStatsController) FormatAsRelativeLink(params ...httprouter.Param) string {
+	return formatURL(params, channelStatsPath, channelIDPathParamKey)
+}
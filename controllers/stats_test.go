@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/newscred/webhook-broker/storage/data"
+	storagemocks "github.com/newscred/webhook-broker/storage/mocks"
+)
+
+func getJobStats(t *testing.T, router http.Handler, path string) (int, *JobStatsModel) {
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	router.ServeHTTP(rr, req)
+	stats := &JobStatsModel{}
+	if rr.Code == http.StatusOK {
+		assert.Nil(t, json.NewDecoder(rr.Body).Decode(stats))
+	}
+	return rr.Code, stats
+}
+
+func TestJobStatsControllers(t *testing.T) {
+	channel, _ := data.NewChannel("channel-for-job-stats", consumerTestChannel.Token)
+	channel, err := channelRepo.Store(channel)
+	assert.Nil(t, err)
+	callbackURL, _ := url.Parse("https://imytech.net/")
+	consumer, _ := data.NewConsumer(channel, "job-stats-consumer", "job-stats-consumer-token", callbackURL)
+	consumer, err = consumerRepo.Store(consumer)
+	assert.Nil(t, err)
+	createDeadJobsForDLQTest(t, channel, consumer, "connection refused")
+	message, _ := data.NewMessage(channel, messageProducer, messagePayload, messageContentType)
+	assert.Nil(t, messageRepo.Create(message))
+	job, _ := data.NewDeliveryJob(message, consumer)
+	assert.Nil(t, djRepo.DispatchMessage(message, job))
+	assert.Nil(t, djRepo.MarkJobInflight(job))
+	assert.Nil(t, djRepo.MarkJobDelivered(job))
+
+	consumerController := NewConsumerStatsController(consumerRepo, djRepo)
+	channelController := NewChannelStatsController(channelRepo, djRepo)
+	testRouter := createTestRouter(consumerController, channelController)
+	consumerParams := []httprouter.Param{getRouterParam(channel.ChannelID), {Key: consumerIDPathParamKey, Value: consumer.ConsumerID}}
+	consumerStatsURL := consumerController.FormatAsRelativeLink(consumerParams...)
+	channelStatsURL := channelController.FormatAsRelativeLink(getRouterParam(channel.ChannelID))
+	assert.Equal(t, "/channel/channel-for-job-stats/consumer/job-stats-consumer/stats", consumerStatsURL)
+	assert.Equal(t, "/channel/channel-for-job-stats/stats", channelStatsURL)
+	assert.Equal(t, consumerStatsPath, consumerController.GetPath())
+	assert.Equal(t, channelStatsPath, channelController.GetPath())
+	t.Run("200", func(t *testing.T) {
+		for _, path := range []string{consumerStatsURL, channelStatsURL} {
+			code, stats := getJobStats(t, testRouter, path)
+			assert.Equal(t, http.StatusOK, code, path)
+			assert.Equal(t, map[string]uint{data.JobDeadStr: 1, data.JobDeliveredStr: 1}, stats.JobCounts)
+			assert.Equal(t, 1/60.0, stats.DeliveredPerMinute["60m"])
+			assert.Equal(t, 1.0, stats.DeliveredPerMinute["1m"])
+			assert.Equal(t, 0.5, stats.SuccessRate)
+			assert.Equal(t, uint(2), stats.SampledJobCount)
+			assert.Equal(t, float64(0), stats.OldestQueuedJobAgeSeconds)
+			assert.Nil(t, stats.NextRetryAt)
+			assert.False(t, stats.ComputedAt.IsZero())
+		}
+	})
+	t.Run("404", func(t *testing.T) {
+		for _, path := range []string{"/channel/no-such-channel/stats", "/channel/channel-for-job-stats/consumer/no-such-consumer/stats"} {
+			code, _ := getJobStats(t, testRouter, path)
+			assert.Equal(t, http.StatusNotFound, code, path)
+		}
+	})
+	t.Run("500", func(t *testing.T) {
+		mockDJRepo := new(storagemocks.DeliveryJobRepository)
+		mockDJRepo.On("GetJobStatsForConsumer", mock.Anything).Return(nil, errors.New("stats error"))
+		mockDJRepo.On("GetJobStatsForChannel", mock.Anything).Return(nil, errors.New("stats error"))
+		errRouter := createTestRouter(NewConsumerStatsController(consumerRepo, mockDJRepo), NewChannelStatsController(channelRepo, mockDJRepo))
+		for _, path := range []string{consumerStatsURL, channelStatsURL} {
+			code, _ := getJobStats(t, errRouter, path)
+			assert.Equal(t, http.StatusInternalServerError, code, path)
+		}
+		mockDJRepo.AssertExpectations(t)
+	})
+}
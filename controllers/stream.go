@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog/hlog"
+
+	"github.com/newscred/webhook-broker/config"
+	"github.com/newscred/webhook-broker/dispatcher"
+	"github.com/newscred/webhook-broker/storage"
+)
+
+const (
+	consumerStreamPath             = consumerPath + "/stream"
+	consumerStreamAckPath          = consumerStreamPath + "/ack"
+	headerConsumerToken            = "X-Broker-Consumer-Token"
+	headerLastEventID              = "Last-Event-ID"
+	headerCacheControl             = "Cache-Control"
+	streamContentType              = "text/event-stream"
+	streamMessageEventName         = "message"
+	streamBatchSize                = 25
+	eventIDFormParamName           = "eventId"
+	consumerTokenQueryParamName    = "token"
+	streamEndMarginInPollIntervals = 2
+)
+
+// ConsumerStreamController represents the Server-Sent Events endpoint streaming consumers hold open to receive messages
+type ConsumerStreamController struct {
+	ConsumerRepo   storage.ConsumerRepository
+	Streamer       dispatcher.ConsumerStreamer
+	PollInterval   time.Duration
+	StreamDuration time.Duration
+}
+
+// NewConsumerStreamController creates and returns a new instance of ConsumerStreamController; the jobs are polled for at the rational delay
+// in case they were dispatched by another instance and streams are closed before the HTTP write timeout would cut them
+func NewConsumerStreamController(consumerRepo storage.ConsumerRepository, streamer dispatcher.ConsumerStreamer, brokerConfig config.BrokerConfig, httpConfig config.HTTPConfig) *ConsumerStreamController {
+	pollInterval := brokerConfig.GetRationalDelay()
+	streamDuration := httpConfig.GetHTTPWriteTimeout() - streamEndMarginInPollIntervals*pollInterval
+	if streamDuration <= 0 {
+		streamDuration = httpConfig.GetHTTPWriteTimeout()
+	}
+	return &ConsumerStreamController{ConsumerRepo: consumerRepo, Streamer: streamer, PollInterval: pollInterval, StreamDuration: streamDuration}
+}
+
+// Get implements the GET /channel/:channelId/consumer/:consumerId/stream endpoint; the consumer authenticates with its token in the
+// `X-Broker-Consumer-Token` header or the `token` query param, since browsers' EventSource can not set headers. Each message is sent as a
+// `message` event whose ID is to be acked. On reconnect with `Last-Event-ID` the events sent after it and not acked yet are sent again before
+// the backlog is resumed.
+func (controller *ConsumerStreamController) Get(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	consumer, err := controller.ConsumerRepo.Get(findParam(params, channelIDPathParamKey), findParam(params, consumerIDPathParamKey))
+	switch err {
+	case nil:
+	case sql.ErrNoRows:
+		writeNotFound(w)
+		return
+	default:
+		writeErr(w, err)
+		return
+	}
+	token := r.Header.Get(headerConsumerToken)
+	if len(token) <= 0 {
+		token = r.URL.Query().Get(consumerTokenQueryParamName)
+	}
+	if token != consumer.Token {
+		writeStatus(w, http.StatusBadRequest, ErrBadRequestForStreamToken)
+		return
+	}
+	if !consumer.IsStreaming() {
+		writeStatus(w, http.StatusConflict, ErrConsumerNotStreaming)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeErr(w, ErrStreamingUnsupported)
+		return
+	}
+	wake, unsubscribe := controller.Streamer.Subscribe(consumer)
+	defer unsubscribe()
+	w.Header().Set(headerContentType, streamContentType)
+	w.Header().Set(headerCacheControl, "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	logger := hlog.FromRequest(r)
+	if lastEventID := r.Header.Get(headerLastEventID); len(lastEventID) > 0 {
+		events, err := controller.Streamer.Unacked(consumer, lastEventID)
+		if err != nil {
+			logger.Error().Err(err).Msg("error - could not load unacked stream events")
+		}
+		if writeStreamEvents(w, flusher, events) != nil {
+			return
+		}
+	}
+	streamEnd := time.After(controller.StreamDuration)
+	for {
+		events, err := controller.Streamer.Next(consumer, streamBatchSize)
+		if err != nil {
+			logger.Error().Err(err).Msg("error - could not load stream events")
+		}
+		if writeStreamEvents(w, flusher, events) != nil {
+			return
+		}
+		if len(events) >= streamBatchSize {
+			continue
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-streamEnd:
+			return
+		case <-wake:
+		case <-time.After(controller.PollInterval):
+			// Comment line keeps proxies from timing out the idle connection and detects the client having gone away
+			if _, err := io.WriteString(w, ":\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeStreamEvents(w http.ResponseWriter, flusher http.Flusher, events []*dispatcher.StreamEvent) error {
+	for _, event := range events {
+		body, err := json.Marshal(event)
+		if err == nil {
+			_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, streamMessageEventName, body)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	flusher.Flush()
+	return nil
+}
+
+// GetPath returns the endpoint's path
+func (controller *ConsumerStreamController) GetPath() string {
+	return consumerStreamPath
+}
+
+// FormatAsRelativeLink formats this controllers URL with the parameters provided. Both `consumerId` and `channelId` params must be sent else it will return the templated URL
+func (controller *ConsumerStreamController) FormatAsRelativeLink(params ...httprouter.Param) string {
+	return formatURL(params, consumerStreamPath, channelIDPathParamKey, consumerIDPathParamKey)
+}
+
+// ConsumerStreamAckController represents the endpoint streaming consumers ack the events they received with
+type ConsumerStreamAckController struct {
+	ConsumerRepo storage.ConsumerRepository
+	Streamer     dispatcher.ConsumerStreamer
+}
+
+// NewConsumerStreamAckController creates and returns a new instance of ConsumerStreamAckController
+func NewConsumerStreamAckController(consumerRepo storage.ConsumerRepository, streamer dispatcher.ConsumerStreamer) *ConsumerStreamAckController {
+	return &ConsumerStreamAckController{ConsumerRepo: consumerRepo, Streamer: streamer}
+}
+
+// Post implements the POST /channel/:channelId/consumer/:consumerId/stream/ack endpoint; the `eventId` form param is the ID of the event
+// received and the job is marked delivered. Events not acked in time are retried, and eventually are dead, like any failed delivery.
+func (controller *ConsumerStreamAckController) Post(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	consumer := getConsumerForTokenAuthorizedPost(controller.ConsumerRepo, w, r, params)
+	if consumer == nil {
+		return
+	}
+	if !consumer.IsStreaming() {
+		writeStatus(w, http.StatusConflict, ErrConsumerNotStreaming)
+		return
+	}
+	eventID := r.PostFormValue(eventIDFormParamName)
+	if len(eventID) <= 0 {
+		writeStatus(w, http.StatusBadRequest, ErrBadRequestForStreamAck)
+		return
+	}
+	switch err := controller.Streamer.Ack(consumer, eventID); err {
+	case nil:
+		writeStatus(w, http.StatusNoContent, nil)
+	case dispatcher.ErrStreamEventNotFound:
+		writeNotFound(w)
+	case storage.ErrNoRowsUpdated:
+		writeStatus(w, http.StatusConflict, ErrStreamEventNotInflight)
+	default:
+		writeErr(w, err)
+	}
+}
+
+// GetPath returns the endpoint's path
+func (controller *ConsumerStreamAckController) GetPath() string {
+	return consumerStreamAckPath
+}
+
+// FormatAsRelativeLink formats this controllers URL with the parameters provided. Both `consumerId` and `channelId` params must be sent else it will return the templated URL
+func (controller *ConsumerStreamAckController) FormatAsRelativeLink(params ...httprouter.Param) string {
+	return formatURL(params, consumerStreamAckPath, channelIDPathParamKey, consumerIDPathParamKey)
+}
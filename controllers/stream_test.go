@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/newscred/webhook-broker/dispatcher"
+	dispatchermocks "github.com/newscred/webhook-broker/dispatcher/mocks"
+	"github.com/newscred/webhook-broker/storage"
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+func getMockedConsumerStreamer() *dispatchermocks.ConsumerStreamer {
+	mockStreamer := new(dispatchermocks.ConsumerStreamer)
+	var wake <-chan bool = make(chan bool)
+	mockStreamer.On("Subscribe", mock.Anything).Return(wake, func() {})
+	return mockStreamer
+}
+
+func getStreamTestController(streamer dispatcher.ConsumerStreamer) *ConsumerStreamController {
+	controller := NewConsumerStreamController(consumerRepo, streamer, configuration, configuration)
+	controller.PollInterval = 10 * time.Millisecond
+	controller.StreamDuration = 100 * time.Millisecond
+	return controller
+}
+
+func TestConsumerStreamController(t *testing.T) {
+	streamConsumer, _ := data.NewStreamConsumer(consumerTestChannel, "streaming-consumer", successfulGetTestToken)
+	_, err := consumerRepo.Store(streamConsumer)
+	assert.Nil(t, err)
+	pushConsumer, _ := data.NewConsumer(consumerTestChannel, "push-consumer-for-stream", successfulGetTestToken, callbackURL)
+	_, err = consumerRepo.Store(pushConsumer)
+	assert.Nil(t, err)
+	getStreamURL := func(consumerID string) string {
+		return getStreamTestController(nil).FormatAsRelativeLink(httprouter.Param{Key: channelIDPathParamKey, Value: consumerTestChannel.ChannelID},
+			httprouter.Param{Key: consumerIDPathParamKey, Value: consumerID})
+	}
+	streamURL := getStreamURL(streamConsumer.ConsumerID)
+	assert.Equal(t, "/channel/"+consumerTestChannel.ChannelID+"/consumer/"+streamConsumer.ConsumerID+"/stream", streamURL)
+	assert.Equal(t, consumerStreamPath, getStreamTestController(nil).GetPath())
+	events := []*dispatcher.StreamEvent{{ID: "job-1", MessageID: "message-1", Payload: "payload-1"}, {ID: "job-2", MessageID: "message-2", Payload: "payload-2"}}
+	t.Run("NotFound", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", getStreamURL("no-such-consumer"), nil)
+		createTestRouter(getStreamTestController(nil)).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+	t.Run("BadToken", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", streamURL+"?token=wrong", nil)
+		createTestRouter(getStreamTestController(nil)).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, ErrBadRequestForStreamToken.Error(), rr.Body.String())
+	})
+	t.Run("NotStreaming", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", getStreamURL(pushConsumer.ConsumerID), nil)
+		req.Header.Set(headerConsumerToken, successfulGetTestToken)
+		createTestRouter(getStreamTestController(nil)).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+	t.Run("Stream", func(t *testing.T) {
+		mockStreamer := getMockedConsumerStreamer()
+		mockStreamer.On("Next", mock.Anything, streamBatchSize).Return(events, nil).Once()
+		mockStreamer.On("Next", mock.Anything, streamBatchSize).Return([]*dispatcher.StreamEvent{}, errors.New("expected error")).Once()
+		mockStreamer.On("Next", mock.Anything, streamBatchSize).Return([]*dispatcher.StreamEvent{}, nil)
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", streamURL+"?token="+successfulGetTestToken, nil)
+		createTestRouter(getStreamTestController(mockStreamer)).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, streamContentType, rr.Header().Get(headerContentType))
+		body := rr.Body.String()
+		assert.True(t, strings.HasPrefix(body, "id: job-1\nevent: message\ndata: {\"ID\":\"job-1\",\"MessageID\":\"message-1\""))
+		assert.Contains(t, body, "id: job-2\nevent: message\n")
+		assert.Contains(t, body, ":\n\n")
+		mockStreamer.AssertNotCalled(t, "Unacked", mock.Anything, mock.Anything)
+	})
+	t.Run("Resume", func(t *testing.T) {
+		mockStreamer := getMockedConsumerStreamer()
+		mockStreamer.On("Unacked", mock.MatchedBy(func(consumer *data.Consumer) bool { return consumer.ConsumerID == streamConsumer.ConsumerID }), "job-0").Return(events[:1], nil)
+		mockStreamer.On("Next", mock.Anything, streamBatchSize).Return(events[1:], nil).Once()
+		mockStreamer.On("Next", mock.Anything, streamBatchSize).Return([]*dispatcher.StreamEvent{}, nil)
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", streamURL, nil)
+		req.Header.Set(headerConsumerToken, successfulGetTestToken)
+		req.Header.Set(headerLastEventID, "job-0")
+		createTestRouter(getStreamTestController(mockStreamer)).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		body := rr.Body.String()
+		assert.True(t, strings.Index(body, "id: job-1\n") < strings.Index(body, "id: job-2\n"))
+		mockStreamer.AssertExpectations(t)
+	})
+}
+
+func TestConsumerStreamAckController(t *testing.T) {
+	streamConsumer, _ := data.NewStreamConsumer(consumerTestChannel, "streaming-consumer-to-ack", successfulGetTestToken)
+	_, err := consumerRepo.Store(streamConsumer)
+	assert.Nil(t, err)
+	pushConsumer, _ := data.NewConsumer(consumerTestChannel, "push-consumer-to-ack", successfulGetTestToken, callbackURL)
+	_, err = consumerRepo.Store(pushConsumer)
+	assert.Nil(t, err)
+	mockStreamer := new(dispatchermocks.ConsumerStreamer)
+	mockStreamer.On("Ack", mock.Anything, "acked-job").Return(nil)
+	mockStreamer.On("Ack", mock.Anything, "unknown-job").Return(dispatcher.ErrStreamEventNotFound)
+	mockStreamer.On("Ack", mock.Anything, "retried-job").Return(storage.ErrNoRowsUpdated)
+	mockStreamer.On("Ack", mock.Anything, "failing-job").Return(errors.New("expected error"))
+	ackController := NewConsumerStreamAckController(consumerRepo, mockStreamer)
+	testRouter := createTestRouter(ackController)
+	getAckURL := func(consumerID string) string {
+		return ackController.FormatAsRelativeLink(httprouter.Param{Key: channelIDPathParamKey, Value: consumerTestChannel.ChannelID},
+			httprouter.Param{Key: consumerIDPathParamKey, Value: consumerID})
+	}
+	assert.Equal(t, "/channel/"+consumerTestChannel.ChannelID+"/consumer/"+streamConsumer.ConsumerID+"/stream/ack", getAckURL(streamConsumer.ConsumerID))
+	assert.Equal(t, consumerStreamAckPath, ackController.GetPath())
+	ack := func(consumerID, token, eventID string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", getAckURL(consumerID), nil)
+		req.Header.Add(headerContentType, formDataContentTypeHeaderValue)
+		req.PostForm = url.Values{consumerTokenFormParamName: {token}}
+		if len(eventID) > 0 {
+			req.PostForm.Set(eventIDFormParamName, eventID)
+		}
+		rr := httptest.NewRecorder()
+		testRouter.ServeHTTP(rr, req)
+		return rr
+	}
+	assert.Equal(t, http.StatusBadRequest, ack(streamConsumer.ConsumerID, "wrong token", "acked-job").Code)
+	assert.Equal(t, http.StatusConflict, ack(pushConsumer.ConsumerID, successfulGetTestToken, "acked-job").Code)
+	rr := ack(streamConsumer.ConsumerID, successfulGetTestToken, "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, ErrBadRequestForStreamAck.Error(), rr.Body.String())
+	assert.Equal(t, http.StatusNoContent, ack(streamConsumer.ConsumerID, successfulGetTestToken, "acked-job").Code)
+	assert.Equal(t, http.StatusNotFound, ack(streamConsumer.ConsumerID, successfulGetTestToken, "unknown-job").Code)
+	rr = ack(streamConsumer.ConsumerID, successfulGetTestToken, "retried-job")
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Equal(t, ErrStreamEventNotInflight.Error(), rr.Body.String())
+	assert.Equal(t, http.StatusInternalServerError, ack(streamConsumer.ConsumerID, successfulGetTestToken, "failing-job").Code)
+}
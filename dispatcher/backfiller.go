@@ -0,0 +1,52 @@
+package dispatcher
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/newscred/webhook-broker/storage"
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+const (
+	backfillIDLogFieldKey = "backfillId"
+)
+
+var (
+	startBackfills = func(msgDispatcher *MessageDispatcherImpl) {
+		defer genericPanicRecoveryFunc()
+		backfills := msgDispatcher.djRepo.GetBackfillsToRun()
+		for _, backfill := range backfills {
+			err := msgDispatcher.djRepo.LeaseBackfill(backfill, msgDispatcher.instanceID, msgDispatcher.replayLeaseDuration(0))
+			if err == nil {
+				go runBackfill(msgDispatcher, backfill)
+			} else if err != storage.ErrNoRowsUpdated {
+				log.Error().Err(err).Msg("error - could not lease backfill " + backfill.ID.String())
+			}
+		}
+	}
+
+	// runBackfill creates the jobs of the backfill spread at the replay pace; a backfill picked up after another broker left it midway
+	// continues with its next job due right away. The jobs are delivered by the workers like any other job.
+	runBackfill = func(msgDispatcher *MessageDispatcherImpl, backfill *data.Backfill) {
+		defer genericPanicRecoveryFunc()
+		pace := msgDispatcher.replayPace()
+		start := time.Now().Add(-1 * time.Duration(backfill.JobCount) * pace)
+		if err := msgDispatcher.djRepo.BackfillJobsForConsumer(backfill, start, pace, msgDispatcher.replayLeaseDuration(0)); err != nil {
+			log.Error().Err(err).Str(backfillIDLogFieldKey, backfill.ID.String()).Msg("error - could not create jobs of backfill")
+		}
+	}
+)
+
+func (msgDispatcher *MessageDispatcherImpl) runBackfills() {
+	for {
+		timer := time.After(msgDispatcher.rationalDelay)
+		select {
+		case <-msgDispatcher.backfillWorkerStop:
+			return
+		case <-timer:
+			startBackfills(msgDispatcher)
+		}
+	}
+}
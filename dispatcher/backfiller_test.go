@@ -0,0 +1,69 @@
+package dispatcher
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+func getBackfillTestFixture(t *testing.T, consumerID string, messageCount int) *data.Backfill {
+	channel, _ := data.NewChannel(consumerID+"-channel", "token")
+	channel, _ = dataAccessor.GetChannelRepository().Store(channel)
+	for index := 0; index < messageCount; index++ {
+		message, _ := data.NewMessage(channel, producer, consumerID+"-payload", "text/plain")
+		message.ReceivedAt = time.Now().Add(time.Duration(index-messageCount) * time.Minute)
+		assert.Nil(t, dataAccessor.GetMessageRepository().Create(message))
+		assert.Nil(t, dataAccessor.GetDeliveryJobRepository().DispatchMessage(message))
+	}
+	callbackURL, _ := url.Parse(consumers[0].CallbackURL)
+	consumer, err := data.NewConsumer(channel, consumerID, consumerToken, callbackURL)
+	assert.Nil(t, err)
+	consumer, err = dataAccessor.GetConsumerRepository().Store(consumer)
+	assert.Nil(t, err)
+	backfill, err := data.NewBackfill(consumer, time.Now().Add(-1*time.Hour))
+	assert.Nil(t, err)
+	assert.Nil(t, dataAccessor.GetDeliveryJobRepository().CreateBackfill(backfill))
+	return backfill
+}
+
+func TestRunBackfill(t *testing.T) {
+	msgDispatcher := getReplayTestDispatcher()
+	defer msgDispatcher.Stop()
+	backfill := getBackfillTestFixture(t, "backfill-run", 3)
+	assert.Nil(t, dataAccessor.GetDeliveryJobRepository().LeaseBackfill(backfill, msgDispatcher.instanceID, time.Minute))
+	runBackfill(msgDispatcher, backfill)
+	dbBackfill, err := dataAccessor.GetDeliveryJobRepository().GetBackfillForConsumer(backfill.Consumer)
+	assert.Nil(t, err)
+	assert.True(t, dbBackfill.JobsCreated)
+	assert.Equal(t, uint(3), dbBackfill.JobCount)
+	assert.Equal(t, uint(3), dbBackfill.QueuedCount)
+}
+
+func TestStartBackfills(t *testing.T) {
+	msgDispatcher := getReplayTestDispatcher()
+	defer msgDispatcher.Stop()
+	backfill := getBackfillTestFixture(t, "backfill-start", 1)
+	started := make(chan *data.Backfill, 10)
+	oldRunBackfill := runBackfill
+	runBackfill = func(msgDispatcher *MessageDispatcherImpl, backfill *data.Backfill) {
+		started <- backfill
+	}
+	defer func() { runBackfill = oldRunBackfill }()
+	startBackfills(msgDispatcher)
+	var startedBackfill *data.Backfill
+	for startedBackfill == nil || startedBackfill.ID != backfill.ID {
+		startedBackfill = <-started
+	}
+	assert.Equal(t, msgDispatcher.instanceID, startedBackfill.LeaseOwner)
+	assert.Equal(t, backfill.Consumer.ID, startedBackfill.Consumer.ID)
+	// Already leased backfill is not started again
+	startBackfills(msgDispatcher)
+	time.Sleep(50 * time.Millisecond)
+	for len(started) > 0 {
+		assert.NotEqual(t, backfill.ID, (<-started).ID)
+	}
+}
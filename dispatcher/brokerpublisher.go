@@ -0,0 +1,88 @@
+package dispatcher
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/rs/xid"
+
+	"github.com/newscred/webhook-broker/storage"
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+const (
+	brokerMessageContentType = "application/json"
+)
+
+// brokerPublisher publishes the broker's own messages, e.g. delivery receipts and system events, as the reserved broker producer to reserved
+// channels created on first use
+type brokerPublisher struct {
+	channelRepo  storage.ChannelRepository
+	producerRepo storage.ProducerRepository
+	msgRepo      storage.MessageRepository
+	dispatcher   MessageDispatcher
+	mutex        sync.Mutex
+	producer     *data.Producer
+	channels     map[string]*data.Channel
+}
+
+func newBrokerPublisher(configuration *Configuration, dispatcher MessageDispatcher) *brokerPublisher {
+	if configuration.ChannelRepo == nil || configuration.ProducerRepo == nil {
+		return nil
+	}
+	return &brokerPublisher{channelRepo: configuration.ChannelRepo, producerRepo: configuration.ProducerRepo, msgRepo: configuration.MsgRepo,
+		dispatcher: dispatcher, channels: make(map[string]*data.Channel)}
+}
+
+// getChannel ensures the broker producer and the reserved channel exist
+func (publisher *brokerPublisher) getChannel(channelID string) (*data.Channel, error) {
+	publisher.mutex.Lock()
+	defer publisher.mutex.Unlock()
+	if channel, ok := publisher.channels[channelID]; ok {
+		return channel, nil
+	}
+	if publisher.producer == nil {
+		producer, err := publisher.producerRepo.Get(data.BrokerProducerID)
+		if err != nil {
+			producer, _ = data.NewProducer(data.BrokerProducerID, xid.New().String())
+			producer, err = publisher.producerRepo.Store(producer)
+		}
+		if err != nil {
+			return nil, err
+		}
+		publisher.producer = producer
+	}
+	channel, err := publisher.channelRepo.Get(channelID)
+	if err != nil {
+		channel, _ = data.NewChannel(channelID, xid.New().String())
+		channel, err = publisher.channelRepo.Store(channel)
+	}
+	if err != nil {
+		return nil, err
+	}
+	publisher.channels[channelID] = channel
+	return channel, nil
+}
+
+// publish creates and dispatches the payload as JSON message; the message being published already, e.g. by another worker, is not an error
+func (publisher *brokerPublisher) publish(channel *data.Channel, messageID, routingKey string, priority uint, payload interface{}) error {
+	serialized, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	message, err := data.NewMessage(channel, publisher.producer, string(serialized), brokerMessageContentType)
+	if err != nil {
+		return err
+	}
+	message.MessageID = messageID
+	message.RoutingKey = routingKey
+	message.Priority = priority
+	err = publisher.msgRepo.Create(message)
+	if err == storage.ErrDuplicateMessageIDForChannel {
+		return nil
+	}
+	if err == nil {
+		publisher.dispatcher.Dispatch(message)
+	}
+	return err
+}
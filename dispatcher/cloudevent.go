@@ -0,0 +1,90 @@
+package dispatcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+const (
+	headerCloudEventSpecVersion     = "ce-specversion"
+	headerCloudEventID              = "ce-id"
+	headerCloudEventSource          = "ce-source"
+	headerCloudEventType            = "ce-type"
+	headerCloudEventSubject         = "ce-subject"
+	headerCloudEventTime            = "ce-time"
+	structuredCloudEventContentType = "application/cloudevents+json"
+)
+
+// structuredCloudEvent is the JSON format of a structured mode CloudEvent; exactly one of Data and DataBase64 is set
+type structuredCloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            string          `json:"time,omitempty"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data,omitempty"`
+	DataBase64      []byte          `json:"data_base64,omitempty"`
+}
+
+func formatCloudEventTime(value time.Time) string {
+	if value.IsZero() {
+		return ""
+	}
+	return value.Format(time.RFC3339Nano)
+}
+
+// setCloudEventHeaders sets the `ce-*` headers of a binary mode CloudEvent for the message
+func setCloudEventHeaders(header http.Header, message *data.Message) {
+	cloudEvent := message.GetCloudEvent()
+	// CloudEvents headers are lower case in the spec, so they are set as is instead of canonicalized
+	header[headerCloudEventSpecVersion] = []string{data.CloudEventsSpecVersion}
+	header[headerCloudEventID] = []string{message.MessageID}
+	header[headerCloudEventSource] = []string{cloudEvent.Source}
+	header[headerCloudEventType] = []string{cloudEvent.Type}
+	if len(cloudEvent.Subject) > 0 {
+		header[headerCloudEventSubject] = []string{cloudEvent.Subject}
+	}
+	if eventTime := formatCloudEventTime(cloudEvent.Time); len(eventTime) > 0 {
+		header[headerCloudEventTime] = []string{eventTime}
+	}
+}
+
+// newStructuredCloudEventBody reads and closes the payload and returns the body of a structured mode CloudEvent for the message; a JSON payload is
+// sent as `data` as is, a text payload as `data` string and any other payload as `data_base64`
+func newStructuredCloudEventBody(payload io.ReadCloser, message *data.Message) (io.ReadCloser, error) {
+	defer payload.Close()
+	content, err := ioutil.ReadAll(payload)
+	if err != nil {
+		return nil, err
+	}
+	cloudEvent := message.GetCloudEvent()
+	event := &structuredCloudEvent{SpecVersion: data.CloudEventsSpecVersion, ID: message.MessageID, Source: cloudEvent.Source, Type: cloudEvent.Type,
+		Subject: cloudEvent.Subject, Time: formatCloudEventTime(cloudEvent.Time), DataContentType: message.ContentType}
+	mediaType, _, _ := mime.ParseMediaType(message.ContentType)
+	switch {
+	case (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")) && json.Valid(content):
+		event.Data = content
+	case strings.HasPrefix(mediaType, "text/"):
+		event.Data, err = json.Marshal(string(content))
+	default:
+		event.DataBase64 = content
+	}
+	var body []byte
+	if err == nil {
+		body, err = json.Marshal(event)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(body)), nil
+}
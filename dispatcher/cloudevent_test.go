@@ -0,0 +1,119 @@
+package dispatcher
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+type cloudEventRequest struct {
+	header http.Header
+	body   string
+}
+
+func callCloudEventConsumer(t *testing.T, format data.DeliveryFormat, msg *data.Message) *cloudEventRequest {
+	requests := make(chan *cloudEventRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests <- &cloudEventRequest{header: r.Header, body: string(body)}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	callbackURL, _ := url.Parse(server.URL)
+	consumer, _ := data.NewConsumer(channel, "cloudevent-test-consumer", "cloudevent-test-token", callbackURL)
+	consumer.DeliveryFormat = format
+	deliveryJob, _ := data.NewDeliveryJob(msg, consumer)
+	assert.Nil(t, callConsumer(server.Client(), nil, "cloudevent-test-request", log.Logger, NewJob(deliveryJob)))
+	return <-requests
+}
+
+func TestCallConsumerCloudEvents(t *testing.T) {
+	eventTime := time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)
+	cloudEventMsg, _ := data.NewMessage(channel, producer, `{"key": "cloudevent"}`, "application/json")
+	cloudEventMsg.CloudEvent = data.CloudEvent{Type: "com.example.order.created", Source: "/orders", Subject: "order-1", Time: eventTime}
+	t.Run("Raw", func(t *testing.T) {
+		request := callCloudEventConsumer(t, data.RawDelivery, cloudEventMsg)
+		assert.Equal(t, `{"key": "cloudevent"}`, request.body)
+		assert.Equal(t, "application/json", request.header.Get(headerContentType))
+		assert.Empty(t, request.header.Get(headerCloudEventID))
+	})
+	t.Run("Binary", func(t *testing.T) {
+		request := callCloudEventConsumer(t, data.BinaryCloudEventDelivery, cloudEventMsg)
+		assert.Equal(t, `{"key": "cloudevent"}`, request.body)
+		assert.Equal(t, "application/json", request.header.Get(headerContentType))
+		assert.Equal(t, "1.0", request.header.Get(headerCloudEventSpecVersion))
+		assert.Equal(t, cloudEventMsg.MessageID, request.header.Get(headerCloudEventID))
+		assert.Equal(t, "/orders", request.header.Get(headerCloudEventSource))
+		assert.Equal(t, "com.example.order.created", request.header.Get(headerCloudEventType))
+		assert.Equal(t, "order-1", request.header.Get(headerCloudEventSubject))
+		assert.Equal(t, "2026-10-01T10:00:00Z", request.header.Get(headerCloudEventTime))
+		assert.Equal(t, cloudEventMsg.MessageID, request.header.Get(headerMessageID))
+	})
+	t.Run("BinaryForPlainMessage", func(t *testing.T) {
+		msg, _ := data.NewMessage(channel, producer, "plain", "text/plain")
+		request := callCloudEventConsumer(t, data.BinaryCloudEventDelivery, msg)
+		assert.Equal(t, "plain", request.body)
+		assert.Equal(t, data.DefaultCloudEventType, request.header.Get(headerCloudEventType))
+		assert.Equal(t, "/producer/"+producer.ProducerID, request.header.Get(headerCloudEventSource))
+		assert.Empty(t, request.header.Get(headerCloudEventSubject))
+		eventTime, err := time.Parse(time.RFC3339Nano, request.header.Get(headerCloudEventTime))
+		assert.Nil(t, err)
+		assert.True(t, msg.ReceivedAt.Equal(eventTime))
+	})
+	t.Run("Structured", func(t *testing.T) {
+		request := callCloudEventConsumer(t, data.StructuredCloudEventDelivery, cloudEventMsg)
+		assert.Equal(t, structuredCloudEventContentType, request.header.Get(headerContentType))
+		event := make(map[string]interface{})
+		assert.Nil(t, json.Unmarshal([]byte(request.body), &event))
+		assert.Equal(t, "1.0", event["specversion"])
+		assert.Equal(t, cloudEventMsg.MessageID, event["id"])
+		assert.Equal(t, "/orders", event["source"])
+		assert.Equal(t, "com.example.order.created", event["type"])
+		assert.Equal(t, "order-1", event["subject"])
+		assert.Equal(t, "2026-10-01T10:00:00Z", event["time"])
+		assert.Equal(t, "application/json", event["datacontenttype"])
+		assert.Equal(t, map[string]interface{}{"key": "cloudevent"}, event["data"])
+		assert.NotContains(t, event, "data_base64")
+	})
+	t.Run("StructuredText", func(t *testing.T) {
+		msg, _ := data.NewMessage(channel, producer, "plain text", "text/plain; charset=utf-8")
+		request := callCloudEventConsumer(t, data.StructuredCloudEventDelivery, msg)
+		event := make(map[string]interface{})
+		assert.Nil(t, json.Unmarshal([]byte(request.body), &event))
+		assert.Equal(t, "plain text", event["data"])
+		assert.NotContains(t, event, "subject")
+	})
+	t.Run("StructuredBinary", func(t *testing.T) {
+		msg, _ := data.NewMessage(channel, producer, "\x00\x01binary", "application/octet-stream")
+		request := callCloudEventConsumer(t, data.StructuredCloudEventDelivery, msg)
+		event := make(map[string]interface{})
+		assert.Nil(t, json.Unmarshal([]byte(request.body), &event))
+		assert.Equal(t, "AAFiaW5hcnk=", event["data_base64"])
+		assert.NotContains(t, event, "data")
+	})
+}
+
+type errReadCloser struct{}
+
+func (errReadCloser) Read(p []byte) (int, error) { return 0, errors.New("read failed") }
+func (errReadCloser) Close() error               { return nil }
+
+func TestNewStructuredCloudEventBodyReadError(t *testing.T) {
+	msg, _ := data.NewMessage(channel, producer, "payload", "text/plain")
+	_, err := newStructuredCloudEventBody(errReadCloser{}, msg)
+	assert.NotNil(t, err)
+	body, err := newStructuredCloudEventBody(ioutil.NopCloser(strings.NewReader("payload")), msg)
+	assert.Nil(t, err)
+	assert.NotNil(t, body)
+}
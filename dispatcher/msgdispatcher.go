@@ -2,10 +2,12 @@ package dispatcher
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"sync"
 	"time"
 
+	"github.com/rs/xid"
 	"github.com/rs/zerolog/log"
 
 	"github.com/newscred/webhook-broker/config"
@@ -17,6 +19,10 @@ const (
 	panicString = "parameters null"
 )
 
+var (
+	errMessageInInvalidState = errors.New("message is not in a valid state to dispatch")
+)
+
 // MessageDispatcher is the contract for dispatching message
 type MessageDispatcher interface {
 	Dispatch(message *data.Message)
@@ -29,6 +35,8 @@ type MessageDispatcherImpl struct {
 	djRepo                            storage.DeliveryJobRepository
 	lockRepo                          storage.LockRepository
 	msgRepo                           storage.MessageRepository
+	replayRepo                        storage.ReplayRepository
+	blobStore                         storage.BlobStore
 	workerPool                        chan chan *Job
 	workers                           []*Worker
 	jobQueue                          chan *Job
@@ -40,26 +48,47 @@ type MessageDispatcherImpl struct {
 	messageRecoverWorkerStop          chan bool
 	jobRecoverStaleInflightWorkerStop chan bool
 	jobRecoverRetryWorkerStop         chan bool
+	replayWorkerStop                  chan bool
+	backfillWorkerStop                chan bool
+	payloadRecompressionStop          chan bool
 	recoveryWorkersEnabled            bool
+	instanceID                        string
+	undispatchableMessages            map[string]bool
+	systemEvents                      *SystemEventPublisherImpl
+	receipts                          *receiptSender
+	streams                           *streamHub
 }
 
 // Dispatch is responsible for dispatching delivery jobs for the message
 func (msgDispatcher *MessageDispatcherImpl) Dispatch(message *data.Message) {
-	if message == nil || !message.IsInValidState() {
+	if message == nil {
 		return
 	}
+	msgDispatcher.dispatch(message)
+}
+
+func (msgDispatcher *MessageDispatcherImpl) dispatch(message *data.Message) error {
+	if !message.IsInValidState() {
+		return errMessageInInvalidState
+	}
 	jobs, err := createJobs(msgDispatcher, message)
 	if err == nil {
 		err = msgDispatcher.djRepo.DispatchMessage(message, jobs...)
 	}
 	if err == nil {
 		for _, job := range jobs {
-			queueJob(msgDispatcher, job)
+			// Jobs of paused consumers stay queued until the consumer is resumed; jobs of streaming consumers wait for their connections
+			if job.Listener.IsStreaming() {
+				msgDispatcher.streams.notify(job.Listener.ID.String())
+			} else if job.Listener.IsDeliverable() {
+				queueJob(msgDispatcher, job)
+			}
 		}
 	}
 	if err != nil {
 		log.Error().Err(err).Msg("error dispatching")
 	}
+	return err
 }
 
 func (msgDispatcher *MessageDispatcherImpl) startMessageDispatcher() {
@@ -78,7 +107,8 @@ var (
 		channelID := message.BroadcastedTo.ChannelID
 		consumers := make([]*data.Consumer, 0)
 		page := data.NewPagination(nil, nil)
-		more := true
+		// Messages yet to be dispatched when their channel or producer got deleted are not delivered to anyone
+		more := !message.BroadcastedTo.IsDeleted() && (message.ProducedBy == nil || !message.ProducedBy.IsDeleted())
 		var err error
 		for more {
 			var consumersPage []*data.Consumer
@@ -87,10 +117,14 @@ var (
 			page.Previous = nil
 			consumers = append(consumers, consumersPage...)
 		}
-		jobs := make([]*data.DeliveryJob, len(consumers))
-		for index, consumer := range consumers {
-			if err == nil {
-				jobs[index], err = data.NewDeliveryJob(message, consumer)
+		jobs := make([]*data.DeliveryJob, 0, len(consumers))
+		for _, consumer := range consumers {
+			// Consumers yet to verify their callback URL do not receive messages published in the meantime, neither do consumers not subscribed to
+			// the message's routing key
+			if err == nil && consumer.IsVerified() && consumer.IsSubscribedTo(message) {
+				var job *data.DeliveryJob
+				job, err = data.NewDeliveryJob(message, consumer)
+				jobs = append(jobs, job)
 			}
 		}
 		return jobs, err
@@ -111,23 +145,34 @@ var (
 		}
 	}
 
-	attemptMessageDispatch = func(msgDispatcher MessageDispatcher, message *data.Message) (err error) {
-		msgDispatcher.Dispatch(message)
-		return err
+	attemptMessageDispatch = func(msgDispatcher *MessageDispatcherImpl, message *data.Message) (err error) {
+		return msgDispatcher.dispatch(message)
 	}
 
 	recoverMessagesNotYetDispatched = func(msgDispatcher *MessageDispatcherImpl) {
 		defer genericPanicRecoveryFunc()
 		msgDispatcher.lockRepo.TimeoutLocks(msgDispatcher.rationalDelay)
 		messages := msgDispatcher.msgRepo.GetMessagesNotDispatchedForCertainPeriod(msgDispatcher.rationalDelay)
+		// Messages still failing to dispatch are only reported the first pass they fail in; the event's ID being the message's keeps other
+		// brokers and restarts from reporting it again
+		undispatchableMessages := make(map[string]bool)
 		for _, message := range messages {
+			var dispatchErr error
 			err := inLockRun(msgDispatcher.lockRepo, message, func() error {
-				return attemptMessageDispatch(msgDispatcher, message)
+				dispatchErr = attemptMessageDispatch(msgDispatcher, message)
+				return dispatchErr
 			})
+			if dispatchErr != nil {
+				if !msgDispatcher.undispatchableMessages[message.ID.String()] {
+					msgDispatcher.systemEvents.messageUndispatchable(message, dispatchErr)
+				}
+				undispatchableMessages[message.ID.String()] = true
+			}
 			if err != nil {
 				log.Error().Err(err).Msg("error - could ensure dispatch from recover worker " + message.MessageID)
 			}
 		}
+		msgDispatcher.undispatchableMessages = undispatchableMessages
 	}
 
 	computeEarliestDelta = func(retryAttempt uint, brokerConfig config.BrokerConfig) time.Duration {
@@ -156,8 +201,12 @@ var (
 		defer genericPanicRecoveryFunc()
 		jobs := msgDispatcher.djRepo.GetJobsInflightSince(msgDispatcher.stopTimeout + msgDispatcher.rationalDelay)
 		for _, job := range jobs {
-			// Ignore max retry intentionally since we are recovering likely from a process crash during delivery.
+			// Ignore max retry intentionally since we are recovering likely from a process crash during delivery; streamed jobs though were
+			// not acked by the consumer in time, so they fail as any other delivery attempt.
 			err := inLockRun(msgDispatcher.lockRepo, job, func() error {
+				if job.Listener != nil && job.Listener.IsStreaming() {
+					return msgDispatcher.failUnackedStreamJob(job)
+				}
 				msgDispatcher.djRepo.MarkJobRetry(job, computeEarliestDelta(job.RetryAttemptCount+1, msgDispatcher.brokerConfig))
 				return nil
 			})
@@ -226,6 +275,9 @@ func (msgDispatcher *MessageDispatcherImpl) StartDispatcher() {
 		go msgDispatcher.ensureMessageDispatched()
 		go msgDispatcher.recoverStaleInflight()
 		go msgDispatcher.retryJob()
+		go msgDispatcher.runReplays()
+		go msgDispatcher.runBackfills()
+		go recompressPayloads(msgDispatcher)
 	}
 }
 
@@ -246,6 +298,10 @@ func (msgDispatcher *MessageDispatcherImpl) Stop() {
 				msgDispatcher.messageRecoverWorkerStop <- true
 				msgDispatcher.jobRecoverRetryWorkerStop <- true
 				msgDispatcher.jobRecoverStaleInflightWorkerStop <- true
+				// closing instead of sending so that running replays are signalled as well
+				close(msgDispatcher.replayWorkerStop)
+				msgDispatcher.backfillWorkerStop <- true
+				close(msgDispatcher.payloadRecompressionStop)
 			}
 			wg.Done()
 		}()
@@ -290,13 +346,17 @@ type Configuration struct {
 	ConsumerRepo             storage.ConsumerRepository
 	LockRepo                 storage.LockRepository
 	MsgRepo                  storage.MessageRepository
+	ReplayRepo               storage.ReplayRepository
+	ChannelRepo              storage.ChannelRepository
+	ProducerRepo             storage.ProducerRepository
+	BlobStore                storage.BlobStore
 	BrokerConfig             config.BrokerConfig
 	ConsumerConnectionConfig config.ConsumerConnectionConfig
 }
 
 // NewMessageDispatcher retrieves new instance of MessageDispatcher
 func NewMessageDispatcher(configuration *Configuration) MessageDispatcher {
-	if configuration.DeliveryJobRepo == nil || configuration.ConsumerRepo == nil || configuration.MsgRepo == nil || configuration.LockRepo == nil {
+	if configuration.DeliveryJobRepo == nil || configuration.ConsumerRepo == nil || configuration.MsgRepo == nil || configuration.LockRepo == nil || configuration.ReplayRepo == nil {
 		panic(panicString)
 	}
 	if configuration.BrokerConfig == nil || configuration.ConsumerConnectionConfig == nil {
@@ -312,10 +372,17 @@ func NewMessageDispatcher(configuration *Configuration) MessageDispatcher {
 		workerPool: make(chan chan *Job, brokerConfig.GetMaxWorkers()), jobPriorityQueue: NewJobPriorityQueue(), messageRecoverWorkerStop: make(chan bool),
 		jobQueue: make(chan *Job, brokerConfig.GetMaxMessageQueueSize()), rationalDelay: brokerConfig.GetRationalDelay(), lockRepo: lockRepo,
 		recoveryWorkersEnabled: brokerConfig.IsRecoveryWorkersEnabled(), jobRecoverStaleInflightWorkerStop: make(chan bool), jobRecoverRetryWorkerStop: make(chan bool),
-		brokerConfig: brokerConfig}
+		brokerConfig: brokerConfig, replayRepo: configuration.ReplayRepo, blobStore: configuration.BlobStore, replayWorkerStop: make(chan bool), backfillWorkerStop: make(chan bool), payloadRecompressionStop: make(chan bool), instanceID: xid.New().String(),
+		streams: newStreamHub()}
+	publisher := newBrokerPublisher(configuration, dispatcherImpl)
+	receipts := newReceiptSender(configuration, publisher)
+	dispatcherImpl.receipts = receipts
+	dispatcherImpl.systemEvents = newSystemEventPublisher(publisher)
 	workers := make([]*Worker, brokerConfig.GetMaxWorkers())
 	for i := 0; i < len(workers); i++ {
-		worker := NewWorker(dispatcherImpl.workerPool, consumerConfig, brokerConfig, djRepo)
+		worker := NewWorker(dispatcherImpl.workerPool, consumerConfig, brokerConfig, djRepo, configuration.BlobStore)
+		worker.receipts = receipts
+		worker.systemEvents = dispatcherImpl.systemEvents
 		worker.Start()
 		workers[i] = &worker
 	}
@@ -146,6 +146,7 @@ func getMockedBrokerConfig(workerEnabled ...interface{}) *configmocks.BrokerConf
 	}
 	mockedConfig.On("GetRationalDelay").Return(100 * time.Millisecond)
 	mockedConfig.On("GetMaxRetry").Return(uint8(5))
+	mockedConfig.On("GetResumeCatchUpRate").Return(uint(0))
 	if len(workerEnabled) <= 1 {
 		mockedConfig.On("GetRetryBackoffDelays").Return([]time.Duration{5 * time.Second})
 	} else {
@@ -168,6 +169,7 @@ func getCompleteDispatcherConfiguration(msgRepo storage.MessageRepository, djRep
 		ConsumerConnectionConfig: consumerConfig,
 		LockRepo:                 lockRepo,
 		MsgRepo:                  msgRepo,
+		ReplayRepo:               dataAccessor.GetReplayRepository(),
 	}
 }
 
@@ -203,6 +205,15 @@ func TestNewMessageDispatcher(t *testing.T) {
 		lockRepo := new(storagemocks.LockRepository)
 		assert.NotNil(t, NewMessageDispatcher(getDispatcherConfiguration(nil, cRepo, mockBrokerConfig, mockConsumerConfig, lockRepo)))
 	})
+	t.Run("ReplayRepoNil", func(t *testing.T) {
+		t.Parallel()
+		defer deferFunc()
+		mockBrokerConfig := new(configmocks.BrokerConfig)
+		mockConsumerConfig := new(configmocks.ConsumerConnectionConfig)
+		configuration := getDispatcherConfiguration(new(storagemocks.DeliveryJobRepository), new(storagemocks.ConsumerRepository), mockBrokerConfig, mockConsumerConfig, new(storagemocks.LockRepository))
+		configuration.ReplayRepo = nil
+		assert.NotNil(t, NewMessageDispatcher(configuration))
+	})
 	t.Run("ConsumerRepoNil", func(t *testing.T) {
 		t.Parallel()
 		defer deferFunc()
@@ -545,3 +556,103 @@ func TestJobWorkers(t *testing.T) {
 		}
 	})
 }
+
+func TestDispatch_PausedConsumerJobsStayQueued(t *testing.T) {
+	pausedChannel, _ := data.NewChannel("paused-dispatch-test-channel", "token")
+	pausedChannel, _ = dataAccessor.GetChannelRepository().Store(pausedChannel)
+	callbackURL, _ := url.Parse(consumers[0].CallbackURL)
+	activeConsumer, _ := data.NewConsumer(pausedChannel, "dispatch-active-consumer", consumerToken, callbackURL)
+	activeConsumer, _ = dataAccessor.GetConsumerRepository().Store(activeConsumer)
+	pausedConsumer, _ := data.NewConsumer(pausedChannel, "dispatch-paused-consumer", consumerToken, callbackURL)
+	pausedConsumer, _ = dataAccessor.GetConsumerRepository().Store(pausedConsumer)
+	assert.Nil(t, dataAccessor.GetConsumerRepository().SetPaused(pausedConsumer, true))
+	msgDispatcher := NewMessageDispatcher(getCompleteDispatcherConfiguration(dataAccessor.GetMessageRepository(), dataAccessor.GetDeliveryJobRepository(), dataAccessor.GetConsumerRepository(), getMockedBrokerConfig(), getMockedConsumerConfig(), dataAccessor.GetLockRepository())).(*MessageDispatcherImpl)
+	defer msgDispatcher.Stop()
+	oldQueueJob := queueJob
+	defer func() { queueJob = oldQueueJob }()
+	queuedFor := make([]string, 0)
+	queueJob = func(msgDispatcher *MessageDispatcherImpl, job *data.DeliveryJob) {
+		queuedFor = append(queuedFor, job.Listener.ConsumerID)
+	}
+	msg, _ := data.NewMessage(pausedChannel, producer, "payload", "text/plain")
+	assert.Nil(t, dataAccessor.GetMessageRepository().Create(msg))
+	msgDispatcher.Dispatch(msg)
+	assert.Equal(t, []string{activeConsumer.ConsumerID}, queuedFor)
+	jobs, _, err := dataAccessor.GetDeliveryJobRepository().GetJobsForMessage(msg, data.NewPagination(nil, nil))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(jobs))
+	for _, job := range jobs {
+		assert.Equal(t, data.JobQueued, job.Status)
+	}
+	// Once resumed, the backlog is only queued for delivery by the retry recovery worker, which this dispatcher does not run on its own
+	assert.Nil(t, dataAccessor.GetDeliveryJobRepository().RescheduleQueuedJobsForConsumer(pausedConsumer, time.Now().Add(-1*time.Second), 0))
+	assert.Nil(t, dataAccessor.GetConsumerRepository().SetPaused(pausedConsumer, false))
+	time.Sleep(2 * msgDispatcher.rationalDelay)
+	assert.Equal(t, []string{activeConsumer.ConsumerID}, queuedFor)
+	retryQueuedJobs(msgDispatcher)
+	assert.Contains(t, queuedFor, pausedConsumer.ConsumerID)
+}
+
+func TestDispatch_RoutingKeyPattern(t *testing.T) {
+	routedChannel, _ := data.NewChannel("routed-dispatch-test-channel", "token")
+	routedChannel, _ = dataAccessor.GetChannelRepository().Store(routedChannel)
+	callbackURL, _ := url.Parse(consumers[0].CallbackURL)
+	patterns := map[string]string{"dispatch-all-consumer": "", "dispatch-user-consumer": "user.*", "dispatch-user-tree-consumer": "user.#", "dispatch-order-consumer": "order.#"}
+	for consumerID, pattern := range patterns {
+		consumer, _ := data.NewConsumer(routedChannel, consumerID, consumerToken, callbackURL)
+		consumer.RoutingKeyPattern = pattern
+		_, err := dataAccessor.GetConsumerRepository().Store(consumer)
+		assert.Nil(t, err)
+	}
+	msgDispatcher := NewMessageDispatcher(getCompleteDispatcherConfiguration(dataAccessor.GetMessageRepository(), dataAccessor.GetDeliveryJobRepository(), dataAccessor.GetConsumerRepository(), getMockedBrokerConfig(), getMockedConsumerConfig(), dataAccessor.GetLockRepository())).(*MessageDispatcherImpl)
+	defer msgDispatcher.Stop()
+	oldQueueJob := queueJob
+	defer func() { queueJob = oldQueueJob }()
+	queueJob = func(msgDispatcher *MessageDispatcherImpl, job *data.DeliveryJob) {}
+	expectations := map[string][]string{
+		"user.created.v2": {"dispatch-all-consumer", "dispatch-user-tree-consumer"},
+		"user.created":    {"dispatch-all-consumer", "dispatch-user-consumer", "dispatch-user-tree-consumer"},
+		"":                {"dispatch-all-consumer"},
+	}
+	for routingKey, expectedConsumerIDs := range expectations {
+		msg, _ := data.NewMessage(routedChannel, producer, "payload", "text/plain")
+		msg.RoutingKey = routingKey
+		assert.Nil(t, dataAccessor.GetMessageRepository().Create(msg))
+		msgDispatcher.Dispatch(msg)
+		jobs, _, err := dataAccessor.GetDeliveryJobRepository().GetJobsForMessage(msg, data.NewPagination(nil, nil))
+		assert.Nil(t, err)
+		consumerIDs := make([]string, 0, len(jobs))
+		for _, job := range jobs {
+			consumerIDs = append(consumerIDs, job.Listener.ConsumerID)
+		}
+		assert.ElementsMatch(t, expectedConsumerIDs, consumerIDs, routingKey)
+	}
+}
+
+func TestDispatch_DeletedChannel(t *testing.T) {
+	deletedChannel, _ := data.NewChannel("deleted-dispatch-test-channel", "token")
+	deletedChannel, _ = dataAccessor.GetChannelRepository().Store(deletedChannel)
+	callbackURL, _ := url.Parse(consumers[0].CallbackURL)
+	consumer, _ := data.NewConsumer(deletedChannel, "dispatch-deleted-channel-consumer", consumerToken, callbackURL)
+	_, err := dataAccessor.GetConsumerRepository().Store(consumer)
+	assert.Nil(t, err)
+	msgDispatcher := NewMessageDispatcher(getCompleteDispatcherConfiguration(dataAccessor.GetMessageRepository(), dataAccessor.GetDeliveryJobRepository(), dataAccessor.GetConsumerRepository(), getMockedBrokerConfig(), getMockedConsumerConfig(), dataAccessor.GetLockRepository())).(*MessageDispatcherImpl)
+	defer msgDispatcher.Stop()
+	oldQueueJob := queueJob
+	defer func() { queueJob = oldQueueJob }()
+	queueJob = func(msgDispatcher *MessageDispatcherImpl, job *data.DeliveryJob) {
+		assert.Fail(t, "job queued for message of deleted channel")
+	}
+	msg, _ := data.NewMessage(deletedChannel, producer, "payload", "text/plain")
+	assert.Nil(t, dataAccessor.GetMessageRepository().Create(msg))
+	assert.Nil(t, dataAccessor.GetChannelRepository().Delete(deletedChannel, false))
+	msg, err = dataAccessor.GetMessageRepository().GetByID(msg.ID.String())
+	assert.Nil(t, err)
+	msgDispatcher.Dispatch(msg)
+	dispatchedMsg, err := dataAccessor.GetMessageRepository().GetByID(msg.ID.String())
+	assert.Nil(t, err)
+	assert.Equal(t, data.MsgStatusDispatched, dispatchedMsg.Status)
+	jobs, _, err := dataAccessor.GetDeliveryJobRepository().GetJobsForMessage(msg, data.NewPagination(nil, nil))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(jobs))
+}
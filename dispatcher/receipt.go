@@ -0,0 +1,108 @@
+package dispatcher
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/newscred/webhook-broker/config"
+	"github.com/newscred/webhook-broker/storage"
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+const (
+	receiptMessageIDJoint = "-"
+)
+
+// ReceiptModel is the payload of the delivery receipt sent to the receipt URL of the message or its producer
+type ReceiptModel struct {
+	Event         string
+	MessageID     string
+	ChannelID     string
+	ProducerID    string
+	ReceivedAt    time.Time
+	ConsumerID    string `json:",omitempty"`
+	JobID         string `json:",omitempty"`
+	FailureReason string `json:",omitempty"`
+	OccurredAt    time.Time
+}
+
+// receiptSender publishes delivery receipts as messages to the receipts channel, each routed to the consumer of its receipt URL so that
+// receipts are delivered, retried and recovered just like any other message
+type receiptSender struct {
+	brokerConfig config.BrokerConfig
+	consumerRepo storage.ConsumerRepository
+	djRepo       storage.DeliveryJobRepository
+	publisher    *brokerPublisher
+}
+
+func newReceiptSender(configuration *Configuration, publisher *brokerPublisher) *receiptSender {
+	if publisher == nil {
+		return nil
+	}
+	return &receiptSender{brokerConfig: configuration.BrokerConfig, consumerRepo: configuration.ConsumerRepo, djRepo: configuration.DeliveryJobRepo, publisher: publisher}
+}
+
+// jobDelivered sends the delivered receipt once no job of the message is left undelivered
+func (sender *receiptSender) jobDelivered(logger zerolog.Logger, job *data.DeliveryJob) {
+	if sender == nil || !sender.brokerConfig.IsReceiptOnDeliveredEnabled() || !isReceiptRequired(job.Message) {
+		return
+	}
+	count, err := sender.djRepo.GetUndeliveredJobCount(job.Message)
+	if err == nil && count <= 0 {
+		err = sender.send(job.Message, &ReceiptModel{Event: data.ReceiptDelivered}, data.ReceiptDelivered)
+	}
+	if err != nil {
+		logger.Error().Err(err).Msg("error - could not send delivered receipt")
+	}
+}
+
+// jobDead sends the dead receipt for the job
+func (sender *receiptSender) jobDead(logger zerolog.Logger, job *data.DeliveryJob) {
+	if sender == nil || !sender.brokerConfig.IsReceiptOnDeadEnabled() || !isReceiptRequired(job.Message) {
+		return
+	}
+	receipt := &ReceiptModel{Event: data.ReceiptDead, JobID: job.ID.String(), FailureReason: job.FailureReason}
+	if job.Listener != nil {
+		receipt.ConsumerID = job.Listener.ConsumerID
+	}
+	if err := sender.send(job.Message, receipt, data.ReceiptDead+receiptMessageIDJoint+job.ID.String()); err != nil {
+		logger.Error().Err(err).Msg("error - could not send dead receipt")
+	}
+}
+
+// isReceiptRequired returns whether receipts are to be sent for the message; receipts are not sent for receipts themselves
+func isReceiptRequired(message *data.Message) bool {
+	return message != nil && message.GetChannelIDSafely() != data.ReceiptsChannelID && len(message.GetReceiptURL()) > 0
+}
+
+// send publishes the receipt; a receipt being published already for the same event of the message, e.g. by another worker, is not an error
+func (sender *receiptSender) send(message *data.Message, receipt *ReceiptModel, eventSuffix string) error {
+	consumer, err := sender.getReceiptConsumer(message.ProducedBy, message.GetReceiptURL())
+	if err != nil {
+		return err
+	}
+	receipt.MessageID, receipt.ChannelID, receipt.ProducerID = message.MessageID, message.GetChannelIDSafely(), message.ProducedBy.ProducerID
+	receipt.ReceivedAt, receipt.OccurredAt = message.ReceivedAt, time.Now()
+	return sender.publisher.publish(consumer.ConsumingFrom, message.MessageID+receiptMessageIDJoint+eventSuffix, consumer.ConsumerID, message.Priority, receipt)
+}
+
+// getReceiptConsumer ensures the receipts channel, the broker producer and the consumer delivering the producer's receipts to the URL exist;
+// the consumer's token is the producer's token so that the receiver can verify the receipt
+func (sender *receiptSender) getReceiptConsumer(producer *data.Producer, receiptURL string) (*data.Consumer, error) {
+	channel, err := sender.publisher.getChannel(data.ReceiptsChannelID)
+	if err != nil {
+		return nil, err
+	}
+	callbackURL, err := url.Parse(receiptURL)
+	if err != nil {
+		return nil, err
+	}
+	consumer, err := data.NewConsumer(channel, data.GetReceiptConsumerID(producer, receiptURL), producer.Token, callbackURL)
+	if err != nil {
+		return nil, err
+	}
+	consumer.RoutingKeyPattern = consumer.ConsumerID
+	return sender.consumerRepo.Store(consumer)
+}
@@ -0,0 +1,164 @@
+package dispatcher
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+const (
+	receiptTestURL = "https://imytech.net/receipts"
+)
+
+func getReceiptTestDispatcher(deliveredEnabled, deadEnabled bool) *MessageDispatcherImpl {
+	brokerConf := getMockedBrokerConfig()
+	brokerConf.On("IsReceiptOnDeliveredEnabled").Return(deliveredEnabled)
+	brokerConf.On("IsReceiptOnDeadEnabled").Return(deadEnabled)
+	dispatcherConfig := getCompleteDispatcherConfiguration(dataAccessor.GetMessageRepository(), dataAccessor.GetDeliveryJobRepository(), dataAccessor.GetConsumerRepository(), brokerConf, getMockedConsumerConfig(), dataAccessor.GetLockRepository())
+	dispatcherConfig.ChannelRepo = dataAccessor.GetChannelRepository()
+	dispatcherConfig.ProducerRepo = dataAccessor.GetProducerRepository()
+	return NewMessageDispatcher(dispatcherConfig).(*MessageDispatcherImpl)
+}
+
+func getReceiptTestMessage(t *testing.T, msgDispatcher *MessageDispatcherImpl, receiptChannel *data.Channel, receiptProducer *data.Producer) (*data.Message, []*data.DeliveryJob) {
+	msg, _ := data.NewMessage(receiptChannel, receiptProducer, "payload", "text/plain")
+	assert.Nil(t, dataAccessor.GetMessageRepository().Create(msg))
+	msgDispatcher.Dispatch(msg)
+	jobs, _, err := dataAccessor.GetDeliveryJobRepository().GetJobsForMessage(msg, data.NewPagination(nil, nil))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(jobs))
+	return msg, jobs
+}
+
+func getReceipt(t *testing.T, receiptMessageID string) (*data.Message, *ReceiptModel) {
+	receiptMessage, err := dataAccessor.GetMessageRepository().Get(data.ReceiptsChannelID, receiptMessageID)
+	if err != nil {
+		return nil, nil
+	}
+	receipt := &ReceiptModel{}
+	assert.Nil(t, json.Unmarshal([]byte(receiptMessage.Payload), receipt))
+	return receiptMessage, receipt
+}
+
+func TestReceipts(t *testing.T) {
+	receiptChannel, _ := data.NewChannel("receipt-dispatch-test-channel", "token")
+	receiptChannel, _ = dataAccessor.GetChannelRepository().Store(receiptChannel)
+	receiptProducer, _ := data.NewProducer("receipt-dispatch-test-producer", "receipt-token")
+	receiptProducer.ReceiptURL = receiptTestURL
+	receiptProducer, err := dataAccessor.GetProducerRepository().Store(receiptProducer)
+	assert.Nil(t, err)
+	callbackURL, _ := url.Parse(consumers[0].CallbackURL)
+	for _, consumerID := range []string{"receipt-dispatch-consumer-1", "receipt-dispatch-consumer-2"} {
+		consumer, _ := data.NewConsumer(receiptChannel, consumerID, consumerToken, callbackURL)
+		_, err := dataAccessor.GetConsumerRepository().Store(consumer)
+		assert.Nil(t, err)
+	}
+	oldQueueJob := queueJob
+	defer func() { queueJob = oldQueueJob }()
+	queuedFor := make([]string, 0)
+	queueJob = func(msgDispatcher *MessageDispatcherImpl, job *data.DeliveryJob) {
+		queuedFor = append(queuedFor, job.Listener.ConsumerID)
+	}
+	receiptConsumerID := data.GetReceiptConsumerID(receiptProducer, receiptTestURL)
+	djRepo := dataAccessor.GetDeliveryJobRepository()
+	// Did not make them parallel since they share the queued jobs
+	t.Run("Delivered", func(t *testing.T) {
+		msgDispatcher := getReceiptTestDispatcher(true, true)
+		defer msgDispatcher.Stop()
+		msg, jobs := getReceiptTestMessage(t, msgDispatcher, receiptChannel, receiptProducer)
+		receipts := msgDispatcher.workers[0].receipts
+		queuedFor = queuedFor[:0]
+		assert.Nil(t, djRepo.MarkJobInflight(jobs[0]))
+		assert.Nil(t, djRepo.MarkJobDelivered(jobs[0]))
+		receipts.jobDelivered(log.Logger, jobs[0])
+		receiptMessage, _ := getReceipt(t, msg.MessageID+"-delivered")
+		assert.Nil(t, receiptMessage)
+		assert.Nil(t, djRepo.MarkJobInflight(jobs[1]))
+		assert.Nil(t, djRepo.MarkJobDelivered(jobs[1]))
+		receipts.jobDelivered(log.Logger, jobs[1])
+		receiptMessage, receipt := getReceipt(t, msg.MessageID+"-delivered")
+		assert.NotNil(t, receiptMessage)
+		assert.Equal(t, data.BrokerProducerID, receiptMessage.ProducedBy.ProducerID)
+		assert.Equal(t, receiptConsumerID, receiptMessage.RoutingKey)
+		assert.Equal(t, data.ReceiptDelivered, receipt.Event)
+		assert.Equal(t, msg.MessageID, receipt.MessageID)
+		assert.Equal(t, receiptChannel.ChannelID, receipt.ChannelID)
+		assert.Equal(t, receiptProducer.ProducerID, receipt.ProducerID)
+		assert.Equal(t, []string{receiptConsumerID}, queuedFor)
+		receiptConsumer, err := dataAccessor.GetConsumerRepository().Get(data.ReceiptsChannelID, receiptConsumerID)
+		assert.Nil(t, err)
+		assert.Equal(t, receiptTestURL, receiptConsumer.CallbackURL)
+		assert.Equal(t, receiptProducer.Token, receiptConsumer.Token)
+		// Sending again, e.g. from another worker, does not publish another receipt
+		receipts.jobDelivered(log.Logger, jobs[1])
+		assert.Equal(t, []string{receiptConsumerID}, queuedFor)
+		// Receipts of receipts are not sent
+		receiptJobs, _, err := djRepo.GetJobsForMessage(receiptMessage, data.NewPagination(nil, nil))
+		assert.Nil(t, err)
+		assert.Nil(t, djRepo.MarkJobInflight(receiptJobs[0]))
+		assert.Nil(t, djRepo.MarkJobDelivered(receiptJobs[0]))
+		receipts.jobDelivered(log.Logger, receiptJobs[0])
+		assert.Equal(t, []string{receiptConsumerID}, queuedFor)
+	})
+	t.Run("Dead", func(t *testing.T) {
+		msgDispatcher := getReceiptTestDispatcher(true, true)
+		defer msgDispatcher.Stop()
+		msg, jobs := getReceiptTestMessage(t, msgDispatcher, receiptChannel, receiptProducer)
+		jobs[0].Message.ReceiptURL = "https://imytech.net/message-receipts"
+		receipts := msgDispatcher.workers[0].receipts
+		queuedFor = queuedFor[:0]
+		assert.Nil(t, djRepo.MarkJobInflight(jobs[0]))
+		jobs[0].SetFailure(errConsumer)
+		assert.Nil(t, djRepo.MarkJobDead(jobs[0]))
+		receipts.jobDead(log.Logger, jobs[0])
+		receiptMessage, receipt := getReceipt(t, msg.MessageID+"-dead-"+jobs[0].ID.String())
+		assert.NotNil(t, receiptMessage)
+		assert.Equal(t, data.ReceiptDead, receipt.Event)
+		assert.Equal(t, jobs[0].ID.String(), receipt.JobID)
+		assert.Equal(t, jobs[0].Listener.ConsumerID, receipt.ConsumerID)
+		assert.Equal(t, errConsumer.Error(), receipt.FailureReason)
+		assert.Equal(t, []string{data.GetReceiptConsumerID(receiptProducer, jobs[0].Message.ReceiptURL)}, queuedFor)
+	})
+	t.Run("Disabled", func(t *testing.T) {
+		msgDispatcher := getReceiptTestDispatcher(false, false)
+		defer msgDispatcher.Stop()
+		msg, jobs := getReceiptTestMessage(t, msgDispatcher, receiptChannel, receiptProducer)
+		receipts := msgDispatcher.workers[0].receipts
+		queuedFor = queuedFor[:0]
+		for _, job := range jobs {
+			assert.Nil(t, djRepo.MarkJobInflight(job))
+			assert.Nil(t, djRepo.MarkJobDelivered(job))
+			receipts.jobDelivered(log.Logger, job)
+		}
+		receipts.jobDead(log.Logger, jobs[0])
+		receiptMessage, _ := getReceipt(t, msg.MessageID+"-delivered")
+		assert.Nil(t, receiptMessage)
+		assert.Equal(t, 0, len(queuedFor))
+	})
+	t.Run("NoReceiptURL", func(t *testing.T) {
+		msgDispatcher := getReceiptTestDispatcher(true, true)
+		defer msgDispatcher.Stop()
+		msg, jobs := getReceiptTestMessage(t, msgDispatcher, receiptChannel, producer)
+		receipts := msgDispatcher.workers[0].receipts
+		queuedFor = queuedFor[:0]
+		for _, job := range jobs {
+			assert.Nil(t, djRepo.MarkJobInflight(job))
+			assert.Nil(t, djRepo.MarkJobDelivered(job))
+			receipts.jobDelivered(log.Logger, job)
+		}
+		receiptMessage, _ := getReceipt(t, msg.MessageID+"-delivered")
+		assert.Nil(t, receiptMessage)
+		assert.Equal(t, 0, len(queuedFor))
+	})
+	t.Run("NotConfigured", func(t *testing.T) {
+		msgDispatcher := NewMessageDispatcher(getCompleteDispatcherConfiguration(dataAccessor.GetMessageRepository(), djRepo, dataAccessor.GetConsumerRepository(), getMockedBrokerConfig(), getMockedConsumerConfig(), dataAccessor.GetLockRepository())).(*MessageDispatcherImpl)
+		defer msgDispatcher.Stop()
+		assert.Nil(t, msgDispatcher.workers[0].receipts)
+		msgDispatcher.workers[0].receipts.jobDead(log.Logger, &data.DeliveryJob{})
+	})
+}
@@ -0,0 +1,37 @@
+package dispatcher
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	payloadRecompressionBatchSize     = 500
+	payloadRecompressionBatchInterval = 250 * time.Millisecond
+)
+
+var (
+	// recompressPayloads goes through stored messages in batches, once per cluster, so that payloads written before the compression configuration
+	// changed are stored as per the current configuration
+	recompressPayloads = func(msgDispatcher *MessageDispatcherImpl) {
+		defer genericPanicRecoveryFunc()
+		lastID := ""
+		for {
+			var err error
+			lastID, err = msgDispatcher.msgRepo.RecompressPayloads(msgDispatcher.instanceID, lastID, payloadRecompressionBatchSize)
+			if err != nil {
+				log.Error().Err(err).Msg("error - could not recompress payloads after " + lastID)
+				return
+			}
+			if len(lastID) <= 0 {
+				return
+			}
+			select {
+			case <-msgDispatcher.payloadRecompressionStop:
+				return
+			case <-time.After(payloadRecompressionBatchInterval):
+			}
+		}
+	}
+)
@@ -0,0 +1,45 @@
+package dispatcher
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	storagemocks "github.com/newscred/webhook-broker/storage/mocks"
+)
+
+func getRecompressorTestDispatcher(msgRepo *storagemocks.MessageRepository) *MessageDispatcherImpl {
+	return NewMessageDispatcher(getCompleteDispatcherConfiguration(msgRepo, dataAccessor.GetDeliveryJobRepository(), dataAccessor.GetConsumerRepository(),
+		getMockedBrokerConfig(false, []time.Duration{10 * time.Millisecond}), getMockedConsumerConfig(), dataAccessor.GetLockRepository())).(*MessageDispatcherImpl)
+}
+
+func TestRecompressPayloads(t *testing.T) {
+	t.Run("AllBatches", func(t *testing.T) {
+		mockMsgRepo := new(storagemocks.MessageRepository)
+		mockMsgRepo.On("RecompressPayloads", mock.Anything, "", payloadRecompressionBatchSize).Return("id-1", nil).Once()
+		mockMsgRepo.On("RecompressPayloads", mock.Anything, "id-1", payloadRecompressionBatchSize).Return("", nil).Once()
+		msgDispatcher := getRecompressorTestDispatcher(mockMsgRepo)
+		defer msgDispatcher.Stop()
+		recompressPayloads(msgDispatcher)
+		mockMsgRepo.AssertExpectations(t)
+	})
+	t.Run("Error", func(t *testing.T) {
+		mockMsgRepo := new(storagemocks.MessageRepository)
+		mockMsgRepo.On("RecompressPayloads", mock.Anything, "", payloadRecompressionBatchSize).Return("id-1", errors.New("expected error")).Once()
+		msgDispatcher := getRecompressorTestDispatcher(mockMsgRepo)
+		defer msgDispatcher.Stop()
+		recompressPayloads(msgDispatcher)
+		mockMsgRepo.AssertExpectations(t)
+	})
+	t.Run("Stopped", func(t *testing.T) {
+		mockMsgRepo := new(storagemocks.MessageRepository)
+		mockMsgRepo.On("RecompressPayloads", mock.Anything, "", payloadRecompressionBatchSize).Return("id-1", nil).Once()
+		msgDispatcher := getRecompressorTestDispatcher(mockMsgRepo)
+		defer msgDispatcher.Stop()
+		close(msgDispatcher.payloadRecompressionStop)
+		recompressPayloads(msgDispatcher)
+		mockMsgRepo.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,109 @@
+package dispatcher
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/newscred/webhook-broker/storage"
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+const (
+	replayIDLogFieldKey    = "replayId"
+	replayMessageBatchSize = 100
+)
+
+var (
+	startReplays = func(msgDispatcher *MessageDispatcherImpl) {
+		defer genericPanicRecoveryFunc()
+		replays := msgDispatcher.replayRepo.GetReplaysToRun()
+		for _, replay := range replays {
+			err := msgDispatcher.replayRepo.Lease(replay, msgDispatcher.instanceID, msgDispatcher.replayLeaseDuration(0))
+			if err == nil {
+				go runReplay(msgDispatcher, replay)
+			} else if err != storage.ErrNoRowsUpdated {
+				log.Error().Err(err).Msg("error - could not lease replay " + replay.ID.String())
+			}
+		}
+	}
+
+	// runReplay creates the jobs of the replay a batch of messages at a time; the jobs are spread at the replay pace and the next batch is
+	// created once they are due, so that jobs of a large replay do not pile up. The jobs are delivered by the workers like any other job.
+	runReplay = func(msgDispatcher *MessageDispatcherImpl, replay *data.Replay) {
+		defer genericPanicRecoveryFunc()
+		logger := log.With().Str(replayIDLogFieldKey, replay.ID.String()).Logger()
+		pace := msgDispatcher.replayPace()
+		for !replay.IsFinished() {
+			// Leave the lease to expire for a paused consumer, the replay will be picked up again once it is resumed
+			if !msgDispatcher.isReplayConsumerDeliverable(replay) {
+				return
+			}
+			messages, err := msgDispatcher.replayRepo.GetNextMessages(replay, replayMessageBatchSize)
+			if err != nil {
+				logger.Error().Err(err).Msg("error - could not load messages to replay")
+				return
+			}
+			if len(messages) <= 0 {
+				replay.Status = data.ReplayCompleted
+				break
+			}
+			jobCount, err := msgDispatcher.replayRepo.CreateJobs(replay, messages, time.Now(), pace, msgDispatcher.replayLeaseDuration(time.Duration(len(messages))*pace))
+			if err != nil {
+				logger.Error().Err(err).Msg("error - could not create jobs to replay")
+				return
+			}
+			if !msgDispatcher.waitForReplay(time.Duration(jobCount) * pace) {
+				return
+			}
+		}
+		if err := msgDispatcher.replayRepo.UpdateProgress(replay, 0); err != nil {
+			logger.Error().Err(err).Msg("error - could not complete replay")
+		}
+	}
+)
+
+func (msgDispatcher *MessageDispatcherImpl) replayLeaseDuration(wait time.Duration) time.Duration {
+	return msgDispatcher.stopTimeout + msgDispatcher.rationalDelay + wait
+}
+
+// replayPace returns the interval between replayed messages, same as the rate at which queued jobs are caught up on resume
+func (msgDispatcher *MessageDispatcherImpl) replayPace() time.Duration {
+	rate := msgDispatcher.brokerConfig.GetResumeCatchUpRate()
+	if rate == 0 {
+		return 0
+	}
+	return time.Second / time.Duration(rate)
+}
+
+func (msgDispatcher *MessageDispatcherImpl) isReplayConsumerDeliverable(replay *data.Replay) bool {
+	consumer, err := msgDispatcher.consumerRepo.GetByID(replay.Consumer.ID.String())
+	if err != nil {
+		log.Error().Err(err).Str(replayIDLogFieldKey, replay.ID.String()).Msg("error - could not load replay consumer")
+		return false
+	}
+	replay.Consumer = consumer
+	return consumer.IsDeliverable()
+}
+
+// waitForReplay waits for the duration unless dispatcher is stopped in the meantime; returns false if dispatcher was stopped
+func (msgDispatcher *MessageDispatcherImpl) waitForReplay(duration time.Duration) bool {
+	select {
+	case <-msgDispatcher.replayWorkerStop:
+		return false
+	case <-time.After(duration):
+		return true
+	}
+}
+
+func (msgDispatcher *MessageDispatcherImpl) runReplays() {
+	for {
+		timer := time.After(msgDispatcher.rationalDelay)
+		select {
+		case <-msgDispatcher.replayWorkerStop:
+			return
+		case <-timer:
+			startReplays(msgDispatcher)
+		}
+	}
+}
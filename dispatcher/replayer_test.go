@@ -0,0 +1,180 @@
+package dispatcher
+
+import (
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/newscred/webhook-broker/storage/data"
+	"github.com/stretchr/testify/assert"
+)
+
+var replayChannel *data.Channel
+
+// getReplayTestFixture creates the consumer and messages already delivered to it, as replays re-deliver messages consumers already have jobs for
+func getReplayTestFixture(t *testing.T, consumerID string, messageCount int) (*data.Consumer, []*data.Message) {
+	if replayChannel == nil {
+		replayChannel, _ = data.NewChannel("replay-test-channel", "token")
+		replayChannel, _ = dataAccessor.GetChannelRepository().Store(replayChannel)
+	}
+	callbackURL, _ := url.Parse(consumers[0].CallbackURL)
+	callbackURL.Path = "/" + consumerID
+	consumer, err := data.NewConsumer(replayChannel, consumerID, consumerToken, callbackURL)
+	assert.Nil(t, err)
+	consumer, err = dataAccessor.GetConsumerRepository().Store(consumer)
+	assert.Nil(t, err)
+	messages := make([]*data.Message, 0, messageCount)
+	for index := 0; index < messageCount; index++ {
+		message, _ := data.NewMessage(replayChannel, producer, consumerID+"-payload", "text/plain")
+		assert.Nil(t, dataAccessor.GetMessageRepository().Create(message))
+		job, _ := data.NewDeliveryJob(message, consumer)
+		assert.Nil(t, dataAccessor.GetDeliveryJobRepository().DispatchMessage(message, job))
+		assert.Nil(t, dataAccessor.GetDeliveryJobRepository().MarkJobInflight(job))
+		assert.Nil(t, dataAccessor.GetDeliveryJobRepository().MarkJobDelivered(job))
+		messages = append(messages, message)
+	}
+	return consumer, messages
+}
+
+func getReplayTestDispatcher() *MessageDispatcherImpl {
+	return NewMessageDispatcher(getCompleteDispatcherConfiguration(dataAccessor.GetMessageRepository(), dataAccessor.GetDeliveryJobRepository(), dataAccessor.GetConsumerRepository(),
+		getMockedBrokerConfig(false, []time.Duration{10 * time.Millisecond}), getMockedConsumerConfig(), dataAccessor.GetLockRepository())).(*MessageDispatcherImpl)
+}
+
+// deliverReplayJobs has the queued replay jobs picked up like the fail-safe retry worker does till the replay's jobs are all delivered or dead
+func deliverReplayJobs(t *testing.T, msgDispatcher *MessageDispatcherImpl, replay *data.Replay, expectedDelivered, expectedFailed uint) *data.Replay {
+	var dbReplay *data.Replay
+	assert.Eventually(t, func() bool {
+		retryQueuedJobs(msgDispatcher)
+		var err error
+		dbReplay, err = dataAccessor.GetReplayRepository().Get(replay.Consumer, replay.ID.String())
+		return err == nil && dbReplay.DeliveredCount == expectedDelivered && dbReplay.FailedCount == expectedFailed
+	}, 5*time.Second, 150*time.Millisecond)
+	return dbReplay
+}
+
+func TestRunReplay(t *testing.T) {
+	replayRepo := dataAccessor.GetReplayRepository()
+	t.Run("Success", func(t *testing.T) {
+		msgDispatcher := getReplayTestDispatcher()
+		defer msgDispatcher.Stop()
+		consumer, messages := getReplayTestFixture(t, "replay-success", 3)
+		received := make(chan string, 10)
+		var attempts int32
+		consumerHandler[consumer.ConsumerID] = func(s string, rw http.ResponseWriter, r *http.Request) {
+			// First message fails once and is retried with backoff
+			if atomic.AddInt32(&attempts, 1) == 1 {
+				rw.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			received <- r.Header.Get(headerMessageID)
+			rw.WriteHeader(http.StatusNoContent)
+		}
+		replay, err := data.NewReplay(consumer, time.Time{}, time.Time{}, []string{messages[2].MessageID, messages[0].MessageID})
+		assert.Nil(t, err)
+		assert.Nil(t, replayRepo.Create(replay))
+		assert.Nil(t, replayRepo.Lease(replay, msgDispatcher.instanceID, time.Minute))
+		runReplay(msgDispatcher, replay)
+		dbReplay, err := replayRepo.Get(consumer, replay.ID.String())
+		assert.Nil(t, err)
+		assert.Equal(t, data.ReplayCompleted, dbReplay.Status)
+		assert.Equal(t, uint(2), dbReplay.TotalCount)
+		assert.Equal(t, uint(2), dbReplay.ProcessedCount)
+		assert.Equal(t, messages[2].ID.String(), dbReplay.CursorID)
+		jobs, _, err := dataAccessor.GetDeliveryJobRepository().GetJobsForConsumer(consumer, data.JobQueued, data.NewPagination(nil, nil))
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(jobs))
+		dbReplay = deliverReplayJobs(t, msgDispatcher, replay, 2, 0)
+		assert.Equal(t, uint(2), dbReplay.DeliveredCount)
+		assert.Equal(t, 2, len(received))
+		// The replay's jobs are delivered alongside the jobs that originally delivered the messages
+		deliveredJobs, _, err := dataAccessor.GetDeliveryJobRepository().GetJobsForConsumer(consumer, data.JobDelivered, data.NewPagination(nil, nil))
+		assert.Nil(t, err)
+		assert.Equal(t, 5, len(deliveredJobs))
+	})
+	t.Run("Failed", func(t *testing.T) {
+		msgDispatcher := getReplayTestDispatcher()
+		defer msgDispatcher.Stop()
+		consumer, messages := getReplayTestFixture(t, "replay-failed", 1)
+		consumerHandler[consumer.ConsumerID] = func(s string, rw http.ResponseWriter, r *http.Request) {
+			rw.WriteHeader(http.StatusBadGateway)
+		}
+		replay, _ := data.NewReplay(consumer, time.Time{}, time.Time{}, []string{messages[0].MessageID})
+		assert.Nil(t, replayRepo.Create(replay))
+		assert.Nil(t, replayRepo.Lease(replay, msgDispatcher.instanceID, time.Minute))
+		runReplay(msgDispatcher, replay)
+		dbReplay := deliverReplayJobs(t, msgDispatcher, replay, 0, 1)
+		assert.Equal(t, data.ReplayCompleted, dbReplay.Status)
+		assert.Equal(t, uint(1), dbReplay.ProcessedCount)
+		deadJobs, _, err := dataAccessor.GetDeliveryJobRepository().GetJobsForConsumer(consumer, data.JobDead, data.NewPagination(nil, nil))
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(deadJobs))
+		assert.Contains(t, deadJobs[0].FailureReason, "502")
+	})
+	t.Run("RoutingKeyPattern", func(t *testing.T) {
+		msgDispatcher := getReplayTestDispatcher()
+		defer msgDispatcher.Stop()
+		consumer, messages := getReplayTestFixture(t, "replay-routed", 1)
+		consumer.RoutingKeyPattern = "user.#"
+		consumer, err := dataAccessor.GetConsumerRepository().Store(consumer)
+		assert.Nil(t, err)
+		routedMessage, _ := data.NewMessage(replayChannel, producer, "replay-routed-payload", "text/plain")
+		routedMessage.RoutingKey = "user.created"
+		assert.Nil(t, dataAccessor.GetMessageRepository().Create(routedMessage))
+		assert.Nil(t, dataAccessor.GetDeliveryJobRepository().DispatchMessage(routedMessage))
+		consumerHandler[consumer.ConsumerID] = func(s string, rw http.ResponseWriter, r *http.Request) {
+			rw.WriteHeader(http.StatusNoContent)
+		}
+		replay, _ := data.NewReplay(consumer, time.Time{}, time.Time{}, []string{messages[0].MessageID, routedMessage.MessageID})
+		assert.Nil(t, replayRepo.Create(replay))
+		assert.Nil(t, replayRepo.Lease(replay, msgDispatcher.instanceID, time.Minute))
+		runReplay(msgDispatcher, replay)
+		dbReplay := deliverReplayJobs(t, msgDispatcher, replay, 1, 0)
+		assert.Equal(t, data.ReplayCompleted, dbReplay.Status)
+		assert.Equal(t, uint(1), dbReplay.ProcessedCount)
+		assert.Equal(t, routedMessage.ID.String(), dbReplay.CursorID)
+	})
+	t.Run("PausedConsumer", func(t *testing.T) {
+		msgDispatcher := getReplayTestDispatcher()
+		defer msgDispatcher.Stop()
+		consumer, messages := getReplayTestFixture(t, "replay-paused", 1)
+		assert.Nil(t, dataAccessor.GetConsumerRepository().SetPaused(consumer, true))
+		replay, _ := data.NewReplay(consumer, time.Time{}, time.Time{}, []string{messages[0].MessageID})
+		assert.Nil(t, replayRepo.Create(replay))
+		assert.Nil(t, replayRepo.Lease(replay, msgDispatcher.instanceID, time.Minute))
+		runReplay(msgDispatcher, replay)
+		dbReplay, err := replayRepo.Get(consumer, replay.ID.String())
+		assert.Nil(t, err)
+		assert.Equal(t, data.ReplayRunning, dbReplay.Status)
+		assert.Equal(t, uint(0), dbReplay.ProcessedCount)
+	})
+}
+
+func TestStartReplays(t *testing.T) {
+	msgDispatcher := getReplayTestDispatcher()
+	defer msgDispatcher.Stop()
+	consumer, messages := getReplayTestFixture(t, "replay-start", 1)
+	replay, _ := data.NewReplay(consumer, time.Time{}, time.Time{}, []string{messages[0].MessageID})
+	assert.Nil(t, dataAccessor.GetReplayRepository().Create(replay))
+	started := make(chan *data.Replay, 10)
+	oldRunReplay := runReplay
+	runReplay = func(msgDispatcher *MessageDispatcherImpl, replay *data.Replay) {
+		started <- replay
+	}
+	defer func() { runReplay = oldRunReplay }()
+	startReplays(msgDispatcher)
+	var startedReplay *data.Replay
+	for startedReplay == nil || startedReplay.ID != replay.ID {
+		startedReplay = <-started
+	}
+	assert.Equal(t, msgDispatcher.instanceID, startedReplay.LeaseOwner)
+	assert.Equal(t, data.ReplayRunning, startedReplay.Status)
+	// Already leased replay is not started again
+	startReplays(msgDispatcher)
+	time.Sleep(50 * time.Millisecond)
+	for len(started) > 0 {
+		assert.NotEqual(t, replay.ID, (<-started).ID)
+	}
+}
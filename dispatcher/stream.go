@@ -0,0 +1,231 @@
+package dispatcher
+
+import (
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/newscred/webhook-broker/storage"
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+var (
+	// ErrStreamEventNotFound is returned when the event acked is not a job of the streaming consumer
+	ErrStreamEventNotFound = errors.New("stream event not found for consumer")
+	errStreamEventNotAcked = errors.New("error - stream event not acked in time")
+)
+
+// ConsumerStreamer hands the jobs of streaming consumers to the connections they hold open and settles the jobs as the consumers ack them;
+// jobs not acked in time are failed by the dispatcher into retry or dead just like failed callbacks
+type ConsumerStreamer interface {
+	// Subscribe returns a channel signalled when jobs are dispatched to the consumer, unsubscribe once the connection is closed
+	Subscribe(consumer *data.Consumer) (wake <-chan bool, unsubscribe func())
+	// Next marks up to limit jobs of the consumer ready for delivery inflight and returns them as events
+	Next(consumer *data.Consumer, limit int) ([]*StreamEvent, error)
+	// Unacked returns the events sent to the consumer after the last event ID and not acked yet, all of them if the last event is not inflight
+	Unacked(consumer *data.Consumer, lastEventID string) ([]*StreamEvent, error)
+	// Ack marks the job of the event delivered
+	Ack(consumer *data.Consumer, eventID string) error
+}
+
+// StreamEvent is the message delivered over the stream, its ID is the ID of the delivery job to ack
+type StreamEvent struct {
+	ID              string
+	MessageID       string
+	ChannelID       string
+	ProducerID      string
+	ContentType     string
+	Priority        uint
+	RoutingKey      string            `json:",omitempty"`
+	Attributes      map[string]string `json:",omitempty"`
+	ReceivedAt      time.Time
+	DeliveryAttempt uint
+	Payload         string
+}
+
+// streamHub signals the connections of streaming consumers on this instance when jobs are dispatched to them
+type streamHub struct {
+	mutex       sync.Mutex
+	subscribers map[string]map[chan bool]bool
+}
+
+func newStreamHub() *streamHub {
+	return &streamHub{subscribers: make(map[string]map[chan bool]bool)}
+}
+
+func (hub *streamHub) subscribe(consumerID string) (<-chan bool, func()) {
+	wake := make(chan bool, 1)
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+	if _, ok := hub.subscribers[consumerID]; !ok {
+		hub.subscribers[consumerID] = make(map[chan bool]bool)
+	}
+	hub.subscribers[consumerID][wake] = true
+	return wake, func() {
+		hub.mutex.Lock()
+		defer hub.mutex.Unlock()
+		delete(hub.subscribers[consumerID], wake)
+		if len(hub.subscribers[consumerID]) <= 0 {
+			delete(hub.subscribers, consumerID)
+		}
+	}
+}
+
+// notify signals the consumer's connections without blocking; a connection already signalled picks up all the jobs at once
+func (hub *streamHub) notify(consumerID string) {
+	if hub == nil {
+		return
+	}
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+	for wake := range hub.subscribers[consumerID] {
+		select {
+		case wake <- true:
+		default:
+		}
+	}
+}
+
+// ConsumerStreamerImpl implements ConsumerStreamer over the delivery job repository
+type ConsumerStreamerImpl struct {
+	djRepo       storage.DeliveryJobRepository
+	consumerRepo storage.ConsumerRepository
+	blobStore    storage.BlobStore
+	hub          *streamHub
+	receipts     *receiptSender
+}
+
+// Subscribe returns a channel signalled when jobs are dispatched to the consumer through this instance
+func (streamer *ConsumerStreamerImpl) Subscribe(consumer *data.Consumer) (<-chan bool, func()) {
+	return streamer.hub.subscribe(consumer.ID.String())
+}
+
+// Next marks the consumer's jobs ready for delivery inflight; nothing is streamed to a paused consumer and a job claimed by another
+// connection in the meantime is skipped
+func (streamer *ConsumerStreamerImpl) Next(consumer *data.Consumer, limit int) ([]*StreamEvent, error) {
+	events := make([]*StreamEvent, 0, limit)
+	current, err := streamer.consumerRepo.GetByID(consumer.ID.String())
+	if err != nil || !current.IsDeliverable() || !current.IsStreaming() {
+		return events, err
+	}
+	jobs, err := streamer.djRepo.GetJobsReadyForConsumer(current, limit)
+	for _, job := range jobs {
+		if err != nil {
+			break
+		}
+		if inflightErr := streamer.djRepo.MarkJobInflight(job); inflightErr != nil {
+			continue
+		}
+		var event *StreamEvent
+		if event, err = streamer.newStreamEvent(job); err == nil {
+			events = append(events, event)
+		}
+	}
+	return events, err
+}
+
+// Unacked returns the consumer's inflight jobs sent later than the job of the last event ID, in the order they were sent
+func (streamer *ConsumerStreamerImpl) Unacked(consumer *data.Consumer, lastEventID string) ([]*StreamEvent, error) {
+	jobs := make([]*data.DeliveryJob, 0)
+	page := data.NewPagination(nil, nil)
+	for {
+		pageJobs, pagination, err := streamer.djRepo.GetJobsForConsumer(consumer, data.JobInflight, page)
+		if err != nil {
+			return nil, err
+		}
+		if len(pageJobs) <= 0 {
+			break
+		}
+		jobs = append(jobs, pageJobs...)
+		page.Next = pagination.Next
+	}
+	var sentAfter time.Time
+	for _, job := range jobs {
+		if job.ID.String() == lastEventID {
+			sentAfter = job.StatusChangedAt
+		}
+	}
+	sort.SliceStable(jobs, func(i, j int) bool { return jobs[i].StatusChangedAt.Before(jobs[j].StatusChangedAt) })
+	events := make([]*StreamEvent, 0, len(jobs))
+	for _, job := range jobs {
+		if !job.StatusChangedAt.After(sentAfter) {
+			continue
+		}
+		event, err := streamer.newStreamEvent(job)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// Ack marks the job of the event delivered and sends its receipt; acking a job no longer inflight, e.g. already acked or failed for not
+// being acked in time, returns storage.ErrNoRowsUpdated
+func (streamer *ConsumerStreamerImpl) Ack(consumer *data.Consumer, eventID string) error {
+	job, err := streamer.djRepo.GetByID(eventID)
+	if err == sql.ErrNoRows || (err == nil && job.Listener.ID != consumer.ID) {
+		return ErrStreamEventNotFound
+	}
+	if err == nil {
+		err = streamer.djRepo.MarkJobDelivered(job)
+	}
+	if err == nil {
+		streamer.receipts.jobDelivered(log.Logger, job)
+	}
+	return err
+}
+
+func (streamer *ConsumerStreamerImpl) newStreamEvent(job *data.DeliveryJob) (*StreamEvent, error) {
+	message := job.Message
+	body, err := openPayload(streamer.blobStore, message)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	payload, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	event := &StreamEvent{ID: job.ID.String(), MessageID: message.MessageID, ChannelID: message.GetChannelIDSafely(), ContentType: message.ContentType,
+		Priority: message.Priority, RoutingKey: message.RoutingKey, Attributes: message.Attributes, ReceivedAt: message.ReceivedAt,
+		DeliveryAttempt: job.RetryAttemptCount + 1, Payload: string(payload)}
+	if message.ProducedBy != nil {
+		event.ProducerID = message.ProducedBy.ProducerID
+	}
+	return event, nil
+}
+
+// failUnackedStreamJob fails the job streamed but not acked in time into retry, or dead at max retry
+func (msgDispatcher *MessageDispatcherImpl) failUnackedStreamJob(job *data.DeliveryJob) (err error) {
+	job.SetFailure(errStreamEventNotAcked)
+	if job.RetryAttemptCount >= uint(msgDispatcher.brokerConfig.GetMaxRetry()) {
+		err = msgDispatcher.djRepo.MarkJobDead(job)
+		if err == nil {
+			msgDispatcher.receipts.jobDead(log.Logger, job)
+			msgDispatcher.systemEvents.jobDead(job)
+		}
+	} else {
+		err = msgDispatcher.djRepo.MarkJobRetry(job, computeEarliestDelta(job.RetryAttemptCount+1, msgDispatcher.brokerConfig))
+	}
+	return err
+}
+
+// NewConsumerStreamer creates a new ConsumerStreamer; it is signalled of the jobs dispatched by the dispatcher provided, else it relies on
+// the connections polling for jobs
+func NewConsumerStreamer(configuration *Configuration, msgDispatcher MessageDispatcher) ConsumerStreamer {
+	if configuration.DeliveryJobRepo == nil || configuration.ConsumerRepo == nil || configuration.BrokerConfig == nil {
+		panic(panicString)
+	}
+	streamer := &ConsumerStreamerImpl{djRepo: configuration.DeliveryJobRepo, consumerRepo: configuration.ConsumerRepo, blobStore: configuration.BlobStore,
+		hub: newStreamHub(), receipts: newReceiptSender(configuration, newBrokerPublisher(configuration, msgDispatcher))}
+	if dispatcherImpl, ok := msgDispatcher.(*MessageDispatcherImpl); ok {
+		streamer.hub = dispatcherImpl.streams
+	}
+	return streamer
+}
@@ -0,0 +1,121 @@
+package dispatcher
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/newscred/webhook-broker/storage"
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+func TestConsumerStreamer(t *testing.T) {
+	streamChannel, _ := data.NewChannel("stream-dispatch-test-channel", "token")
+	streamChannel, _ = dataAccessor.GetChannelRepository().Store(streamChannel)
+	consumer, _ := data.NewStreamConsumer(streamChannel, "stream-dispatch-consumer", consumerToken)
+	consumer, err := dataAccessor.GetConsumerRepository().Store(consumer)
+	assert.Nil(t, err)
+	otherConsumer, _ := data.NewStreamConsumer(streamChannel, "other-stream-dispatch-consumer", consumerToken)
+	otherConsumer, err = dataAccessor.GetConsumerRepository().Store(otherConsumer)
+	assert.Nil(t, err)
+	oldQueueJob := queueJob
+	defer func() { queueJob = oldQueueJob }()
+	queuedFor := make([]string, 0)
+	queueJob = func(msgDispatcher *MessageDispatcherImpl, job *data.DeliveryJob) {
+		queuedFor = append(queuedFor, job.Listener.ConsumerID)
+	}
+	brokerConf := getMockedBrokerConfig()
+	brokerConf.On("IsReceiptOnDeliveredEnabled").Return(false)
+	brokerConf.On("IsReceiptOnDeadEnabled").Return(false)
+	dispatcherConfig := getCompleteDispatcherConfiguration(dataAccessor.GetMessageRepository(), dataAccessor.GetDeliveryJobRepository(), dataAccessor.GetConsumerRepository(), brokerConf, getMockedConsumerConfig(), dataAccessor.GetLockRepository())
+	msgDispatcher := NewMessageDispatcher(dispatcherConfig).(*MessageDispatcherImpl)
+	defer msgDispatcher.Stop()
+	streamer := NewConsumerStreamer(dispatcherConfig, msgDispatcher)
+	djRepo := dataAccessor.GetDeliveryJobRepository()
+	dispatchMessage := func(payload string) {
+		msg, _ := data.NewMessage(streamChannel, producer, payload, "text/plain")
+		msg.Attributes = map[string]string{"tenant": "acme"}
+		assert.Nil(t, dataAccessor.GetMessageRepository().Create(msg))
+		msgDispatcher.Dispatch(msg)
+	}
+	// Did not make them parallel since they share the streamed jobs
+	t.Run("DispatchAndAck", func(t *testing.T) {
+		wake, unsubscribe := streamer.Subscribe(consumer)
+		defer unsubscribe()
+		dispatchMessage("payload-1")
+		assert.Equal(t, 0, len(queuedFor))
+		assert.True(t, <-wake)
+		events, err := streamer.Next(consumer, 10)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(events))
+		assert.Equal(t, "payload-1", events[0].Payload)
+		assert.Equal(t, streamChannel.ChannelID, events[0].ChannelID)
+		assert.Equal(t, producer.ProducerID, events[0].ProducerID)
+		assert.Equal(t, map[string]string{"tenant": "acme"}, events[0].Attributes)
+		assert.Equal(t, uint(1), events[0].DeliveryAttempt)
+		events, err = streamer.Next(consumer, 10)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(events))
+		unacked, err := streamer.Unacked(consumer, "")
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(unacked))
+		assert.Equal(t, "payload-1", unacked[0].Payload)
+		assert.Equal(t, ErrStreamEventNotFound, streamer.Ack(otherConsumer, unacked[0].ID))
+		assert.Equal(t, ErrStreamEventNotFound, streamer.Ack(consumer, "no-such-job"))
+		assert.Nil(t, streamer.Ack(consumer, unacked[0].ID))
+		assert.Equal(t, storage.ErrNoRowsUpdated, streamer.Ack(consumer, unacked[0].ID))
+		job, err := djRepo.GetByID(unacked[0].ID)
+		assert.Nil(t, err)
+		assert.Equal(t, data.JobDelivered, job.Status)
+	})
+	t.Run("UnackedAfterLastEvent", func(t *testing.T) {
+		dispatchMessage("payload-2")
+		first, err := streamer.Next(consumer, 10)
+		assert.Nil(t, err)
+		dispatchMessage("payload-3")
+		second, err := streamer.Next(consumer, 10)
+		assert.Nil(t, err)
+		unacked, err := streamer.Unacked(consumer, first[0].ID)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(unacked))
+		assert.Equal(t, second[0].ID, unacked[0].ID)
+		unacked, err = streamer.Unacked(consumer, "unknown-event")
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(unacked))
+		assert.Equal(t, first[0].ID, unacked[0].ID)
+		assert.Nil(t, streamer.Ack(consumer, first[0].ID))
+		assert.Nil(t, streamer.Ack(consumer, second[0].ID))
+	})
+	t.Run("NotAckedInTime", func(t *testing.T) {
+		dispatchMessage("payload-4")
+		events, err := streamer.Next(consumer, 10)
+		assert.Nil(t, err)
+		job, _ := djRepo.GetByID(events[0].ID)
+		assert.Nil(t, msgDispatcher.failUnackedStreamJob(job))
+		assert.Equal(t, data.JobQueued, job.Status)
+		assert.Equal(t, uint(1), job.RetryAttemptCount)
+		assert.Equal(t, errStreamEventNotAcked.Error(), job.FailureReason)
+		// Retried only once the backoff has elapsed
+		events, err = streamer.Next(consumer, 10)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(events))
+		job.RetryAttemptCount = 5
+		assert.Nil(t, djRepo.MarkJobInflight(job))
+		assert.Nil(t, msgDispatcher.failUnackedStreamJob(job))
+		assert.Equal(t, data.JobDead, job.Status)
+	})
+	t.Run("Paused", func(t *testing.T) {
+		assert.Nil(t, dataAccessor.GetConsumerRepository().SetPaused(consumer, true))
+		defer dataAccessor.GetConsumerRepository().SetPaused(consumer, false)
+		dispatchMessage("payload-5")
+		events, err := streamer.Next(consumer, 10)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(events))
+	})
+	t.Run("NotConfigured", func(t *testing.T) {
+		defer func() {
+			assert.Equal(t, panicString, recover())
+		}()
+		NewConsumerStreamer(getDispatcherConfiguration(nil, nil, nil, nil, nil), msgDispatcher)
+	})
+}
@@ -0,0 +1,126 @@
+package dispatcher
+
+import (
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+const (
+	systemEventMessageIDJoint = "-"
+	systemEventLogFieldKey    = "systemEvent"
+)
+
+// SystemEventPublisher is the contract for publishing broker lifecycle events to the system channel
+type SystemEventPublisher interface {
+	Publish(eventType string, eventID string, eventData interface{})
+}
+
+// SystemEvent is the payload of the messages published to the system channel
+type SystemEvent struct {
+	Event      string
+	OccurredAt time.Time
+	Data       interface{}
+}
+
+// JobEventData is the data of the events about a delivery job
+type JobEventData struct {
+	JobID             string
+	MessageID         string
+	ChannelID         string
+	ConsumerID        string
+	RetryAttemptCount uint
+	FailureReason     string `json:",omitempty"`
+}
+
+// MessageEventData is the data of the events about a message
+type MessageEventData struct {
+	MessageID  string
+	ChannelID  string
+	ProducerID string
+	ReceivedAt time.Time
+	Error      string `json:",omitempty"`
+}
+
+// ConsumerEventData is the data of the events about a consumer
+type ConsumerEventData struct {
+	ConsumerID        string
+	ChannelID         string
+	CallbackURL       string
+	RoutingKeyPattern string
+	Created           bool `json:",omitempty"`
+}
+
+// ChannelEventData is the data of the events about a channel
+type ChannelEventData struct {
+	ChannelID    string
+	Created      bool `json:",omitempty"`
+	TokenRotated bool `json:",omitempty"`
+}
+
+// SystemEventPublisherImpl publishes system events as messages to the system channel with the event type as routing key, so that ordinary
+// consumers can subscribe to all or some of the events
+type SystemEventPublisherImpl struct {
+	publisher *brokerPublisher
+}
+
+// Publish publishes the event; the event ID makes the message ID of the event so that the same event is published once, a random ID is used if
+// it is empty. Failing to publish is logged and does not fail the caller.
+func (events *SystemEventPublisherImpl) Publish(eventType string, eventID string, eventData interface{}) {
+	if events == nil {
+		return
+	}
+	if len(eventID) <= 0 {
+		eventID = xid.New().String()
+	}
+	channel, err := events.publisher.getChannel(data.SystemChannelID)
+	if err == nil {
+		err = events.publisher.publish(channel, eventType+systemEventMessageIDJoint+eventID, eventType, 0, &SystemEvent{Event: eventType, OccurredAt: time.Now(), Data: eventData})
+	}
+	if err != nil {
+		log.Error().Err(err).Str(systemEventLogFieldKey, eventType).Msg("error - could not publish system event")
+	}
+}
+
+// jobDead publishes the job's death unless the job is of a system event itself, as then a dead subscriber would keep producing events
+func (events *SystemEventPublisherImpl) jobDead(job *data.DeliveryJob) {
+	if events == nil || job.Message == nil || job.Message.GetChannelIDSafely() == data.SystemChannelID {
+		return
+	}
+	eventData := &JobEventData{JobID: job.ID.String(), MessageID: job.Message.MessageID, ChannelID: job.Message.GetChannelIDSafely(),
+		RetryAttemptCount: job.RetryAttemptCount, FailureReason: job.FailureReason}
+	if job.Listener != nil {
+		eventData.ConsumerID = job.Listener.ConsumerID
+	}
+	events.Publish(data.SystemEventJobDead, job.ID.String(), eventData)
+}
+
+// messageUndispatchable publishes the message failing to be dispatched once per message
+func (events *SystemEventPublisherImpl) messageUndispatchable(message *data.Message, err error) {
+	if events == nil || message.GetChannelIDSafely() == data.SystemChannelID {
+		return
+	}
+	eventData := &MessageEventData{MessageID: message.MessageID, ChannelID: message.GetChannelIDSafely(), ReceivedAt: message.ReceivedAt, Error: err.Error()}
+	if message.ProducedBy != nil {
+		eventData.ProducerID = message.ProducedBy.ProducerID
+	}
+	events.Publish(data.SystemEventMessageUndispatchable, message.ID.String(), eventData)
+}
+
+func newSystemEventPublisher(publisher *brokerPublisher) *SystemEventPublisherImpl {
+	if publisher == nil {
+		return nil
+	}
+	return &SystemEventPublisherImpl{publisher: publisher}
+}
+
+// NewSystemEventPublisher creates a new SystemEventPublisher publishing through the dispatcher
+func NewSystemEventPublisher(configuration *Configuration, msgDispatcher MessageDispatcher) SystemEventPublisher {
+	if configuration.ChannelRepo == nil || configuration.ProducerRepo == nil || configuration.MsgRepo == nil || msgDispatcher == nil {
+		panic(panicString)
+	}
+	return newSystemEventPublisher(newBrokerPublisher(configuration, msgDispatcher))
+}
@@ -0,0 +1,132 @@
+package dispatcher
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/newscred/webhook-broker/storage"
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+type systemEventCountingMessageRepository struct {
+	storage.MessageRepository
+	created int
+}
+
+func (msgRepo *systemEventCountingMessageRepository) Create(message *data.Message) error {
+	if message.GetChannelIDSafely() == data.SystemChannelID {
+		msgRepo.created++
+	}
+	return msgRepo.MessageRepository.Create(message)
+}
+
+func getSystemEventTestDispatcher() (*MessageDispatcherImpl, *Configuration) {
+	brokerConf := getMockedBrokerConfig()
+	brokerConf.On("IsReceiptOnDeliveredEnabled").Return(false)
+	brokerConf.On("IsReceiptOnDeadEnabled").Return(false)
+	dispatcherConfig := getCompleteDispatcherConfiguration(dataAccessor.GetMessageRepository(), dataAccessor.GetDeliveryJobRepository(), dataAccessor.GetConsumerRepository(), brokerConf, getMockedConsumerConfig(), dataAccessor.GetLockRepository())
+	dispatcherConfig.ChannelRepo = dataAccessor.GetChannelRepository()
+	dispatcherConfig.ProducerRepo = dataAccessor.GetProducerRepository()
+	return NewMessageDispatcher(dispatcherConfig).(*MessageDispatcherImpl), dispatcherConfig
+}
+
+func getSystemEvent(t *testing.T, eventMessageID string, eventData interface{}) (*data.Message, *SystemEvent) {
+	eventMessage, err := dataAccessor.GetMessageRepository().Get(data.SystemChannelID, eventMessageID)
+	if err != nil {
+		return nil, nil
+	}
+	event := &SystemEvent{Data: eventData}
+	assert.Nil(t, json.Unmarshal([]byte(eventMessage.Payload), event))
+	return eventMessage, event
+}
+
+func TestSystemEvents(t *testing.T) {
+	oldQueueJob := queueJob
+	defer func() { queueJob = oldQueueJob }()
+	queuedFor := make([]string, 0)
+	queueJob = func(msgDispatcher *MessageDispatcherImpl, job *data.DeliveryJob) {
+		queuedFor = append(queuedFor, job.Listener.ConsumerID)
+	}
+	msgDispatcher, dispatcherConfig := getSystemEventTestDispatcher()
+	defer msgDispatcher.Stop()
+	// Did not make them parallel since they share the queued jobs
+	t.Run("Publish", func(t *testing.T) {
+		publisher := NewSystemEventPublisher(dispatcherConfig, msgDispatcher)
+		publisher.Publish(data.SystemEventChannelUpdated, "publish-test", &ChannelEventData{ChannelID: "some-channel", TokenRotated: true})
+		systemChannel, err := dataAccessor.GetChannelRepository().Get(data.SystemChannelID)
+		assert.Nil(t, err)
+		callbackURL, _ := url.Parse(consumers[0].CallbackURL)
+		subscriber, _ := data.NewConsumer(systemChannel, "system-event-subscriber", consumerToken, callbackURL)
+		subscriber.RoutingKeyPattern = "job.*"
+		_, err = dataAccessor.GetConsumerRepository().Store(subscriber)
+		assert.Nil(t, err)
+		queuedFor = queuedFor[:0]
+		publisher.Publish(data.SystemEventJobDead, "publish-test", &JobEventData{JobID: "some-job"})
+		publisher.Publish(data.SystemEventJobDead, "publish-test", &JobEventData{JobID: "some-job"})
+		publisher.Publish(data.SystemEventChannelUpdated, "", &ChannelEventData{ChannelID: "some-channel"})
+		assert.Equal(t, []string{"system-event-subscriber"}, queuedFor)
+		eventMessage, event := getSystemEvent(t, data.SystemEventChannelUpdated+"-publish-test", &ChannelEventData{})
+		assert.NotNil(t, eventMessage)
+		assert.Equal(t, data.BrokerProducerID, eventMessage.ProducedBy.ProducerID)
+		assert.Equal(t, data.SystemEventChannelUpdated, eventMessage.RoutingKey)
+		assert.Equal(t, data.SystemEventChannelUpdated, event.Event)
+		assert.Equal(t, &ChannelEventData{ChannelID: "some-channel", TokenRotated: true}, event.Data)
+	})
+	t.Run("JobDead", func(t *testing.T) {
+		msg, _ := data.NewMessage(channel, producer, "payload", "text/plain")
+		assert.Nil(t, dataAccessor.GetMessageRepository().Create(msg))
+		job, _ := data.NewDeliveryJob(msg, consumers[0])
+		job.RetryAttemptCount = 5
+		job.SetFailure(errConsumer)
+		msgDispatcher.workers[0].systemEvents.jobDead(job)
+		eventMessage, event := getSystemEvent(t, data.SystemEventJobDead+"-"+job.ID.String(), &JobEventData{})
+		assert.NotNil(t, eventMessage)
+		assert.Equal(t, &JobEventData{JobID: job.ID.String(), MessageID: msg.MessageID, ChannelID: channel.ChannelID, ConsumerID: consumers[0].ConsumerID,
+			RetryAttemptCount: 5, FailureReason: errConsumer.Error()}, event.Data)
+		// Dead jobs of system events do not publish further events
+		job, _ = data.NewDeliveryJob(eventMessage, consumers[0])
+		msgDispatcher.workers[0].systemEvents.jobDead(job)
+		eventMessage, _ = getSystemEvent(t, data.SystemEventJobDead+"-"+job.ID.String(), &JobEventData{})
+		assert.Nil(t, eventMessage)
+	})
+	t.Run("MessageUndispatchable", func(t *testing.T) {
+		expectedErr := errors.New("consumers could not be listed")
+		oldCreateJobs := createJobs
+		defer func() { createJobs = oldCreateJobs }()
+		createJobs = func(msgDispatcher *MessageDispatcherImpl, message *data.Message) ([]*data.DeliveryJob, error) {
+			if message.GetChannelIDSafely() == data.SystemChannelID {
+				return oldCreateJobs(msgDispatcher, message)
+			}
+			return nil, expectedErr
+		}
+		msg, _ := data.NewMessage(channel, producer, "payload", "text/plain")
+		msg.ReceivedAt = msg.ReceivedAt.Add(-5 * time.Second)
+		assert.Nil(t, dataAccessor.GetMessageRepository().Create(msg))
+		recoverMessagesNotYetDispatched(msgDispatcher)
+		eventMessage, event := getSystemEvent(t, data.SystemEventMessageUndispatchable+"-"+msg.ID.String(), &MessageEventData{})
+		assert.NotNil(t, eventMessage)
+		eventData := event.Data.(*MessageEventData)
+		assert.Equal(t, msg.MessageID, eventData.MessageID)
+		assert.Equal(t, channel.ChannelID, eventData.ChannelID)
+		assert.Equal(t, producer.ProducerID, eventData.ProducerID)
+		assert.Equal(t, expectedErr.Error(), eventData.Error)
+		// Still undispatchable on the next pass, it is not reported again
+		msgRepo := &systemEventCountingMessageRepository{MessageRepository: msgDispatcher.systemEvents.publisher.msgRepo}
+		msgDispatcher.systemEvents.publisher.msgRepo = msgRepo
+		defer func() { msgDispatcher.systemEvents.publisher.msgRepo = msgRepo.MessageRepository }()
+		recoverMessagesNotYetDispatched(msgDispatcher)
+		assert.Equal(t, 0, msgRepo.created)
+		assert.True(t, msgDispatcher.undispatchableMessages[msg.ID.String()])
+	})
+	t.Run("NotConfigured", func(t *testing.T) {
+		defer func() {
+			assert.Equal(t, panicString, recover())
+		}()
+		NewSystemEventPublisher(getDispatcherConfiguration(nil, nil, nil, nil, nil), msgDispatcher)
+	})
+}
@@ -0,0 +1,128 @@
+package dispatcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/newscred/webhook-broker/config"
+	"github.com/newscred/webhook-broker/storage"
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+const (
+	headerVerificationChallenge = "X-Broker-Verification-Challenge"
+	verificationContentType     = "application/json"
+	maxVerificationResponseSize = 4096
+)
+
+var (
+	// verificationRetryDelays are the delays before retrying a failed verification handshake; it is not retried once they are exhausted
+	verificationRetryDelays = []time.Duration{5 * time.Second, 30 * time.Second, time.Minute, 5 * time.Minute, 15 * time.Minute}
+
+	// ErrConsumerNotPendingVerification is returned when verification is attempted for a consumer not waiting for it
+	ErrConsumerNotPendingVerification = errors.New("consumer is not pending verification")
+	// ErrChallengeMismatch is returned when the callback URL does not echo the challenge sent to it
+	ErrChallengeMismatch = errors.New("callback did not echo the verification challenge")
+)
+
+// ConsumerVerifier is the contract for performing the callback URL verification handshake
+type ConsumerVerifier interface {
+	Verify(consumer *data.Consumer) error
+	VerifyWithRetry(consumer *data.Consumer)
+}
+
+// ConsumerVerifierImpl verifies consumer callback URLs by POSTing a challenge to them
+type ConsumerVerifierImpl struct {
+	consumerRepo   storage.ConsumerRepository
+	consumerConfig config.ConsumerConnectionConfig
+	httpClient     *http.Client
+}
+
+type verificationChallenge struct {
+	Type       string `json:"type"`
+	Challenge  string `json:"challenge"`
+	ConsumerID string `json:"consumerId"`
+	ChannelID  string `json:"channelId"`
+}
+
+// Verify sends the consumer's pending challenge to its callback URL and marks it verified if the challenge is echoed back
+// either as the plain response body or as the `challenge` attribute of a JSON response body
+func (verifier *ConsumerVerifierImpl) Verify(consumer *data.Consumer) (err error) {
+	if consumer == nil || consumer.VerificationStatus != data.ConsumerPendingVerification {
+		return ErrConsumerNotPendingVerification
+	}
+	logger := log.With().Str(requestIDLogFieldKey, xid.New().String()).Str("consumerId", consumer.ID.String()).Logger()
+	challenge := consumer.VerificationChallenge
+	body, _ := json.Marshal(&verificationChallenge{Type: "url_verification", Challenge: challenge, ConsumerID: consumer.ConsumerID, ChannelID: consumer.GetChannelIDSafely()})
+	var req *http.Request
+	req, err = http.NewRequest(http.MethodPost, consumer.CallbackURL, bytes.NewReader(body))
+	if err == nil {
+		req.Header.Set(headerContentType, verificationContentType)
+		req.Header.Set(headerConsumerToken, consumer.Token)
+		req.Header.Set(headerVerificationChallenge, challenge)
+		var resp *http.Response
+		resp, err = verifier.httpClient.Do(req)
+		if err == nil {
+			defer resp.Body.Close()
+			if resp.StatusCode < 200 || resp.StatusCode > 299 {
+				err = errConsumer
+			} else if !isChallengeEchoed(resp, challenge) {
+				err = ErrChallengeMismatch
+			}
+		}
+	}
+	if err == nil {
+		err = verifier.consumerRepo.MarkVerified(consumer, challenge)
+	}
+	if err != nil {
+		logger.Error().Err(err).Msg("error - consumer callback verification failed")
+	}
+	return err
+}
+
+// VerifyWithRetry performs the verification handshake retrying with backoff until it succeeds, the retries are exhausted or the consumer is no longer
+// pending the same challenge, e.g. it was deleted or re-triggered, as a new challenge is verified on its own; meant to be run in its own goroutine
+func (verifier *ConsumerVerifierImpl) VerifyWithRetry(consumer *data.Consumer) {
+	for attempt := 0; ; attempt++ {
+		err := verifier.Verify(consumer)
+		if err == nil || err == ErrConsumerNotPendingVerification {
+			return
+		}
+		if attempt >= len(verificationRetryDelays) {
+			log.Error().Err(err).Str("consumerId", consumer.ID.String()).Msg("error - consumer callback verification failed after all retries")
+			return
+		}
+		time.Sleep(verificationRetryDelays[attempt])
+		current, err := verifier.consumerRepo.Get(consumer.GetChannelIDSafely(), consumer.ConsumerID)
+		if err != nil || current.VerificationChallenge != consumer.VerificationChallenge {
+			return
+		}
+		consumer = current
+	}
+}
+
+func isChallengeEchoed(resp *http.Response, challenge string) bool {
+	respBody, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxVerificationResponseSize))
+	if err != nil {
+		return false
+	}
+	if strings.TrimSpace(string(respBody)) == challenge {
+		return true
+	}
+	echo := &verificationChallenge{}
+	return json.Unmarshal(respBody, echo) == nil && echo.Challenge == challenge
+}
+
+// NewConsumerVerifier creates a new ConsumerVerifier
+func NewConsumerVerifier(consumerConfig config.ConsumerConnectionConfig, consumerRepo storage.ConsumerRepository) ConsumerVerifier {
+	return &ConsumerVerifierImpl{consumerRepo: consumerRepo, consumerConfig: consumerConfig, httpClient: createHTTPClient(consumerConfig)}
+}
@@ -0,0 +1,160 @@
+package dispatcher
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/newscred/webhook-broker/storage/data"
+	storagemocks "github.com/newscred/webhook-broker/storage/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+var verificationChannel *data.Channel
+
+func getVerificationTestConsumer(t *testing.T, consumerID string) *data.Consumer {
+	if verificationChannel == nil {
+		verificationChannel, _ = data.NewChannel("verification-test-channel", "token")
+		verificationChannel, _ = dataAccessor.GetChannelRepository().Store(verificationChannel)
+	}
+	callbackURL, _ := url.Parse(consumers[0].CallbackURL)
+	callbackURL.Path = "/" + consumerID
+	consumer, err := data.NewConsumer(verificationChannel, consumerID, consumerToken, callbackURL)
+	assert.Nil(t, err)
+	consumer.VerificationStatus = data.ConsumerPendingVerification
+	consumer.VerificationChallenge = "challenge-" + consumerID
+	consumer, err = dataAccessor.GetConsumerRepository().Store(consumer)
+	assert.Nil(t, err)
+	return consumer
+}
+
+func TestConsumerVerifierVerify(t *testing.T) {
+	verifier := NewConsumerVerifier(getMockedConsumerConfig(), dataAccessor.GetConsumerRepository())
+	t.Run("PlainEcho", func(t *testing.T) {
+		consumer := getVerificationTestConsumer(t, "verify-plain-echo")
+		consumerHandler[consumer.ConsumerID] = func(s string, rw http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, consumerToken, r.Header.Get(headerConsumerToken))
+			rw.Write([]byte(r.Header.Get(headerVerificationChallenge) + "\n"))
+		}
+		assert.Nil(t, verifier.Verify(consumer))
+		assert.True(t, consumer.IsVerified())
+		dbConsumer, err := dataAccessor.GetConsumerRepository().Get(verificationChannel.ChannelID, consumer.ConsumerID)
+		assert.Nil(t, err)
+		assert.Equal(t, data.ConsumerVerified, dbConsumer.VerificationStatus)
+	})
+	t.Run("JSONEcho", func(t *testing.T) {
+		consumer := getVerificationTestConsumer(t, "verify-json-echo")
+		consumerHandler[consumer.ConsumerID] = func(s string, rw http.ResponseWriter, r *http.Request) {
+			challenge := &verificationChallenge{}
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(challenge))
+			assert.Equal(t, "url_verification", challenge.Type)
+			rw.Header().Set(headerContentType, "application/json")
+			rw.Write([]byte(`{"challenge": "` + challenge.Challenge + `"}`))
+		}
+		assert.Nil(t, verifier.Verify(consumer))
+		assert.True(t, consumer.IsVerified())
+	})
+	t.Run("ChallengeMismatch", func(t *testing.T) {
+		consumer := getVerificationTestConsumer(t, "verify-mismatch")
+		consumerHandler[consumer.ConsumerID] = func(s string, rw http.ResponseWriter, r *http.Request) {
+			rw.Write([]byte("something else"))
+		}
+		assert.Equal(t, ErrChallengeMismatch, verifier.Verify(consumer))
+		assert.False(t, consumer.IsVerified())
+	})
+	t.Run("Non2xx", func(t *testing.T) {
+		consumer := getVerificationTestConsumer(t, "verify-non-2xx")
+		consumerHandler[consumer.ConsumerID] = func(s string, rw http.ResponseWriter, r *http.Request) {
+			rw.WriteHeader(http.StatusForbidden)
+			rw.Write([]byte(r.Header.Get(headerVerificationChallenge)))
+		}
+		assert.Equal(t, errConsumer, verifier.Verify(consumer))
+		assert.False(t, consumer.IsVerified())
+	})
+	t.Run("NotPending", func(t *testing.T) {
+		assert.Equal(t, ErrConsumerNotPendingVerification, verifier.Verify(consumers[0]))
+		assert.Equal(t, ErrConsumerNotPendingVerification, verifier.Verify(nil))
+	})
+	t.Run("MarkVerifiedError", func(t *testing.T) {
+		consumer := getVerificationTestConsumer(t, "verify-repo-error")
+		consumerHandler[consumer.ConsumerID] = func(s string, rw http.ResponseWriter, r *http.Request) {
+			rw.Write([]byte(r.Header.Get(headerVerificationChallenge)))
+		}
+		expectedErr := errors.New("expected error")
+		mockConsumerRepo := new(storagemocks.ConsumerRepository)
+		mockConsumerRepo.On("MarkVerified", consumer, consumer.VerificationChallenge).Return(expectedErr)
+		mockVerifier := NewConsumerVerifier(getMockedConsumerConfig(), mockConsumerRepo)
+		assert.Equal(t, expectedErr, mockVerifier.Verify(consumer))
+		mockConsumerRepo.AssertExpectations(t)
+	})
+}
+
+func TestConsumerVerifierVerifyWithRetry(t *testing.T) {
+	defaultDelays := verificationRetryDelays
+	verificationRetryDelays = []time.Duration{10 * time.Millisecond, 10 * time.Millisecond}
+	defer func() { verificationRetryDelays = defaultDelays }()
+	verifier := NewConsumerVerifier(getMockedConsumerConfig(), dataAccessor.GetConsumerRepository())
+	t.Run("VerifiedOnRetry", func(t *testing.T) {
+		consumer := getVerificationTestConsumer(t, "verify-retry-success")
+		var attempts int32
+		consumerHandler[consumer.ConsumerID] = func(s string, rw http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&attempts, 1) < 2 {
+				rw.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			rw.Write([]byte(r.Header.Get(headerVerificationChallenge)))
+		}
+		verifier.VerifyWithRetry(consumer)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+		dbConsumer, err := dataAccessor.GetConsumerRepository().Get(verificationChannel.ChannelID, consumer.ConsumerID)
+		assert.Nil(t, err)
+		assert.Equal(t, data.ConsumerVerified, dbConsumer.VerificationStatus)
+		// Not to be a consumer of the channel other tests create jobs for
+		assert.Nil(t, dataAccessor.GetConsumerRepository().Delete(dbConsumer, false))
+	})
+	t.Run("RetriesExhausted", func(t *testing.T) {
+		consumer := getVerificationTestConsumer(t, "verify-retry-exhausted")
+		var attempts int32
+		consumerHandler[consumer.ConsumerID] = func(s string, rw http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		}
+		verifier.VerifyWithRetry(consumer)
+		assert.Equal(t, int32(len(verificationRetryDelays)+1), atomic.LoadInt32(&attempts))
+		dbConsumer, err := dataAccessor.GetConsumerRepository().Get(verificationChannel.ChannelID, consumer.ConsumerID)
+		assert.Nil(t, err)
+		assert.Equal(t, data.ConsumerPendingVerification, dbConsumer.VerificationStatus)
+	})
+	t.Run("ReChallenged", func(t *testing.T) {
+		consumer := getVerificationTestConsumer(t, "verify-retry-rechallenged")
+		var attempts int32
+		consumerHandler[consumer.ConsumerID] = func(s string, rw http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			update := *consumer
+			update.VerificationChallenge = "new-challenge"
+			_, err := dataAccessor.GetConsumerRepository().Store(&update)
+			assert.Nil(t, err)
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		}
+		verifier.VerifyWithRetry(consumer)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+	})
+}
+
+func TestCreateJobs_SkipsUnverifiedConsumers(t *testing.T) {
+	consumer := getVerificationTestConsumer(t, "create-jobs-unverified")
+	msgDispatcher := NewMessageDispatcher(getCompleteDispatcherConfiguration(dataAccessor.GetMessageRepository(), dataAccessor.GetDeliveryJobRepository(), dataAccessor.GetConsumerRepository(), getMockedBrokerConfig(), getMockedConsumerConfig(), dataAccessor.GetLockRepository())).(*MessageDispatcherImpl)
+	defer msgDispatcher.Stop()
+	msg, _ := data.NewMessage(verificationChannel, producer, "payload", "text/plain")
+	jobs, err := createJobs(msgDispatcher, msg)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(jobs))
+	for _, job := range jobs {
+		assert.True(t, job.Listener.IsVerified())
+		assert.NotEqual(t, consumer.ConsumerID, job.Listener.ConsumerID)
+	}
+}
@@ -3,6 +3,7 @@ package dispatcher
 import (
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"strconv"
@@ -15,6 +16,7 @@ import (
 
 	"github.com/newscred/webhook-broker/config"
 	"github.com/newscred/webhook-broker/storage"
+	"github.com/newscred/webhook-broker/storage/data"
 )
 
 const (
@@ -22,8 +24,16 @@ const (
 	headerBrokerPriority = "X-Broker-Message-Priority"
 	headerConsumerToken  = "X-Broker-Consumer-Token"
 	headerRequestID      = "X-Request-ID"
-	requestIDLogFieldKey = "requestId"
-	jobIDLogFieldKey     = "jobId"
+	// Delivery metadata headers sent along with every call to the consumer
+	headerMessageID         = "X-Broker-Message-ID"
+	headerChannelID         = "X-Broker-Channel-ID"
+	headerProducerID        = "X-Broker-Producer-ID"
+	headerMessageReceivedAt = "X-Broker-Message-Received-At"
+	headerJobID             = "X-Broker-Job-ID"
+	headerDeliveryAttempt   = "X-Broker-Delivery-Attempt"
+	headerAttributePrefix   = "X-Broker-Attr-"
+	requestIDLogFieldKey    = "requestId"
+	jobIDLogFieldKey        = "jobId"
 )
 
 var (
@@ -39,11 +49,14 @@ type Worker struct {
 	brokerConfig             config.BrokerConfig
 	working                  bool
 	djRepo                   storage.DeliveryJobRepository
+	blobStore                storage.BlobStore
+	receipts                 *receiptSender
+	systemEvents             *SystemEventPublisherImpl
 	httpClient               *http.Client
 }
 
 // NewWorker creates a Worker
-func NewWorker(workerPool chan chan *Job, consumerConfig config.ConsumerConnectionConfig, brokerConfig config.BrokerConfig, deliveryJobRepo storage.DeliveryJobRepository) Worker {
+func NewWorker(workerPool chan chan *Job, consumerConfig config.ConsumerConnectionConfig, brokerConfig config.BrokerConfig, deliveryJobRepo storage.DeliveryJobRepository, blobStore storage.BlobStore) Worker {
 	return Worker{
 		workerPool:               workerPool,
 		jobChannel:               make(chan *Job, 1),
@@ -52,6 +65,7 @@ func NewWorker(workerPool chan chan *Job, consumerConfig config.ConsumerConnecti
 		consumerConnectionConfig: consumerConfig,
 		brokerConfig:             brokerConfig,
 		djRepo:                   deliveryJobRepo,
+		blobStore:                blobStore,
 		httpClient:               createHTTPClient(consumerConfig)}
 }
 
@@ -64,6 +78,12 @@ var deliverJob = func(w *Worker, job *Job) {
 	logger := log.With().Str(requestIDLogFieldKey, reqID).Str(jobIDLogFieldKey, job.Data.ID.String()).Logger()
 	// we have received a work request.
 	logger.Debug().Msg("processing job in worker ")
+	// Jobs of paused consumers are not queued by the dispatcher nor picked up by the recovery workers; this only guards against a job queued
+	// with its consumer already paused, which is left queued until the consumer is resumed
+	if !job.Data.Listener.IsDeliverable() {
+		logger.Debug().Msg("consumer not deliverable, job left queued")
+		return
+	}
 	// Put to Inflight
 	err := w.djRepo.MarkJobInflight(job.Data)
 	if err != nil {
@@ -76,11 +96,20 @@ var deliverJob = func(w *Worker, job *Job) {
 	if err == nil {
 		logger.Debug().Msg("delivered job")
 		err = w.djRepo.MarkJobDelivered(job.Data)
+		if err == nil {
+			w.receipts.jobDelivered(logger, job.Data)
+		}
 	} else if job.Data.RetryAttemptCount >= uint(w.brokerConfig.GetMaxRetry()) {
 		logger.Debug().Err(err).Msg("job marked dead")
+		job.Data.SetFailure(err)
 		err = w.djRepo.MarkJobDead(job.Data)
+		if err == nil {
+			w.receipts.jobDead(logger, job.Data)
+			w.systemEvents.jobDead(job.Data)
+		}
 	} else {
 		logger.Debug().Err(err).Msg("schedule for retry job ")
+		job.Data.SetFailure(err)
 		err = w.djRepo.MarkJobRetry(job.Data, w.earliestDelta(job.Data.RetryAttemptCount+1))
 	}
 	if err != nil {
@@ -113,15 +142,31 @@ func (w *Worker) earliestDelta(retryAttempt uint) time.Duration {
 	return computeEarliestDelta(retryAttempt, w.brokerConfig)
 }
 
-var callConsumer = func(httpClient *http.Client, requestID string, logger zerolog.Logger, job *Job) (err error) {
+var callConsumer = func(httpClient *http.Client, blobStore storage.BlobStore, requestID string, logger zerolog.Logger, job *Job) (err error) {
 	var req *http.Request
-	req, err = http.NewRequest(http.MethodPost, job.Data.Listener.CallbackURL, strings.NewReader(job.Data.Message.Payload))
+	var body io.ReadCloser
+	body, err = openPayload(blobStore, job.Data.Message)
+	contentType := job.Data.Message.ContentType
+	if err == nil && job.Data.Listener.DeliveryFormat == data.StructuredCloudEventDelivery {
+		body, err = newStructuredCloudEventBody(body, job.Data.Message)
+		contentType = structuredCloudEventContentType
+	}
+	if err == nil {
+		req, err = http.NewRequest(http.MethodPost, job.Data.Listener.CallbackURL, body)
+		if err != nil {
+			body.Close()
+		}
+	}
 	if err == nil {
 		defer req.Body.Close()
-		req.Header.Set(headerContentType, job.Data.Message.ContentType)
+		req.Header.Set(headerContentType, contentType)
 		req.Header.Set(headerBrokerPriority, strconv.Itoa(int(job.Priority)))
 		req.Header.Set(headerConsumerToken, job.Data.Listener.Token)
 		req.Header.Set(headerRequestID, requestID)
+		setDeliveryMetadataHeaders(req.Header, job)
+		if job.Data.Listener.DeliveryFormat == data.BinaryCloudEventDelivery {
+			setCloudEventHeaders(req.Header, job.Data.Message)
+		}
 		var resp *http.Response
 		resp, err = httpClient.Do(req)
 		if err == nil {
@@ -134,7 +179,7 @@ var callConsumer = func(httpClient *http.Client, requestID string, logger zerolo
 					errString = string(errBody)
 				}
 				logger.Error().Msg(fmt.Sprint("error - consumer connection error ", resp.Status, " ", errString))
-				err = errConsumer
+				err = fmt.Errorf("%w: %s", errConsumer, resp.Status)
 			}
 		}
 	}
@@ -144,6 +189,33 @@ var callConsumer = func(httpClient *http.Client, requestID string, logger zerolo
 	return err
 }
 
+// setDeliveryMetadataHeaders sets the headers identifying the message and the delivery attempt along with the message's attributes
+func setDeliveryMetadataHeaders(header http.Header, job *Job) {
+	message := job.Data.Message
+	header.Set(headerMessageID, message.MessageID)
+	header.Set(headerChannelID, message.GetChannelIDSafely())
+	if message.ProducedBy != nil {
+		header.Set(headerProducerID, message.ProducedBy.ProducerID)
+	}
+	header.Set(headerMessageReceivedAt, message.ReceivedAt.Format(time.RFC3339Nano))
+	header.Set(headerJobID, job.Data.ID.String())
+	header.Set(headerDeliveryAttempt, strconv.Itoa(int(job.Data.RetryAttemptCount)+1))
+	for name, value := range message.Attributes {
+		header.Set(headerAttributePrefix+name, value)
+	}
+}
+
+// openPayload opens the message payload for reading, streaming it from the blob store if it was offloaded
+func openPayload(blobStore storage.BlobStore, message *data.Message) (io.ReadCloser, error) {
+	if len(message.PayloadRef) <= 0 {
+		return ioutil.NopCloser(strings.NewReader(message.Payload)), nil
+	}
+	if blobStore == nil {
+		return nil, storage.ErrBlobStoreNotConfigured
+	}
+	return blobStore.Get(message.PayloadRef)
+}
+
 func (w *Worker) executeJob(requestID string, logger zerolog.Logger, job *Job) (err error) {
 	// Do not let the worker crash due to any panic
 	defer func() {
@@ -152,7 +224,7 @@ func (w *Worker) executeJob(requestID string, logger zerolog.Logger, job *Job) (
 			err = errors.New("panic in executeJob")
 		}
 	}()
-	return callConsumer(w.httpClient, requestID, logger, job)
+	return callConsumer(w.httpClient, w.blobStore, requestID, logger, job)
 }
 
 // IsWorking retrieves whether the work is active
@@ -3,10 +3,15 @@ package dispatcher
 import (
 	"bytes"
 	"errors"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/newscred/webhook-broker/storage"
 	"github.com/newscred/webhook-broker/storage/data"
 	storagemocks "github.com/newscred/webhook-broker/storage/mocks"
 	"github.com/rs/zerolog"
@@ -40,10 +45,14 @@ func TestDeliverJob_MarkDead(t *testing.T) {
 		callConsumer = oldCallConsumer
 	}()
 	expectedErr := errors.New("Expected error")
-	callConsumer = func(httpClient *http.Client, requestID string, logger zerolog.Logger, job *Job) (err error) {
+	callConsumer = func(httpClient *http.Client, blobStore storage.BlobStore, requestID string, logger zerolog.Logger, job *Job) (err error) {
 		return expectedErr
 	}
 	deliverJob(worker, NewJob(inflightJob))
+	deadJob, err := dataAccessor.GetDeliveryJobRepository().GetByID(inflightJob.ID.String())
+	assert.Nil(t, err)
+	assert.Equal(t, data.JobDead, deadJob.Status)
+	assert.Equal(t, expectedErr.Error(), deadJob.FailureReason)
 }
 
 func TestDeliverJob_MarkOpFailed(t *testing.T) {
@@ -83,6 +92,43 @@ func TestDeliverJob_MarkOpFailed(t *testing.T) {
 	assert.Contains(t, buf.String(), inflightJob.ID.String())
 }
 
+func TestDeliverJob_ConsumerPaused(t *testing.T) {
+	pausedChannel, _ := data.NewChannel("paused-worker-test-channel", "token")
+	pausedChannel, _ = dataAccessor.GetChannelRepository().Store(pausedChannel)
+	callbackURL, _ := url.Parse(consumers[0].CallbackURL)
+	consumer, _ := data.NewConsumer(pausedChannel, "worker-paused-consumer", consumerToken, callbackURL)
+	consumer, err := dataAccessor.GetConsumerRepository().Store(consumer)
+	assert.Nil(t, err)
+	msgDispatcher := NewMessageDispatcher(getCompleteDispatcherConfiguration(dataAccessor.GetMessageRepository(), dataAccessor.GetDeliveryJobRepository(), dataAccessor.GetConsumerRepository(), getMockedBrokerConfig(), configuration, dataAccessor.GetLockRepository())).(*MessageDispatcherImpl)
+	defer msgDispatcher.Stop()
+	msg, _ := data.NewMessage(pausedChannel, producer, "payload", "text/plain")
+	assert.Nil(t, dataAccessor.GetMessageRepository().Create(msg))
+	job, _ := data.NewDeliveryJob(msg, consumer)
+	assert.Nil(t, dataAccessor.GetDeliveryJobRepository().DispatchMessage(msg, job))
+	assert.Nil(t, dataAccessor.GetConsumerRepository().SetPaused(consumer, true))
+	oldCallConsumer := callConsumer
+	defer func() {
+		callConsumer = oldCallConsumer
+	}()
+	called := false
+	callConsumer = func(httpClient *http.Client, blobStore storage.BlobStore, requestID string, logger zerolog.Logger, job *Job) (err error) {
+		called = true
+		return nil
+	}
+	// The job's consumer is checked as loaded along with the job
+	deliverJob(msgDispatcher.workers[0], NewJob(job))
+	assert.False(t, called)
+	queuedJob, err := dataAccessor.GetDeliveryJobRepository().GetByID(job.ID.String())
+	assert.Nil(t, err)
+	assert.Equal(t, data.JobQueued, queuedJob.Status)
+	assert.Nil(t, dataAccessor.GetConsumerRepository().SetPaused(consumer, false))
+	deliverJob(msgDispatcher.workers[0], NewJob(job))
+	assert.True(t, called)
+	deliveredJob, err := dataAccessor.GetDeliveryJobRepository().GetByID(job.ID.String())
+	assert.Nil(t, err)
+	assert.Equal(t, data.JobDelivered, deliveredJob.Status)
+}
+
 func TestCallConsumerPanic(t *testing.T) {
 	var buf bytes.Buffer
 	oldLogger := log.Logger
@@ -112,7 +158,7 @@ func TestCallConsumerPanic(t *testing.T) {
 	defer func() {
 		callConsumer = oldCallConsumer
 	}()
-	callConsumer = func(httpClient *http.Client, requestID string, logger zerolog.Logger, job *Job) (err error) {
+	callConsumer = func(httpClient *http.Client, blobStore storage.BlobStore, requestID string, logger zerolog.Logger, job *Job) (err error) {
 		panic("test panic")
 	}
 	deliverJob(worker, NewJob(inflightJob))
@@ -121,3 +167,59 @@ func TestCallConsumerPanic(t *testing.T) {
 	assert.Contains(t, buf.String(), inflightJob.ID.String())
 
 }
+
+func TestOpenPayload(t *testing.T) {
+	t.Run("Inline", func(t *testing.T) {
+		msg, _ := data.NewMessage(channel, producer, `{"key": "inline"}`, "application/json")
+		reader, err := openPayload(nil, msg)
+		assert.Nil(t, err)
+		content, _ := ioutil.ReadAll(reader)
+		assert.Equal(t, msg.Payload, string(content))
+	})
+	t.Run("OffloadedWithoutBlobStore", func(t *testing.T) {
+		msg, _ := data.NewMessage(channel, producer, "", "application/json")
+		msg.PayloadRef = "filesystem:somefile"
+		_, err := openPayload(nil, msg)
+		assert.Equal(t, storage.ErrBlobStoreNotConfigured, err)
+	})
+	t.Run("Offloaded", func(t *testing.T) {
+		msg, _ := data.NewMessage(channel, producer, "", "application/json")
+		msg.PayloadRef = "filesystem:somefile"
+		blobStore := new(storagemocks.BlobStore)
+		expectedReader := ioutil.NopCloser(strings.NewReader(`{"key": "offloaded"}`))
+		blobStore.On("Get", msg.PayloadRef).Return(expectedReader, nil)
+		reader, err := openPayload(blobStore, msg)
+		assert.Nil(t, err)
+		assert.Equal(t, expectedReader, reader)
+		blobStore.AssertExpectations(t)
+	})
+}
+
+func TestCallConsumerHeaders(t *testing.T) {
+	headers := make(chan http.Header, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	callbackURL, _ := url.Parse(server.URL)
+	consumer, _ := data.NewConsumer(channel, "header-test-consumer", "header-test-token", callbackURL)
+	msg, _ := data.NewMessage(channel, producer, `{"key": "headers"}`, "application/json")
+	msg.Attributes = data.MessageAttributes{"Tenant-Id": "tenant-1", "Trace": "abc"}
+	deliveryJob, _ := data.NewDeliveryJob(msg, consumer)
+	deliveryJob.RetryAttemptCount = 2
+	err := callConsumer(server.Client(), nil, "header-test-request", log.Logger, NewJob(deliveryJob))
+	assert.Nil(t, err)
+	header := <-headers
+	assert.Equal(t, msg.MessageID, header.Get(headerMessageID))
+	assert.Equal(t, channel.ChannelID, header.Get(headerChannelID))
+	assert.Equal(t, producer.ProducerID, header.Get(headerProducerID))
+	receivedAt, err := time.Parse(time.RFC3339Nano, header.Get(headerMessageReceivedAt))
+	assert.Nil(t, err)
+	assert.True(t, msg.ReceivedAt.Equal(receivedAt))
+	assert.Equal(t, deliveryJob.ID.String(), header.Get(headerJobID))
+	assert.Equal(t, "3", header.Get(headerDeliveryAttempt))
+	assert.Equal(t, "tenant-1", header.Get("X-Broker-Attr-Tenant-Id"))
+	assert.Equal(t, "abc", header.Get("X-Broker-Attr-Trace"))
+	assert.Equal(t, "header-test-token", header.Get(headerConsumerToken))
+}
@@ -0,0 +1,241 @@
+package grpcapi
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/newscred/webhook-broker/grpcapi/pb"
+	"github.com/newscred/webhook-broker/publish"
+	"github.com/newscred/webhook-broker/storage"
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+const (
+	metadataChannelToken      = "x-broker-channel-token"
+	metadataProducerID        = "x-broker-producer-id"
+	metadataProducerToken     = "x-broker-producer-token"
+	defaultMessageContentType = "application/octet-stream"
+	messageIDLogFieldKey      = "messageId"
+)
+
+var (
+	errChannelTokenNotMatching  = status.Error(codes.PermissionDenied, "channel token does not match")
+	errProducerTokenNotMatching = status.Error(codes.PermissionDenied, "producer token does not match")
+	errProducerDoesNotExist     = status.Error(codes.Unauthenticated, "producer could not be found")
+	errPayloadTooLarge          = status.Error(codes.ResourceExhausted, publish.ErrPayloadTooLarge.Error())
+	errProducerNotGranted       = status.Error(codes.PermissionDenied, "producer is not granted to publish to the channel")
+)
+
+// Broadcast implements the Broadcast RPC, the counterpart of POST /channel/:channelId/broadcast
+func (server *BrokerServer) Broadcast(ctx context.Context, request *pb.BroadcastRequest) (*pb.BroadcastResponse, error) {
+	channel, producer, err := server.getChannelAndProducer(ctx, request.ChannelId)
+	if err != nil {
+		return nil, err
+	}
+	message, err := server.createMessage(ctx, channel, producer, request)
+	if err != nil {
+		return nil, err
+	}
+	go server.Dispatcher.Dispatch(message)
+	return &pb.BroadcastResponse{MessageId: message.MessageID}, nil
+}
+
+// PublishStream implements the PublishStream RPC; each message streamed is stored and dispatched as it is received, so a message failing does not
+// fail the rest of the stream
+func (server *BrokerServer) PublishStream(stream pb.Broker_PublishStreamServer) error {
+	ctx := stream.Context()
+	results := make([]*pb.PublishResult, 0)
+	for {
+		request, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&pb.PublishStreamResponse{Results: results})
+		}
+		if err != nil {
+			return err
+		}
+		result := &pb.PublishResult{MessageId: request.MessageId}
+		channel, producer, err := server.getChannelAndProducer(ctx, request.ChannelId)
+		var message *data.Message
+		if err == nil {
+			message, err = server.createMessage(ctx, channel, producer, request)
+		}
+		if err == nil {
+			result.MessageId = message.MessageID
+			go server.Dispatcher.Dispatch(message)
+		} else {
+			result.Code = int32(status.Code(err))
+			result.Error = status.Convert(err).Message()
+		}
+		results = append(results, result)
+	}
+}
+
+// getChannelAndProducer authenticates the producer against the channel with the same tokens as the HTTP API, sent as metadata
+func (server *BrokerServer) getChannelAndProducer(ctx context.Context, channelID string) (*data.Channel, *data.Producer, error) {
+	logger := zerolog.Ctx(ctx)
+	channel, err := server.DataAccessor.GetChannelRepository().Get(channelID)
+	if err != nil {
+		logger.Error().Err(err).Msg("no channel found: " + channelID)
+		return nil, nil, errNotFound
+	}
+	if channel.Token != getMetadataValue(ctx, metadataChannelToken) {
+		return nil, nil, errChannelTokenNotMatching
+	}
+	producerID := getMetadataValue(ctx, metadataProducerID)
+	producer, err := server.DataAccessor.GetProducerRepository().Get(producerID)
+	if err != nil {
+		logger.Error().Err(err).Msg("no producer found: " + producerID)
+		return nil, nil, errProducerDoesNotExist
+	}
+	if producer.Token != getMetadataValue(ctx, metadataProducerToken) {
+		return nil, nil, errProducerTokenNotMatching
+	}
+	switch _, err = server.DataAccessor.GetPublishGrantRepository().Get(channel, producer); err {
+	case nil:
+		return channel, producer, nil
+	case sql.ErrNoRows:
+		if server.BrokerConfig.IsPublishGrantEnforced() {
+			return nil, nil, errProducerNotGranted
+		}
+		logger.Warn().Msg(fmt.Sprintf("producer %s not granted to publish to channel %s, allowed as grants are not enforced", producerID, channelID))
+		return channel, producer, nil
+	default:
+		logger.Error().Err(err).Msg("error checking publish grant")
+		return nil, nil, status.Error(codes.Internal, err.Error())
+	}
+}
+
+// createMessage validates and stores the message through the same publish pipeline as the HTTP API
+func (server *BrokerServer) createMessage(ctx context.Context, channel *data.Channel, producer *data.Producer, request *pb.BroadcastRequest) (*data.Message, error) {
+	logger := zerolog.Ctx(ctx)
+	attributes := data.MessageAttributes(request.Attributes)
+	if len(attributes) <= 0 {
+		attributes = nil
+	}
+	contentType := request.ContentType
+	if len(contentType) < 1 {
+		contentType = defaultMessageContentType
+	}
+	err := server.Publisher.CheckPayloadSize(channel, uint(len(request.Payload)))
+	if err == nil {
+		err = server.Publisher.ValidateMessage(request.RoutingKey, attributes, request.ReceiptUrl)
+	}
+	if err == nil {
+		_, err = server.Publisher.CheckPayloadSchema(channel, contentType, attributes, bytes.NewReader(request.Payload))
+	}
+	var payload, payloadRef string
+	if err == nil {
+		payload, payloadRef, err = server.Publisher.StorePayload(bytes.NewReader(request.Payload))
+	}
+	if err != nil {
+		return nil, publishError(ctx, err, 0)
+	}
+	message, _ := data.NewMessage(channel, producer, payload, contentType)
+	message.PayloadRef = payloadRef
+	message.RoutingKey = request.RoutingKey
+	message.Attributes = attributes
+	message.ReceiptURL = request.ReceiptUrl
+	message.Priority = uint(request.Priority)
+	if len(request.MessageId) > 0 {
+		message.MessageID = request.MessageId
+	}
+	wait, err := server.Publisher.Publish(message, uint(len(request.Payload)))
+	if err != nil {
+		return nil, publishError(ctx, err, wait)
+	}
+	logger.Info().Str(messageIDLogFieldKey, message.ID.String()).Msg("Message accepted for broadcast")
+	return message, nil
+}
+
+// publishError converts the error of the publish pipeline; a rate limited message is told after how many seconds to retry and the violations of a
+// payload not conforming to the channel's schema are reported in the message
+func publishError(ctx context.Context, err error, wait time.Duration) error {
+	var validationErr *publish.SchemaValidationError
+	switch {
+	case err == publish.ErrPayloadTooLarge:
+		return errPayloadTooLarge
+	case err == publish.ErrInvalidRoutingKey || err == publish.ErrInvalidAttributes || err == publish.ErrInvalidReceiptURL || err == publish.ErrInvalidSchemaVersion ||
+		err == data.ErrInsufficientInformationForCreating:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.As(err, &validationErr):
+		reasons := make([]string, 0, len(validationErr.Violations))
+		for _, violation := range validationErr.Violations {
+			reasons = append(reasons, violation.InstanceLocation+": "+violation.Error)
+		}
+		return status.Errorf(codes.InvalidArgument, "payload does not conform to version %d of the channel's schema: %s", validationErr.SchemaVersion, strings.Join(reasons, "; "))
+	case err == publish.ErrRateLimitExceeded:
+		return status.Errorf(codes.ResourceExhausted, "%s, retry after %d seconds", err.Error(), int(math.Max(math.Ceil(wait.Seconds()), 1)))
+	case err == storage.ErrDuplicateMessageIDForChannel:
+		return status.Error(codes.AlreadyExists, err.Error())
+	default:
+		zerolog.Ctx(ctx).Error().Err(err).Msg("error publishing message")
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+func (server *BrokerServer) readPayload(message *data.Message) ([]byte, error) {
+	if len(message.PayloadRef) <= 0 {
+		return []byte(message.Payload), nil
+	}
+	if server.BlobStore == nil {
+		return nil, storage.ErrBlobStoreNotConfigured
+	}
+	body, err := server.BlobStore.Get(message.PayloadRef)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return ioutil.ReadAll(body)
+}
+
+// GetMessage implements the GetMessage RPC, the counterpart of GET /channel/:channelId/message/:messageId
+func (server *BrokerServer) GetMessage(ctx context.Context, request *pb.GetMessageRequest) (*pb.Message, error) {
+	message, err := server.DataAccessor.GetMessageRepository().Get(request.ChannelId, request.MessageId)
+	switch err {
+	case nil:
+	case sql.ErrNoRows:
+		return nil, errNotFound
+	default:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	jobs := make([]*pb.DeliveryJob, 0)
+	page := data.NewPagination(nil, nil)
+	for {
+		var pageJobs []*data.DeliveryJob
+		pageJobs, page, err = server.DataAccessor.GetDeliveryJobRepository().GetJobsForMessage(message, page)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		if len(pageJobs) <= 0 {
+			break
+		}
+		for _, job := range pageJobs {
+			jobs = append(jobs, &pb.DeliveryJob{JobId: job.ID.String(), ConsumerId: job.Listener.ConsumerID, Status: job.Status.String(),
+				StatusChangedAt: toTimestamp(job.StatusChangedAt)})
+		}
+		page.Previous = nil
+	}
+	payload, err := server.readPayload(message)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	result := &pb.Message{MessageId: message.MessageID, ChannelId: message.GetChannelIDSafely(), ContentType: message.ContentType, Priority: uint32(message.Priority),
+		RoutingKey: message.RoutingKey, Attributes: message.Attributes, ReceiptUrl: message.ReceiptURL, Payload: payload, Status: message.Status.String(),
+		ReceivedAt: toTimestamp(message.ReceivedAt), DispatchedAt: toTimestamp(message.OutboxedAt), Jobs: jobs}
+	if message.ProducedBy != nil {
+		result.ProducerId = message.ProducedBy.ProducerID
+	}
+	return result, nil
+}
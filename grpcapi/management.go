@@ -0,0 +1,242 @@
+package grpcapi
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/newscred/webhook-broker/dispatcher"
+	"github.com/newscred/webhook-broker/grpcapi/pb"
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+var (
+	errInvalidCallbackURL       = status.Error(codes.InvalidArgument, "`callback_url` must be an absolute URL for push consumers")
+	errInvalidRoutingKeyPattern = status.Error(codes.InvalidArgument, "`routing_key_pattern` must be dot separated words of letters, digits, `_` and `-` or wildcards `*` and `#`")
+	errInvalidPaginationCursor  = status.Error(codes.InvalidArgument, "pagination cursor is not valid")
+	consumerTypes               = map[pb.ConsumerType]data.ConsumerType{pb.ConsumerType_PUSH: data.PushConsumer, pb.ConsumerType_STREAM: data.StreamConsumer}
+	pbConsumerTypes             = map[data.ConsumerType]pb.ConsumerType{data.PushConsumer: pb.ConsumerType_PUSH, data.StreamConsumer: pb.ConsumerType_STREAM}
+)
+
+func newChannel(channel *data.Channel) *pb.Channel {
+	return &pb.Channel{ChannelId: channel.ChannelID, Name: channel.Name, Token: channel.Token, MaxPayloadSize: uint64(channel.MaxPayloadSize),
+		ChangedAt: toTimestamp(channel.UpdatedAt)}
+}
+
+func newConsumer(consumer *data.Consumer) *pb.Consumer {
+	return &pb.Consumer{ConsumerId: consumer.ConsumerID, ChannelId: consumer.GetChannelIDSafely(), Name: consumer.Name, Token: consumer.Token,
+		CallbackUrl: consumer.CallbackURL, Type: pbConsumerTypes[consumer.Type], RoutingKeyPattern: consumer.RoutingKeyPattern,
+		VerificationStatus: consumer.VerificationStatus.String(), Paused: consumer.Paused, ChangedAt: toTimestamp(consumer.UpdatedAt)}
+}
+
+func getUpdateData(token, name, defaultName string) (string, string) {
+	if len(token) < 1 {
+		token = randomToken()
+	}
+	if len(name) < 1 {
+		name = defaultName
+	}
+	return token, name
+}
+
+func getPagination(page *pb.Page) (*data.Pagination, error) {
+	pagination := &data.Pagination{}
+	var err error
+	if len(page.GetNext()) > 0 {
+		pagination.Next, err = data.ParseCursor(page.GetNext())
+	}
+	if err == nil && len(page.GetPrevious()) > 0 {
+		pagination.Previous, err = data.ParseCursor(page.GetPrevious())
+	}
+	if err != nil {
+		return nil, errInvalidPaginationCursor
+	}
+	return pagination, nil
+}
+
+func newPage(pagination *data.Pagination) *pb.Page {
+	page := &pb.Page{}
+	if pagination != nil && pagination.Next != nil {
+		page.Next = pagination.Next.String()
+	}
+	if pagination != nil && pagination.Previous != nil {
+		page.Previous = pagination.Previous.String()
+	}
+	return page
+}
+
+// GetChannel implements the GetChannel RPC, the counterpart of GET /channel/:channelId
+func (server *BrokerServer) GetChannel(ctx context.Context, request *pb.GetChannelRequest) (*pb.Channel, error) {
+	channel, err := server.DataAccessor.GetChannelRepository().Get(request.ChannelId)
+	if err != nil {
+		return nil, errNotFound
+	}
+	return newChannel(channel), nil
+}
+
+// PutChannel implements the PutChannel RPC, the counterpart of PUT /channel/:channelId
+func (server *BrokerServer) PutChannel(ctx context.Context, request *pb.PutChannelRequest) (*pb.Channel, error) {
+	channelRepo := server.DataAccessor.GetChannelRepository()
+	existingChannel, err := channelRepo.Get(request.ChannelId)
+	existing := err == nil
+	if existing {
+		if err = checkUnmodifiedSince(existingChannel.UpdatedAt, request.UnmodifiedSince); err != nil {
+			return nil, err
+		}
+	}
+	token, name := getUpdateData(request.Token, request.Name, request.ChannelId)
+	channel, err := data.NewChannel(request.ChannelId, token)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	channel.Name = name
+	channel.MaxPayloadSize = uint(request.MaxPayloadSize)
+	if channel, err = channelRepo.Store(channel); err != nil {
+		return nil, storeError(err)
+	}
+	server.SystemEvents.Publish(data.SystemEventChannelUpdated, "", &dispatcher.ChannelEventData{ChannelID: channel.ChannelID, Created: !existing,
+		TokenRotated: existing && existingChannel.Token != channel.Token})
+	return newChannel(channel), nil
+}
+
+// ListChannels implements the ListChannels RPC, the counterpart of GET /channels
+func (server *BrokerServer) ListChannels(ctx context.Context, request *pb.ListChannelsRequest) (*pb.ListChannelsResponse, error) {
+	pagination, err := getPagination(request.Page)
+	if err != nil {
+		return nil, err
+	}
+	channels, resultPagination, err := server.DataAccessor.GetChannelRepository().GetList(pagination)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	response := &pb.ListChannelsResponse{Channels: make([]*pb.Channel, 0, len(channels)), Page: newPage(resultPagination)}
+	for _, channel := range channels {
+		response.Channels = append(response.Channels, newChannel(channel))
+	}
+	return response, nil
+}
+
+// GetConsumer implements the GetConsumer RPC, the counterpart of GET /channel/:channelId/consumer/:consumerId
+func (server *BrokerServer) GetConsumer(ctx context.Context, request *pb.GetConsumerRequest) (*pb.Consumer, error) {
+	consumer, err := server.DataAccessor.GetConsumerRepository().Get(request.ChannelId, request.ConsumerId)
+	if err != nil {
+		return nil, errNotFound
+	}
+	return newConsumer(consumer), nil
+}
+
+// PutConsumer implements the PutConsumer RPC, the counterpart of PUT /channel/:channelId/consumer/:consumerId; backfilling a new consumer is only
+// supported over HTTP
+func (server *BrokerServer) PutConsumer(ctx context.Context, request *pb.PutConsumerRequest) (*pb.Consumer, error) {
+	channel, err := server.DataAccessor.GetChannelRepository().Get(request.ChannelId)
+	if err != nil {
+		return nil, errNotFound
+	}
+	consumerRepo := server.DataAccessor.GetConsumerRepository()
+	existingConsumer, err := consumerRepo.Get(request.ChannelId, request.ConsumerId)
+	if err == nil {
+		if err = checkUnmodifiedSince(existingConsumer.UpdatedAt, request.UnmodifiedSince); err != nil {
+			return nil, err
+		}
+	} else {
+		existingConsumer = nil
+	}
+	if !data.IsValidRoutingKeyPattern(request.RoutingKeyPattern) {
+		return nil, errInvalidRoutingKeyPattern
+	}
+	token, name := getUpdateData(request.Token, request.Name, request.ConsumerId)
+	var consumer *data.Consumer
+	if consumerTypes[request.Type] == data.StreamConsumer {
+		consumer, err = data.NewStreamConsumer(channel, request.ConsumerId, token)
+	} else {
+		callbackURL, uErr := url.Parse(request.CallbackUrl)
+		if len(request.CallbackUrl) < 1 || uErr != nil || !callbackURL.IsAbs() {
+			return nil, errInvalidCallbackURL
+		}
+		consumer, err = data.NewConsumer(channel, request.ConsumerId, token, callbackURL)
+	}
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	consumer.Name = name
+	consumer.RoutingKeyPattern = request.RoutingKeyPattern
+	// Delivery format can only be set over HTTP, so it is kept as is on update
+	if existingConsumer != nil {
+		consumer.DeliveryFormat = existingConsumer.DeliveryFormat
+	}
+	verify := server.setupVerification(existingConsumer, consumer)
+	if consumer, err = consumerRepo.Store(consumer); err != nil {
+		return nil, storeError(err)
+	}
+	if verify {
+		go server.Verifier.VerifyWithRetry(consumer)
+	}
+	server.SystemEvents.Publish(data.SystemEventConsumerUpdated, "", newConsumerEventData(consumer, existingConsumer == nil))
+	return newConsumer(consumer), nil
+}
+
+// setupVerification decides the verification state of the consumer to be stored and returns whether a handshake needs to be initiated
+func (server *BrokerServer) setupVerification(existingConsumer *data.Consumer, consumer *data.Consumer) bool {
+	if !server.VerificationRequired || consumer.IsStreaming() {
+		return false
+	}
+	if existingConsumer != nil && existingConsumer.CallbackURL == consumer.CallbackURL {
+		consumer.VerificationStatus = existingConsumer.VerificationStatus
+		consumer.VerificationChallenge = existingConsumer.VerificationChallenge
+		return false
+	}
+	consumer.VerificationStatus = data.ConsumerPendingVerification
+	consumer.VerificationChallenge = randomToken()
+	return true
+}
+
+func newConsumerEventData(consumer *data.Consumer, created bool) *dispatcher.ConsumerEventData {
+	return &dispatcher.ConsumerEventData{ConsumerID: consumer.ConsumerID, ChannelID: consumer.GetChannelIDSafely(), CallbackURL: consumer.CallbackURL,
+		RoutingKeyPattern: consumer.RoutingKeyPattern, Created: created}
+}
+
+// DeleteConsumer implements the DeleteConsumer RPC, the counterpart of DELETE /channel/:channelId/consumer/:consumerId
+func (server *BrokerServer) DeleteConsumer(ctx context.Context, request *pb.DeleteConsumerRequest) (*emptypb.Empty, error) {
+	consumerRepo := server.DataAccessor.GetConsumerRepository()
+	consumer, err := consumerRepo.Get(request.ChannelId, request.ConsumerId)
+	switch err {
+	case nil:
+	case sql.ErrNoRows:
+		return nil, errNotFound
+	default:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if err = checkUnmodifiedSince(consumer.UpdatedAt, request.UnmodifiedSince); err != nil {
+		return nil, err
+	}
+	if err = consumerRepo.Delete(consumer, false); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	server.SystemEvents.Publish(data.SystemEventConsumerDeleted, "", newConsumerEventData(consumer, false))
+	return &emptypb.Empty{}, nil
+}
+
+// ListConsumers implements the ListConsumers RPC, the counterpart of GET /channel/:channelId/consumers
+func (server *BrokerServer) ListConsumers(ctx context.Context, request *pb.ListConsumersRequest) (*pb.ListConsumersResponse, error) {
+	pagination, err := getPagination(request.Page)
+	if err != nil {
+		return nil, err
+	}
+	consumers, resultPagination, err := server.DataAccessor.GetConsumerRepository().GetList(request.ChannelId, pagination)
+	switch err {
+	case nil:
+	case sql.ErrNoRows:
+		return nil, errNotFound
+	default:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	response := &pb.ListConsumersResponse{Consumers: make([]*pb.Consumer, 0, len(consumers)), Page: newPage(resultPagination)}
+	for _, consumer := range consumers {
+		response.Consumers = append(response.Consumers, newConsumer(consumer))
+	}
+	return response, nil
+}
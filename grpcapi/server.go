@@ -0,0 +1,216 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/rand"
+	"math"
+	"math/big"
+	"net"
+	"time"
+
+	"github.com/google/wire"
+	"github.com/rs/xid"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/newscred/webhook-broker/config"
+	"github.com/newscred/webhook-broker/dispatcher"
+	"github.com/newscred/webhook-broker/grpcapi/pb"
+	"github.com/newscred/webhook-broker/publish"
+	"github.com/newscred/webhook-broker/storage"
+)
+
+const (
+	metadataRequestID    = "x-request-id"
+	requestIDLogFieldKey = "requestId"
+	shutdownTimeout      = 15 * time.Second
+	charset              = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	// maxMessageOverhead is the room left in a received message for everything other than the payload, e.g. the attributes
+	maxMessageOverhead = 64 * 1024
+)
+
+var (
+	// GRPCInjector binds the gRPC API server
+	GRPCInjector = wire.NewSet(NewBrokerServer, ConfigureGRPCAPI)
+
+	errUnmodifiedSinceRequired = status.Error(codes.InvalidArgument, "`unmodified_since` is required to update an existing resource")
+	errConditionalFailed       = status.Error(codes.FailedPrecondition, "update failed due to mismatch of `unmodified_since`")
+	errNotFound                = status.Error(codes.NotFound, "request resource not found")
+)
+
+// BrokerServer implements the gRPC API over the same storage and dispatcher as the HTTP API
+type BrokerServer struct {
+	pb.UnimplementedBrokerServer
+	DataAccessor         storage.DataAccessor
+	Dispatcher           dispatcher.MessageDispatcher
+	Streamer             dispatcher.ConsumerStreamer
+	Verifier             dispatcher.ConsumerVerifier
+	SystemEvents         dispatcher.SystemEventPublisher
+	Publisher            *publish.Publisher
+	BlobStore            storage.BlobStore
+	BrokerConfig         config.BrokerConfig
+	VerificationRequired bool
+	PollInterval         time.Duration
+}
+
+// NewBrokerServer creates a new instance of the gRPC API server; messages go through the same publish pipeline as the HTTP API's and subscriptions
+// poll for jobs at the rational delay in case they were dispatched by another instance
+func NewBrokerServer(dataAccessor storage.DataAccessor, msgDispatcher dispatcher.MessageDispatcher, streamer dispatcher.ConsumerStreamer, verifier dispatcher.ConsumerVerifier,
+	systemEvents dispatcher.SystemEventPublisher, blobStore storage.BlobStore, brokerConfig config.BrokerConfig, blobStoreConfig config.BlobStoreConfig,
+	consumerConfig config.ConsumerConnectionConfig, rateLimitConfig config.RateLimitConfig) *BrokerServer {
+	publisher := publish.NewPublisher(dataAccessor.GetMessageRepository(), dataAccessor.GetSchemaRepository(), dataAccessor.GetPublishQuotaRepository(), blobStore,
+		blobStoreConfig, brokerConfig, rateLimitConfig)
+	return &BrokerServer{DataAccessor: dataAccessor, Dispatcher: msgDispatcher, Streamer: streamer, Verifier: verifier, SystemEvents: systemEvents, Publisher: publisher,
+		BlobStore: blobStore, BrokerConfig: brokerConfig, VerificationRequired: consumerConfig.IsCallbackVerificationEnabled(), PollInterval: brokerConfig.GetRationalDelay()}
+}
+
+// ConfigureGRPCAPI starts serving the gRPC API when it is enabled and returns the server, else returns nil
+func ConfigureGRPCAPI(grpcConfig config.GRPCConfig, brokerServer *BrokerServer) *grpc.Server {
+	if !grpcConfig.IsGRPCEnabled() {
+		return nil
+	}
+	server := NewGRPCServer(brokerServer)
+	go func() {
+		log.Print("Listening to grpc at -", grpcConfig.GetGRPCListeningAddr())
+		ln, err := net.Listen("tcp", grpcConfig.GetGRPCListeningAddr())
+		if err == nil {
+			err = server.Serve(ln)
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("gRPC server stopped serving")
+		}
+	}()
+	return server
+}
+
+// NewGRPCServer returns a server with the broker service registered and every call logged like the HTTP API's access log; received messages are
+// limited to the broker's maximum payload size, along with room for the rest of the message, instead of gRPC's default of 4MiB
+func NewGRPCServer(brokerServer *BrokerServer) *grpc.Server {
+	server := grpc.NewServer(grpc.UnaryInterceptor(logUnaryCall), grpc.StreamInterceptor(logStreamCall),
+		grpc.MaxRecvMsgSize(getMaxRecvMsgSize(brokerServer.BrokerConfig.GetMaxPayloadSize())))
+	pb.RegisterBrokerServer(server, brokerServer)
+	return server
+}
+
+func getMaxRecvMsgSize(maxPayloadSize uint) int {
+	if maxPayloadSize <= 0 || maxPayloadSize > math.MaxInt32-maxMessageOverhead {
+		return math.MaxInt32
+	}
+	return int(maxPayloadSize) + maxMessageOverhead
+}
+
+// StopServer gracefully stops the server; the calls still open after the shutdown timeout, such as subscriptions, are cancelled
+func StopServer(server *grpc.Server) {
+	if server == nil {
+		return
+	}
+	stopped := make(chan bool)
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(shutdownTimeout):
+		server.Stop()
+	}
+	log.Print("gRPC server stopped!")
+}
+
+// withRequestLogger attaches a logger with the request ID, taken from the `x-request-id` metadata if sent, to the call's context
+func withRequestLogger(ctx context.Context) context.Context {
+	requestID := getMetadataValue(ctx, metadataRequestID)
+	if len(requestID) < 1 {
+		requestID = xid.New().String()
+	}
+	logger := log.With().Str(requestIDLogFieldKey, requestID).Logger()
+	return logger.WithContext(ctx)
+}
+
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	zerolog.Ctx(ctx).Info().
+		Str("method", method).
+		Str("code", status.Code(err).String()).
+		Dur("duration", time.Since(start)).
+		Msg("")
+}
+
+func logUnaryCall(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	ctx = withRequestLogger(ctx)
+	resp, err := handler(ctx, req)
+	logCall(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+// loggedServerStream overrides the context of the stream with the one carrying the request logger
+type loggedServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (stream *loggedServerStream) Context() context.Context {
+	return stream.ctx
+}
+
+func logStreamCall(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	ctx := withRequestLogger(stream.Context())
+	err := handler(srv, &loggedServerStream{ServerStream: stream, ctx: ctx})
+	logCall(ctx, info.FullMethod, start, err)
+	return err
+}
+
+func getMetadataValue(ctx context.Context, key string) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(key); len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// checkUnmodifiedSince enforces the optimistic locking the HTTP API does with `If-Unmodified-Since`, at the same precision of seconds
+func checkUnmodifiedSince(updatedAt time.Time, unmodifiedSince *timestamppb.Timestamp) error {
+	if unmodifiedSince == nil {
+		return errUnmodifiedSinceRequired
+	}
+	if updatedAt.Unix() != unmodifiedSince.AsTime().Unix() {
+		return errConditionalFailed
+	}
+	return nil
+}
+
+// storeError converts the error storing a channel or consumer, where recreating a deleted one is refused until it is purged
+func storeError(err error) error {
+	if err == storage.ErrDeleted {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// randomToken generates tokens and verification challenges, so they must not be predictable
+func randomToken() string {
+	b := make([]byte, 12)
+	max := big.NewInt(int64(len(charset)))
+	for i := range b {
+		index, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			panic(err)
+		}
+		b[i] = charset[index.Int64()]
+	}
+	return string(b)
+}
+
+func toTimestamp(value time.Time) *timestamppb.Timestamp {
+	if value.IsZero() {
+		return nil
+	}
+	return timestamppb.New(value)
+}
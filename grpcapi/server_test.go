@@ -0,0 +1,447 @@
+package grpcapi
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/newscred/webhook-broker/config"
+	configmocks "github.com/newscred/webhook-broker/config/mocks"
+	"github.com/newscred/webhook-broker/dispatcher"
+	dispatchermocks "github.com/newscred/webhook-broker/dispatcher/mocks"
+	"github.com/newscred/webhook-broker/grpcapi/pb"
+	"github.com/newscred/webhook-broker/publish"
+	"github.com/newscred/webhook-broker/storage"
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+const (
+	testChannelID     = "grpc-test-channel"
+	testChannelToken  = "grpc-test-channel-token"
+	testProducerID    = "grpc-test-producer"
+	testProducerToken = "grpc-test-producer-token"
+)
+
+var (
+	migrationLocation, _ = filepath.Abs("../migration/sqls/")
+	defaultMigrationConf = &storage.MigrationConfig{MigrationEnabled: true, MigrationSource: "file://" + migrationLocation}
+	dataAccessor         storage.DataAccessor
+	configuration        *config.Config
+)
+
+func TestMain(m *testing.M) {
+	// Setup DB and migration
+	os.Remove("./webhook-broker.sqlite3")
+	configuration, _ = config.GetAutoConfiguration()
+	configuration.PublishGrantEnforced = true
+	var dbErr error
+	dataAccessor, dbErr = storage.GetNewDataAccessor(configuration, defaultMigrationConf, configuration)
+	if dbErr == nil {
+		setupTestFixture()
+		m.Run()
+		defer dataAccessor.Close()
+	}
+}
+
+func setupTestFixture() {
+	channel, _ := data.NewChannel(testChannelID, testChannelToken)
+	dataAccessor.GetChannelRepository().Store(channel)
+	producer, _ := data.NewProducer(testProducerID, testProducerToken)
+	dataAccessor.GetProducerRepository().Store(producer)
+	dataAccessor.GetPublishGrantRepository().Grant(channel, producer)
+}
+
+// grantTestProducer grants the test producer to publish to the channel
+func grantTestProducer(t *testing.T, channel *data.Channel) {
+	producer, err := dataAccessor.GetProducerRepository().Get(testProducerID)
+	if err == nil {
+		_, err = dataAccessor.GetPublishGrantRepository().Grant(channel, producer)
+	}
+	assert.Nil(t, err)
+}
+
+type testServer struct {
+	client     pb.BrokerClient
+	dispatcher *dispatchermocks.MessageDispatcher
+	streamer   *dispatchermocks.ConsumerStreamer
+	events     *dispatchermocks.SystemEventPublisher
+}
+
+func newTestServer(t *testing.T) *testServer {
+	msgDispatcher := new(dispatchermocks.MessageDispatcher)
+	msgDispatcher.On("Dispatch", mock.Anything).Return()
+	streamer := new(dispatchermocks.ConsumerStreamer)
+	events := new(dispatchermocks.SystemEventPublisher)
+	events.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return()
+	brokerServer := NewBrokerServer(dataAccessor, msgDispatcher, streamer, new(dispatchermocks.ConsumerVerifier), events, nil, configuration, configuration,
+		configuration, configuration)
+	brokerServer.PollInterval = 10 * time.Millisecond
+	listener := bufconn.Listen(1024 * 1024)
+	server := NewGRPCServer(brokerServer)
+	go server.Serve(listener)
+	conn, err := grpc.Dial("bufnet", grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.Nil(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+		server.Stop()
+	})
+	return &testServer{client: pb.NewBrokerClient(conn), dispatcher: msgDispatcher, streamer: streamer, events: events}
+}
+
+func getProducerContext(channelToken, producerID, producerToken string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), metadataChannelToken, channelToken, metadataProducerID, producerID,
+		metadataProducerToken, producerToken)
+}
+
+func TestConfigureGRPCAPI(t *testing.T) {
+	t.Run("Disabled", func(t *testing.T) {
+		grpcConfig := new(configmocks.GRPCConfig)
+		grpcConfig.On("IsGRPCEnabled").Return(false)
+		assert.Nil(t, ConfigureGRPCAPI(grpcConfig, &BrokerServer{}))
+		grpcConfig.AssertExpectations(t)
+	})
+	t.Run("Enabled", func(t *testing.T) {
+		grpcConfig := new(configmocks.GRPCConfig)
+		grpcConfig.On("IsGRPCEnabled").Return(true)
+		grpcConfig.On("GetGRPCListeningAddr").Return("127.0.0.1:0")
+		server := ConfigureGRPCAPI(grpcConfig, &BrokerServer{BrokerConfig: configuration})
+		assert.NotNil(t, server)
+		StopServer(server)
+	})
+	t.Run("StopNil", func(t *testing.T) {
+		StopServer(nil)
+	})
+}
+
+func TestGetMaxRecvMsgSize(t *testing.T) {
+	assert.Equal(t, 16777215+maxMessageOverhead, getMaxRecvMsgSize(16777215))
+	assert.Equal(t, math.MaxInt32, getMaxRecvMsgSize(0))
+	assert.Equal(t, math.MaxInt32, getMaxRecvMsgSize(math.MaxInt32))
+}
+
+func TestBroadcast(t *testing.T) {
+	testServer := newTestServer(t)
+	validCtx := getProducerContext(testChannelToken, testProducerID, testProducerToken)
+	t.Run("Success", func(t *testing.T) {
+		response, err := testServer.client.Broadcast(validCtx, &pb.BroadcastRequest{ChannelId: testChannelID, MessageId: "grpc-broadcast-1",
+			ContentType: "text/plain", Priority: 2, RoutingKey: "order.created", Attributes: map[string]string{"tenant": "acme"}, Payload: []byte("hello")})
+		assert.Nil(t, err)
+		assert.Equal(t, "grpc-broadcast-1", response.MessageId)
+		message, err := testServer.client.GetMessage(context.Background(), &pb.GetMessageRequest{ChannelId: testChannelID, MessageId: "grpc-broadcast-1"})
+		assert.Nil(t, err)
+		assert.Equal(t, "hello", string(message.Payload))
+		assert.Equal(t, "text/plain", message.ContentType)
+		assert.Equal(t, uint32(2), message.Priority)
+		assert.Equal(t, "order.created", message.RoutingKey)
+		assert.Equal(t, "acme", message.Attributes["tenant"])
+		assert.Equal(t, testProducerID, message.ProducerId)
+		assert.Equal(t, 0, len(message.Jobs))
+	})
+	t.Run("Duplicate", func(t *testing.T) {
+		_, err := testServer.client.Broadcast(validCtx, &pb.BroadcastRequest{ChannelId: testChannelID, MessageId: "grpc-broadcast-1", Payload: []byte("hello")})
+		assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	})
+	t.Run("DefaultContentType", func(t *testing.T) {
+		response, err := testServer.client.Broadcast(validCtx, &pb.BroadcastRequest{ChannelId: testChannelID, Payload: []byte("hello")})
+		assert.Nil(t, err)
+		assert.NotEmpty(t, response.MessageId)
+		message, err := testServer.client.GetMessage(context.Background(), &pb.GetMessageRequest{ChannelId: testChannelID, MessageId: response.MessageId})
+		assert.Nil(t, err)
+		assert.Equal(t, defaultMessageContentType, message.ContentType)
+	})
+	t.Run("LargerThanDefaultMaxMessage", func(t *testing.T) {
+		_, err := testServer.client.Broadcast(validCtx, &pb.BroadcastRequest{ChannelId: testChannelID, Payload: bytes.Repeat([]byte("a"), 5*1024*1024)})
+		assert.Nil(t, err)
+	})
+	ungrantedChannel, _ := data.NewChannel("grpc-ungranted-channel", testChannelToken)
+	_, err := dataAccessor.GetChannelRepository().Store(ungrantedChannel)
+	assert.Nil(t, err)
+	errorCases := map[string]struct {
+		ctx     context.Context
+		request *pb.BroadcastRequest
+		code    codes.Code
+	}{
+		"ChannelNotFound":       {validCtx, &pb.BroadcastRequest{ChannelId: "grpc-missing-channel", Payload: []byte("hello")}, codes.NotFound},
+		"ChannelTokenMismatch":  {getProducerContext("wrong", testProducerID, testProducerToken), &pb.BroadcastRequest{ChannelId: testChannelID}, codes.PermissionDenied},
+		"ProducerNotFound":      {getProducerContext(testChannelToken, "grpc-missing", testProducerToken), &pb.BroadcastRequest{ChannelId: testChannelID}, codes.Unauthenticated},
+		"ProducerTokenMismatch": {getProducerContext(testChannelToken, testProducerID, "wrong"), &pb.BroadcastRequest{ChannelId: testChannelID}, codes.PermissionDenied},
+		"ProducerNotGranted":    {validCtx, &pb.BroadcastRequest{ChannelId: ungrantedChannel.ChannelID, Payload: []byte("hello")}, codes.PermissionDenied},
+		"InvalidRoutingKey":     {validCtx, &pb.BroadcastRequest{ChannelId: testChannelID, RoutingKey: "order..created", Payload: []byte("hello")}, codes.InvalidArgument},
+		"InvalidAttributes": {validCtx, &pb.BroadcastRequest{ChannelId: testChannelID, Attributes: map[string]string{"bad name": "value"}, Payload: []byte("hello")},
+			codes.InvalidArgument},
+		"InvalidReceiptURL": {validCtx, &pb.BroadcastRequest{ChannelId: testChannelID, ReceiptUrl: "ftp://example.com/receipt", Payload: []byte("hello")},
+			codes.InvalidArgument},
+		"EmptyPayload": {validCtx, &pb.BroadcastRequest{ChannelId: testChannelID}, codes.InvalidArgument},
+	}
+	for name, testCase := range errorCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := testServer.client.Broadcast(testCase.ctx, testCase.request)
+			assert.Equal(t, testCase.code, status.Code(err))
+		})
+	}
+	t.Run("PayloadTooLarge", func(t *testing.T) {
+		brokerConfig := new(configmocks.BrokerConfig)
+		brokerConfig.On("GetMaxPayloadSize").Return(uint(4))
+		server := &BrokerServer{DataAccessor: dataAccessor, BrokerConfig: brokerConfig, Publisher: &publish.Publisher{BrokerConfig: brokerConfig}}
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(metadataChannelToken, testChannelToken, metadataProducerID, testProducerID,
+			metadataProducerToken, testProducerToken))
+		_, err := server.Broadcast(ctx, &pb.BroadcastRequest{ChannelId: testChannelID, Payload: []byte("hello")})
+		assert.Equal(t, errPayloadTooLarge, err)
+		brokerConfig.AssertExpectations(t)
+	})
+}
+
+func TestPublishStream(t *testing.T) {
+	testServer := newTestServer(t)
+	stream, err := testServer.client.PublishStream(getProducerContext(testChannelToken, testProducerID, testProducerToken))
+	assert.Nil(t, err)
+	assert.Nil(t, stream.Send(&pb.BroadcastRequest{ChannelId: testChannelID, MessageId: "grpc-stream-1", Payload: []byte("first")}))
+	assert.Nil(t, stream.Send(&pb.BroadcastRequest{ChannelId: testChannelID, MessageId: "grpc-stream-2", RoutingKey: "bad..key", Payload: []byte("second")}))
+	assert.Nil(t, stream.Send(&pb.BroadcastRequest{ChannelId: "grpc-missing-channel", MessageId: "grpc-stream-3", Payload: []byte("third")}))
+	assert.Nil(t, stream.Send(&pb.BroadcastRequest{ChannelId: testChannelID, MessageId: "grpc-stream-4", Payload: []byte("fourth")}))
+	response, err := stream.CloseAndRecv()
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(response.Results))
+	assert.Equal(t, "grpc-stream-1", response.Results[0].MessageId)
+	assert.Equal(t, int32(codes.OK), response.Results[0].Code)
+	assert.Equal(t, int32(codes.InvalidArgument), response.Results[1].Code)
+	assert.NotEmpty(t, response.Results[1].Error)
+	assert.Equal(t, int32(codes.NotFound), response.Results[2].Code)
+	assert.Equal(t, int32(codes.OK), response.Results[3].Code)
+	_, err = testServer.client.GetMessage(context.Background(), &pb.GetMessageRequest{ChannelId: testChannelID, MessageId: "grpc-stream-4"})
+	assert.Nil(t, err)
+	_, err = testServer.client.GetMessage(context.Background(), &pb.GetMessageRequest{ChannelId: testChannelID, MessageId: "grpc-stream-2"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestBroadcastSchema(t *testing.T) {
+	testServer := newTestServer(t)
+	validCtx := getProducerContext(testChannelToken, testProducerID, testProducerToken)
+	channel, _ := data.NewChannel("grpc-schema-channel", testChannelToken)
+	channel, err := dataAccessor.GetChannelRepository().Store(channel)
+	assert.Nil(t, err)
+	grantTestProducer(t, channel)
+	t.Run("NoSchema", func(t *testing.T) {
+		_, err := testServer.client.Broadcast(validCtx, &pb.BroadcastRequest{ChannelId: channel.ChannelID, Payload: []byte("not json")})
+		assert.Nil(t, err)
+	})
+	for version, definition := range []string{`{"type": "object", "properties": {"id": {"type": "integer"}}}`, `{"type": "object", "properties": {"id": {"type": "number"}}}`} {
+		schema, _ := data.NewChannelSchema(channel, uint(version+1), definition)
+		assert.Nil(t, dataAccessor.GetSchemaRepository().Create(schema))
+	}
+	t.Run("Conforming", func(t *testing.T) {
+		_, err := testServer.client.Broadcast(validCtx, &pb.BroadcastRequest{ChannelId: channel.ChannelID, ContentType: "application/json", Payload: []byte(`{"id": 1.5}`)})
+		assert.Nil(t, err)
+	})
+	t.Run("NotConforming", func(t *testing.T) {
+		_, err := testServer.client.Broadcast(validCtx, &pb.BroadcastRequest{ChannelId: channel.ChannelID, ContentType: "application/json; schema-version=1",
+			Payload: []byte(`{"id": 1.5}`)})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Equal(t, "payload does not conform to version 1 of the channel's schema: /id: expected integer, but got number", status.Convert(err).Message())
+	})
+	t.Run("UnknownVersion", func(t *testing.T) {
+		_, err := testServer.client.Broadcast(validCtx, &pb.BroadcastRequest{ChannelId: channel.ChannelID, Attributes: map[string]string{data.SchemaVersionAttributeName: "3"},
+			Payload: []byte(`{"id": 1}`)})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Equal(t, publish.ErrInvalidSchemaVersion.Error(), status.Convert(err).Message())
+	})
+}
+
+func TestBroadcastRateLimit(t *testing.T) {
+	testServer := newTestServer(t)
+	validCtx := getProducerContext(testChannelToken, testProducerID, testProducerToken)
+	channel, _ := data.NewChannel("grpc-rate-limit-channel", testChannelToken)
+	channel, err := dataAccessor.GetChannelRepository().Store(channel)
+	assert.Nil(t, err)
+	grantTestProducer(t, channel)
+	_, err = dataAccessor.GetPublishQuotaRepository().SetLimit(data.ChannelQuota, channel.ChannelID, config.PublishRateLimit{MessagesPerSecond: 1})
+	assert.Nil(t, err)
+	_, err = testServer.client.Broadcast(validCtx, &pb.BroadcastRequest{ChannelId: channel.ChannelID, Payload: []byte("first")})
+	assert.Nil(t, err)
+	_, err = testServer.client.Broadcast(validCtx, &pb.BroadcastRequest{ChannelId: channel.ChannelID, Payload: []byte("second")})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, "publish rate limit of the producer or the channel exceeded, retry after 1 seconds", status.Convert(err).Message())
+}
+
+func TestChannelManagement(t *testing.T) {
+	testServer := newTestServer(t)
+	ctx := context.Background()
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := testServer.client.GetChannel(ctx, &pb.GetChannelRequest{ChannelId: "grpc-missing-channel"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+	t.Run("CreateAndUpdate", func(t *testing.T) {
+		channel, err := testServer.client.PutChannel(ctx, &pb.PutChannelRequest{ChannelId: "grpc-managed-channel", Token: "token-1", MaxPayloadSize: 1024})
+		assert.Nil(t, err)
+		assert.Equal(t, "grpc-managed-channel", channel.Name)
+		assert.Equal(t, uint64(1024), channel.MaxPayloadSize)
+		_, err = testServer.client.PutChannel(ctx, &pb.PutChannelRequest{ChannelId: "grpc-managed-channel", Token: "token-2"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		_, err = testServer.client.PutChannel(ctx, &pb.PutChannelRequest{ChannelId: "grpc-managed-channel", Token: "token-2",
+			UnmodifiedSince: timestamppb.New(time.Now().Add(-time.Hour))})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+		updated, err := testServer.client.PutChannel(ctx, &pb.PutChannelRequest{ChannelId: "grpc-managed-channel", Name: "Managed", Token: "token-2",
+			UnmodifiedSince: channel.ChangedAt})
+		assert.Nil(t, err)
+		assert.Equal(t, "Managed", updated.Name)
+		assert.Equal(t, "token-2", updated.Token)
+		fetched, err := testServer.client.GetChannel(ctx, &pb.GetChannelRequest{ChannelId: "grpc-managed-channel"})
+		assert.Nil(t, err)
+		assert.Equal(t, "Managed", fetched.Name)
+		testServer.events.AssertCalled(t, "Publish", data.SystemEventChannelUpdated, "", &dispatcher.ChannelEventData{ChannelID: "grpc-managed-channel",
+			Created: true})
+		testServer.events.AssertCalled(t, "Publish", data.SystemEventChannelUpdated, "", &dispatcher.ChannelEventData{ChannelID: "grpc-managed-channel",
+			TokenRotated: true})
+	})
+	t.Run("List", func(t *testing.T) {
+		response, err := testServer.client.ListChannels(ctx, &pb.ListChannelsRequest{})
+		assert.Nil(t, err)
+		assert.True(t, len(response.Channels) >= 2)
+		_, err = testServer.client.ListChannels(ctx, &pb.ListChannelsRequest{Page: &pb.Page{Next: "not-a-cursor"}})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestConsumerManagement(t *testing.T) {
+	testServer := newTestServer(t)
+	ctx := context.Background()
+	t.Run("ChannelNotFound", func(t *testing.T) {
+		_, err := testServer.client.PutConsumer(ctx, &pb.PutConsumerRequest{ChannelId: "grpc-missing-channel", ConsumerId: "grpc-consumer",
+			CallbackUrl: "https://example.com/"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+		_, err = testServer.client.ListConsumers(ctx, &pb.ListConsumersRequest{ChannelId: "grpc-missing-channel"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+	t.Run("InvalidInput", func(t *testing.T) {
+		_, err := testServer.client.PutConsumer(ctx, &pb.PutConsumerRequest{ChannelId: testChannelID, ConsumerId: "grpc-consumer", CallbackUrl: "/relative"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		_, err = testServer.client.PutConsumer(ctx, &pb.PutConsumerRequest{ChannelId: testChannelID, ConsumerId: "grpc-consumer",
+			CallbackUrl: "https://example.com/", RoutingKeyPattern: "order..*"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+	t.Run("Lifecycle", func(t *testing.T) {
+		consumer, err := testServer.client.PutConsumer(ctx, &pb.PutConsumerRequest{ChannelId: testChannelID, ConsumerId: "grpc-consumer",
+			CallbackUrl: "https://example.com/", RoutingKeyPattern: "order.*"})
+		assert.Nil(t, err)
+		assert.Equal(t, "grpc-consumer", consumer.Name)
+		assert.Equal(t, pb.ConsumerType_PUSH, consumer.Type)
+		assert.Equal(t, "order.*", consumer.RoutingKeyPattern)
+		_, err = testServer.client.PutConsumer(ctx, &pb.PutConsumerRequest{ChannelId: testChannelID, ConsumerId: "grpc-consumer", CallbackUrl: "https://example.org/"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		updated, err := testServer.client.PutConsumer(ctx, &pb.PutConsumerRequest{ChannelId: testChannelID, ConsumerId: "grpc-consumer", Token: consumer.Token,
+			CallbackUrl: "https://example.org/", UnmodifiedSince: consumer.ChangedAt})
+		assert.Nil(t, err)
+		assert.Equal(t, "https://example.org/", updated.CallbackUrl)
+		fetched, err := testServer.client.GetConsumer(ctx, &pb.GetConsumerRequest{ChannelId: testChannelID, ConsumerId: "grpc-consumer"})
+		assert.Nil(t, err)
+		assert.Equal(t, "https://example.org/", fetched.CallbackUrl)
+		list, err := testServer.client.ListConsumers(ctx, &pb.ListConsumersRequest{ChannelId: testChannelID})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(list.Consumers))
+		_, err = testServer.client.DeleteConsumer(ctx, &pb.DeleteConsumerRequest{ChannelId: testChannelID, ConsumerId: "grpc-consumer",
+			UnmodifiedSince: timestamppb.New(time.Now().Add(-time.Hour))})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+		_, err = testServer.client.DeleteConsumer(ctx, &pb.DeleteConsumerRequest{ChannelId: testChannelID, ConsumerId: "grpc-consumer", UnmodifiedSince: fetched.ChangedAt})
+		assert.Nil(t, err)
+		_, err = testServer.client.GetConsumer(ctx, &pb.GetConsumerRequest{ChannelId: testChannelID, ConsumerId: "grpc-consumer"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+		_, err = testServer.client.DeleteConsumer(ctx, &pb.DeleteConsumerRequest{ChannelId: testChannelID, ConsumerId: "grpc-consumer", UnmodifiedSince: fetched.ChangedAt})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+		testServer.events.AssertCalled(t, "Publish", data.SystemEventConsumerDeleted, "", mock.Anything)
+	})
+}
+
+func TestSubscribeAndAck(t *testing.T) {
+	testServer := newTestServer(t)
+	consumer, err := testServer.client.PutConsumer(context.Background(), &pb.PutConsumerRequest{ChannelId: testChannelID, ConsumerId: "grpc-stream-consumer",
+		Token: "stream-token", Type: pb.ConsumerType_STREAM})
+	assert.Nil(t, err)
+	assert.Equal(t, pb.ConsumerType_STREAM, consumer.Type)
+	pushConsumer, err := testServer.client.PutConsumer(context.Background(), &pb.PutConsumerRequest{ChannelId: testChannelID, ConsumerId: "grpc-push-consumer",
+		Token: "push-token", CallbackUrl: "https://example.com/"})
+	assert.Nil(t, err)
+	consumerCtx := metadata.AppendToOutgoingContext(context.Background(), metadataConsumerToken, "stream-token")
+	t.Run("TokenMismatch", func(t *testing.T) {
+		_, err := testServer.client.Ack(context.Background(), &pb.AckRequest{ChannelId: testChannelID, ConsumerId: "grpc-stream-consumer", EventId: "event"})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+	t.Run("NotStreaming", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), metadataConsumerToken, pushConsumer.Token)
+		stream, err := testServer.client.Subscribe(ctx, &pb.SubscribeRequest{ChannelId: testChannelID, ConsumerId: "grpc-push-consumer"})
+		assert.Nil(t, err)
+		_, err = stream.Recv()
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+	t.Run("Subscribe", func(t *testing.T) {
+		wake := make(chan bool)
+		unsubscribed := make(chan bool)
+		testServer.streamer.On("Subscribe", mock.Anything).Return((<-chan bool)(wake), func() { close(unsubscribed) }).Once()
+		testServer.streamer.On("Unacked", mock.Anything, "event-0").Return([]*dispatcher.StreamEvent{{ID: "event-1", MessageID: "message-1",
+			ChannelID: testChannelID, Payload: "resent"}}, nil).Once()
+		testServer.streamer.On("Next", mock.Anything, streamBatchSize).Return([]*dispatcher.StreamEvent{{ID: "event-2", MessageID: "message-2",
+			ChannelID: testChannelID, Attributes: map[string]string{"tenant": "acme"}, DeliveryAttempt: 1, Payload: "new"}}, nil).Once()
+		testServer.streamer.On("Next", mock.Anything, streamBatchSize).Return([]*dispatcher.StreamEvent{}, nil)
+		ctx, cancel := context.WithCancel(consumerCtx)
+		stream, err := testServer.client.Subscribe(ctx, &pb.SubscribeRequest{ChannelId: testChannelID, ConsumerId: "grpc-stream-consumer", LastEventId: "event-0"})
+		assert.Nil(t, err)
+		event, err := stream.Recv()
+		assert.Nil(t, err)
+		assert.Equal(t, "event-1", event.Id)
+		assert.Equal(t, "resent", string(event.Payload))
+		event, err = stream.Recv()
+		assert.Nil(t, err)
+		assert.Equal(t, "event-2", event.Id)
+		assert.Equal(t, "acme", event.Attributes["tenant"])
+		assert.Equal(t, uint32(1), event.DeliveryAttempt)
+		cancel()
+		_, err = stream.Recv()
+		assert.Equal(t, codes.Canceled, status.Code(err))
+		select {
+		case <-unsubscribed:
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "subscription was not removed")
+		}
+	})
+	t.Run("Ack", func(t *testing.T) {
+		_, err := testServer.client.Ack(consumerCtx, &pb.AckRequest{ChannelId: testChannelID, ConsumerId: "grpc-stream-consumer"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		testServer.streamer.On("Ack", mock.Anything, "event-1").Return(nil).Once()
+		_, err = testServer.client.Ack(consumerCtx, &pb.AckRequest{ChannelId: testChannelID, ConsumerId: "grpc-stream-consumer", EventId: "event-1"})
+		assert.Nil(t, err)
+		testServer.streamer.On("Ack", mock.Anything, "event-1").Return(storage.ErrNoRowsUpdated).Once()
+		_, err = testServer.client.Ack(consumerCtx, &pb.AckRequest{ChannelId: testChannelID, ConsumerId: "grpc-stream-consumer", EventId: "event-1"})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+		testServer.streamer.On("Ack", mock.Anything, "event-9").Return(dispatcher.ErrStreamEventNotFound).Once()
+		_, err = testServer.client.Ack(consumerCtx, &pb.AckRequest{ChannelId: testChannelID, ConsumerId: "grpc-stream-consumer", EventId: "event-9"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+		testServer.streamer.On("Ack", mock.Anything, "event-8").Return(io.ErrUnexpectedEOF).Once()
+		_, err = testServer.client.Ack(consumerCtx, &pb.AckRequest{ChannelId: testChannelID, ConsumerId: "grpc-stream-consumer", EventId: "event-8"})
+		assert.Equal(t, codes.Internal, status.Code(err))
+		testServer.streamer.AssertExpectations(t)
+	})
+}
+
+func TestCheckUnmodifiedSince(t *testing.T) {
+	now := time.Now()
+	assert.Equal(t, errUnmodifiedSinceRequired, checkUnmodifiedSince(now, nil))
+	assert.Equal(t, errConditionalFailed, checkUnmodifiedSince(now, timestamppb.New(now.Add(-2*time.Second))))
+	assert.Nil(t, checkUnmodifiedSince(now, timestamppb.New(now)))
+	assert.Nil(t, toTimestamp(time.Time{}))
+}
@@ -0,0 +1,124 @@
+package grpcapi
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/newscred/webhook-broker/dispatcher"
+	"github.com/newscred/webhook-broker/grpcapi/pb"
+	"github.com/newscred/webhook-broker/storage"
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+const (
+	metadataConsumerToken = "x-broker-consumer-token"
+	streamBatchSize       = 25
+)
+
+var (
+	errConsumerTokenNotMatching = status.Error(codes.PermissionDenied, "`x-broker-consumer-token` metadata must match consumer token")
+	errConsumerNotStreaming     = status.Error(codes.FailedPrecondition, "consumer is not streaming, set its type to `STREAM`")
+	errEventIDRequired          = status.Error(codes.InvalidArgument, "`event_id` is required")
+	errStreamEventNotInflight   = status.Error(codes.FailedPrecondition, "stream event is already acked or was not acked in time")
+)
+
+func newStreamEvent(event *dispatcher.StreamEvent) *pb.StreamEvent {
+	return &pb.StreamEvent{Id: event.ID, MessageId: event.MessageID, ChannelId: event.ChannelID, ProducerId: event.ProducerID, ContentType: event.ContentType,
+		Priority: uint32(event.Priority), RoutingKey: event.RoutingKey, Attributes: event.Attributes, ReceivedAt: toTimestamp(event.ReceivedAt),
+		DeliveryAttempt: uint32(event.DeliveryAttempt), Payload: []byte(event.Payload)}
+}
+
+// getStreamingConsumer loads the consumer provided the call carries its token and it is a streaming consumer
+func (server *BrokerServer) getStreamingConsumer(ctx context.Context, channelID, consumerID string) (*data.Consumer, error) {
+	consumer, err := server.DataAccessor.GetConsumerRepository().Get(channelID, consumerID)
+	switch err {
+	case nil:
+	case sql.ErrNoRows:
+		return nil, errNotFound
+	default:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if getMetadataValue(ctx, metadataConsumerToken) != consumer.Token {
+		return nil, errConsumerTokenNotMatching
+	}
+	if !consumer.IsStreaming() {
+		return nil, errConsumerNotStreaming
+	}
+	return consumer, nil
+}
+
+// Subscribe implements the Subscribe RPC, the counterpart of GET /channel/:channelId/consumer/:consumerId/stream; the events are sent until the call
+// is cancelled and on resuming with `last_event_id` the events sent after it and not acked yet are sent again before the backlog is resumed
+func (server *BrokerServer) Subscribe(request *pb.SubscribeRequest, stream pb.Broker_SubscribeServer) error {
+	ctx := stream.Context()
+	consumer, err := server.getStreamingConsumer(ctx, request.ChannelId, request.ConsumerId)
+	if err != nil {
+		return err
+	}
+	wake, unsubscribe := server.Streamer.Subscribe(consumer)
+	defer unsubscribe()
+	logger := zerolog.Ctx(ctx)
+	if len(request.LastEventId) > 0 {
+		events, err := server.Streamer.Unacked(consumer, request.LastEventId)
+		if err != nil {
+			logger.Error().Err(err).Msg("error - could not load unacked stream events")
+		}
+		if err = sendStreamEvents(stream, events); err != nil {
+			return err
+		}
+	}
+	for {
+		events, err := server.Streamer.Next(consumer, streamBatchSize)
+		if err != nil {
+			logger.Error().Err(err).Msg("error - could not load stream events")
+		}
+		if err = sendStreamEvents(stream, events); err != nil {
+			return err
+		}
+		if len(events) >= streamBatchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-wake:
+		case <-time.After(server.PollInterval):
+		}
+	}
+}
+
+func sendStreamEvents(stream pb.Broker_SubscribeServer, events []*dispatcher.StreamEvent) error {
+	for _, event := range events {
+		if err := stream.Send(newStreamEvent(event)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Ack implements the Ack RPC, the counterpart of POST /channel/:channelId/consumer/:consumerId/stream/ack
+func (server *BrokerServer) Ack(ctx context.Context, request *pb.AckRequest) (*emptypb.Empty, error) {
+	consumer, err := server.getStreamingConsumer(ctx, request.ChannelId, request.ConsumerId)
+	if err != nil {
+		return nil, err
+	}
+	if len(request.EventId) <= 0 {
+		return nil, errEventIDRequired
+	}
+	switch err = server.Streamer.Ack(consumer, request.EventId); err {
+	case nil:
+		return &emptypb.Empty{}, nil
+	case dispatcher.ErrStreamEventNotFound:
+		return nil, errNotFound
+	case storage.ErrNoRowsUpdated:
+		return nil, errStreamEventNotInflight
+	default:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+}
@@ -208,6 +208,24 @@ func updateChannel() (err error) {
 	return err
 }
 
+func grantProducer() (err error) {
+	req, _ := http.NewRequest(http.MethodPut, brokerBaseURL+"/channel/"+channelID+"/producers/"+producerID, nil)
+	var resp *http.Response
+	resp, err = client.Do(req)
+	if err == nil {
+		defer resp.Body.Close()
+	} else {
+		log.Println(err)
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		err = errDuringCreation
+		body, _ := io.ReadAll(resp.Body)
+		log.Println(resp.Status, string(body))
+	}
+	return err
+}
+
 func createConsumers(baseURI string) int {
 	for index := 0; index < consumerCount; index++ {
 		indexString := strconv.Itoa(index)
@@ -399,6 +417,11 @@ func testBasicObjectCreation(portString string) {
 		log.Println("error updating channel", err)
 		return
 	}
+	err = grantProducer()
+	if err != nil {
+		log.Println("error granting producer", err)
+		return
+	}
 	baseURLString := "http://" + consumerHostName + portString
 	count = createConsumers(baseURLString)
 	log.Println("number of consumers created", count)
@@ -2,12 +2,14 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"sync"
 	"syscall"
@@ -15,11 +17,14 @@ import (
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
 
 	"github.com/google/wire"
+	"github.com/newscred/webhook-broker/admincli"
 	"github.com/newscred/webhook-broker/config"
 	"github.com/newscred/webhook-broker/controllers"
 	"github.com/newscred/webhook-broker/dispatcher"
+	"github.com/newscred/webhook-broker/grpcapi"
 	"github.com/newscred/webhook-broker/storage"
 	"github.com/newscred/webhook-broker/storage/data"
 	lumberjack "gopkg.in/natefinch/lumberjack.v2"
@@ -50,8 +55,13 @@ type HTTPServiceContainer struct {
 	DataAccessor  storage.DataAccessor
 	Listener      *ServerLifecycleListenerImpl
 	Dispatcher    dispatcher.MessageDispatcher
+	GRPCServer    *grpc.Server
 }
 
+const (
+	adminCommand = "admin"
+)
+
 var (
 	exit = func(code int) {
 		os.Exit(code)
@@ -60,6 +70,13 @@ var (
 		fmt.Println(output)
 	}
 
+	// runAdminCommand runs `webhook-broker admin ...`, the command line client to manage a broker, until done or interrupted
+	runAdminCommand = func(programName string, args []string) int {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return admincli.Run(ctx, programName, args, os.Stdin, os.Stdout, os.Stderr)
+	}
+
 	// ErrMigrationSrcNotDir for error when migration source specified is not a directory
 	ErrMigrationSrcNotDir = errors.New("migration source not a dir")
 
@@ -154,7 +171,9 @@ var (
 				producer.Name = seedProducer.Name
 				_, err = dataAccessor.GetProducerRepository().Store(producer)
 			}
-			if err != nil {
+			if err == storage.ErrDeleted {
+				log.Warn().Msg("Skipping deleted producer, it is only recreated once purged: " + seedProducer.ID)
+			} else if err != nil {
 				log.Error().Err(err).Msg("Error creating producer: " + seedProducer.ID)
 			}
 		}
@@ -164,7 +183,9 @@ var (
 				channel.Name = seedChannel.Name
 				_, err = dataAccessor.GetChannelRepository().Store(channel)
 			}
-			if err != nil {
+			if err == storage.ErrDeleted {
+				log.Warn().Msg("Skipping deleted channel, it is only recreated once purged: " + seedChannel.ID)
+			} else if err != nil {
 				log.Error().Err(err).Msg("Error creating channel" + seedChannel.ID)
 			}
 		}
@@ -181,14 +202,33 @@ var (
 				consumer.CallbackURL = seedConsumer.CallbackURL.String()
 				_, err = dataAccessor.GetConsumerRepository().Store(consumer)
 			}
-			if err != nil {
+			if err == storage.ErrDeleted {
+				log.Warn().Msg("Skipping deleted consumer, it is only recreated once purged: " + seedConsumer.ID)
+			} else if err != nil {
 				log.Error().Err(err).Msg("Error creating consumer" + seedConsumer.ID)
 			}
 		}
+		for _, seedGrant := range seedDataConfig.GetSeedData().PublishGrants {
+			channel, err := dataAccessor.GetChannelRepository().Get(seedGrant.ChannelID)
+			var producer *data.Producer
+			if err == nil {
+				producer, err = dataAccessor.GetProducerRepository().Get(seedGrant.ProducerID)
+			}
+			if err == nil {
+				_, err = dataAccessor.GetPublishGrantRepository().Grant(channel, producer)
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("Error granting producer " + seedGrant.ProducerID + " to publish to channel " + seedGrant.ChannelID)
+			}
+		}
 	}
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == adminCommand {
+		exit(runAdminCommand(os.Args[0], os.Args[2:]))
+		return
+	}
 	log.Print("Webhook Broker - " + string(GetAppVersion()))
 	inConfig, output, cliCfgErr := parseArgs(os.Args[0], os.Args[1:])
 	if cliCfgErr != nil {
@@ -237,6 +277,7 @@ func main() {
 		// Setup Log Output
 		setupLogger(httpServiceContainer.Configuration)
 		<-httpServiceContainer.Listener.shutdownListener
+		grpcapi.StopServer(httpServiceContainer.GRPCServer)
 		httpServiceContainer.Dispatcher.Stop()
 	}
 	inConfig.StopWatcher()
@@ -304,8 +345,24 @@ func newLockRepository(dataAccessor storage.DataAccessor) storage.LockRepository
 	return dataAccessor.GetLockRepository()
 }
 
+func newReplayRepository(dataAccessor storage.DataAccessor) storage.ReplayRepository {
+	return dataAccessor.GetReplayRepository()
+}
+
+func newSchemaRepository(dataAccessor storage.DataAccessor) storage.SchemaRepository {
+	return dataAccessor.GetSchemaRepository()
+}
+
+func newPublishQuotaRepository(dataAccessor storage.DataAccessor) storage.PublishQuotaRepository {
+	return dataAccessor.GetPublishQuotaRepository()
+}
+
+func newPublishGrantRepository(dataAccessor storage.DataAccessor) storage.PublishGrantRepository {
+	return dataAccessor.GetPublishGrantRepository()
+}
+
 var (
-	httpServiceContainerInjectorSet = wire.NewSet(wire.Struct(new(HTTPServiceContainer), "Configuration", "Server", "DataAccessor", "Listener", "Dispatcher"))
+	httpServiceContainerInjectorSet = wire.NewSet(wire.Struct(new(HTTPServiceContainer), "Configuration", "Server", "DataAccessor", "Listener", "Dispatcher", "GRPCServer"))
 	configInjectorSet               = wire.NewSet(httpServiceContainerInjectorSet, NewServerListener, GetMigrationConfig, wire.Bind(new(controllers.ServerLifecycleListener), new(*ServerLifecycleListenerImpl)), config.ConfigInjector)
-	relationalDBWithControllerSet   = wire.NewSet(controllers.ControllerInjector, storage.GetNewDataAccessor, newLockRepository, newDeliveryJobRepository, newAppRepository, newChannelRepository, newProducerRepository, newConsumerRepository, newMessageRepository, dispatcher.DispatcherInjector)
+	relationalDBWithControllerSet   = wire.NewSet(controllers.ControllerInjector, storage.GetNewDataAccessor, storage.NewBlobStore, newLockRepository, newReplayRepository, newSchemaRepository, newPublishQuotaRepository, newPublishGrantRepository, newDeliveryJobRepository, newAppRepository, newChannelRepository, newProducerRepository, newConsumerRepository, newMessageRepository, dispatcher.DispatcherInjector, grpcapi.GRPCInjector)
 )
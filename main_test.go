@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -17,6 +18,7 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
+	"github.com/newscred/webhook-broker/admincli"
 	"github.com/newscred/webhook-broker/config"
 	"github.com/newscred/webhook-broker/controllers"
 	"github.com/newscred/webhook-broker/storage"
@@ -292,6 +294,28 @@ func TestMainFunc(t *testing.T) {
 			}()
 		}
 	})
+	t.Run("AdminCommand", func(t *testing.T) {
+		oldExit := exit
+		oldArgs := os.Args
+		oldRunAdminCommand := runAdminCommand
+		defer func() {
+			exit = oldExit
+			os.Args = oldArgs
+			runAdminCommand = oldRunAdminCommand
+		}()
+		var exitCode int
+		var adminArgs []string
+		exit = func(code int) { exitCode = code }
+		runAdminCommand = func(programName string, args []string) int {
+			adminArgs = args
+			return admincli.ExitUsage
+		}
+		os.Args = []string{"webhook-broker", "admin", "-profile", "prod", "channel", "list"}
+		main()
+		assert.Equal(t, admincli.ExitUsage, exitCode)
+		assert.Equal(t, []string{"-profile", "prod", "channel", "list"}, adminArgs)
+		assert.Equal(t, admincli.ExitOK, oldRunAdminCommand("webhook-broker", []string{"-profiles", filepath.Join(t.TempDir(), "none.cfg"), "help"}))
+	})
 }
 
 func TestParseArgs(t *testing.T) {
@@ -435,6 +459,7 @@ func TestCreateSeedData_ErrorFlows(t *testing.T) {
 		assert.Contains(t, "Error creating producer", buf.String())
 		assert.Contains(t, "Error creating channel", buf.String())
 		assert.Contains(t, "Error creating consumer", buf.String())
+		assert.Contains(t, "Error granting producer", buf.String())
 		log.Logger = oldLogger
 	}()
 	configuration, _ := config.GetAutoConfiguration()
@@ -448,6 +473,7 @@ func TestCreateSeedData_ErrorFlows(t *testing.T) {
 		dataAccessor.On("GetChannelRepository").Return(channelRepo)
 		dataAccessor.On("GetConsumerRepository").Return(consumerRepo)
 		productRepo.On("Store", mock.Anything).Return(nil, expectedErr)
+		productRepo.On("Get", mock.Anything).Return(nil, expectedErr)
 		channelRepo.On("Get", mock.Anything).Return(&data.Channel{}, nil)
 		channelRepo.On("Store", mock.Anything).Return(nil, expectedErr)
 		consumerRepo.On("Store", mock.Anything).Return(nil, expectedErr)
@@ -467,4 +493,23 @@ func TestCreateSeedData_ErrorFlows(t *testing.T) {
 		createSeedData(dataAccessor, configuration)
 		dataAccessor.AssertExpectations(t)
 	})
+	t.Run("Deleted", func(t *testing.T) {
+		t.Parallel()
+		dataAccessor := new(mocks.DataAccessor)
+		productRepo := new(mocks.ProducerRepository)
+		channelRepo := new(mocks.ChannelRepository)
+		consumerRepo := new(mocks.ConsumerRepository)
+		dataAccessor.On("GetProducerRepository").Return(productRepo)
+		dataAccessor.On("GetChannelRepository").Return(channelRepo)
+		dataAccessor.On("GetConsumerRepository").Return(consumerRepo)
+		productRepo.On("Store", mock.Anything).Return(&data.Producer{}, storage.ErrDeleted)
+		productRepo.On("Get", mock.Anything).Return(nil, sql.ErrNoRows)
+		channelRepo.On("Get", mock.Anything).Return(&data.Channel{}, nil)
+		channelRepo.On("Store", mock.Anything).Return(&data.Channel{}, storage.ErrDeleted)
+		consumerRepo.On("Store", mock.Anything).Return(&data.Consumer{}, storage.ErrDeleted)
+		createSeedData(dataAccessor, configuration)
+		productRepo.AssertNumberOfCalls(t, "Store", len(configuration.GetSeedData().Producers))
+		consumerRepo.AssertNumberOfCalls(t, "Store", len(configuration.GetSeedData().Consumers))
+		dataAccessor.AssertExpectations(t)
+	})
 }
@@ -0,0 +1,3 @@
+ALTER TABLE `consumer` DROP COLUMN `verificationChallenge`;
+
+ALTER TABLE `consumer` DROP COLUMN `verificationStatus`;
@@ -0,0 +1,3 @@
+ALTER TABLE `consumer` ADD COLUMN `verificationStatus` INTEGER NOT NULL DEFAULT 2000;
+
+ALTER TABLE `consumer` ADD COLUMN `verificationChallenge` VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE `consumer` DROP COLUMN `paused`;
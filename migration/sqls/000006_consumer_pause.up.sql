@@ -0,0 +1 @@
+ALTER TABLE `consumer` ADD COLUMN `paused` BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,7 @@
+DROP INDEX `replay_messages` ON `message`;
+
+DROP INDEX `replays_by_consumer` ON `replay`;
+
+DROP INDEX `replays_to_run` ON `replay`;
+
+DROP TABLE IF EXISTS `replay`;
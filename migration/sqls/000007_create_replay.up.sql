@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS `replay` (
+    `id` VARCHAR(255) NOT NULL PRIMARY KEY,
+    `consumerId` VARCHAR(255) NOT NULL,
+    `receivedFrom` DATETIME NULL,
+    `receivedTo` DATETIME NULL,
+    `messageIds` MEDIUMTEXT NOT NULL,
+    `status` INTEGER NOT NULL,
+    `totalCount` INTEGER NOT NULL DEFAULT 0,
+    `processedCount` INTEGER NOT NULL DEFAULT 0,
+    `deliveredCount` INTEGER NOT NULL DEFAULT 0,
+    `failedCount` INTEGER NOT NULL DEFAULT 0,
+    `cursorReceivedAt` DATETIME NULL,
+    `cursorId` VARCHAR(255) NOT NULL,
+    `leaseOwner` VARCHAR(255) NOT NULL,
+    `leaseExpiresAt` DATETIME NOT NULL,
+    `createdAt` DATETIME NOT NULL,
+    `updatedAt` DATETIME NOT NULL,
+    CONSTRAINT `consumerReplayRef` FOREIGN KEY (`consumerId`) REFERENCES consumer(`id`) ON UPDATE CASCADE ON DELETE RESTRICT
+);
+
+CREATE INDEX `replays_to_run` ON `replay` (`status`, `leaseExpiresAt`);
+
+CREATE INDEX `replays_by_consumer` ON `replay` (`consumerId`, `id`, `createdAt`);
+
+CREATE INDEX `replay_messages` ON `message` (`channelId`, `receivedAt`, `id`);
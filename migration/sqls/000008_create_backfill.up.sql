@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS `backfill` (
+    `id` VARCHAR(255) NOT NULL PRIMARY KEY,
+    `consumerId` VARCHAR(255) NOT NULL,
+    `receivedFrom` DATETIME NOT NULL,
+    `receivedTo` DATETIME NOT NULL,
+    `jobCount` INTEGER NOT NULL DEFAULT 0,
+    `createdAt` DATETIME NOT NULL,
+    `updatedAt` DATETIME NOT NULL,
+    UNIQUE (`consumerId`),
+    CONSTRAINT `consumerBackfillRef` FOREIGN KEY (`consumerId`) REFERENCES consumer(`id`) ON UPDATE CASCADE ON DELETE RESTRICT
+);
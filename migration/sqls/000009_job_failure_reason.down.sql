@@ -0,0 +1 @@
+ALTER TABLE `job` DROP COLUMN `failureReason`;
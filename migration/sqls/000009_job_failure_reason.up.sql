@@ -0,0 +1 @@
+ALTER TABLE `job` ADD COLUMN `failureReason` VARCHAR(255) NOT NULL DEFAULT '';
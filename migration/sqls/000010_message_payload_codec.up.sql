@@ -0,0 +1 @@
+ALTER TABLE `message` ADD COLUMN `payloadCodec` VARCHAR(16) NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+ALTER TABLE `channel` DROP COLUMN `maxPayloadSize`;
+ALTER TABLE `message` DROP COLUMN `payloadRef`;
@@ -0,0 +1,2 @@
+ALTER TABLE `message` ADD COLUMN `payloadRef` VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE `channel` ADD COLUMN `maxPayloadSize` BIGINT NOT NULL DEFAULT 0;
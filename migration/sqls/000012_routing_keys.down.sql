@@ -0,0 +1,2 @@
+ALTER TABLE `consumer` DROP COLUMN `routingKeyPattern`;
+ALTER TABLE `message` DROP COLUMN `routingKey`;
@@ -0,0 +1,2 @@
+ALTER TABLE `message` ADD COLUMN `routingKey` VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE `consumer` ADD COLUMN `routingKeyPattern` VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE `message` ADD COLUMN `attributes` VARCHAR(4096) NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+ALTER TABLE `message` DROP COLUMN `receiptUrl`;
+ALTER TABLE `producer` DROP COLUMN `receiptUrl`;
@@ -0,0 +1,2 @@
+ALTER TABLE `producer` ADD COLUMN `receiptUrl` VARCHAR(1000) NOT NULL DEFAULT '';
+ALTER TABLE `message` ADD COLUMN `receiptUrl` VARCHAR(1000) NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE `consumer` ADD COLUMN `consumerType` INTEGER NOT NULL DEFAULT 3000;
@@ -0,0 +1,2 @@
+ALTER TABLE `consumer` DROP COLUMN `deliveryFormat`;
+ALTER TABLE `message` DROP COLUMN `cloudEvent`;
@@ -0,0 +1,2 @@
+ALTER TABLE `message` ADD COLUMN `cloudEvent` VARCHAR(2048) NOT NULL DEFAULT '';
+ALTER TABLE `consumer` ADD COLUMN `deliveryFormat` INTEGER NOT NULL DEFAULT 4000;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS `channel_schema` (
+    `id` VARCHAR(255) NOT NULL PRIMARY KEY,
+    `channelId` VARCHAR(255) NOT NULL,
+    `version` INTEGER NOT NULL,
+    `definition` MEDIUMTEXT NOT NULL,
+    `createdAt` DATETIME NOT NULL,
+    `updatedAt` DATETIME NOT NULL,
+    UNIQUE (`channelId`, `version`),
+    CONSTRAINT `channelSchemaRef` FOREIGN KEY (`channelId`) REFERENCES channel(`channelId`) ON UPDATE CASCADE ON DELETE RESTRICT
+);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS `publish_quota` (
+    `id` VARCHAR(255) NOT NULL PRIMARY KEY,
+    `subjectType` VARCHAR(20) NOT NULL,
+    `subjectId` VARCHAR(255) NOT NULL,
+    `messagesPerSecond` INTEGER NOT NULL DEFAULT 0,
+    `burst` INTEGER NOT NULL DEFAULT 0,
+    `bytesPerMinute` BIGINT NOT NULL DEFAULT 0,
+    `messageTokens` DOUBLE NOT NULL DEFAULT 0,
+    `byteTokens` DOUBLE NOT NULL DEFAULT 0,
+    `refilledAt` DATETIME NULL,
+    `revision` INTEGER NOT NULL DEFAULT 0,
+    `createdAt` DATETIME NOT NULL,
+    `updatedAt` DATETIME NOT NULL,
+    UNIQUE (`subjectType`, `subjectId`)
+);
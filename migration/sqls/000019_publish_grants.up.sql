@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS `publish_grant` (
+    `id` VARCHAR(255) NOT NULL PRIMARY KEY,
+    `channelId` VARCHAR(255) NOT NULL,
+    `producerId` VARCHAR(255) NOT NULL,
+    `createdAt` DATETIME NOT NULL,
+    `updatedAt` DATETIME NOT NULL,
+    UNIQUE (`channelId`, `producerId`),
+    CONSTRAINT `channelPublishGrantRef` FOREIGN KEY (`channelId`) REFERENCES channel(`channelId`) ON UPDATE CASCADE ON DELETE RESTRICT,
+    CONSTRAINT `producerPublishGrantRef` FOREIGN KEY (`producerId`) REFERENCES producer(`producerId`) ON UPDATE CASCADE ON DELETE RESTRICT
+);
+
+CREATE INDEX `publish_grants_by_producer` on `publish_grant` (`producerId`, `id`, `createdAt`);
+
+INSERT INTO `publish_grant` (`id`, `channelId`, `producerId`, `createdAt`, `updatedAt`)
+    SELECT MIN(`id`), `channelId`, `producerId`, MIN(`createdAt`), MIN(`createdAt`) FROM `message` GROUP BY `channelId`, `producerId`;
@@ -0,0 +1,3 @@
+ALTER TABLE `consumer` DROP COLUMN `deletedAt`;
+ALTER TABLE `channel` DROP COLUMN `deletedAt`;
+ALTER TABLE `producer` DROP COLUMN `deletedAt`;
@@ -0,0 +1,3 @@
+ALTER TABLE `producer` ADD COLUMN `deletedAt` DATETIME NULL;
+ALTER TABLE `channel` ADD COLUMN `deletedAt` DATETIME NULL;
+ALTER TABLE `consumer` ADD COLUMN `deletedAt` DATETIME NULL;
@@ -0,0 +1,7 @@
+DROP INDEX `jobs_by_message_status` on `job`;
+
+DROP INDEX `messages_by_channel_received` on `message`;
+
+DROP INDEX `messages_by_channel_producer` on `message`;
+
+DROP INDEX `messages_by_channel_status` on `message`;
@@ -0,0 +1,7 @@
+CREATE INDEX `messages_by_channel_status` on `message` (`channelId`, `status`, `id`, `createdAt`);
+
+CREATE INDEX `messages_by_channel_producer` on `message` (`channelId`, `producerId`, `id`, `createdAt`);
+
+CREATE INDEX `messages_by_channel_received` on `message` (`channelId`, `receivedAt`, `id`, `createdAt`);
+
+CREATE INDEX `jobs_by_message_status` on `job` (`messageId`, `status`);
@@ -0,0 +1,5 @@
+DROP INDEX `jobs_by_backfill_status` on `job`;
+
+ALTER TABLE `backfill` DROP COLUMN `jobsCreated`;
+
+ALTER TABLE `job` DROP COLUMN `backfillId`;
@@ -0,0 +1,9 @@
+ALTER TABLE `job` ADD COLUMN `backfillId` VARCHAR(255) NULL;
+
+ALTER TABLE `backfill` ADD COLUMN `jobsCreated` BOOLEAN NOT NULL DEFAULT TRUE;
+
+UPDATE `job` SET `backfillId` = (SELECT `backfill`.`id` FROM `backfill` WHERE `backfill`.`consumerId` = `job`.`consumerId`)
+WHERE EXISTS (SELECT 1 FROM `backfill` JOIN `message` ON `message`.`id` = `job`.`messageId`
+    WHERE `backfill`.`consumerId` = `job`.`consumerId` AND `message`.`receivedAt` >= `backfill`.`receivedFrom` AND `message`.`receivedAt` <= `backfill`.`receivedTo`);
+
+CREATE INDEX `jobs_by_backfill_status` on `job` (`backfillId`, `status`);
@@ -0,0 +1,3 @@
+DROP INDEX `jobs_by_replay_status` on `job`;
+
+ALTER TABLE `job` DROP COLUMN `replayId`;
@@ -0,0 +1,3 @@
+ALTER TABLE `job` ADD COLUMN `replayId` VARCHAR(255) NULL;
+
+CREATE INDEX `jobs_by_replay_status` on `job` (`replayId`, `status`);
@@ -0,0 +1,3 @@
+ALTER TABLE `app` DROP COLUMN `payloadRecompressionLeaseExpiresAt`;
+
+ALTER TABLE `app` DROP COLUMN `payloadsRecompressedFor`;
@@ -0,0 +1,3 @@
+ALTER TABLE `app` ADD COLUMN `payloadsRecompressedFor` VARCHAR(255) NOT NULL DEFAULT '';
+
+ALTER TABLE `app` ADD COLUMN `payloadRecompressionLeaseExpiresAt` DATETIME NULL;
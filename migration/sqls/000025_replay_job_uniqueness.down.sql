@@ -0,0 +1,41 @@
+CREATE TABLE `job_without_replays` (
+    `id` VARCHAR(255) NOT NULL PRIMARY KEY,
+    `messageId` VARCHAR(255) NOT NULL,
+    `consumerId` VARCHAR(255) NOT NULL,
+    `status` INTEGER NOT NULL,
+    `retryAttemptCount` INTEGER NOT NULL DEFAULT 0,
+    `statusChangedAt` DATETIME NOT NULL,
+    `dispatchReceivedAt` DATETIME NOT NULL,
+    `earliestNextAttemptAt` DATETIME NOT NULL,
+    `createdAt` DATETIME NOT NULL,
+    `updatedAt` DATETIME NOT NULL,
+    `failureReason` VARCHAR(255) NOT NULL DEFAULT '',
+    `backfillId` VARCHAR(255) NULL,
+    `replayId` VARCHAR(255) NULL,
+    UNIQUE (`messageId`, `consumerId`),
+    FOREIGN KEY (`messageId`) REFERENCES message(`id`) ON UPDATE CASCADE ON DELETE RESTRICT,
+    FOREIGN KEY (`consumerId`) REFERENCES consumer(`id`) ON UPDATE CASCADE ON DELETE RESTRICT
+);
+
+INSERT INTO `job_without_replays` (`id`, `messageId`, `consumerId`, `status`, `retryAttemptCount`, `statusChangedAt`, `dispatchReceivedAt`, `earliestNextAttemptAt`, `createdAt`, `updatedAt`, `failureReason`, `backfillId`, `replayId`)
+SELECT `id`, `messageId`, `consumerId`, `status`, `retryAttemptCount`, `statusChangedAt`, `dispatchReceivedAt`, `earliestNextAttemptAt`, `createdAt`, `updatedAt`, `failureReason`, `backfillId`, NULL FROM `job` WHERE `replayId` = '';
+
+DROP TABLE `job`;
+
+ALTER TABLE `job_without_replays` RENAME TO `job`;
+
+CREATE INDEX `retry_job` on `job` (`status`, `earliestNextAttemptAt`, `id`, `createdAt`);
+
+CREATE INDEX `force_timeout_job` on `job` (`status`, `statusChangedAt`, `id`, `createdAt`);
+
+CREATE INDEX `jobs_by_message` on `job` (`messageId`, `id`, `createdAt`);
+
+CREATE INDEX `jobs_by_consumer` on `job` (`consumerId`, `status`, `id`, `createdAt`);
+
+CREATE INDEX `order_job_createdAt_id` ON `job` (`createdAt`, `id`);
+
+CREATE INDEX `jobs_by_message_status` on `job` (`messageId`, `status`);
+
+CREATE INDEX `jobs_by_backfill_status` on `job` (`backfillId`, `status`);
+
+CREATE INDEX `jobs_by_replay_status` on `job` (`replayId`, `status`);
@@ -0,0 +1,5 @@
+DROP INDEX `backfills_to_run` on `backfill`;
+
+ALTER TABLE `backfill` DROP COLUMN `leaseExpiresAt`;
+
+ALTER TABLE `backfill` DROP COLUMN `leaseOwner`;
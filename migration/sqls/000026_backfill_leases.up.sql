@@ -0,0 +1,5 @@
+ALTER TABLE `backfill` ADD COLUMN `leaseOwner` VARCHAR(255) NOT NULL DEFAULT '';
+
+ALTER TABLE `backfill` ADD COLUMN `leaseExpiresAt` DATETIME NULL;
+
+CREATE INDEX `backfills_to_run` on `backfill` (`jobsCreated`, `leaseExpiresAt`);
@@ -0,0 +1 @@
+ALTER TABLE `app` DROP COLUMN `payloadRecompressionLeaseOwner`;
@@ -0,0 +1 @@
+ALTER TABLE `app` ADD COLUMN `payloadRecompressionLeaseOwner` VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1,252 @@
+// Package publish is the pipeline a message goes through to be accepted for broadcast - validation, schema conformance, payload offloading,
+// publish rate limits and storage - shared by the HTTP and the gRPC APIs so that both accept exactly the same messages
+package publish
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+
+	"github.com/newscred/webhook-broker/config"
+	"github.com/newscred/webhook-broker/storage"
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+var (
+	// ErrPayloadTooLarge is returned when the payload exceeds the maximum payload size of the channel, else of the broker
+	ErrPayloadTooLarge = errors.New("payload exceeds the maximum payload size of the channel")
+	// ErrInvalidRoutingKey is returned when the message's routing key is not dot separated words
+	ErrInvalidRoutingKey = errors.New("routing key must be dot separated words of letters, digits, `_` and `-`")
+	// ErrInvalidAttributes is returned when the message's attributes have invalid names or values or are too large
+	ErrInvalidAttributes = errors.New("message attribute names must be letters, digits and `-`, values must not have line breaks and all attributes serialized as JSON must not exceed 4096 bytes")
+	// ErrInvalidReceiptURL is returned when the receipt URL of the message is not an absolute HTTP(S) URL
+	ErrInvalidReceiptURL = errors.New("receipt URL must be an absolute `http` or `https` URL of at most 1000 characters")
+	// ErrInvalidSchemaVersion is returned when the message's `Schema-Version` attribute or `schema-version` content type parameter is not
+	// a version of the channel's schema
+	ErrInvalidSchemaVersion = errors.New("`Schema-Version` attribute or `schema-version` content type parameter must be an existing version of the channel's schema")
+	// ErrPayloadNotConforming is the message of the SchemaValidationError
+	ErrPayloadNotConforming = errors.New("payload does not conform to the schema of the channel")
+	// ErrRateLimitExceeded is returned when publishing the message would exceed the publish rate limit of the producer or the channel
+	ErrRateLimitExceeded = errors.New("publish rate limit of the producer or the channel exceeded")
+)
+
+// SchemaValidationError is returned when the payload does not conform to the schema version it was validated against
+type SchemaValidationError struct {
+	SchemaVersion uint
+	Violations    []*data.SchemaViolation
+}
+
+func (validationErr *SchemaValidationError) Error() string {
+	return ErrPayloadNotConforming.Error()
+}
+
+// Publisher validates, rate limits and stores the messages published to channels
+type Publisher struct {
+	MessageRepository storage.MessageRepository
+	SchemaRepository  storage.SchemaRepository
+	QuotaRepository   storage.PublishQuotaRepository
+	BlobStore         storage.BlobStore
+	BlobStoreConfig   config.BlobStoreConfig
+	BrokerConfig      config.BrokerConfig
+	RateLimitConfig   config.RateLimitConfig
+	compiledSchemas   sync.Map
+}
+
+// NewPublisher creates a new instance of the publish pipeline
+func NewPublisher(msgRepo storage.MessageRepository, schemaRepo storage.SchemaRepository, quotaRepo storage.PublishQuotaRepository, blobStore storage.BlobStore,
+	blobStoreConfig config.BlobStoreConfig, brokerConfig config.BrokerConfig, rateLimitConfig config.RateLimitConfig) *Publisher {
+	return &Publisher{MessageRepository: msgRepo, SchemaRepository: schemaRepo, QuotaRepository: quotaRepo, BlobStore: blobStore, BlobStoreConfig: blobStoreConfig,
+		BrokerConfig: brokerConfig, RateLimitConfig: rateLimitConfig}
+}
+
+// GetMaxPayloadSize returns the maximum payload size of the channel, else of the broker; 0 means unlimited
+func (publisher *Publisher) GetMaxPayloadSize(channel *data.Channel) uint {
+	if channel.MaxPayloadSize > 0 {
+		return channel.MaxPayloadSize
+	}
+	return publisher.BrokerConfig.GetMaxPayloadSize()
+}
+
+// CheckPayloadSize returns ErrPayloadTooLarge if a payload of the size exceeds the maximum payload size for the channel
+func (publisher *Publisher) CheckPayloadSize(channel *data.Channel, size uint) error {
+	if maxPayloadSize := publisher.GetMaxPayloadSize(channel); maxPayloadSize > 0 && size > maxPayloadSize {
+		return ErrPayloadTooLarge
+	}
+	return nil
+}
+
+// ValidateMessage validates the routing key, the attributes and the receipt URL of the message
+func (publisher *Publisher) ValidateMessage(routingKey string, attributes data.MessageAttributes, receiptURL string) error {
+	switch {
+	case !data.IsValidRoutingKey(routingKey):
+		return ErrInvalidRoutingKey
+	case !attributes.IsValid():
+		return ErrInvalidAttributes
+	case !data.IsValidReceiptURL(receiptURL):
+		return ErrInvalidReceiptURL
+	default:
+		return nil
+	}
+}
+
+// getChannelSchema returns the schema version the message must conform to, the latest version unless one is requested; nil if the channel has
+// no schema. Returns ErrInvalidSchemaVersion if the requested version is malformed or does not exist.
+func (publisher *Publisher) getChannelSchema(channel *data.Channel, contentType string, attributes data.MessageAttributes) (*data.ChannelSchema, error) {
+	version, err := data.GetRequestedSchemaVersion(contentType, attributes)
+	if err != nil {
+		return nil, ErrInvalidSchemaVersion
+	}
+	var schema *data.ChannelSchema
+	if version > 0 {
+		schema, err = publisher.SchemaRepository.Get(channel, version)
+	} else {
+		schema, err = publisher.SchemaRepository.GetLatest(channel)
+	}
+	switch {
+	case err == sql.ErrNoRows && version > 0:
+		return nil, ErrInvalidSchemaVersion
+	case err == sql.ErrNoRows:
+		return nil, nil
+	default:
+		return schema, err
+	}
+}
+
+// CheckPayloadSchema reads the whole body to validate it against the channel's schema and returns the reader of the validated payload; the body is
+// returned as is if the channel has no schema. Returns *SchemaValidationError if the payload does not conform to the schema.
+func (publisher *Publisher) CheckPayloadSchema(channel *data.Channel, contentType string, attributes data.MessageAttributes, body io.Reader) (io.Reader, error) {
+	schema, err := publisher.getChannelSchema(channel, contentType, attributes)
+	if err != nil || schema == nil {
+		return body, err
+	}
+	payload, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	compiled, err := publisher.getCompiledSchema(schema)
+	var violations []*data.SchemaViolation
+	if err == nil {
+		violations, err = data.GetSchemaViolations(compiled, payload)
+	}
+	if err == nil && len(violations) > 0 {
+		err = &SchemaValidationError{SchemaVersion: schema.Version, Violations: violations}
+	}
+	return bytes.NewReader(payload), err
+}
+
+// getCompiledSchema compiles the schema once per version as versions are immutable
+func (publisher *Publisher) getCompiledSchema(schema *data.ChannelSchema) (*jsonschema.Schema, error) {
+	if compiled, ok := publisher.compiledSchemas.Load(schema.ID.String()); ok {
+		return compiled.(*jsonschema.Schema), nil
+	}
+	compiled, err := schema.Compile()
+	if err == nil {
+		publisher.compiledSchemas.Store(schema.ID.String(), compiled)
+	}
+	return compiled, err
+}
+
+// StorePayload reads the body as the payload to be stored inline unless it is larger than the offload threshold, in which case it is streamed
+// to the blob store and only the reference to it is returned
+func (publisher *Publisher) StorePayload(body io.Reader) (payload string, payloadRef string, err error) {
+	if publisher.BlobStore == nil {
+		var content []byte
+		content, err = ioutil.ReadAll(body)
+		return string(content), "", err
+	}
+	threshold := int64(publisher.BlobStoreConfig.GetPayloadOffloadThreshold())
+	head, err := ioutil.ReadAll(io.LimitReader(body, threshold+1))
+	if err != nil || int64(len(head)) <= threshold {
+		return string(head), "", err
+	}
+	payloadRef, err = publisher.BlobStore.Put(io.MultiReader(bytes.NewReader(head), body))
+	return "", payloadRef, err
+}
+
+// DeletePayloadBlob deletes the offloaded payload of a message that was not accepted; failures are only logged
+func (publisher *Publisher) DeletePayloadBlob(payloadRef string) {
+	if len(payloadRef) > 0 {
+		if err := publisher.BlobStore.Delete(payloadRef); err != nil {
+			log.Error().Err(err).Msg("error - could not delete blob of rejected message " + payloadRef)
+		}
+	}
+}
+
+// takeTokens takes the tokens for the producer to publish a message of the size to the channel; returns how long to wait before retrying if the
+// rate limit of either is exceeded or if the quotas are too contended to take the tokens from right now
+func (publisher *Publisher) takeTokens(message *data.Message, size uint) (time.Duration, error) {
+	wait, err := publisher.QuotaRepository.TakeTokens(size, data.NewPublishClaims(message.ProducedBy, message.BroadcastedTo, publisher.RateLimitConfig)...)
+	if err == storage.ErrQuotaChangedConcurrently {
+		return storage.QuotaChangedConcurrentlyWait, nil
+	}
+	return wait, err
+}
+
+// returnTokens returns the publish tokens taken for a message that turned out to be a duplicate, so that retrying a publish does not use up quota
+func (publisher *Publisher) returnTokens(message *data.Message, size uint) {
+	err := publisher.QuotaRepository.ReturnTokens(size, data.NewPublishClaims(message.ProducedBy, message.BroadcastedTo, publisher.RateLimitConfig)...)
+	if err != nil {
+		log.Error().Err(err).Msg("error - could not return publish tokens of duplicate message " + message.MessageID)
+	}
+}
+
+// Publish takes the publish tokens for the message whose payload is of the size and stores it; if the message is rejected, ErrRateLimitExceeded
+// along with how long to wait before retrying when rate limited, its offloaded payload is deleted. The tokens of a duplicate are returned.
+func (publisher *Publisher) Publish(message *data.Message, size uint) (time.Duration, error) {
+	wait, err := publisher.takeTokens(message, size)
+	if err == nil && wait > 0 {
+		err = ErrRateLimitExceeded
+	}
+	if err == nil {
+		err = publisher.MessageRepository.Create(message)
+		if err == storage.ErrDuplicateMessageIDForChannel {
+			publisher.returnTokens(message, size)
+		}
+	}
+	if err != nil {
+		publisher.DeletePayloadBlob(message.PayloadRef)
+	}
+	return wait, err
+}
+
+// PublishBatch stores the messages whose payloads are of the sizes, rejecting each that is a duplicate or exceeds the publish rate limit on its own;
+// returns the error of each message, in order, and the longest wait before the rate limited messages can be retried. The offloaded payloads of
+// rejected messages are deleted.
+func (publisher *Publisher) PublishBatch(messages []*data.Message, sizes []uint) ([]error, time.Duration) {
+	var rateLimitWait time.Duration
+	payloadSizes := make(map[*data.Message]uint, len(messages))
+	for index, message := range messages {
+		payloadSizes[message] = sizes[index]
+	}
+	// Publish tokens are only taken for messages that are not rejected as duplicates; they are returned for those found to be duplicates after
+	// all, i.e. of a concurrent request
+	admitted := make(map[*data.Message]bool, len(messages))
+	takeTokens := func(message *data.Message) error {
+		wait, err := publisher.takeTokens(message, payloadSizes[message])
+		if err == nil && wait > 0 {
+			if wait > rateLimitWait {
+				rateLimitWait = wait
+			}
+			err = ErrRateLimitExceeded
+		}
+		admitted[message] = err == nil
+		return err
+	}
+	errs := publisher.MessageRepository.CreateBatch(messages, takeTokens)
+	for index, err := range errs {
+		if err == storage.ErrDuplicateMessageIDForChannel && admitted[messages[index]] {
+			publisher.returnTokens(messages[index], sizes[index])
+		}
+		if err != nil {
+			publisher.DeletePayloadBlob(messages[index].PayloadRef)
+		}
+	}
+	return errs, rateLimitWait
+}
@@ -0,0 +1,205 @@
+package publish
+
+import (
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/newscred/webhook-broker/config"
+	configmocks "github.com/newscred/webhook-broker/config/mocks"
+	"github.com/newscred/webhook-broker/storage"
+	"github.com/newscred/webhook-broker/storage/data"
+	storagemocks "github.com/newscred/webhook-broker/storage/mocks"
+)
+
+func getTestMessage(t *testing.T, payloadRef string) *data.Message {
+	channel, err := data.NewChannel("publish-test-channel", "publish-test-channel-token")
+	assert.Nil(t, err)
+	producer, err := data.NewProducer("publish-test-producer", "publish-test-producer-token")
+	assert.Nil(t, err)
+	message, err := data.NewMessage(channel, producer, "hello", "text/plain")
+	assert.Nil(t, err)
+	message.PayloadRef = payloadRef
+	return message
+}
+
+func getTestRateLimitConfig() *configmocks.RateLimitConfig {
+	rateLimitConfig := new(configmocks.RateLimitConfig)
+	rateLimitConfig.On("GetProducerRateLimit").Return(config.PublishRateLimit{})
+	rateLimitConfig.On("GetChannelRateLimit").Return(config.PublishRateLimit{})
+	return rateLimitConfig
+}
+
+func TestGetMaxPayloadSize(t *testing.T) {
+	brokerConfig := new(configmocks.BrokerConfig)
+	brokerConfig.On("GetMaxPayloadSize").Return(uint(4))
+	publisher := NewPublisher(nil, nil, nil, nil, nil, brokerConfig, nil)
+	channel, _ := data.NewChannel("publish-payload-size-channel", "token")
+	assert.Equal(t, uint(4), publisher.GetMaxPayloadSize(channel))
+	assert.Equal(t, ErrPayloadTooLarge, publisher.CheckPayloadSize(channel, 5))
+	channel.MaxPayloadSize = 8
+	assert.Equal(t, uint(8), publisher.GetMaxPayloadSize(channel))
+	assert.Nil(t, publisher.CheckPayloadSize(channel, 5))
+}
+
+func TestValidateMessage(t *testing.T) {
+	publisher := &Publisher{}
+	assert.Nil(t, publisher.ValidateMessage("order.created", data.MessageAttributes{"tenant": "acme"}, "https://example.com/receipt"))
+	assert.Equal(t, ErrInvalidRoutingKey, publisher.ValidateMessage("order..created", nil, ""))
+	assert.Equal(t, ErrInvalidAttributes, publisher.ValidateMessage("", data.MessageAttributes{"bad name": "value"}, ""))
+	assert.Equal(t, ErrInvalidReceiptURL, publisher.ValidateMessage("", nil, "ftp://example.com/receipt"))
+}
+
+func TestCheckPayloadSchema(t *testing.T) {
+	channel, _ := data.NewChannel("publish-schema-channel", "token")
+	t.Run("NoSchema", func(t *testing.T) {
+		schemaRepo := new(storagemocks.SchemaRepository)
+		schemaRepo.On("GetLatest", channel).Return(nil, sql.ErrNoRows)
+		body := strings.NewReader("anything")
+		reader, err := NewPublisher(nil, schemaRepo, nil, nil, nil, nil, nil).CheckPayloadSchema(channel, "text/plain", nil, body)
+		assert.Nil(t, err)
+		assert.Equal(t, body, reader)
+	})
+	t.Run("UnknownVersion", func(t *testing.T) {
+		schemaRepo := new(storagemocks.SchemaRepository)
+		schemaRepo.On("Get", channel, uint(2)).Return(nil, sql.ErrNoRows)
+		_, err := NewPublisher(nil, schemaRepo, nil, nil, nil, nil, nil).CheckPayloadSchema(channel, "application/json; schema-version=2", nil, strings.NewReader("{}"))
+		assert.Equal(t, ErrInvalidSchemaVersion, err)
+	})
+	t.Run("NotConforming", func(t *testing.T) {
+		schema, err := data.NewChannelSchema(channel, 1, `{"type": "object", "properties": {"id": {"type": "integer"}}}`)
+		assert.Nil(t, err)
+		schemaRepo := new(storagemocks.SchemaRepository)
+		schemaRepo.On("GetLatest", channel).Return(schema, nil)
+		publisher := NewPublisher(nil, schemaRepo, nil, nil, nil, nil, nil)
+		reader, err := publisher.CheckPayloadSchema(channel, "application/json", nil, strings.NewReader(`{"id": 1}`))
+		assert.Nil(t, err)
+		payload, _ := ioutil.ReadAll(reader)
+		assert.Equal(t, `{"id": 1}`, string(payload))
+		_, err = publisher.CheckPayloadSchema(channel, "application/json", nil, strings.NewReader(`{"id": "one"}`))
+		var validationErr *SchemaValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		assert.Equal(t, uint(1), validationErr.SchemaVersion)
+		assert.Equal(t, 1, len(validationErr.Violations))
+		assert.Equal(t, ErrPayloadNotConforming.Error(), err.Error())
+	})
+}
+
+func TestStorePayload(t *testing.T) {
+	t.Run("NoBlobStore", func(t *testing.T) {
+		payload, payloadRef, err := (&Publisher{}).StorePayload(strings.NewReader("inline"))
+		assert.Nil(t, err)
+		assert.Equal(t, "inline", payload)
+		assert.Empty(t, payloadRef)
+	})
+	blobStoreConfig := new(configmocks.BlobStoreConfig)
+	blobStoreConfig.On("GetPayloadOffloadThreshold").Return(uint(4))
+	blobStore := new(storagemocks.BlobStore)
+	blobStore.On("Put", mock.Anything).Return("blob-ref", nil)
+	publisher := NewPublisher(nil, nil, nil, blobStore, blobStoreConfig, nil, nil)
+	t.Run("WithinThreshold", func(t *testing.T) {
+		payload, payloadRef, err := publisher.StorePayload(strings.NewReader("four"))
+		assert.Nil(t, err)
+		assert.Equal(t, "four", payload)
+		assert.Empty(t, payloadRef)
+	})
+	t.Run("Offloaded", func(t *testing.T) {
+		payload, payloadRef, err := publisher.StorePayload(strings.NewReader("offloaded"))
+		assert.Nil(t, err)
+		assert.Empty(t, payload)
+		assert.Equal(t, "blob-ref", payloadRef)
+		blobStore.AssertNumberOfCalls(t, "Put", 1)
+	})
+}
+
+func TestPublish(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		quotaRepo := new(storagemocks.PublishQuotaRepository)
+		quotaRepo.On("TakeTokens", uint(5), mock.Anything, mock.Anything).Return(time.Duration(0), nil)
+		msgRepo := new(storagemocks.MessageRepository)
+		msgRepo.On("Create", mock.Anything).Return(nil)
+		wait, err := NewPublisher(msgRepo, nil, quotaRepo, nil, nil, nil, getTestRateLimitConfig()).Publish(getTestMessage(t, ""), 5)
+		assert.Nil(t, err)
+		assert.Equal(t, time.Duration(0), wait)
+		msgRepo.AssertExpectations(t)
+	})
+	t.Run("RateLimited", func(t *testing.T) {
+		quotaRepo := new(storagemocks.PublishQuotaRepository)
+		quotaRepo.On("TakeTokens", mock.Anything, mock.Anything, mock.Anything).Return(2*time.Second, nil)
+		blobStore := new(storagemocks.BlobStore)
+		blobStore.On("Delete", "blob-ref").Return(nil)
+		msgRepo := new(storagemocks.MessageRepository)
+		wait, err := NewPublisher(msgRepo, nil, quotaRepo, blobStore, nil, nil, getTestRateLimitConfig()).Publish(getTestMessage(t, "blob-ref"), 5)
+		assert.Equal(t, ErrRateLimitExceeded, err)
+		assert.Equal(t, 2*time.Second, wait)
+		blobStore.AssertExpectations(t)
+		msgRepo.AssertNotCalled(t, "Create", mock.Anything)
+	})
+	t.Run("ChangedConcurrently", func(t *testing.T) {
+		quotaRepo := new(storagemocks.PublishQuotaRepository)
+		quotaRepo.On("TakeTokens", mock.Anything, mock.Anything, mock.Anything).Return(time.Duration(0), storage.ErrQuotaChangedConcurrently)
+		wait, err := NewPublisher(nil, nil, quotaRepo, nil, nil, nil, getTestRateLimitConfig()).Publish(getTestMessage(t, ""), 5)
+		assert.Equal(t, ErrRateLimitExceeded, err)
+		assert.Equal(t, storage.QuotaChangedConcurrentlyWait, wait)
+	})
+	t.Run("Duplicate", func(t *testing.T) {
+		quotaRepo := new(storagemocks.PublishQuotaRepository)
+		quotaRepo.On("TakeTokens", mock.Anything, mock.Anything, mock.Anything).Return(time.Duration(0), nil)
+		quotaRepo.On("ReturnTokens", uint(5), mock.Anything, mock.Anything).Return(errors.New("return error"))
+		msgRepo := new(storagemocks.MessageRepository)
+		msgRepo.On("Create", mock.Anything).Return(storage.ErrDuplicateMessageIDForChannel)
+		blobStore := new(storagemocks.BlobStore)
+		blobStore.On("Delete", "blob-ref").Return(errors.New("delete error"))
+		_, err := NewPublisher(msgRepo, nil, quotaRepo, blobStore, nil, nil, getTestRateLimitConfig()).Publish(getTestMessage(t, "blob-ref"), 5)
+		assert.Equal(t, storage.ErrDuplicateMessageIDForChannel, err)
+		blobStore.AssertExpectations(t)
+		quotaRepo.AssertExpectations(t)
+	})
+}
+
+func TestPublishBatch(t *testing.T) {
+	quotaRepo := new(storagemocks.PublishQuotaRepository)
+	quotaRepo.On("TakeTokens", uint(1), mock.Anything, mock.Anything).Return(time.Duration(0), nil)
+	quotaRepo.On("TakeTokens", uint(2), mock.Anything, mock.Anything).Return(3*time.Second, nil)
+	msgRepo := new(storagemocks.MessageRepository)
+	msgRepo.On("CreateBatch", mock.Anything, mock.Anything).Return(func(messages []*data.Message, admit func(message *data.Message) error) []error {
+		errs := make([]error, 0, len(messages))
+		for _, message := range messages {
+			errs = append(errs, admit(message))
+		}
+		return errs
+	})
+	blobStore := new(storagemocks.BlobStore)
+	blobStore.On("Delete", "rejected-blob-ref").Return(nil)
+	messages := []*data.Message{getTestMessage(t, "accepted-blob-ref"), getTestMessage(t, "rejected-blob-ref")}
+	errs, wait := NewPublisher(msgRepo, nil, quotaRepo, blobStore, nil, nil, getTestRateLimitConfig()).PublishBatch(messages, []uint{1, 2})
+	assert.Equal(t, []error{nil, ErrRateLimitExceeded}, errs)
+	assert.Equal(t, 3*time.Second, wait)
+	blobStore.AssertExpectations(t)
+}
+
+func TestPublishBatchDuplicate(t *testing.T) {
+	quotaRepo := new(storagemocks.PublishQuotaRepository)
+	quotaRepo.On("TakeTokens", mock.Anything, mock.Anything, mock.Anything).Return(time.Duration(0), nil)
+	quotaRepo.On("ReturnTokens", uint(2), mock.Anything, mock.Anything).Return(nil)
+	msgRepo := new(storagemocks.MessageRepository)
+	// The first is rejected as a duplicate before tokens are taken, the second is found to be a duplicate on insert
+	msgRepo.On("CreateBatch", mock.Anything, mock.Anything).Return(func(messages []*data.Message, admit func(message *data.Message) error) []error {
+		admit(messages[1])
+		return []error{storage.ErrDuplicateMessageIDForChannel, storage.ErrDuplicateMessageIDForChannel}
+	})
+	blobStore := new(storagemocks.BlobStore)
+	blobStore.On("Delete", mock.Anything).Return(nil)
+	messages := []*data.Message{getTestMessage(t, "first-blob-ref"), getTestMessage(t, "second-blob-ref")}
+	errs, _ := NewPublisher(msgRepo, nil, quotaRepo, blobStore, nil, nil, getTestRateLimitConfig()).PublishBatch(messages, []uint{1, 2})
+	assert.Equal(t, []error{storage.ErrDuplicateMessageIDForChannel, storage.ErrDuplicateMessageIDForChannel}, errs)
+	quotaRepo.AssertNumberOfCalls(t, "TakeTokens", 1)
+	quotaRepo.AssertNumberOfCalls(t, "ReturnTokens", 1)
+	blobStore.AssertNumberOfCalls(t, "Delete", 2)
+}
@@ -12,6 +12,20 @@ type MockChannelRepository struct {
 	mock.Mock
 }
 
+// Delete provides a mock function with given fields: channel, drain
+func (_m *MockChannelRepository) Delete(channel *data.Channel, drain bool) error {
+	ret := _m.Called(channel, drain)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*data.Channel, bool) error); ok {
+		r0 = rf(channel, drain)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Get provides a mock function with given fields: channelID
 func (_m *MockChannelRepository) Get(channelID string) (*data.Channel, error) {
 	ret := _m.Called(channelID)
@@ -35,6 +49,29 @@ func (_m *MockChannelRepository) Get(channelID string) (*data.Channel, error) {
 	return r0, r1
 }
 
+// GetIncludingDeleted provides a mock function with given fields: channelID
+func (_m *MockChannelRepository) GetIncludingDeleted(channelID string) (*data.Channel, error) {
+	ret := _m.Called(channelID)
+
+	var r0 *data.Channel
+	if rf, ok := ret.Get(0).(func(string) *data.Channel); ok {
+		r0 = rf(channelID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*data.Channel)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(channelID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetList provides a mock function with given fields: page
 func (_m *MockChannelRepository) GetList(page *data.Pagination) ([]*data.Channel, *data.Pagination, error) {
 	ret := _m.Called(page)
@@ -67,6 +104,29 @@ func (_m *MockChannelRepository) GetList(page *data.Pagination) ([]*data.Channel
 	return r0, r1, r2
 }
 
+// Purge provides a mock function with given fields: channel
+func (_m *MockChannelRepository) Purge(channel *data.Channel) ([]string, error) {
+	ret := _m.Called(channel)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(*data.Channel) []string); ok {
+		r0 = rf(channel)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*data.Channel) error); ok {
+		r1 = rf(channel)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Store provides a mock function with given fields: channel
 func (_m *MockChannelRepository) Store(channel *data.Channel) (*data.Channel, error) {
 	ret := _m.Called(channel)
@@ -12,6 +12,20 @@ type MockProducerRepository struct {
 	mock.Mock
 }
 
+// Delete provides a mock function with given fields: producer, drain
+func (_m *MockProducerRepository) Delete(producer *data.Producer, drain bool) error {
+	ret := _m.Called(producer, drain)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*data.Producer, bool) error); ok {
+		r0 = rf(producer, drain)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Get provides a mock function with given fields: producerID
 func (_m *MockProducerRepository) Get(producerID string) (*data.Producer, error) {
 	ret := _m.Called(producerID)
@@ -35,6 +49,29 @@ func (_m *MockProducerRepository) Get(producerID string) (*data.Producer, error)
 	return r0, r1
 }
 
+// GetIncludingDeleted provides a mock function with given fields: producerID
+func (_m *MockProducerRepository) GetIncludingDeleted(producerID string) (*data.Producer, error) {
+	ret := _m.Called(producerID)
+
+	var r0 *data.Producer
+	if rf, ok := ret.Get(0).(func(string) *data.Producer); ok {
+		r0 = rf(producerID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*data.Producer)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(producerID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetList provides a mock function with given fields: page
 func (_m *MockProducerRepository) GetList(page *data.Pagination) ([]*data.Producer, *data.Pagination, error) {
 	ret := _m.Called(page)
@@ -67,6 +104,29 @@ func (_m *MockProducerRepository) GetList(page *data.Pagination) ([]*data.Produc
 	return r0, r1, r2
 }
 
+// Purge provides a mock function with given fields: producer
+func (_m *MockProducerRepository) Purge(producer *data.Producer) ([]string, error) {
+	ret := _m.Called(producer)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(*data.Producer) []string); ok {
+		r0 = rf(producer)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*data.Producer) error); ok {
+		r1 = rf(producer)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Store provides a mock function with given fields: producer
 func (_m *MockProducerRepository) Store(producer *data.Producer) (*data.Producer, error) {
 	ret := _m.Called(producer)
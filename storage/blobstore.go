@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/xid"
+
+	"github.com/newscred/webhook-broker/config"
+)
+
+const (
+	fileSystemBlobRefPrefix = string(config.FileSystemBlobStoreProvider) + ":"
+	tempBlobSuffix          = ".tmp"
+)
+
+var (
+	// ErrInvalidBlobRef is returned when a blob reference is not one the blob store issued
+	ErrInvalidBlobRef = errors.New("invalid blob reference")
+	// ErrBlobStoreNotConfigured is returned when an offloaded payload is accessed while no blob store is configured
+	ErrBlobStoreNotConfigured = errors.New("blob store not configured")
+)
+
+// FileSystemBlobStore is the BlobStore implementation keeping payloads as files in a directory
+type FileSystemBlobStore struct {
+	basePath string
+}
+
+// Put writes the content to a new file and returns its reference; the file only becomes visible once the content is completely written
+func (blobStore *FileSystemBlobStore) Put(content io.Reader) (ref string, err error) {
+	name := xid.New().String()
+	tempPath := filepath.Join(blobStore.basePath, name+tempBlobSuffix)
+	file, err := os.Create(tempPath)
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(file, content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempPath, filepath.Join(blobStore.basePath, name))
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return "", err
+	}
+	return fileSystemBlobRefPrefix + name, nil
+}
+
+// Get opens the blob for reading; caller is responsible for closing it
+func (blobStore *FileSystemBlobStore) Get(ref string) (io.ReadCloser, error) {
+	path, err := blobStore.getPath(ref)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// Delete removes the blob; removing an already removed blob is not an error
+func (blobStore *FileSystemBlobStore) Delete(ref string) error {
+	path, err := blobStore.getPath(ref)
+	if err == nil {
+		err = os.Remove(path)
+	}
+	if os.IsNotExist(err) {
+		err = nil
+	}
+	return err
+}
+
+func (blobStore *FileSystemBlobStore) getPath(ref string) (string, error) {
+	name := strings.TrimPrefix(ref, fileSystemBlobRefPrefix)
+	if name == ref || len(name) <= 0 || filepath.Base(name) != name {
+		return "", ErrInvalidBlobRef
+	}
+	return filepath.Join(blobStore.basePath, name), nil
+}
+
+// NewBlobStore creates the BlobStore as per the configuration; returns nil when payloads are not to be offloaded
+func NewBlobStore(blobStoreConfig config.BlobStoreConfig) (BlobStore, error) {
+	switch blobStoreConfig.GetBlobStoreProvider() {
+	case config.FileSystemBlobStoreProvider:
+		basePath := blobStoreConfig.GetBlobStoreFileSystemPath()
+		if err := os.MkdirAll(basePath, 0755); err != nil {
+			return nil, err
+		}
+		return &FileSystemBlobStore{basePath: basePath}, nil
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/newscred/webhook-broker/config"
+	configmocks "github.com/newscred/webhook-broker/config/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingReader struct{}
+
+func (reader failingReader) Read(p []byte) (int, error) {
+	return 0, errors.New("read failed")
+}
+
+func getTestBlobStore(t *testing.T) *FileSystemBlobStore {
+	basePath, err := ioutil.TempDir("", "blobstore-test")
+	assert.Nil(t, err)
+	t.Cleanup(func() { os.RemoveAll(basePath) })
+	return &FileSystemBlobStore{basePath: basePath}
+}
+
+func TestFileSystemBlobStore(t *testing.T) {
+	t.Run("PutGetDelete", func(t *testing.T) {
+		blobStore := getTestBlobStore(t)
+		ref, err := blobStore.Put(strings.NewReader(samplePayload))
+		assert.Nil(t, err)
+		assert.True(t, strings.HasPrefix(ref, fileSystemBlobRefPrefix))
+		reader, err := blobStore.Get(ref)
+		assert.Nil(t, err)
+		content, err := ioutil.ReadAll(reader)
+		reader.Close()
+		assert.Nil(t, err)
+		assert.Equal(t, samplePayload, string(content))
+		assert.Nil(t, blobStore.Delete(ref))
+		_, err = blobStore.Get(ref)
+		assert.True(t, os.IsNotExist(err))
+		assert.Nil(t, blobStore.Delete(ref))
+	})
+	t.Run("PutFailed", func(t *testing.T) {
+		blobStore := getTestBlobStore(t)
+		_, err := blobStore.Put(failingReader{})
+		assert.NotNil(t, err)
+		entries, _ := ioutil.ReadDir(blobStore.basePath)
+		assert.Equal(t, 0, len(entries))
+		_, err = (&FileSystemBlobStore{basePath: filepath.Join(blobStore.basePath, "missing")}).Put(strings.NewReader(samplePayload))
+		assert.NotNil(t, err)
+	})
+	t.Run("InvalidRef", func(t *testing.T) {
+		blobStore := getTestBlobStore(t)
+		for _, ref := range []string{"", "somefile", fileSystemBlobRefPrefix, fileSystemBlobRefPrefix + "../somefile", "s3:somefile"} {
+			_, err := blobStore.Get(ref)
+			assert.Equal(t, ErrInvalidBlobRef, err)
+			assert.Equal(t, ErrInvalidBlobRef, blobStore.Delete(ref))
+		}
+	})
+}
+
+func TestNewBlobStore(t *testing.T) {
+	t.Run("None", func(t *testing.T) {
+		blobStoreConfig := new(configmocks.BlobStoreConfig)
+		blobStoreConfig.On("GetBlobStoreProvider").Return(config.NoBlobStoreProvider)
+		blobStore, err := NewBlobStore(blobStoreConfig)
+		assert.Nil(t, err)
+		assert.Nil(t, blobStore)
+	})
+	t.Run("FileSystem", func(t *testing.T) {
+		basePath := filepath.Join(getTestBlobStore(t).basePath, "blobs")
+		blobStoreConfig := new(configmocks.BlobStoreConfig)
+		blobStoreConfig.On("GetBlobStoreProvider").Return(config.FileSystemBlobStoreProvider)
+		blobStoreConfig.On("GetBlobStoreFileSystemPath").Return(basePath)
+		blobStore, err := NewBlobStore(blobStoreConfig)
+		assert.Nil(t, err)
+		assert.NotNil(t, blobStore)
+		info, err := os.Stat(basePath)
+		assert.Nil(t, err)
+		assert.True(t, info.IsDir())
+	})
+	t.Run("FileSystemError", func(t *testing.T) {
+		blobStore := getTestBlobStore(t)
+		filePath := filepath.Join(blobStore.basePath, "file")
+		assert.Nil(t, ioutil.WriteFile(filePath, []byte(samplePayload), 0644))
+		blobStoreConfig := new(configmocks.BlobStoreConfig)
+		blobStoreConfig.On("GetBlobStoreProvider").Return(config.FileSystemBlobStoreProvider)
+		blobStoreConfig.On("GetBlobStoreFileSystemPath").Return(filepath.Join(filePath, "blobs"))
+		_, err := NewBlobStore(blobStoreConfig)
+		assert.NotNil(t, err)
+	})
+}
@@ -18,28 +18,38 @@ type ChannelDBRepository struct {
 	db *sql.DB
 }
 
-// Store either creates or updates the channel information
+const (
+	channelSelectRowCommonQuery = "SELECT id, channelId, name, token, maxPayloadSize, deletedAt, createdAt, updatedAt FROM channel WHERE"
+	channelMessagesCriteria     = " channelId like ?"
+	channelConsumersCriteria    = " consumerId IN (SELECT id FROM consumer WHERE channelId like ?)"
+)
+
+// Store either creates or updates the channel information; a deleted channel can not be stored until it is purged
 func (repo *ChannelDBRepository) Store(channel *data.Channel) (*data.Channel, error) {
-	inChannel, err := repo.Get(channel.ChannelID)
+	inChannel, err := repo.GetIncludingDeleted(channel.ChannelID)
 	if err != nil {
 		return repo.insertChannel(channel)
 	}
-	if channel.Name != inChannel.Name || channel.Token != inChannel.Token {
+	if inChannel.IsDeleted() {
+		return inChannel, ErrDeleted
+	}
+	if channel.Name != inChannel.Name || channel.Token != inChannel.Token || channel.MaxPayloadSize != inChannel.MaxPayloadSize {
 		if !channel.IsInValidState() {
 			return &data.Channel{}, ErrInvalidStateToSave
 		}
-		return repo.updateChannel(inChannel, channel.Name, channel.Token)
+		return repo.updateChannel(inChannel, channel.Name, channel.Token, channel.MaxPayloadSize)
 	}
 	return inChannel, err
 }
 
-func (repo *ChannelDBRepository) updateChannel(channel *data.Channel, name, token string) (*data.Channel, error) {
+func (repo *ChannelDBRepository) updateChannel(channel *data.Channel, name, token string, maxPayloadSize uint) (*data.Channel, error) {
 	err := transactionalSingleRowWriteExec(repo.db, func() {
 		channel.Name = name
 		channel.Token = token
+		channel.MaxPayloadSize = maxPayloadSize
 		channel.UpdatedAt = time.Now()
-	}, "UPDATE channel SET name = ?, token = ?, updatedAt = ? WHERE channelId = ?",
-		args2SliceFnWrapper(&channel.Name, &channel.Token, &channel.UpdatedAt, &channel.ChannelID))
+	}, "UPDATE channel SET name = ?, token = ?, maxPayloadSize = ?, updatedAt = ? WHERE channelId = ?",
+		args2SliceFnWrapper(&channel.Name, &channel.Token, &channel.MaxPayloadSize, &channel.UpdatedAt, &channel.ChannelID))
 	return channel, err
 }
 
@@ -48,31 +58,72 @@ func (repo *ChannelDBRepository) insertChannel(channel *data.Channel) (*data.Cha
 	if !channel.IsInValidState() {
 		return channel, ErrInvalidStateToSave
 	}
-	err := transactionalSingleRowWriteExec(repo.db, emptyOps, "INSERT INTO channel (id, channelId, name, token, createdAt, updatedAt) VALUES (?, ?, ?, ?, ?, ?)",
-		args2SliceFnWrapper(channel.ID, channel.ChannelID, channel.Name, channel.Token, channel.CreatedAt, channel.UpdatedAt))
+	err := transactionalSingleRowWriteExec(repo.db, emptyOps, "INSERT INTO channel (id, channelId, name, token, maxPayloadSize, createdAt, updatedAt) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		args2SliceFnWrapper(channel.ID, channel.ChannelID, channel.Name, channel.Token, channel.MaxPayloadSize, channel.CreatedAt, channel.UpdatedAt))
 	return channel, err
 }
 
-// Get retrieves the channel with matching channel id
+// Get retrieves the channel with matching channel id unless it is deleted
 func (repo *ChannelDBRepository) Get(channelID string) (*data.Channel, error) {
+	return repo.getSingleChannel(channelSelectRowCommonQuery+" channelId like ? AND"+notDeletedCriteria, channelID)
+}
+
+// GetIncludingDeleted retrieves the channel with matching channel id even if it is deleted
+func (repo *ChannelDBRepository) GetIncludingDeleted(channelID string) (*data.Channel, error) {
+	return repo.getSingleChannel(channelSelectRowCommonQuery+" channelId like ?", channelID)
+}
+
+func (repo *ChannelDBRepository) getSingleChannel(query string, channelID string) (*data.Channel, error) {
 	channel := &data.Channel{}
-	err := querySingleRow(repo.db, "SELECT id, channelId, name, token, createdAt, updatedAt FROM channel WHERE channelId like ?", args2SliceFnWrapper(channelID),
-		args2SliceFnWrapper(&channel.ID, &channel.ChannelID, &channel.Name, &channel.Token, &channel.CreatedAt, &channel.UpdatedAt))
+	err := querySingleRow(repo.db, query, args2SliceFnWrapper(channelID), args2SliceFnWrapper(&channel.ID, &channel.ChannelID, &channel.Name, &channel.Token,
+		&channel.MaxPayloadSize, nullTimeScanner{&channel.DeletedAt}, &channel.CreatedAt, &channel.UpdatedAt))
 	return channel, err
 }
 
-// GetList retrieves the list of channel based on pagination params supplied. It will return a error if both after and before is present at the same time
+// Delete soft deletes the channel along with its consumers; their outstanding jobs are cancelled unless drain is set, in which case they are
+// delivered as usual
+func (repo *ChannelDBRepository) Delete(channel *data.Channel, drain bool) error {
+	markDeleted(&channel.MessageStakeholder)
+	ops := []func(tx *sql.Tx) error{softDeleteOp(&channel.MessageStakeholder, "UPDATE channel SET deletedAt = ?, updatedAt = ? WHERE channelId like ? AND"+notDeletedCriteria, channel.ChannelID),
+		execOp("UPDATE consumer SET deletedAt = ?, updatedAt = ? WHERE channelId like ? AND"+notDeletedCriteria, channel.DeletedAt, channel.UpdatedAt, channel.ChannelID)}
+	if !drain {
+		ops = append(ops, cancelOutstandingJobsOps(channelConsumersCriteria, channelConsumersCriteria, channel.ChannelID)...)
+	}
+	return transactionalWrites(repo.db, ops...)
+}
+
+// Purge permanently deletes the deleted channel with its consumers, messages, jobs, schemas, grants and quota; returns the blob store
+// references of its messages' offloaded payloads for them to be deleted as well
+func (repo *ChannelDBRepository) Purge(channel *data.Channel) ([]string, error) {
+	refs, err := getPayloadRefs(repo.db, channelMessagesCriteria, channel.ChannelID)
+	if err == nil {
+		err = transactionalWrites(repo.db,
+			execOp("DELETE FROM job WHERE messageId IN (SELECT id FROM message WHERE channelId like ?)", channel.ChannelID),
+			execOp("DELETE FROM job WHERE"+channelConsumersCriteria, channel.ChannelID),
+			execOp("DELETE FROM replay WHERE"+channelConsumersCriteria, channel.ChannelID),
+			execOp("DELETE FROM backfill WHERE"+channelConsumersCriteria, channel.ChannelID),
+			execOp("DELETE FROM consumer WHERE channelId like ?", channel.ChannelID),
+			execOp("DELETE FROM message WHERE"+channelMessagesCriteria, channel.ChannelID),
+			execOp("DELETE FROM channel_schema WHERE channelId like ?", channel.ChannelID),
+			execOp("DELETE FROM publish_grant WHERE channelId like ?", channel.ChannelID),
+			execOp("DELETE FROM publish_quota WHERE subjectType like ? AND subjectId like ?", data.ChannelQuota, channel.ChannelID),
+			getTxWrapperForSingleWriteQuery(emptyOps, "DELETE FROM channel WHERE channelId like ? AND"+deletedCriteria, args2SliceFnWrapper(channel.ChannelID)))
+	}
+	return refs, err
+}
+
+// GetList retrieves the list of channels, other than deleted ones, based on pagination params supplied. It will return a error if both after and before is present at the same time
 func (repo *ChannelDBRepository) GetList(page *data.Pagination) ([]*data.Channel, *data.Pagination, error) {
 	channels := make([]*data.Channel, 0)
 	pagination := &data.Pagination{}
 	if page == nil || (page.Next != nil && page.Previous != nil) {
 		return channels, pagination, ErrPaginationDeadlock
 	}
-	baseQuery := "SELECT id, channelId, name, token, createdAt, updatedAt FROM channel" + getPaginationQueryFragment(page, false)
+	baseQuery := "SELECT id, channelId, name, token, maxPayloadSize, createdAt, updatedAt FROM channel WHERE" + notDeletedCriteria + getPaginationQueryFragment(page, true)
 	scanArgs := func() []interface{} {
 		channel := &data.Channel{}
 		channels = append(channels, channel)
-		return []interface{}{&channel.ID, &channel.ChannelID, &channel.Name, &channel.Token, &channel.CreatedAt, &channel.UpdatedAt}
+		return []interface{}{&channel.ID, &channel.ChannelID, &channel.Name, &channel.Token, &channel.MaxPayloadSize, &channel.CreatedAt, &channel.UpdatedAt}
 	}
 	err := queryRows(repo.db, baseQuery, args2SliceFnWrapper(getPaginationTimestampQueryArgs(page)...), scanArgs)
 	if err == nil {
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"database/sql"
 	"errors"
 	"strconv"
 	"strings"
@@ -13,14 +14,15 @@ import (
 )
 
 const (
-	successfulGetTestChannelID      = "get-test"
-	nonExistingGetTestChannelID     = "get-test-ne"
-	successfulInsertTestChannelID   = "s-insert-test"
-	invalidStateUpdateTestChannelID = "i-update-test"
-	successfulUpdateTestChannelID   = "s-update-test"
-	dbErrUpdateTestChannelID        = "db-update-test"
-	noChangeUpdateTestChannelID     = "nc-update-test"
-	listTestChannelIDPrefix         = "get-list-"
+	successfulGetTestChannelID        = "get-test"
+	nonExistingGetTestChannelID       = "get-test-ne"
+	successfulInsertTestChannelID     = "s-insert-test"
+	invalidStateUpdateTestChannelID   = "i-update-test"
+	successfulUpdateTestChannelID     = "s-update-test"
+	dbErrUpdateTestChannelID          = "db-update-test"
+	noChangeUpdateTestChannelID       = "nc-update-test"
+	maxPayloadSizeUpdateTestChannelID = "mps-update-test"
+	listTestChannelIDPrefix           = "get-list-"
 )
 
 func getChannelRepo() ChannelRepository {
@@ -75,7 +77,7 @@ func TestChannelStore(t *testing.T) {
 		db, mock, _ := sqlmock.New()
 		expectedErr := errors.New("Insertion failed")
 		mock.ExpectBegin()
-		mock.ExpectExec("INSERT INTO").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnError(expectedErr)
+		mock.ExpectExec("INSERT INTO").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnError(expectedErr)
 		mock.ExpectRollback()
 		mock.MatchExpectationsInOrder(true)
 		repo := &ChannelDBRepository{db: db}
@@ -126,10 +128,10 @@ func TestChannelStore(t *testing.T) {
 		expectedErr := errors.New("Update failed")
 		channel, _ := data.NewChannel(dbErrUpdateTestChannelID, successfulGetTestToken)
 		channel.QuickFix()
-		rows := sqlmock.NewRows([]string{"id", "channelId", "name", "token", "createdAt", "updatedAt"}).AddRow(channel.ID, channel.ChannelID, channel.Name, channel.Token, channel.CreatedAt, channel.UpdatedAt)
-		mock.ExpectQuery("SELECT id, channelId, name, token, createdAt, updatedAt FROM channel WHERE channelId like").WithArgs(dbErrUpdateTestChannelID).WillReturnRows(rows).WillReturnError(nil)
+		rows := sqlmock.NewRows([]string{"id", "channelId", "name", "token", "maxPayloadSize", "deletedAt", "createdAt", "updatedAt"}).AddRow(channel.ID, channel.ChannelID, channel.Name, channel.Token, channel.MaxPayloadSize, nil, channel.CreatedAt, channel.UpdatedAt)
+		mock.ExpectQuery(channelSelectRowCommonQuery + " channelId like").WithArgs(dbErrUpdateTestChannelID).WillReturnRows(rows).WillReturnError(nil)
 		mock.ExpectBegin()
-		mock.ExpectExec("UPDATE channel").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), dbErrUpdateTestChannelID).WillReturnError(expectedErr)
+		mock.ExpectExec("UPDATE channel").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), dbErrUpdateTestChannelID).WillReturnError(expectedErr)
 		mock.ExpectRollback()
 		mock.MatchExpectationsInOrder(true)
 		repo := &ChannelDBRepository{db: db}
@@ -144,11 +146,11 @@ func TestChannelStore(t *testing.T) {
 		db, mock, _ := sqlmock.New()
 		channel, _ := data.NewChannel(dbErrUpdateTestChannelID, successfulGetTestToken)
 		channel.QuickFix()
-		rows := sqlmock.NewRows([]string{"id", "channelId", "name", "token", "createdAt", "updatedAt"}).AddRow(channel.ID, channel.ChannelID, channel.Name, channel.Token, channel.CreatedAt, channel.UpdatedAt)
+		rows := sqlmock.NewRows([]string{"id", "channelId", "name", "token", "maxPayloadSize", "deletedAt", "createdAt", "updatedAt"}).AddRow(channel.ID, channel.ChannelID, channel.Name, channel.Token, channel.MaxPayloadSize, nil, channel.CreatedAt, channel.UpdatedAt)
 		result := sqlmock.NewResult(1, 0)
-		mock.ExpectQuery("SELECT id, channelId, name, token, createdAt, updatedAt FROM channel WHERE channelId like").WithArgs(dbErrUpdateTestChannelID).WillReturnRows(rows).WillReturnError(nil)
+		mock.ExpectQuery(channelSelectRowCommonQuery + " channelId like").WithArgs(dbErrUpdateTestChannelID).WillReturnRows(rows).WillReturnError(nil)
 		mock.ExpectBegin()
-		mock.ExpectExec("UPDATE channel").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), dbErrUpdateTestChannelID).WillReturnResult(result).WillReturnError(nil)
+		mock.ExpectExec("UPDATE channel").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), dbErrUpdateTestChannelID).WillReturnResult(result).WillReturnError(nil)
 		mock.ExpectRollback()
 		mock.MatchExpectationsInOrder(true)
 		repo := &ChannelDBRepository{db: db}
@@ -177,6 +179,20 @@ func TestChannelStore(t *testing.T) {
 		assert.Equal(t, successfulGetTestToken, updatedChannel.Token)
 		assert.True(t, channel.UpdatedAt.Before(updatedChannel.UpdatedAt))
 	})
+	t.Run("Update:MaxPayloadSize", func(t *testing.T) {
+		t.Parallel()
+		channel, _ := data.NewChannel(maxPayloadSizeUpdateTestChannelID, successfulGetTestToken)
+		repo := getChannelRepo()
+		channel, err := repo.Store(channel)
+		assert.Nil(t, err)
+		assert.Equal(t, uint(0), channel.MaxPayloadSize)
+		channel.MaxPayloadSize = 2048
+		_, err = repo.Store(channel)
+		assert.Nil(t, err)
+		updatedChannel, err := repo.Get(maxPayloadSizeUpdateTestChannelID)
+		assert.Nil(t, err)
+		assert.Equal(t, uint(2048), updatedChannel.MaxPayloadSize)
+	})
 }
 
 func TestNewChannelRepository(t *testing.T) {
@@ -206,7 +222,7 @@ func TestChannelGetList(t *testing.T) {
 		t.Parallel()
 		db, mock, _ := sqlmock.New()
 		expectedErr := errors.New("DB Query Error")
-		mock.ExpectQuery("SELECT id, channelId, name, token, createdAt, updatedAt FROM channel").WillReturnError(expectedErr)
+		mock.ExpectQuery("SELECT id, channelId, name, token, maxPayloadSize, createdAt, updatedAt FROM channel").WillReturnError(expectedErr)
 		mock.MatchExpectationsInOrder(true)
 		repo := &ChannelDBRepository{db: db}
 		_, _, err := repo.GetList(data.NewPagination(nil, nil))
@@ -285,3 +301,65 @@ func TestChannelGetList(t *testing.T) {
 		assert.Equal(t, 100, count)
 	})
 }
+
+// createDeletionTestFixture creates a consumer of the channel with a dispatched message of the producer queued for it
+func createDeletionTestFixture(t *testing.T, channel *data.Channel, producer *data.Producer, consumerID string) (*data.Consumer, *data.Message, *data.DeliveryJob) {
+	consumer, _ := data.NewConsumer(channel, consumerID, successfulGetTestToken, callbackURL)
+	consumer, err := getConsumerRepo().Store(consumer)
+	assert.Nil(t, err)
+	message, _ := data.NewMessage(channel, producer, samplePayload, sampleContentType)
+	message.PayloadRef = "ref-of-" + consumerID
+	assert.Nil(t, getMessageRepository().Create(message))
+	job, _ := data.NewDeliveryJob(message, consumer)
+	assert.Nil(t, getDeliverJobRepository().DispatchMessage(message, job))
+	return consumer, message, job
+}
+
+func TestChannelDeleteAndPurge(t *testing.T) {
+	repo := getChannelRepo()
+	channel := createTestChannel("delete-test-channel", successfulGetTestToken, repo)
+	consumer, message, job := createDeletionTestFixture(t, channel, producer1, "delete-test-channel-consumer")
+	t.Run("PurgeNotDeleted", func(t *testing.T) {
+		_, err := repo.Purge(channel)
+		assert.Equal(t, ErrNoRowsUpdated, err)
+	})
+	t.Run("Delete", func(t *testing.T) {
+		assert.Nil(t, repo.Delete(channel, false))
+		assert.True(t, channel.IsDeleted())
+		assert.Equal(t, channel.DeletedAt, channel.UpdatedAt)
+		_, err := repo.Get(channel.ChannelID)
+		assert.Equal(t, sql.ErrNoRows, err)
+		deletedChannel, err := repo.GetIncludingDeleted(channel.ChannelID)
+		assert.Nil(t, err)
+		assert.True(t, deletedChannel.IsDeleted())
+		channels, _, err := repo.GetList(data.NewPagination(nil, nil))
+		assert.Nil(t, err)
+		for _, listedChannel := range channels {
+			assert.NotEqual(t, channel.ChannelID, listedChannel.ChannelID)
+		}
+		deletedConsumer, err := getConsumerRepo().GetByID(consumer.ID.String())
+		assert.Nil(t, err)
+		assert.True(t, deletedConsumer.IsDeleted())
+		assert.True(t, deletedConsumer.ConsumingFrom.IsDeleted())
+		cancelledJob, err := getDeliverJobRepository().GetByID(job.ID.String())
+		assert.Nil(t, err)
+		assert.Equal(t, data.JobCancelled, cancelledJob.Status)
+		assert.Equal(t, ErrNoRowsUpdated, repo.Delete(deletedChannel, false))
+		_, err = repo.Store(deletedChannel)
+		assert.Equal(t, ErrDeleted, err)
+	})
+	t.Run("Purge", func(t *testing.T) {
+		refs, err := repo.Purge(channel)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{message.PayloadRef}, refs)
+		_, err = repo.GetIncludingDeleted(channel.ChannelID)
+		assert.Equal(t, sql.ErrNoRows, err)
+		_, err = getMessageRepository().GetByID(message.ID.String())
+		assert.Equal(t, sql.ErrNoRows, err)
+		_, err = getConsumerRepo().GetByID(consumer.ID.String())
+		assert.Equal(t, sql.ErrNoRows, err)
+		recreatedChannel, _ := data.NewChannel(channel.ChannelID, successfulGetTestToken)
+		_, err = repo.Store(recreatedChannel)
+		assert.Nil(t, err)
+	})
+}
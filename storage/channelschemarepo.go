@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+const (
+	schemaSelectRowCommonQuery = "SELECT id, version, definition, createdAt, updatedAt FROM channel_schema WHERE channelId like ?"
+)
+
+var (
+	// ErrDuplicateSchemaVersion is returned when a version of the channel's schema is created more than once
+	ErrDuplicateSchemaVersion = errors.New("schema version already exists for channel")
+	schemaErrorMap            = map[uint16]error{
+		1062: ErrDuplicateSchemaVersion,
+	}
+)
+
+// SchemaDBRepository is the RDBMS implementation for SchemaRepository
+type SchemaDBRepository struct {
+	db *sql.DB
+}
+
+// Create stores the new version of the channel's schema; returns ErrDuplicateSchemaVersion if the version already exists
+func (schemaRepo *SchemaDBRepository) Create(schema *data.ChannelSchema) error {
+	schema.QuickFix()
+	if !schema.IsInValidState() {
+		return ErrInvalidStateToSave
+	}
+	return normalizeDBError(transactionalSingleRowWriteExec(schemaRepo.db, emptyOps, "INSERT INTO channel_schema (id, channelId, version, definition, createdAt, updatedAt) VALUES (?, ?, ?, ?, ?, ?)",
+		args2SliceFnWrapper(schema.ID, schema.Channel.ChannelID, schema.Version, schema.Definition, schema.CreatedAt, schema.UpdatedAt)), schemaErrorMap)
+}
+
+func (schemaRepo *SchemaDBRepository) getSchemas(channel *data.Channel, query string, args ...interface{}) ([]*data.ChannelSchema, error) {
+	schemas := make([]*data.ChannelSchema, 0)
+	scanArgs := func() []interface{} {
+		schema := &data.ChannelSchema{Channel: channel}
+		schemas = append(schemas, schema)
+		return []interface{}{&schema.ID, &schema.Version, &schema.Definition, &schema.CreatedAt, &schema.UpdatedAt}
+	}
+	err := queryRows(schemaRepo.db, schemaSelectRowCommonQuery+query, args2SliceFnWrapper(append([]interface{}{channel.ChannelID}, args...)...), scanArgs)
+	return schemas, err
+}
+
+func (schemaRepo *SchemaDBRepository) getSchema(channel *data.Channel, query string, args ...interface{}) (*data.ChannelSchema, error) {
+	schemas, err := schemaRepo.getSchemas(channel, query, args...)
+	if err == nil && len(schemas) <= 0 {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		return nil, err
+	}
+	return schemas[0], nil
+}
+
+// Get retrieves the version of the channel's schema
+func (schemaRepo *SchemaDBRepository) Get(channel *data.Channel, version uint) (*data.ChannelSchema, error) {
+	return schemaRepo.getSchema(channel, " AND version = ?", version)
+}
+
+// GetLatest retrieves the most recent version of the channel's schema; returns sql.ErrNoRows if the channel has no schema
+func (schemaRepo *SchemaDBRepository) GetLatest(channel *data.Channel) (*data.ChannelSchema, error) {
+	return schemaRepo.getSchema(channel, " ORDER BY version desc LIMIT 1")
+}
+
+// GetList retrieves all versions of the channel's schema, most recent first
+func (schemaRepo *SchemaDBRepository) GetList(channel *data.Channel) ([]*data.ChannelSchema, error) {
+	return schemaRepo.getSchemas(channel, " ORDER BY version desc")
+}
+
+// NewSchemaRepository creates a new instance of SchemaRepository
+func NewSchemaRepository(db *sql.DB) SchemaRepository {
+	panicIfNoDBConnectionPool(db)
+	return &SchemaDBRepository{db: db}
+}
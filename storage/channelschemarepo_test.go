@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/newscred/webhook-broker/storage/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemaRepository(t *testing.T) {
+	schemaRepo := NewSchemaRepository(testDB)
+	channel := createTestChannel("channel-for-schemas", "sampletoken", NewChannelRepository(testDB))
+	t.Run("NoSchema", func(t *testing.T) {
+		_, err := schemaRepo.GetLatest(channel)
+		assert.Equal(t, sql.ErrNoRows, err)
+		schemas, err := schemaRepo.GetList(channel)
+		assert.Nil(t, err)
+		assert.Empty(t, schemas)
+	})
+	t.Run("InvalidState", func(t *testing.T) {
+		assert.Equal(t, ErrInvalidStateToSave, schemaRepo.Create(&data.ChannelSchema{Channel: channel, Definition: `{}`}))
+	})
+	t.Run("CreateAndGet", func(t *testing.T) {
+		first, _ := data.NewChannelSchema(channel, 1, `{"type": "object"}`)
+		second, _ := data.NewChannelSchema(channel, 2, `{"type": ["object", "null"]}`)
+		assert.Nil(t, schemaRepo.Create(first))
+		assert.Nil(t, schemaRepo.Create(second))
+		duplicate, _ := data.NewChannelSchema(channel, 2, `{}`)
+		assert.Equal(t, ErrDuplicateSchemaVersion, schemaRepo.Create(duplicate))
+		schema, err := schemaRepo.Get(channel, 1)
+		assert.Nil(t, err)
+		assert.Equal(t, first.ID, schema.ID)
+		assert.Equal(t, first.Definition, schema.Definition)
+		assert.Equal(t, channel, schema.Channel)
+		_, err = schemaRepo.Get(channel, 3)
+		assert.Equal(t, sql.ErrNoRows, err)
+		latest, err := schemaRepo.GetLatest(channel)
+		assert.Nil(t, err)
+		assert.Equal(t, second.ID, latest.ID)
+		schemas, err := schemaRepo.GetList(channel)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(schemas))
+		assert.Equal(t, uint(2), schemas[0].Version)
+		assert.Equal(t, uint(1), schemas[1].Version)
+	})
+}
@@ -8,7 +8,8 @@ import (
 )
 
 const (
-	consumerSelectRowCommonQuery = "SELECT id, consumerId, channelId, name, token, callbackUrl, createdAt, updatedAt FROM consumer WHERE"
+	consumerSelectRowCommonQuery = "SELECT id, consumerId, channelId, name, token, callbackUrl, verificationStatus, verificationChallenge, paused, routingKeyPattern, consumerType, deliveryFormat, deletedAt, createdAt, updatedAt FROM consumer WHERE"
+	consumerCriteria             = " consumerId like ?"
 )
 
 // ConsumerDBRepository is the RDBMS implementation for ConsumerRepository
@@ -17,56 +18,114 @@ type ConsumerDBRepository struct {
 	channelRepository ChannelRepository
 }
 
-// Store stores consumer with either update or insert
+// Store stores consumer with either update or insert; a deleted consumer can not be stored until it is purged
 func (consumerRepo *ConsumerDBRepository) Store(consumer *data.Consumer) (*data.Consumer, error) {
 	inChannel, err := consumerRepo.channelRepository.Get(consumer.GetChannelIDSafely())
 	if err != nil {
 		return &data.Consumer{}, err
 	}
-	inConsumer, err := consumerRepo.Get(inChannel.ChannelID, consumer.ConsumerID)
+	inConsumer, err := consumerRepo.getSingleConsumer(consumerSelectRowCommonQuery+" channelId like ? and consumerId like ?", args2SliceFnWrapper(inChannel.ChannelID, consumer.ConsumerID), false)
 	if err != nil {
 		return consumerRepo.insertConsumer(consumer)
 	}
-	if consumer.Name != inConsumer.Name || consumer.Token != inConsumer.Token || consumer.CallbackURL != inConsumer.CallbackURL {
+	inConsumer.ConsumingFrom = inChannel
+	if inConsumer.IsDeleted() {
+		return inConsumer, ErrDeleted
+	}
+	consumer.QuickFix()
+	if consumer.Name != inConsumer.Name || consumer.Token != inConsumer.Token || consumer.CallbackURL != inConsumer.CallbackURL ||
+		consumer.VerificationStatus != inConsumer.VerificationStatus || consumer.VerificationChallenge != inConsumer.VerificationChallenge ||
+		consumer.RoutingKeyPattern != inConsumer.RoutingKeyPattern || consumer.Type != inConsumer.Type || consumer.DeliveryFormat != inConsumer.DeliveryFormat {
 		if consumer.IsInValidState() {
-			return consumerRepo.updateConsumer(inConsumer, consumer.Name, consumer.Token, consumer.CallbackURL)
+			return consumerRepo.updateConsumer(inConsumer, consumer)
 		}
 		err = ErrInvalidStateToSave
 	}
 	return inConsumer, err
 }
 
-func (consumerRepo *ConsumerDBRepository) updateConsumer(consumer *data.Consumer, name, token, callbackURL string) (*data.Consumer, error) {
+func (consumerRepo *ConsumerDBRepository) updateConsumer(consumer *data.Consumer, updated *data.Consumer) (*data.Consumer, error) {
 	err := transactionalSingleRowWriteExec(consumerRepo.db, func() {
-		consumer.Name = name
-		consumer.Token = token
-		consumer.CallbackURL = callbackURL
+		consumer.Name = updated.Name
+		consumer.Token = updated.Token
+		consumer.CallbackURL = updated.CallbackURL
+		consumer.VerificationStatus = updated.VerificationStatus
+		consumer.VerificationChallenge = updated.VerificationChallenge
+		consumer.RoutingKeyPattern = updated.RoutingKeyPattern
+		consumer.Type = updated.Type
+		consumer.DeliveryFormat = updated.DeliveryFormat
 		consumer.UpdatedAt = time.Now()
-	}, "UPDATE consumer SET name = ?, token = ?, callbackUrl=?, updatedAt = ? WHERE consumerId = ? and channelId = ?",
-		args2SliceFnWrapper(&consumer.Name, &consumer.Token, &consumer.CallbackURL, &consumer.UpdatedAt, consumer.ConsumerID, consumer.ConsumingFrom.ChannelID))
+	}, "UPDATE consumer SET name = ?, token = ?, callbackUrl=?, verificationStatus = ?, verificationChallenge = ?, routingKeyPattern = ?, consumerType = ?, deliveryFormat = ?, updatedAt = ? WHERE consumerId = ? and channelId = ?",
+		args2SliceFnWrapper(&consumer.Name, &consumer.Token, &consumer.CallbackURL, &consumer.VerificationStatus, &consumer.VerificationChallenge, &consumer.RoutingKeyPattern, &consumer.Type, &consumer.DeliveryFormat, &consumer.UpdatedAt, consumer.ConsumerID, consumer.ConsumingFrom.ChannelID))
 	return consumer, err
 }
 
+// MarkVerified marks the consumer as verified provided it is still waiting on the same challenge
+func (consumerRepo *ConsumerDBRepository) MarkVerified(consumer *data.Consumer, challenge string) error {
+	if consumer.VerificationStatus != data.ConsumerPendingVerification || consumer.VerificationChallenge != challenge {
+		return ErrInvalidStateToSave
+	}
+	return transactionalSingleRowWriteExec(consumerRepo.db, func() {
+		consumer.VerificationStatus = data.ConsumerVerified
+		consumer.UpdatedAt = time.Now()
+	}, "UPDATE consumer SET verificationStatus = ?, updatedAt = ? WHERE id = ? and verificationStatus = ? and verificationChallenge = ?",
+		args2SliceFnWrapper(&consumer.VerificationStatus, &consumer.UpdatedAt, consumer.ID, data.ConsumerPendingVerification, challenge))
+}
+
 func (consumerRepo *ConsumerDBRepository) insertConsumer(consumer *data.Consumer) (*data.Consumer, error) {
 	consumer.QuickFix()
 	var err error
 	if consumer.IsInValidState() {
-		err = transactionalSingleRowWriteExec(consumerRepo.db, emptyOps, "INSERT INTO consumer (id, channelId, consumerId, name, token, callbackUrl, createdAt, updatedAt) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
-			args2SliceFnWrapper(consumer.ID, consumer.ConsumingFrom.ChannelID, consumer.ConsumerID, consumer.Name, consumer.Token, consumer.CallbackURL, consumer.CreatedAt, consumer.UpdatedAt))
+		err = transactionalSingleRowWriteExec(consumerRepo.db, emptyOps, "INSERT INTO consumer (id, channelId, consumerId, name, token, callbackUrl, verificationStatus, verificationChallenge, routingKeyPattern, consumerType, deliveryFormat, createdAt, updatedAt) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			args2SliceFnWrapper(consumer.ID, consumer.ConsumingFrom.ChannelID, consumer.ConsumerID, consumer.Name, consumer.Token, consumer.CallbackURL, consumer.VerificationStatus, consumer.VerificationChallenge, consumer.RoutingKeyPattern, consumer.Type, consumer.DeliveryFormat, consumer.CreatedAt, consumer.UpdatedAt))
 	} else {
 		err = ErrInvalidStateToSave
 	}
 	return consumer, err
 }
 
-// Delete deletes consumer from DB
-func (consumerRepo *ConsumerDBRepository) Delete(consumer *data.Consumer) error {
-	return transactionalSingleRowWriteExec(consumerRepo.db, emptyOps, "DELETE from consumer WHERE channelId = ? and consumerId = ?", args2SliceFnWrapper(consumer.GetChannelIDSafely(), consumer.ConsumerID))
+// SetPaused pauses or resumes deliveries to the consumer
+func (consumerRepo *ConsumerDBRepository) SetPaused(consumer *data.Consumer, paused bool) error {
+	return transactionalSingleRowWriteExec(consumerRepo.db, func() {
+		consumer.Paused = paused
+		consumer.UpdatedAt = time.Now()
+	}, "UPDATE consumer SET paused = ?, updatedAt = ? WHERE id = ?", args2SliceFnWrapper(&consumer.Paused, &consumer.UpdatedAt, consumer.ID))
+}
+
+// Delete soft deletes the consumer; its outstanding jobs and replays are cancelled unless drain is set, in which case they are delivered as usual
+func (consumerRepo *ConsumerDBRepository) Delete(consumer *data.Consumer, drain bool) error {
+	markDeleted(&consumer.MessageStakeholder)
+	ops := []func(tx *sql.Tx) error{softDeleteOp(&consumer.MessageStakeholder, "UPDATE consumer SET deletedAt = ?, updatedAt = ? WHERE id like ? AND"+notDeletedCriteria, consumer.ID)}
+	if !drain {
+		ops = append(ops, cancelOutstandingJobsOps(consumerCriteria, consumerCriteria, consumer.ID)...)
+	}
+	return transactionalWrites(consumerRepo.db, ops...)
+}
+
+// Purge permanently deletes the deleted consumer with its jobs, replays and backfill
+func (consumerRepo *ConsumerDBRepository) Purge(consumer *data.Consumer) error {
+	return transactionalWrites(consumerRepo.db,
+		execOp("DELETE FROM job WHERE"+consumerCriteria, consumer.ID),
+		execOp("DELETE FROM replay WHERE"+consumerCriteria, consumer.ID),
+		execOp("DELETE FROM backfill WHERE"+consumerCriteria, consumer.ID),
+		getTxWrapperForSingleWriteQuery(emptyOps, "DELETE FROM consumer WHERE id like ? AND"+deletedCriteria, args2SliceFnWrapper(consumer.ID)))
 }
 
-// Get retrieves consumer for specific consumer, error if either consumer or channel does not exist
+// Get retrieves consumer for specific consumer, error if either consumer or channel does not exist or is deleted
 func (consumerRepo *ConsumerDBRepository) Get(channelID string, consumerID string) (consumer *data.Consumer, err error) {
 	channel, err := consumerRepo.channelRepository.Get(channelID)
+	if err == nil {
+		consumer, err = consumerRepo.getSingleConsumer(consumerSelectRowCommonQuery+" channelId like ? and consumerId like ? AND"+notDeletedCriteria, args2SliceFnWrapper(channelID, consumerID), false)
+	}
+	if err == nil {
+		consumer.ConsumingFrom = channel
+	}
+	return consumer, err
+}
+
+// GetIncludingDeleted retrieves consumer for specific consumer even if it, or its channel, is deleted
+func (consumerRepo *ConsumerDBRepository) GetIncludingDeleted(channelID string, consumerID string) (consumer *data.Consumer, err error) {
+	channel, err := consumerRepo.channelRepository.GetIncludingDeleted(channelID)
 	if err == nil {
 		consumer, err = consumerRepo.getSingleConsumer(consumerSelectRowCommonQuery+" channelId like ? and consumerId like ?", args2SliceFnWrapper(channelID, consumerID), false)
 	}
@@ -80,14 +139,14 @@ func (consumerRepo *ConsumerDBRepository) getSingleConsumer(query string, queryA
 	consumer = &data.Consumer{}
 	consumer.ConsumingFrom = &data.Channel{}
 	err = querySingleRow(consumerRepo.db, query, queryArgs,
-		args2SliceFnWrapper(&consumer.ID, &consumer.ConsumerID, &consumer.ConsumingFrom.ChannelID, &consumer.Name, &consumer.Token, &consumer.CallbackURL, &consumer.CreatedAt, &consumer.UpdatedAt))
+		args2SliceFnWrapper(&consumer.ID, &consumer.ConsumerID, &consumer.ConsumingFrom.ChannelID, &consumer.Name, &consumer.Token, &consumer.CallbackURL, &consumer.VerificationStatus, &consumer.VerificationChallenge, &consumer.Paused, &consumer.RoutingKeyPattern, &consumer.Type, &consumer.DeliveryFormat, nullTimeScanner{&consumer.DeletedAt}, &consumer.CreatedAt, &consumer.UpdatedAt))
 	if loadChannel && err == nil {
-		consumer.ConsumingFrom, err = consumerRepo.channelRepository.Get(consumer.ConsumingFrom.ChannelID)
+		consumer.ConsumingFrom, err = consumerRepo.channelRepository.GetIncludingDeleted(consumer.ConsumingFrom.ChannelID)
 	}
 	return consumer, err
 }
 
-// GetList retrieves consumers for specific consumer; return error if channel does not exist
+// GetList retrieves consumers, other than deleted ones, for specific channel; return error if channel does not exist or is deleted
 func (consumerRepo *ConsumerDBRepository) GetList(channelID string, page *data.Pagination) ([]*data.Consumer, *data.Pagination, error) {
 	consumers := make([]*data.Consumer, 0)
 	pagination := &data.Pagination{}
@@ -96,12 +155,12 @@ func (consumerRepo *ConsumerDBRepository) GetList(channelID string, page *data.P
 	}
 	channel, err := consumerRepo.channelRepository.Get(channelID)
 	if err == nil {
-		baseQuery := "SELECT id, consumerId, name, token, callbackUrl, createdAt, updatedAt FROM consumer WHERE channelId like ?" + getPaginationQueryFragment(page, true)
+		baseQuery := "SELECT id, consumerId, name, token, callbackUrl, verificationStatus, verificationChallenge, paused, routingKeyPattern, consumerType, deliveryFormat, createdAt, updatedAt FROM consumer WHERE channelId like ? AND" + notDeletedCriteria + getPaginationQueryFragment(page, true)
 		scanArgs := func() []interface{} {
 			consumer := &data.Consumer{}
 			consumer.ConsumingFrom = channel
 			consumers = append(consumers, consumer)
-			return []interface{}{&consumer.ID, &consumer.ConsumerID, &consumer.Name, &consumer.Token, &consumer.CallbackURL, &consumer.CreatedAt, &consumer.UpdatedAt}
+			return []interface{}{&consumer.ID, &consumer.ConsumerID, &consumer.Name, &consumer.Token, &consumer.CallbackURL, &consumer.VerificationStatus, &consumer.VerificationChallenge, &consumer.Paused, &consumer.RoutingKeyPattern, &consumer.Type, &consumer.DeliveryFormat, &consumer.CreatedAt, &consumer.UpdatedAt}
 		}
 		var argsFunc func() []interface{} = args2SliceFnWrapper(channelID)
 		times := getPaginationTimestampQueryArgs(page)
@@ -119,7 +178,7 @@ func (consumerRepo *ConsumerDBRepository) GetList(channelID string, page *data.P
 	return consumers, pagination, err
 }
 
-// GetByID retrieves a consumer by its ID
+// GetByID retrieves a consumer by its ID even if it is deleted, e.g. to deliver its jobs being drained
 func (consumerRepo *ConsumerDBRepository) GetByID(id string) (consumer *data.Consumer, err error) {
 	return consumerRepo.getSingleConsumer(consumerSelectRowCommonQuery+" id like ?", args2SliceFnWrapper(id), true)
 }
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"database/sql"
 	"errors"
 	"net/url"
 	"strconv"
@@ -22,11 +23,14 @@ const (
 	successfulInsertTestConsumerID   = "s-insert-test"
 	invalidStateUpdateTestConsumerID = "i-update-test"
 	successfulUpdateTestConsumerID   = "s-update-test"
+	routingKeyUpdateTestConsumerID   = "rk-update-test"
+	deliveryFormatTestConsumerID     = "delivery-format-update-test"
 	dbErrUpdateTestConsumerID        = "db-update-test"
 	noChangeUpdateTestConsumerID     = "nc-update-test"
 	successfulDeleteTestConsumerID   = "s-delete-test"
 	failedDeleteTestConsumerID       = "s-delete-ne-test"
 	listTestConsumerIDPrefix         = "get-list-"
+	verificationTestConsumerID       = "verification-test"
 )
 
 var (
@@ -136,7 +140,7 @@ func TestConsumerDelete(t *testing.T) {
 		assert.True(t, sampleConsumer.IsInValidState())
 		resultConsumer, err := repo.Store(sampleConsumer)
 		assert.Nil(t, err)
-		err = repo.Delete(resultConsumer)
+		err = repo.Delete(resultConsumer, false)
 		assert.Nil(t, err)
 
 	})
@@ -146,7 +150,7 @@ func TestConsumerDelete(t *testing.T) {
 		sampleConsumer, err := data.NewConsumer(channel1, failedDeleteTestConsumerID, successfulGetTestToken, callbackURL)
 		assert.Nil(t, err)
 		sampleConsumer.QuickFix()
-		err = repo.Delete(sampleConsumer)
+		err = repo.Delete(sampleConsumer, false)
 		assert.NotNil(t, err)
 	})
 }
@@ -190,7 +194,7 @@ func TestConsumerStore(t *testing.T) {
 		mockChannelRepo := new(MockChannelRepository)
 		mockChannelRepo.On("Get", channel1.ChannelID).Return(channel1, nil)
 		mock.ExpectBegin()
-		mock.ExpectExec("INSERT INTO").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnError(expectedErr)
+		mock.ExpectExec("INSERT INTO").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnError(expectedErr)
 		mock.ExpectRollback()
 		mock.MatchExpectationsInOrder(true)
 		repo := &ConsumerDBRepository{db: db, channelRepository: mockChannelRepo}
@@ -247,10 +251,10 @@ func TestConsumerStore(t *testing.T) {
 		mockChannelRepo.On("Get", channel2.ChannelID).Return(channel2, nil)
 		consumer, _ := data.NewConsumer(channel2, dbErrUpdateTestConsumerID, successfulGetTestToken, callbackURL)
 		consumer.QuickFix()
-		rows := sqlmock.NewRows([]string{"id", "consumerId", "channelId", "name", "token", "callbackUrl", "createdAt", "updatedAt"}).AddRow(consumer.ID, consumer.ConsumerID, channel2.ChannelID, consumer.Name, consumer.Token, consumer.CallbackURL, consumer.CreatedAt, consumer.UpdatedAt)
+		rows := sqlmock.NewRows([]string{"id", "consumerId", "channelId", "name", "token", "callbackUrl", "verificationStatus", "verificationChallenge", "paused", "routingKeyPattern", "consumerType", "deliveryFormat", "deletedAt", "createdAt", "updatedAt"}).AddRow(consumer.ID, consumer.ConsumerID, channel2.ChannelID, consumer.Name, consumer.Token, consumer.CallbackURL, consumer.VerificationStatus, consumer.VerificationChallenge, consumer.Paused, consumer.RoutingKeyPattern, consumer.Type, consumer.DeliveryFormat, nil, consumer.CreatedAt, consumer.UpdatedAt)
 		mock.ExpectQuery(consumerSelectRowCommonQuery+" channelId like").WithArgs(channel2.ChannelID, dbErrUpdateTestConsumerID).WillReturnRows(rows).WillReturnError(nil)
 		mock.ExpectBegin()
-		mock.ExpectExec("UPDATE consumer").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), dbErrUpdateTestConsumerID, channel2.ChannelID).WillReturnError(expectedErr)
+		mock.ExpectExec("UPDATE consumer").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), dbErrUpdateTestConsumerID, channel2.ChannelID).WillReturnError(expectedErr)
 		mock.ExpectRollback()
 		mock.MatchExpectationsInOrder(true)
 		repo := &ConsumerDBRepository{db: db, channelRepository: mockChannelRepo}
@@ -267,11 +271,11 @@ func TestConsumerStore(t *testing.T) {
 		consumer.QuickFix()
 		mockChannelRepo := new(MockChannelRepository)
 		mockChannelRepo.On("Get", channel2.ChannelID).Return(channel2, nil)
-		rows := sqlmock.NewRows([]string{"id", "consumerId", "channelId", "name", "token", "callbackUrl", "createdAt", "updatedAt"}).AddRow(consumer.ID, consumer.ConsumerID, channel2.ChannelID, consumer.Name, consumer.Token, consumer.CallbackURL, consumer.CreatedAt, consumer.UpdatedAt)
+		rows := sqlmock.NewRows([]string{"id", "consumerId", "channelId", "name", "token", "callbackUrl", "verificationStatus", "verificationChallenge", "paused", "routingKeyPattern", "consumerType", "deliveryFormat", "deletedAt", "createdAt", "updatedAt"}).AddRow(consumer.ID, consumer.ConsumerID, channel2.ChannelID, consumer.Name, consumer.Token, consumer.CallbackURL, consumer.VerificationStatus, consumer.VerificationChallenge, consumer.Paused, consumer.RoutingKeyPattern, consumer.Type, consumer.DeliveryFormat, nil, consumer.CreatedAt, consumer.UpdatedAt)
 		mock.ExpectQuery(consumerSelectRowCommonQuery+" channelId like").WithArgs(channel2.ChannelID, dbErrUpdateTestConsumerID).WillReturnRows(rows).WillReturnError(nil)
 		result := sqlmock.NewResult(1, 0)
 		mock.ExpectBegin()
-		mock.ExpectExec("UPDATE consumer").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), dbErrUpdateTestConsumerID, channel2.ChannelID).WillReturnResult(result).WillReturnError(nil)
+		mock.ExpectExec("UPDATE consumer").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), dbErrUpdateTestConsumerID, channel2.ChannelID).WillReturnResult(result).WillReturnError(nil)
 		mock.ExpectRollback()
 		mock.MatchExpectationsInOrder(true)
 		repo := &ConsumerDBRepository{db: db, channelRepository: mockChannelRepo}
@@ -294,6 +298,63 @@ func TestConsumerStore(t *testing.T) {
 		assert.Equal(t, successfulGetTestToken, updatedConsumer.Token)
 		assert.True(t, consumer.UpdatedAt.Before(updatedConsumer.UpdatedAt))
 	})
+	t.Run("Update:RoutingKeyPattern", func(t *testing.T) {
+		t.Parallel()
+		consumer, _ := data.NewConsumer(channel1, routingKeyUpdateTestConsumerID, successfulGetTestToken, callbackURL)
+		consumer.RoutingKeyPattern = "user.*"
+		repo := getConsumerRepo()
+		_, err := repo.Store(consumer)
+		assert.Nil(t, err)
+		dbConsumer, err := repo.Get(channel1.ChannelID, routingKeyUpdateTestConsumerID)
+		assert.Nil(t, err)
+		assert.Equal(t, "user.*", dbConsumer.RoutingKeyPattern)
+		dbConsumer.RoutingKeyPattern = "user.#"
+		_, err = repo.Store(dbConsumer)
+		assert.Nil(t, err)
+		dbConsumer, err = repo.GetByID(consumer.ID.String())
+		assert.Nil(t, err)
+		assert.Equal(t, "user.#", dbConsumer.RoutingKeyPattern)
+		dbConsumer.RoutingKeyPattern = "user..#"
+		_, err = repo.Store(dbConsumer)
+		assert.Equal(t, ErrInvalidStateToSave, err)
+	})
+	t.Run("Update:DeliveryFormat", func(t *testing.T) {
+		t.Parallel()
+		consumer, _ := data.NewConsumer(channel1, deliveryFormatTestConsumerID, successfulGetTestToken, callbackURL)
+		consumer.DeliveryFormat = data.BinaryCloudEventDelivery
+		repo := getConsumerRepo()
+		_, err := repo.Store(consumer)
+		assert.Nil(t, err)
+		dbConsumer, err := repo.Get(channel1.ChannelID, deliveryFormatTestConsumerID)
+		assert.Nil(t, err)
+		assert.Equal(t, data.BinaryCloudEventDelivery, dbConsumer.DeliveryFormat)
+		dbConsumer.DeliveryFormat = data.StructuredCloudEventDelivery
+		_, err = repo.Store(dbConsumer)
+		assert.Nil(t, err)
+		dbConsumer, err = repo.GetByID(consumer.ID.String())
+		assert.Nil(t, err)
+		assert.Equal(t, data.StructuredCloudEventDelivery, dbConsumer.DeliveryFormat)
+	})
+}
+
+func TestConsumerMarkVerified(t *testing.T) {
+	repo := getConsumerRepo()
+	consumer, err := data.NewConsumer(channel1, verificationTestConsumerID, "sometoken", callbackURL)
+	assert.Nil(t, err)
+	consumer.VerificationStatus = data.ConsumerPendingVerification
+	consumer.VerificationChallenge = "challenge"
+	consumer, err = repo.Store(consumer)
+	assert.Nil(t, err)
+	assert.Equal(t, ErrInvalidStateToSave, repo.MarkVerified(consumer, "wrong-challenge"))
+	staleConsumer := *consumer
+	assert.Nil(t, repo.MarkVerified(consumer, "challenge"))
+	assert.True(t, consumer.IsVerified())
+	dbConsumer, err := repo.Get(channel1.ChannelID, verificationTestConsumerID)
+	assert.Nil(t, err)
+	assert.Equal(t, data.ConsumerVerified, dbConsumer.VerificationStatus)
+	assert.Equal(t, "challenge", dbConsumer.VerificationChallenge)
+	assert.Equal(t, ErrInvalidStateToSave, repo.MarkVerified(dbConsumer, "challenge"))
+	assert.Equal(t, ErrNoRowsUpdated, repo.MarkVerified(&staleConsumer, "challenge"))
 }
 
 func TestNewConsumerRepository(t *testing.T) {
@@ -328,7 +389,7 @@ func TestConsumerGetList(t *testing.T) {
 		mockChannelRepo.On("Get", channel2.ChannelID).Return(channel2, nil)
 		db, mock, _ := sqlmock.New()
 		expectedErr := errors.New("DB Query Error")
-		mock.ExpectQuery("SELECT id, consumerId, name, token, callbackUrl, createdAt, updatedAt FROM consumer").WillReturnError(expectedErr)
+		mock.ExpectQuery("SELECT id, consumerId, name, token, callbackUrl, verificationStatus, verificationChallenge, paused, routingKeyPattern, consumerType, deliveryFormat, createdAt, updatedAt FROM consumer").WillReturnError(expectedErr)
 		mock.MatchExpectationsInOrder(true)
 		repo := &ConsumerDBRepository{db: db, channelRepository: mockChannelRepo}
 		_, _, err := repo.GetList(channel2.ChannelID, data.NewPagination(nil, nil))
@@ -418,3 +479,40 @@ func TestConsumerGetList(t *testing.T) {
 		assert.Equal(t, 100, count)
 	})
 }
+
+func TestConsumerDeleteAndPurge(t *testing.T) {
+	repo := getConsumerRepo()
+	channel := createTestChannel("delete-test-consumer-channel", successfulGetTestToken, getChannelRepo())
+	consumer, _, job := createDeletionTestFixture(t, channel, producer1, "delete-test-consumer")
+	replay, _ := data.NewReplay(consumer, time.Now().Add(-time.Hour), time.Now(), nil)
+	replayRepo := getReplayRepository()
+	assert.Nil(t, replayRepo.Create(replay))
+	t.Run("Delete", func(t *testing.T) {
+		assert.Nil(t, getDeliverJobRepository().MarkJobInflight(job))
+		assert.Nil(t, repo.Delete(consumer, false))
+		_, err := repo.Get(channel.ChannelID, consumer.ConsumerID)
+		assert.Equal(t, sql.ErrNoRows, err)
+		consumers, _, err := repo.GetList(channel.ChannelID, data.NewPagination(nil, nil))
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(consumers))
+		deletedConsumer, err := repo.GetIncludingDeleted(channel.ChannelID, consumer.ConsumerID)
+		assert.Nil(t, err)
+		assert.True(t, deletedConsumer.IsDeleted())
+		cancelledJob, err := getDeliverJobRepository().GetByID(job.ID.String())
+		assert.Nil(t, err)
+		assert.Equal(t, data.JobCancelled, cancelledJob.Status)
+		completedReplay, err := replayRepo.Get(consumer, replay.ID.String())
+		assert.Nil(t, err)
+		assert.Equal(t, data.ReplayCompleted, completedReplay.Status)
+		_, err = repo.Store(deletedConsumer)
+		assert.Equal(t, ErrDeleted, err)
+	})
+	t.Run("Purge", func(t *testing.T) {
+		assert.Nil(t, repo.Purge(consumer))
+		_, err := repo.GetIncludingDeleted(channel.ChannelID, consumer.ConsumerID)
+		assert.Equal(t, sql.ErrNoRows, err)
+		_, err = getDeliverJobRepository().GetByID(job.ID.String())
+		assert.Equal(t, sql.ErrNoRows, err)
+		assert.Equal(t, ErrNoRowsUpdated, repo.Purge(consumer))
+	})
+}
@@ -0,0 +1,56 @@
+package data
+
+import (
+	"time"
+)
+
+// Backfill represents delivery of a channel's past messages to a newly created consumer; DeliveryJobs are created for the messages
+// received within [Since, Until] and the progress is derived from the status of those jobs. Jobs are created after the backfill is recorded
+// by the broker holding its lease, till LeaseExpiresAt, and JobsCreated is set once all of them are created.
+type Backfill struct {
+	BasePaginateable
+	Consumer       *Consumer
+	Since          time.Time
+	Until          time.Time
+	JobCount       uint
+	QueuedCount    uint
+	InflightCount  uint
+	DeliveredCount uint
+	DeadCount      uint
+	DiscardedCount uint
+	CancelledCount uint
+	JobsCreated    bool
+	LeaseOwner     string
+	LeaseExpiresAt time.Time
+}
+
+// QuickFix fixes the object state automatically as much as possible
+func (backfill *Backfill) QuickFix() bool {
+	madeChanges := backfill.BasePaginateable.QuickFix()
+	if backfill.Until.IsZero() {
+		backfill.Until = time.Now()
+		madeChanges = true
+	}
+	return madeChanges
+}
+
+// IsInValidState returns false if consumer is not valid or the window is empty or inverted
+func (backfill *Backfill) IsInValidState() bool {
+	return backfill.Consumer != nil && backfill.Consumer.IsInValidState() && !backfill.Since.IsZero() && backfill.Since.Before(backfill.Until)
+}
+
+// IsComplete returns whether all backfilled jobs are created and have reached a terminal status, i.e. delivered, dead, discarded or cancelled
+func (backfill *Backfill) IsComplete() bool {
+	return backfill.JobsCreated && backfill.DeliveredCount+backfill.DeadCount+backfill.DiscardedCount+backfill.CancelledCount >= backfill.JobCount
+}
+
+// NewBackfill creates a new backfill of consumer's channel messages received since the time provided till now
+func NewBackfill(consumer *Consumer, since time.Time) (*Backfill, error) {
+	backfill := &Backfill{Consumer: consumer, Since: since}
+	backfill.QuickFix()
+	var err error
+	if !backfill.IsInValidState() {
+		err = ErrInsufficientInformationForCreating
+	}
+	return backfill, err
+}
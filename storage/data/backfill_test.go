@@ -0,0 +1,50 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewBackfill(t *testing.T) {
+	consumer, _ := NewConsumer(sampleChannel, someID, someToken, sampleCallbackURL)
+	t.Run("NilConsumer", func(t *testing.T) {
+		t.Parallel()
+		_, err := NewBackfill(nil, time.Now().Add(-1*time.Hour))
+		assert.Equal(t, ErrInsufficientInformationForCreating, err)
+	})
+	t.Run("NoSince", func(t *testing.T) {
+		t.Parallel()
+		_, err := NewBackfill(consumer, time.Time{})
+		assert.Equal(t, ErrInsufficientInformationForCreating, err)
+	})
+	t.Run("SinceInFuture", func(t *testing.T) {
+		t.Parallel()
+		_, err := NewBackfill(consumer, time.Now().Add(1*time.Hour))
+		assert.Equal(t, ErrInsufficientInformationForCreating, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		backfill, err := NewBackfill(consumer, time.Now().Add(-1*time.Hour))
+		assert.Nil(t, err)
+		assert.NotNil(t, backfill.ID)
+		assert.False(t, backfill.Until.IsZero())
+		assert.False(t, backfill.QuickFix())
+		assert.False(t, backfill.IsComplete())
+		backfill.JobsCreated = true
+		assert.True(t, backfill.IsComplete())
+		backfill.JobCount = 3
+		backfill.DeliveredCount = 1
+		backfill.QueuedCount = 2
+		assert.False(t, backfill.IsComplete())
+		backfill.QueuedCount = 0
+		backfill.DeadCount = 2
+		assert.True(t, backfill.IsComplete())
+		backfill.DeadCount = 0
+		backfill.DiscardedCount = 1
+		assert.False(t, backfill.IsComplete())
+		backfill.CancelledCount = 1
+		assert.True(t, backfill.IsComplete())
+	})
+}
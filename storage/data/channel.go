@@ -4,6 +4,8 @@ package data
 type Channel struct {
 	MessageStakeholder
 	ChannelID string
+	// MaxPayloadSize is the maximum size in bytes of a message payload accepted by the channel; 0 means the broker-wide limit applies
+	MaxPayloadSize uint
 }
 
 // QuickFix fixes the model to set default ID, name same as channel id, created and updated at to current time.
@@ -0,0 +1,235 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+const (
+	// SchemaVersionAttributeName is the message attribute requesting the version of the channel's schema to validate the payload against
+	SchemaVersionAttributeName = "Schema-Version"
+	// SchemaVersionContentTypeParam is the content type parameter requesting the version of the channel's schema if the attribute is absent
+	SchemaVersionContentTypeParam = "schema-version"
+	schemaResourceURL             = "channel-schema.json"
+)
+
+var (
+	// ErrPayloadNotJSON is returned when a payload validated against a channel schema is not a JSON document
+	ErrPayloadNotJSON = errors.New("payload is not a JSON document")
+	// ErrInvalidSchemaVersion is returned when the schema version requested for a message is not a positive number
+	ErrInvalidSchemaVersion = errors.New("schema version must be a positive number")
+	errRemoteSchemaRef      = errors.New("remote references are not supported in channel schemas")
+)
+
+// SchemaViolation is a location in a payload not conforming to a channel's schema along with the schema keyword it failed
+type SchemaViolation struct {
+	InstanceLocation string
+	KeywordLocation  string
+	Error            string
+}
+
+// ChannelSchema is a version of the JSON Schema the payloads of messages broadcasted to a channel must conform to; versions are immutable once
+// created and are numbered from 1 in the order they were registered
+type ChannelSchema struct {
+	BasePaginateable
+	Channel    *Channel
+	Version    uint
+	Definition string
+}
+
+// IsInValidState returns false if channel is not valid, version is not positive or the definition does not compile as a JSON Schema
+func (schema *ChannelSchema) IsInValidState() bool {
+	if schema.Channel == nil || !schema.Channel.IsInValidState() || schema.Version <= 0 {
+		return false
+	}
+	_, err := schema.Compile()
+	return err == nil
+}
+
+// Compile compiles the definition as a JSON Schema; the definition must be self contained as references to other documents are not resolved
+func (schema *ChannelSchema) Compile() (*jsonschema.Schema, error) {
+	compiler := jsonschema.NewCompiler()
+	compiler.LoadURL = func(url string) (io.ReadCloser, error) {
+		return nil, errRemoteSchemaRef
+	}
+	if err := compiler.AddResource(schemaResourceURL, strings.NewReader(schema.Definition)); err != nil {
+		return nil, err
+	}
+	return compiler.Compile(schemaResourceURL)
+}
+
+// GetIncompatibilities returns why the definition is not backward compatible with the previous version's, i.e. why payloads conforming to the
+// previous version may not conform to this one. Object properties are compared recursively and a property becoming required, a type being
+// narrowed or additional properties being disallowed are reported as incompatible.
+func (schema *ChannelSchema) GetIncompatibilities(previous *ChannelSchema) ([]string, error) {
+	var previousDefinition, definition interface{}
+	if err := json.Unmarshal([]byte(previous.Definition), &previousDefinition); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(schema.Definition), &definition); err != nil {
+		return nil, err
+	}
+	incompatibilities := make([]string, 0)
+	previousNode, previousOK := previousDefinition.(map[string]interface{})
+	node, ok := definition.(map[string]interface{})
+	switch {
+	case previousOK && ok:
+		compareSchemaNodes("#", previousNode, node, &incompatibilities)
+	case definition == false && previousDefinition != false:
+		incompatibilities = append(incompatibilities, "#: no payload is allowed")
+	}
+	return incompatibilities, nil
+}
+
+func compareSchemaNodes(location string, previous, next map[string]interface{}, incompatibilities *[]string) {
+	previousRequired := getSchemaStrings(previous["required"])
+	for _, property := range sortedKeys(getSchemaStrings(next["required"])) {
+		if !previousRequired[property] {
+			*incompatibilities = append(*incompatibilities, fmt.Sprintf("%s: property %q is now required", location, property))
+		}
+	}
+	previousTypes, nextTypes := getSchemaStrings(previous["type"]), getSchemaStrings(next["type"])
+	if len(nextTypes) > 0 {
+		narrowed := len(previousTypes) <= 0
+		for schemaType := range previousTypes {
+			if !nextTypes[schemaType] && !(schemaType == "integer" && nextTypes["number"]) {
+				narrowed = true
+			}
+		}
+		if narrowed {
+			*incompatibilities = append(*incompatibilities, fmt.Sprintf("%s: type is narrowed to %s", location, strings.Join(sortedKeys(nextTypes), ", ")))
+		}
+	}
+	if next["additionalProperties"] == false && previous["additionalProperties"] != false {
+		*incompatibilities = append(*incompatibilities, location+": additional properties are no longer allowed")
+	}
+	previousProperties, _ := previous["properties"].(map[string]interface{})
+	nextProperties, _ := next["properties"].(map[string]interface{})
+	propertyNames := make(map[string]bool)
+	for property := range nextProperties {
+		propertyNames[property] = true
+	}
+	for _, property := range sortedKeys(propertyNames) {
+		previousProperty, previousOK := previousProperties[property].(map[string]interface{})
+		nextProperty, ok := nextProperties[property].(map[string]interface{})
+		if previousOK && ok {
+			compareSchemaNodes(location+"/properties/"+property, previousProperty, nextProperty, incompatibilities)
+		}
+	}
+	previousItems, previousOK := previous["items"].(map[string]interface{})
+	nextItems, ok := next["items"].(map[string]interface{})
+	if previousOK && ok {
+		compareSchemaNodes(location+"/items", previousItems, nextItems, incompatibilities)
+	}
+}
+
+// getSchemaStrings returns the set of strings of a keyword whose value is either a string or an array of strings, e.g. `type` and `required`
+func getSchemaStrings(value interface{}) map[string]bool {
+	result := make(map[string]bool)
+	switch typedValue := value.(type) {
+	case string:
+		result[typedValue] = true
+	case []interface{}:
+		for _, item := range typedValue {
+			if stringItem, ok := item.(string); ok {
+				result[stringItem] = true
+			}
+		}
+	}
+	return result
+}
+
+func sortedKeys(values map[string]bool) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// GetRequestedSchemaVersion returns the schema version requested for a message by either the `Schema-Version` attribute or the `schema-version`
+// content type parameter; 0 if neither is present
+func GetRequestedSchemaVersion(contentType string, attributes MessageAttributes) (uint, error) {
+	version, ok := attributes[SchemaVersionAttributeName]
+	if !ok {
+		if _, params, err := mime.ParseMediaType(contentType); err == nil {
+			version, ok = params[SchemaVersionContentTypeParam]
+		}
+	}
+	if !ok {
+		return 0, nil
+	}
+	parsedVersion, err := strconv.ParseUint(version, 10, 0)
+	if err != nil || parsedVersion <= 0 {
+		return 0, ErrInvalidSchemaVersion
+	}
+	return uint(parsedVersion), nil
+}
+
+// GetSchemaViolations validates the payload against the compiled schema and returns why it does not conform; empty if it conforms
+func GetSchemaViolations(compiled *jsonschema.Schema, payload []byte) ([]*SchemaViolation, error) {
+	violations := make([]*SchemaViolation, 0)
+	document, err := decodeSchemaPayload(payload)
+	if err != nil {
+		return append(violations, &SchemaViolation{Error: err.Error()}), nil
+	}
+	var validationErr *jsonschema.ValidationError
+	if err = compiled.Validate(document); err != nil && !errors.As(err, &validationErr) {
+		return nil, err
+	}
+	if validationErr != nil {
+		violations = addSchemaViolations(violations, validationErr)
+	}
+	// Causes are not in a deterministic order, so violations are sorted by where they are in the payload
+	sort.SliceStable(violations, func(i, j int) bool {
+		if violations[i].InstanceLocation != violations[j].InstanceLocation {
+			return violations[i].InstanceLocation < violations[j].InstanceLocation
+		}
+		return violations[i].KeywordLocation < violations[j].KeywordLocation
+	})
+	return violations, nil
+}
+
+// addSchemaViolations adds the leaves of the validation error tree as the errors of their ancestors only name the failed sub-schemas
+func addSchemaViolations(violations []*SchemaViolation, validationErr *jsonschema.ValidationError) []*SchemaViolation {
+	if len(validationErr.Causes) <= 0 {
+		violations = append(violations, &SchemaViolation{InstanceLocation: validationErr.InstanceLocation, KeywordLocation: validationErr.KeywordLocation,
+			Error: validationErr.Message})
+	}
+	for _, cause := range validationErr.Causes {
+		violations = addSchemaViolations(violations, cause)
+	}
+	return violations
+}
+
+// decodeSchemaPayload decodes the payload as a single JSON document, keeping numbers precise
+func decodeSchemaPayload(payload []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil || decoder.More() {
+		return nil, ErrPayloadNotJSON
+	}
+	return document, nil
+}
+
+// NewChannelSchema creates a new version of the channel's schema
+func NewChannelSchema(channel *Channel, version uint, definition string) (*ChannelSchema, error) {
+	schema := &ChannelSchema{Channel: channel, Version: version, Definition: definition}
+	schema.QuickFix()
+	var err error
+	if !schema.IsInValidState() {
+		err = ErrInsufficientInformationForCreating
+	}
+	return schema, err
+}
@@ -0,0 +1,131 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	sampleSchemaDefinition = `{"type": "object", "properties": {"id": {"type": "integer"}, "tags": {"type": "array", "items": {"type": "object", "properties": {"name": {"type": "string"}}}}}, "required": ["id"]}`
+)
+
+func TestNewChannelSchema(t *testing.T) {
+	t.Run("NilChannel", func(t *testing.T) {
+		t.Parallel()
+		_, err := NewChannelSchema(nil, 1, sampleSchemaDefinition)
+		assert.Equal(t, ErrInsufficientInformationForCreating, err)
+	})
+	t.Run("ZeroVersion", func(t *testing.T) {
+		t.Parallel()
+		_, err := NewChannelSchema(sampleChannel, 0, sampleSchemaDefinition)
+		assert.Equal(t, ErrInsufficientInformationForCreating, err)
+	})
+	t.Run("InvalidDefinition", func(t *testing.T) {
+		t.Parallel()
+		for _, definition := range []string{"", `{"type": `, `{"type": "unknown"}`, `{"minLength": -1}`} {
+			_, err := NewChannelSchema(sampleChannel, 1, definition)
+			assert.Equal(t, ErrInsufficientInformationForCreating, err, definition)
+		}
+	})
+	t.Run("RemoteReference", func(t *testing.T) {
+		t.Parallel()
+		_, err := NewChannelSchema(sampleChannel, 1, `{"$ref": "file:///etc/passwd"}`)
+		assert.Equal(t, ErrInsufficientInformationForCreating, err)
+		_, err = NewChannelSchema(sampleChannel, 1, `{"$ref": "https://example.com/schema.json"}`)
+		assert.Equal(t, ErrInsufficientInformationForCreating, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		schema, err := NewChannelSchema(sampleChannel, 2, `{"$defs": {"id": {"type": "integer"}}, "properties": {"id": {"$ref": "#/$defs/id"}}}`)
+		assert.Nil(t, err)
+		assert.False(t, schema.ID.IsNil())
+		assert.Equal(t, uint(2), schema.Version)
+		compiled, err := schema.Compile()
+		assert.Nil(t, err)
+		violations, err := GetSchemaViolations(compiled, []byte(`{"id": "1"}`))
+		assert.Nil(t, err)
+		assert.Equal(t, []*SchemaViolation{{InstanceLocation: "/id", KeywordLocation: "/properties/id/$ref/type", Error: "expected integer, but got string"}}, violations)
+	})
+}
+
+func TestGetSchemaViolations(t *testing.T) {
+	schema, _ := NewChannelSchema(sampleChannel, 1, sampleSchemaDefinition)
+	compiled, _ := schema.Compile()
+	violations, err := GetSchemaViolations(compiled, []byte(` {"id": 12345678901234567890, "tags": [{"name": "a"}]} `))
+	assert.Nil(t, err)
+	assert.Empty(t, violations)
+	violations, err = GetSchemaViolations(compiled, []byte(`{"id": 1.5, "tags": [{"name": 1}]}`))
+	assert.Nil(t, err)
+	assert.Equal(t, []*SchemaViolation{{InstanceLocation: "/id", KeywordLocation: "/properties/id/type", Error: "expected integer, but got number"},
+		{InstanceLocation: "/tags/0/name", KeywordLocation: "/properties/tags/items/properties/name/type", Error: "expected string, but got number"}}, violations)
+	for _, payload := range []string{"", "not json", `{"id": 1} {"id": 2}`, `{"id": 1`} {
+		violations, err = GetSchemaViolations(compiled, []byte(payload))
+		assert.Nil(t, err)
+		assert.Equal(t, []*SchemaViolation{{Error: ErrPayloadNotJSON.Error()}}, violations, payload)
+	}
+}
+
+func TestGetRequestedSchemaVersion(t *testing.T) {
+	testCases := map[string]struct {
+		contentType string
+		attributes  MessageAttributes
+		version     uint
+		err         error
+	}{
+		"None":                 {"application/json", nil, 0, nil},
+		"Attribute":            {"application/json; schema-version=1", MessageAttributes{SchemaVersionAttributeName: "2"}, 2, nil},
+		"ContentTypeParam":     {"application/json; schema-version=3", MessageAttributes{"Tenant-Id": "1"}, 3, nil},
+		"MalformedContentType": {"application/json; schema-version", nil, 0, nil},
+		"InvalidAttribute":     {"application/json", MessageAttributes{SchemaVersionAttributeName: "latest"}, 0, ErrInvalidSchemaVersion},
+		"ZeroContentTypeParam": {"application/json; schema-version=0", nil, 0, ErrInvalidSchemaVersion},
+		"NegativeContentParam": {"application/json; schema-version=-1", nil, 0, ErrInvalidSchemaVersion},
+	}
+	for name, testCase := range testCases {
+		version, err := GetRequestedSchemaVersion(testCase.contentType, testCase.attributes)
+		assert.Equal(t, testCase.version, version, name)
+		assert.Equal(t, testCase.err, err, name)
+	}
+}
+
+func TestChannelSchemaGetIncompatibilities(t *testing.T) {
+	previous, _ := NewChannelSchema(sampleChannel, 1, sampleSchemaDefinition)
+	testCases := map[string]struct {
+		definition        string
+		incompatibilities []string
+	}{
+		"Same":                    {sampleSchemaDefinition, []string{}},
+		"OptionalPropertyAdded":   {`{"type": "object", "properties": {"id": {"type": "number"}, "name": {"type": "string"}}, "required": ["id"]}`, []string{}},
+		"RequiredPropertyRemoved": {`{"type": "object"}`, []string{}},
+		"TypeWidened":             {`{"type": ["object", "null"], "properties": {"id": {"type": ["integer", "string"]}}}`, []string{}},
+		"RequiredPropertyAdded":   {`{"type": "object", "required": ["id", "name"]}`, []string{`#: property "name" is now required`}},
+		"TypeNarrowed":            {`{"type": "object", "properties": {"id": {"type": "string"}}}`, []string{"#/properties/id: type is narrowed to string"}},
+		"NestedChanges": {`{"properties": {"tags": {"items": {"type": "object", "properties": {"name": {"enum": ["a"], "type": "string"}}, "additionalProperties": false, "required": ["name"]}}}}`,
+			[]string{`#/properties/tags/items: property "name" is now required`, "#/properties/tags/items: additional properties are no longer allowed"}},
+		"NothingAllowed": {`false`, []string{"#: no payload is allowed"}},
+	}
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			schema, err := NewChannelSchema(sampleChannel, 2, testCase.definition)
+			assert.Nil(t, err)
+			incompatibilities, err := schema.GetIncompatibilities(previous)
+			assert.Nil(t, err)
+			assert.Equal(t, testCase.incompatibilities, incompatibilities)
+		})
+	}
+	t.Run("TypeAdded", func(t *testing.T) {
+		t.Parallel()
+		untyped, _ := NewChannelSchema(sampleChannel, 1, `{}`)
+		schema, _ := NewChannelSchema(sampleChannel, 2, `{"type": "object"}`)
+		incompatibilities, err := schema.GetIncompatibilities(untyped)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"#: type is narrowed to object"}, incompatibilities)
+	})
+	t.Run("InvalidPrevious", func(t *testing.T) {
+		t.Parallel()
+		_, err := previous.GetIncompatibilities(&ChannelSchema{Definition: "{"})
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,86 @@
+package data
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+const (
+	// CloudEventsSpecVersion is the version of the CloudEvents spec accepted on broadcast and used for delivery
+	CloudEventsSpecVersion = "1.0"
+	// MaxCloudEventLength is the maximum length of the JSON serialized CloudEvent metadata of a message
+	MaxCloudEventLength = 2048
+	// DefaultCloudEventType is the type of the CloudEvent delivered for a message that was not broadcasted as a CloudEvent
+	DefaultCloudEventType  = "webhook-broker.message"
+	cloudEventSourcePrefix = "/producer/"
+)
+
+// CloudEvent is the CloudEvents metadata of a message broadcasted as a CloudEvent, the event's ID being the message ID; zero value means the message
+// was not a CloudEvent
+type CloudEvent struct {
+	Type    string
+	Source  string
+	Subject string
+	Time    time.Time
+}
+
+// IsZero returns whether the message has no CloudEvent metadata
+func (cloudEvent CloudEvent) IsZero() bool {
+	return len(cloudEvent.Type) <= 0 && len(cloudEvent.Source) <= 0 && len(cloudEvent.Subject) <= 0 && cloudEvent.Time.IsZero()
+}
+
+// IsValid returns whether the metadata is either zero or has both type and source, no value contains a line break and the serialized metadata fits
+// in MaxCloudEventLength
+func (cloudEvent CloudEvent) IsValid() bool {
+	if cloudEvent.IsZero() {
+		return true
+	}
+	if len(cloudEvent.Type) <= 0 || len(cloudEvent.Source) <= 0 || strings.ContainsAny(cloudEvent.Type+cloudEvent.Source+cloudEvent.Subject, "\r\n") {
+		return false
+	}
+	value, err := cloudEvent.Value()
+	return err == nil && len(value.(string)) <= MaxCloudEventLength
+}
+
+// Scan de-serializes CloudEvent for reading from DB; empty column value is read as zero value
+func (cloudEvent *CloudEvent) Scan(value interface{}) (err error) {
+	var stringVal string
+	switch typedValue := value.(type) {
+	case string:
+		stringVal = typedValue
+	case sql.RawBytes:
+		stringVal = string(typedValue)
+	case []byte:
+		stringVal = string(typedValue)
+	}
+	*cloudEvent = CloudEvent{}
+	if len(stringVal) > 0 {
+		err = json.Unmarshal([]byte(stringVal), cloudEvent)
+	}
+	return err
+}
+
+// Value serializes CloudEvent to write to DB; zero value is written as empty string
+func (cloudEvent CloudEvent) Value() (driver.Value, error) {
+	if cloudEvent.IsZero() {
+		return "", nil
+	}
+	serialized, err := json.Marshal(cloudEvent)
+	return string(serialized), err
+}
+
+// GetCloudEvent retrieves the CloudEvent metadata to deliver the message with; for a message not broadcasted as a CloudEvent its type is
+// DefaultCloudEventType, its source is the producer and its time is when the message was received
+func (message *Message) GetCloudEvent() CloudEvent {
+	if !message.CloudEvent.IsZero() {
+		return message.CloudEvent
+	}
+	cloudEvent := CloudEvent{Type: DefaultCloudEventType, Time: message.ReceivedAt}
+	if message.ProducedBy != nil {
+		cloudEvent.Source = cloudEventSourcePrefix + message.ProducedBy.ProducerID
+	}
+	return cloudEvent
+}
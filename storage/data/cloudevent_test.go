@@ -0,0 +1,51 @@
+package data
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloudEventIsValid(t *testing.T) {
+	assert.True(t, CloudEvent{}.IsValid())
+	assert.True(t, CloudEvent{Type: "com.example.created", Source: "/example"}.IsValid())
+	assert.True(t, CloudEvent{Type: "com.example.created", Source: "/example", Subject: "item-1", Time: time.Now()}.IsValid())
+	for _, cloudEvent := range []CloudEvent{{Type: "com.example.created"}, {Source: "/example"}, {Subject: "item-1"}, {Time: time.Now()},
+		{Type: "com.example\ncreated", Source: "/example"}, {Type: "com.example.created", Source: "/example", Subject: "item\r1"},
+		{Type: "com.example.created", Source: strings.Repeat("a", MaxCloudEventLength)}} {
+		assert.False(t, cloudEvent.IsValid(), cloudEvent)
+	}
+}
+
+func TestCloudEventValueAndScan(t *testing.T) {
+	value, err := CloudEvent{}.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, "", value)
+	cloudEvent := CloudEvent{Type: "com.example.created", Source: "/example", Subject: "item-1", Time: time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)}
+	value, err = cloudEvent.Value()
+	assert.Nil(t, err)
+	for _, dbValue := range []interface{}{value, []byte(value.(string))} {
+		scanned := CloudEvent{Type: "stale"}
+		assert.Nil(t, scanned.Scan(dbValue))
+		assert.Equal(t, cloudEvent, scanned)
+	}
+	scanned := CloudEvent{Type: "stale"}
+	assert.Nil(t, scanned.Scan(""))
+	assert.True(t, scanned.IsZero())
+	assert.NotNil(t, scanned.Scan("{"))
+}
+
+func TestMessageGetCloudEvent(t *testing.T) {
+	channel, _ := NewChannel("cloudevent-channel", "token")
+	producer, _ := NewProducer("cloudevent-producer", "token")
+	msg, err := NewMessage(channel, producer, "payload", "text/plain")
+	assert.Nil(t, err)
+	assert.Equal(t, CloudEvent{Type: DefaultCloudEventType, Source: "/producer/cloudevent-producer", Time: msg.ReceivedAt}, msg.GetCloudEvent())
+	msg.CloudEvent = CloudEvent{Type: "com.example.created", Source: "/example"}
+	assert.True(t, msg.IsInValidState())
+	assert.Equal(t, msg.CloudEvent, msg.GetCloudEvent())
+	msg.CloudEvent = CloudEvent{Type: "com.example.created"}
+	assert.False(t, msg.IsInValidState())
+}
@@ -1,27 +1,181 @@
 package data
 
-import "net/url"
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ConsumerVerificationStatus represents whether the consumer's callback URL has proven that it wants to receive messages
+type ConsumerVerificationStatus int
+
+func (status ConsumerVerificationStatus) String() string {
+	switch status {
+	case ConsumerVerified:
+		return ConsumerVerifiedStr
+	case ConsumerPendingVerification:
+		return ConsumerPendingVerificationStr
+	default:
+		return strconv.Itoa(int(status))
+	}
+}
+
+const (
+	// ConsumerVerified signifies that the callback URL is trusted to receive messages
+	ConsumerVerified ConsumerVerificationStatus = iota + 2000
+	// ConsumerPendingVerification signifies that the callback URL is yet to echo the verification challenge
+	ConsumerPendingVerification
+	// ConsumerVerifiedStr is the string rep of ConsumerVerified
+	ConsumerVerifiedStr = "VERIFIED"
+	// ConsumerPendingVerificationStr is the string rep of ConsumerPendingVerification
+	ConsumerPendingVerificationStr = "PENDING_VERIFICATION"
+)
+
+// ConsumerType represents how the consumer receives the messages of the channel
+type ConsumerType int
+
+func (consumerType ConsumerType) String() string {
+	switch consumerType {
+	case PushConsumer:
+		return PushConsumerStr
+	case StreamConsumer:
+		return StreamConsumerStr
+	default:
+		return strconv.Itoa(int(consumerType))
+	}
+}
+
+// ParseConsumerType returns the consumer type for its string rep, case insensitive; empty string is considered push
+func ParseConsumerType(value string) (ConsumerType, bool) {
+	switch strings.ToUpper(value) {
+	case "", PushConsumerStr:
+		return PushConsumer, true
+	case StreamConsumerStr:
+		return StreamConsumer, true
+	default:
+		return 0, false
+	}
+}
+
+const (
+	// PushConsumer receives messages as calls to its callback URL
+	PushConsumer ConsumerType = iota + 3000
+	// StreamConsumer receives messages over a connection it holds open with the broker and acks each of them
+	StreamConsumer
+	// PushConsumerStr is the string rep of PushConsumer
+	PushConsumerStr = "PUSH"
+	// StreamConsumerStr is the string rep of StreamConsumer
+	StreamConsumerStr = "STREAM"
+)
+
+// DeliveryFormat represents how a message is formatted in the call to the consumer's callback URL
+type DeliveryFormat int
+
+func (format DeliveryFormat) String() string {
+	switch format {
+	case RawDelivery:
+		return RawDeliveryStr
+	case BinaryCloudEventDelivery:
+		return BinaryCloudEventDeliveryStr
+	case StructuredCloudEventDelivery:
+		return StructuredCloudEventDeliveryStr
+	default:
+		return strconv.Itoa(int(format))
+	}
+}
+
+// ParseDeliveryFormat returns the delivery format for its string rep, case insensitive; empty string is considered raw
+func ParseDeliveryFormat(value string) (DeliveryFormat, bool) {
+	switch strings.ToUpper(value) {
+	case "", RawDeliveryStr:
+		return RawDelivery, true
+	case BinaryCloudEventDeliveryStr:
+		return BinaryCloudEventDelivery, true
+	case StructuredCloudEventDeliveryStr:
+		return StructuredCloudEventDelivery, true
+	default:
+		return 0, false
+	}
+}
+
+const (
+	// RawDelivery sends the message payload as the request body with the message's content type
+	RawDelivery DeliveryFormat = iota + 4000
+	// BinaryCloudEventDelivery sends the message as a binary mode CloudEvent, i.e. the payload as body and the CloudEvent attributes as `ce-*` headers
+	BinaryCloudEventDelivery
+	// StructuredCloudEventDelivery sends the message as a structured mode CloudEvent, i.e. the whole event as `application/cloudevents+json` body
+	StructuredCloudEventDelivery
+	// RawDeliveryStr is the string rep of RawDelivery
+	RawDeliveryStr = "RAW"
+	// BinaryCloudEventDeliveryStr is the string rep of BinaryCloudEventDelivery
+	BinaryCloudEventDeliveryStr = "CLOUDEVENT-BINARY"
+	// StructuredCloudEventDeliveryStr is the string rep of StructuredCloudEventDelivery
+	StructuredCloudEventDeliveryStr = "CLOUDEVENT-STRUCTURED"
+)
 
 // Consumer is the object that producer broadcasts to and consumer consumes from
 type Consumer struct {
 	MessageStakeholder
-	ConsumerID    string
-	CallbackURL   string
-	ConsumingFrom *Channel
+	ConsumerID            string
+	CallbackURL           string
+	ConsumingFrom         *Channel
+	VerificationStatus    ConsumerVerificationStatus
+	VerificationChallenge string
+	Paused                bool
+	// RoutingKeyPattern limits the messages of the channel the consumer receives to those whose routing key matches it; empty means all
+	RoutingKeyPattern string
+	Type              ConsumerType
+	DeliveryFormat    DeliveryFormat
 }
 
 // QuickFix fixes the model to set default ID, name same as producer id, created and updated at to current time.
 func (consumer *Consumer) QuickFix() bool {
 	madeChanges := consumer.BasePaginateable.QuickFix()
 	madeChanges = setValIfBothNotEmpty(&consumer.Name, &consumer.ConsumerID) || madeChanges
+	switch consumer.VerificationStatus {
+	case ConsumerVerified:
+	case ConsumerPendingVerification:
+	default:
+		consumer.VerificationStatus = ConsumerVerified
+		madeChanges = true
+	}
+	switch consumer.Type {
+	case PushConsumer:
+	case StreamConsumer:
+	default:
+		consumer.Type = PushConsumer
+		madeChanges = true
+	}
+	switch consumer.DeliveryFormat {
+	case RawDelivery:
+	case BinaryCloudEventDelivery:
+	case StructuredCloudEventDelivery:
+	default:
+		consumer.DeliveryFormat = RawDelivery
+		madeChanges = true
+	}
 	return madeChanges
 }
 
-// IsInValidState returns false if any of consumer id or name or token is empty, channel is not nil and callback URL is absolute URL
+// IsInValidState returns false if any of consumer id or name or token is empty, channel is not nil, callback URL is absolute URL, verification status and
+// delivery format are recognized and routing key pattern is valid; streaming consumers need no callback URL
 func (consumer *Consumer) IsInValidState() bool {
-	if len(consumer.ConsumerID) <= 0 || len(consumer.Name) <= 0 || len(consumer.Token) <= 0 || consumer.ConsumingFrom == nil || !consumer.ConsumingFrom.IsInValidState() {
+	if len(consumer.ConsumerID) <= 0 || len(consumer.Name) <= 0 || len(consumer.Token) <= 0 || consumer.ConsumingFrom == nil || !consumer.ConsumingFrom.IsInValidState() ||
+		!IsValidRoutingKeyPattern(consumer.RoutingKeyPattern) {
+		return false
+	}
+	if consumer.VerificationStatus != ConsumerVerified && consumer.VerificationStatus != ConsumerPendingVerification {
+		return false
+	}
+	if consumer.Type != PushConsumer && consumer.Type != StreamConsumer {
 		return false
 	}
+	if consumer.DeliveryFormat != RawDelivery && consumer.DeliveryFormat != BinaryCloudEventDelivery && consumer.DeliveryFormat != StructuredCloudEventDelivery {
+		return false
+	}
+	if consumer.IsStreaming() && len(consumer.CallbackURL) <= 0 {
+		return true
+	}
 	if callbackURL, err := url.Parse(consumer.CallbackURL); err != nil || !callbackURL.IsAbs() {
 		return false
 	}
@@ -36,11 +190,42 @@ func (consumer *Consumer) GetChannelIDSafely() (channelID string) {
 	return channelID
 }
 
+// IsDeliverable returns whether jobs for the consumer should be attempted at this point
+func (consumer *Consumer) IsDeliverable() bool {
+	return consumer.IsVerified() && !consumer.Paused
+}
+
+// IsStreaming returns whether the consumer receives its messages over a stream instead of calls to its callback URL
+func (consumer *Consumer) IsStreaming() bool {
+	return consumer.Type == StreamConsumer
+}
+
+// IsSubscribedTo returns whether the message's routing key matches the consumer's routing key pattern
+func (consumer *Consumer) IsSubscribedTo(message *Message) bool {
+	return MatchRoutingKey(consumer.RoutingKeyPattern, message.RoutingKey)
+}
+
+// IsVerified returns whether the consumer's callback URL is allowed to receive messages
+func (consumer *Consumer) IsVerified() bool {
+	return consumer.VerificationStatus == ConsumerVerified
+}
+
 // NewConsumer creates new Consumer
 func NewConsumer(channel *Channel, consumerID, token string, callbackURL *url.URL) (*Consumer, error) {
 	if len(consumerID) <= 0 || len(token) <= 0 || channel == nil || !callbackURL.IsAbs() {
 		return nil, ErrInsufficientInformationForCreating
 	}
-	consumer := Consumer{ConsumerID: consumerID, ConsumingFrom: channel, CallbackURL: callbackURL.String(), MessageStakeholder: createMessageStakeholder(consumerID, token)}
+	consumer := Consumer{ConsumerID: consumerID, ConsumingFrom: channel, CallbackURL: callbackURL.String(), MessageStakeholder: createMessageStakeholder(consumerID, token),
+		VerificationStatus: ConsumerVerified, Type: PushConsumer, DeliveryFormat: RawDelivery}
+	return &consumer, nil
+}
+
+// NewStreamConsumer creates new Consumer receiving messages over a stream
+func NewStreamConsumer(channel *Channel, consumerID, token string) (*Consumer, error) {
+	if len(consumerID) <= 0 || len(token) <= 0 || channel == nil {
+		return nil, ErrInsufficientInformationForCreating
+	}
+	consumer := Consumer{ConsumerID: consumerID, ConsumingFrom: channel, MessageStakeholder: createMessageStakeholder(consumerID, token),
+		VerificationStatus: ConsumerVerified, Type: StreamConsumer, DeliveryFormat: RawDelivery}
 	return &consumer, nil
 }
@@ -78,6 +78,26 @@ func TestConsumerIsInValidState(t *testing.T) {
 		consumer.CallbackURL = sampleRelativeCallbackURL.String()
 		assert.False(t, consumer.IsInValidState())
 	})
+	t.Run("InvalidRoutingKeyPatternFalse", func(t *testing.T) {
+		t.Parallel()
+		consumer, _ := NewConsumer(sampleChannel, someID, someToken, sampleCallbackURL)
+		consumer.RoutingKeyPattern = "user.*"
+		assert.True(t, consumer.IsInValidState())
+		consumer.RoutingKeyPattern = "user..*"
+		assert.False(t, consumer.IsInValidState())
+	})
+}
+
+func TestConsumerIsSubscribedTo(t *testing.T) {
+	consumer, _ := NewConsumer(sampleChannel, someID, someToken, sampleCallbackURL)
+	message := &Message{RoutingKey: "user.created"}
+	assert.True(t, consumer.IsSubscribedTo(message))
+	assert.True(t, consumer.IsSubscribedTo(&Message{}))
+	consumer.RoutingKeyPattern = "user.*"
+	assert.True(t, consumer.IsSubscribedTo(message))
+	assert.False(t, consumer.IsSubscribedTo(&Message{}))
+	consumer.RoutingKeyPattern = "order.#"
+	assert.False(t, consumer.IsSubscribedTo(message))
 }
 
 func TestConsumerQuickFix(t *testing.T) {
@@ -107,3 +127,74 @@ func TestGetChannelIDSafely(t *testing.T) {
 	consumer.ConsumingFrom = channel
 	assert.Equal(t, someID, consumer.GetChannelIDSafely())
 }
+
+func TestConsumerVerificationStatus(t *testing.T) {
+	assert.Equal(t, ConsumerVerifiedStr, ConsumerVerified.String())
+	assert.Equal(t, ConsumerPendingVerificationStr, ConsumerPendingVerification.String())
+	assert.Equal(t, "1", ConsumerVerificationStatus(1).String())
+	consumer, err := NewConsumer(sampleChannel, someID, someToken, sampleCallbackURL)
+	assert.Nil(t, err)
+	assert.True(t, consumer.IsVerified())
+	consumer.VerificationStatus = ConsumerPendingVerification
+	assert.False(t, consumer.IsVerified())
+	assert.True(t, consumer.IsInValidState())
+	consumer.VerificationStatus = ConsumerVerificationStatus(0)
+	assert.False(t, consumer.IsInValidState())
+	assert.True(t, consumer.QuickFix())
+	assert.True(t, consumer.IsVerified())
+	assert.True(t, consumer.IsDeliverable())
+	consumer.Paused = true
+	assert.False(t, consumer.IsDeliverable())
+}
+
+func TestConsumerType(t *testing.T) {
+	assert.Equal(t, PushConsumerStr, PushConsumer.String())
+	assert.Equal(t, StreamConsumerStr, StreamConsumer.String())
+	assert.Equal(t, "1", ConsumerType(1).String())
+	for value, expected := range map[string]ConsumerType{"": PushConsumer, "push": PushConsumer, "STREAM": StreamConsumer, "Stream": StreamConsumer} {
+		consumerType, ok := ParseConsumerType(value)
+		assert.True(t, ok)
+		assert.Equal(t, expected, consumerType)
+	}
+	_, ok := ParseConsumerType("pull")
+	assert.False(t, ok)
+	consumer, err := NewStreamConsumer(sampleChannel, someID, someToken)
+	assert.Nil(t, err)
+	assert.True(t, consumer.IsStreaming())
+	assert.Empty(t, consumer.CallbackURL)
+	assert.True(t, consumer.IsInValidState())
+	consumer.CallbackURL = "/relative"
+	assert.False(t, consumer.IsInValidState())
+	consumer.Type = ConsumerType(0)
+	assert.False(t, consumer.IsInValidState())
+	assert.True(t, consumer.QuickFix())
+	assert.False(t, consumer.IsStreaming())
+	_, err = NewStreamConsumer(nil, someID, someToken)
+	assert.Equal(t, ErrInsufficientInformationForCreating, err)
+	consumer, _ = NewConsumer(sampleChannel, someID, someToken, sampleCallbackURL)
+	assert.False(t, consumer.IsStreaming())
+}
+
+func TestConsumerDeliveryFormat(t *testing.T) {
+	assert.Equal(t, RawDeliveryStr, RawDelivery.String())
+	assert.Equal(t, BinaryCloudEventDeliveryStr, BinaryCloudEventDelivery.String())
+	assert.Equal(t, StructuredCloudEventDeliveryStr, StructuredCloudEventDelivery.String())
+	assert.Equal(t, "1", DeliveryFormat(1).String())
+	for value, expected := range map[string]DeliveryFormat{"": RawDelivery, "raw": RawDelivery, "cloudevent-binary": BinaryCloudEventDelivery,
+		"CloudEvent-Structured": StructuredCloudEventDelivery} {
+		format, ok := ParseDeliveryFormat(value)
+		assert.True(t, ok)
+		assert.Equal(t, expected, format)
+	}
+	_, ok := ParseDeliveryFormat("cloudevent")
+	assert.False(t, ok)
+	consumer, _ := NewConsumer(sampleChannel, someID, someToken, sampleCallbackURL)
+	assert.Equal(t, RawDelivery, consumer.DeliveryFormat)
+	consumer.DeliveryFormat = StructuredCloudEventDelivery
+	assert.True(t, consumer.IsInValidState())
+	consumer.DeliveryFormat = DeliveryFormat(0)
+	assert.False(t, consumer.IsInValidState())
+	assert.True(t, consumer.QuickFix())
+	assert.Equal(t, RawDelivery, consumer.DeliveryFormat)
+	assert.True(t, consumer.IsInValidState())
+}
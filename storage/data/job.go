@@ -18,13 +18,18 @@ func (status JobStatus) String() string {
 		return JobDeliveredStr
 	case JobDead:
 		return JobDeadStr
+	case JobDiscarded:
+		return JobDiscardedStr
+	case JobCancelled:
+		return JobCancelledStr
 	default:
 		return strconv.Itoa(int(status))
 	}
 }
 
 const (
-	deliverJobLockPrefix = "dj-"
+	deliverJobLockPrefix   = "dj-"
+	maxFailureReasonLength = 255
 	// JobQueued is the job status during first attempt
 	JobQueued JobStatus = iota + 1000
 	// JobInflight is to signify that the DeliveryJob is in its first attempt
@@ -33,6 +38,10 @@ const (
 	JobDelivered
 	// JobDead signifies that retry has taken its toll and max retried happened
 	JobDead
+	// JobDiscarded signifies that a dead job was discarded from the DLQ and will never be attempted again
+	JobDiscarded
+	// JobCancelled signifies that the job was outstanding when its consumer, channel or producer was deleted and will never be attempted again
+	JobCancelled
 	// JobQueuedStr is the string rep of JobQueued
 	JobQueuedStr = "QUEUED"
 	// JobInflightStr is the string rep of JobInflight
@@ -41,6 +50,10 @@ const (
 	JobDeliveredStr = "DELIVERED"
 	// JobDeadStr is the string rep of JobDead
 	JobDeadStr = "DEAD"
+	// JobDiscardedStr is the string rep of JobDiscarded
+	JobDiscardedStr = "DISCARDED"
+	// JobCancelledStr is the string rep of JobCancelled
+	JobCancelledStr = "CANCELLED"
 )
 
 // DeliveryJob represents the DTO object for deliverying a Message to a consumer
@@ -53,6 +66,29 @@ type DeliveryJob struct {
 	DispatchReceivedAt    time.Time
 	EarliestNextAttemptAt time.Time
 	RetryAttemptCount     uint
+	FailureReason         string
+}
+
+// DeadJobFilter selects dead jobs of a channel; rest of the criteria narrow down the selection only when set
+type DeadJobFilter struct {
+	Channel       *Channel
+	Consumer      *Consumer
+	JobIDs        []string
+	MessageIDs    []string
+	DeadSince     time.Time
+	DeadUntil     time.Time
+	FailureReason string
+}
+
+// IsInValidState returns false if channel is missing, consumer is not of the channel or the time range is inverted
+func (filter *DeadJobFilter) IsInValidState() bool {
+	if filter.Channel == nil || !filter.Channel.IsInValidState() {
+		return false
+	}
+	if filter.Consumer != nil && filter.Consumer.GetChannelIDSafely() != filter.Channel.ChannelID {
+		return false
+	}
+	return filter.DeadSince.IsZero() || filter.DeadUntil.IsZero() || !filter.DeadUntil.Before(filter.DeadSince)
 }
 
 // QuickFix fixes the object state automatically as much as possible
@@ -76,6 +112,8 @@ func (job *DeliveryJob) QuickFix() bool {
 	case JobInflight:
 	case JobDelivered:
 	case JobDead:
+	case JobDiscarded:
+	case JobCancelled:
 	default:
 		job.Status = JobQueued
 		madeChanges = true
@@ -90,7 +128,8 @@ func (job *DeliveryJob) IsInValidState() bool {
 	if job.Message == nil || !job.Message.IsInValidState() || job.Listener == nil || !job.Listener.IsInValidState() {
 		valid = false
 	}
-	if valid && job.Status != JobQueued && job.Status != JobInflight && job.Status != JobDelivered && job.Status != JobDead {
+	if valid && job.Status != JobQueued && job.Status != JobInflight && job.Status != JobDelivered && job.Status != JobDead && job.Status != JobDiscarded &&
+		job.Status != JobCancelled {
 		valid = false
 	}
 	if valid {
@@ -105,6 +144,17 @@ func (job *DeliveryJob) IsInValidState() bool {
 	return valid
 }
 
+// SetFailure records the error of the last delivery attempt as the failure reason, truncated to fit storage
+func (job *DeliveryJob) SetFailure(err error) {
+	job.FailureReason = ""
+	if err != nil {
+		job.FailureReason = err.Error()
+	}
+	if len(job.FailureReason) > maxFailureReasonLength {
+		job.FailureReason = job.FailureReason[:maxFailureReasonLength]
+	}
+}
+
 // GetLockID retrieves the Lock ID representing this instance of DeliveryJob
 func (job *DeliveryJob) GetLockID() string {
 	return deliverJobLockPrefix + job.ID.String()
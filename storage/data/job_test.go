@@ -1,6 +1,8 @@
 package data
 
 import (
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -37,6 +39,10 @@ func TestDeliveryJobQuickFix(t *testing.T) {
 		assert.Equal(t, false, job.QuickFix())
 		job.Status = JobDelivered
 		assert.Equal(t, false, job.QuickFix())
+		job.Status = JobDiscarded
+		assert.Equal(t, false, job.QuickFix())
+		job.Status = JobCancelled
+		assert.Equal(t, false, job.QuickFix())
 	})
 	t.Run("BaseFix", func(t *testing.T) {
 		t.Parallel()
@@ -111,6 +117,10 @@ func TestDeliveryJobIsInValidState(t *testing.T) {
 		job := getDeliveryJob()
 		job.Status = JobDead
 		assert.Equal(t, true, job.IsInValidState())
+		job.Status = JobDiscarded
+		assert.Equal(t, true, job.IsInValidState())
+		job.Status = JobCancelled
+		assert.Equal(t, true, job.IsInValidState())
 		job.Status = JobDelivered
 		assert.Equal(t, true, job.IsInValidState())
 		job.Status = JobInflight
@@ -156,5 +166,28 @@ func TestDJString(t *testing.T) {
 	assert.Equal(t, JobDeliveredStr, JobDelivered.String())
 	assert.Equal(t, JobInflightStr, JobInflight.String())
 	assert.Equal(t, JobQueuedStr, JobQueued.String())
+	assert.Equal(t, JobDiscardedStr, JobDiscarded.String())
+	assert.Equal(t, JobCancelledStr, JobCancelled.String())
 	assert.Equal(t, "1", JobStatus(1).String())
 }
+
+func TestDJSetFailure(t *testing.T) {
+	job := getDeliveryJob()
+	job.SetFailure(errors.New("error - client status not 2xx: 503 Service Unavailable"))
+	assert.Equal(t, "error - client status not 2xx: 503 Service Unavailable", job.FailureReason)
+	job.SetFailure(errors.New(strings.Repeat("a", maxFailureReasonLength+10)))
+	assert.Equal(t, maxFailureReasonLength, len(job.FailureReason))
+	job.SetFailure(nil)
+	assert.Equal(t, "", job.FailureReason)
+}
+
+func TestDeadJobFilterIsInValidState(t *testing.T) {
+	consumer := getConsumer()
+	otherChannel, _ := NewChannel("other-channel", "sample-token")
+	now := time.Now()
+	assert.False(t, (&DeadJobFilter{}).IsInValidState())
+	assert.True(t, (&DeadJobFilter{Channel: consumer.ConsumingFrom}).IsInValidState())
+	assert.True(t, (&DeadJobFilter{Channel: consumer.ConsumingFrom, Consumer: consumer, DeadSince: now.Add(-1 * time.Hour), DeadUntil: now}).IsInValidState())
+	assert.False(t, (&DeadJobFilter{Channel: otherChannel, Consumer: consumer}).IsInValidState())
+	assert.False(t, (&DeadJobFilter{Channel: consumer.ConsumingFrom, DeadSince: now, DeadUntil: now.Add(-1 * time.Hour)}).IsInValidState())
+}
@@ -0,0 +1,60 @@
+package data
+
+import "time"
+
+var (
+	// JobStatsWindows are the recent windows, ending when the stats are computed, deliveries are counted over
+	JobStatsWindows = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute, time.Hour}
+)
+
+// JobStats summarizes the backlog and the recent deliveries of the jobs of a consumer, or of all consumers of a channel
+type JobStats struct {
+	StatusCounts map[JobStatus]uint
+	// OldestQueuedAt is when the oldest job still queued was dispatched; zero if none is queued
+	OldestQueuedAt time.Time
+	// NextRetryAt is the earliest next attempt of the queued jobs that failed before; zero if none is to be retried
+	NextRetryAt time.Time
+	// DeliveredInWindows is the count of jobs delivered within each of the JobStatsWindows
+	DeliveredInWindows map[time.Duration]uint
+	// SampledCount is the count of the most recently finished, i.e. delivered or dead, jobs sampled for success rate and latency;
+	// SampledDelivered of them got delivered
+	SampledCount     uint
+	SampledDelivered uint
+	// TotalLatency is the sum of the time from dispatch to delivery of the delivered jobs sampled
+	TotalLatency time.Duration
+	ComputedAt   time.Time
+}
+
+// NewJobStats creates stats, computed now, with no jobs
+func NewJobStats() *JobStats {
+	return &JobStats{StatusCounts: make(map[JobStatus]uint), DeliveredInWindows: make(map[time.Duration]uint), ComputedAt: time.Now()}
+}
+
+// GetOldestQueuedAge returns for how long the oldest queued job has been waiting; zero if none is queued
+func (stats *JobStats) GetOldestQueuedAge() time.Duration {
+	if stats.OldestQueuedAt.IsZero() {
+		return 0
+	}
+	return stats.ComputedAt.Sub(stats.OldestQueuedAt)
+}
+
+// GetDeliveredPerMinute returns the average number of jobs delivered per minute within the window
+func (stats *JobStats) GetDeliveredPerMinute(window time.Duration) float64 {
+	return float64(stats.DeliveredInWindows[window]) / window.Minutes()
+}
+
+// GetSuccessRate returns the fraction of the sampled jobs that got delivered rather than dead; 1 if none is sampled
+func (stats *JobStats) GetSuccessRate() float64 {
+	if stats.SampledCount == 0 {
+		return 1
+	}
+	return float64(stats.SampledDelivered) / float64(stats.SampledCount)
+}
+
+// GetAverageLatency returns the average time from dispatch to delivery of the delivered jobs sampled
+func (stats *JobStats) GetAverageLatency() time.Duration {
+	if stats.SampledDelivered == 0 {
+		return 0
+	}
+	return stats.TotalLatency / time.Duration(stats.SampledDelivered)
+}
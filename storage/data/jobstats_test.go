@@ -0,0 +1,25 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobStats(t *testing.T) {
+	stats := NewJobStats()
+	assert.Equal(t, time.Duration(0), stats.GetOldestQueuedAge())
+	assert.Equal(t, 1.0, stats.GetSuccessRate())
+	assert.Equal(t, time.Duration(0), stats.GetAverageLatency())
+	assert.Equal(t, 0.0, stats.GetDeliveredPerMinute(time.Minute))
+	stats.OldestQueuedAt = stats.ComputedAt.Add(-1 * time.Hour)
+	stats.DeliveredInWindows[5*time.Minute] = 10
+	stats.SampledCount = 4
+	stats.SampledDelivered = 3
+	stats.TotalLatency = 3 * time.Second
+	assert.Equal(t, time.Hour, stats.GetOldestQueuedAge())
+	assert.Equal(t, 2.0, stats.GetDeliveredPerMinute(5*time.Minute))
+	assert.Equal(t, 0.75, stats.GetSuccessRate())
+	assert.Equal(t, time.Second, stats.GetAverageLatency())
+}
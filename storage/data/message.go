@@ -2,6 +2,7 @@ package data
 
 import (
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/rs/xid"
@@ -21,6 +22,18 @@ func (status MsgStatus) String() string {
 	}
 }
 
+// ParseMsgStatus parses the string representation of a message status, case insensitively
+func ParseMsgStatus(value string) (MsgStatus, bool) {
+	switch strings.ToUpper(value) {
+	case MsgStatusAcknowledgedStr:
+		return MsgStatusAcknowledged, true
+	case MsgStatusDispatchedStr:
+		return MsgStatusDispatched, true
+	default:
+		return 0, false
+	}
+}
+
 const (
 	messageLockPrefix = "msg-"
 	// MsgStatusAcknowledged represents the state after receiving the message but before it is dispatched
@@ -38,6 +51,11 @@ type Message struct {
 	BasePaginateable
 	MessageID     string
 	Payload       string
+	PayloadRef    string
+	RoutingKey    string
+	Attributes    MessageAttributes
+	CloudEvent    CloudEvent
+	ReceiptURL    string
 	ContentType   string
 	Priority      uint
 	Status        MsgStatus
@@ -72,11 +90,12 @@ func (message *Message) QuickFix() bool {
 	return madeChanges
 }
 
-// IsInValidState returns false if any of message id or payload or content type is empty, channel is nil, callback URL is not url or not absolute URL,
-// status not recognized, received at and outboxed at not set properly. Call QuickFix before IsInValidState is called.
+// IsInValidState returns false if any of message id or content type is empty, both payload and its blob reference are empty, routing key, attributes,
+// CloudEvent metadata or receipt URL are not valid, channel is nil, callback URL is not url or not absolute URL, status not recognized, received at and outboxed at not set properly. Call QuickFix
+// before IsInValidState is called.
 func (message *Message) IsInValidState() bool {
 	valid := true
-	if len(message.MessageID) <= 0 || len(message.Payload) <= 0 || len(message.ContentType) <= 0 {
+	if len(message.MessageID) <= 0 || (len(message.Payload) <= 0 && len(message.PayloadRef) <= 0) || len(message.ContentType) <= 0 || !IsValidRoutingKey(message.RoutingKey) || !message.Attributes.IsValid() || !message.CloudEvent.IsValid() || !IsValidReceiptURL(message.ReceiptURL) {
 		valid = false
 	}
 	if message.BroadcastedTo == nil || !message.BroadcastedTo.IsInValidState() || message.ProducedBy == nil || !message.ProducedBy.IsInValidState() {
@@ -100,6 +119,27 @@ func (message *Message) IsInValidState() bool {
 	return valid
 }
 
+// MessageFilter selects messages of a channel; rest of the criteria narrow down the selection only when set
+type MessageFilter struct {
+	Channel         *Channel
+	Statuses        []MsgStatus
+	ProducerIDs     []string
+	ReceivedSince   time.Time
+	ReceivedUntil   time.Time
+	ContentType     string
+	Priorities      []uint
+	MessageIDPrefix string
+	HasDeadJobs     bool
+}
+
+// IsInValidState returns false if channel is missing or the time range is inverted
+func (filter *MessageFilter) IsInValidState() bool {
+	if filter.Channel == nil || !filter.Channel.IsInValidState() {
+		return false
+	}
+	return filter.ReceivedSince.IsZero() || filter.ReceivedUntil.IsZero() || !filter.ReceivedUntil.Before(filter.ReceivedSince)
+}
+
 // GetChannelIDSafely retrieves channel id account for the fact that BroadcastedTo may be null
 func (message *Message) GetChannelIDSafely() (channelID string) {
 	if message.BroadcastedTo != nil {
@@ -108,6 +148,14 @@ func (message *Message) GetChannelIDSafely() (channelID string) {
 	return channelID
 }
 
+// GetReceiptURL retrieves where delivery receipts of the message are sent to; its own receipt URL else its producer's
+func (message *Message) GetReceiptURL() string {
+	if len(message.ReceiptURL) > 0 || message.ProducedBy == nil {
+		return message.ReceiptURL
+	}
+	return message.ProducedBy.ReceiptURL
+}
+
 // GetLockID retrieves lock ID for the current instance of message
 func (message *Message) GetLockID() string {
 	return messageLockPrefix + message.ID.String()
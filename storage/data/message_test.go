@@ -108,6 +108,14 @@ func TestMessageIsInValidState(t *testing.T) {
 		msg.MessageID = ""
 		assert.False(t, msg.IsInValidState())
 	})
+	t.Run("RoutingKey", func(t *testing.T) {
+		t.Parallel()
+		msg := getCompleteMessageFixture()
+		msg.RoutingKey = "user.created"
+		assert.True(t, msg.IsInValidState())
+		msg.RoutingKey = "user.*"
+		assert.False(t, msg.IsInValidState())
+	})
 	t.Run("InvalidStatus", func(t *testing.T) {
 		t.Parallel()
 		msg := getCompleteMessageFixture()
@@ -193,3 +201,23 @@ func TestMessageString(t *testing.T) {
 	var status MsgStatus
 	assert.Equal(t, "0", status.String())
 }
+
+func TestParseMsgStatus(t *testing.T) {
+	for value, expected := range map[string]MsgStatus{"acknowledged": MsgStatusAcknowledged, MsgStatusDispatchedStr: MsgStatusDispatched} {
+		status, ok := ParseMsgStatus(value)
+		assert.True(t, ok)
+		assert.Equal(t, expected, status)
+	}
+	for _, value := range []string{"", "DELIVERED"} {
+		_, ok := ParseMsgStatus(value)
+		assert.False(t, ok)
+	}
+}
+
+func TestMessageFilterIsInValidState(t *testing.T) {
+	now := time.Now()
+	assert.False(t, (&MessageFilter{}).IsInValidState())
+	assert.True(t, (&MessageFilter{Channel: getChannel()}).IsInValidState())
+	assert.True(t, (&MessageFilter{Channel: getChannel(), ReceivedSince: now.Add(-1 * time.Hour), ReceivedUntil: now}).IsInValidState())
+	assert.False(t, (&MessageFilter{Channel: getChannel(), ReceivedSince: now, ReceivedUntil: now.Add(-1 * time.Hour)}).IsInValidState())
+}
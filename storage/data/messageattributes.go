@@ -0,0 +1,59 @@
+package data
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+)
+
+const (
+	// MaxMessageAttributesLength is the maximum length of the JSON serialized attributes of a message
+	MaxMessageAttributesLength = 4096
+)
+
+// MessageAttributes are the producer supplied name value pairs of a message forwarded to its consumers as headers
+type MessageAttributes map[string]string
+
+// IsValid returns whether every attribute name is non-empty and consists of letters, digits and `-`, no value contains a line break and the
+// serialized attributes fit in MaxMessageAttributesLength
+func (attributes MessageAttributes) IsValid() bool {
+	for name, value := range attributes {
+		if len(name) <= 0 || strings.IndexFunc(name, isInvalidAttributeNameRune) >= 0 || strings.ContainsAny(value, "\r\n") {
+			return false
+		}
+	}
+	value, err := attributes.Value()
+	return err == nil && len(value.(string)) <= MaxMessageAttributesLength
+}
+
+func isInvalidAttributeNameRune(r rune) bool {
+	return !((r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-')
+}
+
+// Scan de-serializes MessageAttributes for reading from DB; empty column value is read as no attributes
+func (attributes *MessageAttributes) Scan(value interface{}) (err error) {
+	var stringVal string
+	switch typedValue := value.(type) {
+	case string:
+		stringVal = typedValue
+	case sql.RawBytes:
+		stringVal = string(typedValue)
+	case []byte:
+		stringVal = string(typedValue)
+	}
+	*attributes = nil
+	if len(stringVal) > 0 {
+		err = json.Unmarshal([]byte(stringVal), attributes)
+	}
+	return err
+}
+
+// Value serializes MessageAttributes to write to DB; no attributes is written as empty string
+func (attributes MessageAttributes) Value() (driver.Value, error) {
+	if len(attributes) <= 0 {
+		return "", nil
+	}
+	serialized, err := json.Marshal(map[string]string(attributes))
+	return string(serialized), err
+}
@@ -0,0 +1,49 @@
+package data
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageAttributesIsValid(t *testing.T) {
+	var noAttributes MessageAttributes
+	assert.True(t, noAttributes.IsValid())
+	assert.True(t, MessageAttributes{"Tenant-Id": "tenant 1", "Trace": ""}.IsValid())
+	for _, attributes := range []MessageAttributes{{"": "value"}, {"Tenant_Id": "value"}, {"Tenant Id": "value"}, {"Tenant": "line\nbreak"},
+		{"Tenant": "carriage\rreturn"}, {"Tenant": strings.Repeat("a", MaxMessageAttributesLength)}} {
+		assert.False(t, attributes.IsValid(), attributes)
+	}
+}
+
+func TestMessageAttributesValueAndScan(t *testing.T) {
+	var noAttributes MessageAttributes
+	value, err := noAttributes.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, "", value)
+	attributes := MessageAttributes{"Tenant-Id": "tenant-1"}
+	value, err = attributes.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, `{"Tenant-Id":"tenant-1"}`, value)
+	for _, dbValue := range []interface{}{value, []byte(value.(string))} {
+		scanned := MessageAttributes{"Stale": "value"}
+		assert.Nil(t, scanned.Scan(dbValue))
+		assert.Equal(t, attributes, scanned)
+	}
+	scanned := MessageAttributes{"Stale": "value"}
+	assert.Nil(t, scanned.Scan(""))
+	assert.Nil(t, scanned)
+	assert.NotNil(t, scanned.Scan("{"))
+}
+
+func TestMessageIsInValidStateWithAttributes(t *testing.T) {
+	channel, _ := NewChannel("attributes-channel", "token")
+	producer, _ := NewProducer("attributes-producer", "token")
+	msg, err := NewMessage(channel, producer, "payload", "text/plain")
+	assert.Nil(t, err)
+	msg.Attributes = MessageAttributes{"Tenant-Id": "tenant-1"}
+	assert.True(t, msg.IsInValidState())
+	msg.Attributes = MessageAttributes{"Tenant Id": "tenant-1"}
+	assert.False(t, msg.IsInValidState())
+}
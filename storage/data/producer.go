@@ -1,16 +1,35 @@
 package data
 
+import (
+	"net/url"
+	"time"
+)
+
 // MessageStakeholder represents all objects around a message, for example, Producer, Channel, Consumer
 type MessageStakeholder struct {
 	BasePaginateable
 	Name  string
 	Token string
+	// DeletedAt is when the stakeholder was soft deleted; zero unless deleted
+	DeletedAt time.Time
+}
+
+// IsDeleted returns whether the stakeholder is soft deleted
+func (stakeholder *MessageStakeholder) IsDeleted() bool {
+	return !stakeholder.DeletedAt.IsZero()
+}
+
+// IsPurgeable returns whether the stakeholder has been deleted for at least the retention period
+func (stakeholder *MessageStakeholder) IsPurgeable(retention time.Duration) bool {
+	return stakeholder.IsDeleted() && time.Since(stakeholder.DeletedAt) >= retention
 }
 
 // Producer represents generator of messages
 type Producer struct {
 	MessageStakeholder
 	ProducerID string
+	// ReceiptURL is where delivery receipts of the producer's messages are sent to unless the message has its own; empty means no receipts
+	ReceiptURL string
 }
 
 // QuickFix fixes the model to set default ID, name same as producer id, created and updated at to current time.
@@ -20,14 +39,23 @@ func (prod *Producer) QuickFix() bool {
 	return madeChanges
 }
 
-// IsInValidState returns false if any of producer id or name or token is empty
+// IsInValidState returns false if any of producer id or name or token is empty or receipt URL is set but not a valid receipt URL
 func (prod *Producer) IsInValidState() bool {
-	if len(prod.ProducerID) <= 0 || len(prod.Name) <= 0 || len(prod.Token) <= 0 {
+	if len(prod.ProducerID) <= 0 || len(prod.Name) <= 0 || len(prod.Token) <= 0 || !IsValidReceiptURL(prod.ReceiptURL) {
 		return false
 	}
 	return true
 }
 
+// IsValidReceiptURL returns whether the receipt URL is either empty or an absolute HTTP(S) URL
+func IsValidReceiptURL(receiptURL string) bool {
+	if len(receiptURL) <= 0 {
+		return true
+	}
+	parsedURL, err := url.Parse(receiptURL)
+	return err == nil && parsedURL.IsAbs() && (parsedURL.Scheme == "http" || parsedURL.Scheme == "https") && len(receiptURL) <= maxReceiptURLLength
+}
+
 // NewProducer creates new Producer
 func NewProducer(producerID string, token string) (*Producer, error) {
 	if len(producerID) <= 0 || len(token) <= 0 {
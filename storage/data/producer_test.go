@@ -1,7 +1,9 @@
 package data
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -106,3 +108,25 @@ func TestProducerIsInValidState(t *testing.T) {
 		assert.False(t, producer.IsInValidState())
 	})
 }
+
+func TestProducerIsInValidState_ReceiptURL(t *testing.T) {
+	producer, _ := NewProducer(someID, someToken)
+	for _, receiptURL := range []string{"", "https://imytech.net/receipts", "http://localhost:8080/receipts?id=1"} {
+		producer.ReceiptURL = receiptURL
+		assert.True(t, producer.IsInValidState(), receiptURL)
+	}
+	for _, receiptURL := range []string{"/receipts", "ftp://imytech.net/receipts", "https://imytech.net/" + strings.Repeat("a", 1000), "://"} {
+		producer.ReceiptURL = receiptURL
+		assert.False(t, producer.IsInValidState(), receiptURL)
+	}
+}
+
+func TestMessageStakeholderIsDeleted(t *testing.T) {
+	producer, _ := NewProducer(someID, someToken)
+	assert.False(t, producer.IsDeleted())
+	assert.False(t, producer.IsPurgeable(0))
+	producer.DeletedAt = time.Now().Add(-2 * time.Hour)
+	assert.True(t, producer.IsDeleted())
+	assert.True(t, producer.IsPurgeable(time.Hour))
+	assert.False(t, producer.IsPurgeable(3*time.Hour))
+}
@@ -0,0 +1,29 @@
+package data
+
+// PublishGrant allows a producer to broadcast messages to a channel; a producer can only broadcast to the channels it is granted
+type PublishGrant struct {
+	BasePaginateable
+	Channel  *Channel
+	Producer *Producer
+}
+
+// QuickFix fixes the model to set default ID, created and updated at to current time.
+func (grant *PublishGrant) QuickFix() bool {
+	return grant.BasePaginateable.QuickFix()
+}
+
+// IsInValidState returns false if either the channel or the producer is not valid
+func (grant *PublishGrant) IsInValidState() bool {
+	return grant.Channel != nil && grant.Channel.IsInValidState() && grant.Producer != nil && grant.Producer.IsInValidState()
+}
+
+// NewPublishGrant creates a new grant for the producer to broadcast to the channel
+func NewPublishGrant(channel *Channel, producer *Producer) (*PublishGrant, error) {
+	grant := &PublishGrant{Channel: channel, Producer: producer}
+	grant.QuickFix()
+	var err error
+	if !grant.IsInValidState() {
+		err = ErrInsufficientInformationForCreating
+	}
+	return grant, err
+}
@@ -0,0 +1,29 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPublishGrant(t *testing.T) {
+	producer, _ := NewProducer("grant-producer", "token")
+	t.Run("Invalid", func(t *testing.T) {
+		t.Parallel()
+		_, err := NewPublishGrant(nil, producer)
+		assert.Equal(t, ErrInsufficientInformationForCreating, err)
+		_, err = NewPublishGrant(sampleChannel, nil)
+		assert.Equal(t, ErrInsufficientInformationForCreating, err)
+		_, err = NewPublishGrant(sampleChannel, &Producer{})
+		assert.Equal(t, ErrInsufficientInformationForCreating, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		grant, err := NewPublishGrant(sampleChannel, producer)
+		assert.Nil(t, err)
+		assert.NotNil(t, grant.ID)
+		assert.False(t, grant.CreatedAt.IsZero())
+		assert.False(t, grant.QuickFix())
+		assert.True(t, grant.IsInValidState())
+	})
+}
@@ -0,0 +1,175 @@
+package data
+
+import (
+	"math"
+	"time"
+
+	"github.com/newscred/webhook-broker/config"
+)
+
+// QuotaSubjectType is the type of the resource a publish quota belongs to
+type QuotaSubjectType string
+
+const (
+	// ProducerQuota is the quota of a producer, i.e. messages published by the producer to any channel
+	ProducerQuota QuotaSubjectType = "producer"
+	// ChannelQuota is the quota of a channel, i.e. messages published to the channel by any producer
+	ChannelQuota QuotaSubjectType = "channel"
+)
+
+// PublishQuota is the publish rate limit set on a producer or a channel along with the token buckets enforcing it; one bucket for the number of
+// messages and one for the bytes of their payloads
+type PublishQuota struct {
+	BasePaginateable
+	SubjectType QuotaSubjectType
+	SubjectID   string
+	// Limit is the rate limit set on the subject; a zero field means the broker-wide default applies
+	Limit         config.PublishRateLimit
+	MessageTokens float64
+	ByteTokens    float64
+	// RefilledAt is when the buckets were last refilled; zero if they never were
+	RefilledAt time.Time
+	// Revision is incremented on every change to the buckets so that concurrent changes can be detected
+	Revision uint
+}
+
+// QuotaClaim is the claim for tokens from the quota of the subject, whose limit is the default unless the quota has one set
+type QuotaClaim struct {
+	SubjectType  QuotaSubjectType
+	SubjectID    string
+	DefaultLimit config.PublishRateLimit
+}
+
+// QuotaUsage is the usage of a quota at a time as per its effective limit
+type QuotaUsage struct {
+	Limit config.PublishRateLimit
+	// RemainingMessages is the number of messages that can be published right away; only set if the messages are limited
+	RemainingMessages uint
+	// RemainingBytes is the number of bytes that can be published right away; negative if a message larger than what was remaining was
+	// published; only set if the bytes are limited
+	RemainingBytes int64
+}
+
+// QuickFix fixes the model to set default ID, created and updated at to current time.
+func (quota *PublishQuota) QuickFix() bool {
+	return quota.BasePaginateable.QuickFix()
+}
+
+// IsInValidState returns false if the subject is not a producer or a channel or its ID is empty
+func (quota *PublishQuota) IsInValidState() bool {
+	return (quota.SubjectType == ProducerQuota || quota.SubjectType == ChannelQuota) && len(quota.SubjectID) > 0
+}
+
+// GetEffectiveLimit returns the limit set on the quota with its zero fields set from the default limit
+func (quota *PublishQuota) GetEffectiveLimit(defaultLimit config.PublishRateLimit) config.PublishRateLimit {
+	limit := quota.Limit
+	if limit.MessagesPerSecond <= 0 {
+		limit.MessagesPerSecond = defaultLimit.MessagesPerSecond
+	}
+	if limit.Burst <= 0 {
+		limit.Burst = defaultLimit.Burst
+	}
+	if limit.BytesPerMinute <= 0 {
+		limit.BytesPerMinute = defaultLimit.BytesPerMinute
+	}
+	return limit
+}
+
+// Refill adds the tokens accrued since the buckets were last refilled as per the limit, up to their capacity; buckets never refilled are filled up
+func (quota *PublishQuota) Refill(limit config.PublishRateLimit, now time.Time) {
+	elapsed := now.Sub(quota.RefilledAt).Seconds()
+	if quota.RefilledAt.IsZero() {
+		elapsed = math.MaxFloat64
+	} else if elapsed < 0 {
+		elapsed = 0
+	}
+	if limit.MessagesPerSecond > 0 {
+		quota.MessageTokens = refillBucket(quota.MessageTokens, getMessageCapacity(limit), float64(limit.MessagesPerSecond), elapsed)
+	}
+	if limit.BytesPerMinute > 0 {
+		quota.ByteTokens = refillBucket(quota.ByteTokens, float64(limit.BytesPerMinute), float64(limit.BytesPerMinute)/60, elapsed)
+	}
+	quota.RefilledAt = now
+}
+
+// GetWait returns how long to wait before the refilled buckets allow a message to be published; zero if they allow it right away. A message
+// needs a token from the message bucket while the byte bucket only needs to have any token left, it is debited the size of the message once
+// published, so that a message larger than the bytes per minute can be published too.
+func (quota *PublishQuota) GetWait(limit config.PublishRateLimit) time.Duration {
+	var wait float64
+	if limit.MessagesPerSecond > 0 && quota.MessageTokens < 1 {
+		wait = (1 - quota.MessageTokens) / float64(limit.MessagesPerSecond)
+	}
+	if limit.BytesPerMinute > 0 && quota.ByteTokens < 1 {
+		wait = math.Max(wait, (1-quota.ByteTokens)/(float64(limit.BytesPerMinute)/60))
+	}
+	return time.Duration(wait * float64(time.Second))
+}
+
+// Take takes the tokens of a message of the size from the refilled buckets
+func (quota *PublishQuota) Take(limit config.PublishRateLimit, size uint) {
+	if limit.MessagesPerSecond > 0 {
+		quota.MessageTokens--
+	}
+	if limit.BytesPerMinute > 0 {
+		quota.ByteTokens -= float64(size)
+	}
+}
+
+// Return gives back the tokens taken for a message of the size, e.g. when it was not published after all; the buckets are not filled beyond
+// their capacity
+func (quota *PublishQuota) Return(limit config.PublishRateLimit, size uint) {
+	if limit.MessagesPerSecond > 0 {
+		quota.MessageTokens = math.Min(quota.MessageTokens+1, getMessageCapacity(limit))
+	}
+	if limit.BytesPerMinute > 0 {
+		quota.ByteTokens = math.Min(quota.ByteTokens+float64(size), float64(limit.BytesPerMinute))
+	}
+}
+
+// GetUsage returns the usage of the quota at the time as per the default limit unless the quota has one set
+func (quota *PublishQuota) GetUsage(defaultLimit config.PublishRateLimit, now time.Time) *QuotaUsage {
+	limit := quota.GetEffectiveLimit(defaultLimit)
+	refilled := *quota
+	refilled.Refill(limit, now)
+	usage := &QuotaUsage{Limit: limit}
+	if limit.MessagesPerSecond > 0 {
+		usage.RemainingMessages = uint(math.Max(math.Floor(refilled.MessageTokens), 0))
+	}
+	if limit.BytesPerMinute > 0 {
+		usage.RemainingBytes = int64(math.Floor(refilled.ByteTokens))
+	}
+	return usage
+}
+
+func getMessageCapacity(limit config.PublishRateLimit) float64 {
+	if limit.Burst > 0 {
+		return float64(limit.Burst)
+	}
+	return float64(limit.MessagesPerSecond)
+}
+
+func refillBucket(tokens, capacity, ratePerSecond, elapsedSeconds float64) float64 {
+	if elapsedSeconds >= (capacity-tokens)/ratePerSecond {
+		return capacity
+	}
+	return tokens + elapsedSeconds*ratePerSecond
+}
+
+// NewPublishQuota creates a new quota, with full buckets once refilled, for the subject
+func NewPublishQuota(subjectType QuotaSubjectType, subjectID string) (*PublishQuota, error) {
+	quota := &PublishQuota{SubjectType: subjectType, SubjectID: subjectID}
+	if !quota.IsInValidState() {
+		return nil, ErrInsufficientInformationForCreating
+	}
+	quota.QuickFix()
+	return quota, nil
+}
+
+// NewPublishClaims returns the claims for tokens to publish a message to the channel as the producer, as per the configured default limits
+func NewPublishClaims(producer *Producer, channel *Channel, rateLimitConfig config.RateLimitConfig) []*QuotaClaim {
+	return []*QuotaClaim{
+		{SubjectType: ProducerQuota, SubjectID: producer.ProducerID, DefaultLimit: rateLimitConfig.GetProducerRateLimit()},
+		{SubjectType: ChannelQuota, SubjectID: channel.ChannelID, DefaultLimit: rateLimitConfig.GetChannelRateLimit()},
+	}
+}
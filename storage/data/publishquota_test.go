@@ -0,0 +1,126 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/newscred/webhook-broker/config"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockRateLimitConfig struct {
+}
+
+func (mockRateLimitConfig) GetProducerRateLimit() config.PublishRateLimit {
+	return config.PublishRateLimit{MessagesPerSecond: 1}
+}
+
+func (mockRateLimitConfig) GetChannelRateLimit() config.PublishRateLimit {
+	return config.PublishRateLimit{BytesPerMinute: 60}
+}
+
+func TestNewPublishQuota(t *testing.T) {
+	t.Parallel()
+	_, err := NewPublishQuota(QuotaSubjectType("consumer"), "someone")
+	assert.Equal(t, ErrInsufficientInformationForCreating, err)
+	_, err = NewPublishQuota(ProducerQuota, "")
+	assert.Equal(t, ErrInsufficientInformationForCreating, err)
+	quota, err := NewPublishQuota(ChannelQuota, "some-channel")
+	assert.Nil(t, err)
+	assert.False(t, quota.ID.IsNil())
+	assert.True(t, quota.RefilledAt.IsZero())
+}
+
+func TestPublishQuotaGetEffectiveLimit(t *testing.T) {
+	t.Parallel()
+	quota, _ := NewPublishQuota(ProducerQuota, "some-producer")
+	defaultLimit := config.PublishRateLimit{MessagesPerSecond: 5, Burst: 10, BytesPerMinute: 1024}
+	assert.Equal(t, defaultLimit, quota.GetEffectiveLimit(defaultLimit))
+	quota.Limit = config.PublishRateLimit{MessagesPerSecond: 50}
+	assert.Equal(t, config.PublishRateLimit{MessagesPerSecond: 50, Burst: 10, BytesPerMinute: 1024}, quota.GetEffectiveLimit(defaultLimit))
+}
+
+func TestPublishQuotaTokenBuckets(t *testing.T) {
+	t.Run("MessagesWithBurst", func(t *testing.T) {
+		t.Parallel()
+		limit := config.PublishRateLimit{MessagesPerSecond: 2, Burst: 3}
+		quota, _ := NewPublishQuota(ProducerQuota, "some-producer")
+		now := time.Now()
+		for index := 0; index < 3; index++ {
+			quota.Refill(limit, now)
+			assert.Equal(t, time.Duration(0), quota.GetWait(limit))
+			quota.Take(limit, 1000)
+		}
+		quota.Refill(limit, now)
+		assert.Equal(t, 500*time.Millisecond, quota.GetWait(limit))
+		assert.Equal(t, float64(0), quota.ByteTokens)
+		quota.Refill(limit, now.Add(time.Second))
+		assert.Equal(t, float64(2), quota.MessageTokens)
+		quota.Refill(limit, now.Add(time.Minute))
+		assert.Equal(t, float64(3), quota.MessageTokens)
+		quota.Refill(limit, now)
+		assert.Equal(t, float64(3), quota.MessageTokens)
+	})
+	t.Run("BytesInDebt", func(t *testing.T) {
+		t.Parallel()
+		limit := config.PublishRateLimit{BytesPerMinute: 600}
+		quota, _ := NewPublishQuota(ChannelQuota, "some-channel")
+		now := time.Now()
+		quota.Refill(limit, now)
+		assert.Equal(t, time.Duration(0), quota.GetWait(limit))
+		quota.Take(limit, 1000)
+		assert.Equal(t, float64(-400), quota.ByteTokens)
+		assert.Equal(t, float64(0), quota.MessageTokens)
+		assert.Equal(t, 40100*time.Millisecond, quota.GetWait(limit))
+		quota.Refill(limit, now.Add(41*time.Second))
+		assert.Equal(t, time.Duration(0), quota.GetWait(limit))
+	})
+	t.Run("Returned", func(t *testing.T) {
+		t.Parallel()
+		limit := config.PublishRateLimit{MessagesPerSecond: 1, Burst: 2, BytesPerMinute: 600}
+		quota, _ := NewPublishQuota(ProducerQuota, "some-producer")
+		quota.Refill(limit, time.Now())
+		quota.Take(limit, 100)
+		quota.Return(limit, 100)
+		assert.Equal(t, float64(2), quota.MessageTokens)
+		assert.Equal(t, float64(600), quota.ByteTokens)
+		// Returning does not fill the buckets beyond their capacity
+		quota.Return(limit, 100)
+		assert.Equal(t, float64(2), quota.MessageTokens)
+		assert.Equal(t, float64(600), quota.ByteTokens)
+	})
+	t.Run("LimitLowered", func(t *testing.T) {
+		t.Parallel()
+		quota, _ := NewPublishQuota(ChannelQuota, "some-channel")
+		now := time.Now()
+		quota.Refill(config.PublishRateLimit{MessagesPerSecond: 10, BytesPerMinute: 6000}, now)
+		quota.Refill(config.PublishRateLimit{MessagesPerSecond: 1, BytesPerMinute: 60}, now)
+		assert.Equal(t, float64(1), quota.MessageTokens)
+		assert.Equal(t, float64(60), quota.ByteTokens)
+	})
+}
+
+func TestPublishQuotaGetUsage(t *testing.T) {
+	t.Parallel()
+	quota, _ := NewPublishQuota(ProducerQuota, "some-producer")
+	now := time.Now()
+	usage := quota.GetUsage(config.PublishRateLimit{}, now)
+	assert.Equal(t, &QuotaUsage{}, usage)
+	limit := config.PublishRateLimit{MessagesPerSecond: 10, BytesPerMinute: 60}
+	quota.Refill(limit, now)
+	quota.Take(limit, 100)
+	usage = quota.GetUsage(limit, now.Add(500*time.Millisecond))
+	assert.Equal(t, &QuotaUsage{Limit: limit, RemainingMessages: 10, RemainingBytes: -40}, usage)
+	assert.Equal(t, float64(9), quota.MessageTokens)
+	assert.Equal(t, now, quota.RefilledAt)
+}
+
+func TestNewPublishClaims(t *testing.T) {
+	t.Parallel()
+	producer, _ := NewProducer("some-producer", "token")
+	channel, _ := NewChannel("some-channel", "token")
+	assert.Equal(t, []*QuotaClaim{
+		{SubjectType: ProducerQuota, SubjectID: "some-producer", DefaultLimit: config.PublishRateLimit{MessagesPerSecond: 1}},
+		{SubjectType: ChannelQuota, SubjectID: "some-channel", DefaultLimit: config.PublishRateLimit{BytesPerMinute: 60}},
+	}, NewPublishClaims(producer, channel, mockRateLimitConfig{}))
+}
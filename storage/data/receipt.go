@@ -0,0 +1,26 @@
+package data
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+)
+
+const (
+	// BrokerProducerID is the reserved producer the broker publishes its own messages, e.g. delivery receipts, as
+	BrokerProducerID = "$broker"
+	// ReceiptsChannelID is the reserved channel delivery receipts are published to; every receipt URL has a consumer of its own in it
+	ReceiptsChannelID = "$receipts"
+	// ReceiptDelivered is the receipt event sent when all the jobs of a message are delivered
+	ReceiptDelivered = "delivered"
+	// ReceiptDead is the receipt event sent when a job of a message is dead
+	ReceiptDead             = "dead"
+	receiptConsumerIDPrefix = "receipt-"
+	maxReceiptURLLength     = 1000
+)
+
+// GetReceiptConsumerID returns the ID of the consumer of the receipts channel delivering receipts of the producer to the receipt URL; it is
+// also the routing key of those receipts and the routing key pattern of the consumer so that receipts only reach their own URL
+func GetReceiptConsumerID(producer *Producer, receiptURL string) string {
+	hash := sha1.Sum([]byte(producer.ProducerID + " " + receiptURL))
+	return receiptConsumerIDPrefix + hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,36 @@
+package data
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetReceiptConsumerID(t *testing.T) {
+	producer, _ := NewProducer(someID, someToken)
+	anotherProducer, _ := NewProducer(someID+"-another", someToken)
+	consumerID := GetReceiptConsumerID(producer, "https://imytech.net/receipts")
+	assert.True(t, strings.HasPrefix(consumerID, receiptConsumerIDPrefix))
+	assert.True(t, IsValidRoutingKey(consumerID))
+	assert.True(t, IsValidRoutingKeyPattern(consumerID))
+	assert.Equal(t, consumerID, GetReceiptConsumerID(producer, "https://imytech.net/receipts"))
+	assert.NotEqual(t, consumerID, GetReceiptConsumerID(producer, "https://imytech.net/other-receipts"))
+	assert.NotEqual(t, consumerID, GetReceiptConsumerID(anotherProducer, "https://imytech.net/receipts"))
+}
+
+func TestMessageGetReceiptURL(t *testing.T) {
+	msg := getCompleteMessageFixture()
+	msg.ProducedBy.ReceiptURL = ""
+	assert.Equal(t, "", msg.GetReceiptURL())
+	msg.ProducedBy.ReceiptURL = "https://imytech.net/producer-receipts"
+	assert.Equal(t, "https://imytech.net/producer-receipts", msg.GetReceiptURL())
+	msg.ReceiptURL = "https://imytech.net/message-receipts"
+	assert.Equal(t, "https://imytech.net/message-receipts", msg.GetReceiptURL())
+	assert.True(t, msg.IsInValidState())
+	msg.ReceiptURL = "not a url"
+	assert.False(t, msg.IsInValidState())
+	msg.ProducedBy = nil
+	msg.ReceiptURL = ""
+	assert.Equal(t, "", msg.GetReceiptURL())
+}
@@ -0,0 +1,126 @@
+package data
+
+import (
+	"strconv"
+	"time"
+)
+
+// ReplayStatus represents the state of a replay of messages to a consumer
+type ReplayStatus int
+
+func (status ReplayStatus) String() string {
+	switch status {
+	case ReplayQueued:
+		return ReplayQueuedStr
+	case ReplayRunning:
+		return ReplayRunningStr
+	case ReplayCompleted:
+		return ReplayCompletedStr
+	default:
+		return strconv.Itoa(int(status))
+	}
+}
+
+const (
+	// ReplayQueued signifies that the replay is yet to be picked by any broker
+	ReplayQueued ReplayStatus = iota + 3000
+	// ReplayRunning signifies that the replay has started and progress is being tracked
+	ReplayRunning
+	// ReplayCompleted signifies that jobs have been created for all messages matching the replay criteria
+	ReplayCompleted
+	// ReplayQueuedStr is the string rep of ReplayQueued
+	ReplayQueuedStr = "QUEUED"
+	// ReplayRunningStr is the string rep of ReplayRunning
+	ReplayRunningStr = "RUNNING"
+	// ReplayCompletedStr is the string rep of ReplayCompleted
+	ReplayCompletedStr = "COMPLETED"
+)
+
+// Replay represents re-delivery of past messages of a channel to one of its consumers. Messages are selected either by a received at
+// time range or by message IDs (or both) and are re-delivered, in received at order, through delivery jobs created for them; the cursor
+// keeps track of the last message jobs were created for so that a replay can be resumed by any broker. Processed count is the number
+// of jobs created while delivered and failed counts are of those delivered and dead.
+type Replay struct {
+	BasePaginateable
+	Consumer         *Consumer
+	From             time.Time
+	To               time.Time
+	MessageIDs       []string
+	Status           ReplayStatus
+	TotalCount       uint
+	ProcessedCount   uint
+	DeliveredCount   uint
+	FailedCount      uint
+	CursorReceivedAt time.Time
+	CursorID         string
+	LeaseOwner       string
+	LeaseExpiresAt   time.Time
+}
+
+// QuickFix fixes the object state automatically as much as possible
+func (replay *Replay) QuickFix() bool {
+	madeChanges := replay.BasePaginateable.QuickFix()
+	if !replay.From.IsZero() && replay.To.IsZero() {
+		replay.To = time.Now()
+		madeChanges = true
+	}
+	if replay.MessageIDs == nil {
+		replay.MessageIDs = make([]string, 0)
+		madeChanges = true
+	}
+	switch replay.Status {
+	case ReplayQueued:
+	case ReplayRunning:
+	case ReplayCompleted:
+	default:
+		replay.Status = ReplayQueued
+		madeChanges = true
+	}
+	return madeChanges
+}
+
+// HasTimeRange returns whether messages are selected by received at range
+func (replay *Replay) HasTimeRange() bool {
+	return !replay.From.IsZero()
+}
+
+// IsInValidState returns false if consumer is not valid, neither time range nor message IDs are provided, time range is inverted or
+// status is not recognized
+func (replay *Replay) IsInValidState() bool {
+	if replay.Consumer == nil || !replay.Consumer.IsInValidState() {
+		return false
+	}
+	if !replay.HasTimeRange() && len(replay.MessageIDs) <= 0 {
+		return false
+	}
+	if replay.HasTimeRange() && replay.To.Before(replay.From) {
+		return false
+	}
+	for _, messageID := range replay.MessageIDs {
+		if len(messageID) <= 0 {
+			return false
+		}
+	}
+	switch replay.Status {
+	case ReplayQueued, ReplayRunning, ReplayCompleted:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsFinished returns whether the replay has created jobs for all matching messages
+func (replay *Replay) IsFinished() bool {
+	return replay.Status == ReplayCompleted
+}
+
+// NewReplay creates a new replay of messages to consumer; zero `to` defaults to current time when `from` is set
+func NewReplay(consumer *Consumer, from, to time.Time, messageIDs []string) (*Replay, error) {
+	replay := &Replay{Consumer: consumer, From: from, To: to, MessageIDs: messageIDs}
+	replay.QuickFix()
+	var err error
+	if !replay.IsInValidState() {
+		err = ErrInsufficientInformationForCreating
+	}
+	return replay, err
+}
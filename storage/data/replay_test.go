@@ -0,0 +1,63 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplayStatus(t *testing.T) {
+	assert.Equal(t, ReplayQueuedStr, ReplayQueued.String())
+	assert.Equal(t, ReplayRunningStr, ReplayRunning.String())
+	assert.Equal(t, ReplayCompletedStr, ReplayCompleted.String())
+	assert.Equal(t, "1", ReplayStatus(1).String())
+}
+
+func TestNewReplay(t *testing.T) {
+	consumer, _ := NewConsumer(sampleChannel, someID, someToken, sampleCallbackURL)
+	from := time.Now().Add(-1 * time.Hour)
+	t.Run("NilConsumer", func(t *testing.T) {
+		t.Parallel()
+		_, err := NewReplay(nil, from, time.Time{}, nil)
+		assert.Equal(t, ErrInsufficientInformationForCreating, err)
+	})
+	t.Run("NoCriteria", func(t *testing.T) {
+		t.Parallel()
+		_, err := NewReplay(consumer, time.Time{}, time.Time{}, nil)
+		assert.Equal(t, ErrInsufficientInformationForCreating, err)
+	})
+	t.Run("InvertedRange", func(t *testing.T) {
+		t.Parallel()
+		_, err := NewReplay(consumer, from, from.Add(-1*time.Second), nil)
+		assert.Equal(t, ErrInsufficientInformationForCreating, err)
+	})
+	t.Run("EmptyMessageID", func(t *testing.T) {
+		t.Parallel()
+		_, err := NewReplay(consumer, time.Time{}, time.Time{}, []string{""})
+		assert.Equal(t, ErrInsufficientInformationForCreating, err)
+	})
+	t.Run("TimeRange", func(t *testing.T) {
+		t.Parallel()
+		replay, err := NewReplay(consumer, from, time.Time{}, nil)
+		assert.Nil(t, err)
+		assert.True(t, replay.HasTimeRange())
+		assert.False(t, replay.To.IsZero())
+		assert.NotNil(t, replay.MessageIDs)
+		assert.Equal(t, ReplayQueued, replay.Status)
+		assert.False(t, replay.IsFinished())
+	})
+	t.Run("MessageIDs", func(t *testing.T) {
+		t.Parallel()
+		replay, err := NewReplay(consumer, time.Time{}, time.Time{}, []string{someID})
+		assert.Nil(t, err)
+		assert.False(t, replay.HasTimeRange())
+		assert.True(t, replay.To.IsZero())
+		replay.Status = ReplayStatus(1)
+		assert.False(t, replay.IsInValidState())
+		assert.True(t, replay.QuickFix())
+		assert.Equal(t, ReplayQueued, replay.Status)
+		replay.Status = ReplayCompleted
+		assert.True(t, replay.IsFinished())
+	})
+}
@@ -0,0 +1,83 @@
+package data
+
+import (
+	"strings"
+)
+
+const (
+	routingKeySeparator = "."
+	// RoutingKeySingleWordWildcard matches exactly one word of a routing key in a consumer's routing key pattern
+	RoutingKeySingleWordWildcard = "*"
+	// RoutingKeyMultiWordWildcard matches zero or more words of a routing key in a consumer's routing key pattern
+	RoutingKeyMultiWordWildcard = "#"
+	maxRoutingKeyLength         = 255
+)
+
+// IsValidRoutingKey returns whether the key is either empty or dot separated words consisting of letters, digits, `_` and `-`
+func IsValidRoutingKey(key string) bool {
+	return isValidRoutingKeyOrPattern(key, false)
+}
+
+// IsValidRoutingKeyPattern returns whether the pattern is either empty or dot separated words where a word can also be `*` or `#`
+func IsValidRoutingKeyPattern(pattern string) bool {
+	return isValidRoutingKeyOrPattern(pattern, true)
+}
+
+func isValidRoutingKeyOrPattern(key string, allowWildcards bool) bool {
+	if len(key) <= 0 {
+		return true
+	}
+	if len(key) > maxRoutingKeyLength {
+		return false
+	}
+	for _, word := range strings.Split(key, routingKeySeparator) {
+		if allowWildcards && (word == RoutingKeySingleWordWildcard || word == RoutingKeyMultiWordWildcard) {
+			continue
+		}
+		if len(word) <= 0 || strings.IndexFunc(word, isInvalidRoutingKeyRune) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func isInvalidRoutingKeyRune(r rune) bool {
+	return !((r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '-')
+}
+
+// MatchRoutingKey returns whether the routing key matches the pattern; an empty pattern matches all keys including the empty one, else an
+// empty key only matches `#`
+func MatchRoutingKey(pattern string, key string) bool {
+	if len(pattern) <= 0 {
+		return true
+	}
+	var keyWords []string
+	if len(key) > 0 {
+		keyWords = strings.Split(key, routingKeySeparator)
+	}
+	return matchRoutingKeyWords(strings.Split(pattern, routingKeySeparator), keyWords)
+}
+
+func matchRoutingKeyWords(patternWords []string, keyWords []string) bool {
+	for len(patternWords) > 0 {
+		switch patternWords[0] {
+		case RoutingKeyMultiWordWildcard:
+			for skip := 0; skip <= len(keyWords); skip++ {
+				if matchRoutingKeyWords(patternWords[1:], keyWords[skip:]) {
+					return true
+				}
+			}
+			return false
+		case RoutingKeySingleWordWildcard:
+			if len(keyWords) <= 0 {
+				return false
+			}
+		default:
+			if len(keyWords) <= 0 || patternWords[0] != keyWords[0] {
+				return false
+			}
+		}
+		patternWords, keyWords = patternWords[1:], keyWords[1:]
+	}
+	return len(keyWords) <= 0
+}
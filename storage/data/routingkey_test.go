@@ -0,0 +1,61 @@
+package data
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsValidRoutingKey(t *testing.T) {
+	for _, key := range []string{"", "user", "user.created.v2", "User_1.created-at"} {
+		assert.True(t, IsValidRoutingKey(key), key)
+	}
+	for _, key := range []string{".", "user.", ".user", "user..created", "user.*", "user.#", "user created", "user/created", strings.Repeat("a", 256)} {
+		assert.False(t, IsValidRoutingKey(key), key)
+	}
+}
+
+func TestIsValidRoutingKeyPattern(t *testing.T) {
+	for _, pattern := range []string{"", "user", "user.created.v2", "user.*", "user.#", "#", "*", "*.created.#", "#.v2"} {
+		assert.True(t, IsValidRoutingKeyPattern(pattern), pattern)
+	}
+	for _, pattern := range []string{".", "user.", "user..#", "user.*s", "user.#a", "user.**", "user created"} {
+		assert.False(t, IsValidRoutingKeyPattern(pattern), pattern)
+	}
+}
+
+func TestMatchRoutingKey(t *testing.T) {
+	matches := map[string][]string{
+		"":              {"", "user", "user.created.v2"},
+		"#":             {"", "user", "user.created.v2"},
+		"user":          {"user"},
+		"user.*":        {"user.created", "user.deleted"},
+		"user.#":        {"user", "user.created", "user.created.v2"},
+		"*.created.#":   {"user.created", "order.created.v2"},
+		"#.v2":          {"v2", "user.v2", "user.created.v2"},
+		"user.#.v2":     {"user.v2", "user.created.v2", "user.created.by.admin.v2"},
+		"*.*":           {"user.created"},
+		"user.created":  {"user.created"},
+		"#.created.#.*": {"created.v2", "user.created.by.v2"},
+	}
+	nonMatches := map[string][]string{
+		"user":          {"", "users", "user.created"},
+		"user.*":        {"", "user", "user.created.v2", "order.created"},
+		"user.#":        {"", "users.created", "order.user"},
+		"*.created.#":   {"created", "user.deleted"},
+		"#.v2":          {"", "user.v1", "v2.user"},
+		"*.*":           {"user", "user.created.v2"},
+		"#.created.#.*": {"created", "user.created"},
+	}
+	for pattern, keys := range matches {
+		for _, key := range keys {
+			assert.True(t, MatchRoutingKey(pattern, key), pattern+" should match "+key)
+		}
+	}
+	for pattern, keys := range nonMatches {
+		for _, key := range keys {
+			assert.False(t, MatchRoutingKey(pattern, key), pattern+" should not match "+key)
+		}
+	}
+}
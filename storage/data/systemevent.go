@@ -0,0 +1,18 @@
+package data
+
+const (
+	// SystemChannelID is the reserved channel the broker publishes its lifecycle events to; the event type is the routing key of the message
+	SystemChannelID = "$system"
+	// SystemEventJobDead is published when a delivery job is marked dead
+	SystemEventJobDead = "job.dead"
+	// SystemEventMessageUndispatchable is published when a message could not be dispatched by the recovery worker
+	SystemEventMessageUndispatchable = "message.undispatchable"
+	// SystemEventConsumerUpdated is published when a consumer is created or updated
+	SystemEventConsumerUpdated = "consumer.updated"
+	// SystemEventConsumerDeleted is published when a consumer is deleted
+	SystemEventConsumerDeleted = "consumer.deleted"
+	// SystemEventChannelUpdated is published when a channel is created or updated, including its token being rotated
+	SystemEventChannelUpdated = "channel.updated"
+	// SystemEventChannelDeleted is published when a channel is deleted along with its consumers
+	SystemEventChannelDeleted = "channel.deleted"
+)
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"io"
 	"time"
 
 	"github.com/newscred/webhook-broker/config"
@@ -17,6 +18,10 @@ type DataAccessor interface {
 	GetMessageRepository() MessageRepository
 	GetDeliveryJobRepository() DeliveryJobRepository
 	GetLockRepository() LockRepository
+	GetReplayRepository() ReplayRepository
+	GetSchemaRepository() SchemaRepository
+	GetPublishQuotaRepository() PublishQuotaRepository
+	GetPublishGrantRepository() PublishGrantRepository
 	Close()
 }
 
@@ -31,33 +36,46 @@ type AppRepository interface {
 type ProducerRepository interface {
 	Store(producer *data.Producer) (*data.Producer, error)
 	Get(producerID string) (*data.Producer, error)
+	GetIncludingDeleted(producerID string) (*data.Producer, error)
 	GetList(page *data.Pagination) ([]*data.Producer, *data.Pagination, error)
+	Delete(producer *data.Producer, drain bool) error
+	Purge(producer *data.Producer) ([]string, error)
 }
 
 // ChannelRepository allows storage operation interaction for Channel
 type ChannelRepository interface {
 	Store(channel *data.Channel) (*data.Channel, error)
 	Get(channelID string) (*data.Channel, error)
+	GetIncludingDeleted(channelID string) (*data.Channel, error)
 	GetList(page *data.Pagination) ([]*data.Channel, *data.Pagination, error)
+	Delete(channel *data.Channel, drain bool) error
+	Purge(channel *data.Channel) ([]string, error)
 }
 
 // ConsumerRepository allows storage operation interaction for Consumer
 type ConsumerRepository interface {
 	Store(consumer *data.Consumer) (*data.Consumer, error)
-	Delete(consumer *data.Consumer) error
+	Delete(consumer *data.Consumer, drain bool) error
+	Purge(consumer *data.Consumer) error
 	Get(channelID string, consumerID string) (*data.Consumer, error)
+	GetIncludingDeleted(channelID string, consumerID string) (*data.Consumer, error)
 	GetList(channelID string, page *data.Pagination) ([]*data.Consumer, *data.Pagination, error)
 	GetByID(id string) (*data.Consumer, error)
+	MarkVerified(consumer *data.Consumer, challenge string) error
+	SetPaused(consumer *data.Consumer, paused bool) error
 }
 
 // MessageRepository allows storage operations over Message. SetDispatched does not accept TX directly to keep the API storage class independent
 type MessageRepository interface {
 	Create(message *data.Message) error
+	CreateBatch(messages []*data.Message, admit func(message *data.Message) error) []error
 	Get(channelID string, messageID string) (*data.Message, error)
 	GetByID(id string) (*data.Message, error)
 	SetDispatched(txContext context.Context, message *data.Message) error
 	GetMessagesNotDispatchedForCertainPeriod(delta time.Duration) []*data.Message
 	GetMessagesForChannel(channelID string, page *data.Pagination) ([]*data.Message, *data.Pagination, error)
+	SearchMessages(filter *data.MessageFilter, page *data.Pagination) ([]*data.Message, *data.Pagination, error)
+	RecompressPayloads(owner string, afterID string, limit int) (string, error)
 }
 
 // DeliveryJobRepository allows storage operations over DeliveryJob
@@ -68,11 +86,25 @@ type DeliveryJobRepository interface {
 	MarkJobDead(deliveryJob *data.DeliveryJob) error
 	MarkJobRetry(deliveryJob *data.DeliveryJob, earliestDelta time.Duration) error
 	RequeueDeadJobsForConsumer(consumer *data.Consumer) error
+	GetDeadJobs(filter *data.DeadJobFilter, page *data.Pagination) ([]*data.DeliveryJob, *data.Pagination, error)
+	RequeueDeadJobs(filter *data.DeadJobFilter) error
+	DiscardDeadJobs(filter *data.DeadJobFilter) error
 	GetJobsForMessage(message *data.Message, page *data.Pagination) ([]*data.DeliveryJob, *data.Pagination, error)
+	GetJobsForMessages(messages []*data.Message) ([]*data.DeliveryJob, error)
+	GetUndeliveredJobCount(message *data.Message) (uint, error)
 	GetJobsForConsumer(consumer *data.Consumer, jobStatus data.JobStatus, page *data.Pagination) ([]*data.DeliveryJob, *data.Pagination, error)
 	GetByID(id string) (*data.DeliveryJob, error)
 	GetJobsInflightSince(delta time.Duration) []*data.DeliveryJob
 	GetJobsReadyForInflightSince(delta time.Duration) []*data.DeliveryJob
+	GetJobsReadyForConsumer(consumer *data.Consumer, limit int) ([]*data.DeliveryJob, error)
+	RescheduleQueuedJobsForConsumer(consumer *data.Consumer, start time.Time, interval time.Duration) error
+	CreateBackfill(backfill *data.Backfill) error
+	GetBackfillsToRun() []*data.Backfill
+	LeaseBackfill(backfill *data.Backfill, owner string, duration time.Duration) error
+	BackfillJobsForConsumer(backfill *data.Backfill, start time.Time, interval time.Duration, leaseDuration time.Duration) error
+	GetBackfillForConsumer(consumer *data.Consumer) (*data.Backfill, error)
+	GetJobStatsForConsumer(consumer *data.Consumer) (*data.JobStats, error)
+	GetJobStatsForChannel(channel *data.Channel) (*data.JobStats, error)
 }
 
 // LockRepository allows storage operations over Lock
@@ -81,3 +113,44 @@ type LockRepository interface {
 	ReleaseLock(lock *data.Lock) error
 	TimeoutLocks(threshold time.Duration) error
 }
+
+// SchemaRepository allows storage operations over ChannelSchema
+type SchemaRepository interface {
+	Create(schema *data.ChannelSchema) error
+	Get(channel *data.Channel, version uint) (*data.ChannelSchema, error)
+	GetLatest(channel *data.Channel) (*data.ChannelSchema, error)
+	GetList(channel *data.Channel) ([]*data.ChannelSchema, error)
+}
+
+// PublishQuotaRepository allows storage operations over PublishQuota
+type PublishQuotaRepository interface {
+	Get(subjectType data.QuotaSubjectType, subjectID string) (*data.PublishQuota, error)
+	SetLimit(subjectType data.QuotaSubjectType, subjectID string, limit config.PublishRateLimit) (*data.PublishQuota, error)
+	TakeTokens(size uint, claims ...*data.QuotaClaim) (time.Duration, error)
+	ReturnTokens(size uint, claims ...*data.QuotaClaim) error
+}
+
+// PublishGrantRepository allows storage operations over PublishGrant
+type PublishGrantRepository interface {
+	Grant(channel *data.Channel, producer *data.Producer) (*data.PublishGrant, error)
+	Get(channel *data.Channel, producer *data.Producer) (*data.PublishGrant, error)
+	Revoke(grant *data.PublishGrant) error
+}
+
+// ReplayRepository allows storage operations over Replay
+type ReplayRepository interface {
+	Create(replay *data.Replay) error
+	Get(consumer *data.Consumer, id string) (*data.Replay, error)
+	GetReplaysToRun() []*data.Replay
+	Lease(replay *data.Replay, owner string, duration time.Duration) error
+	UpdateProgress(replay *data.Replay, leaseDuration time.Duration) error
+	CreateJobs(replay *data.Replay, messages []*data.Message, start time.Time, interval time.Duration, leaseDuration time.Duration) (uint, error)
+	GetNextMessages(replay *data.Replay, limit int) ([]*data.Message, error)
+}
+
+// BlobStore stores message payloads too large to be kept in the database; only the reference returned by Put is kept with the message
+type BlobStore interface {
+	Put(content io.Reader) (string, error)
+	Get(ref string) (io.ReadCloser, error)
+	Delete(ref string) error
+}
@@ -9,6 +9,8 @@ func normalizeDBError(mysqlDriverErr error, mappedError map[uint16]error) (err e
 	err = mysqlDriverErr
 	if mysqlErr, ok := err.(*mysql.MySQLError); ok {
 		err = lookup(mysqlErr.Number, mappedError, mysqlErr)
+	} else if sqliteErr, ok := err.(sqlite.Error); ok && sqliteErr.ExtendedCode == sqlite.ErrConstraintUnique {
+		err = lookup(1062, mappedError, sqliteErr)
 	} else if sqliteErr, ok := err.(*sqlite.ErrNoExtended); ok {
 		switch *sqliteErr {
 		case sqlite.ErrConstraintUnique:
@@ -4,16 +4,24 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/rs/xid"
 	"github.com/rs/zerolog/log"
 
 	"github.com/newscred/webhook-broker/storage/data"
 )
 
 const (
-	jobPropertyCount     = 9
-	jobCommonSelectQuery = "SELECT id, messageId, consumerId, status, dispatchReceivedAt, retryAttemptCount, statusChangedAt, earliestNextAttemptAt, createdAt, updatedAt FROM job WHERE"
+	jobPropertyCount            = 9
+	jobCommonSelectQuery        = "SELECT id, messageId, consumerId, status, dispatchReceivedAt, retryAttemptCount, statusChangedAt, earliestNextAttemptAt, createdAt, updatedAt, failureReason FROM job WHERE"
+	jobOfActiveConsumerFragment = " AND consumerId NOT IN (SELECT id FROM consumer WHERE paused = ? OR verificationStatus = ? OR consumerType = ?)"
+	rescheduleBatchSize         = 100
+	backfillJobStatusCountQuery = "SELECT status, COUNT(*) FROM job WHERE backfillId like ? GROUP BY status"
+	backfillSelectQuery         = "SELECT id, consumerId, receivedFrom, receivedTo, jobCount, jobsCreated, leaseOwner, leaseExpiresAt, createdAt, updatedAt FROM backfill WHERE"
+	jobStatsSampleSize          = 1000
+	jobStatsSampleWindow        = time.Hour
 )
 
 // DeliveryJobDBRepository is the DeliveryJobRepository's RDBMS implementation
@@ -77,16 +85,23 @@ func (djRepo *DeliveryJobDBRepository) MarkJobDelivered(deliveryJob *data.Delive
 	return djRepo.updateJobStatus(deliveryJob, data.JobInflight, data.JobDelivered)
 }
 
-// MarkJobDead sets the status of the job to Dead if the job's current status is Inflight in the object and DB; else returns error
-func (djRepo *DeliveryJobDBRepository) MarkJobDead(deliveryJob *data.DeliveryJob) error {
-	return djRepo.updateJobStatus(deliveryJob, data.JobInflight, data.JobDead)
+// MarkJobDead sets the status of the job to Dead along with its failure reason if the job's current status is Inflight in the object and DB; else returns error
+func (djRepo *DeliveryJobDBRepository) MarkJobDead(deliveryJob *data.DeliveryJob) (err error) {
+	currentTime := time.Now()
+	err = transactionalSingleRowWriteExec(djRepo.db, emptyOps, "UPDATE job SET status = ?, statusChangedAt = ?, updatedAt = ?, failureReason = ? WHERE id like ? and status = ?", args2SliceFnWrapper(data.JobDead, currentTime, currentTime, deliveryJob.FailureReason, deliveryJob.ID, data.JobInflight))
+	if err == nil {
+		deliveryJob.Status = data.JobDead
+		deliveryJob.StatusChangedAt = currentTime
+		deliveryJob.UpdatedAt = currentTime
+	}
+	return err
 }
 
-// MarkJobRetry increases the retry attempt count and sets the status of the job to Queued if the job's current status is Inflight in the object and DB; else returns error
+// MarkJobRetry increases the retry attempt count, records the failure reason and sets the status of the job to Queued if the job's current status is Inflight in the object and DB; else returns error
 func (djRepo *DeliveryJobDBRepository) MarkJobRetry(deliveryJob *data.DeliveryJob, earliestDelta time.Duration) (err error) {
 	currentTime := time.Now()
 	nextTime := currentTime.Add(earliestDelta)
-	err = transactionalSingleRowWriteExec(djRepo.db, emptyOps, "UPDATE job SET status = ?, statusChangedAt = ?, updatedAt = ?, earliestNextAttemptAt = ?, retryAttemptCount = ? WHERE id like ? and status = ?", args2SliceFnWrapper(data.JobQueued, currentTime, currentTime, nextTime, deliveryJob.RetryAttemptCount+1, deliveryJob.ID, data.JobInflight))
+	err = transactionalSingleRowWriteExec(djRepo.db, emptyOps, "UPDATE job SET status = ?, statusChangedAt = ?, updatedAt = ?, earliestNextAttemptAt = ?, retryAttemptCount = ?, failureReason = ? WHERE id like ? and status = ?", args2SliceFnWrapper(data.JobQueued, currentTime, currentTime, nextTime, deliveryJob.RetryAttemptCount+1, deliveryJob.FailureReason, deliveryJob.ID, data.JobInflight))
 	if err == nil {
 		deliveryJob.Status = data.JobQueued
 		deliveryJob.StatusChangedAt = currentTime
@@ -105,7 +120,7 @@ func (djRepo *DeliveryJobDBRepository) getJobs(baseQuery string, message *data.M
 		job.Message = &data.Message{}
 		job.Listener = &data.Consumer{}
 		jobs = append(jobs, job)
-		return []interface{}{&job.ID, &job.Message.ID, &job.Listener.ID, &job.Status, &job.DispatchReceivedAt, &job.RetryAttemptCount, &job.StatusChangedAt, &job.EarliestNextAttemptAt, &job.CreatedAt, &job.UpdatedAt}
+		return []interface{}{&job.ID, &job.Message.ID, &job.Listener.ID, &job.Status, &job.DispatchReceivedAt, &job.RetryAttemptCount, &job.StatusChangedAt, &job.EarliestNextAttemptAt, &job.CreatedAt, &job.UpdatedAt, &job.FailureReason}
 	}
 	err = queryRows(djRepo.db, baseQuery, args2SliceFnWrapper(args...), scanArgs)
 	if err == nil {
@@ -131,7 +146,7 @@ func (djRepo *DeliveryJobDBRepository) getJobs(baseQuery string, message *data.M
 	return jobs, pagination, err
 }
 
-func (djRepo *DeliveryJobDBRepository) getJobsForStatusAndDelta(status data.JobStatus, delta time.Duration, useStatusChangedAt bool) []*data.DeliveryJob {
+func (djRepo *DeliveryJobDBRepository) getJobsForStatusAndDelta(status data.JobStatus, delta time.Duration, useStatusChangedAt bool, skipPausedConsumers bool) []*data.DeliveryJob {
 	jobs := make([]*data.DeliveryJob, 0)
 	page := data.NewPagination(nil, nil)
 	if delta > 0 {
@@ -142,9 +157,17 @@ func (djRepo *DeliveryJobDBRepository) getJobsForStatusAndDelta(status data.JobS
 	if useStatusChangedAt {
 		dateCol = "statusChangedAt"
 	}
+	condition := " status = ? AND " + dateCol + " <= ?"
+	if skipPausedConsumers {
+		condition = condition + jobOfActiveConsumerFragment
+	}
 	for more {
-		baseQuery := jobCommonSelectQuery + " status = ? AND " + dateCol + " <= ?" + getPaginationQueryFragmentWithConfigurablePageSize(page, true, largePageSizeWithOrder)
-		pageJobs, pagination, err := djRepo.getJobs(baseQuery, nil, nil, appendWithPaginationArgs(page, status, time.Now().Add(delta)))
+		baseQuery := jobCommonSelectQuery + condition + getPaginationQueryFragmentWithConfigurablePageSize(page, true, largePageSizeWithOrder)
+		args := []interface{}{status, time.Now().Add(delta)}
+		if skipPausedConsumers {
+			args = append(args, true, data.ConsumerPendingVerification, data.StreamConsumer)
+		}
+		pageJobs, pagination, err := djRepo.getJobs(baseQuery, nil, nil, appendWithPaginationArgs(page, args...))
 		if err == nil {
 			jobs = append(jobs, pageJobs...)
 			jobCount := len(pageJobs)
@@ -174,6 +197,53 @@ func (djRepo *DeliveryJobDBRepository) GetJobsForMessage(message *data.Message,
 	return djRepo.getJobs(baseQuery, message, nil, appendWithPaginationArgs(page, message.ID.String()))
 }
 
+// GetJobsForMessages retrieves all the jobs created for the messages in a single query, e.g. for a page of messages; consumers are loaded
+// once irrespective of the number of their jobs
+func (djRepo *DeliveryJobDBRepository) GetJobsForMessages(messages []*data.Message) ([]*data.DeliveryJob, error) {
+	jobs := make([]*data.DeliveryJob, 0)
+	if len(messages) <= 0 {
+		return jobs, nil
+	}
+	messagesByID := make(map[string]*data.Message, len(messages))
+	args := make([]interface{}, 0, len(messages))
+	for _, message := range messages {
+		messagesByID[message.ID.String()] = message
+		args = append(args, message.ID.String())
+	}
+	baseQuery := jobCommonSelectQuery + " messageId IN (?" + strings.Repeat(", ?", len(messages)-1) + ") ORDER BY createdAt, id"
+	scanArgs := func() []interface{} {
+		job := &data.DeliveryJob{}
+		job.Message = &data.Message{}
+		job.Listener = &data.Consumer{}
+		jobs = append(jobs, job)
+		return []interface{}{&job.ID, &job.Message.ID, &job.Listener.ID, &job.Status, &job.DispatchReceivedAt, &job.RetryAttemptCount, &job.StatusChangedAt, &job.EarliestNextAttemptAt, &job.CreatedAt, &job.UpdatedAt, &job.FailureReason}
+	}
+	err := queryRows(djRepo.db, baseQuery, args2SliceFnWrapper(args...), scanArgs)
+	if err != nil {
+		return jobs, err
+	}
+	consumers := make(map[string]*data.Consumer)
+	for _, job := range jobs {
+		consumerID := job.Listener.ID.String()
+		consumer, ok := consumers[consumerID]
+		if !ok {
+			if consumer, err = djRepo.consumerRepository.GetByID(consumerID); err != nil {
+				return jobs, err
+			}
+			consumers[consumerID] = consumer
+		}
+		job.Listener = consumer
+		job.Message = messagesByID[job.Message.ID.String()]
+	}
+	return jobs, nil
+}
+
+// GetUndeliveredJobCount retrieves the number of jobs of the message not delivered yet
+func (djRepo *DeliveryJobDBRepository) GetUndeliveredJobCount(message *data.Message) (count uint, err error) {
+	err = querySingleRow(djRepo.db, "SELECT COUNT(*) FROM job WHERE messageId like ? AND status != ?", args2SliceFnWrapper(message.ID.String(), data.JobDelivered), args2SliceFnWrapper(&count))
+	return count, err
+}
+
 // RequeueDeadJobsForConsumer queues up dead jobs for a specific consumer
 func (djRepo *DeliveryJobDBRepository) RequeueDeadJobsForConsumer(consumer *data.Consumer) (err error) {
 	currentTime := time.Now()
@@ -183,6 +253,80 @@ func (djRepo *DeliveryJobDBRepository) RequeueDeadJobsForConsumer(consumer *data
 	return err
 }
 
+// getDeadJobCriteria returns the where clause and its args to select dead jobs matching the filter
+func getDeadJobCriteria(filter *data.DeadJobFilter) (string, []interface{}) {
+	query := " status = ?"
+	args := []interface{}{data.JobDead}
+	if filter.Consumer != nil {
+		query = query + " AND consumerId like ?"
+		args = append(args, filter.Consumer.ID.String())
+	} else {
+		query = query + " AND consumerId IN (SELECT id FROM consumer WHERE channelId like ?)"
+		args = append(args, filter.Channel.ChannelID)
+	}
+	if len(filter.JobIDs) > 0 {
+		query = query + " AND id IN (?" + strings.Repeat(", ?", len(filter.JobIDs)-1) + ")"
+		for _, jobID := range filter.JobIDs {
+			args = append(args, jobID)
+		}
+	}
+	if len(filter.MessageIDs) > 0 {
+		query = query + " AND messageId IN (SELECT id FROM message WHERE channelId like ? AND messageId IN (?" + strings.Repeat(", ?", len(filter.MessageIDs)-1) + "))"
+		args = append(args, filter.Channel.ChannelID)
+		for _, messageID := range filter.MessageIDs {
+			args = append(args, messageID)
+		}
+	}
+	if !filter.DeadSince.IsZero() {
+		query = query + " AND statusChangedAt >= ?"
+		args = append(args, filter.DeadSince)
+	}
+	if !filter.DeadUntil.IsZero() {
+		query = query + " AND statusChangedAt <= ?"
+		args = append(args, filter.DeadUntil)
+	}
+	if len(filter.FailureReason) > 0 {
+		query = query + " AND failureReason like ?"
+		args = append(args, "%"+filter.FailureReason+"%")
+	}
+	return query, args
+}
+
+// GetDeadJobs retrieves dead jobs of a channel, or of one of its consumers, matching the filter
+func (djRepo *DeliveryJobDBRepository) GetDeadJobs(filter *data.DeadJobFilter, page *data.Pagination) ([]*data.DeliveryJob, *data.Pagination, error) {
+	if page == nil || (page.Next != nil && page.Previous != nil) {
+		return getDefaultErrorResponseForJobs()
+	}
+	if filter == nil || !filter.IsInValidState() {
+		return make([]*data.DeliveryJob, 0), &data.Pagination{}, ErrInvalidStateToSave
+	}
+	criteria, args := getDeadJobCriteria(filter)
+	baseQuery := jobCommonSelectQuery + criteria + getPaginationQueryFragmentWithConfigurablePageSize(page, true, pageSizeWithOrder)
+	return djRepo.getJobs(baseQuery, nil, filter.Consumer, appendWithPaginationArgs(page, args...))
+}
+
+func (djRepo *DeliveryJobDBRepository) updateDeadJobs(filter *data.DeadJobFilter, to data.JobStatus) (err error) {
+	if filter == nil || !filter.IsInValidState() {
+		return ErrInvalidStateToSave
+	}
+	currentTime := time.Now()
+	criteria, args := getDeadJobCriteria(filter)
+	err = transactionalWrites(djRepo.db, func(tx *sql.Tx) error {
+		return inTransactionExec(tx, emptyOps, "UPDATE job SET status = ?, statusChangedAt = ?, updatedAt = ? WHERE"+criteria, args2SliceFnWrapper(append([]interface{}{to, currentTime, currentTime}, args...)...), 0)
+	})
+	return err
+}
+
+// RequeueDeadJobs queues up dead jobs matching the filter for another delivery attempt
+func (djRepo *DeliveryJobDBRepository) RequeueDeadJobs(filter *data.DeadJobFilter) error {
+	return djRepo.updateDeadJobs(filter, data.JobQueued)
+}
+
+// DiscardDeadJobs moves dead jobs matching the filter to the terminal Discarded status so that they no longer show up in DLQ
+func (djRepo *DeliveryJobDBRepository) DiscardDeadJobs(filter *data.DeadJobFilter) error {
+	return djRepo.updateDeadJobs(filter, data.JobDiscarded)
+}
+
 // GetJobsForConsumer retrieves DeliveryJob created for delivery to a customer and it has to be filtered by a specific status
 func (djRepo *DeliveryJobDBRepository) GetJobsForConsumer(consumer *data.Consumer, jobStatus data.JobStatus, page *data.Pagination) ([]*data.DeliveryJob, *data.Pagination, error) {
 	if page == nil || (page.Next != nil && page.Previous != nil) {
@@ -194,12 +338,299 @@ func (djRepo *DeliveryJobDBRepository) GetJobsForConsumer(consumer *data.Consume
 
 // GetJobsInflightSince retrieves jobs in inflight status since the delta duration
 func (djRepo *DeliveryJobDBRepository) GetJobsInflightSince(delta time.Duration) []*data.DeliveryJob {
-	return djRepo.getJobsForStatusAndDelta(data.JobInflight, delta, true)
+	return djRepo.getJobsForStatusAndDelta(data.JobInflight, delta, true, false)
 }
 
-// GetJobsReadyForInflightSince retrieves jobs in queued status and earliestNextAttemptAt < `now`-delta
+// GetJobsReadyForInflightSince retrieves jobs in queued status and earliestNextAttemptAt < `now`-delta; jobs of paused consumers, of
+// consumers pending verification and of streaming consumers are skipped
 func (djRepo *DeliveryJobDBRepository) GetJobsReadyForInflightSince(delta time.Duration) []*data.DeliveryJob {
-	return djRepo.getJobsForStatusAndDelta(data.JobQueued, delta, false)
+	return djRepo.getJobsForStatusAndDelta(data.JobQueued, delta, false, true)
+}
+
+// GetJobsReadyForConsumer retrieves up to limit jobs of the consumer in queued status whose earliest next attempt is due, earliest first
+func (djRepo *DeliveryJobDBRepository) GetJobsReadyForConsumer(consumer *data.Consumer, limit int) ([]*data.DeliveryJob, error) {
+	baseQuery := jobCommonSelectQuery + " consumerId like ? AND status = ? AND earliestNextAttemptAt <= ? ORDER BY earliestNextAttemptAt, id LIMIT ?"
+	jobs, _, err := djRepo.getJobs(baseQuery, nil, consumer, []interface{}{consumer.ID.String(), data.JobQueued, time.Now(), limit})
+	return jobs, err
+}
+
+// RescheduleQueuedJobsForConsumer spreads the earliest next attempt of the consumer's queued jobs, oldest first, starting from `start` and
+// `interval` apart; retry attempt count of the jobs remain untouched
+func (djRepo *DeliveryJobDBRepository) RescheduleQueuedJobsForConsumer(consumer *data.Consumer, start time.Time, interval time.Duration) (err error) {
+	jobIDs := make([]string, 0)
+	err = queryRows(djRepo.db, "SELECT id FROM job WHERE consumerId like ? AND status = ? ORDER BY createdAt, id", args2SliceFnWrapper(consumer.ID.String(), data.JobQueued), func() []interface{} {
+		jobIDs = append(jobIDs, "")
+		return []interface{}{&jobIDs[len(jobIDs)-1]}
+	})
+	for batchStart := 0; err == nil && batchStart < len(jobIDs); batchStart += rescheduleBatchSize {
+		batchEnd := batchStart + rescheduleBatchSize
+		if batchEnd > len(jobIDs) {
+			batchEnd = len(jobIDs)
+		}
+		currentTime := time.Now()
+		txs := make([]func(tx *sql.Tx) error, 0, batchEnd-batchStart)
+		for index := batchStart; index < batchEnd; index++ {
+			nextTime := start.Add(time.Duration(index) * interval)
+			jobID := jobIDs[index]
+			txs = append(txs, func(tx *sql.Tx) error {
+				return inTransactionExec(tx, emptyOps, "UPDATE job SET earliestNextAttemptAt = ?, updatedAt = ? WHERE id like ? and status = ?", args2SliceFnWrapper(nextTime, currentTime, jobID, data.JobQueued), 0)
+			})
+		}
+		err = transactionalWrites(djRepo.db, txs...)
+	}
+	return err
+}
+
+// CreateBackfill records the backfill of the consumer; its jobs are created afterwards by BackfillJobsForConsumer under a lease on it
+func (djRepo *DeliveryJobDBRepository) CreateBackfill(backfill *data.Backfill) error {
+	backfill.QuickFix()
+	if !backfill.IsInValidState() {
+		return ErrInvalidStateToSave
+	}
+	return transactionalSingleRowWriteExec(djRepo.db, emptyOps, "INSERT INTO backfill (id, consumerId, receivedFrom, receivedTo, jobCount, jobsCreated, createdAt, updatedAt) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		args2SliceFnWrapper(backfill.ID, backfill.Consumer.ID, backfill.Since, backfill.Until, backfill.JobCount, backfill.JobsCreated, backfill.CreatedAt, backfill.UpdatedAt))
+}
+
+// GetBackfillsToRun retrieves the backfills whose jobs are yet to be all created and that no broker currently holds a lease for
+func (djRepo *DeliveryJobDBRepository) GetBackfillsToRun() []*data.Backfill {
+	backfills := make([]*data.Backfill, 0)
+	consumerIDs := make([]string, 0)
+	err := queryRows(djRepo.db, backfillSelectQuery+" jobsCreated = ? AND (leaseExpiresAt IS NULL OR leaseExpiresAt < ?) ORDER BY createdAt LIMIT 100",
+		args2SliceFnWrapper(false, time.Now()), func() []interface{} {
+			backfill := &data.Backfill{}
+			backfills = append(backfills, backfill)
+			consumerIDs = append(consumerIDs, "")
+			return []interface{}{&backfill.ID, &consumerIDs[len(consumerIDs)-1], &backfill.Since, &backfill.Until, &backfill.JobCount, &backfill.JobsCreated,
+				&backfill.LeaseOwner, nullTimeScanner{&backfill.LeaseExpiresAt}, &backfill.CreatedAt, &backfill.UpdatedAt}
+		})
+	if err != nil {
+		log.Error().Err(err).Msg("error - could not list backfills to run")
+		return make([]*data.Backfill, 0)
+	}
+	toRun := make([]*data.Backfill, 0, len(backfills))
+	for index, backfill := range backfills {
+		if backfill.Consumer, err = djRepo.consumerRepository.GetByID(consumerIDs[index]); err != nil {
+			log.Error().Err(err).Msg("error - could not load consumer of backfill " + backfill.ID.String())
+			continue
+		}
+		toRun = append(toRun, backfill)
+	}
+	return toRun
+}
+
+// LeaseBackfill marks the backfill as being run by owner for the duration provided no other owner holds an unexpired lease and its jobs are
+// yet to be all created; returns ErrNoRowsUpdated otherwise
+func (djRepo *DeliveryJobDBRepository) LeaseBackfill(backfill *data.Backfill, owner string, duration time.Duration) error {
+	currentTime := time.Now()
+	leaseExpiresAt := currentTime.Add(duration)
+	err := transactionalSingleRowWriteExec(djRepo.db, emptyOps, "UPDATE backfill SET leaseOwner = ?, leaseExpiresAt = ?, updatedAt = ? WHERE id like ? AND jobsCreated = ? AND (leaseExpiresAt IS NULL OR leaseExpiresAt < ?)",
+		args2SliceFnWrapper(owner, leaseExpiresAt, currentTime, backfill.ID, false, currentTime))
+	if err == nil {
+		backfill.LeaseOwner = owner
+		backfill.LeaseExpiresAt = leaseExpiresAt
+		backfill.UpdatedAt = currentTime
+	}
+	return err
+}
+
+// BackfillJobsForConsumer creates queued jobs of the leased backfill, oldest message first, for the messages of the consumer's channel
+// dispatched within the backfill window that the consumer does not have a job for already; earliest next attempt of the jobs are spread
+// starting from `start` and `interval` apart, counting the jobs the backfill created before. Jobs are created a batch of messages per
+// transaction along with the backfill's job count and the extension of its lease, so that no transaction grows with the window and another
+// broker can pick up where this one left should the lease expire. The backfill is marked once all its jobs are created; no more jobs are
+// created for a deleted consumer. Returns ErrNoRowsUpdated if the lease has been taken over by another owner.
+func (djRepo *DeliveryJobDBRepository) BackfillJobsForConsumer(backfill *data.Backfill, start time.Time, interval time.Duration, leaseDuration time.Duration) (err error) {
+	if !backfill.IsInValidState() {
+		return ErrInvalidStateToSave
+	}
+	consumer := backfill.Consumer
+	var cursor *data.Message
+	for !consumer.IsDeleted() {
+		criteria := " channelId like ? AND status = ? AND receivedAt >= ? AND receivedAt <= ? AND id NOT IN (SELECT messageId FROM job WHERE consumerId like ?)"
+		args := []interface{}{consumer.GetChannelIDSafely(), data.MsgStatusDispatched, backfill.Since, backfill.Until, consumer.ID.String()}
+		if cursor != nil {
+			criteria = criteria + " AND (receivedAt > ? OR (receivedAt = ? AND id > ?))"
+			args = append(args, cursor.ReceivedAt, cursor.ReceivedAt, cursor.ID.String())
+		}
+		messages := make([]*data.Message, 0, rescheduleBatchSize)
+		err = queryRows(djRepo.db, "SELECT id, routingKey, receivedAt FROM message WHERE"+criteria+" ORDER BY receivedAt, id LIMIT ?", args2SliceFnWrapper(append(args, rescheduleBatchSize)...), func() []interface{} {
+			message := &data.Message{}
+			messages = append(messages, message)
+			return []interface{}{&message.ID, &message.RoutingKey, &message.ReceivedAt}
+		})
+		if err != nil || len(messages) <= 0 {
+			break
+		}
+		cursor = messages[len(messages)-1]
+		if err = djRepo.createBackfillJobs(backfill, messages, start, interval, leaseDuration); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		currentTime := time.Now()
+		err = transactionalSingleRowWriteExec(djRepo.db, emptyOps, "UPDATE backfill SET jobsCreated = ?, updatedAt = ? WHERE id like ? AND leaseOwner = ?", args2SliceFnWrapper(true, currentTime, backfill.ID, backfill.LeaseOwner))
+		if err == nil {
+			backfill.JobsCreated = true
+			backfill.UpdatedAt = currentTime
+		}
+	}
+	return err
+}
+
+// createBackfillJobs creates, in a single transaction, the backfill's jobs for the messages the consumer is subscribed to, adds them
+// to the backfill's job count and extends its lease
+func (djRepo *DeliveryJobDBRepository) createBackfillJobs(backfill *data.Backfill, messages []*data.Message, start time.Time, interval time.Duration, leaseDuration time.Duration) error {
+	consumer := backfill.Consumer
+	query := "INSERT INTO job (id, messageId, consumerId, backfillId, dispatchReceivedAt, statusChangedAt, earliestNextAttemptAt, status, createdAt, updatedAt) VALUES"
+	args := make([]interface{}, 0, len(messages)*(jobPropertyCount+1))
+	currentTime := time.Now()
+	index := backfill.JobCount
+	for _, message := range messages {
+		if !consumer.IsSubscribedTo(message) {
+			continue
+		}
+		args = append(args, xid.New(), message.ID, consumer.ID, backfill.ID, currentTime, currentTime, start.Add(time.Duration(index)*interval), data.JobQueued, currentTime, currentTime)
+		query = query + " (?, ?, ?, ?, ?, ?, ?, ?, ?, ?),"
+		index++
+	}
+	jobCount := index - backfill.JobCount
+	leaseExpiresAt := currentTime.Add(leaseDuration)
+	ops := make([]func(tx *sql.Tx) error, 0, 2)
+	if jobCount > 0 {
+		query = query[:len(query)-1]
+		ops = append(ops, func(tx *sql.Tx) error {
+			return inTransactionExec(tx, emptyOps, query, args2SliceFnWrapper(args...), int64(jobCount))
+		})
+	}
+	ops = append(ops, func(tx *sql.Tx) error {
+		return inTransactionExec(tx, emptyOps, "UPDATE backfill SET jobCount = jobCount + ?, leaseExpiresAt = ?, updatedAt = ? WHERE id like ? AND leaseOwner = ?",
+			args2SliceFnWrapper(jobCount, leaseExpiresAt, currentTime, backfill.ID, backfill.LeaseOwner), 1)
+	})
+	err := transactionalWrites(djRepo.db, ops...)
+	if err == nil {
+		backfill.JobCount = index
+		backfill.LeaseExpiresAt = leaseExpiresAt
+		backfill.UpdatedAt = currentTime
+	}
+	return err
+}
+
+// GetBackfillForConsumer loads the backfill of the consumer along with the count of the jobs it created by status; returns sql.ErrNoRows if
+// the consumer was not backfilled
+func (djRepo *DeliveryJobDBRepository) GetBackfillForConsumer(consumer *data.Consumer) (*data.Backfill, error) {
+	backfill := &data.Backfill{Consumer: consumer}
+	var consumerID string
+	err := querySingleRow(djRepo.db, backfillSelectQuery+" consumerId like ?", args2SliceFnWrapper(consumer.ID.String()),
+		args2SliceFnWrapper(&backfill.ID, &consumerID, &backfill.Since, &backfill.Until, &backfill.JobCount, &backfill.JobsCreated, &backfill.LeaseOwner,
+			nullTimeScanner{&backfill.LeaseExpiresAt}, &backfill.CreatedAt, &backfill.UpdatedAt))
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]data.JobStatus, 0, 4)
+	counts := make([]uint, 0, 4)
+	err = queryRows(djRepo.db, backfillJobStatusCountQuery, args2SliceFnWrapper(backfill.ID.String()), func() []interface{} {
+		statuses = append(statuses, data.JobQueued)
+		counts = append(counts, 0)
+		return []interface{}{&statuses[len(statuses)-1], &counts[len(counts)-1]}
+	})
+	for index := 0; err == nil && index < len(statuses); index++ {
+		switch statuses[index] {
+		case data.JobQueued:
+			backfill.QueuedCount = counts[index]
+		case data.JobInflight:
+			backfill.InflightCount = counts[index]
+		case data.JobDelivered:
+			backfill.DeliveredCount = counts[index]
+		case data.JobDead:
+			backfill.DeadCount = counts[index]
+		case data.JobDiscarded:
+			backfill.DiscardedCount = counts[index]
+		case data.JobCancelled:
+			backfill.CancelledCount = counts[index]
+		}
+	}
+	return backfill, err
+}
+
+// getJobStats computes the stats of the jobs matching the criteria; time columns are not aggregated as SQLite loses their type on MIN/MAX
+func (djRepo *DeliveryJobDBRepository) getJobStats(criteria string, criteriaArgs ...interface{}) (*data.JobStats, error) {
+	stats := data.NewJobStats()
+	withCriteriaArgs := func(args ...interface{}) func() []interface{} {
+		return args2SliceFnWrapper(append(args, criteriaArgs...)...)
+	}
+	statuses := make([]data.JobStatus, 0, 4)
+	counts := make([]uint, 0, 4)
+	err := queryRows(djRepo.db, "SELECT status, COUNT(*) FROM job WHERE"+criteria+" GROUP BY status", withCriteriaArgs(), func() []interface{} {
+		statuses = append(statuses, data.JobQueued)
+		counts = append(counts, 0)
+		return []interface{}{&statuses[len(statuses)-1], &counts[len(counts)-1]}
+	})
+	for index := 0; err == nil && index < len(statuses); index++ {
+		stats.StatusCounts[statuses[index]] = counts[index]
+	}
+	if err == nil {
+		err = querySingleRow(djRepo.db, "SELECT dispatchReceivedAt FROM job WHERE status = ? AND"+criteria+" ORDER BY dispatchReceivedAt LIMIT 1",
+			withCriteriaArgs(data.JobQueued), args2SliceFnWrapper(&stats.OldestQueuedAt))
+		if err == sql.ErrNoRows {
+			err = nil
+		}
+	}
+	if err == nil {
+		err = querySingleRow(djRepo.db, "SELECT earliestNextAttemptAt FROM job WHERE status = ? AND retryAttemptCount > 0 AND"+criteria+" ORDER BY earliestNextAttemptAt LIMIT 1",
+			withCriteriaArgs(data.JobQueued), args2SliceFnWrapper(&stats.NextRetryAt))
+		if err == sql.ErrNoRows {
+			err = nil
+		}
+	}
+	if err == nil {
+		windowCounts := make([]uint, len(data.JobStatsWindows))
+		query := "SELECT "
+		args := make([]interface{}, 0, len(data.JobStatsWindows)+2)
+		scanArgs := make([]interface{}, 0, len(data.JobStatsWindows))
+		var longestWindow time.Duration
+		for index, window := range data.JobStatsWindows {
+			query = query + "COUNT(CASE WHEN statusChangedAt >= ? THEN 1 END), "
+			args = append(args, stats.ComputedAt.Add(-window))
+			scanArgs = append(scanArgs, &windowCounts[index])
+			if window > longestWindow {
+				longestWindow = window
+			}
+		}
+		query = query[:len(query)-2] + " FROM job WHERE status = ? AND statusChangedAt >= ? AND" + criteria
+		args = append(args, data.JobDelivered, stats.ComputedAt.Add(-longestWindow))
+		err = querySingleRow(djRepo.db, query, withCriteriaArgs(args...), args2SliceFnWrapper(scanArgs...))
+		for index := 0; err == nil && index < len(windowCounts); index++ {
+			stats.DeliveredInWindows[data.JobStatsWindows[index]] = windowCounts[index]
+		}
+	}
+	if err == nil {
+		samples := make([]*data.DeliveryJob, 0)
+		args := append([]interface{}{data.JobDelivered, data.JobDead, stats.ComputedAt.Add(-jobStatsSampleWindow)}, criteriaArgs...)
+		err = queryRows(djRepo.db, "SELECT status, dispatchReceivedAt, statusChangedAt FROM job WHERE status IN (?, ?) AND statusChangedAt >= ? AND"+criteria+
+			" ORDER BY statusChangedAt DESC LIMIT ?", args2SliceFnWrapper(append(args, jobStatsSampleSize)...), func() []interface{} {
+			job := &data.DeliveryJob{}
+			samples = append(samples, job)
+			return []interface{}{&job.Status, &job.DispatchReceivedAt, &job.StatusChangedAt}
+		})
+		for _, job := range samples {
+			stats.SampledCount++
+			if job.Status == data.JobDelivered {
+				stats.SampledDelivered++
+				stats.TotalLatency += job.StatusChangedAt.Sub(job.DispatchReceivedAt)
+			}
+		}
+	}
+	return stats, err
+}
+
+// GetJobStatsForConsumer computes the backlog and recent delivery stats of the consumer's jobs
+func (djRepo *DeliveryJobDBRepository) GetJobStatsForConsumer(consumer *data.Consumer) (*data.JobStats, error) {
+	return djRepo.getJobStats(consumerCriteria, consumer.ID.String())
+}
+
+// GetJobStatsForChannel computes the backlog and recent delivery stats of the jobs of all the consumers of the channel
+func (djRepo *DeliveryJobDBRepository) GetJobStatsForChannel(channel *data.Channel) (*data.JobStats, error) {
+	return djRepo.getJobStats(channelConsumersCriteria, channel.ChannelID)
 }
 
 // GetByID loads the delivery job with specified id if it exists, else returns an error
@@ -209,7 +640,7 @@ func (djRepo *DeliveryJobDBRepository) GetByID(id string) (job *data.DeliveryJob
 	var consumerID string
 	err = querySingleRow(djRepo.db, jobCommonSelectQuery+" id like ?", args2SliceFnWrapper(id),
 		args2SliceFnWrapper(&job.ID, &messageID, &consumerID, &job.Status, &job.DispatchReceivedAt, &job.RetryAttemptCount, &job.StatusChangedAt,
-			&job.EarliestNextAttemptAt, &job.CreatedAt, &job.UpdatedAt))
+			&job.EarliestNextAttemptAt, &job.CreatedAt, &job.UpdatedAt, &job.FailureReason))
 	if err == nil {
 		job.Message, err = djRepo.mesageRepository.GetByID(messageID)
 	}
@@ -246,6 +246,24 @@ func TestStatusUpdatesForJob(t *testing.T) {
 	})
 }
 
+func TestGetUndeliveredJobCount(t *testing.T) {
+	djRepo := getDeliverJobRepository()
+	message := getMessageForJob()
+	assert.Nil(t, getMessageRepository().Create(message))
+	jobs := getDeliveryJobsInFixture(message)
+	assert.Nil(t, djRepo.DispatchMessage(message, jobs...))
+	count, err := djRepo.GetUndeliveredJobCount(message)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(len(jobs)), count)
+	for index, job := range jobs {
+		assert.Nil(t, djRepo.MarkJobInflight(job))
+		assert.Nil(t, djRepo.MarkJobDelivered(job))
+		count, err = djRepo.GetUndeliveredJobCount(message)
+		assert.Nil(t, err)
+		assert.Equal(t, uint(len(jobs)-index-1), count)
+	}
+}
+
 func TestStatusBasedJobsListing(t *testing.T) {
 	t.Run("SuccessRetryList", func(t *testing.T) {
 		t.Parallel()
@@ -397,3 +415,482 @@ func TestRequeueDeadJobsForConsumer(t *testing.T) {
 		assert.Equal(t, data.JobQueued, job.Status)
 	}
 }
+
+func TestPausedConsumerJobs(t *testing.T) {
+	djRepo := getDeliverJobRepository()
+	consumerRepo := getConsumerRepo()
+	pauseChannel := createTestChannel("channel-for-paused-consumer", "sampletoken", NewChannelRepository(testDB))
+	consumer, _ := data.NewConsumer(pauseChannel, "paused-consumer", "sometoken", callbackURL)
+	consumer, err := consumerRepo.Store(consumer)
+	assert.Nil(t, err)
+	assert.Nil(t, consumerRepo.SetPaused(consumer, true))
+	dbConsumer, err := consumerRepo.Get(pauseChannel.ChannelID, consumer.ConsumerID)
+	assert.Nil(t, err)
+	assert.True(t, dbConsumer.Paused)
+	jobs := make([]*data.DeliveryJob, 0, 3)
+	for index := 0; index < 3; index++ {
+		message, _ := data.NewMessage(pauseChannel, producer1, samplePayload, sampleContentType)
+		assert.Nil(t, getMessageRepository().Create(message))
+		job, _ := data.NewDeliveryJob(message, consumer)
+		assert.Nil(t, djRepo.DispatchMessage(message, job))
+		jobs = append(jobs, job)
+	}
+	containsJob := func(jobs []*data.DeliveryJob, expectedJob *data.DeliveryJob) bool {
+		for _, job := range jobs {
+			if job.ID == expectedJob.ID {
+				return true
+			}
+		}
+		return false
+	}
+	for _, job := range jobs {
+		assert.False(t, containsJob(djRepo.GetJobsReadyForInflightSince(0), job))
+	}
+	start := time.Now().Add(time.Hour)
+	assert.Nil(t, djRepo.RescheduleQueuedJobsForConsumer(consumer, start, time.Second))
+	assert.Nil(t, consumerRepo.SetPaused(consumer, false))
+	readyJobs := djRepo.GetJobsReadyForInflightSince(0)
+	for index, job := range jobs {
+		assert.False(t, containsJob(readyJobs, job))
+		dbJob, err := djRepo.GetByID(job.ID.String())
+		assert.Nil(t, err)
+		assert.Equal(t, start.Add(time.Duration(index)*time.Second).Unix(), dbJob.EarliestNextAttemptAt.Unix())
+		assert.Equal(t, uint(0), dbJob.RetryAttemptCount)
+	}
+	assert.Nil(t, djRepo.RescheduleQueuedJobsForConsumer(consumer, time.Now().Add(-1*time.Second), 0))
+	readyJobs = djRepo.GetJobsReadyForInflightSince(0)
+	for _, job := range jobs {
+		assert.True(t, containsJob(readyJobs, job))
+	}
+}
+
+func TestBackfillJobsForConsumer(t *testing.T) {
+	djRepo := getDeliverJobRepository()
+	backfillChannel := createTestChannel("channel-for-backfill", "sampletoken", NewChannelRepository(testDB))
+	messages := createDispatchedMessagesForReplay(t, backfillChannel, 4)
+	consumer, _ := data.NewConsumer(backfillChannel, "backfill-consumer", "sometoken", callbackURL)
+	consumer, err := getConsumerRepo().Store(consumer)
+	assert.Nil(t, err)
+	_, err = djRepo.GetBackfillForConsumer(consumer)
+	assert.Equal(t, sql.ErrNoRows, err)
+	assert.Equal(t, ErrInvalidStateToSave, djRepo.CreateBackfill(&data.Backfill{Consumer: consumer}))
+	assert.Equal(t, ErrInvalidStateToSave, djRepo.BackfillJobsForConsumer(&data.Backfill{Consumer: consumer}, time.Now(), 0, time.Minute))
+	// A job already dispatched to the consumer is not backfilled again
+	existingJob, _ := data.NewDeliveryJob(messages[3], consumer)
+	assert.Nil(t, testDBInsertJob(existingJob))
+	backfill, err := data.NewBackfill(consumer, messages[1].ReceivedAt)
+	assert.Nil(t, err)
+	assert.Nil(t, djRepo.CreateBackfill(backfill))
+	dbBackfill, err := djRepo.GetBackfillForConsumer(consumer)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(0), dbBackfill.JobCount)
+	assert.False(t, dbBackfill.JobsCreated)
+	assert.False(t, dbBackfill.IsComplete())
+	assert.True(t, containsBackfill(djRepo.GetBackfillsToRun(), backfill))
+	assert.Nil(t, djRepo.LeaseBackfill(backfill, "owner-1", time.Minute))
+	assert.Equal(t, "owner-1", backfill.LeaseOwner)
+	assert.Equal(t, ErrNoRowsUpdated, djRepo.LeaseBackfill(dbBackfill, "owner-2", time.Minute))
+	assert.False(t, containsBackfill(djRepo.GetBackfillsToRun(), backfill))
+	// Only the lease owner creates the jobs
+	dbBackfill.LeaseOwner = "owner-2"
+	assert.Equal(t, ErrNoRowsUpdated, djRepo.BackfillJobsForConsumer(dbBackfill, time.Now(), 0, time.Minute))
+	start := time.Now().Add(time.Hour)
+	assert.Nil(t, djRepo.BackfillJobsForConsumer(backfill, start, time.Second, time.Minute))
+	assert.Equal(t, uint(2), backfill.JobCount)
+	assert.True(t, backfill.JobsCreated)
+	assert.False(t, containsBackfill(djRepo.GetBackfillsToRun(), backfill))
+	backfill.LeaseExpiresAt = time.Now()
+	assert.Equal(t, ErrNoRowsUpdated, djRepo.LeaseBackfill(backfill, "owner-1", time.Minute))
+	jobs, _, err := djRepo.GetJobsForConsumer(consumer, data.JobQueued, data.NewPagination(nil, nil))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(jobs))
+	earliestNextAttempts := make(map[xid.ID]int64)
+	for _, job := range jobs {
+		earliestNextAttempts[job.Message.ID] = job.EarliestNextAttemptAt.Unix()
+	}
+	assert.Equal(t, start.Unix(), earliestNextAttempts[messages[1].ID])
+	assert.Equal(t, start.Add(time.Second).Unix(), earliestNextAttempts[messages[2].ID])
+	_, ok := earliestNextAttempts[messages[0].ID]
+	assert.False(t, ok)
+	dbBackfill, err = djRepo.GetBackfillForConsumer(consumer)
+	assert.Nil(t, err)
+	assert.Equal(t, backfill.ID, dbBackfill.ID)
+	assert.Equal(t, uint(2), dbBackfill.JobCount)
+	assert.True(t, dbBackfill.JobsCreated)
+	// Only the jobs created by the backfill are counted
+	assert.Equal(t, uint(2), dbBackfill.QueuedCount)
+	assert.False(t, dbBackfill.IsComplete())
+	for _, job := range jobs {
+		if job.ID == existingJob.ID {
+			continue
+		}
+		assert.Nil(t, djRepo.MarkJobInflight(job))
+		if job.Message.ID == messages[1].ID {
+			assert.Nil(t, djRepo.MarkJobDead(job))
+		} else {
+			assert.Nil(t, djRepo.MarkJobDelivered(job))
+		}
+	}
+	dbBackfill, err = djRepo.GetBackfillForConsumer(consumer)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(0), dbBackfill.QueuedCount)
+	assert.Equal(t, uint(1), dbBackfill.DeliveredCount)
+	assert.Equal(t, uint(1), dbBackfill.DeadCount)
+	assert.True(t, dbBackfill.IsComplete())
+	// Discarding the dead backfilled job keeps the backfill complete
+	assert.Nil(t, djRepo.DiscardDeadJobs(&data.DeadJobFilter{Channel: backfillChannel, Consumer: consumer}))
+	dbBackfill, err = djRepo.GetBackfillForConsumer(consumer)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(0), dbBackfill.DeadCount)
+	assert.Equal(t, uint(1), dbBackfill.DiscardedCount)
+	assert.True(t, dbBackfill.IsComplete())
+	// Consumer can only be backfilled once
+	backfill, _ = data.NewBackfill(consumer, messages[0].ReceivedAt)
+	assert.NotNil(t, djRepo.CreateBackfill(backfill))
+}
+
+func containsBackfill(backfills []*data.Backfill, expected *data.Backfill) bool {
+	for _, backfill := range backfills {
+		if backfill.ID == expected.ID {
+			return backfill.Consumer != nil && backfill.Consumer.ID == expected.Consumer.ID
+		}
+	}
+	return false
+}
+
+func TestBackfillJobsResumedAfterLeaseExpiry(t *testing.T) {
+	djRepo := getDeliverJobRepository()
+	backfillChannel := createTestChannel("channel-for-resumed-backfill", "sampletoken", NewChannelRepository(testDB))
+	messageCount := rescheduleBatchSize + rescheduleBatchSize/2
+	createDispatchedMessagesForReplay(t, backfillChannel, messageCount)
+	consumer, _ := data.NewConsumer(backfillChannel, "resumed-backfill-consumer", "sometoken", callbackURL)
+	consumer, err := getConsumerRepo().Store(consumer)
+	assert.Nil(t, err)
+	backfill, _ := data.NewBackfill(consumer, time.Now().Add(-24*time.Hour))
+	assert.Nil(t, djRepo.CreateBackfill(backfill))
+	assert.Nil(t, djRepo.LeaseBackfill(backfill, "crashed-owner", 0))
+	// The owner went away after creating the first batch of jobs
+	messages := make([]*data.Message, 0, rescheduleBatchSize)
+	assert.Nil(t, queryRows(testDB, "SELECT id, routingKey, receivedAt FROM message WHERE channelId like ? ORDER BY receivedAt, id LIMIT ?", args2SliceFnWrapper(backfillChannel.ChannelID, rescheduleBatchSize), func() []interface{} {
+		message := &data.Message{}
+		messages = append(messages, message)
+		return []interface{}{&message.ID, &message.RoutingKey, &message.ReceivedAt}
+	}))
+	assert.Equal(t, rescheduleBatchSize, len(messages))
+	assert.Nil(t, djRepo.(*DeliveryJobDBRepository).createBackfillJobs(backfill, messages, time.Now(), 0, 0))
+	var resumed *data.Backfill
+	for _, toRun := range djRepo.GetBackfillsToRun() {
+		if toRun.ID == backfill.ID {
+			resumed = toRun
+		}
+	}
+	assert.NotNil(t, resumed)
+	assert.Equal(t, uint(rescheduleBatchSize), resumed.JobCount)
+	assert.False(t, resumed.JobsCreated)
+	assert.Nil(t, djRepo.LeaseBackfill(resumed, "owner", time.Minute))
+	assert.Nil(t, djRepo.BackfillJobsForConsumer(resumed, time.Now(), 0, time.Minute))
+	dbBackfill, err := djRepo.GetBackfillForConsumer(consumer)
+	assert.Nil(t, err)
+	assert.True(t, dbBackfill.JobsCreated)
+	assert.Equal(t, uint(messageCount), dbBackfill.JobCount)
+	assert.Equal(t, uint(messageCount), dbBackfill.QueuedCount)
+}
+
+func TestBackfillJobsForDeletedConsumer(t *testing.T) {
+	djRepo := getDeliverJobRepository()
+	backfillChannel := createTestChannel("channel-for-deleted-backfill", "sampletoken", NewChannelRepository(testDB))
+	createDispatchedMessagesForReplay(t, backfillChannel, 2)
+	consumer, _ := data.NewConsumer(backfillChannel, "deleted-backfill-consumer", "sometoken", callbackURL)
+	consumer, err := getConsumerRepo().Store(consumer)
+	assert.Nil(t, err)
+	backfill, _ := data.NewBackfill(consumer, time.Now().Add(-24*time.Hour))
+	assert.Nil(t, djRepo.CreateBackfill(backfill))
+	assert.Nil(t, getConsumerRepo().Delete(consumer, false))
+	assert.Nil(t, djRepo.LeaseBackfill(backfill, "owner", time.Minute))
+	assert.Nil(t, djRepo.BackfillJobsForConsumer(backfill, time.Now(), 0, time.Minute))
+	assert.True(t, backfill.JobsCreated)
+	assert.Equal(t, uint(0), backfill.JobCount)
+}
+
+func TestBackfillJobsForConsumerInBatches(t *testing.T) {
+	djRepo := getDeliverJobRepository()
+	backfillChannel := createTestChannel("channel-for-batched-backfill", "sampletoken", NewChannelRepository(testDB))
+	messageCount := rescheduleBatchSize + rescheduleBatchSize/2
+	createDispatchedMessagesForReplay(t, backfillChannel, messageCount)
+	consumer, _ := data.NewConsumer(backfillChannel, "batched-backfill-consumer", "sometoken", callbackURL)
+	consumer, err := getConsumerRepo().Store(consumer)
+	assert.Nil(t, err)
+	backfill, _ := data.NewBackfill(consumer, time.Now().Add(-24*time.Hour))
+	assert.Nil(t, djRepo.CreateBackfill(backfill))
+	assert.Nil(t, djRepo.BackfillJobsForConsumer(backfill, time.Now(), 0, time.Minute))
+	assert.Equal(t, uint(messageCount), backfill.JobCount)
+	dbBackfill, err := djRepo.GetBackfillForConsumer(consumer)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(messageCount), dbBackfill.JobCount)
+	assert.Equal(t, uint(messageCount), dbBackfill.QueuedCount)
+	assert.True(t, dbBackfill.JobsCreated)
+}
+
+func TestBackfillJobsForConsumerWithRoutingKeyPattern(t *testing.T) {
+	djRepo := getDeliverJobRepository()
+	backfillChannel := createTestChannel("channel-for-routed-backfill", "sampletoken", NewChannelRepository(testDB))
+	routingKeys := []string{"user.created", "order.created", "", "user.deleted.v2"}
+	messages := make([]*data.Message, 0, len(routingKeys))
+	for index, routingKey := range routingKeys {
+		message, _ := data.NewMessage(backfillChannel, producer1, samplePayload, sampleContentType)
+		message.RoutingKey = routingKey
+		message.ReceivedAt = time.Now().Add(time.Duration(index-len(routingKeys)) * time.Minute)
+		assert.Nil(t, getMessageRepository().Create(message))
+		assert.Nil(t, djRepo.DispatchMessage(message))
+		messages = append(messages, message)
+	}
+	consumer, _ := data.NewConsumer(backfillChannel, "routed-backfill-consumer", "sometoken", callbackURL)
+	consumer.RoutingKeyPattern = "user.#"
+	consumer, err := getConsumerRepo().Store(consumer)
+	assert.Nil(t, err)
+	backfill, _ := data.NewBackfill(consumer, messages[0].ReceivedAt.Add(-1*time.Second))
+	assert.Nil(t, djRepo.CreateBackfill(backfill))
+	assert.Nil(t, djRepo.BackfillJobsForConsumer(backfill, time.Now(), 0, time.Minute))
+	assert.Equal(t, uint(2), backfill.JobCount)
+	jobs, _, err := djRepo.GetJobsForConsumer(consumer, data.JobQueued, data.NewPagination(nil, nil))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(jobs))
+	for _, job := range jobs {
+		assert.Contains(t, []xid.ID{messages[0].ID, messages[3].ID}, job.Message.ID)
+		assert.Contains(t, []string{messages[0].RoutingKey, messages[3].RoutingKey}, job.Message.RoutingKey)
+	}
+}
+
+func testDBInsertJob(job *data.DeliveryJob) error {
+	_, err := testDB.Exec("INSERT INTO job (id, messageId, consumerId, dispatchReceivedAt, statusChangedAt, earliestNextAttemptAt, status, createdAt, updatedAt) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		job.ID, job.Message.ID, job.Listener.ID, job.DispatchReceivedAt, job.StatusChangedAt, job.EarliestNextAttemptAt, job.Status, job.CreatedAt, job.UpdatedAt)
+	return err
+}
+
+func TestJobsOfUnverifiedConsumerNotReady(t *testing.T) {
+	djRepo := getDeliverJobRepository()
+	unverifiedChannel := createTestChannel("channel-for-unverified-consumer", "sampletoken", NewChannelRepository(testDB))
+	consumer, _ := data.NewConsumer(unverifiedChannel, "unverified-consumer", "sometoken", callbackURL)
+	consumer.VerificationStatus = data.ConsumerPendingVerification
+	consumer.VerificationChallenge = "challenge"
+	consumer, err := getConsumerRepo().Store(consumer)
+	assert.Nil(t, err)
+	message, _ := data.NewMessage(unverifiedChannel, producer1, samplePayload, sampleContentType)
+	assert.Nil(t, getMessageRepository().Create(message))
+	job, _ := data.NewDeliveryJob(message, consumer)
+	assert.Nil(t, djRepo.DispatchMessage(message, job))
+	assert.False(t, containsJobWithID(djRepo.GetJobsReadyForInflightSince(0), job))
+	assert.Nil(t, getConsumerRepo().MarkVerified(consumer, "challenge"))
+	assert.True(t, containsJobWithID(djRepo.GetJobsReadyForInflightSince(0), job))
+}
+
+func TestJobsOfStreamingConsumer(t *testing.T) {
+	djRepo := getDeliverJobRepository()
+	streamChannel := createTestChannel("channel-for-streaming-consumer", "sampletoken", NewChannelRepository(testDB))
+	consumer, _ := data.NewStreamConsumer(streamChannel, "streaming-consumer", "sometoken")
+	consumer, err := getConsumerRepo().Store(consumer)
+	assert.Nil(t, err)
+	consumer, err = getConsumerRepo().Get(streamChannel.ChannelID, consumer.ConsumerID)
+	assert.Nil(t, err)
+	assert.True(t, consumer.IsStreaming())
+	assert.Empty(t, consumer.CallbackURL)
+	jobs := make([]*data.DeliveryJob, 0, 2)
+	for index := 0; index < 2; index++ {
+		message, _ := data.NewMessage(streamChannel, producer1, samplePayload, sampleContentType)
+		assert.Nil(t, getMessageRepository().Create(message))
+		job, _ := data.NewDeliveryJob(message, consumer)
+		job.EarliestNextAttemptAt = job.EarliestNextAttemptAt.Add(time.Duration(index-2) * time.Second)
+		assert.Nil(t, djRepo.DispatchMessage(message, job))
+		jobs = append(jobs, job)
+	}
+	// Jobs of streaming consumers are not delivered by the workers
+	assert.False(t, containsJobWithID(djRepo.GetJobsReadyForInflightSince(0), jobs[0]))
+	readyJobs, err := djRepo.GetJobsReadyForConsumer(consumer, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(readyJobs))
+	assert.Equal(t, jobs[0].ID, readyJobs[0].ID)
+	assert.Equal(t, consumer, readyJobs[0].Listener)
+	assert.Equal(t, jobs[0].Message.MessageID, readyJobs[0].Message.MessageID)
+	assert.Nil(t, djRepo.MarkJobInflight(readyJobs[0]))
+	assert.Nil(t, djRepo.MarkJobRetry(readyJobs[0], time.Minute))
+	readyJobs, err = djRepo.GetJobsReadyForConsumer(consumer, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(readyJobs))
+	assert.Equal(t, jobs[1].ID, readyJobs[0].ID)
+}
+
+func containsJobWithID(jobs []*data.DeliveryJob, expectedJob *data.DeliveryJob) bool {
+	for _, job := range jobs {
+		if job.ID == expectedJob.ID {
+			return true
+		}
+	}
+	return false
+}
+
+func createDeadJob(t *testing.T, djRepo DeliveryJobRepository, message *data.Message, consumer *data.Consumer, failureReason string) *data.DeliveryJob {
+	job, _ := data.NewDeliveryJob(message, consumer)
+	assert.Nil(t, testDBInsertJob(job))
+	assert.Nil(t, djRepo.MarkJobInflight(job))
+	job.FailureReason = failureReason
+	assert.Nil(t, djRepo.MarkJobDead(job))
+	return job
+}
+
+func TestGetJobsForMessages(t *testing.T) {
+	djRepo := getDeliverJobRepository()
+	jobsChannel := createTestChannel("channel-for-jobs-of-messages", "sampletoken", NewChannelRepository(testDB))
+	messages := createDispatchedMessagesForReplay(t, jobsChannel, 3)
+	consumer, _ := data.NewConsumer(jobsChannel, "jobs-of-messages-consumer", "sometoken", callbackURL)
+	consumer, err := getConsumerRepo().Store(consumer)
+	assert.Nil(t, err)
+	otherConsumer, _ := data.NewConsumer(jobsChannel, "jobs-of-messages-other-consumer", "sometoken", callbackURL)
+	otherConsumer, err = getConsumerRepo().Store(otherConsumer)
+	assert.Nil(t, err)
+	createDeadJob(t, djRepo, messages[0], consumer, "connection refused")
+	createDeadJob(t, djRepo, messages[0], otherConsumer, "connection refused")
+	createDeadJob(t, djRepo, messages[2], consumer, "connection refused")
+	jobs, err := djRepo.GetJobsForMessages(messages[:2])
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(jobs))
+	for _, job := range jobs {
+		assert.Same(t, messages[0], job.Message)
+		assert.Equal(t, data.JobDead, job.Status)
+	}
+	assert.ElementsMatch(t, []string{consumer.ConsumerID, otherConsumer.ConsumerID}, []string{jobs[0].Listener.ConsumerID, jobs[1].Listener.ConsumerID})
+	jobs, err = djRepo.GetJobsForMessages(nil)
+	assert.Nil(t, err)
+	assert.Empty(t, jobs)
+	expectedErr := errors.New("consumer error")
+	_, err = NewDeliveryJobRepository(testDB, getMessageRepository(), &consumerRepoWithGetByIDError{ConsumerRepository: getConsumerRepo(), err: expectedErr}).GetJobsForMessages(messages[:2])
+	assert.Equal(t, expectedErr, err)
+}
+
+type consumerRepoWithGetByIDError struct {
+	ConsumerRepository
+	err error
+}
+
+func (consumerRepo *consumerRepoWithGetByIDError) GetByID(id string) (*data.Consumer, error) {
+	return nil, consumerRepo.err
+}
+
+func TestDeadJobsManagement(t *testing.T) {
+	djRepo := getDeliverJobRepository()
+	dlqChannel := createTestChannel("channel-for-dlq-management", "sampletoken", NewChannelRepository(testDB))
+	messages := createDispatchedMessagesForReplay(t, dlqChannel, 3)
+	consumer1, _ := data.NewConsumer(dlqChannel, "dlq-consumer-1", "sometoken", callbackURL)
+	consumer1, err := getConsumerRepo().Store(consumer1)
+	assert.Nil(t, err)
+	consumer2, _ := data.NewConsumer(dlqChannel, "dlq-consumer-2", "sometoken", callbackURL)
+	consumer2, err = getConsumerRepo().Store(consumer2)
+	assert.Nil(t, err)
+	jobs := make([]*data.DeliveryJob, 0, 4)
+	for index, message := range messages {
+		jobs = append(jobs, createDeadJob(t, djRepo, message, consumer1, "error - client status not 2xx: 50"+strconv.Itoa(index)+" Server Error"))
+	}
+	jobs = append(jobs, createDeadJob(t, djRepo, messages[0], consumer2, "connection refused"))
+	getDeadJobIDs := func(filter *data.DeadJobFilter) []xid.ID {
+		deadJobs, _, err := djRepo.GetDeadJobs(filter, data.NewPagination(nil, nil))
+		assert.Nil(t, err)
+		ids := make([]xid.ID, 0, len(deadJobs))
+		for _, job := range deadJobs {
+			ids = append(ids, job.ID)
+		}
+		return ids
+	}
+	t.Run("InvalidFilter", func(t *testing.T) {
+		_, _, err := djRepo.GetDeadJobs(&data.DeadJobFilter{}, data.NewPagination(nil, nil))
+		assert.Equal(t, ErrInvalidStateToSave, err)
+		_, _, err = djRepo.GetDeadJobs(&data.DeadJobFilter{Channel: dlqChannel}, nil)
+		assert.Equal(t, ErrPaginationDeadlock, err)
+		assert.Equal(t, ErrInvalidStateToSave, djRepo.RequeueDeadJobs(nil))
+		assert.Equal(t, ErrInvalidStateToSave, djRepo.DiscardDeadJobs(&data.DeadJobFilter{}))
+	})
+	t.Run("Filters", func(t *testing.T) {
+		assert.Equal(t, 4, len(getDeadJobIDs(&data.DeadJobFilter{Channel: dlqChannel})))
+		assert.Equal(t, 3, len(getDeadJobIDs(&data.DeadJobFilter{Channel: dlqChannel, Consumer: consumer1})))
+		assert.ElementsMatch(t, []xid.ID{jobs[0].ID, jobs[3].ID}, getDeadJobIDs(&data.DeadJobFilter{Channel: dlqChannel, MessageIDs: []string{messages[0].MessageID}}))
+		assert.ElementsMatch(t, []xid.ID{jobs[1].ID}, getDeadJobIDs(&data.DeadJobFilter{Channel: dlqChannel, JobIDs: []string{jobs[1].ID.String()}}))
+		assert.ElementsMatch(t, []xid.ID{jobs[2].ID}, getDeadJobIDs(&data.DeadJobFilter{Channel: dlqChannel, FailureReason: "502"}))
+		assert.Equal(t, 0, len(getDeadJobIDs(&data.DeadJobFilter{Channel: dlqChannel, DeadSince: time.Now().Add(time.Hour)})))
+		assert.Equal(t, 4, len(getDeadJobIDs(&data.DeadJobFilter{Channel: dlqChannel, DeadSince: time.Now().Add(-1 * time.Hour), DeadUntil: time.Now().Add(time.Hour)})))
+		deadJobs, _, err := djRepo.GetDeadJobs(&data.DeadJobFilter{Channel: dlqChannel, JobIDs: []string{jobs[3].ID.String()}}, data.NewPagination(nil, nil))
+		assert.Nil(t, err)
+		assert.Equal(t, "connection refused", deadJobs[0].FailureReason)
+		assert.Equal(t, consumer2.ID, deadJobs[0].Listener.ID)
+		assert.Equal(t, messages[0].MessageID, deadJobs[0].Message.MessageID)
+	})
+	t.Run("RequeueAndDiscard", func(t *testing.T) {
+		assert.Nil(t, djRepo.RequeueDeadJobs(&data.DeadJobFilter{Channel: dlqChannel, Consumer: consumer1, JobIDs: []string{jobs[0].ID.String()}}))
+		assert.Nil(t, djRepo.DiscardDeadJobs(&data.DeadJobFilter{Channel: dlqChannel, FailureReason: "Server Error"}))
+		requeuedJob, err := djRepo.GetByID(jobs[0].ID.String())
+		assert.Nil(t, err)
+		assert.Equal(t, data.JobQueued, requeuedJob.Status)
+		for _, job := range jobs[1:3] {
+			discardedJob, err := djRepo.GetByID(job.ID.String())
+			assert.Nil(t, err)
+			assert.Equal(t, data.JobDiscarded, discardedJob.Status)
+		}
+		assert.ElementsMatch(t, []xid.ID{jobs[3].ID}, getDeadJobIDs(&data.DeadJobFilter{Channel: dlqChannel}))
+	})
+}
+
+func TestGetJobStats(t *testing.T) {
+	djRepo := getDeliverJobRepository()
+	statsChannel := createTestChannel("channel-for-job-stats", "sampletoken", NewChannelRepository(testDB))
+	messages := createDispatchedMessagesForReplay(t, statsChannel, 4)
+	consumer, _ := data.NewConsumer(statsChannel, "job-stats-consumer", "sometoken", callbackURL)
+	consumer, err := getConsumerRepo().Store(consumer)
+	assert.Nil(t, err)
+	otherConsumer, _ := data.NewConsumer(statsChannel, "job-stats-other-consumer", "sometoken", callbackURL)
+	otherConsumer, err = getConsumerRepo().Store(otherConsumer)
+	assert.Nil(t, err)
+	t.Run("NoJobs", func(t *testing.T) {
+		stats, err := djRepo.GetJobStatsForConsumer(consumer)
+		assert.Nil(t, err)
+		assert.Empty(t, stats.StatusCounts)
+		assert.True(t, stats.OldestQueuedAt.IsZero())
+		assert.True(t, stats.NextRetryAt.IsZero())
+		assert.Equal(t, uint(0), stats.SampledCount)
+		assert.Equal(t, len(data.JobStatsWindows), len(stats.DeliveredInWindows))
+	})
+	newJob := func(message *data.Message, consumer *data.Consumer, dispatchedAgo time.Duration) *data.DeliveryJob {
+		job, _ := data.NewDeliveryJob(message, consumer)
+		job.DispatchReceivedAt = time.Now().Add(-dispatchedAgo)
+		job.EarliestNextAttemptAt = job.DispatchReceivedAt
+		assert.Nil(t, testDBInsertJob(job))
+		return job
+	}
+	delivered := newJob(messages[0], consumer, 2*time.Second)
+	assert.Nil(t, djRepo.MarkJobInflight(delivered))
+	assert.Nil(t, djRepo.MarkJobDelivered(delivered))
+	createDeadJob(t, djRepo, messages[1], consumer, "connection refused")
+	oldestQueued := newJob(messages[2], consumer, 10*time.Minute)
+	retried := newJob(messages[3], consumer, time.Minute)
+	assert.Nil(t, djRepo.MarkJobInflight(retried))
+	assert.Nil(t, djRepo.MarkJobRetry(retried, 5*time.Minute))
+	otherQueued := newJob(messages[0], otherConsumer, 20*time.Minute)
+	t.Run("Consumer", func(t *testing.T) {
+		stats, err := djRepo.GetJobStatsForConsumer(consumer)
+		assert.Nil(t, err)
+		assert.Equal(t, map[data.JobStatus]uint{data.JobQueued: 2, data.JobDelivered: 1, data.JobDead: 1}, stats.StatusCounts)
+		assert.Equal(t, oldestQueued.DispatchReceivedAt.Unix(), stats.OldestQueuedAt.Unix())
+		assert.Equal(t, retried.EarliestNextAttemptAt.Unix(), stats.NextRetryAt.Unix())
+		for _, window := range data.JobStatsWindows {
+			assert.Equal(t, uint(1), stats.DeliveredInWindows[window], window)
+		}
+		assert.Equal(t, uint(2), stats.SampledCount)
+		assert.Equal(t, uint(1), stats.SampledDelivered)
+		assert.Equal(t, 0.5, stats.GetSuccessRate())
+		assert.InDelta(t, 2*time.Second, stats.GetAverageLatency(), float64(time.Second))
+	})
+	t.Run("Channel", func(t *testing.T) {
+		stats, err := djRepo.GetJobStatsForChannel(statsChannel)
+		assert.Nil(t, err)
+		assert.Equal(t, uint(3), stats.StatusCounts[data.JobQueued])
+		assert.Equal(t, otherQueued.DispatchReceivedAt.Unix(), stats.OldestQueuedAt.Unix())
+		assert.Equal(t, retried.EarliestNextAttemptAt.Unix(), stats.NextRetryAt.Unix())
+		assert.Equal(t, uint(2), stats.SampledCount)
+	})
+}
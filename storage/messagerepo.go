@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -16,6 +17,7 @@ type MessageDBRepository struct {
 	db                 *sql.DB
 	channelRepository  ChannelRepository
 	producerRepository ProducerRepository
+	compressor         *PayloadCompressor
 }
 
 var (
@@ -33,8 +35,14 @@ var (
 type ContextKey string
 
 const (
-	messageSelectRowCommonQuery            = "SELECT id, messageId, producerId, channelId, payload, contentType, priority, status, receivedAt, outboxedAt, createdAt, updatedAt FROM message WHERE"
-	txContextKey                ContextKey = "tx"
+	messageSelectRowCommonQuery    = "SELECT id, messageId, producerId, channelId, payload, payloadCodec, payloadRef, routingKey, attributes, cloudEvent, receiptUrl, contentType, priority, status, receivedAt, outboxedAt, createdAt, updatedAt FROM message WHERE"
+	payloadRecompressionQuery      = "SELECT id, payload, payloadCodec FROM message WHERE id > ? AND payloadCodec != ? AND (payloadCodec != ? OR LENGTH(payload) > ?) ORDER BY id LIMIT ?"
+	payloadRecompressionLease      = time.Minute
+	messageInsertQuery             = "INSERT INTO message (id, channelId, producerId, messageId, payload, payloadCodec, payloadRef, routingKey, attributes, cloudEvent, receiptUrl, contentType, priority, status, receivedAt, outboxedAt, createdAt, updatedAt) VALUES"
+	messageInsertValuesPlaceholder = " (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?),"
+	// messageBatchChunkSize keeps the placeholders in a chunk's insert well below the limits of the DB drivers
+	messageBatchChunkSize            = 50
+	txContextKey          ContextKey = "tx"
 )
 
 // Create creates a new message if message.MessageID does not already exist; please ensure QuickFix is called before repo is called
@@ -47,14 +55,131 @@ func (msgRepo *MessageDBRepository) Create(message *data.Message) (err error) {
 		if msgErr == nil {
 			err = ErrDuplicateMessageIDForChannel
 		} else {
-			err = transactionalSingleRowWriteExec(msgRepo.db, emptyOps, "INSERT INTO message (id, channelId, producerId, messageId, payload, contentType, priority, status, receivedAt, outboxedAt, createdAt, updatedAt) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
-				args2SliceFnWrapper(message.ID, message.BroadcastedTo.ChannelID, message.ProducedBy.ProducerID, message.MessageID, message.Payload, message.ContentType, message.Priority, message.Status, message.ReceivedAt, message.OutboxedAt, message.CreatedAt, message.UpdatedAt))
-			err = normalizeDBError(err, mysqlErrorMap)
+			var payload, codec string
+			payload, codec, err = msgRepo.compressor.compress(message.Payload)
+			if err == nil {
+				query := messageInsertQuery + messageInsertValuesPlaceholder
+				err = transactionalSingleRowWriteExec(msgRepo.db, emptyOps, query[:len(query)-1], args2SliceFnWrapper(getMessageInsertArgs(message, payload, codec)...))
+				err = normalizeDBError(err, mysqlErrorMap)
+			}
 		}
 	}
 	return err
 }
 
+func getMessageInsertArgs(message *data.Message, payload string, codec string) []interface{} {
+	return []interface{}{message.ID, message.BroadcastedTo.ChannelID, message.ProducedBy.ProducerID, message.MessageID, payload, codec, message.PayloadRef, message.RoutingKey, message.Attributes, message.CloudEvent, message.ReceiptURL, message.ContentType, message.Priority, message.Status, message.ReceivedAt, message.OutboxedAt, message.CreatedAt, message.UpdatedAt}
+}
+
+// CreateBatch creates the messages in chunks, each chunk in a single transaction. The returned slice has the error, if any, for the message at the
+// same index; so a duplicate message ID only rejects that message and not the batch. When set, admit is called for each message about to be
+// created, after it is found not to be a duplicate, and an error it returns rejects that message.
+func (msgRepo *MessageDBRepository) CreateBatch(messages []*data.Message, admit func(message *data.Message) error) []error {
+	errs := make([]error, len(messages))
+	for start := 0; start < len(messages); start += messageBatchChunkSize {
+		end := start + messageBatchChunkSize
+		if end > len(messages) {
+			end = len(messages)
+		}
+		msgRepo.createChunk(messages[start:end], errs[start:end], admit)
+	}
+	return errs
+}
+
+func (msgRepo *MessageDBRepository) createChunk(messages []*data.Message, errs []error, admit func(message *data.Message) error) {
+	candidates := make([]int, 0, len(messages))
+	messageIDsByChannel := make(map[string][]string)
+	seen := make(map[string]map[string]bool)
+	for index, message := range messages {
+		if message == nil || !message.IsInValidState() {
+			errs[index] = data.ErrInsufficientInformationForCreating
+			continue
+		}
+		channelID := message.GetChannelIDSafely()
+		if seen[channelID] == nil {
+			seen[channelID] = make(map[string]bool)
+		}
+		if seen[channelID][message.MessageID] {
+			errs[index] = ErrDuplicateMessageIDForChannel
+			continue
+		}
+		seen[channelID][message.MessageID] = true
+		messageIDsByChannel[channelID] = append(messageIDsByChannel[channelID], message.MessageID)
+		candidates = append(candidates, index)
+	}
+	existing, err := msgRepo.getExistingMessageIDs(messageIDsByChannel)
+	query := messageInsertQuery
+	args := make([]interface{}, 0, len(candidates)*15)
+	toInsert := make([]int, 0, len(candidates))
+	for _, index := range candidates {
+		message := messages[index]
+		if err != nil {
+			errs[index] = err
+			continue
+		}
+		if existing[message.GetChannelIDSafely()][message.MessageID] {
+			errs[index] = ErrDuplicateMessageIDForChannel
+			continue
+		}
+		payload, codec, compressErr := msgRepo.compressor.compress(message.Payload)
+		if compressErr != nil {
+			errs[index] = compressErr
+			continue
+		}
+		if admit != nil {
+			if admitErr := admit(message); admitErr != nil {
+				errs[index] = admitErr
+				continue
+			}
+		}
+		query = query + messageInsertValuesPlaceholder
+		args = append(args, getMessageInsertArgs(message, payload, codec)...)
+		toInsert = append(toInsert, index)
+	}
+	if len(toInsert) <= 0 {
+		return
+	}
+	err = transactionalWrites(msgRepo.db, func(tx *sql.Tx) error {
+		return inTransactionExec(tx, emptyOps, query[:len(query)-1], args2SliceFnWrapper(args...), int64(len(toInsert)))
+	})
+	err = normalizeDBError(err, mysqlErrorMap)
+	for _, index := range toInsert {
+		if err == ErrDuplicateMessageIDForChannel {
+			// A concurrent request stored one of the message IDs after the check, so fall back to creating one by one to isolate it
+			errs[index] = msgRepo.Create(messages[index])
+		} else {
+			errs[index] = err
+		}
+	}
+}
+
+func (msgRepo *MessageDBRepository) getExistingMessageIDs(messageIDsByChannel map[string][]string) (map[string]map[string]bool, error) {
+	existing := make(map[string]map[string]bool)
+	for channelID, messageIDs := range messageIDsByChannel {
+		existing[channelID] = make(map[string]bool)
+		args := make([]interface{}, 0, len(messageIDs)+1)
+		args = append(args, channelID)
+		placeholders := ""
+		for _, messageID := range messageIDs {
+			args = append(args, messageID)
+			placeholders = placeholders + "?, "
+		}
+		foundIDs := make([]string, 0, len(messageIDs))
+		scanArgs := func() []interface{} {
+			foundIDs = append(foundIDs, "")
+			return []interface{}{&foundIDs[len(foundIDs)-1]}
+		}
+		err := queryRows(msgRepo.db, "SELECT messageId FROM message WHERE channelId like ? and messageId IN ("+placeholders[:len(placeholders)-2]+")", args2SliceFnWrapper(args...), scanArgs)
+		if err != nil {
+			return existing, err
+		}
+		for _, messageID := range foundIDs {
+			existing[channelID][messageID] = true
+		}
+	}
+	return existing, nil
+}
+
 // Get retrieves a message for a channel if it exists
 func (msgRepo *MessageDBRepository) Get(channelID string, messageID string) (*data.Message, error) {
 	channel, err := msgRepo.channelRepository.Get(channelID)
@@ -71,16 +196,20 @@ func (msgRepo *MessageDBRepository) Get(channelID string, messageID string) (*da
 func (msgRepo *MessageDBRepository) getSingleMessage(query string, queryArgs func() []interface{}, loadChannel bool) (message *data.Message, err error) {
 	var producerID string
 	var channelID string
+	var codec string
 	message = &data.Message{}
 	if err == nil {
 		err = querySingleRow(msgRepo.db, query, queryArgs,
-			args2SliceFnWrapper(&message.ID, &message.MessageID, &producerID, &channelID, &message.Payload, &message.ContentType, &message.Priority, &message.Status, &message.ReceivedAt, &message.OutboxedAt, &message.CreatedAt, &message.UpdatedAt))
+			args2SliceFnWrapper(&message.ID, &message.MessageID, &producerID, &channelID, &message.Payload, &codec, &message.PayloadRef, &message.RoutingKey, &message.Attributes, &message.CloudEvent, &message.ReceiptURL, &message.ContentType, &message.Priority, &message.Status, &message.ReceivedAt, &message.OutboxedAt, &message.CreatedAt, &message.UpdatedAt))
+	}
+	if err == nil {
+		message.Payload, err = decompressPayload(message.Payload, codec)
 	}
 	if err == nil {
-		message.ProducedBy, err = msgRepo.producerRepository.Get(producerID)
+		message.ProducedBy, err = msgRepo.producerRepository.GetIncludingDeleted(producerID)
 	}
 	if loadChannel && err == nil {
-		message.BroadcastedTo, err = msgRepo.channelRepository.Get(channelID)
+		message.BroadcastedTo, err = msgRepo.channelRepository.GetIncludingDeleted(channelID)
 	}
 	return message, err
 }
@@ -111,19 +240,25 @@ func (msgRepo *MessageDBRepository) SetDispatched(txContext context.Context, mes
 
 func (msgRepo *MessageDBRepository) getMessages(baseQuery string, args ...interface{}) ([]*data.Message, *data.Pagination, error) {
 	pageMessages := make([]*data.Message, 0, 100)
+	codecs := make([]*string, 0, 100)
 	newPage := &data.Pagination{}
 	scanArgs := func() []interface{} {
 		msg := &data.Message{}
 		msg.ProducedBy = &data.Producer{}
 		msg.BroadcastedTo = &data.Channel{}
+		codec := new(string)
 		pageMessages = append(pageMessages, msg)
-		return []interface{}{&msg.ID, &msg.MessageID, &msg.ProducedBy.ProducerID, &msg.BroadcastedTo.ChannelID, &msg.Payload, &msg.ContentType, &msg.Priority, &msg.Status, &msg.ReceivedAt, &msg.OutboxedAt, &msg.CreatedAt, &msg.UpdatedAt}
+		codecs = append(codecs, codec)
+		return []interface{}{&msg.ID, &msg.MessageID, &msg.ProducedBy.ProducerID, &msg.BroadcastedTo.ChannelID, &msg.Payload, codec, &msg.PayloadRef, &msg.RoutingKey, &msg.Attributes, &msg.CloudEvent, &msg.ReceiptURL, &msg.ContentType, &msg.Priority, &msg.Status, &msg.ReceivedAt, &msg.OutboxedAt, &msg.CreatedAt, &msg.UpdatedAt}
 	}
 	err := queryRows(msgRepo.db, baseQuery, args2SliceFnWrapper(args...), scanArgs)
+	for index := 0; err == nil && index < len(pageMessages); index++ {
+		pageMessages[index].Payload, err = decompressPayload(pageMessages[index].Payload, *codecs[index])
+	}
 	if err == nil {
 		for _, msg := range pageMessages {
-			msg.BroadcastedTo, _ = msgRepo.channelRepository.Get(msg.BroadcastedTo.ChannelID)
-			msg.ProducedBy, _ = msgRepo.producerRepository.Get(msg.ProducedBy.ProducerID)
+			msg.BroadcastedTo, _ = msgRepo.channelRepository.GetIncludingDeleted(msg.BroadcastedTo.ChannelID)
+			msg.ProducedBy, _ = msgRepo.producerRepository.GetIncludingDeleted(msg.ProducedBy.ProducerID)
 		}
 		msgCount := len(pageMessages)
 		if msgCount > 0 {
@@ -178,8 +313,131 @@ func (msgRepo *MessageDBRepository) GetMessagesForChannel(channelID string, page
 	return msgRepo.getMessages(baseQuery, appendWithPaginationArgs(page, channelID)...)
 }
 
+// getMessageFilterCriteria returns the where clause and its args to select messages matching the filter
+func getMessageFilterCriteria(filter *data.MessageFilter) (string, []interface{}) {
+	query := " channelId like ?"
+	args := []interface{}{filter.Channel.ChannelID}
+	if len(filter.Statuses) > 0 {
+		query = query + " AND status IN (?" + strings.Repeat(", ?", len(filter.Statuses)-1) + ")"
+		for _, status := range filter.Statuses {
+			args = append(args, status)
+		}
+	}
+	if len(filter.ProducerIDs) > 0 {
+		query = query + " AND producerId IN (?" + strings.Repeat(", ?", len(filter.ProducerIDs)-1) + ")"
+		for _, producerID := range filter.ProducerIDs {
+			args = append(args, producerID)
+		}
+	}
+	if !filter.ReceivedSince.IsZero() {
+		query = query + " AND receivedAt >= ?"
+		args = append(args, filter.ReceivedSince)
+	}
+	if !filter.ReceivedUntil.IsZero() {
+		query = query + " AND receivedAt <= ?"
+		args = append(args, filter.ReceivedUntil)
+	}
+	if len(filter.ContentType) > 0 {
+		query = query + " AND contentType like ? ESCAPE '!'"
+		args = append(args, escapeLikePattern(filter.ContentType)+"%")
+	}
+	if len(filter.Priorities) > 0 {
+		query = query + " AND priority IN (?" + strings.Repeat(", ?", len(filter.Priorities)-1) + ")"
+		for _, priority := range filter.Priorities {
+			args = append(args, priority)
+		}
+	}
+	if len(filter.MessageIDPrefix) > 0 {
+		query = query + " AND messageId like ? ESCAPE '!'"
+		args = append(args, escapeLikePattern(filter.MessageIDPrefix)+"%")
+	}
+	if filter.HasDeadJobs {
+		query = query + " AND EXISTS (SELECT 1 FROM job WHERE job.messageId = message.id AND job.status = ?)"
+		args = append(args, data.JobDead)
+	}
+	return query, args
+}
+
+// escapeLikePattern escapes the wildcards of LIKE, with `!` as the escape character, so that the value is matched literally
+func escapeLikePattern(value string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(value)
+}
+
+// SearchMessages retrieves messages broadcasted to a channel matching the filter
+func (msgRepo *MessageDBRepository) SearchMessages(filter *data.MessageFilter, page *data.Pagination) ([]*data.Message, *data.Pagination, error) {
+	if page == nil || (page.Next != nil && page.Previous != nil) {
+		return make([]*data.Message, 0), &data.Pagination{}, ErrPaginationDeadlock
+	}
+	if filter == nil || !filter.IsInValidState() {
+		return make([]*data.Message, 0), &data.Pagination{}, ErrInvalidStateToSave
+	}
+	criteria, args := getMessageFilterCriteria(filter)
+	baseQuery := messageSelectRowCommonQuery + criteria + getPaginationQueryFragmentWithConfigurablePageSize(page, true, pageSizeWithOrder)
+	return msgRepo.getMessages(baseQuery, appendWithPaginationArgs(page, args...)...)
+}
+
+// RecompressPayloads compresses, as per the configured codec, a batch of up to limit stored payloads with ID after afterID that are
+// yet to be; returns the ID of the last message in the batch, which is empty once there is none left to recompress. Recompression is run
+// once per cluster for a compression configuration: the pass, started with an empty afterID, is skipped if it was completed before or
+// another broker holds the lease on it. The owner's lease is renewed with every batch and released once there is none left, when the pass
+// is recorded as completed; ErrNoRowsUpdated is returned if the owner lost the lease meanwhile.
+func (msgRepo *MessageDBRepository) RecompressPayloads(owner string, afterID string, limit int) (lastID string, err error) {
+	if !msgRepo.compressor.IsEnabled() {
+		return "", nil
+	}
+	settings := msgRepo.compressor.settings()
+	currentTime := time.Now()
+	if len(afterID) <= 0 {
+		err = transactionalSingleRowWriteExec(msgRepo.db, emptyOps, "UPDATE app SET payloadRecompressionLeaseOwner = ?, payloadRecompressionLeaseExpiresAt = ? WHERE id = 1 AND payloadsRecompressedFor != ? AND (payloadRecompressionLeaseExpiresAt IS NULL OR payloadRecompressionLeaseExpiresAt < ?)",
+			args2SliceFnWrapper(owner, currentTime.Add(payloadRecompressionLease), settings, currentTime))
+		if err == ErrNoRowsUpdated {
+			return "", nil
+		}
+	} else {
+		err = transactionalSingleRowWriteExec(msgRepo.db, emptyOps, "UPDATE app SET payloadRecompressionLeaseExpiresAt = ? WHERE id = 1 AND payloadRecompressionLeaseOwner = ?",
+			args2SliceFnWrapper(currentTime.Add(payloadRecompressionLease), owner))
+	}
+	if err != nil {
+		return afterID, err
+	}
+	ids := make([]string, 0, limit)
+	payloads := make([]string, 0, limit)
+	codecs := make([]string, 0, limit)
+	scanArgs := func() []interface{} {
+		ids = append(ids, "")
+		payloads = append(payloads, "")
+		codecs = append(codecs, "")
+		index := len(ids) - 1
+		return []interface{}{&ids[index], &payloads[index], &codecs[index]}
+	}
+	targetCodec := msgRepo.compressor.storageCodec()
+	err = queryRows(msgRepo.db, payloadRecompressionQuery, args2SliceFnWrapper(afterID, targetCodec, uncompressedPayloadCodec, msgRepo.compressor.threshold, limit), scanArgs)
+	for index := 0; err == nil && index < len(ids); index++ {
+		lastID = ids[index]
+		payload, decompressErr := decompressPayload(payloads[index], codecs[index])
+		if decompressErr != nil {
+			log.Error().Err(decompressErr).Msg("error - could not decompress payload of message " + ids[index])
+			continue
+		}
+		var stored, codec string
+		stored, codec, err = msgRepo.compressor.compress(payload)
+		if err == nil {
+			err = transactionalSingleRowWriteExec(msgRepo.db, emptyOps, "UPDATE message SET payload = ?, payloadCodec = ? WHERE id like ? and payloadCodec = ?", args2SliceFnWrapper(stored, codec, ids[index], codecs[index]))
+		}
+		// Another broker recompressed it in the meantime
+		if err == ErrNoRowsUpdated {
+			err = nil
+		}
+	}
+	if err == nil && len(ids) <= 0 {
+		err = transactionalSingleRowWriteExec(msgRepo.db, emptyOps, "UPDATE app SET payloadsRecompressedFor = ?, payloadRecompressionLeaseOwner = '', payloadRecompressionLeaseExpiresAt = NULL WHERE id = 1 AND payloadRecompressionLeaseOwner = ?",
+			args2SliceFnWrapper(settings, owner))
+	}
+	return lastID, err
+}
+
 // NewMessageRepository creates a new instance of MessageRepository
-func NewMessageRepository(db *sql.DB, channelRepo ChannelRepository, producerRepo ProducerRepository) MessageRepository {
+func NewMessageRepository(db *sql.DB, channelRepo ChannelRepository, producerRepo ProducerRepository, compressor *PayloadCompressor) MessageRepository {
 	panicIfNoDBConnectionPool(db)
-	return &MessageDBRepository{db: db, channelRepository: channelRepo, producerRepository: producerRepo}
+	return &MessageDBRepository{db: db, channelRepository: channelRepo, producerRepository: producerRepo, compressor: compressor}
 }
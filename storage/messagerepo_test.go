@@ -13,6 +13,7 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/go-sql-driver/mysql"
 	sqlite "github.com/mattn/go-sqlite3"
+	"github.com/newscred/webhook-broker/config"
 	"github.com/newscred/webhook-broker/storage/data"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -36,7 +37,7 @@ func SetupForMessageTests() {
 }
 
 func getMessageRepository() MessageRepository {
-	return NewMessageRepository(testDB, NewChannelRepository(testDB), NewProducerRepository(testDB))
+	return NewMessageRepository(testDB, NewChannelRepository(testDB), NewProducerRepository(testDB), NewPayloadCompressor(configuration))
 }
 
 func TestMessageGetByID(t *testing.T) {
@@ -137,8 +138,8 @@ func TestMessageGetCreate(t *testing.T) {
 		msg, err := data.NewMessage(channel1, producer1, samplePayload, sampleContentType)
 		assert.Nil(t, err)
 		mockProducerRepository := new(MockProducerRepository)
-		repo := NewMessageRepository(testDB, NewChannelRepository(testDB), mockProducerRepository)
-		mockProducerRepository.On("Get", mock.Anything).Return(nil, expectedErr)
+		repo := NewMessageRepository(testDB, NewChannelRepository(testDB), mockProducerRepository, NewPayloadCompressor(configuration))
+		mockProducerRepository.On("GetIncludingDeleted", mock.Anything).Return(nil, expectedErr)
 		assert.Nil(t, repo.Create(msg))
 		_, err = repo.Get(channel1.ChannelID, msg.MessageID)
 		assert.NotNil(t, err)
@@ -176,6 +177,7 @@ func TestNormalizeMySQLError(t *testing.T) {
 	assert.Nil(t, normalizeDBError(nil, mysqlErrorMap))
 	assert.Equal(t, ErrDuplicateMessageIDForChannel, normalizeDBError(&sqlite.ErrConstraint, mysqlErrorMap))
 	assert.Equal(t, ErrDuplicateMessageIDForChannel, normalizeDBError(&sqlite.ErrConstraintUnique, mysqlErrorMap))
+	assert.Equal(t, ErrDuplicateMessageIDForChannel, normalizeDBError(sqlite.Error{Code: sqlite.ErrConstraint, ExtendedCode: sqlite.ErrConstraintUnique}, mysqlErrorMap))
 }
 
 func TestGetMessagesNotDispatchedForCertainPeriod(t *testing.T) {
@@ -203,7 +205,7 @@ func TestGetMessagesNotDispatchedForCertainPeriod(t *testing.T) {
 		errString := "sample select error"
 		expectedErr := errors.New(errString)
 		db, mock, _ := sqlmock.New()
-		msgRepo := NewMessageRepository(db, NewChannelRepository(testDB), NewProducerRepository(testDB))
+		msgRepo := NewMessageRepository(db, NewChannelRepository(testDB), NewProducerRepository(testDB), NewPayloadCompressor(configuration))
 		mock.ExpectQuery(messageSelectRowCommonQuery).WillReturnError(expectedErr)
 		mock.MatchExpectationsInOrder(true)
 		msgs := msgRepo.GetMessagesNotDispatchedForCertainPeriod(2 * time.Second)
@@ -258,3 +260,330 @@ func TestGetMessagesByChannel(t *testing.T) {
 		assert.Equal(t, sql.ErrNoRows, err)
 	})
 }
+
+func TestSearchMessages(t *testing.T) {
+	msgRepo := getMessageRepository()
+	searchChannel := createTestChannel("channel-for-message-search", "sampletoken", NewChannelRepository(testDB))
+	messages := createDispatchedMessagesForReplay(t, searchChannel, 3)
+	producer, _ := data.NewProducer("producer-for-message-search", successfulGetTestToken)
+	producer, err := NewProducerRepository(testDB).Store(producer)
+	assert.Nil(t, err)
+	jsonMessage, _ := data.NewMessage(searchChannel, producer, samplePayload, "application/json; charset=utf-8")
+	jsonMessage.MessageID = "order_1"
+	jsonMessage.Priority = 2
+	assert.Nil(t, msgRepo.Create(jsonMessage))
+	similarIDMessage, _ := data.NewMessage(searchChannel, producer1, samplePayload, sampleContentType)
+	similarIDMessage.MessageID = "orderX1"
+	assert.Nil(t, msgRepo.Create(similarIDMessage))
+	consumer, _ := data.NewConsumer(searchChannel, "message-search-consumer", "sometoken", callbackURL)
+	consumer, err = getConsumerRepo().Store(consumer)
+	assert.Nil(t, err)
+	createDeadJob(t, getDeliverJobRepository(), messages[0], consumer, "connection refused")
+	search := func(filter *data.MessageFilter) []string {
+		filter.Channel = searchChannel
+		result, _, err := msgRepo.SearchMessages(filter, data.NewPagination(nil, nil))
+		assert.Nil(t, err)
+		ids := make([]string, 0, len(result))
+		for _, message := range result {
+			ids = append(ids, message.MessageID)
+		}
+		return ids
+	}
+	t.Run("InvalidRequest", func(t *testing.T) {
+		_, _, err := msgRepo.SearchMessages(&data.MessageFilter{}, data.NewPagination(nil, nil))
+		assert.Equal(t, ErrInvalidStateToSave, err)
+		_, _, err = msgRepo.SearchMessages(&data.MessageFilter{Channel: searchChannel}, data.NewPagination(messages[0], messages[1]))
+		assert.Equal(t, ErrPaginationDeadlock, err)
+	})
+	t.Run("Filters", func(t *testing.T) {
+		assert.Equal(t, 5, len(search(&data.MessageFilter{})))
+		assert.ElementsMatch(t, []string{jsonMessage.MessageID, similarIDMessage.MessageID}, search(&data.MessageFilter{Statuses: []data.MsgStatus{data.MsgStatusAcknowledged}}))
+		assert.Equal(t, 3, len(search(&data.MessageFilter{Statuses: []data.MsgStatus{data.MsgStatusDispatched}})))
+		assert.Equal(t, []string{jsonMessage.MessageID}, search(&data.MessageFilter{ProducerIDs: []string{producer.ProducerID}}))
+		assert.Equal(t, []string{messages[1].MessageID}, search(&data.MessageFilter{ReceivedSince: time.Now().Add(-150 * time.Second), ReceivedUntil: time.Now().Add(-90 * time.Second)}))
+		assert.Equal(t, []string{jsonMessage.MessageID}, search(&data.MessageFilter{ContentType: "application/json"}))
+		assert.Equal(t, []string{jsonMessage.MessageID}, search(&data.MessageFilter{Priorities: []uint{2, 3}}))
+		assert.Equal(t, []string{jsonMessage.MessageID}, search(&data.MessageFilter{MessageIDPrefix: "order_"}))
+		assert.Equal(t, 2, len(search(&data.MessageFilter{MessageIDPrefix: "order"})))
+		assert.Equal(t, []string{messages[0].MessageID}, search(&data.MessageFilter{HasDeadJobs: true}))
+		assert.Empty(t, search(&data.MessageFilter{HasDeadJobs: true, Statuses: []data.MsgStatus{data.MsgStatusAcknowledged}}))
+	})
+}
+
+func TestMessagePayloadCompression(t *testing.T) {
+	compressionChannel := createTestChannel("channel-for-compression", "sampletoken", NewChannelRepository(testDB))
+	getCompressingRepository := func(codec config.PayloadCompressionCodec) MessageRepository {
+		return NewMessageRepository(testDB, NewChannelRepository(testDB), NewProducerRepository(testDB), &PayloadCompressor{codec: codec, threshold: 1024})
+	}
+	getStoredPayload := func(id string) (payload string, codec string) {
+		assert.Nil(t, querySingleRow(testDB, "SELECT payload, payloadCodec FROM message WHERE id like ?", args2SliceFnWrapper(id), args2SliceFnWrapper(&payload, &codec)))
+		return payload, codec
+	}
+	t.Run("TransparentRead", func(t *testing.T) {
+		repo := getCompressingRepository(config.ZstdPayloadCompression)
+		msg, _ := data.NewMessage(compressionChannel, producer1, largePayload, sampleContentType)
+		smallMsg, _ := data.NewMessage(compressionChannel, producer1, samplePayload, sampleContentType)
+		assert.Nil(t, repo.Create(msg))
+		assert.Nil(t, repo.Create(smallMsg))
+		assert.Nil(t, getDeliverJobRepository().DispatchMessage(msg))
+		stored, codec := getStoredPayload(msg.ID.String())
+		assert.Equal(t, string(config.ZstdPayloadCompression), codec)
+		assert.NotEqual(t, largePayload, stored)
+		stored, codec = getStoredPayload(smallMsg.ID.String())
+		assert.Equal(t, uncompressedPayloadCodec, codec)
+		assert.Equal(t, samplePayload, stored)
+		// Reads are independent of the configured codec
+		rMsg, err := getMessageRepository().GetByID(msg.ID.String())
+		assert.Nil(t, err)
+		assert.Equal(t, largePayload, rMsg.Payload)
+		rMsg, err = repo.Get(compressionChannel.ChannelID, msg.MessageID)
+		assert.Nil(t, err)
+		assert.Equal(t, largePayload, rMsg.Payload)
+		msgs, _, err := repo.GetMessagesForChannel(compressionChannel.ChannelID, data.NewPagination(nil, nil))
+		assert.Nil(t, err)
+		for _, pageMsg := range msgs {
+			if pageMsg.ID == msg.ID {
+				assert.Equal(t, largePayload, pageMsg.Payload)
+			} else {
+				assert.Equal(t, samplePayload, pageMsg.Payload)
+			}
+		}
+		consumer, _ := data.NewConsumer(compressionChannel, "compression-consumer", "sometoken", callbackURL)
+		consumer, err = getConsumerRepo().Store(consumer)
+		assert.Nil(t, err)
+		replay, _ := data.NewReplay(consumer, time.Time{}, time.Time{}, []string{msg.MessageID})
+		msgs, err = getReplayRepository().GetNextMessages(replay, 10)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(msgs))
+		assert.Equal(t, largePayload, msgs[0].Payload)
+	})
+	t.Run("Recompress", func(t *testing.T) {
+		msg, _ := data.NewMessage(compressionChannel, producer1, largePayload, sampleContentType)
+		assert.Nil(t, getMessageRepository().Create(msg))
+		zstdMsg, _ := data.NewMessage(compressionChannel, producer1, largePayload, sampleContentType)
+		assert.Nil(t, getCompressingRepository(config.ZstdPayloadCompression).Create(zstdMsg))
+		_, codec := getStoredPayload(msg.ID.String())
+		assert.Equal(t, uncompressedPayloadCodec, codec)
+		repo := getCompressingRepository(config.GzipPayloadCompression)
+		_, err := testDB.Exec("UPDATE app SET payloadsRecompressedFor = '', payloadRecompressionLeaseOwner = '', payloadRecompressionLeaseExpiresAt = NULL")
+		assert.Nil(t, err)
+		lastID, err := repo.RecompressPayloads("broker-1", "", 1)
+		assert.Nil(t, err)
+		assert.NotEmpty(t, lastID)
+		// Another broker does not recompress while the lease is held nor can it renew or release the lease
+		otherRepo := getCompressingRepository(config.GzipPayloadCompression)
+		otherLastID, err := otherRepo.RecompressPayloads("broker-2", "", 1)
+		assert.Nil(t, err)
+		assert.Empty(t, otherLastID)
+		_, otherErr := otherRepo.RecompressPayloads("broker-2", lastID, 1)
+		assert.Equal(t, ErrNoRowsUpdated, otherErr)
+		for len(lastID) > 0 && err == nil {
+			lastID, err = repo.RecompressPayloads("broker-1", lastID, 1)
+		}
+		assert.Nil(t, err)
+		var leaseOwner string
+		assert.Nil(t, testDB.QueryRow("SELECT payloadRecompressionLeaseOwner FROM app WHERE id = 1").Scan(&leaseOwner))
+		assert.Empty(t, leaseOwner)
+		for _, id := range []string{msg.ID.String(), zstdMsg.ID.String()} {
+			_, codec = getStoredPayload(id)
+			assert.Equal(t, string(config.GzipPayloadCompression), codec)
+			rMsg, err := getMessageRepository().GetByID(id)
+			assert.Nil(t, err)
+			assert.Equal(t, largePayload, rMsg.Payload)
+		}
+		// Recompression is not run again for the same configuration
+		uncompressedMsg, _ := data.NewMessage(compressionChannel, producer1, largePayload, sampleContentType)
+		assert.Nil(t, getMessageRepository().Create(uncompressedMsg))
+		lastID, err = repo.RecompressPayloads("broker-1", "", 10)
+		assert.Nil(t, err)
+		assert.Empty(t, lastID)
+		_, codec = getStoredPayload(uncompressedMsg.ID.String())
+		assert.Equal(t, uncompressedPayloadCodec, codec)
+		lastID, err = getMessageRepository().RecompressPayloads("broker-1", "", 10)
+		assert.Nil(t, err)
+		assert.Empty(t, lastID)
+	})
+	t.Run("Admit", func(t *testing.T) {
+		existing, _ := data.NewMessage(channel1, producer1, samplePayload, sampleContentType)
+		assert.Nil(t, getMessageRepository().Create(existing))
+		duplicate, _ := data.NewMessage(channel1, producer1, samplePayload, sampleContentType)
+		duplicate.MessageID = existing.MessageID
+		admitted, _ := data.NewMessage(channel1, producer1, samplePayload, sampleContentType)
+		rejected, _ := data.NewMessage(channel1, producer1, samplePayload, sampleContentType)
+		expectedErr := errors.New("not admitted")
+		admitCalls := make([]*data.Message, 0, 3)
+		errs := getMessageRepository().CreateBatch([]*data.Message{duplicate, admitted, rejected}, func(message *data.Message) error {
+			admitCalls = append(admitCalls, message)
+			if message == rejected {
+				return expectedErr
+			}
+			return nil
+		})
+		assert.Equal(t, []error{ErrDuplicateMessageIDForChannel, nil, expectedErr}, errs)
+		// Duplicates are not offered for admission
+		assert.Equal(t, []*data.Message{admitted, rejected}, admitCalls)
+		_, err := getMessageRepository().Get(channel1.ChannelID, admitted.MessageID)
+		assert.Nil(t, err)
+		_, err = getMessageRepository().Get(channel1.ChannelID, rejected.MessageID)
+		assert.NotNil(t, err)
+	})
+	t.Run("QueryError", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer db.Close()
+		expectedErr := errors.New("expected error")
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE app").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT id, payload, payloadCodec FROM message").WillReturnError(expectedErr)
+		repo := NewMessageRepository(db, NewChannelRepository(testDB), NewProducerRepository(testDB), &PayloadCompressor{codec: config.GzipPayloadCompression})
+		_, err := repo.RecompressPayloads("broker-1", "", 10)
+		assert.Equal(t, expectedErr, err)
+	})
+}
+
+func TestMessagePayloadRef(t *testing.T) {
+	msg, _ := data.NewMessage(channel1, producer1, "", sampleContentType)
+	assert.False(t, msg.IsInValidState())
+	msg.PayloadRef = "filesystem:offloaded-payload"
+	assert.True(t, msg.IsInValidState())
+	assert.Nil(t, getMessageRepository().Create(msg))
+	rMsg, err := getMessageRepository().GetByID(msg.ID.String())
+	assert.Nil(t, err)
+	assert.Equal(t, msg.PayloadRef, rMsg.PayloadRef)
+	assert.Empty(t, rMsg.Payload)
+	rMsg, err = getMessageRepository().Get(channel1.ChannelID, msg.MessageID)
+	assert.Nil(t, err)
+	assert.Equal(t, msg.PayloadRef, rMsg.PayloadRef)
+}
+
+func TestMessageRoutingKey(t *testing.T) {
+	msg, _ := data.NewMessage(channel1, producer1, samplePayload, sampleContentType)
+	msg.RoutingKey = "user.created.v2"
+	assert.Nil(t, getMessageRepository().Create(msg))
+	batchMsg, _ := data.NewMessage(channel1, producer1, samplePayload, sampleContentType)
+	batchMsg.RoutingKey = "user.deleted"
+	invalidMsg, _ := data.NewMessage(channel1, producer1, samplePayload, sampleContentType)
+	invalidMsg.RoutingKey = "user.*"
+	assert.Equal(t, []error{nil, data.ErrInsufficientInformationForCreating}, getMessageRepository().CreateBatch([]*data.Message{batchMsg, invalidMsg}, nil))
+	rMsg, err := getMessageRepository().GetByID(msg.ID.String())
+	assert.Nil(t, err)
+	assert.Equal(t, msg.RoutingKey, rMsg.RoutingKey)
+	rMsg, err = getMessageRepository().Get(channel1.ChannelID, batchMsg.MessageID)
+	assert.Nil(t, err)
+	assert.Equal(t, batchMsg.RoutingKey, rMsg.RoutingKey)
+	msgs, _, err := getMessageRepository().GetMessagesForChannel(channel1.ChannelID, data.NewPagination(nil, nil))
+	assert.Nil(t, err)
+	for _, pageMsg := range msgs {
+		if pageMsg.ID == msg.ID {
+			assert.Equal(t, msg.RoutingKey, pageMsg.RoutingKey)
+		}
+	}
+}
+
+func TestMessageAttributes(t *testing.T) {
+	msg, _ := data.NewMessage(channel1, producer1, samplePayload, sampleContentType)
+	msg.Attributes = data.MessageAttributes{"Tenant-Id": "tenant-1", "Trace": "abc"}
+	assert.Nil(t, getMessageRepository().Create(msg))
+	batchMsg, _ := data.NewMessage(channel1, producer1, samplePayload, sampleContentType)
+	batchMsg.Attributes = data.MessageAttributes{"Region": "eu"}
+	noAttributesMsg, _ := data.NewMessage(channel1, producer1, samplePayload, sampleContentType)
+	invalidMsg, _ := data.NewMessage(channel1, producer1, samplePayload, sampleContentType)
+	invalidMsg.Attributes = data.MessageAttributes{"Tenant Id": "tenant-1"}
+	assert.Equal(t, []error{nil, nil, data.ErrInsufficientInformationForCreating}, getMessageRepository().CreateBatch([]*data.Message{batchMsg, noAttributesMsg, invalidMsg}, nil))
+	rMsg, err := getMessageRepository().GetByID(msg.ID.String())
+	assert.Nil(t, err)
+	assert.Equal(t, msg.Attributes, rMsg.Attributes)
+	rMsg, err = getMessageRepository().Get(channel1.ChannelID, batchMsg.MessageID)
+	assert.Nil(t, err)
+	assert.Equal(t, batchMsg.Attributes, rMsg.Attributes)
+	rMsg, err = getMessageRepository().Get(channel1.ChannelID, noAttributesMsg.MessageID)
+	assert.Nil(t, err)
+	assert.Nil(t, rMsg.Attributes)
+	msgs, _, err := getMessageRepository().GetMessagesForChannel(channel1.ChannelID, data.NewPagination(nil, nil))
+	assert.Nil(t, err)
+	for _, pageMsg := range msgs {
+		if pageMsg.ID == msg.ID {
+			assert.Equal(t, msg.Attributes, pageMsg.Attributes)
+		}
+	}
+}
+
+func TestMessageCloudEvent(t *testing.T) {
+	msg, _ := data.NewMessage(channel1, producer1, samplePayload, sampleContentType)
+	msg.CloudEvent = data.CloudEvent{Type: "com.example.created", Source: "/example", Subject: "item-1", Time: time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)}
+	assert.Nil(t, getMessageRepository().Create(msg))
+	batchMsg, _ := data.NewMessage(channel1, producer1, samplePayload, sampleContentType)
+	batchMsg.CloudEvent = data.CloudEvent{Type: "com.example.updated", Source: "/example"}
+	assert.Equal(t, []error{nil}, getMessageRepository().CreateBatch([]*data.Message{batchMsg}, nil))
+	rMsg, err := getMessageRepository().GetByID(msg.ID.String())
+	assert.Nil(t, err)
+	assert.Equal(t, msg.CloudEvent, rMsg.CloudEvent)
+	rMsg, err = getMessageRepository().Get(channel1.ChannelID, batchMsg.MessageID)
+	assert.Nil(t, err)
+	assert.Equal(t, batchMsg.CloudEvent, rMsg.CloudEvent)
+	invalidMsg, _ := data.NewMessage(channel1, producer1, samplePayload, sampleContentType)
+	invalidMsg.CloudEvent = data.CloudEvent{Subject: "item-1"}
+	assert.Equal(t, data.ErrInsufficientInformationForCreating, getMessageRepository().Create(invalidMsg))
+}
+
+func TestMessageCreateBatch(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		existing, _ := data.NewMessage(channel1, producer1, samplePayload, sampleContentType)
+		assert.Nil(t, getMessageRepository().Create(existing))
+		batchSize := messageBatchChunkSize*2 + 3
+		messages := make([]*data.Message, 0, batchSize)
+		for index := 0; index < batchSize; index++ {
+			msg, _ := data.NewMessage(channel1, producer1, samplePayload, sampleContentType)
+			messages = append(messages, msg)
+		}
+		// Duplicate of a stored message, of one in the same batch and an invalid message
+		messages[1].MessageID = existing.MessageID
+		messages[messageBatchChunkSize+1].MessageID = messages[messageBatchChunkSize].MessageID
+		messages[batchSize-1].Payload = ""
+		errs := getMessageRepository().CreateBatch(messages, nil)
+		assert.Equal(t, batchSize, len(errs))
+		for index, err := range errs {
+			switch index {
+			case 1, messageBatchChunkSize + 1:
+				assert.Equal(t, ErrDuplicateMessageIDForChannel, err)
+			case batchSize - 1:
+				assert.Equal(t, data.ErrInsufficientInformationForCreating, err)
+			default:
+				assert.Nil(t, err)
+				rMsg, getErr := getMessageRepository().Get(channel1.ChannelID, messages[index].MessageID)
+				assert.Nil(t, getErr)
+				assert.Equal(t, messages[index].ID, rMsg.ID)
+			}
+		}
+		rMsg, err := getMessageRepository().Get(channel1.ChannelID, existing.MessageID)
+		assert.Nil(t, err)
+		assert.Equal(t, existing.ID, rMsg.ID)
+		assert.Equal(t, 0, len(getMessageRepository().CreateBatch(nil, nil)))
+	})
+	t.Run("QueryError", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer db.Close()
+		expectedErr := errors.New("expected error")
+		mock.ExpectQuery("SELECT messageId FROM message").WillReturnError(expectedErr)
+		repo := NewMessageRepository(db, NewChannelRepository(testDB), NewProducerRepository(testDB), nil)
+		msg, _ := data.NewMessage(channel1, producer1, samplePayload, sampleContentType)
+		errs := repo.CreateBatch([]*data.Message{msg, nil}, nil)
+		assert.Equal(t, []error{expectedErr, data.ErrInsufficientInformationForCreating}, errs)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+	t.Run("InsertError", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		defer db.Close()
+		expectedErr := errors.New("expected error")
+		mock.ExpectQuery("SELECT messageId FROM message").WillReturnRows(sqlmock.NewRows([]string{"messageId"}))
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO message").WillReturnError(expectedErr)
+		mock.ExpectRollback()
+		repo := NewMessageRepository(db, NewChannelRepository(testDB), NewProducerRepository(testDB), nil)
+		msg1, _ := data.NewMessage(channel1, producer1, samplePayload, sampleContentType)
+		msg2, _ := data.NewMessage(channel1, producer1, samplePayload, sampleContentType)
+		errs := repo.CreateBatch([]*data.Message{msg1, msg2}, nil)
+		assert.Equal(t, []error{expectedErr, expectedErr}, errs)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+}
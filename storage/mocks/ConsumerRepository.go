@@ -12,13 +12,13 @@ type ConsumerRepository struct {
 	mock.Mock
 }
 
-// Delete provides a mock function with given fields: consumer
-func (_m *ConsumerRepository) Delete(consumer *data.Consumer) error {
-	ret := _m.Called(consumer)
+// Delete provides a mock function with given fields: consumer, drain
+func (_m *ConsumerRepository) Delete(consumer *data.Consumer, drain bool) error {
+	ret := _m.Called(consumer, drain)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*data.Consumer) error); ok {
-		r0 = rf(consumer)
+	if rf, ok := ret.Get(0).(func(*data.Consumer, bool) error); ok {
+		r0 = rf(consumer, drain)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -72,6 +72,29 @@ func (_m *ConsumerRepository) GetByID(id string) (*data.Consumer, error) {
 	return r0, r1
 }
 
+// GetIncludingDeleted provides a mock function with given fields: channelID, consumerID
+func (_m *ConsumerRepository) GetIncludingDeleted(channelID string, consumerID string) (*data.Consumer, error) {
+	ret := _m.Called(channelID, consumerID)
+
+	var r0 *data.Consumer
+	if rf, ok := ret.Get(0).(func(string, string) *data.Consumer); ok {
+		r0 = rf(channelID, consumerID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*data.Consumer)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(channelID, consumerID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetList provides a mock function with given fields: channelID, page
 func (_m *ConsumerRepository) GetList(channelID string, page *data.Pagination) ([]*data.Consumer, *data.Pagination, error) {
 	ret := _m.Called(channelID, page)
@@ -104,6 +127,48 @@ func (_m *ConsumerRepository) GetList(channelID string, page *data.Pagination) (
 	return r0, r1, r2
 }
 
+// MarkVerified provides a mock function with given fields: consumer, challenge
+func (_m *ConsumerRepository) MarkVerified(consumer *data.Consumer, challenge string) error {
+	ret := _m.Called(consumer, challenge)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*data.Consumer, string) error); ok {
+		r0 = rf(consumer, challenge)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Purge provides a mock function with given fields: consumer
+func (_m *ConsumerRepository) Purge(consumer *data.Consumer) error {
+	ret := _m.Called(consumer)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*data.Consumer) error); ok {
+		r0 = rf(consumer)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetPaused provides a mock function with given fields: consumer, paused
+func (_m *ConsumerRepository) SetPaused(consumer *data.Consumer, paused bool) error {
+	ret := _m.Called(consumer, paused)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*data.Consumer, bool) error); ok {
+		r0 = rf(consumer, paused)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Store provides a mock function with given fields: consumer
 func (_m *ConsumerRepository) Store(consumer *data.Consumer) (*data.Consumer, error) {
 	ret := _m.Called(consumer)
@@ -128,3 +128,67 @@ func (_m *DataAccessor) GetProducerRepository() storage.ProducerRepository {
 
 	return r0
 }
+
+// GetPublishGrantRepository provides a mock function with given fields:
+func (_m *DataAccessor) GetPublishGrantRepository() storage.PublishGrantRepository {
+	ret := _m.Called()
+
+	var r0 storage.PublishGrantRepository
+	if rf, ok := ret.Get(0).(func() storage.PublishGrantRepository); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(storage.PublishGrantRepository)
+		}
+	}
+
+	return r0
+}
+
+// GetPublishQuotaRepository provides a mock function with given fields:
+func (_m *DataAccessor) GetPublishQuotaRepository() storage.PublishQuotaRepository {
+	ret := _m.Called()
+
+	var r0 storage.PublishQuotaRepository
+	if rf, ok := ret.Get(0).(func() storage.PublishQuotaRepository); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(storage.PublishQuotaRepository)
+		}
+	}
+
+	return r0
+}
+
+// GetReplayRepository provides a mock function with given fields:
+func (_m *DataAccessor) GetReplayRepository() storage.ReplayRepository {
+	ret := _m.Called()
+
+	var r0 storage.ReplayRepository
+	if rf, ok := ret.Get(0).(func() storage.ReplayRepository); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(storage.ReplayRepository)
+		}
+	}
+
+	return r0
+}
+
+// GetSchemaRepository provides a mock function with given fields:
+func (_m *DataAccessor) GetSchemaRepository() storage.SchemaRepository {
+	ret := _m.Called()
+
+	var r0 storage.SchemaRepository
+	if rf, ok := ret.Get(0).(func() storage.SchemaRepository); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(storage.SchemaRepository)
+		}
+	}
+
+	return r0
+}
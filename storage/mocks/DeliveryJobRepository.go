@@ -14,6 +14,48 @@ type DeliveryJobRepository struct {
 	mock.Mock
 }
 
+// BackfillJobsForConsumer provides a mock function with given fields: backfill, start, interval, leaseDuration
+func (_m *DeliveryJobRepository) BackfillJobsForConsumer(backfill *data.Backfill, start time.Time, interval time.Duration, leaseDuration time.Duration) error {
+	ret := _m.Called(backfill, start, interval, leaseDuration)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*data.Backfill, time.Time, time.Duration, time.Duration) error); ok {
+		r0 = rf(backfill, start, interval, leaseDuration)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateBackfill provides a mock function with given fields: backfill
+func (_m *DeliveryJobRepository) CreateBackfill(backfill *data.Backfill) error {
+	ret := _m.Called(backfill)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*data.Backfill) error); ok {
+		r0 = rf(backfill)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DiscardDeadJobs provides a mock function with given fields: filter
+func (_m *DeliveryJobRepository) DiscardDeadJobs(filter *data.DeadJobFilter) error {
+	ret := _m.Called(filter)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*data.DeadJobFilter) error); ok {
+		r0 = rf(filter)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // DispatchMessage provides a mock function with given fields: message, deliveryJobs
 func (_m *DeliveryJobRepository) DispatchMessage(message *data.Message, deliveryJobs ...*data.DeliveryJob) error {
 	_va := make([]interface{}, len(deliveryJobs))
@@ -35,6 +77,45 @@ func (_m *DeliveryJobRepository) DispatchMessage(message *data.Message, delivery
 	return r0
 }
 
+// GetBackfillForConsumer provides a mock function with given fields: consumer
+func (_m *DeliveryJobRepository) GetBackfillForConsumer(consumer *data.Consumer) (*data.Backfill, error) {
+	ret := _m.Called(consumer)
+
+	var r0 *data.Backfill
+	if rf, ok := ret.Get(0).(func(*data.Consumer) *data.Backfill); ok {
+		r0 = rf(consumer)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*data.Backfill)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*data.Consumer) error); ok {
+		r1 = rf(consumer)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBackfillsToRun provides a mock function with given fields:
+func (_m *DeliveryJobRepository) GetBackfillsToRun() []*data.Backfill {
+	ret := _m.Called()
+
+	var r0 []*data.Backfill
+	if rf, ok := ret.Get(0).(func() []*data.Backfill); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*data.Backfill)
+		}
+	}
+
+	return r0
+}
+
 // GetByID provides a mock function with given fields: id
 func (_m *DeliveryJobRepository) GetByID(id string) (*data.DeliveryJob, error) {
 	ret := _m.Called(id)
@@ -58,6 +139,84 @@ func (_m *DeliveryJobRepository) GetByID(id string) (*data.DeliveryJob, error) {
 	return r0, r1
 }
 
+// GetDeadJobs provides a mock function with given fields: filter, page
+func (_m *DeliveryJobRepository) GetDeadJobs(filter *data.DeadJobFilter, page *data.Pagination) ([]*data.DeliveryJob, *data.Pagination, error) {
+	ret := _m.Called(filter, page)
+
+	var r0 []*data.DeliveryJob
+	if rf, ok := ret.Get(0).(func(*data.DeadJobFilter, *data.Pagination) []*data.DeliveryJob); ok {
+		r0 = rf(filter, page)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*data.DeliveryJob)
+		}
+	}
+
+	var r1 *data.Pagination
+	if rf, ok := ret.Get(1).(func(*data.DeadJobFilter, *data.Pagination) *data.Pagination); ok {
+		r1 = rf(filter, page)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*data.Pagination)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(*data.DeadJobFilter, *data.Pagination) error); ok {
+		r2 = rf(filter, page)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetJobStatsForChannel provides a mock function with given fields: channel
+func (_m *DeliveryJobRepository) GetJobStatsForChannel(channel *data.Channel) (*data.JobStats, error) {
+	ret := _m.Called(channel)
+
+	var r0 *data.JobStats
+	if rf, ok := ret.Get(0).(func(*data.Channel) *data.JobStats); ok {
+		r0 = rf(channel)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*data.JobStats)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*data.Channel) error); ok {
+		r1 = rf(channel)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetJobStatsForConsumer provides a mock function with given fields: consumer
+func (_m *DeliveryJobRepository) GetJobStatsForConsumer(consumer *data.Consumer) (*data.JobStats, error) {
+	ret := _m.Called(consumer)
+
+	var r0 *data.JobStats
+	if rf, ok := ret.Get(0).(func(*data.Consumer) *data.JobStats); ok {
+		r0 = rf(consumer)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*data.JobStats)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*data.Consumer) error); ok {
+		r1 = rf(consumer)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetJobsForConsumer provides a mock function with given fields: consumer, jobStatus, page
 func (_m *DeliveryJobRepository) GetJobsForConsumer(consumer *data.Consumer, jobStatus data.JobStatus, page *data.Pagination) ([]*data.DeliveryJob, *data.Pagination, error) {
 	ret := _m.Called(consumer, jobStatus, page)
@@ -122,6 +281,29 @@ func (_m *DeliveryJobRepository) GetJobsForMessage(message *data.Message, page *
 	return r0, r1, r2
 }
 
+// GetJobsForMessages provides a mock function with given fields: messages
+func (_m *DeliveryJobRepository) GetJobsForMessages(messages []*data.Message) ([]*data.DeliveryJob, error) {
+	ret := _m.Called(messages)
+
+	var r0 []*data.DeliveryJob
+	if rf, ok := ret.Get(0).(func([]*data.Message) []*data.DeliveryJob); ok {
+		r0 = rf(messages)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*data.DeliveryJob)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func([]*data.Message) error); ok {
+		r1 = rf(messages)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetJobsInflightSince provides a mock function with given fields: delta
 func (_m *DeliveryJobRepository) GetJobsInflightSince(delta time.Duration) []*data.DeliveryJob {
 	ret := _m.Called(delta)
@@ -138,6 +320,29 @@ func (_m *DeliveryJobRepository) GetJobsInflightSince(delta time.Duration) []*da
 	return r0
 }
 
+// GetJobsReadyForConsumer provides a mock function with given fields: consumer, limit
+func (_m *DeliveryJobRepository) GetJobsReadyForConsumer(consumer *data.Consumer, limit int) ([]*data.DeliveryJob, error) {
+	ret := _m.Called(consumer, limit)
+
+	var r0 []*data.DeliveryJob
+	if rf, ok := ret.Get(0).(func(*data.Consumer, int) []*data.DeliveryJob); ok {
+		r0 = rf(consumer, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*data.DeliveryJob)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*data.Consumer, int) error); ok {
+		r1 = rf(consumer, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetJobsReadyForInflightSince provides a mock function with given fields: delta
 func (_m *DeliveryJobRepository) GetJobsReadyForInflightSince(delta time.Duration) []*data.DeliveryJob {
 	ret := _m.Called(delta)
@@ -154,6 +359,41 @@ func (_m *DeliveryJobRepository) GetJobsReadyForInflightSince(delta time.Duratio
 	return r0
 }
 
+// GetUndeliveredJobCount provides a mock function with given fields: message
+func (_m *DeliveryJobRepository) GetUndeliveredJobCount(message *data.Message) (uint, error) {
+	ret := _m.Called(message)
+
+	var r0 uint
+	if rf, ok := ret.Get(0).(func(*data.Message) uint); ok {
+		r0 = rf(message)
+	} else {
+		r0 = ret.Get(0).(uint)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*data.Message) error); ok {
+		r1 = rf(message)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LeaseBackfill provides a mock function with given fields: backfill, owner, duration
+func (_m *DeliveryJobRepository) LeaseBackfill(backfill *data.Backfill, owner string, duration time.Duration) error {
+	ret := _m.Called(backfill, owner, duration)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*data.Backfill, string, time.Duration) error); ok {
+		r0 = rf(backfill, owner, duration)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // MarkJobDead provides a mock function with given fields: deliveryJob
 func (_m *DeliveryJobRepository) MarkJobDead(deliveryJob *data.DeliveryJob) error {
 	ret := _m.Called(deliveryJob)
@@ -210,6 +450,20 @@ func (_m *DeliveryJobRepository) MarkJobRetry(deliveryJob *data.DeliveryJob, ear
 	return r0
 }
 
+// RequeueDeadJobs provides a mock function with given fields: filter
+func (_m *DeliveryJobRepository) RequeueDeadJobs(filter *data.DeadJobFilter) error {
+	ret := _m.Called(filter)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*data.DeadJobFilter) error); ok {
+		r0 = rf(filter)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // RequeueDeadJobsForConsumer provides a mock function with given fields: consumer
 func (_m *DeliveryJobRepository) RequeueDeadJobsForConsumer(consumer *data.Consumer) error {
 	ret := _m.Called(consumer)
@@ -223,3 +477,17 @@ func (_m *DeliveryJobRepository) RequeueDeadJobsForConsumer(consumer *data.Consu
 
 	return r0
 }
+
+// RescheduleQueuedJobsForConsumer provides a mock function with given fields: consumer, start, interval
+func (_m *DeliveryJobRepository) RescheduleQueuedJobsForConsumer(consumer *data.Consumer, start time.Time, interval time.Duration) error {
+	ret := _m.Called(consumer, start, interval)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*data.Consumer, time.Time, time.Duration) error); ok {
+		r0 = rf(consumer, start, interval)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
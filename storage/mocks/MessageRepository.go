@@ -30,6 +30,22 @@ func (_m *MessageRepository) Create(message *data.Message) error {
 	return r0
 }
 
+// CreateBatch provides a mock function with given fields: messages, admit
+func (_m *MessageRepository) CreateBatch(messages []*data.Message, admit func(*data.Message) error) []error {
+	ret := _m.Called(messages, admit)
+
+	var r0 []error
+	if rf, ok := ret.Get(0).(func([]*data.Message, func(*data.Message) error) []error); ok {
+		r0 = rf(messages, admit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]error)
+		}
+	}
+
+	return r0
+}
+
 // Get provides a mock function with given fields: channelID, messageID
 func (_m *MessageRepository) Get(channelID string, messageID string) (*data.Message, error) {
 	ret := _m.Called(channelID, messageID)
@@ -124,6 +140,59 @@ func (_m *MessageRepository) GetMessagesNotDispatchedForCertainPeriod(delta time
 	return r0
 }
 
+// RecompressPayloads provides a mock function with given fields: owner, afterID, limit
+func (_m *MessageRepository) RecompressPayloads(owner string, afterID string, limit int) (string, error) {
+	ret := _m.Called(owner, afterID, limit)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string, string, int) string); ok {
+		r0 = rf(owner, afterID, limit)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, int) error); ok {
+		r1 = rf(owner, afterID, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SearchMessages provides a mock function with given fields: filter, page
+func (_m *MessageRepository) SearchMessages(filter *data.MessageFilter, page *data.Pagination) ([]*data.Message, *data.Pagination, error) {
+	ret := _m.Called(filter, page)
+
+	var r0 []*data.Message
+	if rf, ok := ret.Get(0).(func(*data.MessageFilter, *data.Pagination) []*data.Message); ok {
+		r0 = rf(filter, page)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*data.Message)
+		}
+	}
+
+	var r1 *data.Pagination
+	if rf, ok := ret.Get(1).(func(*data.MessageFilter, *data.Pagination) *data.Pagination); ok {
+		r1 = rf(filter, page)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*data.Pagination)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(*data.MessageFilter, *data.Pagination) error); ok {
+		r2 = rf(filter, page)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // SetDispatched provides a mock function with given fields: txContext, message
 func (_m *MessageRepository) SetDispatched(txContext context.Context, message *data.Message) error {
 	ret := _m.Called(txContext, message)
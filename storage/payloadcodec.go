@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"strconv"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/newscred/webhook-broker/config"
+)
+
+const (
+	// uncompressedPayloadCodec is the codec marker for payloads stored as is
+	uncompressedPayloadCodec = ""
+)
+
+var (
+	// ErrUnknownPayloadCodec is returned when a stored payload is marked with a codec the broker does not know how to decompress
+	ErrUnknownPayloadCodec = errors.New("unknown payload codec")
+	zstdEncoder            *zstd.Encoder
+	zstdDecoder            *zstd.Decoder
+	zstdInitializer        sync.Once
+)
+
+// PayloadCompressor compresses message payloads above a threshold before they are written to storage
+type PayloadCompressor struct {
+	codec     config.PayloadCompressionCodec
+	threshold uint
+}
+
+// IsEnabled returns whether payloads are compressed at all
+func (compressor *PayloadCompressor) IsEnabled() bool {
+	return compressor != nil && compressor.codec != config.NoPayloadCompression && len(compressor.codec) > 0
+}
+
+// storageCodec returns the codec marker for payloads compressed by this compressor
+func (compressor *PayloadCompressor) storageCodec() string {
+	if !compressor.IsEnabled() {
+		return uncompressedPayloadCodec
+	}
+	return string(compressor.codec)
+}
+
+// settings returns the codec and the threshold of the compressor, that stored payloads are recompressed for
+func (compressor *PayloadCompressor) settings() string {
+	return compressor.storageCodec() + ":" + strconv.FormatUint(uint64(compressor.threshold), 10)
+}
+
+// compress returns the payload as it is to be stored along with its codec marker
+func (compressor *PayloadCompressor) compress(payload string) (string, string, error) {
+	if !compressor.IsEnabled() || uint(len(payload)) <= compressor.threshold {
+		return payload, uncompressedPayloadCodec, nil
+	}
+	var compressed []byte
+	switch compressor.codec {
+	case config.GzipPayloadCompression:
+		buffer := &bytes.Buffer{}
+		writer := gzip.NewWriter(buffer)
+		if _, err := writer.Write([]byte(payload)); err != nil {
+			return payload, uncompressedPayloadCodec, err
+		}
+		if err := writer.Close(); err != nil {
+			return payload, uncompressedPayloadCodec, err
+		}
+		compressed = buffer.Bytes()
+	case config.ZstdPayloadCompression:
+		initZstd()
+		compressed = zstdEncoder.EncodeAll([]byte(payload), nil)
+	default:
+		return payload, uncompressedPayloadCodec, ErrUnknownPayloadCodec
+	}
+	// Text columns can not hold binary content portably, hence the base64 encoding
+	return base64.StdEncoding.EncodeToString(compressed), string(compressor.codec), nil
+}
+
+// decompressPayload returns the original payload from its stored form; codec being the marker stored with it
+func decompressPayload(stored string, codec string) (string, error) {
+	if codec == uncompressedPayloadCodec {
+		return stored, nil
+	}
+	compressed, err := base64.StdEncoding.DecodeString(stored)
+	if err != nil {
+		return stored, err
+	}
+	var payload []byte
+	switch config.PayloadCompressionCodec(codec) {
+	case config.GzipPayloadCompression:
+		var reader *gzip.Reader
+		reader, err = gzip.NewReader(bytes.NewReader(compressed))
+		if err == nil {
+			defer reader.Close()
+			payload, err = ioutil.ReadAll(reader)
+		}
+	case config.ZstdPayloadCompression:
+		initZstd()
+		payload, err = zstdDecoder.DecodeAll(compressed, nil)
+	default:
+		err = ErrUnknownPayloadCodec
+	}
+	if err != nil {
+		return stored, err
+	}
+	return string(payload), nil
+}
+
+func initZstd() {
+	zstdInitializer.Do(func() {
+		// Neither can error without options
+		zstdEncoder, _ = zstd.NewWriter(nil)
+		zstdDecoder, _ = zstd.NewReader(nil)
+	})
+}
+
+// NewPayloadCompressor creates a new PayloadCompressor as per the DB configuration
+func NewPayloadCompressor(dbConfig config.RelationalDatabaseConfig) *PayloadCompressor {
+	return &PayloadCompressor{codec: dbConfig.GetPayloadCompressionCodec(), threshold: dbConfig.GetPayloadCompressionThreshold()}
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/newscred/webhook-broker/config"
+	configmocks "github.com/newscred/webhook-broker/config/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+var largePayload = `{"event": "compressible", "data": "` + strings.Repeat("compressible json event ", 100) + `"}`
+
+func TestNewPayloadCompressor(t *testing.T) {
+	dbConfig := new(configmocks.RelationalDatabaseConfig)
+	dbConfig.On("GetPayloadCompressionCodec").Return(config.ZstdPayloadCompression)
+	dbConfig.On("GetPayloadCompressionThreshold").Return(uint(1024))
+	compressor := NewPayloadCompressor(dbConfig)
+	assert.True(t, compressor.IsEnabled())
+	assert.Equal(t, config.ZstdPayloadCompression, compressor.codec)
+	assert.Equal(t, uint(1024), compressor.threshold)
+	dbConfig.AssertExpectations(t)
+	assert.False(t, (&PayloadCompressor{codec: config.NoPayloadCompression}).IsEnabled())
+	var nilCompressor *PayloadCompressor
+	assert.False(t, nilCompressor.IsEnabled())
+}
+
+func TestPayloadCompression(t *testing.T) {
+	for _, codec := range []config.PayloadCompressionCodec{config.GzipPayloadCompression, config.ZstdPayloadCompression} {
+		t.Run(string(codec), func(t *testing.T) {
+			compressor := &PayloadCompressor{codec: codec, threshold: 100}
+			stored, storedCodec, err := compressor.compress(largePayload)
+			assert.Nil(t, err)
+			assert.Equal(t, string(codec), storedCodec)
+			assert.Less(t, len(stored), len(largePayload))
+			payload, err := decompressPayload(stored, storedCodec)
+			assert.Nil(t, err)
+			assert.Equal(t, largePayload, payload)
+			// Payloads within threshold are stored as is
+			stored, storedCodec, err = compressor.compress(samplePayload)
+			assert.Nil(t, err)
+			assert.Equal(t, uncompressedPayloadCodec, storedCodec)
+			assert.Equal(t, samplePayload, stored)
+		})
+	}
+	t.Run("Disabled", func(t *testing.T) {
+		var compressor *PayloadCompressor
+		stored, storedCodec, err := compressor.compress(largePayload)
+		assert.Nil(t, err)
+		assert.Equal(t, uncompressedPayloadCodec, storedCodec)
+		assert.Equal(t, largePayload, stored)
+		payload, err := decompressPayload(stored, storedCodec)
+		assert.Nil(t, err)
+		assert.Equal(t, largePayload, payload)
+	})
+	t.Run("UnknownCodec", func(t *testing.T) {
+		_, _, err := (&PayloadCompressor{codec: "lz4"}).compress(largePayload)
+		assert.Equal(t, ErrUnknownPayloadCodec, err)
+		_, err = decompressPayload("c29tZSBwYXlsb2Fk", "lz4")
+		assert.Equal(t, ErrUnknownPayloadCodec, err)
+	})
+	t.Run("CorruptPayload", func(t *testing.T) {
+		_, err := decompressPayload("not base64!", string(config.GzipPayloadCompression))
+		assert.NotNil(t, err)
+		_, err = decompressPayload("c29tZSBwYXlsb2Fk", string(config.GzipPayloadCompression))
+		assert.NotNil(t, err)
+		_, err = decompressPayload("c29tZSBwYXlsb2Fk", string(config.ZstdPayloadCompression))
+		assert.NotNil(t, err)
+	})
+}
@@ -12,28 +12,38 @@ type ProducerDBRepository struct {
 	db *sql.DB
 }
 
-// Store either creates or updates the producer information
+const (
+	producerSelectRowCommonQuery = "SELECT id, producerId, name, token, receiptUrl, deletedAt, createdAt, updatedAt FROM producer WHERE"
+	producerMessagesCriteria     = " producerId like ?"
+	producerJobsCriteria         = " messageId IN (SELECT id FROM message WHERE producerId like ?)"
+)
+
+// Store either creates or updates the producer information; a deleted producer can not be stored until it is purged
 func (repo *ProducerDBRepository) Store(producer *data.Producer) (*data.Producer, error) {
-	inProducer, err := repo.Get(producer.ProducerID)
+	inProducer, err := repo.GetIncludingDeleted(producer.ProducerID)
 	if err != nil {
 		return repo.insertProducer(producer)
 	}
-	if producer.Name != inProducer.Name || producer.Token != inProducer.Token {
+	if inProducer.IsDeleted() {
+		return inProducer, ErrDeleted
+	}
+	if producer.Name != inProducer.Name || producer.Token != inProducer.Token || producer.ReceiptURL != inProducer.ReceiptURL {
 		if !producer.IsInValidState() {
 			return &data.Producer{}, ErrInvalidStateToSave
 		}
-		return repo.updateProducer(inProducer, producer.Name, producer.Token)
+		return repo.updateProducer(inProducer, producer.Name, producer.Token, producer.ReceiptURL)
 	}
 	return inProducer, err
 }
 
-func (repo *ProducerDBRepository) updateProducer(producer *data.Producer, name, token string) (*data.Producer, error) {
+func (repo *ProducerDBRepository) updateProducer(producer *data.Producer, name, token, receiptURL string) (*data.Producer, error) {
 	err := transactionalSingleRowWriteExec(repo.db, func() {
 		producer.Name = name
 		producer.Token = token
+		producer.ReceiptURL = receiptURL
 		producer.UpdatedAt = time.Now()
-	}, "UPDATE producer SET name = ?, token = ?, updatedAt = ? WHERE producerId = ?",
-		args2SliceFnWrapper(&producer.Name, &producer.Token, &producer.UpdatedAt, producer.ProducerID))
+	}, "UPDATE producer SET name = ?, token = ?, receiptUrl = ?, updatedAt = ? WHERE producerId = ?",
+		args2SliceFnWrapper(&producer.Name, &producer.Token, &producer.ReceiptURL, &producer.UpdatedAt, producer.ProducerID))
 	return producer, err
 }
 
@@ -42,31 +52,66 @@ func (repo *ProducerDBRepository) insertProducer(producer *data.Producer) (*data
 	if !producer.IsInValidState() {
 		return producer, ErrInvalidStateToSave
 	}
-	err := transactionalSingleRowWriteExec(repo.db, emptyOps, "INSERT INTO producer (id, producerId, name, token, createdAt, updatedAt) VALUES (?, ?, ?, ?, ?, ?)",
-		args2SliceFnWrapper(producer.ID, producer.ProducerID, producer.Name, producer.Token, producer.CreatedAt, producer.UpdatedAt))
+	err := transactionalSingleRowWriteExec(repo.db, emptyOps, "INSERT INTO producer (id, producerId, name, token, receiptUrl, createdAt, updatedAt) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		args2SliceFnWrapper(producer.ID, producer.ProducerID, producer.Name, producer.Token, producer.ReceiptURL, producer.CreatedAt, producer.UpdatedAt))
 	return producer, err
 }
 
-// Get retrieves the producer with matching producer id
+// Get retrieves the producer with matching producer id unless it is deleted
 func (repo *ProducerDBRepository) Get(producerID string) (*data.Producer, error) {
+	return repo.getSingleProducer(producerSelectRowCommonQuery+" producerId like ? AND"+notDeletedCriteria, producerID)
+}
+
+// GetIncludingDeleted retrieves the producer with matching producer id even if it is deleted
+func (repo *ProducerDBRepository) GetIncludingDeleted(producerID string) (*data.Producer, error) {
+	return repo.getSingleProducer(producerSelectRowCommonQuery+" producerId like ?", producerID)
+}
+
+func (repo *ProducerDBRepository) getSingleProducer(query string, producerID string) (*data.Producer, error) {
 	producer := &data.Producer{}
-	err := querySingleRow(repo.db, "SELECT id, producerId, name, token, createdAt, updatedAt FROM producer WHERE producerId like ?", args2SliceFnWrapper(producerID),
-		args2SliceFnWrapper(&producer.ID, &producer.ProducerID, &producer.Name, &producer.Token, &producer.CreatedAt, &producer.UpdatedAt))
+	err := querySingleRow(repo.db, query, args2SliceFnWrapper(producerID), args2SliceFnWrapper(&producer.ID, &producer.ProducerID, &producer.Name, &producer.Token,
+		&producer.ReceiptURL, nullTimeScanner{&producer.DeletedAt}, &producer.CreatedAt, &producer.UpdatedAt))
 	return producer, err
 }
 
-// GetList retrieves the list of producer based on pagination params supplied. It will return a error if both after and before is present at the same time
+// Delete soft deletes the producer; the outstanding jobs of its messages are cancelled unless drain is set, in which case they are delivered
+// as usual
+func (repo *ProducerDBRepository) Delete(producer *data.Producer, drain bool) error {
+	markDeleted(&producer.MessageStakeholder)
+	ops := []func(tx *sql.Tx) error{softDeleteOp(&producer.MessageStakeholder, "UPDATE producer SET deletedAt = ?, updatedAt = ? WHERE producerId like ? AND"+notDeletedCriteria, producer.ProducerID)}
+	if !drain {
+		ops = append(ops, cancelOutstandingJobsOps(producerJobsCriteria, "", producer.ProducerID)...)
+	}
+	return transactionalWrites(repo.db, ops...)
+}
+
+// Purge permanently deletes the deleted producer with its messages, their jobs, its grants and quota; returns the blob store references of
+// its messages' offloaded payloads for them to be deleted as well
+func (repo *ProducerDBRepository) Purge(producer *data.Producer) ([]string, error) {
+	refs, err := getPayloadRefs(repo.db, producerMessagesCriteria, producer.ProducerID)
+	if err == nil {
+		err = transactionalWrites(repo.db,
+			execOp("DELETE FROM job WHERE"+producerJobsCriteria, producer.ProducerID),
+			execOp("DELETE FROM message WHERE"+producerMessagesCriteria, producer.ProducerID),
+			execOp("DELETE FROM publish_grant WHERE producerId like ?", producer.ProducerID),
+			execOp("DELETE FROM publish_quota WHERE subjectType like ? AND subjectId like ?", data.ProducerQuota, producer.ProducerID),
+			getTxWrapperForSingleWriteQuery(emptyOps, "DELETE FROM producer WHERE producerId like ? AND"+deletedCriteria, args2SliceFnWrapper(producer.ProducerID)))
+	}
+	return refs, err
+}
+
+// GetList retrieves the list of producers, other than deleted ones, based on pagination params supplied. It will return a error if both after and before is present at the same time
 func (repo *ProducerDBRepository) GetList(page *data.Pagination) ([]*data.Producer, *data.Pagination, error) {
 	producers := make([]*data.Producer, 0)
 	pagination := &data.Pagination{}
 	if page == nil || (page.Next != nil && page.Previous != nil) {
 		return producers, pagination, ErrPaginationDeadlock
 	}
-	baseQuery := "SELECT id, producerId, name, token, createdAt, updatedAt FROM producer" + getPaginationQueryFragment(page, false)
+	baseQuery := "SELECT id, producerId, name, token, receiptUrl, createdAt, updatedAt FROM producer WHERE" + notDeletedCriteria + getPaginationQueryFragment(page, true)
 	scanArgs := func() []interface{} {
 		producer := &data.Producer{}
 		producers = append(producers, producer)
-		return []interface{}{&producer.ID, &producer.ProducerID, &producer.Name, &producer.Token, &producer.CreatedAt, &producer.UpdatedAt}
+		return []interface{}{&producer.ID, &producer.ProducerID, &producer.Name, &producer.Token, &producer.ReceiptURL, &producer.CreatedAt, &producer.UpdatedAt}
 	}
 	err := queryRows(repo.db, baseQuery, args2SliceFnWrapper(getPaginationTimestampQueryArgs(page)...), scanArgs)
 	if err == nil {
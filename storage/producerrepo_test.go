@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"database/sql"
 	"errors"
 	"strconv"
 	"strings"
@@ -22,6 +23,7 @@ const (
 	dbErrUpdateTestProducerID        = "db-update-test"
 	noChangeUpdateTestProducerID     = "nc-update-test"
 	listTestProducerIDPrefix         = "get-list-"
+	receiptURLUpdateTestProducerID   = "receipt-update-test"
 )
 
 func getProducerRepo() ProducerRepository {
@@ -76,7 +78,7 @@ func TestProducerStore(t *testing.T) {
 		db, mock, _ := sqlmock.New()
 		expectedErr := errors.New("Insertion failed")
 		mock.ExpectBegin()
-		mock.ExpectExec("INSERT INTO").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnError(expectedErr)
+		mock.ExpectExec("INSERT INTO").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnError(expectedErr)
 		mock.ExpectRollback()
 		mock.MatchExpectationsInOrder(true)
 		repo := &ProducerDBRepository{db: db}
@@ -127,10 +129,10 @@ func TestProducerStore(t *testing.T) {
 		expectedErr := errors.New("Update failed")
 		producer, _ := data.NewProducer(dbErrUpdateTestProducerID, successfulGetTestToken)
 		producer.QuickFix()
-		rows := sqlmock.NewRows([]string{"id", "producerId", "name", "token", "createdAt", "updatedAt"}).AddRow(producer.ID, producer.ProducerID, producer.Name, producer.Token, producer.CreatedAt, producer.UpdatedAt)
-		mock.ExpectQuery("SELECT id, producerId, name, token, createdAt, updatedAt FROM producer WHERE producerId like").WithArgs(dbErrUpdateTestProducerID).WillReturnRows(rows).WillReturnError(nil)
+		rows := sqlmock.NewRows([]string{"id", "producerId", "name", "token", "receiptUrl", "deletedAt", "createdAt", "updatedAt"}).AddRow(producer.ID, producer.ProducerID, producer.Name, producer.Token, producer.ReceiptURL, nil, producer.CreatedAt, producer.UpdatedAt)
+		mock.ExpectQuery(producerSelectRowCommonQuery + " producerId like").WithArgs(dbErrUpdateTestProducerID).WillReturnRows(rows).WillReturnError(nil)
 		mock.ExpectBegin()
-		mock.ExpectExec("UPDATE producer").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), dbErrUpdateTestProducerID).WillReturnError(expectedErr)
+		mock.ExpectExec("UPDATE producer").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), dbErrUpdateTestProducerID).WillReturnError(expectedErr)
 		mock.ExpectRollback()
 		mock.MatchExpectationsInOrder(true)
 		repo := &ProducerDBRepository{db: db}
@@ -145,11 +147,11 @@ func TestProducerStore(t *testing.T) {
 		db, mock, _ := sqlmock.New()
 		producer, _ := data.NewProducer(dbErrUpdateTestProducerID, successfulGetTestToken)
 		producer.QuickFix()
-		rows := sqlmock.NewRows([]string{"id", "producerId", "name", "token", "createdAt", "updatedAt"}).AddRow(producer.ID, producer.ProducerID, producer.Name, producer.Token, producer.CreatedAt, producer.UpdatedAt)
+		rows := sqlmock.NewRows([]string{"id", "producerId", "name", "token", "receiptUrl", "deletedAt", "createdAt", "updatedAt"}).AddRow(producer.ID, producer.ProducerID, producer.Name, producer.Token, producer.ReceiptURL, nil, producer.CreatedAt, producer.UpdatedAt)
 		result := sqlmock.NewResult(1, 0)
-		mock.ExpectQuery("SELECT id, producerId, name, token, createdAt, updatedAt FROM producer WHERE producerId like").WithArgs(dbErrUpdateTestProducerID).WillReturnRows(rows).WillReturnError(nil)
+		mock.ExpectQuery(producerSelectRowCommonQuery + " producerId like").WithArgs(dbErrUpdateTestProducerID).WillReturnRows(rows).WillReturnError(nil)
 		mock.ExpectBegin()
-		mock.ExpectExec("UPDATE producer").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), dbErrUpdateTestProducerID).WillReturnResult(result).WillReturnError(nil)
+		mock.ExpectExec("UPDATE producer").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), dbErrUpdateTestProducerID).WillReturnResult(result).WillReturnError(nil)
 		mock.ExpectRollback()
 		mock.MatchExpectationsInOrder(true)
 		repo := &ProducerDBRepository{db: db}
@@ -172,6 +174,26 @@ func TestProducerStore(t *testing.T) {
 		assert.Equal(t, successfulGetTestToken, updatedProducer.Token)
 		assert.True(t, producer.UpdatedAt.Before(updatedProducer.UpdatedAt))
 	})
+	t.Run("Update:ReceiptURL", func(t *testing.T) {
+		t.Parallel()
+		producer, _ := data.NewProducer(receiptURLUpdateTestProducerID, successfulGetTestToken)
+		producer.ReceiptURL = "https://imytech.net/receipts"
+		repo := getProducerRepo()
+		_, err := repo.Store(producer)
+		assert.Nil(t, err)
+		dbProducer, err := repo.Get(receiptURLUpdateTestProducerID)
+		assert.Nil(t, err)
+		assert.Equal(t, producer.ReceiptURL, dbProducer.ReceiptURL)
+		producer.ReceiptURL = ""
+		_, err = repo.Store(producer)
+		assert.Nil(t, err)
+		dbProducer, err = repo.Get(receiptURLUpdateTestProducerID)
+		assert.Nil(t, err)
+		assert.Empty(t, dbProducer.ReceiptURL)
+		producer.ReceiptURL = "ftp://imytech.net/receipts"
+		_, err = repo.Store(producer)
+		assert.Equal(t, ErrInvalidStateToSave, err)
+	})
 }
 
 func TestNewProducerRepository(t *testing.T) {
@@ -201,7 +223,7 @@ func TestProducerGetList(t *testing.T) {
 		t.Parallel()
 		db, mock, _ := sqlmock.New()
 		expectedErr := errors.New("DB Query Error")
-		mock.ExpectQuery("SELECT id, producerId, name, token, createdAt, updatedAt FROM producer").WillReturnError(expectedErr)
+		mock.ExpectQuery("SELECT id, producerId, name, token, receiptUrl, createdAt, updatedAt FROM producer").WillReturnError(expectedErr)
 		mock.MatchExpectationsInOrder(true)
 		repo := &ProducerDBRepository{db: db}
 		_, _, err := repo.GetList(data.NewPagination(nil, nil))
@@ -280,3 +302,40 @@ func TestProducerGetList(t *testing.T) {
 		assert.Equal(t, 100, count)
 	})
 }
+
+func TestProducerDeleteAndPurge(t *testing.T) {
+	repo := getProducerRepo()
+	producer, _ := data.NewProducer("delete-test-producer", successfulGetTestToken)
+	producer, err := repo.Store(producer)
+	assert.Nil(t, err)
+	channel := createTestChannel("delete-test-producer-channel", successfulGetTestToken, getChannelRepo())
+	consumer, message, job := createDeletionTestFixture(t, channel, producer, "delete-test-producer-consumer")
+	t.Run("DeleteDraining", func(t *testing.T) {
+		assert.Nil(t, repo.Delete(producer, true))
+		_, err := repo.Get(producer.ProducerID)
+		assert.Equal(t, sql.ErrNoRows, err)
+		producers, _, err := repo.GetList(data.NewPagination(nil, nil))
+		assert.Nil(t, err)
+		for _, listedProducer := range producers {
+			assert.NotEqual(t, producer.ProducerID, listedProducer.ProducerID)
+		}
+		drainingJob, err := getDeliverJobRepository().GetByID(job.ID.String())
+		assert.Nil(t, err)
+		assert.Equal(t, data.JobQueued, drainingJob.Status)
+		assert.True(t, drainingJob.Message.ProducedBy.IsDeleted())
+		assert.Equal(t, consumer.ID, drainingJob.Listener.ID)
+		_, err = repo.Store(producer)
+		assert.Equal(t, ErrDeleted, err)
+	})
+	t.Run("Purge", func(t *testing.T) {
+		refs, err := repo.Purge(producer)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{message.PayloadRef}, refs)
+		_, err = repo.GetIncludingDeleted(producer.ProducerID)
+		assert.Equal(t, sql.ErrNoRows, err)
+		_, err = getDeliverJobRepository().GetByID(job.ID.String())
+		assert.Equal(t, sql.ErrNoRows, err)
+		_, err = getConsumerRepo().GetByID(consumer.ID.String())
+		assert.Nil(t, err)
+	})
+}
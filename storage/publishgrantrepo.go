@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+var (
+	errDuplicatePublishGrant = errors.New("producer is already granted to publish to the channel")
+	publishGrantErrorMap     = map[uint16]error{
+		1062: errDuplicatePublishGrant,
+	}
+)
+
+// PublishGrantDBRepository is the RDBMS implementation for PublishGrantRepository
+type PublishGrantDBRepository struct {
+	db *sql.DB
+}
+
+// Grant grants the producer to publish to the channel; returns the existing grant if it is already granted
+func (grantRepo *PublishGrantDBRepository) Grant(channel *data.Channel, producer *data.Producer) (*data.PublishGrant, error) {
+	grant, err := data.NewPublishGrant(channel, producer)
+	if err != nil {
+		return nil, ErrInvalidStateToSave
+	}
+	err = normalizeDBError(transactionalSingleRowWriteExec(grantRepo.db, emptyOps, "INSERT INTO publish_grant (id, channelId, producerId, createdAt, updatedAt) VALUES (?, ?, ?, ?, ?)",
+		args2SliceFnWrapper(grant.ID, channel.ChannelID, producer.ProducerID, grant.CreatedAt, grant.UpdatedAt)), publishGrantErrorMap)
+	if err == errDuplicatePublishGrant {
+		return grantRepo.Get(channel, producer)
+	}
+	return grant, err
+}
+
+// Get retrieves the grant of the producer to publish to the channel; returns sql.ErrNoRows if the producer is not granted
+func (grantRepo *PublishGrantDBRepository) Get(channel *data.Channel, producer *data.Producer) (*data.PublishGrant, error) {
+	grant := &data.PublishGrant{Channel: channel, Producer: producer}
+	err := querySingleRow(grantRepo.db, "SELECT id, createdAt, updatedAt FROM publish_grant WHERE channelId like ? AND producerId like ?",
+		args2SliceFnWrapper(channel.ChannelID, producer.ProducerID), args2SliceFnWrapper(&grant.ID, &grant.CreatedAt, &grant.UpdatedAt))
+	if err != nil {
+		return nil, err
+	}
+	return grant, nil
+}
+
+// Revoke revokes the grant so that the producer can no longer publish to the channel
+func (grantRepo *PublishGrantDBRepository) Revoke(grant *data.PublishGrant) error {
+	return transactionalSingleRowWriteExec(grantRepo.db, emptyOps, "DELETE FROM publish_grant WHERE id like ?", args2SliceFnWrapper(grant.ID))
+}
+
+// NewPublishGrantRepository creates a new instance of PublishGrantRepository
+func NewPublishGrantRepository(db *sql.DB) PublishGrantRepository {
+	panicIfNoDBConnectionPool(db)
+	return &PublishGrantDBRepository{db: db}
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/newscred/webhook-broker/storage/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublishGrantRepository(t *testing.T) {
+	grantRepo := NewPublishGrantRepository(testDB)
+	channel := createTestChannel("channel-for-grants", "sampletoken", NewChannelRepository(testDB))
+	producer, _ := data.NewProducer("producer-for-grants", "sampletoken")
+	producer, err := NewProducerRepository(testDB).Store(producer)
+	assert.Nil(t, err)
+	t.Run("InvalidState", func(t *testing.T) {
+		_, err := grantRepo.Grant(channel, &data.Producer{})
+		assert.Equal(t, ErrInvalidStateToSave, err)
+	})
+	t.Run("GrantAndRevoke", func(t *testing.T) {
+		_, err := grantRepo.Get(channel, producer)
+		assert.Equal(t, sql.ErrNoRows, err)
+		grant, err := grantRepo.Grant(channel, producer)
+		assert.Nil(t, err)
+		regrant, err := grantRepo.Grant(channel, producer)
+		assert.Nil(t, err)
+		assert.Equal(t, grant.ID, regrant.ID)
+		existing, err := grantRepo.Get(channel, producer)
+		assert.Nil(t, err)
+		assert.Equal(t, grant.ID, existing.ID)
+		assert.Equal(t, channel, existing.Channel)
+		assert.Equal(t, producer, existing.Producer)
+		assert.Nil(t, grantRepo.Revoke(existing))
+		assert.Equal(t, ErrNoRowsUpdated, grantRepo.Revoke(existing))
+		_, err = grantRepo.Get(channel, producer)
+		assert.Equal(t, sql.ErrNoRows, err)
+	})
+	t.Run("NonExistentChannel", func(t *testing.T) {
+		channel, _ := data.NewChannel("no-such-channel-for-grants", "sampletoken")
+		_, err := grantRepo.Grant(channel, producer)
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/newscred/webhook-broker/config"
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+const (
+	quotaSelectRowCommonQuery = "SELECT id, subjectType, subjectId, messagesPerSecond, burst, bytesPerMinute, messageTokens, byteTokens, refilledAt, revision, createdAt, updatedAt FROM publish_quota WHERE subjectType like ? AND subjectId like ?"
+	maxTakeTokensAttempts     = 3
+	// QuotaChangedConcurrentlyWait is how long a publisher should wait before retrying when tokens could not be taken as the quotas kept being
+	// changed concurrently, i.e. when many brokers are taking tokens from the same quota at once
+	QuotaChangedConcurrentlyWait = time.Second
+)
+
+var (
+	// ErrQuotaChangedConcurrently is returned when the token buckets of a quota kept being changed by other brokers while tokens were taken
+	ErrQuotaChangedConcurrently = errors.New("publish quota changed concurrently")
+	quotaErrorMap               = map[uint16]error{
+		1062: ErrQuotaChangedConcurrently,
+	}
+)
+
+// PublishQuotaDBRepository is the RDBMS implementation for PublishQuotaRepository
+type PublishQuotaDBRepository struct {
+	db *sql.DB
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanQuota scans the quota of the subject from the row; returns a new quota, that is not stored, if there is no row
+func scanQuota(row rowScanner, subjectType data.QuotaSubjectType, subjectID string) (quota *data.PublishQuota, stored bool, err error) {
+	quota = &data.PublishQuota{}
+	var refilledAt sql.NullTime
+	err = row.Scan(&quota.ID, &quota.SubjectType, &quota.SubjectID, &quota.Limit.MessagesPerSecond, &quota.Limit.Burst, &quota.Limit.BytesPerMinute, &quota.MessageTokens,
+		&quota.ByteTokens, &refilledAt, &quota.Revision, &quota.CreatedAt, &quota.UpdatedAt)
+	if err == sql.ErrNoRows {
+		quota, err = data.NewPublishQuota(subjectType, subjectID)
+		return quota, false, err
+	}
+	quota.RefilledAt = refilledAt.Time
+	return quota, err == nil, err
+}
+
+func insertQuota(tx *sql.Tx, quota *data.PublishQuota) error {
+	return inTransactionExec(tx, emptyOps, "INSERT INTO publish_quota (id, subjectType, subjectId, messagesPerSecond, burst, bytesPerMinute, messageTokens, byteTokens, refilledAt, revision, createdAt, updatedAt) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		args2SliceFnWrapper(quota.ID, quota.SubjectType, quota.SubjectID, quota.Limit.MessagesPerSecond, quota.Limit.Burst, quota.Limit.BytesPerMinute, quota.MessageTokens,
+			quota.ByteTokens, nullableTime(quota.RefilledAt), quota.Revision, quota.CreatedAt, quota.UpdatedAt), int64(1))
+}
+
+// Get retrieves the quota of the subject; returns a new quota, that is not stored, if the subject has none
+func (quotaRepo *PublishQuotaDBRepository) Get(subjectType data.QuotaSubjectType, subjectID string) (*data.PublishQuota, error) {
+	quota, _, err := scanQuota(quotaRepo.db.QueryRow(quotaSelectRowCommonQuery, subjectType, subjectID), subjectType, subjectID)
+	return quota, err
+}
+
+// SetLimit sets the rate limit of the subject; a zero field of the limit means the broker-wide default applies
+func (quotaRepo *PublishQuotaDBRepository) SetLimit(subjectType data.QuotaSubjectType, subjectID string, limit config.PublishRateLimit) (*data.PublishQuota, error) {
+	quota, stored, err := scanQuota(quotaRepo.db.QueryRow(quotaSelectRowCommonQuery, subjectType, subjectID), subjectType, subjectID)
+	if err != nil || quota.Limit == limit {
+		return quota, err
+	}
+	quota.Limit = limit
+	quota.UpdatedAt = time.Now()
+	err = transactionalWrites(quotaRepo.db, func(tx *sql.Tx) error {
+		if !stored {
+			return insertQuota(tx, quota)
+		}
+		return inTransactionExec(tx, emptyOps, "UPDATE publish_quota SET messagesPerSecond = ?, burst = ?, bytesPerMinute = ?, updatedAt = ? WHERE id like ?",
+			args2SliceFnWrapper(quota.Limit.MessagesPerSecond, quota.Limit.Burst, quota.Limit.BytesPerMinute, quota.UpdatedAt, quota.ID), int64(1))
+	})
+	return quota, normalizeDBError(err, quotaErrorMap)
+}
+
+// TakeTokens takes the tokens of a message of the size from the quotas of all the claims if all of them allow it; else returns how long to wait
+// before retrying. The quotas are read and written in a transaction and are read again if another broker changed any of them meanwhile.
+func (quotaRepo *PublishQuotaDBRepository) TakeTokens(size uint, claims ...*data.QuotaClaim) (time.Duration, error) {
+	return quotaRepo.updateTokens(claims, func(quota *data.PublishQuota, limit config.PublishRateLimit) time.Duration {
+		wait := quota.GetWait(limit)
+		quota.Take(limit, size)
+		return wait
+	})
+}
+
+// ReturnTokens returns the tokens taken for a message of the size that was not stored after all, e.g. as it was a duplicate, to the quotas of
+// all the claims
+func (quotaRepo *PublishQuotaDBRepository) ReturnTokens(size uint, claims ...*data.QuotaClaim) error {
+	_, err := quotaRepo.updateTokens(claims, func(quota *data.PublishQuota, limit config.PublishRateLimit) time.Duration {
+		quota.Return(limit, size)
+		return 0
+	})
+	return err
+}
+
+// updateTokens refills the limited quotas of the claims and changes their tokens; the quotas are only written if none of the changes returned
+// a wait, which is the longest of them
+func (quotaRepo *PublishQuotaDBRepository) updateTokens(claims []*data.QuotaClaim, change func(quota *data.PublishQuota, limit config.PublishRateLimit) time.Duration) (wait time.Duration, err error) {
+	for attempt := 0; attempt < maxTakeTokensAttempts; attempt++ {
+		wait = 0
+		err = transactionalWrites(quotaRepo.db, func(tx *sql.Tx) error {
+			now := time.Now()
+			writes := make([]func(tx *sql.Tx) error, 0, len(claims))
+			for _, claim := range claims {
+				quota, stored, err := scanQuota(tx.QueryRow(quotaSelectRowCommonQuery, claim.SubjectType, claim.SubjectID), claim.SubjectType, claim.SubjectID)
+				if err != nil {
+					return err
+				}
+				limit := quota.GetEffectiveLimit(claim.DefaultLimit)
+				if limit.MessagesPerSecond <= 0 && limit.BytesPerMinute <= 0 {
+					continue
+				}
+				quota.Refill(limit, now)
+				if quotaWait := change(quota, limit); quotaWait > wait {
+					wait = quotaWait
+				}
+				writes = append(writes, func(tx *sql.Tx) error {
+					if !stored {
+						return insertQuota(tx, quota)
+					}
+					return inTransactionExec(tx, emptyOps, "UPDATE publish_quota SET messageTokens = ?, byteTokens = ?, refilledAt = ?, revision = revision + 1 WHERE id like ? AND revision = ?",
+						args2SliceFnWrapper(quota.MessageTokens, quota.ByteTokens, quota.RefilledAt, quota.ID, quota.Revision), int64(1))
+				})
+			}
+			var writeErr error
+			for index := 0; wait <= 0 && writeErr == nil && index < len(writes); index++ {
+				writeErr = writes[index](tx)
+			}
+			return writeErr
+		})
+		if err = normalizeDBError(err, quotaErrorMap); err != ErrNoRowsUpdated && err != ErrQuotaChangedConcurrently {
+			break
+		}
+		err = ErrQuotaChangedConcurrently
+	}
+	return wait, err
+}
+
+// NewPublishQuotaRepository creates a new instance of PublishQuotaRepository
+func NewPublishQuotaRepository(db *sql.DB) PublishQuotaRepository {
+	panicIfNoDBConnectionPool(db)
+	return &PublishQuotaDBRepository{db: db}
+}
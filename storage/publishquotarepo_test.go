@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/newscred/webhook-broker/config"
+	"github.com/newscred/webhook-broker/storage/data"
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublishQuotaRepository(t *testing.T) {
+	quotaRepo := NewPublishQuotaRepository(testDB)
+	t.Run("GetNotStored", func(t *testing.T) {
+		quota, err := quotaRepo.Get(data.ProducerQuota, "quota-producer-not-stored")
+		assert.Nil(t, err)
+		assert.Equal(t, "quota-producer-not-stored", quota.SubjectID)
+		assert.Equal(t, config.PublishRateLimit{}, quota.Limit)
+		_, err = quotaRepo.Get(data.QuotaSubjectType("consumer"), "quota-consumer")
+		assert.Equal(t, data.ErrInsufficientInformationForCreating, err)
+	})
+	t.Run("SetLimit", func(t *testing.T) {
+		limit := config.PublishRateLimit{MessagesPerSecond: 5, BytesPerMinute: 1024}
+		quota, err := quotaRepo.SetLimit(data.ChannelQuota, "quota-channel-limited", limit)
+		assert.Nil(t, err)
+		assert.Equal(t, limit, quota.Limit)
+		quota, err = quotaRepo.SetLimit(data.ChannelQuota, "quota-channel-limited", limit)
+		assert.Nil(t, err)
+		updatedLimit := config.PublishRateLimit{MessagesPerSecond: 5, Burst: 10}
+		updated, err := quotaRepo.SetLimit(data.ChannelQuota, "quota-channel-limited", updatedLimit)
+		assert.Nil(t, err)
+		assert.Equal(t, quota.ID, updated.ID)
+		readQuota, err := quotaRepo.Get(data.ChannelQuota, "quota-channel-limited")
+		assert.Nil(t, err)
+		assert.Equal(t, quota.ID, readQuota.ID)
+		assert.Equal(t, updatedLimit, readQuota.Limit)
+		assert.True(t, readQuota.RefilledAt.IsZero())
+	})
+	t.Run("TakeTokens", func(t *testing.T) {
+		_, err := quotaRepo.SetLimit(data.ProducerQuota, "quota-producer-limited", config.PublishRateLimit{MessagesPerSecond: 1, Burst: 2})
+		assert.Nil(t, err)
+		claims := []*data.QuotaClaim{
+			{SubjectType: data.ProducerQuota, SubjectID: "quota-producer-limited"},
+			{SubjectType: data.ChannelQuota, SubjectID: "quota-channel-default", DefaultLimit: config.PublishRateLimit{MessagesPerSecond: 100, BytesPerMinute: 600}},
+			{SubjectType: data.ChannelQuota, SubjectID: "quota-channel-unlimited"},
+		}
+		for index := 0; index < 2; index++ {
+			wait, err := quotaRepo.TakeTokens(400, claims...)
+			assert.Nil(t, err)
+			assert.Equal(t, time.Duration(0), wait)
+		}
+		wait, err := quotaRepo.TakeTokens(1, claims...)
+		assert.Nil(t, err)
+		assert.True(t, wait > 19*time.Second, wait)
+		producerQuota, _ := quotaRepo.Get(data.ProducerQuota, "quota-producer-limited")
+		assert.True(t, producerQuota.MessageTokens < 0.1)
+		assert.Equal(t, uint(2), producerQuota.Revision)
+		channelQuota, _ := quotaRepo.Get(data.ChannelQuota, "quota-channel-default")
+		assert.Equal(t, config.PublishRateLimit{}, channelQuota.Limit)
+		assert.True(t, channelQuota.ByteTokens < -199)
+		assert.False(t, channelQuota.RefilledAt.IsZero())
+		unlimitedQuota, _ := quotaRepo.Get(data.ChannelQuota, "quota-channel-unlimited")
+		assert.True(t, unlimitedQuota.RefilledAt.IsZero())
+		assert.Equal(t, uint(0), unlimitedQuota.Revision)
+	})
+	t.Run("ReturnTokens", func(t *testing.T) {
+		_, err := quotaRepo.SetLimit(data.ProducerQuota, "quota-producer-returned", config.PublishRateLimit{MessagesPerSecond: 1, Burst: 2, BytesPerMinute: 600})
+		assert.Nil(t, err)
+		claims := []*data.QuotaClaim{{SubjectType: data.ProducerQuota, SubjectID: "quota-producer-returned"}, {SubjectType: data.ChannelQuota, SubjectID: "quota-channel-unlimited"}}
+		for index := 0; index < 2; index++ {
+			wait, err := quotaRepo.TakeTokens(200, claims...)
+			assert.Nil(t, err)
+			assert.Equal(t, time.Duration(0), wait)
+		}
+		assert.Nil(t, quotaRepo.ReturnTokens(200, claims...))
+		quota, _ := quotaRepo.Get(data.ProducerQuota, "quota-producer-returned")
+		assert.True(t, quota.MessageTokens >= 1 && quota.MessageTokens < 1.1, quota.MessageTokens)
+		assert.True(t, quota.ByteTokens >= 400 && quota.ByteTokens < 410, quota.ByteTokens)
+		assert.Equal(t, uint(3), quota.Revision)
+		wait, err := quotaRepo.TakeTokens(200, claims...)
+		assert.Nil(t, err)
+		assert.Equal(t, time.Duration(0), wait)
+		unlimitedQuota, _ := quotaRepo.Get(data.ChannelQuota, "quota-channel-unlimited")
+		assert.Equal(t, uint(0), unlimitedQuota.Revision)
+	})
+	t.Run("TakeTokensChangedConcurrently", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		quotaRepo := NewPublishQuotaRepository(db)
+		columns := []string{"id", "subjectType", "subjectId", "messagesPerSecond", "burst", "bytesPerMinute", "messageTokens", "byteTokens", "refilledAt", "revision", "createdAt", "updatedAt"}
+		quotaID := xid.New().String()
+		for attempt := 0; attempt < maxTakeTokensAttempts; attempt++ {
+			mock.ExpectBegin()
+			mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows(columns).AddRow(quotaID, "producer", "some-producer", 1, 0, 0, 1.0, 0.0, time.Now(), 3, time.Now(), time.Now()))
+			mock.ExpectExec("UPDATE publish_quota").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectRollback()
+		}
+		_, err := quotaRepo.TakeTokens(10, &data.QuotaClaim{SubjectType: data.ProducerQuota, SubjectID: "some-producer"})
+		assert.Equal(t, ErrQuotaChangedConcurrently, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+	t.Run("TakeTokensError", func(t *testing.T) {
+		db, mock, _ := sqlmock.New()
+		quotaRepo := NewPublishQuotaRepository(db)
+		expectedErr := errors.New("query failed")
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT").WillReturnError(expectedErr)
+		mock.ExpectRollback()
+		_, err := quotaRepo.TakeTokens(10, &data.QuotaClaim{SubjectType: data.ProducerQuota, SubjectID: "some-producer"})
+		assert.Equal(t, expectedErr, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+}
@@ -39,6 +39,10 @@ type RelationalDBDataAccessor struct {
 	messageRepository     MessageRepository
 	deliveryJobRepository DeliveryJobRepository
 	lockRepository        LockRepository
+	replayRepository      ReplayRepository
+	schemaRepository      SchemaRepository
+	quotaRepository       PublishQuotaRepository
+	grantRepository       PublishGrantRepository
 	db                    *sql.DB
 }
 
@@ -77,6 +81,26 @@ func (rdbmsDataAccessor *RelationalDBDataAccessor) GetLockRepository() LockRepos
 	return rdbmsDataAccessor.lockRepository
 }
 
+// GetReplayRepository retrieves the ReplayRepository to be used for Replay ops
+func (rdbmsDataAccessor *RelationalDBDataAccessor) GetReplayRepository() ReplayRepository {
+	return rdbmsDataAccessor.replayRepository
+}
+
+// GetSchemaRepository retrieves the SchemaRepository to be used for ChannelSchema ops
+func (rdbmsDataAccessor *RelationalDBDataAccessor) GetSchemaRepository() SchemaRepository {
+	return rdbmsDataAccessor.schemaRepository
+}
+
+// GetPublishQuotaRepository retrieves the PublishQuotaRepository to be used for PublishQuota ops
+func (rdbmsDataAccessor *RelationalDBDataAccessor) GetPublishQuotaRepository() PublishQuotaRepository {
+	return rdbmsDataAccessor.quotaRepository
+}
+
+// GetPublishGrantRepository retrieves the PublishGrantRepository to be used for PublishGrant ops
+func (rdbmsDataAccessor *RelationalDBDataAccessor) GetPublishGrantRepository() PublishGrantRepository {
+	return rdbmsDataAccessor.grantRepository
+}
+
 // Close closes the connection to DB
 func (rdbmsDataAccessor *RelationalDBDataAccessor) Close() {
 	db.Close()
@@ -115,6 +139,8 @@ var (
 	ErrInvalidStateToSave = errors.New("Data model in invalid state to be stored")
 	// ErrPaginationDeadlock is returned if both after and before is provided in pagination
 	ErrPaginationDeadlock = errors.New("Can not decide on pagination direction! Both after and before provided or pagination is nil")
+	// ErrDeleted is returned when storing a producer, channel or consumer that is deleted; its ID can only be reused once it is purged
+	ErrDeleted = errors.New("deleted and can not be recreated until it is purged")
 )
 
 // AppDBRepository is the repository to access App data
@@ -187,7 +213,7 @@ var (
 	// ErrDBConnectionNeverInitialized is returned when same NewDataAccessor is called the first time and it failed to connec to DB; in all subsequent calls the accessor will remain nil
 	ErrDBConnectionNeverInitialized = errors.New("DB Connection never initialized")
 	// RDBMSStorageInternalInjector injector for data storage related implementation
-	RDBMSStorageInternalInjector = wire.NewSet(GetConnectionPool, NewLockRepository, NewAppRepository, NewProducerRepository, NewChannelRepository, NewConsumerRepository, NewMessageRepository, NewDeliveryJobRepository, wire.Struct(new(RelationalDBDataAccessor), "db", "appRepository", "producerRepository", "channelRepository", "consumerRepository", "messageRepository", "deliveryJobRepository", "lockRepository"), wire.Bind(new(DataAccessor), new(*RelationalDBDataAccessor)))
+	RDBMSStorageInternalInjector = wire.NewSet(GetConnectionPool, NewPayloadCompressor, NewLockRepository, NewAppRepository, NewProducerRepository, NewChannelRepository, NewConsumerRepository, NewMessageRepository, NewDeliveryJobRepository, NewReplayRepository, NewSchemaRepository, NewPublishQuotaRepository, NewPublishGrantRepository, wire.Struct(new(RelationalDBDataAccessor), "db", "appRepository", "producerRepository", "channelRepository", "consumerRepository", "messageRepository", "deliveryJobRepository", "lockRepository", "replayRepository", "schemaRepository", "quotaRepository", "grantRepository"), wire.Bind(new(DataAccessor), new(*RelationalDBDataAccessor)))
 )
 
 func panicIfNoDBConnectionPool(db *sql.DB) {
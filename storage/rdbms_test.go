@@ -53,9 +53,10 @@ var (
 )
 
 func TestGetNewDataAccessor(t *testing.T) {
-	// Clear DB before starting test
-	os.Remove("./webhook-broker.sqlite3")
+	// Start from an empty DB of its own, leaving the DB shared by the other tests intact
+	os.Remove("./data-accessor-test.sqlite3")
 	configuration, _ := config.GetAutoConfiguration()
+	configuration.DBConnectionURL = "data-accessor-test.sqlite3?_foreign_keys=on"
 	t.Run("DBConnectionErr", func(t *testing.T) {
 		dataAccessorInitializer = sync.Once{}
 		oldGetDB := getDB
@@ -135,6 +136,7 @@ func TestGetNewDataAccessor(t *testing.T) {
 		assert.NotNil(t, dataAccessor.GetMessageRepository())
 		assert.NotNil(t, dataAccessor.GetDeliveryJobRepository())
 		assert.NotNil(t, dataAccessor.GetLockRepository())
+		assert.NotNil(t, dataAccessor.GetReplayRepository())
 		// Does nothing
 		dataAccessor.Close()
 		t.Run("InitAppSkip", func(t *testing.T) {
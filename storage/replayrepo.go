@@ -0,0 +1,253 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+const (
+	replaySelectRowCommonQuery = "SELECT id, consumerId, receivedFrom, receivedTo, messageIds, status, totalCount, processedCount, deliveredCount, failedCount, cursorReceivedAt, cursorId, leaseOwner, leaseExpiresAt, createdAt, updatedAt FROM replay WHERE"
+	replayMessageSelectQuery   = "SELECT id, messageId, producerId, payload, payloadCodec, payloadRef, routingKey, attributes, cloudEvent, receiptUrl, contentType, priority, status, receivedAt, outboxedAt, createdAt, updatedAt FROM message WHERE"
+	replaysToRunPageSize       = " ORDER BY createdAt LIMIT 100"
+	replayJobStatusCountQuery  = "SELECT status, COUNT(*) FROM job WHERE replayId like ? GROUP BY status"
+)
+
+// ReplayDBRepository is the RDBMS implementation for ReplayRepository
+type ReplayDBRepository struct {
+	db                 *sql.DB
+	consumerRepository ConsumerRepository
+	producerRepository ProducerRepository
+}
+
+func nullableTime(value time.Time) sql.NullTime {
+	return sql.NullTime{Time: value, Valid: !value.IsZero()}
+}
+
+// getReplayMessageCriteria returns the where clause and its args to select messages matching the replay
+func getReplayMessageCriteria(replay *data.Replay) (string, []interface{}) {
+	query := " channelId like ? AND status = ?"
+	args := []interface{}{replay.Consumer.GetChannelIDSafely(), data.MsgStatusDispatched}
+	if replay.HasTimeRange() {
+		query = query + " AND receivedAt >= ? AND receivedAt <= ?"
+		args = append(args, replay.From, replay.To)
+	}
+	if len(replay.MessageIDs) > 0 {
+		query = query + " AND messageId IN (?" + strings.Repeat(", ?", len(replay.MessageIDs)-1) + ")"
+		for _, messageID := range replay.MessageIDs {
+			args = append(args, messageID)
+		}
+	}
+	return query, args
+}
+
+// Create counts the messages matching the replay and stores it
+func (replayRepo *ReplayDBRepository) Create(replay *data.Replay) (err error) {
+	replay.QuickFix()
+	if !replay.IsInValidState() {
+		return ErrInvalidStateToSave
+	}
+	criteria, args := getReplayMessageCriteria(replay)
+	err = querySingleRow(replayRepo.db, "SELECT COUNT(*) FROM message WHERE"+criteria, args2SliceFnWrapper(args...), args2SliceFnWrapper(&replay.TotalCount))
+	if err == nil {
+		replay.LeaseExpiresAt = replay.CreatedAt
+		messageIDs, _ := json.Marshal(replay.MessageIDs)
+		err = transactionalSingleRowWriteExec(replayRepo.db, emptyOps, "INSERT INTO replay (id, consumerId, receivedFrom, receivedTo, messageIds, status, totalCount, processedCount, deliveredCount, failedCount, cursorReceivedAt, cursorId, leaseOwner, leaseExpiresAt, createdAt, updatedAt) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			args2SliceFnWrapper(replay.ID, replay.Consumer.ID, nullableTime(replay.From), nullableTime(replay.To), string(messageIDs), replay.Status, replay.TotalCount, replay.ProcessedCount, replay.DeliveredCount, replay.FailedCount,
+				nullableTime(replay.CursorReceivedAt), replay.CursorID, replay.LeaseOwner, replay.LeaseExpiresAt, replay.CreatedAt, replay.UpdatedAt))
+	}
+	return err
+}
+
+func (replayRepo *ReplayDBRepository) getReplays(query string, queryArgs func() []interface{}, consumer *data.Consumer) ([]*data.Replay, error) {
+	replays := make([]*data.Replay, 0)
+	consumerIDs := make([]string, 0)
+	type nullableFields struct {
+		from, to, cursorReceivedAt sql.NullTime
+		messageIDs                 string
+	}
+	fields := make([]*nullableFields, 0)
+	scanArgs := func() []interface{} {
+		replay := &data.Replay{}
+		replays = append(replays, replay)
+		consumerIDs = append(consumerIDs, "")
+		field := &nullableFields{}
+		fields = append(fields, field)
+		return []interface{}{&replay.ID, &consumerIDs[len(consumerIDs)-1], &field.from, &field.to, &field.messageIDs, &replay.Status, &replay.TotalCount, &replay.ProcessedCount,
+			&replay.DeliveredCount, &replay.FailedCount, &field.cursorReceivedAt, &replay.CursorID, &replay.LeaseOwner, &replay.LeaseExpiresAt, &replay.CreatedAt, &replay.UpdatedAt}
+	}
+	err := queryRows(replayRepo.db, query, queryArgs, scanArgs)
+	for index := 0; err == nil && index < len(replays); index++ {
+		replay := replays[index]
+		replay.From, replay.To, replay.CursorReceivedAt = fields[index].from.Time, fields[index].to.Time, fields[index].cursorReceivedAt.Time
+		err = json.Unmarshal([]byte(fields[index].messageIDs), &replay.MessageIDs)
+		if err == nil && consumer == nil {
+			replay.Consumer, err = replayRepo.consumerRepository.GetByID(consumerIDs[index])
+		} else {
+			replay.Consumer = consumer
+		}
+		if err == nil {
+			err = replayRepo.addJobCounts(replay)
+		}
+	}
+	return replays, err
+}
+
+// addJobCounts adds the replay's delivered and dead jobs to its delivered and failed counts; the counts stored with the replay are of the
+// messages it re-delivered itself before replays were delivered through jobs
+func (replayRepo *ReplayDBRepository) addJobCounts(replay *data.Replay) error {
+	statuses := make([]data.JobStatus, 0, 4)
+	counts := make([]uint, 0, 4)
+	err := queryRows(replayRepo.db, replayJobStatusCountQuery, args2SliceFnWrapper(replay.ID.String()), func() []interface{} {
+		statuses = append(statuses, data.JobQueued)
+		counts = append(counts, 0)
+		return []interface{}{&statuses[len(statuses)-1], &counts[len(counts)-1]}
+	})
+	for index := 0; err == nil && index < len(statuses); index++ {
+		switch statuses[index] {
+		case data.JobDelivered:
+			replay.DeliveredCount += counts[index]
+		case data.JobDead:
+			replay.FailedCount += counts[index]
+		}
+	}
+	return err
+}
+
+// Get retrieves a replay of the consumer
+func (replayRepo *ReplayDBRepository) Get(consumer *data.Consumer, id string) (*data.Replay, error) {
+	replays, err := replayRepo.getReplays(replaySelectRowCommonQuery+" id like ? AND consumerId like ?", args2SliceFnWrapper(id, consumer.ID.String()), consumer)
+	if err == nil && len(replays) <= 0 {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		return nil, err
+	}
+	return replays[0], err
+}
+
+// GetReplaysToRun retrieves unfinished replays that no broker currently holds a lease for
+func (replayRepo *ReplayDBRepository) GetReplaysToRun() []*data.Replay {
+	replays, err := replayRepo.getReplays(replaySelectRowCommonQuery+" status != ? AND leaseExpiresAt < ?"+replaysToRunPageSize, args2SliceFnWrapper(data.ReplayCompleted, time.Now()), nil)
+	if err != nil {
+		log.Error().Err(err).Msg("error - could not list replays to run")
+	}
+	return replays
+}
+
+// Lease marks the replay as running by owner for the duration provided no other owner holds an unexpired lease; returns ErrNoRowsUpdated otherwise
+func (replayRepo *ReplayDBRepository) Lease(replay *data.Replay, owner string, duration time.Duration) error {
+	currentTime := time.Now()
+	leaseExpiresAt := currentTime.Add(duration)
+	return transactionalSingleRowWriteExec(replayRepo.db, func() {
+		replay.Status = data.ReplayRunning
+		replay.LeaseOwner = owner
+		replay.LeaseExpiresAt = leaseExpiresAt
+		replay.UpdatedAt = currentTime
+	}, "UPDATE replay SET status = ?, leaseOwner = ?, leaseExpiresAt = ?, updatedAt = ? WHERE id like ? AND status != ? AND leaseExpiresAt < ?",
+		args2SliceFnWrapper(data.ReplayRunning, owner, leaseExpiresAt, currentTime, replay.ID, data.ReplayCompleted, currentTime))
+}
+
+// UpdateProgress persists the processed count, cursor and status of the replay while extending the lease; returns ErrNoRowsUpdated if the
+// lease has been taken over by another owner
+func (replayRepo *ReplayDBRepository) UpdateProgress(replay *data.Replay, leaseDuration time.Duration) error {
+	currentTime := time.Now()
+	leaseExpiresAt := currentTime.Add(leaseDuration)
+	return transactionalSingleRowWriteExec(replayRepo.db, func() {
+		replay.LeaseExpiresAt = leaseExpiresAt
+		replay.UpdatedAt = currentTime
+	}, "UPDATE replay SET status = ?, processedCount = ?, cursorReceivedAt = ?, cursorId = ?, leaseExpiresAt = ?, updatedAt = ? WHERE id like ? AND leaseOwner = ?",
+		args2SliceFnWrapper(replay.Status, replay.ProcessedCount, nullableTime(replay.CursorReceivedAt), replay.CursorID, leaseExpiresAt, currentTime, replay.ID, replay.LeaseOwner))
+}
+
+// CreateJobs creates queued jobs of the replay for the messages, which must be the next ones after its cursor, that its consumer is subscribed
+// to; earliest next attempt of the jobs are spread starting from `start` and `interval` apart. The jobs are created in the same transaction
+// as the replay's cursor is moved past the messages, its processed count increased and its lease extended; returns ErrNoRowsUpdated if the
+// lease has been taken over by another owner or the replay was completed meanwhile, e.g. as its consumer was deleted. The jobs are delivered, retried and marked dead by the dispatcher like any other job;
+// they are unique per replay, so they do not collide with the consumer's original jobs of the messages.
+func (replayRepo *ReplayDBRepository) CreateJobs(replay *data.Replay, messages []*data.Message, start time.Time, interval time.Duration, leaseDuration time.Duration) (jobCount uint, err error) {
+	if len(messages) <= 0 {
+		return 0, nil
+	}
+	consumer := replay.Consumer
+	query := "INSERT INTO job (id, messageId, consumerId, replayId, dispatchReceivedAt, statusChangedAt, earliestNextAttemptAt, status, createdAt, updatedAt) VALUES"
+	args := make([]interface{}, 0, len(messages)*(jobPropertyCount+1))
+	currentTime := time.Now()
+	for _, message := range messages {
+		if !consumer.IsSubscribedTo(message) {
+			continue
+		}
+		args = append(args, xid.New(), message.ID, consumer.ID, replay.ID, currentTime, currentTime, start.Add(time.Duration(jobCount)*interval), data.JobQueued, currentTime, currentTime)
+		query = query + " (?, ?, ?, ?, ?, ?, ?, ?, ?, ?),"
+		jobCount++
+	}
+	lastMessage := messages[len(messages)-1]
+	leaseExpiresAt := currentTime.Add(leaseDuration)
+	ops := make([]func(tx *sql.Tx) error, 0, 2)
+	if jobCount > 0 {
+		query = query[:len(query)-1]
+		ops = append(ops, func(tx *sql.Tx) error {
+			return inTransactionExec(tx, emptyOps, query, args2SliceFnWrapper(args...), int64(jobCount))
+		})
+	}
+	ops = append(ops, func(tx *sql.Tx) error {
+		return inTransactionExec(tx, emptyOps, "UPDATE replay SET processedCount = processedCount + ?, cursorReceivedAt = ?, cursorId = ?, leaseExpiresAt = ?, updatedAt = ? WHERE id like ? AND leaseOwner = ? AND status != ?",
+			args2SliceFnWrapper(jobCount, lastMessage.ReceivedAt, lastMessage.ID.String(), leaseExpiresAt, currentTime, replay.ID, replay.LeaseOwner, data.ReplayCompleted), 1)
+	})
+	if err = transactionalWrites(replayRepo.db, ops...); err == nil {
+		replay.ProcessedCount += jobCount
+		replay.CursorReceivedAt = lastMessage.ReceivedAt
+		replay.CursorID = lastMessage.ID.String()
+		replay.LeaseExpiresAt = leaseExpiresAt
+		replay.UpdatedAt = currentTime
+	}
+	return jobCount, err
+}
+
+// GetNextMessages retrieves the next messages, in received at order, matching the replay after its cursor
+func (replayRepo *ReplayDBRepository) GetNextMessages(replay *data.Replay, limit int) ([]*data.Message, error) {
+	criteria, args := getReplayMessageCriteria(replay)
+	if len(replay.CursorID) > 0 {
+		criteria = criteria + " AND (receivedAt > ? OR (receivedAt = ? AND id > ?))"
+		args = append(args, replay.CursorReceivedAt, replay.CursorReceivedAt, replay.CursorID)
+	}
+	args = append(args, limit)
+	messages := make([]*data.Message, 0, limit)
+	codecs := make([]*string, 0, limit)
+	scanArgs := func() []interface{} {
+		msg := &data.Message{}
+		msg.ProducedBy = &data.Producer{}
+		msg.BroadcastedTo = replay.Consumer.ConsumingFrom
+		codec := new(string)
+		messages = append(messages, msg)
+		codecs = append(codecs, codec)
+		return []interface{}{&msg.ID, &msg.MessageID, &msg.ProducedBy.ProducerID, &msg.Payload, codec, &msg.PayloadRef, &msg.RoutingKey, &msg.Attributes, &msg.CloudEvent, &msg.ReceiptURL, &msg.ContentType, &msg.Priority, &msg.Status, &msg.ReceivedAt, &msg.OutboxedAt, &msg.CreatedAt, &msg.UpdatedAt}
+	}
+	err := queryRows(replayRepo.db, replayMessageSelectQuery+criteria+" ORDER BY receivedAt, id LIMIT ?", args2SliceFnWrapper(args...), scanArgs)
+	producers := make(map[string]*data.Producer)
+	for index := 0; err == nil && index < len(messages); index++ {
+		msg := messages[index]
+		if msg.Payload, err = decompressPayload(msg.Payload, *codecs[index]); err != nil {
+			break
+		}
+		producer, ok := producers[msg.ProducedBy.ProducerID]
+		if !ok {
+			producer, err = replayRepo.producerRepository.Get(msg.ProducedBy.ProducerID)
+			producers[msg.ProducedBy.ProducerID] = producer
+		}
+		msg.ProducedBy = producer
+	}
+	return messages, err
+}
+
+// NewReplayRepository creates a new instance of ReplayRepository
+func NewReplayRepository(db *sql.DB, consumerRepo ConsumerRepository, producerRepo ProducerRepository) ReplayRepository {
+	panicIfNoDBConnectionPool(db)
+	return &ReplayDBRepository{db: db, consumerRepository: consumerRepo, producerRepository: producerRepo}
+}
@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+func getReplayRepository() ReplayRepository {
+	return NewReplayRepository(testDB, getConsumerRepo(), NewProducerRepository(testDB))
+}
+
+func createDispatchedMessagesForReplay(t *testing.T, channel *data.Channel, count int) []*data.Message {
+	messages := make([]*data.Message, 0, count)
+	for index := 0; index < count; index++ {
+		message, _ := data.NewMessage(channel, producer1, samplePayload, sampleContentType)
+		message.ReceivedAt = time.Now().Add(time.Duration(index-count) * time.Minute)
+		assert.Nil(t, getMessageRepository().Create(message))
+		assert.Nil(t, getDeliverJobRepository().DispatchMessage(message))
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+func TestReplayLifecycle(t *testing.T) {
+	replayRepo := getReplayRepository()
+	replayChannel := createTestChannel("channel-for-replay", "sampletoken", NewChannelRepository(testDB))
+	consumer, _ := data.NewConsumer(replayChannel, "replay-consumer", "sometoken", callbackURL)
+	consumer, err := getConsumerRepo().Store(consumer)
+	assert.Nil(t, err)
+	messages := createDispatchedMessagesForReplay(t, replayChannel, 5)
+	t.Run("InvalidState", func(t *testing.T) {
+		assert.Equal(t, ErrInvalidStateToSave, replayRepo.Create(&data.Replay{Consumer: consumer}))
+	})
+	t.Run("TimeRange", func(t *testing.T) {
+		replay, err := data.NewReplay(consumer, messages[1].ReceivedAt, messages[3].ReceivedAt, nil)
+		assert.Nil(t, err)
+		assert.Nil(t, replayRepo.Create(replay))
+		assert.Equal(t, uint(3), replay.TotalCount)
+		dbReplay, err := replayRepo.Get(consumer, replay.ID.String())
+		assert.Nil(t, err)
+		assert.Equal(t, data.ReplayQueued, dbReplay.Status)
+		assert.Equal(t, replay.From.Unix(), dbReplay.From.Unix())
+		assert.Equal(t, replay.To.Unix(), dbReplay.To.Unix())
+		assert.True(t, dbReplay.CursorReceivedAt.IsZero())
+		assert.Equal(t, 0, len(dbReplay.MessageIDs))
+		toRun := replayRepo.GetReplaysToRun()
+		assert.True(t, containsReplay(toRun, replay))
+		assert.Nil(t, replayRepo.Lease(dbReplay, "owner-1", time.Minute))
+		assert.Equal(t, data.ReplayRunning, dbReplay.Status)
+		assert.Equal(t, ErrNoRowsUpdated, replayRepo.Lease(replay, "owner-2", time.Minute))
+		assert.False(t, containsReplay(replayRepo.GetReplaysToRun(), replay))
+		nextMessages, err := replayRepo.GetNextMessages(dbReplay, 2)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(nextMessages))
+		assert.Equal(t, messages[1].ID, nextMessages[0].ID)
+		assert.Equal(t, messages[2].ID, nextMessages[1].ID)
+		assert.Equal(t, producer1.ProducerID, nextMessages[0].ProducedBy.ProducerID)
+		start := time.Now()
+		jobCount, err := replayRepo.CreateJobs(dbReplay, nextMessages, start, time.Second, time.Minute)
+		assert.Nil(t, err)
+		assert.Equal(t, uint(2), jobCount)
+		assert.Equal(t, uint(2), dbReplay.ProcessedCount)
+		assert.Equal(t, nextMessages[1].ID.String(), dbReplay.CursorID)
+		jobs, _, err := getDeliverJobRepository().GetJobsForConsumer(consumer, data.JobQueued, data.NewPagination(nil, nil))
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(jobs))
+		for _, job := range jobs {
+			assert.WithinDuration(t, start, job.EarliestNextAttemptAt, 2*time.Second)
+		}
+		assert.Nil(t, getDeliverJobRepository().MarkJobInflight(jobs[0]))
+		assert.Nil(t, getDeliverJobRepository().MarkJobDelivered(jobs[0]))
+		assert.Nil(t, getDeliverJobRepository().MarkJobInflight(jobs[1]))
+		assert.Nil(t, getDeliverJobRepository().MarkJobDead(jobs[1]))
+		nextMessages, err = replayRepo.GetNextMessages(dbReplay, 2)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(nextMessages))
+		assert.Equal(t, messages[3].ID, nextMessages[0].ID)
+		dbReplay.LeaseOwner = "owner-2"
+		assert.Equal(t, ErrNoRowsUpdated, replayRepo.UpdateProgress(dbReplay, time.Minute))
+		_, err = replayRepo.CreateJobs(dbReplay, nextMessages, time.Now(), 0, time.Minute)
+		assert.Equal(t, ErrNoRowsUpdated, err)
+		jobs, _, err = getDeliverJobRepository().GetJobsForConsumer(consumer, data.JobQueued, data.NewPagination(nil, nil))
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(jobs))
+		dbReplay.LeaseOwner = "owner-1"
+		dbReplay.Status = data.ReplayCompleted
+		assert.Nil(t, replayRepo.UpdateProgress(dbReplay, 0))
+		dbReplay, err = replayRepo.Get(consumer, replay.ID.String())
+		assert.Nil(t, err)
+		assert.Equal(t, data.ReplayCompleted, dbReplay.Status)
+		assert.Equal(t, uint(2), dbReplay.ProcessedCount)
+		assert.Equal(t, uint(1), dbReplay.DeliveredCount)
+		assert.Equal(t, uint(1), dbReplay.FailedCount)
+		assert.Equal(t, messages[2].ID.String(), dbReplay.CursorID)
+		assert.False(t, containsReplay(replayRepo.GetReplaysToRun(), replay))
+	})
+	t.Run("MessageIDs", func(t *testing.T) {
+		replay, err := data.NewReplay(consumer, time.Time{}, time.Time{}, []string{messages[4].MessageID, messages[0].MessageID, "non-existent"})
+		assert.Nil(t, err)
+		assert.Nil(t, replayRepo.Create(replay))
+		assert.Equal(t, uint(2), replay.TotalCount)
+		dbReplay, err := replayRepo.Get(consumer, replay.ID.String())
+		assert.Nil(t, err)
+		assert.Equal(t, replay.MessageIDs, dbReplay.MessageIDs)
+		assert.True(t, dbReplay.From.IsZero())
+		nextMessages, err := replayRepo.GetNextMessages(dbReplay, 10)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(nextMessages))
+		assert.Equal(t, messages[0].ID, nextMessages[0].ID)
+		assert.Equal(t, messages[4].ID, nextMessages[1].ID)
+		var toRunReplay *data.Replay
+		for _, toRun := range replayRepo.GetReplaysToRun() {
+			if toRun.ID == replay.ID {
+				toRunReplay = toRun
+			}
+		}
+		assert.NotNil(t, toRunReplay)
+		assert.Equal(t, consumer.ID, toRunReplay.Consumer.ID)
+		assert.Equal(t, replayChannel.ChannelID, toRunReplay.Consumer.ConsumingFrom.ChannelID)
+	})
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := replayRepo.Get(consumer, "non-existent")
+		assert.NotNil(t, err)
+	})
+}
+
+func containsReplay(replays []*data.Replay, expected *data.Replay) bool {
+	for _, replay := range replays {
+		if replay.ID == expected.ID {
+			return true
+		}
+	}
+	return false
+}
+
+func TestReplayCreateJobsForDeliveredMessage(t *testing.T) {
+	replayRepo := getReplayRepository()
+	djRepo := getDeliverJobRepository()
+	replayChannel := createTestChannel("channel-for-delivered-replay", "sampletoken", NewChannelRepository(testDB))
+	consumer, _ := data.NewConsumer(replayChannel, "delivered-replay-consumer", "sometoken", callbackURL)
+	consumer, err := getConsumerRepo().Store(consumer)
+	assert.Nil(t, err)
+	message, _ := data.NewMessage(replayChannel, producer1, samplePayload, sampleContentType)
+	assert.Nil(t, getMessageRepository().Create(message))
+	originalJob, _ := data.NewDeliveryJob(message, consumer)
+	assert.Nil(t, djRepo.DispatchMessage(message, originalJob))
+	assert.Nil(t, djRepo.MarkJobInflight(originalJob))
+	assert.Nil(t, djRepo.MarkJobDelivered(originalJob))
+	// The message can be replayed to the consumer any number of times besides its original delivery
+	for index := 0; index < 2; index++ {
+		replay, err := data.NewReplay(consumer, time.Time{}, time.Time{}, []string{message.MessageID})
+		assert.Nil(t, err)
+		assert.Nil(t, replayRepo.Create(replay))
+		assert.Nil(t, replayRepo.Lease(replay, "owner", time.Minute))
+		nextMessages, err := replayRepo.GetNextMessages(replay, 10)
+		assert.Nil(t, err)
+		jobCount, err := replayRepo.CreateJobs(replay, nextMessages, time.Now(), 0, time.Minute)
+		assert.Nil(t, err)
+		assert.Equal(t, uint(1), jobCount)
+	}
+	queuedJobs, _, err := djRepo.GetJobsForConsumer(consumer, data.JobQueued, data.NewPagination(nil, nil))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(queuedJobs))
+	dbJob, err := djRepo.GetByID(originalJob.ID.String())
+	assert.Nil(t, err)
+	assert.Equal(t, data.JobDelivered, dbJob.Status)
+	// Jobs outside replays are still unique per message and consumer
+	duplicateJob, _ := data.NewDeliveryJob(message, consumer)
+	assert.NotNil(t, testDBInsertJob(duplicateJob))
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/newscred/webhook-broker/storage/data"
+)
+
+const (
+	notDeletedCriteria = " deletedAt IS NULL"
+	deletedCriteria    = " deletedAt IS NOT NULL"
+)
+
+// nullTimeScanner scans a nullable DATETIME column into the time it points to, leaving it zero for NULL
+type nullTimeScanner struct {
+	target *time.Time
+}
+
+// Scan implements sql.Scanner
+func (scanner nullTimeScanner) Scan(value interface{}) error {
+	nullTime := sql.NullTime{}
+	err := nullTime.Scan(value)
+	*scanner.target = nullTime.Time
+	return err
+}
+
+// execOp returns a transaction op executing the write query irrespective of the number of rows it affects
+func execOp(query string, args ...interface{}) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		return inTransactionExec(tx, emptyOps, query, args2SliceFnWrapper(args...), 0)
+	}
+}
+
+// softDeleteOp returns a transaction op marking the single row of the table as deleted at the time of the stakeholder; fails with
+// ErrNoRowsUpdated if it is already deleted
+func softDeleteOp(stakeholder *data.MessageStakeholder, query string, args ...interface{}) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		return inTransactionExec(tx, emptyOps, query, args2SliceFnWrapper(append([]interface{}{stakeholder.DeletedAt, stakeholder.UpdatedAt}, args...)...), 1)
+	}
+}
+
+// markDeleted sets the deletion time of the stakeholder, which is also its last update
+func markDeleted(stakeholder *data.MessageStakeholder) {
+	stakeholder.DeletedAt = time.Now()
+	stakeholder.UpdatedAt = stakeholder.DeletedAt
+}
+
+// cancelOutstandingJobsOps returns the transaction ops cancelling the queued and inflight jobs matching the job criteria and completing the
+// unfinished replays matching the replay criteria, so that neither are attempted any longer
+func cancelOutstandingJobsOps(jobCriteria string, replayCriteria string, args ...interface{}) []func(tx *sql.Tx) error {
+	currentTime := time.Now()
+	ops := []func(tx *sql.Tx) error{execOp("UPDATE job SET status = ?, statusChangedAt = ?, updatedAt = ? WHERE status IN (?, ?) AND"+jobCriteria,
+		append([]interface{}{data.JobCancelled, currentTime, currentTime, data.JobQueued, data.JobInflight}, args...)...)}
+	if len(replayCriteria) > 0 {
+		ops = append(ops, execOp("UPDATE replay SET status = ?, updatedAt = ? WHERE status != ? AND"+replayCriteria,
+			append([]interface{}{data.ReplayCompleted, currentTime, data.ReplayCompleted}, args...)...))
+	}
+	return ops
+}
+
+// getPayloadRefs retrieves the blob store references of the offloaded payloads of the messages matching the criteria
+func getPayloadRefs(db *sql.DB, messageCriteria string, args ...interface{}) ([]string, error) {
+	refs := make([]string, 0)
+	err := queryRows(db, "SELECT payloadRef FROM message WHERE payloadRef != '' AND"+messageCriteria, args2SliceFnWrapper(args...), func() []interface{} {
+		refs = append(refs, "")
+		return []interface{}{&refs[len(refs)-1]}
+	})
+	return refs, err
+}
@@ -26,9 +26,14 @@ func GetNewDataAccessor(dbConfig config.RelationalDatabaseConfig, migrationConf
 	producerRepository := NewProducerRepository(sqlDB)
 	channelRepository := NewChannelRepository(sqlDB)
 	consumerRepository := NewConsumerRepository(sqlDB, channelRepository)
-	messageRepository := NewMessageRepository(sqlDB, channelRepository, producerRepository)
+	payloadCompressor := NewPayloadCompressor(dbConfig)
+	messageRepository := NewMessageRepository(sqlDB, channelRepository, producerRepository, payloadCompressor)
 	deliveryJobRepository := NewDeliveryJobRepository(sqlDB, messageRepository, consumerRepository)
 	lockRepository := NewLockRepository(sqlDB)
+	replayRepository := NewReplayRepository(sqlDB, consumerRepository, producerRepository)
+	schemaRepository := NewSchemaRepository(sqlDB)
+	publishQuotaRepository := NewPublishQuotaRepository(sqlDB)
+	publishGrantRepository := NewPublishGrantRepository(sqlDB)
 	relationalDBDataAccessor := &RelationalDBDataAccessor{
 		db:                    sqlDB,
 		appRepository:         appRepository,
@@ -38,6 +43,10 @@ func GetNewDataAccessor(dbConfig config.RelationalDatabaseConfig, migrationConf
 		messageRepository:     messageRepository,
 		deliveryJobRepository: deliveryJobRepository,
 		lockRepository:        lockRepository,
+		replayRepository:      replayRepository,
+		schemaRepository:      schemaRepository,
+		quotaRepository:       publishQuotaRepository,
+		grantRepository:       publishGrantRepository,
 	}
 	return relationalDBDataAccessor, nil
 }
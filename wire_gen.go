@@ -9,6 +9,7 @@ import (
 	"github.com/newscred/webhook-broker/config"
 	"github.com/newscred/webhook-broker/controllers"
 	"github.com/newscred/webhook-broker/dispatcher"
+	"github.com/newscred/webhook-broker/grpcapi"
 	"github.com/newscred/webhook-broker/storage"
 )
 
@@ -33,18 +34,24 @@ func GetHTTPServer(cliConfig *config.CLIConfig) (*HTTPServiceContainer, error) {
 	appRepository := newAppRepository(dataAccessor)
 	statusController := controllers.NewStatusController(appRepository)
 	producerRepository := newProducerRepository(dataAccessor)
-	producerController := controllers.NewProducerController(producerRepository)
+	publishQuotaRepository := newPublishQuotaRepository(dataAccessor)
+	blobStore, err := storage.NewBlobStore(configConfig)
+	if err != nil {
+		return nil, err
+	}
+	producerController := controllers.NewProducerController(producerRepository, publishQuotaRepository, configConfig, blobStore, configConfig)
 	producersController := controllers.NewProducersController(producerRepository, producerController)
 	channelRepository := newChannelRepository(dataAccessor)
 	consumerRepository := newConsumerRepository(dataAccessor)
-	messageRepository := newMessageRepository(dataAccessor)
 	deliveryJobRepository := newDeliveryJobRepository(dataAccessor)
+	messageRepository := newMessageRepository(dataAccessor)
 	messageController := controllers.NewMessageController(messageRepository, deliveryJobRepository)
-	dlqController := controllers.NewDLQController(messageController, deliveryJobRepository, consumerRepository)
-	consumerController := controllers.NewConsumerController(channelRepository, consumerRepository, dlqController)
-	consumersController := controllers.NewConsumersController(consumerController, consumerRepository)
-	messagesController := controllers.NewMessagesController(messageController, messageRepository)
+	deadJobController := controllers.NewDeadJobController(messageController, deliveryJobRepository, consumerRepository)
+	dlqController := controllers.NewDLQController(messageController, deadJobController, deliveryJobRepository, consumerRepository)
+	consumerVerifier := dispatcher.NewConsumerVerifier(configConfig, consumerRepository)
+	consumerVerificationController := controllers.NewConsumerVerificationController(consumerRepository, consumerVerifier)
 	lockRepository := newLockRepository(dataAccessor)
+	replayRepository := newReplayRepository(dataAccessor)
 	configuration := &dispatcher.Configuration{
 		DeliveryJobRepo:          deliveryJobRepository,
 		ConsumerRepo:             consumerRepository,
@@ -52,32 +59,78 @@ func GetHTTPServer(cliConfig *config.CLIConfig) (*HTTPServiceContainer, error) {
 		BrokerConfig:             configConfig,
 		ConsumerConnectionConfig: configConfig,
 		MsgRepo:                  messageRepository,
+		ReplayRepo:               replayRepository,
+		ChannelRepo:              channelRepository,
+		ProducerRepo:             producerRepository,
+		BlobStore:                blobStore,
 	}
 	messageDispatcher := dispatcher.NewMessageDispatcher(configuration)
-	broadcastController := controllers.NewBroadcastController(channelRepository, messageRepository, producerRepository, messageDispatcher)
-	channelController := controllers.NewChannelController(consumersController, messagesController, broadcastController, channelRepository)
+	systemEventPublisher := dispatcher.NewSystemEventPublisher(configuration, messageDispatcher)
+	consumerController := controllers.NewConsumerController(channelRepository, consumerRepository, deliveryJobRepository, dlqController, consumerVerificationController, consumerVerifier, systemEventPublisher, configConfig, configConfig)
+	consumersController := controllers.NewConsumersController(consumerController, consumerRepository)
+	messagesController := controllers.NewMessagesController(messageController, messageRepository, channelRepository, deliveryJobRepository)
+	schemaRepository := newSchemaRepository(dataAccessor)
+	publishGrantRepository := newPublishGrantRepository(dataAccessor)
+	broadcastController := controllers.NewBroadcastController(channelRepository, messageRepository, producerRepository, deliveryJobRepository, schemaRepository, publishQuotaRepository, publishGrantRepository, messageDispatcher, blobStore, configConfig, configConfig, configConfig)
+	channelController := controllers.NewChannelController(consumersController, messagesController, broadcastController, channelRepository, systemEventPublisher, publishQuotaRepository, configConfig, blobStore, configConfig)
+	broadcastBatchController := controllers.NewBroadcastBatchController(broadcastController)
+	channelDLQController := controllers.NewChannelDLQController(messageController, deadJobController, deliveryJobRepository, channelRepository, consumerRepository)
+	dlqExportController := controllers.NewDLQExportController(deliveryJobRepository, channelRepository, consumerRepository, blobStore)
 	channelsController := controllers.NewChannelsController(channelRepository, channelController)
+	consumerPauseController := controllers.NewConsumerPauseController(consumerRepository)
+	consumerResumeController := controllers.NewConsumerResumeController(consumerRepository, deliveryJobRepository, configConfig)
+	replayController := controllers.NewReplayController(consumerRepository, replayRepository)
+	replaysController := controllers.NewReplaysController(consumerRepository, replayRepository, replayController)
+	consumerStreamer := dispatcher.NewConsumerStreamer(configuration, messageDispatcher)
+	consumerStreamController := controllers.NewConsumerStreamController(consumerRepository, consumerStreamer, configConfig, configConfig)
+	consumerStreamAckController := controllers.NewConsumerStreamAckController(consumerRepository, consumerStreamer)
+	schemaController := controllers.NewSchemaController(channelRepository, schemaRepository)
+	schemasController := controllers.NewSchemasController(channelRepository, schemaRepository, schemaController)
+	publishGrantController := controllers.NewPublishGrantController(channelRepository, producerRepository, publishGrantRepository, channelController, producerController)
+	consumerStatsController := controllers.NewConsumerStatsController(consumerRepository, deliveryJobRepository)
+	channelStatsController := controllers.NewChannelStatsController(channelRepository, deliveryJobRepository)
+	adminConsoleController := controllers.NewAdminConsoleController(configConfig)
 	controllersControllers := &controllers.Controllers{
-		StatusController:    statusController,
-		ProducersController: producersController,
-		ProducerController:  producerController,
-		ChannelController:   channelController,
-		ConsumerController:  consumerController,
-		ConsumersController: consumersController,
-		BroadcastController: broadcastController,
-		MessageController:   messageController,
-		MessagesController:  messagesController,
-		DLQController:       dlqController,
-		ChannelsController:  channelsController,
+		StatusController:               statusController,
+		ProducersController:            producersController,
+		ProducerController:             producerController,
+		ChannelController:              channelController,
+		ConsumerController:             consumerController,
+		ConsumersController:            consumersController,
+		BroadcastController:            broadcastController,
+		BroadcastBatchController:       broadcastBatchController,
+		MessageController:              messageController,
+		MessagesController:             messagesController,
+		DLQController:                  dlqController,
+		DeadJobController:              deadJobController,
+		ChannelDLQController:           channelDLQController,
+		DLQExportController:            dlqExportController,
+		ChannelsController:             channelsController,
+		ConsumerVerificationController: consumerVerificationController,
+		ConsumerPauseController:        consumerPauseController,
+		ConsumerResumeController:       consumerResumeController,
+		ReplaysController:              replaysController,
+		ReplayController:               replayController,
+		ConsumerStreamController:       consumerStreamController,
+		ConsumerStreamAckController:    consumerStreamAckController,
+		SchemasController:              schemasController,
+		SchemaController:               schemaController,
+		PublishGrantController:         publishGrantController,
+		ConsumerStatsController:        consumerStatsController,
+		ChannelStatsController:         channelStatsController,
+		AdminConsoleController:         adminConsoleController,
 	}
 	router := controllers.NewRouter(controllersControllers)
 	server := controllers.ConfigureAPI(configConfig, serverLifecycleListenerImpl, router)
+	brokerServer := grpcapi.NewBrokerServer(dataAccessor, messageDispatcher, consumerStreamer, consumerVerifier, systemEventPublisher, blobStore, configConfig, configConfig, configConfig, configConfig)
+	grpcServer := grpcapi.ConfigureGRPCAPI(configConfig, brokerServer)
 	httpServiceContainer := &HTTPServiceContainer{
 		Configuration: configConfig,
 		Server:        server,
 		DataAccessor:  dataAccessor,
 		Listener:      serverLifecycleListenerImpl,
 		Dispatcher:    messageDispatcher,
+		GRPCServer:    grpcServer,
 	}
 	return httpServiceContainer, nil
 }